@@ -0,0 +1,99 @@
+package app
+
+import (
+	"context"
+	"github.com/mfbonfigli/gocesiumtiler/converters/gh_ellipsoid_to_geoid_z_converter"
+	"github.com/mfbonfigli/gocesiumtiler/converters/proj4_coordinate_converter"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"time"
+)
+
+// Tiles point cloud files with a fixed set of options, wiring the reading, the loader, the octree and the writers of
+// the tiles together, so that library users need a single call per input
+type Tiler struct {
+	opts *tiler.TilerOptions
+}
+
+// Statistics of a Tiler run. If several tilesets are written, e.g. one per input file, the counts are summed, the
+// maximum depth and root geometric error are the largest ones and the bounding region contains all their root regions
+type TilerResult struct {
+	TotalPoints        int64         // Number of points stored in the written tiles
+	TilesWritten       int           // Number of written content files
+	MaxDepth           int           // Depth of the deepest written tile, 1 if only the root tile was written
+	RootGeometricError float64       // Geometric error of the root tile in meters
+	BoundingRegion     []float64     // WGS84 bounding region of the root tile as west, south, east, north in radians and min, max height in meters
+	Elapsed            time.Duration // Duration of the run
+}
+
+// Instances a new Tiler with the given options. Their Input and Output are ignored, they are given to each Run. If
+// not set, the coordinate converter defaults to the proj4 one and, if the geoid correction is enabled, the elevation
+// converter to the default global geoid model, as for the command line tool
+func New(opts *tiler.TilerOptions) *Tiler {
+	tilerOpts := *opts
+	if tilerOpts.CoordinateConverter == nil {
+		tilerOpts.CoordinateConverter = proj4_coordinate_converter.NewProj4CoordinateConverter()
+	}
+	if tilerOpts.ElevationConverter == nil && tilerOpts.EnableGeoidZCorrection {
+		tilerOpts.ElevationConverter = gh_ellipsoid_to_geoid_z_converter.NewGHElevationConverter(tilerOpts.CoordinateConverter)
+	}
+	return &Tiler{opts: &tilerOpts}
+}
+
+// Tiles the given input file, or the files in the given input folder if FolderProcessing is set, writing the tileset
+// of each file in the subfolder of the given output folder named after the file, or in the output folder itself if
+// MergeFiles is set. The output folder is created if missing. Progress is reported to the ProgressCallback of the
+// options, if set. Stops as soon as the given context is done, removing the files of the tiles already written for
+// the tileset being exported and returning the context error. Returns the statistics of the written tiles. Runs do not
+// change the options of the Tiler, so that it can be reused
+func (t *Tiler) Run(ctx context.Context, inputFile, outputDir string) (*TilerResult, error) {
+	start := time.Now()
+	opts := *t.opts
+	opts.Input = inputFile
+	opts.Output = outputDir
+	result := &TilerResult{}
+	onTileWritten := opts.OnTileWritten
+	opts.OnTileWritten = func(tile tiler.TileInfo) {
+		result.addTile(tile)
+		if onTileWritten != nil {
+			onTileWritten(tile)
+		}
+	}
+	writer := opts.TileWriter
+	if writer == nil {
+		writer = io.NewOSTileWriter(&opts)
+	}
+	if err := writer.MkdirAll(outputDir); err != nil {
+		return nil, err
+	}
+	if err := RunTilerContext(ctx, &opts); err != nil {
+		return nil, err
+	}
+	result.Elapsed = time.Since(start)
+	return result, nil
+}
+
+// Adds the given written tile to the statistics
+func (result *TilerResult) addTile(tile tiler.TileInfo) {
+	result.TotalPoints += int64(tile.PointCount)
+	result.TilesWritten++
+	if tile.Depth > result.MaxDepth {
+		result.MaxDepth = tile.Depth
+	}
+	if tile.Depth != 1 {
+		return
+	}
+	if tile.GeometricError > result.RootGeometricError {
+		result.RootGeometricError = tile.GeometricError
+	}
+	if result.BoundingRegion == nil {
+		result.BoundingRegion = append([]float64{}, tile.Region...)
+		return
+	}
+	for i := range tile.Region {
+		// west, south and min height are minimums, the others maximums
+		if (i == 0 || i == 1 || i == 4) == (tile.Region[i] < result.BoundingRegion[i]) {
+			result.BoundingRegion[i] = tile.Region[i]
+		}
+	}
+}
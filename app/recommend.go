@@ -0,0 +1,195 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"github.com/mfbonfigli/gocesiumtiler/structs/point_loader"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"github.com/mfbonfigli/gocesiumtiler/utils"
+	"math"
+	"strconv"
+)
+
+// Meters spanned by a degree of latitude, and of longitude at the equator
+const metersPerDegree = 111320.0
+
+// Inputs sampled to fewer points are read again in full, their estimates being too coarse otherwise
+const minAnalysisSample = 10000
+
+// Max number of cells along each side of the grid measuring the covered area and the density variation of the sample
+const maxAnalysisGridSize = 64
+
+// Sampled points farther than this number of standard deviations from the mean along any axis are outliers
+const outlierSigmas = 6.0
+
+// Coefficient of variation of the points per covered cell above which the density is considered uneven
+const unevenDensityVariation = 1.0
+
+// Extent and density of the input points, measured on a sample
+type inputAnalysis struct {
+	TotalPoints      int64   // Number of points of the input files
+	SampledPoints    int     // Number of points analyzed
+	Width, Length    float64 // Size in meters of the bounds of the points along the longitude and the latitude
+	Height           float64 // Size in meters of the bounds of the points along the vertical
+	CoveredArea      float64 // Area in square meters of the cells of the analysis grid holding points
+	Density          float64 // Points per square meter of the covered area
+	Spacing          float64 // Mean distance in meters between neighbouring points, assuming an even density
+	DensityVariation float64 // Coefficient of variation of the number of points of the covered cells
+	Outliers         int     // Number of sampled points farther than outlierSigmas standard deviations from the mean
+}
+
+// Reads about the given fraction of the points of the input files of the given options, decimating them while read,
+// measures their extent and density and returns a copy of the options with the max number of points per tile, the
+// loader strategy, the geometric errors and the bounds sigmas recommended for them. The rationale of each
+// recommendation is logged
+func RecommendOptions(opts *tiler.TilerOptions, sampleFraction float64) (*tiler.TilerOptions, error) {
+	if sampleFraction <= 0 || sampleFraction > 1 {
+		return nil, errors.New("the sample fraction must be greater than 0 and at most 1")
+	}
+	files := getLasFilesToProcess(opts)
+	if len(files) == 0 {
+		return nil, errors.New("no input files found")
+	}
+
+	stride := int(math.Round(1 / sampleFraction))
+	utils.LogOutput("Sampling one point every", stride, "to recommend the tiling options...")
+	sample, total, err := readSample(opts, files, stride)
+	if err != nil {
+		return nil, err
+	}
+	if len(sample) < minAnalysisSample && stride > 1 {
+		utils.LogOutput("> sample too small, reading all the points")
+		if sample, total, err = readSample(opts, files, 1); err != nil {
+			return nil, err
+		}
+	}
+	if len(sample) == 0 {
+		return nil, errors.New("no points found in the input files")
+	}
+
+	analysis := analyzeSample(sample, total)
+	utils.LogOutput(fmt.Sprintf("> %d points over %.0f x %.0f x %.0f m, covering %.0f m2", analysis.TotalPoints, analysis.Width, analysis.Length, analysis.Height, analysis.CoveredArea))
+	utils.LogOutput(fmt.Sprintf("> density %.2f points/m2, spacing %.3f m, density variation %.2f, %d outliers in %d sampled points", analysis.Density, analysis.Spacing, analysis.DensityVariation, analysis.Outliers, analysis.SampledPoints))
+	return recommendOptions(opts, analysis), nil
+}
+
+// Reads one point every given number of points of the given files and returns them together with the total number of
+// points read
+func readSample(opts *tiler.TilerOptions, files []string, stride int) ([]*data.Point, int64, error) {
+	// the readers change the srid and the progress refers to the tiling
+	readOpts := *opts
+	readOpts.ProgressCallback = nil
+	loader := point_loader.NewRandomLoader(0)
+	sampler := point_loader.NewSamplingLoader(loader, stride)
+	if err := readFilesConcurrently(context.Background(), files, &readOpts, sampler); err != nil {
+		return nil, 0, err
+	}
+	loader.Initialize()
+	sample := make([]*data.Point, 0)
+	for {
+		point, hasNext := loader.GetNext()
+		if point != nil {
+			sample = append(sample, point)
+		}
+		if !hasNext {
+			break
+		}
+	}
+	return sample, sampler.GetTotalCount(), nil
+}
+
+// Measures the extent and the density of the given sample, in EPSG:4326 coordinates, of the given number of points
+func analyzeSample(sample []*data.Point, total int64) inputAnalysis {
+	analysis := inputAnalysis{TotalPoints: total, SampledPoints: len(sample)}
+	min := [3]float64{math.MaxFloat64, math.MaxFloat64, math.MaxFloat64}
+	max := [3]float64{-math.MaxFloat64, -math.MaxFloat64, -math.MaxFloat64}
+	var mean, squares [3]float64
+	for _, point := range sample {
+		for axis, value := range [3]float64{point.X, point.Y, point.Z} {
+			min[axis] = math.Min(min[axis], value)
+			max[axis] = math.Max(max[axis], value)
+			mean[axis] += value
+			squares[axis] += value * value
+		}
+	}
+	var sigma [3]float64
+	for axis := range mean {
+		mean[axis] /= float64(len(sample))
+		sigma[axis] = math.Sqrt(math.Max(squares[axis]/float64(len(sample))-mean[axis]*mean[axis], 0))
+	}
+	for _, point := range sample {
+		for axis, value := range [3]float64{point.X, point.Y, point.Z} {
+			if sigma[axis] > 0 && math.Abs(value-mean[axis]) > outlierSigmas*sigma[axis] {
+				analysis.Outliers++
+				break
+			}
+		}
+	}
+
+	metersPerLongitude := metersPerDegree * math.Cos(mean[1]*math.Pi/180)
+	analysis.Width = (max[0] - min[0]) * metersPerLongitude
+	analysis.Length = (max[1] - min[1]) * metersPerDegree
+	analysis.Height = max[2] - min[2]
+
+	// grid with about 10 sampled points per cell if evenly spread, to count the covered cells and their variation
+	gridSize := int(math.Max(math.Min(math.Sqrt(float64(len(sample))/10), maxAnalysisGridSize), 1))
+	counts := make(map[[2]int]int)
+	for _, point := range sample {
+		counts[[2]int{gridCell(point.X, min[0], max[0], gridSize), gridCell(point.Y, min[1], max[1], gridSize)}]++
+	}
+	cellArea := analysis.Width / float64(gridSize) * analysis.Length / float64(gridSize)
+	analysis.CoveredArea = float64(len(counts)) * cellArea
+	if analysis.CoveredArea > 0 {
+		analysis.Density = float64(total) / analysis.CoveredArea
+		analysis.Spacing = 1 / math.Sqrt(analysis.Density)
+	}
+	meanCount := float64(len(sample)) / float64(len(counts))
+	variance := 0.0
+	for _, count := range counts {
+		variance += (float64(count) - meanCount) * (float64(count) - meanCount)
+	}
+	analysis.DensityVariation = math.Sqrt(variance/float64(len(counts))) / meanCount
+	return analysis
+}
+
+// Returns the index of the cell holding the given value in a grid of the given number of cells spanning min to max
+func gridCell(value, min, max float64, gridSize int) int {
+	if max <= min {
+		return 0
+	}
+	return int(math.Min((value-min)/(max-min)*float64(gridSize), float64(gridSize-1)))
+}
+
+// Returns a copy of the given options with the max number of points per tile, the loader strategy, the geometric
+// errors and the bounds sigmas recommended for the given analysis, logging the rationale of each
+func recommendOptions(opts *tiler.TilerOptions, analysis inputAnalysis) *tiler.TilerOptions {
+	recommended := *opts
+
+	// about 64 leaf tiles, i.e. at least 3 levels, with small inputs, but tiles small enough to be streamed with
+	// large ones
+	maxPoints := int32(math.Min(math.Max(float64(analysis.TotalPoints)/64, 5000), 50000)) / 1000 * 1000
+	recommended.MaxNumPointsPerNode = maxPoints
+	utils.LogOutput("> max points per tile", maxPoints, "to split the points in tiles of 5000 to 50000 points, at least 3 levels deep if possible")
+
+	if analysis.DensityVariation > unevenDensityVariation {
+		recommended.Strategy = tiler.GridDecimation
+		utils.LogOutput("> grid sampling, as the density is uneven and grid sampling evens the coarse tiles")
+	} else {
+		recommended.Strategy = tiler.BoxedRandom
+		utils.LogOutput("> boxed random sampling, as the density is even and boxed random sampling spaces the points of the coarse tiles at a low cost")
+	}
+
+	// a tile holding the max number of points over its footprint has a point spacing of its side over the square
+	// root of the points, i.e. its diagonal over the square root of 3 times the points
+	recommended.GeometricErrors = tiler.DiagonalGeometricErrors
+	recommended.DiagonalFraction = 1 / math.Sqrt(3*float64(maxPoints))
+	utils.LogOutput("> geometric errors of", strconv.FormatFloat(recommended.DiagonalFraction, 'f', 5, 64), "times the tile diagonal, about the point spacing of the tiles, so that they halve at each level")
+
+	if analysis.Outliers > 0 && opts.BoundsSigmas <= 0 {
+		recommended.BoundsSigmas = outlierSigmas
+		utils.LogOutput("> bounds sigmas", outlierSigmas, "to keep the", analysis.Outliers, "outliers of the sample out of the root bounds")
+	}
+	return &recommended
+}
@@ -1,21 +1,24 @@
 package app
 
 import (
+	"context"
 	"errors"
-	"fmt"
 	"github.com/mfbonfigli/gocesiumtiler/converters"
 	"github.com/mfbonfigli/gocesiumtiler/converters/geoid_elevation_corrector"
+	"github.com/mfbonfigli/gocesiumtiler/converters/geoid_height_corrector"
 	"github.com/mfbonfigli/gocesiumtiler/converters/offset_elevation_corrector"
 	"github.com/mfbonfigli/gocesiumtiler/io"
 	"github.com/mfbonfigli/gocesiumtiler/lasread"
+	"github.com/mfbonfigli/gocesiumtiler/plyread"
 	"github.com/mfbonfigli/gocesiumtiler/structs/octree"
 	"github.com/mfbonfigli/gocesiumtiler/structs/point_loader"
 	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"github.com/mfbonfigli/gocesiumtiler/textread"
 	"github.com/mfbonfigli/gocesiumtiler/utils"
 	"log"
+	"math"
 	"os"
 	"path/filepath"
-	"runtime"
 	"strconv"
 	"strings"
 	"sync"
@@ -23,64 +26,649 @@ import (
 
 // Starts the tiling process
 func RunTiler(opts *tiler.TilerOptions) error {
+	return RunTilerContext(context.Background(), opts)
+}
+
+// Starts the tiling process, stopping it as soon as the given context is done. In this case the files of the tiles
+// already written for the tileset being exported are removed and the context error is returned
+func RunTilerContext(ctx context.Context, opts *tiler.TilerOptions) error {
 	utils.LogOutput("Preparing list of files to process...")
+	if opts.DracoCompression && opts.DracoEncoder == nil {
+		utils.LogOutput("No Draco encoder available, tiles are written uncompressed")
+	}
+
+	if opts.TileWriter != nil && (opts.MasterTileset || opts.GraftTileset != "") {
+		return errors.New("the master and graft tilesets read the written tilesets back from the local disk and cannot be used together with a custom TileWriter")
+	}
+	if err := checkImplicitTiling(opts); err != nil {
+		return err
+	}
+	if opts.Resume && opts.RandomSeed == 0 {
+		return errors.New("resuming requires a random seed, so that the tiles of the resumed run match the ones already written")
+	}
 
 	// Prepare list of files to process
 	lasFiles := getLasFilesToProcess(opts)
 
-	// Define elevation (Z) correction algorithm to apply
-	elevationCorrectionAlg := getElevationCorrectionAlgorithm(opts)
+	if err := tileFiles(ctx, opts, lasFiles); err != nil {
+		return err
+	}
+	return graftTileset(opts, lasFiles)
+}
+
+// Returns an error if the implicit tiling is requested together with options it does not support. The uniform
+// subdivisions of the root bounding volume match the octree cells only for the bounding regions of the points in
+// longitude and latitude, and the tiles of the implicit tiling are named after their coordinates
+func checkImplicitTiling(opts *tiler.TilerOptions) error {
+	if opts.ImplicitTiling && (opts.DepthFolders || opts.TileNamingStrategy != tiler.NumericTileNames || len(opts.SubtreePath) > 0 || opts.BoundingVolumes != tiler.RegionBoundingVolumes || opts.OutputMode == tiler.RawCoordinates || opts.TightBounds || opts.EnforceRegionContainment) {
+		return errors.New("implicit tiling cannot be used together with depth folders, hierarchical tile names, subtrees, bounding spheres or boxes, raw output coordinates, tight bounds or the region containment")
+	}
+	return nil
+}
+
+// Tiles the given files as per the options
+func tileFiles(ctx context.Context, opts *tiler.TilerOptions, lasFiles []string) error {
+	if err := checkFileZOffsets(opts, lasFiles); err != nil {
+		return err
+	}
+	if err := stretchIntensities(opts, lasFiles); err != nil {
+		return err
+	}
+	if err := resolveExtraAttributes(opts, lasFiles); err != nil {
+		return err
+	}
+
+	// Eventually tile the files in parallel
+	if opts.Concurrency > 0 {
+		jobs := make([]BatchJob, len(lasFiles))
+		for i, filePath := range lasFiles {
+			jobs[i] = BatchJob{Input: filePath, Output: opts.Output}
+		}
+		if err := runBatchTiler(ctx, jobs, opts, opts.Concurrency); err != nil {
+			return err
+		}
+		return writeMasterTileset(opts, lasFiles)
+	}
 
 	// Define point_loader strategy
-	var loader = getLoaderFromLoaderStrategy(opts.Strategy)
+	var loader = getLoaderFromLoaderStrategy(opts)
+
+	// Eventually merge all the files in a single tileset
+	if opts.MergeFiles {
+		err := processMergedFiles(ctx, lasFiles, opts, loader)
+		opts.CoordinateConverter.Cleanup()
+		return err
+	}
 
 	// load las points in octree buffer
+	inputSrid := opts.Srid
 	for i, filePath := range lasFiles {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		utils.LogOutput("Processing file " + strconv.Itoa(i+1) + "/" + strconv.Itoa(len(lasFiles)))
-		processLasFile(filePath, opts, loader, elevationCorrectionAlg)
+
+		// the readers change the srid to the one of the read points
+		opts.Srid = getFileSrid(opts, inputSrid, filePath)
+
+		// Define elevation (Z) correction algorithm to apply, including the vertical offset specific to the file
+		elevationCorrectionAlg := getElevationCorrectionAlgorithm(opts, opts.ZOffset+opts.FileZOffsets[filepath.Base(filePath)])
+
+		err := processLasFile(ctx, filePath, opts, loader, elevationCorrectionAlg)
+		opts.CoordinateConverter.Cleanup()
+		if err != nil {
+			return err
+		}
+	}
+
+	return writeMasterTileset(opts, lasFiles)
+}
+
+// Returns an error if a file Z offset of the options applies to more than one of the given files. The offsets are
+// keyed by file name, which files with the same name in different folders of a recursive processing share
+func checkFileZOffsets(opts *tiler.TilerOptions, filePaths []string) error {
+	files := make(map[string]string)
+	for _, filePath := range filePaths {
+		name := filepath.Base(filePath)
+		if _, ok := opts.FileZOffsets[name]; !ok {
+			continue
+		}
+		if other, ok := files[name]; ok {
+			return errors.New("the file z offset of " + name + " is ambiguous, both " + other + " and " + filePath + " have this name")
+		}
+		files[name] = filePath
+	}
+	return nil
+}
+
+// If the options request the auto stretch of the intensities, reads the intensity range of all the given LAS and LAZ
+// files and replaces the auto stretch with the fixed scaling of this range, so that every tile and every file uses
+// the same scale
+func stretchIntensities(opts *tiler.TilerOptions, filePaths []string) error {
+	if opts.IntensityScaling.Mode != tiler.AutoStretchIntensityScaling {
+		return nil
+	}
+	min, max := uint16(math.MaxUint16), uint16(0)
+	for _, filePath := range filePaths {
+		if !isLasFile(filePath) {
+			continue
+		}
+		fileMin, fileMax, err := lidario.ReadIntensityRange(filePath, opts.ReadBufferSize)
+		if err != nil {
+			return err
+		}
+		if fileMin < min {
+			min = fileMin
+		}
+		if fileMax > max {
+			max = fileMax
+		}
+	}
+	if min > max {
+		// no intensities, the scaling is irrelevant
+		min, max = 0, math.MaxUint16
 	}
+	utils.LogOutput("> stretching the intensities from", min, "-", max, "to 0 - 255")
+	opts.IntensityScaling = tiler.NewFixedIntensityScaling(min, max)
+	return nil
+}
 
+// Sets the component types of the extra attributes of the options left empty from the Extra Bytes VLRs of the given
+// LAS and LAZ files, so that every tile writes them with the same type. Attributes declared with different types by
+// different files are written as doubles. Attributes not declared by any file, or only with unsupported data types,
+// are logged and dropped
+func resolveExtraAttributes(opts *tiler.TilerOptions, filePaths []string) error {
+	if len(opts.ExtraAttributes) == 0 {
+		return nil
+	}
+	attributes := append([]tiler.ExtraAttribute(nil), opts.ExtraAttributes...)
+	resolved := make([]string, len(attributes))
+	for _, filePath := range filePaths {
+		if !isLasFile(filePath) {
+			continue
+		}
+		header, err := lidario.ReadLasHeaderInfo(filePath)
+		if err != nil {
+			return err
+		}
+		for i, attribute := range attributes {
+			for _, descriptor := range header.ExtraBytes {
+				componentType := descriptor.ComponentType()
+				if attribute.ComponentType != "" || descriptor.Name != attribute.Name || componentType == "" {
+					continue
+				}
+				if resolved[i] == "" {
+					resolved[i] = componentType
+				} else if resolved[i] != componentType {
+					resolved[i] = "DOUBLE"
+				}
+			}
+		}
+	}
+	opts.ExtraAttributes = make([]tiler.ExtraAttribute, 0, len(attributes))
+	for i, attribute := range attributes {
+		if attribute.ComponentType == "" {
+			attribute.ComponentType = resolved[i]
+		}
+		if attribute.ComponentType == "" {
+			utils.LogOutput("> warning: skipping the extra attribute", attribute.Name, "not declared with a supported data type by any las file")
+			continue
+		}
+		opts.ExtraAttributes = append(opts.ExtraAttributes, attribute)
+	}
 	return nil
 }
 
-func processLasFile(filePath string, opts *tiler.TilerOptions, loader point_loader.Loader, elevationCorrectionAlg converters.ElevationCorrector) {
-	// Create empty octree
-	OctTree := octree.NewOctTree(opts)
+// If requested by the options, writes a tileset.json in the output folder loading the tilesets of the given files
+func writeMasterTileset(opts *tiler.TilerOptions, filePaths []string) error {
+	if !opts.MasterTileset || len(filePaths) == 0 {
+		return nil
+	}
+	children := make([]io.ChildTilesetRef, len(filePaths))
+	for i, filePath := range filePaths {
+		child, err := io.ReadChildTilesetRef(opts.Output, getFilenameWithoutExtension(filePath)+"/tileset.json")
+		if err != nil {
+			return err
+		}
+		child.Url = io.WithUrlQuery(child.Url, opts)
+		children[i] = child
+	}
+	utils.LogOutput("Writing the master tileset of", len(children), "tilesets")
+	return io.WriteMasterTileset(opts.Output, children, opts)
+}
+
+// Input LAS file and output folder of a tileset generated by RunBatchTiler
+type BatchJob struct {
+	Input  string // Input LAS file
+	Output string // Output folder, the tileset is written in its subfolder named as the LAS file
+}
 
-	readLasData(filePath, elevationCorrectionAlg, opts, loader)
-	prepareDataStructure(OctTree, loader)
-	exportToCesiumTileset(OctTree, opts, getFilenameWithoutExtension(filePath))
+// Error raised tiling the input file of a BatchJob
+type JobError struct {
+	Input string // Input LAS file of the failed job
+	Err   error
+}
 
+func (e *JobError) Error() string {
+	return "file " + e.Input + ": " + e.Err.Error()
+}
+
+func (e *JobError) Unwrap() error {
+	return e.Err
+}
+
+// Combined error of all the jobs of a batch that failed
+type BatchError struct {
+	Errors []error
+}
+
+func (e *BatchError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return strconv.Itoa(len(e.Errors)) + " jobs failed:\n" + strings.Join(messages, "\n")
+}
+
+// Returns the errors of the single jobs, to be inspected by errors.Is and errors.As
+func (e *BatchError) Unwrap() []error {
+	return e.Errors
+}
+
+// If requested by the options, links the written tileset in the slot of the existing tileset hierarchy
+func graftTileset(opts *tiler.TilerOptions, filePaths []string) error {
+	if opts.GraftTileset == "" || len(filePaths) == 0 {
+		return nil
+	}
+	tilesetFile := filepath.Join(opts.Output, "tileset.json")
+	if !opts.MergeFiles && !opts.MasterTileset {
+		tilesetFile = filepath.Join(opts.Output, getFilenameWithoutExtension(filePaths[0]), "tileset.json")
+	}
+	utils.LogOutput("Grafting the tileset in", opts.GraftTileset)
+	return io.GraftTileset(opts.GraftTileset, opts.GraftSlot, tilesetFile, opts.UrlQuery)
+}
+
+// Tiles each of the given LAS files into its own tileset, processing up to the given number of files at a time. The
+// reading, building and exporting work of all files runs on a single pool of the given number of goroutines, so that
+// the total number of goroutines stays bounded regardless of the number of files. Options other than input and output
+// are shared by all jobs. Returns a BatchError listing every job that failed, if any.
+func RunBatchTiler(jobs []BatchJob, opts *tiler.TilerOptions, concurrency int) error {
+	if err := checkImplicitTiling(opts); err != nil {
+		return err
+	}
+	if opts.Resume && opts.RandomSeed == 0 {
+		return errors.New("resuming requires a random seed, so that the tiles of the resumed run match the ones already written")
+	}
+	return runBatchTiler(context.Background(), jobs, opts, concurrency)
+}
+
+func runBatchTiler(ctx context.Context, jobs []BatchJob, opts *tiler.TilerOptions, concurrency int) error {
+	inputs := make([]string, len(jobs))
+	for i, job := range jobs {
+		inputs[i] = job.Input
+	}
+	batchOpts := *opts
+	opts = &batchOpts
+	if err := checkFileZOffsets(opts, inputs); err != nil {
+		return err
+	}
+	if err := stretchIntensities(opts, inputs); err != nil {
+		return err
+	}
+	if err := resolveExtraAttributes(opts, inputs); err != nil {
+		return err
+	}
+	pool := utils.NewWorkerPool(concurrency)
+	defer pool.Close()
+	defer opts.CoordinateConverter.Cleanup()
+
+	semaphore := make(chan struct{}, pool.Size())
+	errs := make(chan error, len(jobs))
+	var waitGroup sync.WaitGroup
+	for i, job := range jobs {
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if err := ctx.Err(); err != nil {
+			errs <- err
+			break
+		}
+		utils.LogOutput("Processing file " + strconv.Itoa(i+1) + "/" + strconv.Itoa(len(jobs)))
+
+		// each job gets its own options as the las reader changes the srid
+		jobOpts := *opts
+		jobOpts.Input = job.Input
+		jobOpts.Output = job.Output
+		jobOpts.FolderProcessing = false
+		jobOpts.WorkerPool = pool
+		jobOpts.Srid = getFileSrid(opts, opts.Srid, job.Input)
+
+		waitGroup.Add(1)
+		go func(jobOpts *tiler.TilerOptions) {
+			defer waitGroup.Done()
+			defer func() { <-semaphore }()
+			elevationCorrectionAlg := getElevationCorrectionAlgorithm(jobOpts, jobOpts.ZOffset+jobOpts.FileZOffsets[filepath.Base(jobOpts.Input)])
+			if err := processLasFile(ctx, jobOpts.Input, jobOpts, getLoaderFromLoaderStrategy(jobOpts), elevationCorrectionAlg); err != nil {
+				errs <- &JobError{Input: jobOpts.Input, Err: err}
+			}
+		}(&jobOpts)
+	}
+	waitGroup.Wait()
+	close(errs)
+
+	failed := make([]error, 0)
+	for err := range errs {
+		failed = append(failed, err)
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return &BatchError{Errors: failed}
+}
+
+func processLasFile(ctx context.Context, filePath string, opts *tiler.TilerOptions, loader point_loader.Loader, elevationCorrectionAlg converters.ElevationCorrector) error {
+	read := func(readLoader point_loader.Loader) error {
+		return readLasData(ctx, filePath, elevationCorrectionAlg, opts, readLoader)
+	}
+	if err := tilePoints(ctx, read, opts, loader, getFilenameWithoutExtension(filePath)); err != nil {
+		return err
+	}
 	utils.LogOutput("> done processing", filepath.Base(filePath))
-	opts.CoordinateConverter.Cleanup()
+	return nil
 }
 
-func readLasData(filePath string, elevationCorrectionAlg converters.ElevationCorrector, opts *tiler.TilerOptions, loader point_loader.Loader) {
-	// Reading files
-	utils.LogOutput("> reading data from las file...", filepath.Base(filePath))
-	err := readLas(filePath, elevationCorrectionAlg, opts, loader)
+// Reads all the given files concurrently into the given loader and tiles their points in a single tileset, written
+// in the output folder
+func processMergedFiles(ctx context.Context, filePaths []string, opts *tiler.TilerOptions, loader point_loader.Loader) error {
+	if len(filePaths) == 0 {
+		return nil
+	}
+	utils.LogOutput("Processing " + strconv.Itoa(len(filePaths)) + " files merged in a single tileset")
+	read := func(readLoader point_loader.Loader) error {
+		return readFilesConcurrently(ctx, filePaths, opts, readLoader)
+	}
+	if err := tilePoints(ctx, read, opts, loader, ""); err != nil {
+		return err
+	}
+	utils.LogOutput("> done processing", strconv.Itoa(len(filePaths)), "files")
+	return nil
+}
+
+// Reads the given files into the given loader, up to a file per worker of the pool at a time, each file with its own
+// reader goroutines. LAS and LAZ files are read after the other ones. Returns the first error raised, if any
+func readFilesConcurrently(ctx context.Context, filePaths []string, opts *tiler.TilerOptions, loader point_loader.Loader) error {
+	lasFiles := make([]string, 0, len(filePaths))
+	otherFiles := make([]string, 0)
+	for _, filePath := range filePaths {
+		if isLasFile(filePath) {
+			lasFiles = append(lasFiles, filePath)
+		} else {
+			otherFiles = append(otherFiles, filePath)
+		}
+	}
+
+	semaphore := make(chan struct{}, opts.WorkerPool.Size())
+	errs := make(chan error, len(otherFiles))
+	var waitGroup sync.WaitGroup
+	for _, filePath := range otherFiles {
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if err := ctx.Err(); err != nil {
+			errs <- err
+			break
+		}
+
+		// each file gets its own options as the readers change the srid
+		fileOpts := *opts
+		fileOpts.Srid = getFileSrid(opts, opts.Srid, filePath)
 
+		waitGroup.Add(1)
+		go func(filePath string, fileOpts *tiler.TilerOptions) {
+			defer waitGroup.Done()
+			defer func() { <-semaphore }()
+			elevationCorrectionAlg := getElevationCorrectionAlgorithm(fileOpts, fileOpts.ZOffset+fileOpts.FileZOffsets[filepath.Base(filePath)])
+			if err := readLasData(ctx, filePath, elevationCorrectionAlg, fileOpts, loader); err != nil {
+				errs <- err
+			}
+		}(filePath, &fileOpts)
+	}
+	waitGroup.Wait()
+	close(errs)
+	if err := <-errs; err != nil {
+		return err
+	}
+	if err := readLasFiles(ctx, lasFiles, opts, loader); err != nil {
+		return err
+	}
+
+	// the readers convert the points to EPSG:4326
+	opts.Srid = 4326
+	return nil
+}
+
+// Reads the given LAS and LAZ files concurrently into the given loader, each one with its own srid and vertical offset
+func readLasFiles(ctx context.Context, filePaths []string, opts *tiler.TilerOptions, loader point_loader.Loader) error {
+	if len(filePaths) == 0 {
+		return nil
+	}
+	utils.LogOutput("> reading data from", len(filePaths), "las files...")
+	srids := make([]int, len(filePaths))
+	zCorrections := make([]converters.ElevationCorrector, len(filePaths))
+	for i, filePath := range filePaths {
+		srids[i] = getFileSrid(opts, opts.Srid, filePath)
+		zCorrections[i] = getElevationCorrectionAlgorithm(opts, opts.ZOffset+opts.FileZOffsets[filepath.Base(filePath)])
+	}
+	lasFileLoader := lidario.NewLasFileLoader(opts.CoordinateConverter, opts.ElevationConverter, loader, opts.WorkerPool, opts.UseWktProjection)
+	lasFileLoader.ReadBufferSize = opts.ReadBufferSize
+	lasFileLoader.DropOriginPoints = opts.DropOriginPoints
+	lasFileLoader.ProgressCallback = opts.ProgressCallback
+	lasFileLoader.ScaleIntensity = opts.IntensityScaling.Scale
+	lasFileLoader.ColorClassification = opts.GetClassificationColorizer()
+	lasFileLoader.ForceClassColor = opts.ForceClassificationColor
+	lasFileLoader.ClipBounds = opts.ClipBounds
+	lasFileLoader.KeepEveryNth = opts.KeepEveryNth
+	lasFileLoader.ExtraAttributes = opts.GetExtraAttributeNames()
+	multiLasLoader, err := lidario.NewMultiLasLoader(filePaths, srids, zCorrections, lasFileLoader)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	return multiLasLoader.LoadLasFilesContext(ctx)
+}
+
+// Returns the srid of the points of the given file, either the one configured for the file name or the given one
+func getFileSrid(opts *tiler.TilerOptions, srid int, filePath string) int {
+	if fileSrid, ok := opts.FileSrids[filepath.Base(filePath)]; ok {
+		return fileSrid
+	}
+	return srid
+}
+
+// Reads points with the given function, passing it the loader to fill, and tiles them in the given subfolder of the
+// output folder. Stops as soon as the given context is done
+func tilePoints(ctx context.Context, read func(loader point_loader.Loader) error, opts *tiler.TilerOptions, loader point_loader.Loader, subfolder string) error {
+	// Eventually divert the points of each classification group to a dedicated loader
+	readLoader := loader
+	var classificationLoader *point_loader.ClassificationLoader
+	if len(opts.ClassificationGroups) > 0 {
+		classificationLoader = getClassificationLoader(opts, loader)
+		readLoader = classificationLoader
+	}
+
+	// Eventually collect the statistics of the loaded points
+	var statisticsLoader *point_loader.StatisticsLoader
+	if opts.WriteStatistics || opts.NormalizeIntensity || opts.WriteLegend {
+		statisticsLoader = point_loader.NewStatisticsLoader(readLoader)
+		readLoader = statisticsLoader
+	}
+
+	// Eventually drop the duplicate points, before they are counted in the statistics
+	var deduplicationLoader *point_loader.DeduplicationLoader
+	if opts.DeduplicateTolerance > 0 {
+		// points are always read as EPSG:4326 longitudes and latitudes
+		deduplicationLoader = point_loader.NewDeduplicationLoader(readLoader, opts.DeduplicateTolerance, true)
+		readLoader = deduplicationLoader
+	}
+
+	// Eventually drop the points of the filtered classifications, before they reach any other loader
+	var filterLoader *point_loader.ClassificationFilterLoader
+	if len(opts.IncludeClasses) > 0 || len(opts.ExcludeClasses) > 0 {
+		filterLoader = point_loader.NewClassificationFilterLoader(readLoader, opts.IncludeClasses, opts.ExcludeClasses)
+		readLoader = filterLoader
+	}
+
+	if err := read(readLoader); err != nil {
+		return err
 	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if filterLoader != nil {
+		utils.LogOutput("> filtered out", filterLoader.GetDroppedCount(), "points by classification")
+	}
+	if deduplicationLoader != nil {
+		utils.LogOutput("> removed", deduplicationLoader.GetDroppedCount(), "duplicate points")
+	}
+	if opts.NormalizeIntensity && opts.MaxIntensity == 0 {
+		// normalize by the max intensity of the loaded points
+		normalizedOpts := *opts
+		normalizedOpts.MaxIntensity = statisticsLoader.GetStatistics().Intensity.Max
+		opts = &normalizedOpts
+	}
+	if classificationLoader != nil {
+		if err := tileClassificationGroups(ctx, classificationLoader, opts, subfolder); err != nil {
+			return err
+		}
+	} else {
+		// Create empty octree
+		OctTree := octree.NewOctTree(opts)
+		if err := prepareDataStructure(OctTree, readLoader); err != nil {
+			return err
+		}
+		if err := exportToCesiumTileset(ctx, OctTree, opts, subfolder); err != nil {
+			return err
+		}
+	}
+
+	if opts.WriteStatistics {
+		statistics := statisticsLoader.GetStatistics()
+		if deduplicationLoader != nil {
+			statistics.DuplicatePoints = deduplicationLoader.GetDroppedCount()
+		}
+		if err := exportStatistics(statistics, opts, subfolder); err != nil {
+			return err
+		}
+	}
+	if opts.WriteLegend {
+		if err := exportLegend(statisticsLoader.GetStatistics(), opts, subfolder); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns a ClassificationLoader diverting the points of each classification group to a new loader and adding the
+// points of the other classifications to the given loader
+func getClassificationLoader(opts *tiler.TilerOptions, loader point_loader.Loader) *point_loader.ClassificationLoader {
+	loaders := make([]point_loader.Loader, len(opts.ClassificationGroups))
+	classifications := make([][]uint8, len(opts.ClassificationGroups))
+	for i, group := range opts.ClassificationGroups {
+		loaders[i] = getLoaderFromLoaderStrategy(opts)
+		classifications[i] = group.Classifications
+	}
+	return point_loader.NewClassificationLoader(loader, loaders, classifications)
+}
+
+// Tiles the points loaded for each classification group in a separate tileset, written in the subfolder of the given
+// subfolder named as the group. Points of the other classifications are tiled in the "other" subfolder
+func tileClassificationGroups(ctx context.Context, classificationLoader *point_loader.ClassificationLoader, opts *tiler.TilerOptions, subfolder string) error {
+	counts := classificationLoader.GetCounts()
+	groups := make([]tiler.ClassificationGroup, 0, len(opts.ClassificationGroups)+1)
+	groups = append(groups, opts.ClassificationGroups...)
+	groups = append(groups, tiler.ClassificationGroup{Name: tiler.OtherClassificationGroup, GeometricErrorMultiplier: 1})
+	for i, group := range groups {
+		if counts[i] == 0 {
+			continue
+		}
+		loader := classificationLoader.Loader
+		if i < len(opts.ClassificationGroups) {
+			loader = classificationLoader.GetLoader(i)
+		}
+
+		utils.LogOutput("> tiling classification group", group.Name)
+		groupOpts := *opts
+		groupOpts.GeometricErrorMultiplier = getGeometricErrorMultiplier(opts) * group.GeometricErrorMultiplier
+		OctTree := octree.NewOctTree(&groupOpts)
+		if err := prepareDataStructure(OctTree, loader); err != nil {
+			return err
+		}
+		if err := exportToCesiumTileset(ctx, OctTree, &groupOpts, filepath.Join(subfolder, group.Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getGeometricErrorMultiplier(opts *tiler.TilerOptions) float64 {
+	if opts.GeometricErrorMultiplier <= 0 {
+		return 1
+	}
+	return opts.GeometricErrorMultiplier
+}
+
+func readLasData(ctx context.Context, filePath string, elevationCorrectionAlg converters.ElevationCorrector, opts *tiler.TilerOptions, loader point_loader.Loader) error {
+	// Reading files
+	if isTextFile(filePath) {
+		utils.LogOutput("> reading data from text file...", filepath.Base(filePath))
+		return readText(ctx, filePath, elevationCorrectionAlg, opts, loader)
+	}
+	if isPlyFile(filePath) {
+		utils.LogOutput("> reading data from ply file...", filepath.Base(filePath))
+		return readPly(ctx, filePath, elevationCorrectionAlg, opts, loader)
+	}
+	utils.LogOutput("> reading data from las file...", filepath.Base(filePath))
+	return readLas(ctx, filePath, elevationCorrectionAlg, opts, loader)
+}
+
+func isLasFile(filePath string) bool {
+	extension := strings.ToLower(filepath.Ext(filePath))
+	return extension == ".las" || extension == ".laz"
+}
+
+func isPlyFile(filePath string) bool {
+	return strings.ToLower(filepath.Ext(filePath)) == ".ply"
 }
 
-func prepareDataStructure(octree *octree.OctTree, loader point_loader.Loader) {
+func isTextFile(filePath string) bool {
+	extension := strings.ToLower(filepath.Ext(filePath))
+	return extension == ".xyz" || extension == ".csv"
+}
+
+func prepareDataStructure(octree *octree.OctTree, loader point_loader.Loader) error {
 	// Build tree hierarchical structure
 	utils.LogOutput("> building data structure...")
-	err := octree.Build(loader)
-
-	if err != nil {
-		log.Fatal(err)
+	if err := octree.Build(loader); err != nil {
+		return err
 	}
+	utils.LogOutput("> tree depth:", octree.RootNode.GetTreeDepth())
+	return nil
 }
 
-func exportToCesiumTileset(octree *octree.OctTree, opts *tiler.TilerOptions, fileName string) {
+func exportToCesiumTileset(ctx context.Context, octree *octree.OctTree, opts *tiler.TilerOptions, fileName string) error {
 	utils.LogOutput("> exporting data...")
-	err := exportOctreeAsTileset(opts, octree, fileName)
-	if err != nil {
-		log.Fatal(err)
-	}
+	return exportOctreeAsTileset(ctx, opts, octree, fileName)
+}
+
+func exportStatistics(statistics point_loader.Statistics, opts *tiler.TilerOptions, fileName string) error {
+	utils.LogOutput("> writing statistics...")
+	return io.WriteStatisticsFile(statistics, opts, filepath.Join(opts.Output, fileName))
+}
+
+func exportLegend(statistics point_loader.Statistics, opts *tiler.TilerOptions, fileName string) error {
+	utils.LogOutput("> writing legend...")
+	return io.WriteLegendFile(statistics, opts, filepath.Join(opts.Output, fileName))
 }
 
 func getFilenameWithoutExtension(filePath string) string {
@@ -89,22 +677,36 @@ func getFilenameWithoutExtension(filePath string) string {
 	return nameWext[0 : len(nameWext)-len(extension)]
 }
 
-func getLoaderFromLoaderStrategy(strategy tiler.LoaderStrategy) point_loader.Loader {
-	var loader point_loader.Loader
-
-	loader = point_loader.NewRandomLoader()
-	if strategy == tiler.BoxedRandom {
-		loader = point_loader.NewRandomBoxLoader()
+func getLoaderFromLoaderStrategy(opts *tiler.TilerOptions) point_loader.Loader {
+	var loader point_loader.SeedableLoader
+	switch opts.Strategy {
+	case tiler.BoxedRandom:
+		loader = point_loader.NewRandomBoxLoader(opts.BoundsSigmas)
+	case tiler.GridDecimation:
+		loader = point_loader.NewGridLoader(opts.BoundsSigmas)
+	case tiler.PoissonDisk:
+		loader = point_loader.NewPoissonDiskLoader(opts.BoundsSigmas)
+	default:
+		loader = point_loader.NewRandomLoader(opts.BoundsSigmas)
 	}
+	loader.SetRandomSeed(opts.RandomSeed)
 
+	if opts.OverlapCellSize > 0 {
+		// points are always read as EPSG:4326 longitudes and latitudes
+		return point_loader.NewOverlapLoader(loader, opts.OverlapCellSize, true)
+	}
 	return loader
 }
 
-func getElevationCorrectionAlgorithm(opts *tiler.TilerOptions) converters.ElevationCorrector {
+func getElevationCorrectionAlgorithm(opts *tiler.TilerOptions, zOffset float64) converters.ElevationCorrector {
 	if !opts.EnableGeoidZCorrection {
-		return offset_elevation_corrector.NewOffsetElevationCorrector(opts.ZOffset)
+		return offset_elevation_corrector.NewOffsetElevationCorrector(zOffset)
 	} else {
-		return geoid_elevation_corrector.NewGeoidElevationCorrector(opts.ZOffset, opts.ElevationConverter)
+		var defaultCorrector = geoid_elevation_corrector.NewGeoidElevationCorrector(zOffset, opts.ElevationConverter)
+		if len(opts.GeoidHeightProviders) == 0 {
+			return defaultCorrector
+		}
+		return geoid_height_corrector.NewGeoidHeightCorrector(zOffset, opts.GeoidHeightProviders, defaultCorrector)
 	}
 }
 
@@ -128,7 +730,7 @@ func getLasFilesFromInputFolder(opts *tiler.TilerOptions) []string {
 			if info.IsDir() && !opts.Recursive && !os.SameFile(info, baseInfo) {
 				return filepath.SkipDir
 			} else {
-				if strings.ToLower(filepath.Ext(info.Name())) == ".las" {
+				if isLasFile(info.Name()) || isPlyFile(info.Name()) || isTextFile(info.Name()) {
 					lasFiles = append(lasFiles, path)
 				}
 			}
@@ -144,11 +746,20 @@ func getLasFilesFromInputFolder(opts *tiler.TilerOptions) []string {
 }
 
 // Reads the given las file and preloads data in a list of Point
-func readLas(file string, zCorrection converters.ElevationCorrector, opts *tiler.TilerOptions, loader point_loader.Loader) error {
+func readLas(ctx context.Context, file string, zCorrection converters.ElevationCorrector, opts *tiler.TilerOptions, loader point_loader.Loader) error {
 	var lf *lidario.LasFile
 	var err error
-	var lasFileLoader = lidario.NewLasFileLoader(opts.CoordinateConverter, opts.ElevationConverter, loader)
-	lf, err = lasFileLoader.LoadLasFile(file, zCorrection, opts.Srid)
+	var lasFileLoader = lidario.NewLasFileLoader(opts.CoordinateConverter, opts.ElevationConverter, loader, opts.WorkerPool, opts.UseWktProjection)
+	lasFileLoader.ReadBufferSize = opts.ReadBufferSize
+	lasFileLoader.DropOriginPoints = opts.DropOriginPoints
+	lasFileLoader.ProgressCallback = opts.ProgressCallback
+	lasFileLoader.ScaleIntensity = opts.IntensityScaling.Scale
+	lasFileLoader.ColorClassification = opts.GetClassificationColorizer()
+	lasFileLoader.ForceClassColor = opts.ForceClassificationColor
+	lasFileLoader.ClipBounds = opts.ClipBounds
+	lasFileLoader.KeepEveryNth = opts.KeepEveryNth
+	lasFileLoader.ExtraAttributes = opts.GetExtraAttributeNames()
+	lf, err = lasFileLoader.LoadLasFileContext(ctx, file, zCorrection, opts.Srid)
 	if err != nil {
 		return err
 	}
@@ -157,50 +768,131 @@ func readLas(file string, zCorrection converters.ElevationCorrector, opts *tiler
 	return nil
 }
 
+// Reads the vertices of the given ply file into the given loader
+func readPly(ctx context.Context, file string, zCorrection converters.ElevationCorrector, opts *tiler.TilerOptions, loader point_loader.Loader) error {
+	var plyFileLoader = plyread.NewPlyFileLoader(opts.CoordinateConverter, loader, opts.WorkerPool)
+	plyFileLoader.ColorClassification = opts.GetClassificationColorizer()
+	plyFileLoader.ForceClassColor = opts.ForceClassificationColor
+	plyFileLoader.ClipBounds = opts.ClipBounds
+	plyFileLoader.KeepEveryNth = opts.KeepEveryNth
+	if err := plyFileLoader.LoadPlyFile(file, zCorrection, opts.Srid); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	opts.Srid = 4326
+	return nil
+}
+
+// Reads the points of the given xyz or csv file into the given loader
+func readText(ctx context.Context, file string, zCorrection converters.ElevationCorrector, opts *tiler.TilerOptions, loader point_loader.Loader) error {
+	var textFileLoader = textread.NewTextFileLoader(opts.CoordinateConverter, loader, opts.TextColumns, opts.TextDelimiter)
+	textFileLoader.ColorClassification = opts.GetClassificationColorizer()
+	textFileLoader.ForceClassColor = opts.ForceClassificationColor
+	textFileLoader.ClipBounds = opts.ClipBounds
+	textFileLoader.KeepEveryNth = opts.KeepEveryNth
+	if err := textFileLoader.LoadTextFile(file, zCorrection, opts.Srid); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	opts.Srid = 4326
+	return nil
+}
+
 // Exports the data cloud represented by the given built octree into 3D tiles data structure according to the options
-// specified in the TilerOptions instance
-func exportOctreeAsTileset(opts *tiler.TilerOptions, octree *octree.OctTree, subfolder string) error {
+// specified in the TilerOptions instance. If the given context is done before all the tiles are written the files of
+// the written ones are removed and the context error is returned
+func exportOctreeAsTileset(ctx context.Context, opts *tiler.TilerOptions, octree *octree.OctTree, subfolder string) error {
 	// if octree is not built, exit
 	if !octree.Built {
 		return errors.New("octree not built, data structure not initialized")
 	}
 
-	// a consumer goroutine per CPU
-	numConsumers := runtime.NumCPU()
+	// fail rather than writing an empty tileset if the subtree to export is not in the tree
+	if err := io.CheckSubtreePath(&octree.RootNode, opts.SubtreePath); err != nil {
+		return err
+	}
+
+	// a consumer per CPU, or per goroutine of the shared pool, unless a number of writers is given
+	numConsumers := opts.NumWriters
+	if numConsumers <= 0 {
+		numConsumers = opts.WorkerPool.Size()
+	}
 
 	// init channel where to submit work with a buffer 5 times greater than the number of consumer
 	workChannel := make(chan *io.WorkUnit, numConsumers*5)
 
-	// init channel where consumers can eventually submit errors that prevented them to finish the job
-	errorChannel := make(chan error)
+	// eventually precompute the bounding regions so that each one contains the regions of its children
+	regions, err := getContainingRegions(opts, octree)
+	if err != nil {
+		return err
+	}
 
+	// the producer is stopped as soon as the consumers quit, whether they completed the work or not
+	produceCtx, cancelProduce := context.WithCancel(ctx)
+	defer cancelProduce()
 	var waitGroup sync.WaitGroup
 
 	// add producer to waitgroup and launch producer goroutine
 	waitGroup.Add(1)
-	go io.Produce(opts.Output, &octree.RootNode, opts, workChannel, &waitGroup, subfolder)
+	go io.Produce(produceCtx, opts.Output, &octree.RootNode, opts, workChannel, &waitGroup, subfolder, regions)
 
-	// add consumers to waitgroup and launch them
-	for i := 0; i < numConsumers; i++ {
-		waitGroup.Add(1)
-		go io.Consume(workChannel, errorChannel, &waitGroup, opts.CoordinateConverter)
+	// launch the consumers and wait for them and the producer to finish
+	err = io.RunConsumers(ctx, numConsumers, workChannel, opts.WorkerPool, opts.CoordinateConverter)
+	cancelProduce()
+	waitGroup.Wait()
+
+	// an interrupted export leaves an incomplete tileset, its tiles are removed unless kept to resume the export
+	if err := ctx.Err(); err != nil {
+		if !opts.Resume {
+			io.RemoveTileFiles(opts.Output, &octree.RootNode, opts, subfolder)
+		}
+		return err
+	}
+	if err != nil {
+		return err
 	}
 
-	// wait for producers and consumers to finish
-	waitGroup.Wait()
+	// write the single tileset.json of the implicit tiling and its subtrees if requested
+	if opts.ImplicitTiling {
+		if err := io.WriteImplicitTileset(&octree.RootNode, filepath.Join(opts.Output, subfolder), opts, opts.CoordinateConverter, regions); err != nil {
+			return err
+		}
+	}
 
-	// close error chan
-	close(errorChannel)
+	// validate the bounding regions containment if requested. The validations read the tileset.json files back from
+	// the local disk and are skipped if they are written by a custom TileWriter
+	if opts.EnforceRegionContainment && len(opts.SubtreePath) == 0 && opts.TileWriter == nil {
+		err := io.ValidateRegionContainment(filepath.Join(opts.Output, subfolder, "tileset.json"))
+		if err != nil {
+			return err
+		}
+	}
 
-	// find if there are errors in the error channel buffer
-	withErrors := false
-	for err := range errorChannel {
-		fmt.Println(err)
-		withErrors = true
+	// validate the geometric errors if requested
+	if opts.MonotonicGeometricError && len(opts.SubtreePath) == 0 && opts.TileWriter == nil {
+		geometricErrorRange, err := io.ValidateGeometricErrors(filepath.Join(opts.Output, subfolder, "tileset.json"))
+		if err != nil {
+			return err
+		}
+		utils.LogOutput("> geometric errors range from", geometricErrorRange.Min, "to", geometricErrorRange.Max, "meters")
 	}
-	if withErrors {
-		return errors.New("errors raised during execution. Check console output for details")
+
+	// write the availability of the implicit tiling subtrees if requested
+	if opts.SubtreeLevels > 0 && !opts.ImplicitTiling {
+		return io.WriteSubtreeFiles(&octree.RootNode, filepath.Join(opts.Output, subfolder), opts.SubtreeLevels, opts)
 	}
 
 	return nil
 }
+
+// Precomputes bounding regions containing the regions of their children, if requested, returns nil otherwise
+func getContainingRegions(opts *tiler.TilerOptions, tree *octree.OctTree) (map[*octree.OctNode][]float64, error) {
+	if !opts.EnforceRegionContainment {
+		return nil, nil
+	}
+	return io.ComputeContainingRegions(&tree.RootNode, opts, opts.CoordinateConverter)
+}
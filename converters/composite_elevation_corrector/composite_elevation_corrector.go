@@ -0,0 +1,31 @@
+package composite_elevation_corrector
+
+import "github.com/mfbonfigli/gocesiumtiler/converters"
+
+// ElevationCorrector applying to the points inside the given EPSG:4326 extent
+type RegionalElevationCorrector struct {
+	MinLon, MinLat, MaxLon, MaxLat float64
+	Corrector                      converters.ElevationCorrector
+}
+
+// Dispatches each point to the first regional ElevationCorrector covering it, or to the fallback one if none does
+type CompositeElevationCorrector struct {
+	regions  []RegionalElevationCorrector
+	fallback converters.ElevationCorrector
+}
+
+func NewCompositeElevationCorrector(regions []RegionalElevationCorrector, fallback converters.ElevationCorrector) converters.ElevationCorrector {
+	return &CompositeElevationCorrector{
+		regions:  regions,
+		fallback: fallback,
+	}
+}
+
+func (compositeElevationCorrector *CompositeElevationCorrector) CorrectElevation(lon, lat, z float64) float64 {
+	for _, region := range compositeElevationCorrector.regions {
+		if lon >= region.MinLon && lon <= region.MaxLon && lat >= region.MinLat && lat <= region.MaxLat {
+			return region.Corrector.CorrectElevation(lon, lat, z)
+		}
+	}
+	return compositeElevationCorrector.fallback.CorrectElevation(lon, lat, z)
+}
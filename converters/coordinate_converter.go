@@ -6,7 +6,22 @@ import (
 
 type CoordinateConverter interface {
 	ConvertCoordinateSrid(sourceSrid int, targetSrid int, coord geometry.Coordinate) (geometry.Coordinate, error)
+	ConvertCoordinateSridBatch(sourceSrid int, targetSrid int, coords []geometry.Coordinate) ([]geometry.Coordinate, error)
 	Convert2DBoundingboxToWGS84Region(bbox *geometry.BoundingBox, srid int) ([]float64, error)
 	ConvertToWGS84Cartesian(coord geometry.Coordinate, sourceSrid int) (geometry.Coordinate, error)
+	GetWktSrid(wkt string) (int, error)
 	Cleanup()
 }
+
+// Converts the given coordinates one at a time with ConvertCoordinateSrid of the given converter, for the
+// implementations without a batch conversion
+func ConvertEachCoordinateSrid(converter CoordinateConverter, sourceSrid int, targetSrid int, coords []geometry.Coordinate) ([]geometry.Coordinate, error) {
+	converted := make([]geometry.Coordinate, len(coords))
+	for i, coord := range coords {
+		var err error
+		if converted[i], err = converter.ConvertCoordinateSrid(sourceSrid, targetSrid, coord); err != nil {
+			return nil, err
+		}
+	}
+	return converted, nil
+}
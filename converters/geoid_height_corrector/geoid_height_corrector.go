@@ -0,0 +1,29 @@
+package geoid_height_corrector
+
+import "github.com/mfbonfigli/gocesiumtiler/converters"
+
+// Converts elevations above the geoid to elevations above the ellipsoid adding the geoid height given by the first
+// GeoidHeightProvider covering each point, plus a fixed offset. Points not covered by any provider, e.g. outside of
+// the geoid grids or next to their nodes without data, are corrected by the fallback ElevationCorrector
+type GeoidHeightCorrector struct {
+	offset    float64
+	providers []converters.GeoidHeightProvider
+	fallback  converters.ElevationCorrector
+}
+
+func NewGeoidHeightCorrector(offset float64, providers []converters.GeoidHeightProvider, fallback converters.ElevationCorrector) converters.ElevationCorrector {
+	return &GeoidHeightCorrector{
+		offset:    offset,
+		providers: providers,
+		fallback:  fallback,
+	}
+}
+
+func (geoidHeightCorrector *GeoidHeightCorrector) CorrectElevation(lon, lat, z float64) float64 {
+	for _, provider := range geoidHeightCorrector.providers {
+		if height, ok := provider.GetGeoidHeight(lon, lat); ok {
+			return z + height + geoidHeightCorrector.offset
+		}
+	}
+	return geoidHeightCorrector.fallback.CorrectElevation(lon, lat, z)
+}
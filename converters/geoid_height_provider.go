@@ -0,0 +1,7 @@
+package converters
+
+// Provides the height of the geoid above the ellipsoid, i.e. the geoid separation, at EPSG:4326 coordinates
+type GeoidHeightProvider interface {
+	// Returns the geoid height in meters at the given longitude and latitude, false if the provider does not cover it
+	GetGeoidHeight(lon, lat float64) (float64, bool)
+}
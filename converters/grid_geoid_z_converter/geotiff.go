@@ -0,0 +1,329 @@
+package grid_geoid_z_converter
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// TIFF tags read from the GeoTIFF geoid grids
+const (
+	tiffImageWidth      = 256
+	tiffImageLength     = 257
+	tiffBitsPerSample   = 258
+	tiffCompression     = 259
+	tiffStripOffsets    = 273
+	tiffSamplesPerPixel = 277
+	tiffRowsPerStrip    = 278
+	tiffStripByteCounts = 279
+	tiffPredictor       = 317
+	tiffTileWidth       = 322
+	tiffTileLength      = 323
+	tiffTileOffsets     = 324
+	tiffTileByteCounts  = 325
+	tiffSampleFormat    = 339
+	tiffPixelScale      = 33550
+	tiffTiepoint        = 33922
+	tiffGeoKeyDirectory = 34735
+	tiffGdalNoData      = 42113
+)
+
+// GeoKey telling if the raster values refer to the area of the pixels or to their upper left corner, and its value
+// for the latter
+const (
+	geoKeyRasterType  = 1025
+	rasterPixelIsArea = 1
+)
+
+// Entry of an image file directory of a TIFF file
+type tiffEntry struct {
+	dataType uint16
+	count    uint32
+	value    []byte // The values of the entry, in the byte order of the file
+}
+
+// GeoTIFF file being decoded
+type geoTiff struct {
+	content []byte
+	order   binary.ByteOrder
+	entries map[uint16]tiffEntry
+}
+
+// Reads the geoid undulation grid stored in the first image of the given GeoTIFF file, as distributed by PROJ for the
+// EGM96 and EGM2008 models. The image must have a single float32 or float64 sample per pixel, uncompressed or deflate
+// compressed, in strips or tiles. Nodes equal to the GDAL no data value, if any, or NaN are marked as without data
+func NewGeoTiffGeoidGrid(filePath string) (*GeoidGrid, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	tiff, err := readGeoTiff(content)
+	if err != nil {
+		return nil, errors.New("invalid GeoTIFF file " + filePath + ": " + err.Error())
+	}
+	grid, err := tiff.decodeGrid()
+	if err != nil {
+		return nil, errors.New("invalid GeoTIFF file " + filePath + ": " + err.Error())
+	}
+	return grid, nil
+}
+
+// Parses the header and the first image file directory of the given TIFF content
+func readGeoTiff(content []byte) (*geoTiff, error) {
+	if len(content) < 8 {
+		return nil, errors.New("missing TIFF header")
+	}
+	tiff := geoTiff{content: content, entries: make(map[uint16]tiffEntry)}
+	switch string(content[0:2]) {
+	case "II":
+		tiff.order = binary.LittleEndian
+	case "MM":
+		tiff.order = binary.BigEndian
+	default:
+		return nil, errors.New("missing TIFF header")
+	}
+	if version := tiff.order.Uint16(content[2:4]); version != 42 {
+		return nil, errors.New("unsupported TIFF version " + strconv.Itoa(int(version)))
+	}
+	offset := int64(tiff.order.Uint32(content[4:8]))
+	if offset+2 > int64(len(content)) {
+		return nil, errors.New("truncated image file directory")
+	}
+	count := int64(tiff.order.Uint16(content[offset : offset+2]))
+	if offset+2+count*12 > int64(len(content)) {
+		return nil, errors.New("truncated image file directory")
+	}
+	for i := int64(0); i < count; i++ {
+		entry := content[offset+2+i*12 : offset+14+i*12]
+		tag := tiff.order.Uint16(entry[0:2])
+		dataType := tiff.order.Uint16(entry[2:4])
+		valueCount := tiff.order.Uint32(entry[4:8])
+		size := int64(tiffTypeSize(dataType)) * int64(valueCount)
+		value := entry[8:12]
+		if size > 4 {
+			valueOffset := int64(tiff.order.Uint32(entry[8:12]))
+			if valueOffset+size > int64(len(content)) {
+				return nil, errors.New("truncated value of tag " + strconv.Itoa(int(tag)))
+			}
+			value = content[valueOffset : valueOffset+size]
+		}
+		tiff.entries[tag] = tiffEntry{dataType: dataType, count: valueCount, value: value}
+	}
+	return &tiff, nil
+}
+
+// Returns the size in bytes of a value of the given TIFF data type, 0 for the types not read by the tiler
+func tiffTypeSize(dataType uint16) int {
+	switch dataType {
+	case 1, 2, 6, 7: // byte, ascii, signed byte, undefined
+		return 1
+	case 3, 8: // short, signed short
+		return 2
+	case 4, 9, 11: // long, signed long, float
+		return 4
+	case 5, 10, 12: // rational, signed rational, double
+		return 8
+	}
+	return 0
+}
+
+// Returns the values of the given tag as float64, nil if the tag is missing
+func (tiff *geoTiff) getValues(tag uint16) []float64 {
+	entry, ok := tiff.entries[tag]
+	if !ok {
+		return nil
+	}
+	size := tiffTypeSize(entry.dataType)
+	values := make([]float64, 0, entry.count)
+	for i := 0; i < int(entry.count) && size > 0; i++ {
+		value := entry.value[i*size : (i+1)*size]
+		switch entry.dataType {
+		case 1, 7:
+			values = append(values, float64(value[0]))
+		case 6:
+			values = append(values, float64(int8(value[0])))
+		case 3:
+			values = append(values, float64(tiff.order.Uint16(value)))
+		case 8:
+			values = append(values, float64(int16(tiff.order.Uint16(value))))
+		case 4:
+			values = append(values, float64(tiff.order.Uint32(value)))
+		case 9:
+			values = append(values, float64(int32(tiff.order.Uint32(value))))
+		case 11:
+			values = append(values, float64(math.Float32frombits(tiff.order.Uint32(value))))
+		case 12:
+			values = append(values, math.Float64frombits(tiff.order.Uint64(value)))
+		case 5:
+			values = append(values, float64(tiff.order.Uint32(value[0:4]))/float64(tiff.order.Uint32(value[4:8])))
+		case 10:
+			values = append(values, float64(int32(tiff.order.Uint32(value[0:4])))/float64(int32(tiff.order.Uint32(value[4:8]))))
+		}
+	}
+	return values
+}
+
+// Returns the first value of the given tag, or the given default value if the tag is missing
+func (tiff *geoTiff) getValue(tag uint16, defaultValue int) int {
+	if values := tiff.getValues(tag); len(values) > 0 {
+		return int(values[0])
+	}
+	return defaultValue
+}
+
+// Decodes the pixels of the image into a grid whose nodes are the pixel centers, or the pixel corners if the
+// raster type GeoKey says so
+func (tiff *geoTiff) decodeGrid() (*GeoidGrid, error) {
+	width := tiff.getValue(tiffImageWidth, 0)
+	height := tiff.getValue(tiffImageLength, 0)
+	if width < 2 || height < 2 {
+		return nil, errors.New("the grid must have at least 2 rows and 2 columns")
+	}
+	if tiff.getValue(tiffSamplesPerPixel, 1) != 1 {
+		return nil, errors.New("only grids with a sample per pixel are supported")
+	}
+	bitsPerSample := tiff.getValue(tiffBitsPerSample, 1)
+	if tiff.getValue(tiffSampleFormat, 1) != 3 || (bitsPerSample != 32 && bitsPerSample != 64) {
+		return nil, errors.New("only float32 and float64 grids are supported")
+	}
+	compression := tiff.getValue(tiffCompression, 1)
+	if compression != 1 && compression != 8 && compression != 32946 {
+		return nil, errors.New("unsupported compression " + strconv.Itoa(compression))
+	}
+	predictor := tiff.getValue(tiffPredictor, 1)
+	if predictor != 1 && predictor != 3 {
+		return nil, errors.New("unsupported predictor " + strconv.Itoa(predictor))
+	}
+
+	// geo referencing, longitude and latitude of the upper left corner of the upper left pixel and pixel size
+	scale := tiff.getValues(tiffPixelScale)
+	tiepoint := tiff.getValues(tiffTiepoint)
+	if len(scale) < 2 || len(tiepoint) < 6 || scale[0] <= 0 || scale[1] <= 0 {
+		return nil, errors.New("missing pixel scale or tiepoint")
+	}
+	nodeOffset := 0.0
+	if tiff.getRasterType() == rasterPixelIsArea {
+		nodeOffset = 0.5
+	}
+	grid := GeoidGrid{
+		MinLon:  tiepoint[3] + (nodeOffset-tiepoint[0])*scale[0],
+		MinLat:  tiepoint[4] - (float64(height-1)+nodeOffset-tiepoint[1])*scale[1],
+		LatStep: scale[1],
+		LonStep: scale[0],
+		Rows:    height,
+		Cols:    width,
+		Values:  make([]float32, width*height),
+	}
+	if grid.MinLon >= 180 {
+		grid.MinLon -= 360
+	}
+	noData := math.NaN()
+	if entry, ok := tiff.entries[tiffGdalNoData]; ok {
+		if value, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimRight(string(entry.value), "\x00")), 64); err == nil {
+			noData = value
+		}
+	}
+
+	// images are split in strips of full rows or in tiles, decoded in the same way
+	blockWidth, blockHeight := width, tiff.getValue(tiffRowsPerStrip, height)
+	offsets, byteCounts := tiff.getValues(tiffStripOffsets), tiff.getValues(tiffStripByteCounts)
+	if _, ok := tiff.entries[tiffTileOffsets]; ok {
+		blockWidth, blockHeight = tiff.getValue(tiffTileWidth, 0), tiff.getValue(tiffTileLength, 0)
+		offsets, byteCounts = tiff.getValues(tiffTileOffsets), tiff.getValues(tiffTileByteCounts)
+	}
+	if blockWidth <= 0 || blockHeight <= 0 {
+		return nil, errors.New("invalid strip or tile size")
+	}
+	blocksAcross := (width + blockWidth - 1) / blockWidth
+	blocksDown := (height + blockHeight - 1) / blockHeight
+	if len(offsets) < blocksAcross*blocksDown || len(byteCounts) < len(offsets) {
+		return nil, errors.New("missing strip or tile offsets")
+	}
+	sampleSize := bitsPerSample / 8
+	for block := 0; block < blocksAcross*blocksDown; block++ {
+		start, end := int64(offsets[block]), int64(offsets[block])+int64(byteCounts[block])
+		if start < 0 || end > int64(len(tiff.content)) {
+			return nil, errors.New("truncated strip or tile")
+		}
+		data := tiff.content[start:end]
+		if compression != 1 {
+			reader, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			if data, err = io.ReadAll(reader); err != nil {
+				return nil, err
+			}
+		}
+		blockRow, blockCol := block/blocksAcross, block%blocksAcross
+		rowSize := blockWidth * sampleSize
+		for r := 0; r < blockHeight && (r+1)*rowSize <= len(data); r++ {
+			imageRow := blockRow*blockHeight + r
+			if imageRow >= height {
+				break
+			}
+			row := data[r*rowSize : (r+1)*rowSize]
+			if predictor == 3 {
+				row = undoFloatingPointPredictor(row, blockWidth, sampleSize)
+			}
+			for c := 0; c < blockWidth && blockCol*blockWidth+c < width; c++ {
+				value := tiff.decodeSample(row[c*sampleSize:(c+1)*sampleSize], predictor == 3)
+				if math.IsNaN(value) || value == noData {
+					value = gtxNoData
+				}
+				// grid rows start from the south, image rows from the north
+				grid.Values[(height-1-imageRow)*width+blockCol*blockWidth+c] = float32(value)
+			}
+		}
+	}
+	return &grid, nil
+}
+
+// Returns the value of the raster type GeoKey, pixel is area if missing
+func (tiff *geoTiff) getRasterType() int {
+	keys := tiff.getValues(tiffGeoKeyDirectory)
+	for i := 4; i+3 < len(keys); i += 4 {
+		// key id, location of the value, count, value if stored in the directory itself
+		if int(keys[i]) == geoKeyRasterType && keys[i+1] == 0 {
+			return int(keys[i+3])
+		}
+	}
+	return rasterPixelIsArea
+}
+
+// Decodes a float sample, stored in big endian order if the floating point predictor was applied and in the order of
+// the file otherwise
+func (tiff *geoTiff) decodeSample(sample []byte, bigEndian bool) float64 {
+	order := tiff.order
+	if bigEndian {
+		order = binary.BigEndian
+	}
+	if len(sample) == 4 {
+		return float64(math.Float32frombits(order.Uint32(sample)))
+	}
+	return math.Float64frombits(order.Uint64(sample))
+}
+
+// Reverts the floating point predictor of a row of the given number of samples, which stores the byte differences
+// of the samples split in planes, from the most significant bytes to the least significant ones. Returns the samples
+// in big endian order
+func undoFloatingPointPredictor(row []byte, samples int, sampleSize int) []byte {
+	planes := make([]byte, len(row))
+	copy(planes, row)
+	for i := 1; i < len(planes); i++ {
+		planes[i] += planes[i-1]
+	}
+	decoded := make([]byte, len(row))
+	for s := 0; s < samples; s++ {
+		for b := 0; b < sampleSize; b++ {
+			decoded[s*sampleSize+b] = planes[b*samples+s]
+		}
+	}
+	return decoded
+}
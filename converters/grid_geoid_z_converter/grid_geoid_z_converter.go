@@ -0,0 +1,154 @@
+package grid_geoid_z_converter
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Value used by GTX grids to mark the nodes without a known undulation
+const gtxNoData = -88.8888
+
+// Geoid undulation grid with regular spacing in EPSG:4326 degrees, as stored in GTX files
+type GeoidGrid struct {
+	MinLat, MinLon   float64 // Coordinates of the south west node of the grid
+	LatStep, LonStep float64 // Spacing of the grid nodes
+	Rows, Cols       int
+	Values           []float32 // Undulations in meters, row by row starting from the south west node
+}
+
+// Reads the geoid undulation grid stored in the given GTX file. GTX files store a big endian header with the latitude
+// and longitude of the south west node, the latitude and longitude spacing and the number of rows and columns,
+// followed by the float32 undulations row by row starting from the south west node
+func NewGtxGeoidGrid(filePath string) (*GeoidGrid, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if len(content) < 40 {
+		return nil, errors.New("invalid GTX file " + filePath)
+	}
+	readFloat64 := func(offset int) float64 {
+		return math.Float64frombits(binary.BigEndian.Uint64(content[offset : offset+8]))
+	}
+	grid := GeoidGrid{
+		MinLat:  readFloat64(0),
+		MinLon:  readFloat64(8),
+		LatStep: readFloat64(16),
+		LonStep: readFloat64(24),
+		Rows:    int(int32(binary.BigEndian.Uint32(content[32:36]))),
+		Cols:    int(int32(binary.BigEndian.Uint32(content[36:40]))),
+	}
+	if grid.Rows < 2 || grid.Cols < 2 || grid.LatStep <= 0 || grid.LonStep <= 0 || len(content) != 40+grid.Rows*grid.Cols*4 {
+		return nil, errors.New("invalid GTX file " + filePath)
+	}
+	if grid.MinLon >= 180 {
+		grid.MinLon -= 360
+	}
+	grid.Values = make([]float32, grid.Rows*grid.Cols)
+	for i := range grid.Values {
+		grid.Values[i] = math.Float32frombits(binary.BigEndian.Uint32(content[40+i*4 : 44+i*4]))
+	}
+	return &grid, nil
+}
+
+// Returns the minimum longitude, minimum latitude, maximum longitude and maximum latitude covered by the grid
+func (grid *GeoidGrid) GetExtent() (float64, float64, float64, float64) {
+	return grid.MinLon, grid.MinLat, grid.MinLon + float64(grid.Cols-1)*grid.LonStep, grid.MinLat + float64(grid.Rows-1)*grid.LatStep
+}
+
+// Checks if the given EPSG:4326 coordinate falls inside the grid. Longitudes are compared modulo 360 degrees, so that
+// grids spanning 0 to 360 degrees cover negative longitudes too
+func (grid *GeoidGrid) Contains(lon, lat float64) bool {
+	minLon, minLat, maxLon, maxLat := grid.GetExtent()
+	lon = grid.wrapLongitude(lon)
+	return lon >= minLon && lon <= maxLon && lat >= minLat && lat <= maxLat
+}
+
+// Returns the undulation at the given EPSG:4326 coordinate bilinearly interpolated from the four surrounding grid nodes
+func (grid *GeoidGrid) GetEllipsoidToGeoidZOffset(lat, lon float64, sourceSrid int) (float64, error) {
+	if sourceSrid != 4326 {
+		return 0, errors.New("geoid grids require EPSG:4326 coordinates")
+	}
+	if !grid.Contains(lon, lat) {
+		return 0, errors.New("coordinate outside of the geoid grid coverage")
+	}
+	return grid.interpolate(grid.wrapLongitude(lon), lat)
+}
+
+// Returns the undulation at the given EPSG:4326 coordinate as GetEllipsoidToGeoidZOffset does, false if the coordinate
+// is outside of the grid or next to nodes without data
+func (grid *GeoidGrid) GetGeoidHeight(lon, lat float64) (float64, bool) {
+	height, err := grid.GetEllipsoidToGeoidZOffset(lat, lon, 4326)
+	return height, err == nil
+}
+
+// Returns the longitude shifted by multiples of 360 degrees to the nearest value not below the west edge of the grid
+func (grid *GeoidGrid) wrapLongitude(lon float64) float64 {
+	for lon < grid.MinLon && lon+360 <= grid.MinLon+float64(grid.Cols-1)*grid.LonStep {
+		lon += 360
+	}
+	return lon
+}
+
+func (grid *GeoidGrid) interpolate(lon, lat float64) (float64, error) {
+	row := math.Min((lat-grid.MinLat)/grid.LatStep, float64(grid.Rows-1))
+	col := math.Min((lon-grid.MinLon)/grid.LonStep, float64(grid.Cols-1))
+	row0 := int(math.Min(math.Floor(row), float64(grid.Rows-2)))
+	col0 := int(math.Min(math.Floor(col), float64(grid.Cols-2)))
+	dRow, dCol := row-float64(row0), col-float64(col0)
+
+	value := 0.0
+	for _, node := range [][3]float64{
+		{float64(row0), float64(col0), (1 - dRow) * (1 - dCol)},
+		{float64(row0), float64(col0 + 1), (1 - dRow) * dCol},
+		{float64(row0 + 1), float64(col0), dRow * (1 - dCol)},
+		{float64(row0 + 1), float64(col0 + 1), dRow * dCol},
+	} {
+		nodeValue := float64(grid.Values[int(node[0])*grid.Cols+int(node[1])])
+		if node[2] > 0 && math.Abs(nodeValue-gtxNoData) < 1e-3 {
+			return 0, errors.New("geoid grid has no data at the given coordinate")
+		}
+		value += node[2] * nodeValue
+	}
+	return value, nil
+}
+
+// Reads the geoid undulation grids stored in the GTX files of the given comma separated list, in the given order
+func NewGtxGeoidGrids(filePaths string) ([]*GeoidGrid, error) {
+	return readGeoidGrids(filePaths, NewGtxGeoidGrid)
+}
+
+// Reads the geoid undulation grids stored in the files of the given comma separated list, in the given order. Files
+// with the .tif or .tiff extension are read as GeoTIFF grids, the others as GTX grids
+func NewGeoidGrids(filePaths string) ([]*GeoidGrid, error) {
+	return readGeoidGrids(filePaths, NewGeoidGrid)
+}
+
+// Reads the geoid undulation grid stored in the given GeoTIFF or GTX file, depending on its extension
+func NewGeoidGrid(filePath string) (*GeoidGrid, error) {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".tif", ".tiff":
+		return NewGeoTiffGeoidGrid(filePath)
+	default:
+		return NewGtxGeoidGrid(filePath)
+	}
+}
+
+func readGeoidGrids(filePaths string, readGrid func(string) (*GeoidGrid, error)) ([]*GeoidGrid, error) {
+	grids := make([]*GeoidGrid, 0)
+	for _, filePath := range strings.Split(filePaths, ",") {
+		if strings.TrimSpace(filePath) == "" {
+			continue
+		}
+		grid, err := readGrid(strings.TrimSpace(filePath))
+		if err != nil {
+			return nil, err
+		}
+		grids = append(grids, grid)
+	}
+	return grids, nil
+}
@@ -12,26 +12,37 @@ import (
 	"path"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 const toRadians = math.Pi / 180
 const toDeg = 180 / math.Pi
 
+// Projections registered from WKT definitions get codes starting from this value, above all EPSG codes
+const firstWktSrid = 1000000
+
 type proj4CoordinateConverter struct {
-	EpsgDatabase map[int]*epsgProjection
+	sync.RWMutex
+	EpsgDatabase    map[int]*epsgProjection
+	transformations map[[2]int]*transformationPool // Transformations by source and target srid
 }
 
 func NewProj4CoordinateConverter() converters.CoordinateConverter {
-	exPath := utils.GetExecutablePath()
+	return NewProj4CoordinateConverterFromFolder(path.Join(utils.GetExecutablePath(), "static"))
+}
 
+// Instances a converter reading the EPSG Proj4 database and the projection static data from the given folder, rather
+// than from the static folder next to the executable
+func NewProj4CoordinateConverterFromFolder(staticFolder string) converters.CoordinateConverter {
 	// Set path for retrieving projection static data
-	proj.SetFinder([]string{path.Join(exPath, "static", "share")})
+	proj.SetFinder([]string{path.Join(staticFolder, "share")})
 
 	// Initialization of EPSG Proj4 database
-	file := path.Join(exPath, "static", "epsg_projections.txt")
+	file := path.Join(staticFolder, "epsg_projections.txt")
 
 	return &proj4CoordinateConverter{
-		EpsgDatabase: *loadEPSGProjectionDatabase(file),
+		EpsgDatabase:    *loadEPSGProjectionDatabase(file),
+		transformations: make(map[[2]int]*transformationPool),
 	}
 }
 
@@ -78,19 +89,29 @@ func (proj4CoordinateConverter *proj4CoordinateConverter) ConvertCoordinateSrid(
 		return coord, nil
 	}
 
-	src, err := proj4CoordinateConverter.initProjection(sourceSrid)
-	if err != nil {
+	converted, err := proj4CoordinateConverter.ConvertCoordinateSridBatch(sourceSrid, targetSrid, []geometry.Coordinate{coord})
+	if converted == nil {
 		return coord, err
 	}
+	return converted[0], err
+}
 
-	dst, err := proj4CoordinateConverter.initProjection(targetSrid)
-	if err != nil {
-		return coord, err
+// Converts the given coordinates from the given source srid to the given target srid with a single proj call
+func (proj4CoordinateConverter *proj4CoordinateConverter) ConvertCoordinateSridBatch(sourceSrid int, targetSrid int, coords []geometry.Coordinate) ([]geometry.Coordinate, error) {
+	if sourceSrid == targetSrid {
+		return coords, nil
 	}
 
-	var converted, result = executeConversion(&coord, src, dst)
-
-	return *converted, result
+	pool, err := proj4CoordinateConverter.getTransformationPool(sourceSrid, targetSrid)
+	if err != nil {
+		return nil, err
+	}
+	transformation, err := pool.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer pool.release(transformation)
+	return transformation.convertBatch(coords)
 }
 
 // Converts the generic bounding box bounds values from the given input srid to a EPSG:4326 srid (in radians)
@@ -129,84 +150,72 @@ func (proj4CoordinateConverter *proj4CoordinateConverter) ConvertToWGS84Cartesia
 	return res2, err
 }
 
-// Releases all projection objects from memory
-func (proj4CoordinateConverter *proj4CoordinateConverter) Cleanup() {
-	for _, val := range proj4CoordinateConverter.EpsgDatabase {
-		if val.Projection != nil {
-			val.Projection.Close()
+// Returns the srid of the coordinate system described by the given OGC or ESRI WKT. If the WKT declares a known EPSG
+// code that code is returned, otherwise the WKT is translated to proj4 and registered under a new code
+func (proj4CoordinateConverter *proj4CoordinateConverter) GetWktSrid(wkt string) (int, error) {
+	if code, ok := GetWktEpsgCode(wkt); ok {
+		proj4CoordinateConverter.RLock()
+		_, found := proj4CoordinateConverter.EpsgDatabase[code]
+		proj4CoordinateConverter.RUnlock()
+		if found {
+			return code, nil
 		}
 	}
-}
-
-func executeConversion(coord *geometry.Coordinate, sourceProj *proj.Proj, destinationProj *proj.Proj) (*geometry.Coordinate, error) {
-	var x, y, z = getCoordinateArraysForConversion(coord, sourceProj)
-
-	var err = proj.TransformRaw(sourceProj, destinationProj, x, y, z)
-
-	var converted = geometry.Coordinate{
-		X: getCoordinateFromRadiansToSridFormat(x[0], destinationProj),
-		Y: getCoordinateFromRadiansToSridFormat(y[0], destinationProj),
-		Z: extractZPointerIfPresent(z),
+	proj4, err := WktToProj4(wkt)
+	if err != nil {
+		return 0, err
 	}
 
-	return &converted, err
-}
-
-// From a input Coordinate object and associated Proj object, return a set of arrays to be used for coordinate coversion
-func getCoordinateArraysForConversion(coord *geometry.Coordinate, srid *proj.Proj) ([]float64, []float64, []float64) {
-	var x, y, z []float64
-
-	x = []float64{*getCoordinateInRadiansFromSridFormat(*coord.X, srid)}
-	y = []float64{*getCoordinateInRadiansFromSridFormat(*coord.Y, srid)}
-
-	if coord.Z != nil {
-		z = []float64{*coord.Z}
+	proj4CoordinateConverter.Lock()
+	defer proj4CoordinateConverter.Unlock()
+	code := firstWktSrid
+	for ; proj4CoordinateConverter.EpsgDatabase[code] != nil; code++ {
+		if proj4CoordinateConverter.EpsgDatabase[code].Proj4 == proj4 {
+			return code, nil
+		}
 	}
-
-	return x, y, z
-}
-
-// Returns the input coordinate expressed in the given srid converting it into radians if necessary
-func getCoordinateInRadiansFromSridFormat(coord float64, srid *proj.Proj) *float64 {
-	var radians = coord
-
-	if srid.IsLatLong() {
-		radians = coord * toRadians
+	proj4CoordinateConverter.EpsgDatabase[code] = &epsgProjection{
+		EpsgCode:    code,
+		Description: "Projection from WKT",
+		Proj4:       proj4,
 	}
-
-	return &radians
+	return code, nil
 }
 
-func extractZPointerIfPresent(zContainer []float64) *float64 {
-	if zContainer != nil {
-		return &zContainer[0]
+// Releases all projection objects from memory. Transformations are created again as needed
+func (proj4CoordinateConverter *proj4CoordinateConverter) Cleanup() {
+	proj4CoordinateConverter.Lock()
+	defer proj4CoordinateConverter.Unlock()
+	for _, pool := range proj4CoordinateConverter.transformations {
+		pool.close()
 	}
-
-	return nil
+	proj4CoordinateConverter.transformations = make(map[[2]int]*transformationPool)
 }
 
-// Returns the input coordinate expressed in the given srid converting it into radians if necessary
-func getCoordinateFromRadiansToSridFormat(coord float64, srid *proj.Proj) *float64 {
-	var angle = coord
-
-	if srid.IsLatLong() {
-		angle = coord * toDeg
+// Returns the pool of the transformations from the given source srid to the given target srid, creating it if needed
+func (proj4CoordinateConverter *proj4CoordinateConverter) getTransformationPool(sourceSrid int, targetSrid int) (*transformationPool, error) {
+	key := [2]int{sourceSrid, targetSrid}
+	proj4CoordinateConverter.RLock()
+	pool, ok := proj4CoordinateConverter.transformations[key]
+	proj4CoordinateConverter.RUnlock()
+	if ok {
+		return pool, nil
 	}
 
-	return &angle
-}
-
-// Returns the projection corresponding to the given EPSG code, storing it in the relevant EpsgDatabase entry for caching
-func (proj4CoordinateConverter *proj4CoordinateConverter) initProjection(code int) (*proj.Proj, error) {
-	val, ok := proj4CoordinateConverter.EpsgDatabase[code]
+	proj4CoordinateConverter.Lock()
+	defer proj4CoordinateConverter.Unlock()
+	if pool, ok := proj4CoordinateConverter.transformations[key]; ok {
+		return pool, nil
+	}
+	source, ok := proj4CoordinateConverter.EpsgDatabase[sourceSrid]
 	if !ok {
-		return &proj.Proj{}, errors.New("epsg code not found")
-	} else if val.Projection == nil {
-		projection, err := proj.InitPlus(val.Proj4)
-		if err != nil {
-			return &proj.Proj{}, errors.New("unable to init projection")
-		}
-		val.Projection = projection
+		return nil, errors.New("epsg code not found")
+	}
+	target, ok := proj4CoordinateConverter.EpsgDatabase[targetSrid]
+	if !ok {
+		return nil, errors.New("epsg code not found")
 	}
-	return val.Projection, nil
+	pool = &transformationPool{sourceProj4: source.Proj4, targetProj4: target.Proj4}
+	proj4CoordinateConverter.transformations[key] = pool
+	return pool, nil
 }
@@ -0,0 +1,132 @@
+package proj4_coordinate_converter
+
+import (
+	"errors"
+	"github.com/mfbonfigli/gocesiumtiler/structs/geometry"
+	"github.com/xeonx/proj4"
+	"math"
+	"strconv"
+	"sync"
+)
+
+// Transformation between the projections of two srids, with its own buffers. Projections are not safe for concurrent
+// use, so a transformation is used by one goroutine at a time
+type sridTransformation struct {
+	source, target               *proj.Proj
+	sourceLatLong, targetLatLong bool
+	xs, ys, zs                   []float64
+}
+
+// Transformations between the same pair of srids, created as needed so that each concurrent conversion gets its own,
+// and reused across conversions
+type transformationPool struct {
+	sync.Mutex
+	sourceProj4, targetProj4 string
+	idle                     []*sridTransformation
+	all                      []*sridTransformation
+}
+
+// Returns an idle transformation, creating a new one if all are in use. The transformation must be released once done
+func (pool *transformationPool) acquire() (*sridTransformation, error) {
+	pool.Lock()
+	if n := len(pool.idle); n > 0 {
+		transformation := pool.idle[n-1]
+		pool.idle = pool.idle[:n-1]
+		pool.Unlock()
+		return transformation, nil
+	}
+	pool.Unlock()
+
+	source, err := proj.InitPlus(pool.sourceProj4)
+	if err != nil {
+		return nil, errors.New("unable to init projection")
+	}
+	target, err := proj.InitPlus(pool.targetProj4)
+	if err != nil {
+		source.Close()
+		return nil, errors.New("unable to init projection")
+	}
+	transformation := &sridTransformation{
+		source:        source,
+		target:        target,
+		sourceLatLong: source.IsLatLong(),
+		targetLatLong: target.IsLatLong(),
+	}
+	pool.Lock()
+	pool.all = append(pool.all, transformation)
+	pool.Unlock()
+	return transformation, nil
+}
+
+// Makes the given transformation available to the next conversions
+func (pool *transformationPool) release(transformation *sridTransformation) {
+	pool.Lock()
+	pool.idle = append(pool.idle, transformation)
+	pool.Unlock()
+}
+
+// Releases the projections of all the transformations of the pool
+func (pool *transformationPool) close() {
+	pool.Lock()
+	defer pool.Unlock()
+	for _, transformation := range pool.all {
+		transformation.source.Close()
+		transformation.target.Close()
+	}
+	pool.all = nil
+	pool.idle = nil
+}
+
+// Converts the given coordinates, in degrees if the source projection is geographic, to the target projection with a
+// single proj call. Coordinates without Z are converted at zero height and returned without Z. Proj marks the
+// coordinates it cannot convert, e.g. the ones out of the domain of the source projection, with infinite or NaN
+// values, these are reported as an error
+func (transformation *sridTransformation) convertBatch(coords []geometry.Coordinate) ([]geometry.Coordinate, error) {
+	n := len(coords)
+	if cap(transformation.xs) < n {
+		transformation.xs = make([]float64, n)
+		transformation.ys = make([]float64, n)
+		transformation.zs = make([]float64, n)
+	}
+	xs, ys, zs := transformation.xs[:n], transformation.ys[:n], transformation.zs[:n]
+	hasZ := false
+	for i, coord := range coords {
+		xs[i], ys[i], zs[i] = *coord.X, *coord.Y, 0
+		if transformation.sourceLatLong {
+			xs[i] *= toRadians
+			ys[i] *= toRadians
+		}
+		if coord.Z != nil {
+			zs[i] = *coord.Z
+			hasZ = true
+		}
+	}
+	if !hasZ {
+		zs = nil
+	}
+
+	err := proj.TransformRaw(transformation.source, transformation.target, xs, ys, zs)
+	for i := 0; i < n && err == nil; i++ {
+		if math.IsInf(xs[i], 0) || math.IsInf(ys[i], 0) || math.IsNaN(xs[i]) || math.IsNaN(ys[i]) {
+			err = errors.New("cannot reproject the coordinate " + strconv.FormatFloat(*coords[i].X, 'f', -1, 64) + ", " + strconv.FormatFloat(*coords[i].Y, 'f', -1, 64))
+		}
+	}
+
+	// a single backing array for the values of all the converted coordinates
+	values := make([]float64, 3*n)
+	converted := make([]geometry.Coordinate, n)
+	for i, coord := range coords {
+		x, y, z := &values[3*i], &values[3*i+1], &values[3*i+2]
+		*x, *y = xs[i], ys[i]
+		if transformation.targetLatLong {
+			*x *= toDeg
+			*y *= toDeg
+		}
+		converted[i] = geometry.Coordinate{X: x, Y: y}
+		if coord.Z != nil {
+			*z = zs[i]
+			converted[i].Z = z
+		}
+	}
+	return converted, err
+}
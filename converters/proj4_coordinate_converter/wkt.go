@@ -0,0 +1,331 @@
+package proj4_coordinate_converter
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Node of a parsed WKT coordinate system definition, e.g. PARAMETER["False_Easting",500000.0]
+type wktNode struct {
+	Keyword  string
+	Values   []string
+	Children []*wktNode
+}
+
+// Proj4 names of the projections, indexed by their OGC or ESRI WKT name normalized by normalizeWktName
+var wktProjections = map[string]string{
+	"transversemercator":        "tmerc",
+	"gaussskruger":              "tmerc",
+	"lambertconformalconic":     "lcc",
+	"lambertconformalconic1sp":  "lcc",
+	"lambertconformalconic2sp":  "lcc",
+	"mercator":                  "merc",
+	"mercator1sp":               "merc",
+	"mercator2sp":               "merc",
+	"albers":                    "aea",
+	"albersconicequalarea":      "aea",
+	"obliquestereographic":      "sterea",
+	"doublestereographic":       "sterea",
+	"lambertazimuthalequalarea": "laea",
+	"equidistantcylindrical":    "eqc",
+	"platecarree":               "eqc",
+	"cassini":                   "cass",
+	"cassinisoldner":            "cass",
+	"stereographic":             "stere",
+	"azimuthalequidistant":      "aeqd",
+}
+
+// Proj4 parameter names, indexed by their OGC or ESRI WKT name normalized by normalizeWktName
+var wktParameters = map[string]string{
+	"falseeasting":               "x_0",
+	"falsenorthing":              "y_0",
+	"centralmeridian":            "lon_0",
+	"longitudeofcenter":          "lon_0",
+	"longitudeoforigin":          "lon_0",
+	"longitudeofnaturalorigin":   "lon_0",
+	"latitudeoforigin":           "lat_0",
+	"latitudeofcenter":           "lat_0",
+	"latitudeofnaturalorigin":    "lat_0",
+	"scalefactor":                "k",
+	"scalefactoratnaturalorigin": "k",
+	"standardparallel1":          "lat_1",
+	"standardparallel2":          "lat_2",
+}
+
+// Proj4 datum names, indexed by their OGC or ESRI WKT name normalized by normalizeWktName
+var wktDatums = map[string]string{
+	"wgs1984":                 "WGS84",
+	"worldgeodeticsystem1984": "WGS84",
+	"northamerican1983":       "NAD83",
+	"northamericandatum1983":  "NAD83",
+	"northamerican1927":       "NAD27",
+	"northamericandatum1927":  "NAD27",
+}
+
+// Returns the EPSG code declared by the AUTHORITY node of the root of the given WKT, if any
+func GetWktEpsgCode(wkt string) (int, bool) {
+	root, err := parseWkt(wkt)
+	if err != nil {
+		return 0, false
+	}
+	authority := root.child("AUTHORITY")
+	if authority == nil || len(authority.Values) != 2 || !strings.EqualFold(authority.Values[0], "EPSG") {
+		return 0, false
+	}
+	code, err := strconv.Atoi(authority.Values[1])
+	if err != nil {
+		return 0, false
+	}
+	return code, true
+}
+
+// Translates the given OGC or ESRI flavored WKT coordinate system definition into the equivalent proj4 string.
+// ESRI WKT, as written by ArcGIS, differs from OGC WKT in the names of projections, parameters and datums (e.g.
+// D_WGS_1984) and lacks AUTHORITY nodes, so it is translated from the projection parameters rather than looked up.
+func WktToProj4(wkt string) (string, error) {
+	root, err := parseWkt(wkt)
+	if err != nil {
+		return "", err
+	}
+	switch root.Keyword {
+	case "GEOGCS":
+		geographic, err := getWktGeographicParameters(root)
+		if err != nil {
+			return "", err
+		}
+		return "+proj=longlat " + geographic + " +no_defs", nil
+	case "PROJCS":
+		return getWktProjectedProj4(root)
+	}
+	return "", errors.New("unsupported WKT coordinate system " + root.Keyword)
+}
+
+// Translates a PROJCS WKT node into a proj4 string
+func getWktProjectedProj4(root *wktNode) (string, error) {
+	projection := root.child("PROJECTION")
+	if projection == nil || len(projection.Values) == 0 {
+		return "", errors.New("WKT projected coordinate system without projection")
+	}
+	projName, ok := wktProjections[normalizeWktName(projection.Values[0])]
+	if !ok {
+		return "", errors.New("unsupported WKT projection " + projection.Values[0])
+	}
+	geogcs := root.child("GEOGCS")
+	if geogcs == nil {
+		return "", errors.New("WKT projected coordinate system without geographic coordinate system")
+	}
+	geographic, err := getWktGeographicParameters(geogcs)
+	if err != nil {
+		return "", err
+	}
+
+	// false easting and northing are expressed in the linear unit, proj4 always wants them in meters
+	toMeter := 1.0
+	if unit := root.child("UNIT"); unit != nil && len(unit.Values) == 2 {
+		if toMeter, err = strconv.ParseFloat(unit.Values[1], 64); err != nil {
+			return "", errors.New("invalid WKT unit " + unit.Values[1])
+		}
+	}
+
+	proj4 := "+proj=" + projName
+	for _, parameter := range root.children("PARAMETER") {
+		if len(parameter.Values) != 2 {
+			return "", errors.New("invalid WKT parameter")
+		}
+		name, ok := wktParameters[normalizeWktName(parameter.Values[0])]
+		if !ok {
+			continue
+		}
+		value, err := strconv.ParseFloat(parameter.Values[1], 64)
+		if err != nil {
+			return "", errors.New("invalid value of WKT parameter " + parameter.Values[0])
+		}
+		if name == "x_0" || name == "y_0" {
+			// rounded to the micrometer to drop the noise of the unit conversion
+			value = math.Round(value*toMeter*1e6) / 1e6
+		}
+		if name == "lat_1" && projName == "merc" {
+			// the standard parallel of the Mercator projection is its latitude of true scale
+			name = "lat_ts"
+		}
+		proj4 += " +" + name + "=" + formatWktValue(value)
+	}
+	proj4 += " " + geographic
+	if toMeter == 1 {
+		proj4 += " +units=m"
+	} else {
+		proj4 += " +to_meter=" + formatWktValue(toMeter)
+	}
+	return proj4 + " +no_defs", nil
+}
+
+// Translates the datum, ellipsoid and prime meridian of a GEOGCS WKT node into proj4 parameters
+func getWktGeographicParameters(geogcs *wktNode) (string, error) {
+	datum := geogcs.child("DATUM")
+	if datum == nil || len(datum.Values) == 0 {
+		return "", errors.New("WKT geographic coordinate system without datum")
+	}
+	parameters := ""
+	if towgs84 := datum.child("TOWGS84"); towgs84 != nil {
+		parameters = " +towgs84=" + strings.Join(towgs84.Values, ",")
+	}
+	if name, ok := wktDatums[normalizeWktName(strings.TrimPrefix(datum.Values[0], "D_"))]; ok && parameters == "" {
+		parameters = "+datum=" + name
+	} else {
+		spheroid := datum.child("SPHEROID")
+		if spheroid == nil || len(spheroid.Values) < 3 {
+			return "", errors.New("WKT datum without spheroid")
+		}
+		a, errA := strconv.ParseFloat(spheroid.Values[1], 64)
+		rf, errRf := strconv.ParseFloat(spheroid.Values[2], 64)
+		if errA != nil || errRf != nil {
+			return "", errors.New("invalid WKT spheroid " + spheroid.Values[0])
+		}
+		ellipsoid := "+a=" + formatWktValue(a)
+		if rf == 0 {
+			ellipsoid += " +b=" + formatWktValue(a)
+		} else {
+			ellipsoid += " +rf=" + formatWktValue(rf)
+		}
+		parameters = ellipsoid + parameters
+	}
+	if primem := geogcs.child("PRIMEM"); primem != nil && len(primem.Values) == 2 {
+		if pm, err := strconv.ParseFloat(primem.Values[1], 64); err == nil && pm != 0 {
+			parameters += " +pm=" + formatWktValue(pm)
+		}
+	}
+	return parameters, nil
+}
+
+// Lowercases the given WKT name removing all characters other than letters and digits
+func normalizeWktName(name string) string {
+	var builder strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
+
+func formatWktValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// Returns the first child node with the given keyword
+func (node *wktNode) child(keyword string) *wktNode {
+	for _, child := range node.Children {
+		if child.Keyword == keyword {
+			return child
+		}
+	}
+	return nil
+}
+
+// Returns all the child nodes with the given keyword
+func (node *wktNode) children(keyword string) []*wktNode {
+	children := make([]*wktNode, 0)
+	for _, child := range node.Children {
+		if child.Keyword == keyword {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+// Parses the given WKT string into a tree of nodes. Both square brackets and parentheses are accepted as delimiters
+func parseWkt(wkt string) (*wktNode, error) {
+	parser := wktParser{input: strings.TrimSpace(strings.Trim(wkt, "\x00"))}
+	node, err := parser.parseNode()
+	if err != nil {
+		return nil, err
+	}
+	parser.skipSpaces()
+	if parser.position != len(parser.input) {
+		return nil, errors.New("unexpected content after the end of the WKT")
+	}
+	return node, nil
+}
+
+type wktParser struct {
+	input    string
+	position int
+}
+
+func (parser *wktParser) skipSpaces() {
+	for parser.position < len(parser.input) && strings.ContainsRune(" \t\r\n", rune(parser.input[parser.position])) {
+		parser.position++
+	}
+}
+
+// Parses a KEYWORD[value,...] node
+func (parser *wktParser) parseNode() (*wktNode, error) {
+	parser.skipSpaces()
+	start := parser.position
+	for parser.position < len(parser.input) && isWktKeywordChar(parser.input[parser.position]) {
+		parser.position++
+	}
+	node := &wktNode{Keyword: strings.ToUpper(parser.input[start:parser.position])}
+	parser.skipSpaces()
+	if node.Keyword == "" || parser.position >= len(parser.input) || !strings.ContainsRune("[(", rune(parser.input[parser.position])) {
+		return nil, errors.New("invalid WKT node at position " + strconv.Itoa(start))
+	}
+	parser.position++
+	for {
+		parser.skipSpaces()
+		if parser.position >= len(parser.input) {
+			return nil, errors.New("unterminated WKT node " + node.Keyword)
+		}
+		switch c := parser.input[parser.position]; {
+		case c == '"':
+			end := strings.IndexByte(parser.input[parser.position+1:], '"')
+			if end < 0 {
+				return nil, errors.New("unterminated WKT string")
+			}
+			node.Values = append(node.Values, parser.input[parser.position+1:parser.position+1+end])
+			parser.position += end + 2
+		case isWktKeywordChar(c) && !(c >= '0' && c <= '9') && c != '-' && c != '+' && c != '.':
+			lookahead := parser.position
+			for lookahead < len(parser.input) && isWktKeywordChar(parser.input[lookahead]) {
+				lookahead++
+			}
+			rest := strings.TrimLeft(parser.input[lookahead:], " \t\r\n")
+			if rest != "" && strings.ContainsRune("[(", rune(rest[0])) {
+				child, err := parser.parseNode()
+				if err != nil {
+					return nil, err
+				}
+				node.Children = append(node.Children, child)
+			} else {
+				// enumeration values such as the axis directions
+				node.Values = append(node.Values, parser.input[parser.position:lookahead])
+				parser.position = lookahead
+			}
+		default:
+			start := parser.position
+			for parser.position < len(parser.input) && !strings.ContainsRune(",])", rune(parser.input[parser.position])) {
+				parser.position++
+			}
+			node.Values = append(node.Values, strings.TrimSpace(parser.input[start:parser.position]))
+		}
+		parser.skipSpaces()
+		if parser.position >= len(parser.input) {
+			return nil, errors.New("unterminated WKT node " + node.Keyword)
+		}
+		switch parser.input[parser.position] {
+		case ',':
+			parser.position++
+		case ']', ')':
+			parser.position++
+			return node, nil
+		default:
+			return nil, errors.New("unexpected character in WKT at position " + strconv.Itoa(parser.position))
+		}
+	}
+}
+
+func isWktKeywordChar(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' || c == '-' || c == '+' || c == '.'
+}
@@ -0,0 +1,70 @@
+package io
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"os"
+	"path/filepath"
+)
+
+// Writes the given data to the given tile file, atomically if AtomicWrites is set. Temporary files are written in
+// TempDir or, if empty, next to the tile file
+func writeTileFile(filePath string, data []byte, perm os.FileMode, opts *tiler.TilerOptions) error {
+	if !opts.AtomicWrites {
+		return os.WriteFile(filePath, data, perm)
+	}
+	tempDir := opts.TempDir
+	if tempDir == "" {
+		tempDir = filepath.Dir(filePath)
+	}
+	return WriteFileAtomic(filePath, data, perm, tempDir)
+}
+
+// Writes the given data to a temporary file in the given folder, the system temp folder if empty, then moves it to
+// the given path so that readers never see a partially written file. If the folder is on a different volume than the
+// file the move fails, and the data is written again to a temporary file next to the given one. Temporary files are
+// removed on error
+func WriteFileAtomic(filePath string, data []byte, perm os.FileMode, tempDir string) error {
+	return WriteFileAtomicFunc(filePath, func(file *os.File) error {
+		_, err := file.Write(data)
+		return err
+	}, perm, tempDir)
+}
+
+// Same as WriteFileAtomic, but the content is written by the given function to the temporary file. The function may
+// be called again if the temporary folder is on a different volume than the file
+func WriteFileAtomicFunc(filePath string, write func(file *os.File) error, perm os.FileMode, tempDir string) error {
+	err := writeAndRename(filePath, write, perm, tempDir)
+	if _, isLinkError := err.(*os.LinkError); isLinkError {
+		return writeAndRename(filePath, write, perm, filepath.Dir(filePath))
+	}
+	return err
+}
+
+// Writes a temporary file in the given folder with the given function, flushes it to disk and moves it to the given
+// path, replacing any existing file
+func writeAndRename(filePath string, write func(file *os.File) error, perm os.FileMode, folder string) (err error) {
+	file, err := os.CreateTemp(folder, "gocesiumtiler-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = file.Close()
+			_ = os.Remove(file.Name())
+		}
+	}()
+	if err = write(file); err != nil {
+		return err
+	}
+	if err = file.Chmod(perm); err != nil {
+		return err
+	}
+	// the content must be on disk before the rename, or a crash could leave an empty file in place
+	if err = file.Sync(); err != nil {
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	return replaceFile(file.Name(), filePath)
+}
@@ -0,0 +1,48 @@
+package io
+
+// Binary body of a feature table or batch table, made of per point arrays stored one after the other
+type binaryBody struct {
+	bytes      []byte
+	properties []binaryBodyProperty
+}
+
+// A per point array stored in a binary body
+type binaryBodyProperty struct {
+	semantic      string
+	byteOffset    int
+	componentType string // batch table component type, e.g. UNSIGNED_BYTE, empty for feature table semantics
+	propertyType  string // batch table type, e.g. SCALAR, empty for feature table semantics
+}
+
+// Size in bytes of the batch table component types
+var componentTypeSizes = map[string]int{
+	"BYTE":           1,
+	"UNSIGNED_BYTE":  1,
+	"SHORT":          2,
+	"UNSIGNED_SHORT": 2,
+	"INT":            4,
+	"UNSIGNED_INT":   4,
+	"FLOAT":          4,
+	"DOUBLE":         8,
+}
+
+// Appends the given array of a feature table semantic, padding the body so that the array starts at a multiple of
+// the given component size as required by the 3D Tiles specification
+func (body *binaryBody) appendSemantic(semantic string, componentSize int, array []byte) {
+	body.appendProperty(binaryBodyProperty{semantic: semantic}, componentSize, array)
+}
+
+// Appends the given array of a batch table property with the given component type and type
+func (body *binaryBody) appendBatchProperty(name string, componentType string, propertyType string, array []byte) {
+	property := binaryBodyProperty{semantic: name, componentType: componentType, propertyType: propertyType}
+	body.appendProperty(property, componentTypeSizes[componentType], array)
+}
+
+func (body *binaryBody) appendProperty(property binaryBodyProperty, componentSize int, array []byte) {
+	if componentSize > 1 {
+		body.bytes = padBytes(body.bytes, componentSize, 0)
+	}
+	property.byteOffset = len(body.bytes)
+	body.properties = append(body.properties, property)
+	body.bytes = append(body.bytes, array...)
+}
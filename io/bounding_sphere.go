@@ -0,0 +1,71 @@
+package io
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/converters"
+	"github.com/mfbonfigli/gocesiumtiler/structs/geometry"
+	"github.com/mfbonfigli/gocesiumtiler/structs/octree"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"math"
+)
+
+// Returns the bounding volume of the given node, either a bounding sphere, an oriented box or a bounding region,
+// depending on the bounding volume mode set in the options. Spheres and boxes are expressed in the output coordinates
+// of the points, as regions cannot be expressed in raw coordinates boxes replace them for RawCoordinates
+func getBoundingVolume(node *octree.OctNode, opts *tiler.TilerOptions, converter converters.CoordinateConverter, regions map[*octree.OctNode][]float64) (BoundingVolume, error) {
+	outputConverter, err := getOutputConverter(node, opts, converter)
+	if err != nil {
+		return BoundingVolume{}, err
+	}
+	if opts.BoundingVolumes == tiler.BoundingSpheres || (opts.BoundingVolumes == tiler.RootBoundingSphere && node.Parent == nil) {
+		sphere, err := getBoundingSphere(getNodeBoundingBox(node, opts), opts.Srid, outputConverter)
+		return BoundingVolume{Sphere: sphere}, err
+	}
+	if opts.BoundingVolumes == tiler.OrientedBoundingBoxes || opts.OutputMode == tiler.RawCoordinates {
+		box, err := getOrientedBoundingBox(getNodeBoundingBox(node, opts), opts.Srid, outputConverter)
+		return BoundingVolume{Box: box}, err
+	}
+	region, err := getRegion(node, opts, converter, regions)
+	return BoundingVolume{Region: region}, err
+}
+
+// Returns the bounding box of the points of the given node and of its descendants if TightBounds is set and the tree
+// computed it, the bounding box of its octree cell otherwise
+func getNodeBoundingBox(node *octree.OctNode, opts *tiler.TilerOptions) *geometry.BoundingBox {
+	if opts.TightBounds && node.TightBoundingBox != nil {
+		return node.TightBoundingBox
+	}
+	return node.BoundingBox
+}
+
+// Computes the bounding sphere, in EPSG:4978 ECEF coordinates, of the given bounding box expressed in the given srid.
+// The sphere is centered on the center of the box and its radius is the max distance from the center of the corners
+// of the box. Edge midpoints and face centers are measured as well, as the faces of boxes in geographic coordinates
+// are curved once converted to ECEF. Returns the sphere as center X, Y, Z and radius.
+func getBoundingSphere(bbox *geometry.BoundingBox, srid int, converter converters.CoordinateConverter) ([]float64, error) {
+	center, err := convertToEcef(bbox.Xmid, bbox.Ymid, bbox.Zmid, srid, converter)
+	if err != nil {
+		return nil, err
+	}
+	radius := 0.0
+	for _, x := range []float64{bbox.Xmin, bbox.Xmid, bbox.Xmax} {
+		for _, y := range []float64{bbox.Ymin, bbox.Ymid, bbox.Ymax} {
+			for _, z := range []float64{bbox.Zmin, bbox.Zmid, bbox.Zmax} {
+				point, err := convertToEcef(x, y, z, srid, converter)
+				if err != nil {
+					return nil, err
+				}
+				radius = math.Max(radius, math.Sqrt(math.Pow(point[0]-center[0], 2)+math.Pow(point[1]-center[1], 2)+math.Pow(point[2]-center[2], 2)))
+			}
+		}
+	}
+	return []float64{center[0], center[1], center[2], radius}, nil
+}
+
+// Converts the given coordinate, expressed in the given srid, to EPSG:4978 ECEF coordinates
+func convertToEcef(x, y, z float64, srid int, converter converters.CoordinateConverter) ([3]float64, error) {
+	coord, err := converter.ConvertToWGS84Cartesian(geometry.Coordinate{X: &x, Y: &y, Z: &z}, srid)
+	if err != nil {
+		return [3]float64{}, err
+	}
+	return [3]float64{*coord.X, *coord.Y, *coord.Z}, nil
+}
@@ -0,0 +1,201 @@
+package io
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Compares two tileset output folders for semantic equality rather than byte equality. The folders are considered
+// equivalent if they contain the same files, if every tileset.json describes the same tile hierarchy and if every
+// content.pnts stores the same number of points at the same positions, within the given tolerance in meters.
+// Point order inside each content.pnts is ignored, even for points closer than the tolerance. Returns nil if the folders are equivalent, or an error describing
+// the first difference found otherwise.
+func CompareTilesetFolders(expectedFolder, actualFolder string, tolerance float64) error {
+	expectedFiles, err := listFilesRelativeToFolder(expectedFolder)
+	if err != nil {
+		return err
+	}
+	actualFiles, err := listFilesRelativeToFolder(actualFolder)
+	if err != nil {
+		return err
+	}
+	if len(expectedFiles) != len(actualFiles) {
+		return fmt.Errorf("expected %d files, found %d", len(expectedFiles), len(actualFiles))
+	}
+
+	for i, file := range expectedFiles {
+		if actualFiles[i] != file {
+			return fmt.Errorf("expected file %s, found %s", file, actualFiles[i])
+		}
+		expectedPath := filepath.Join(expectedFolder, file)
+		actualPath := filepath.Join(actualFolder, file)
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".json":
+			err = compareTilesetFiles(expectedPath, actualPath, tolerance)
+		case ".pnts":
+			err = comparePntsFiles(expectedPath, actualPath, tolerance)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+	}
+	return nil
+}
+
+// Returns the sorted list of paths, relative to the given folder, of all files stored in it and in its subfolders
+func listFilesRelativeToFolder(folder string) ([]string, error) {
+	files := make([]string, 0)
+	err := filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			relativePath, err := filepath.Rel(folder, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(relativePath))
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// Tile of a tileset.json together with all its nested children, if any
+type comparedTile struct {
+	Content        Content        `json:"content"`
+	BoundingVolume BoundingVolume `json:"boundingVolume"`
+	GeometricError float64        `json:"geometricError"`
+	Refine         string         `json:"refine"`
+	Transform      []float64      `json:"transform,omitempty"`
+	Children       []comparedTile `json:"children"`
+}
+
+// Checks that the two tileset.json files describe the same tile hierarchy, with the same contents, refinements,
+// number of children, bounding volumes, geometric errors and transforms, the values within the given tolerance in
+// meters
+func compareTilesetFiles(expectedPath, actualPath string, tolerance float64) error {
+	var expected, actual struct {
+		GeometricError float64      `json:"geometricError"`
+		Root           comparedTile `json:"root"`
+	}
+	for path, tileset := range map[string]interface{}{expectedPath: &expected, actualPath: &actual} {
+		content, _, err := readTileFile(path)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(content, tileset); err != nil {
+			return err
+		}
+	}
+	if math.Abs(expected.GeometricError-actual.GeometricError) > tolerance {
+		return fmt.Errorf("expected geometric error %f, found %f", expected.GeometricError, actual.GeometricError)
+	}
+	return compareTiles(&expected.Root, &actual.Root, "root", tolerance)
+}
+
+// Checks that the two tiles, identified in the errors by the given name, and their children recursively are the same
+// within the given tolerance in meters
+func compareTiles(expected, actual *comparedTile, name string, tolerance float64) error {
+	if expected.Content.Url != actual.Content.Url {
+		return fmt.Errorf("expected %s content %s, found %s", name, expected.Content.Url, actual.Content.Url)
+	}
+	if expected.Refine != actual.Refine {
+		return fmt.Errorf("expected %s refine %s, found %s", name, expected.Refine, actual.Refine)
+	}
+	if math.Abs(expected.GeometricError-actual.GeometricError) > tolerance {
+		return fmt.Errorf("expected %s geometric error %f, found %f", name, expected.GeometricError, actual.GeometricError)
+	}
+	// the first four values of the regions are longitudes and latitudes in radians
+	regionTolerance := []float64{tolerance / wgs84SemiMajorAxis, tolerance / wgs84SemiMajorAxis, tolerance / wgs84SemiMajorAxis, tolerance / wgs84SemiMajorAxis, tolerance, tolerance}
+	volumes := []struct {
+		kind               string
+		expected, actual   []float64
+		componentTolerance []float64
+	}{
+		{"region", expected.BoundingVolume.Region, actual.BoundingVolume.Region, regionTolerance},
+		{"sphere", expected.BoundingVolume.Sphere, actual.BoundingVolume.Sphere, nil},
+		{"box", expected.BoundingVolume.Box, actual.BoundingVolume.Box, nil},
+		{"transform", expected.Transform, actual.Transform, nil},
+	}
+	for _, volume := range volumes {
+		if len(volume.expected) != len(volume.actual) {
+			return fmt.Errorf("expected %s %s %v, found %v", name, volume.kind, volume.expected, volume.actual)
+		}
+		for i := range volume.expected {
+			componentTolerance := tolerance
+			if i < len(volume.componentTolerance) {
+				componentTolerance = volume.componentTolerance[i]
+			}
+			if math.Abs(volume.expected[i]-volume.actual[i]) > componentTolerance {
+				return fmt.Errorf("expected %s %s %v, found %v", name, volume.kind, volume.expected, volume.actual)
+			}
+		}
+	}
+	if len(expected.Children) != len(actual.Children) {
+		return fmt.Errorf("expected %d children of %s, found %d", len(expected.Children), name, len(actual.Children))
+	}
+	for i := range expected.Children {
+		if err := compareTiles(&expected.Children[i], &actual.Children[i], fmt.Sprintf("%s child %d", name, i), tolerance); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Checks that the two content.pnts files store the same points, regardless of their order
+func comparePntsFiles(expectedPath, actualPath string, tolerance float64) error {
+	expected, err := ReadPntsFile(expectedPath)
+	if err != nil {
+		return err
+	}
+	actual, err := ReadPntsFile(actualPath)
+	if err != nil {
+		return err
+	}
+	if expected.FeatureTable.PointsLength != actual.FeatureTable.PointsLength {
+		return fmt.Errorf("expected %d points, found %d", expected.FeatureTable.PointsLength, actual.FeatureTable.PointsLength)
+	}
+	return matchPositions(groupPositions(expected.Positions), groupPositions(actual.Positions), tolerance)
+}
+
+// Groups the given X, Y, Z triplets
+func groupPositions(positions []float64) [][3]float64 {
+	points := make([][3]float64, len(positions)/3)
+	for i := range points {
+		points[i] = [3]float64{positions[i*3], positions[i*3+1], positions[i*3+2]}
+	}
+	return points
+}
+
+// Matches every expected point to the nearest actual point not matched yet whose coordinates all differ by at most
+// the given tolerance. Points closer than the tolerance can then be found in any order, which sorting them by their
+// exact coordinates would not allow. Returns an error for the first expected point without a match
+func matchPositions(expected, actual [][3]float64, tolerance float64) error {
+	sort.Slice(actual, func(i, j int) bool { return actual[i][0] < actual[j][0] })
+	matched := make([]bool, len(actual))
+	for _, point := range expected {
+		best, bestDistance := -1, math.Inf(1)
+		i := sort.Search(len(actual), func(i int) bool { return actual[i][0] >= point[0]-tolerance })
+		for ; i < len(actual) && actual[i][0] <= point[0]+tolerance; i++ {
+			if matched[i] {
+				continue
+			}
+			distance := math.Max(math.Abs(actual[i][0]-point[0]), math.Max(math.Abs(actual[i][1]-point[1]), math.Abs(actual[i][2]-point[2])))
+			if distance <= tolerance && distance < bestDistance {
+				best, bestDistance = i, distance
+			}
+		}
+		if best < 0 {
+			return fmt.Errorf("expected point %v, found none within %g", point, tolerance)
+		}
+		matched[best] = true
+	}
+	return nil
+}
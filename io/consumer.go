@@ -1,10 +1,10 @@
 package io
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"math"
 	"github.com/mfbonfigli/gocesiumtiler/converters"
 	"github.com/mfbonfigli/gocesiumtiler/structs/data"
@@ -12,82 +12,430 @@ import (
 	"github.com/mfbonfigli/gocesiumtiler/structs/octree"
 	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
 	"github.com/mfbonfigli/gocesiumtiler/utils"
-	"os"
 	"path"
-	"strconv"
-	"strings"
 	"sync"
 )
 
+// Default number of neighbours used to estimate the normal of each point
+const defaultNormalNeighbours = 16
+
+// Maximum number of times the points of a tile are subsampled to fit its content.pnts into the configured byte size
+const maxTileSubsamplingIterations = 10
+
+// Serializes the calls to the tile written callbacks made by the consumers
+var tileWrittenMutex sync.Mutex
+
 // Continually consumes WorkUnits submitted to a work channel producing corresponding content.pnts files and tileset.json files
-// continues working until work channel is closed or if an error is raised. In this last case submits the error to an error
-// channel before quitting
-func Consume(workchan chan *WorkUnit, errchan chan error, wg *sync.WaitGroup, converter converters.CoordinateConverter) {
+// continues working until work channel is closed, the given context is done or an error is raised by any consumer
+// sharing the given ConsumerErrors. Errors raised by this consumer are added to the ConsumerErrors before quitting
+func Consume(ctx context.Context, workchan chan *WorkUnit, errs *ConsumerErrors, wg *sync.WaitGroup, converter converters.CoordinateConverter) {
 	for {
 		// get work from channel
-		work, ok := <-workchan
-		if !ok {
-			// channel was closed by producer, quit infinite loop
+		var work *WorkUnit
+		var ok bool
+		select {
+		case work, ok = <-workchan:
+		case <-ctx.Done():
+		case <-errs.Done():
+		}
+		if !ok || ctx.Err() != nil || errs.failed() {
+			// channel was closed by producer, the context is done or a consumer failed, quit infinite loop
 			break
 		}
 
 		// do work
 		err := doWork(work, converter)
 
-		// if there were errors during work send in error channel and quit
+		// if there were errors during work add them to the consumer errors and quit
 		if err != nil {
-			errchan <- err
+			errs.Add(&TileError{BasePath: work.BasePath, Err: err})
 			fmt.Println("exception in consumer worker")
 			break
 		}
+		work.Progress.Add(1)
 	}
 
 	// signal waitgroup finished work
 	wg.Done()
 }
 
+// Runs the given number of Consume workers on the given pool, or each in its own goroutine if the pool is nil, until
+// the work channel is closed or the given context is done. As soon as a worker raises an error all the other workers
+// are stopped too, once done with their current WorkUnit. Returns a TilingError listing every tile that failed, if
+// any. The producer of the work channel must stop submitting work once this function returns.
+func RunConsumers(ctx context.Context, numWorkers int, workchan chan *WorkUnit, pool *utils.WorkerPool, converter converters.CoordinateConverter) error {
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+	errs := NewConsumerErrors()
+	var waitGroup sync.WaitGroup
+	waitGroup.Add(numWorkers)
+	consumers := make([]func(), numWorkers)
+	for i := range consumers {
+		consumers[i] = func() { Consume(ctx, workchan, errs, &waitGroup, converter) }
+	}
+	pool.Run(consumers...)
+	waitGroup.Wait()
+	return errs.Err()
+}
+
 // Takes a workunit and writes the corresponding content.pnts and tileset.json files
 func doWork(workUnit *WorkUnit, coordinateConverter converters.CoordinateConverter) error {
 	// writes the content.pnts file
-	err := writeBinaryPntsFile(*workUnit, coordinateConverter)
+	tile, err := writeBinaryPntsFile(*workUnit, coordinateConverter)
 	if err != nil {
 		return err
 	}
-	if !workUnit.OctNode.IsLeaf || workUnit.OctNode.Parent == nil {
-		// if the node has children also writes the tileset.json file
+	if !workUnit.Opts.ImplicitTiling && (!workUnit.OctNode.IsLeaf || workUnit.OctNode.Parent == nil) {
+		// if the node has children also writes the tileset.json file, implicit tilesets have a single one
 		err := writeTilesetJsonFile(*workUnit, coordinateConverter)
 		if err != nil {
 			return err
 		}
 	}
+	if callback := workUnit.Opts.OnTileWritten; callback != nil {
+		tile.Region, err = getRegion(workUnit.OctNode, workUnit.Opts, coordinateConverter, workUnit.Regions)
+		if err != nil {
+			return err
+		}
+		tileWrittenMutex.Lock()
+		callback(*tile)
+		tileWrittenMutex.Unlock()
+	}
+	if workUnit.Opts.FreeExportedItems {
+		// release the points held in memory by the nodes no longer needed
+		workUnit.OctNode.MarkExported()
+	}
 	return nil
 }
 
-// Writes a content.pnts binary files from the given WorkUnit
-func writeBinaryPntsFile(workUnit WorkUnit, coordinateConverter converters.CoordinateConverter) error {
+// Writes a content.pnts binary files from the given WorkUnit and returns the path, size, number of points, depth and
+// geometric error of the written tile
+func writeBinaryPntsFile(workUnit WorkUnit, coordinateConverter converters.CoordinateConverter) (*tiler.TileInfo, error) {
 	parentFolder := workUnit.BasePath
 	node := workUnit.OctNode
+	writer := getTileWriter(workUnit.Opts)
 
 	// Create base folder if it does not exist
-	if _, err := os.Stat(parentFolder); os.IsNotExist(err) {
-		err := os.MkdirAll(parentFolder, 0777)
-		if err != nil {
-			return err
+	if err := writer.MkdirAll(parentFolder); err != nil {
+		return nil, err
+	}
+
+	// Constructing pnts or glb output file path
+	pntsFilePath := path.Join(parentFolder, tileContentFile(workUnit.Opts))
+
+	// Skipping the tiles completely written by a previous run
+	if size, pointNo, ok := getResumedTile(pntsFilePath, workUnit.Opts); ok {
+		return &tiler.TileInfo{
+			Path:           pntsFilePath,
+			ByteSize:       size,
+			PointCount:     pointNo,
+			Depth:          int(node.Depth),
+			GeometricError: getGeometricError(node, workUnit.Opts),
+		}, nil
+	}
+
+	outputByte, pointNo, err := encodeContentWithinMaxBytes(node, workUnit.Opts, coordinateConverter)
+	if err != nil {
+		return nil, err
+	}
+	droppedPointNo := len(node.Items) - pointNo
+	if droppedPointNo > 0 {
+		utils.LogOutput("> dropped", droppedPointNo, "of", len(node.Items), "points of", pntsFilePath, "to fit the max tile bytes")
+	}
+	outputByte, err = compressTileData(outputByte, workUnit.Opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Write binary content to file
+	err = writer.WriteFile(pntsFilePath, outputByte)
+
+	if err != nil {
+		return nil, err
+	}
+	if err := writeResumeSidecar(writer, pntsFilePath, outputByte, pointNo, workUnit.Opts); err != nil {
+		return nil, err
+	}
+	return &tiler.TileInfo{
+		Path:           pntsFilePath,
+		ByteSize:       len(outputByte),
+		PointCount:     pointNo,
+		DroppedPoints:  droppedPointNo,
+		Depth:          int(node.Depth),
+		GeometricError: getGeometricError(node, workUnit.Opts),
+	}, nil
+}
+
+// Encodes the content.pnts or content.glb of the given node. If a maximum tile byte size is configured and the content
+// exceeds it, the points are subsampled and encoded again until it fits. The subsample takes the points at a regular
+// stride, so that it spans the whole tile whatever the order of the points, and is sized assuming the byte size is
+// proportional to the number of points. Also returns the number of encoded points
+func encodeContentWithinMaxBytes(node *octree.OctNode, opts *tiler.TilerOptions, coordinateConverter converters.CoordinateConverter) ([]byte, int, error) {
+	items := node.Items
+	content, err := encodeContent(node, items, opts, coordinateConverter)
+	if err != nil || opts.MaxTileBytes <= 0 {
+		return content, len(items), err
+	}
+	for iteration := 0; len(content) > opts.MaxTileBytes && len(items) > 1 && iteration < maxTileSubsamplingIterations; iteration++ {
+		pointNo := int(float64(len(items)) * float64(opts.MaxTileBytes) / float64(len(content)) * 0.95)
+		if pointNo >= len(items) {
+			pointNo = len(items) - 1
 		}
+		if pointNo < 1 {
+			pointNo = 1
+		}
+		items = subsamplePoints(node.Items, pointNo)
+		if content, err = encodeContent(node, items, opts, coordinateConverter); err != nil {
+			return nil, 0, err
+		}
+	}
+	if len(content) > opts.MaxTileBytes {
+		return nil, 0, fmt.Errorf("unable to fit the content of tile at depth %d in %d bytes", node.Depth, opts.MaxTileBytes)
+	}
+	return content, len(items), nil
+}
+
+// Returns the given number of points taken from the given ones at a regular stride
+func subsamplePoints(items []*data.Point, pointNo int) []*data.Point {
+	subsample := make([]*data.Point, pointNo)
+	for i := range subsample {
+		subsample[i] = items[i*len(items)/pointNo]
 	}
+	return subsample
+}
+
+// Returns the number of neighbours used to estimate the normal of each point, defaulting to 16
+func getNormalNeighbours(opts *tiler.TilerOptions) int {
+	if opts.NormalNeighbors > 0 {
+		return opts.NormalNeighbors
+	}
+	return defaultNormalNeighbours
+}
 
-	// Constructing pnts output file path
-	pntsFilePath := path.Join(parentFolder, "content.pnts")
+// Encodes the given points of the given node in the output format set in the options
+func encodeContent(node *octree.OctNode, items []*data.Point, opts *tiler.TilerOptions, coordinateConverter converters.CoordinateConverter) ([]byte, error) {
+	if opts.OutputFormat == tiler.GlbOutput {
+		return encodeGlbPoints(node, items, opts, coordinateConverter)
+	}
+	return EncodePnts(node, items, opts, coordinateConverter)
+}
 
-	pointNo := len(node.Items)
-	coords := make([]float64, pointNo*3)
-	colors := make([]uint8, pointNo*3)
+// Encodes the given points of the given node as the binary content of a content.pnts file. The header lengths cover
+// the feature table, the batch table and their binary bodies. Without points the content is still valid, with
+// POINTS_LENGTH 0 and the RTC_CENTER at the origin
+func EncodePnts(node *octree.OctNode, items []*data.Point, opts *tiler.TilerOptions, coordinateConverter converters.CoordinateConverter) ([]byte, error) {
+	pointNo := len(items)
+
+	// If an alpha is configured for any classification colors are written as RGBA, otherwise as RGB or RGB565
+	colorSemantic := "RGB"
+	colorComponents := 3
+	if len(opts.ClassificationAlpha) > 0 {
+		colorSemantic = "RGBA"
+		colorComponents = 4
+	}
+	colors := make([]uint8, pointNo*colorComponents)
+	var colors565 []uint16
+	if opts.Rgb565Colors && colorSemantic == "RGB" {
+		colorSemantic = "RGB565"
+		colors565 = make([]uint16, pointNo)
+	}
+	var colors16 []uint16
+	if opts.ColorDepth == 16 {
+		colors16 = make([]uint16, pointNo*3)
+	}
 	intensities := make([]uint8, pointNo)
 	classifications := make([]uint8, pointNo)
 
-	// Decomposing tile data properties in separate sublists for coords, colors, intensities and classifications
-	for i := 0; i < len(node.Items); i++ {
-		element := node.Items[i]
+	// Return numbers and GPS times are written only if known for some point, e.g. GPS times not for LAS point
+	// formats 0 and 2
+	var returnNumbers, numbersOfReturns []uint8
+	var gpsTimes []float64
+	for _, element := range items {
+		if returnNumbers == nil && (element.ReturnNumber > 0 || element.NumberOfReturns > 0) {
+			returnNumbers = make([]uint8, pointNo)
+			numbersOfReturns = make([]uint8, pointNo)
+		}
+		if gpsTimes == nil && element.HasGpsTime() {
+			gpsTimes = make([]float64, pointNo)
+		}
+	}
+
+	// Decomposing tile data properties in separate sublists for colors, intensities and classifications
+	ramp := getElevationRamp(node, opts)
+	for i := 0; i < len(items); i++ {
+		element := items[i]
+		R, G, B := getColor(element, ramp)
+		colors[i*colorComponents] = uint8(R >> 8)
+		colors[i*colorComponents+1] = uint8(G >> 8)
+		colors[i*colorComponents+2] = uint8(B >> 8)
+		if colors565 != nil {
+			colors565[i] = packRgb565(R, G, B)
+		}
+		if colors16 != nil {
+			colors16[i*3] = R
+			colors16[i*3+1] = G
+			colors16[i*3+2] = B
+		}
+		if colorComponents == 4 {
+			colors[i*colorComponents+3] = getAlpha(element, opts)
+		}
+
+		intensities[i] = element.Intensity
+		classifications[i] = element.Classification
+		if returnNumbers != nil {
+			returnNumbers[i] = element.ReturnNumber
+			numbersOfReturns[i] = element.NumberOfReturns
+		}
+		if gpsTimes != nil {
+			gpsTimes[i] = element.GpsTime
+		}
+
+	}
+
+	coords, normals, avgX, avgY, avgZ, err := getRelativeCartesianCoordinates(node, items, opts, coordinateConverter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Feature table binary body, each array is referenced by the byte offset it is appended at
+	featureTableBody := binaryBody{}
+	var featureTableExtensions, batchTableExtensions string
+	var volume *quantizedVolume
+	if useDracoCompression(opts) {
+		// positions, normals and colors are all stored in the Draco compressed buffer, which quantizes them itself
+		attributes := []tiler.DracoAttribute{{Semantic: "POSITION", Components: 3, Floats: toFloat32(coords)}}
+		if normals != nil {
+			attributes = append(attributes, tiler.DracoAttribute{Semantic: "NORMAL", Components: 3, Floats: toFloat32(normals)})
+		}
+		if colors565 != nil {
+			// Draco stores the colors as RGB, the packing would only lose precision
+			colorSemantic = "RGB"
+		}
+		attributes = append(attributes, tiler.DracoAttribute{Semantic: colorSemantic, Components: colorComponents, Bytes: colors})
+		if featureTableBody, featureTableExtensions, err = encodeDracoBinaryBody(pointNo, attributes, opts.DracoEncoder); err != nil {
+			return nil, err
+		}
+	} else {
+		if opts.QuantizePositions {
+			var quantized []uint16
+			volume, quantized = quantizePositions(coords)
+			featureTableBody.appendSemantic("POSITION_QUANTIZED", 2, utils.ConvertUint16ToByteArray(quantized))
+		} else {
+			featureTableBody.appendSemantic("POSITION", 4, utils.ConvertTruncateFloat64ToFloat32ByteArray(coords))
+		}
+		if normals != nil {
+			featureTableBody.appendSemantic("NORMAL", 4, utils.ConvertTruncateFloat64ToFloat32ByteArray(normals))
+		}
+		if colors565 != nil {
+			featureTableBody.appendSemantic(colorSemantic, 2, utils.ConvertUint16ToByteArray(colors565))
+		} else {
+			featureTableBody.appendSemantic(colorSemantic, 1, colors)
+		}
+	}
+
+	// Batch table binary body
+	batchTableBody := binaryBody{}
+	batchTableBody.appendBatchProperty("INTENSITY", "UNSIGNED_BYTE", "SCALAR", intensities)
+	batchTableBody.appendBatchProperty("CLASSIFICATION", "UNSIGNED_BYTE", "SCALAR", classifications)
+	if returnNumbers != nil {
+		batchTableBody.appendBatchProperty("RETURN_NUMBER", "UNSIGNED_BYTE", "SCALAR", returnNumbers)
+		batchTableBody.appendBatchProperty("NUMBER_OF_RETURNS", "UNSIGNED_BYTE", "SCALAR", numbersOfReturns)
+	}
+	if colors16 != nil {
+		// the pnts feature table has no semantic for colors deeper than 8 bits per channel, RGB565 being even lossier
+		batchTableBody.appendBatchProperty("RGB16", "UNSIGNED_SHORT", "VEC3", utils.ConvertUint16ToByteArray(colors16))
+		// the batch table binary body starts right after the 4 byte aligned batch table json, so aligning the
+		// feature table binary body aligns the unsigned shorts in the file as well
+		featureTableBody.bytes = padBytes(featureTableBody.bytes, 2, 0)
+	}
+	if opts.NormalizeIntensity {
+		normalizedIntensities := make([]float64, pointNo)
+		for i, intensity := range intensities {
+			if opts.MaxIntensity > 0 {
+				normalizedIntensities[i] = math.Min(float64(intensity)/float64(opts.MaxIntensity), 1)
+			}
+		}
+		batchTableBody.appendBatchProperty("NORMALIZED_INTENSITY", "FLOAT", "SCALAR", utils.ConvertTruncateFloat64ToFloat32ByteArray(normalizedIntensities))
+		// the batch table binary body starts right after the 4 byte aligned batch table json
+		featureTableBody.bytes = padBytes(featureTableBody.bytes, 4, 0)
+	}
+	if gpsTimes != nil {
+		// points of the tile without GPS time, e.g. read from other files, get NaN
+		batchTableBody.appendBatchProperty("GPS_TIME", "DOUBLE", "SCALAR", utils.ConvertFloat64ToByteArray(gpsTimes))
+		featureTableBody.bytes = padBytes(featureTableBody.bytes, 8, 0)
+	}
+	alignDoubles := gpsTimes != nil
+	for i, attribute := range opts.ExtraAttributes {
+		batchTableBody.appendBatchProperty(attribute.Name, attribute.ComponentType, "SCALAR", encodeExtraAttribute(items, i, attribute.ComponentType))
+		featureTableBody.bytes = padBytes(featureTableBody.bytes, componentTypeSizes[attribute.ComponentType], 0)
+		alignDoubles = alignDoubles || attribute.ComponentType == "DOUBLE"
+	}
+
+	// Deflate compressed binary bodies, byte offsets still refer to the decompressed ones
+	if opts.DeflateBuffers {
+		if featureTableExtensions == "" {
+			// Draco buffers are already compressed
+			if featureTableBody.bytes, featureTableExtensions, err = deflateBinaryBody(featureTableBody.bytes); err != nil {
+				return nil, err
+			}
+		}
+		if batchTableBody.bytes, batchTableExtensions, err = deflateBinaryBody(batchTableBody.bytes); err != nil {
+			return nil, err
+		}
+		if featureTableExtensions != "" || batchTableExtensions != "" {
+			// keeps the batch table starting at an 8 byte boundary
+			featureTableBody.bytes = padBytes(featureTableBody.bytes, 8, 0)
+		}
+	}
+
+	// Feature table
+	featureTableBytes, err := generateFeatureTableJson(avgX, avgY, avgZ, pointNo, volume, featureTableBody.properties, featureTableExtensions)
+	if err != nil {
+		return nil, err
+	}
+	featureTableLen := len(featureTableBytes)
+
+	// Batch table
+	batchTableBytes, err := generateBatchTableJson(batchTableBody.properties, batchTableExtensions)
+	if err != nil {
+		return nil, err
+	}
+	if alignDoubles && (28+featureTableLen+len(featureTableBody.bytes)+len(batchTableBytes))%8 != 0 {
+		// the 4 byte aligned tables may leave the batch table binary body, and so the doubles, misaligned in the file
+		batchTableBytes = append(batchTableBytes, []byte("    ")...)
+	}
+	batchTableLen := len(batchTableBytes)
+
+	// Appending binary content to slice
+	outputByte := make([]byte, 0)
+	outputByte = append(outputByte, []byte("pnts")...)                 // magic
+	outputByte = append(outputByte, utils.ConvertIntToByteArray(1)...) // version number
+	byteLength := 28 + featureTableLen + len(featureTableBody.bytes) + batchTableLen + len(batchTableBody.bytes)
+	outputByte = append(outputByte, utils.ConvertIntToByteArray(byteLength)...)
+	outputByte = append(outputByte, utils.ConvertIntToByteArray(featureTableLen)...)             // feature table length
+	outputByte = append(outputByte, utils.ConvertIntToByteArray(len(featureTableBody.bytes))...) // feature table binary length
+	outputByte = append(outputByte, utils.ConvertIntToByteArray(batchTableLen)...)               // batch table length
+	outputByte = append(outputByte, utils.ConvertIntToByteArray(len(batchTableBody.bytes))...)   // batch table binary length
+	outputByte = append(outputByte, featureTableBytes...)                                        // feature table
+	outputByte = append(outputByte, featureTableBody.bytes...)                                   // positions, normals and colors arrays
+	outputByte = append(outputByte, batchTableBytes...)                                          // batch table
+	outputByte = append(outputByte, batchTableBody.bytes...)                                     // intensities, classifications, returns and GPS times arrays
+
+	return outputByte, nil
+}
+
+// Converts the given points of the given node to the output coordinates of the options, EPSG:4978 cartesian
+// coordinates by default, and returns them relative to their average, together with the average itself and the normals of the points, estimated on the absolute coordinates if
+// requested for the depth of the node, or nil
+func getRelativeCartesianCoordinates(node *octree.OctNode, items []*data.Point, opts *tiler.TilerOptions, coordinateConverter converters.CoordinateConverter) (coords []float64, normals []float64, avgX, avgY, avgZ float64, err error) {
+	outputConverter, err := getOutputConverter(node, opts, coordinateConverter)
+	if err != nil {
+		return nil, nil, 0, 0, 0, err
+	}
+	pointNo := len(items)
+	coords = make([]float64, pointNo*3)
+	for i, element := range items {
 		srcCoord := geometry.Coordinate{
 			X: &element.X,
 			Y: &element.Y,
@@ -95,34 +443,33 @@ func writeBinaryPntsFile(workUnit WorkUnit, coordinateConverter converters.Coord
 		}
 
 		// ConvertCoordinateSrid coords according to cesium CRS
-		outCrd, err := coordinateConverter.ConvertToWGS84Cartesian(srcCoord, workUnit.Opts.Srid)
+		outCrd, err := outputConverter.ConvertToWGS84Cartesian(srcCoord, opts.Srid)
 		if err != nil {
-			return err
+			return nil, nil, 0, 0, 0, err
 		}
 
 		coords[i*3] = *outCrd.X
 		coords[i*3+1] = *outCrd.Y
 		coords[i*3+2] = *outCrd.Z
+	}
 
-		colors[i*3] = element.R
-		colors[i*3+1] = element.G
-		colors[i*3+2] = element.B
-
-		intensities[i] = element.Intensity
-		classifications[i] = element.Classification
-
+	// Estimating normals on absolute coordinates, either for all tiles or only for the ones not deeper than the
+	// configured depth
+	if opts.ComputeNormals || int(node.Depth) <= opts.NormalsMaxDepth {
+		normals = geometry.EstimateNormals(coords, getNormalNeighbours(opts))
 	}
 
-	// Evaluating average X, Y, Z to express coords relative to tile center
-	var avgX, avgY, avgZ float64
+	// Evaluating average X, Y, Z to express coords relative to tile center, the origin if there are no points
 	for i := 0; i < pointNo; i++ {
 		avgX = avgX + coords[i*3]
 		avgY = avgY + coords[i*3+1]
 		avgZ = avgZ + coords[i*3+2]
 	}
-	avgX /= float64(pointNo)
-	avgY /= float64(pointNo)
-	avgZ /= float64(pointNo)
+	if pointNo > 0 {
+		avgX /= float64(pointNo)
+		avgY /= float64(pointNo)
+		avgZ /= float64(pointNo)
+	}
 
 	// Normalizing coordinates relative to average
 	for i := 0; i < pointNo; i++ {
@@ -130,137 +477,138 @@ func writeBinaryPntsFile(workUnit WorkUnit, coordinateConverter converters.Coord
 		coords[i*3+1] -= avgY
 		coords[i*3+2] -= avgZ
 	}
-	positionBytes := utils.ConvertTruncateFloat64ToFloat32ByteArray(coords)
-
-	// Feature table
-	featureTableStr := generateFeatureTableJsonContent(avgX, avgY, avgZ, pointNo, 0)
-	featureTableLen := len(featureTableStr)
-	featureTableBytes := []byte(featureTableStr)
-
-	// Batch table
-	batchTableStr := generateBatchTableJsonContent(pointNo, 0)
-	batchTableLen := len(batchTableStr)
-	batchTableBytes := []byte(batchTableStr)
 
-	// Appending binary content to slice
-	outputByte := make([]byte, 0)
-	outputByte = append(outputByte, []byte("pnts")...)                 // magic
-	outputByte = append(outputByte, utils.ConvertIntToByteArray(1)...) // version number
-	byteLength := 28 + featureTableLen + len(positionBytes) + len(colors)
-	outputByte = append(outputByte, utils.ConvertIntToByteArray(byteLength)...)
-	outputByte = append(outputByte, utils.ConvertIntToByteArray(featureTableLen)...)                       // feature table length
-	outputByte = append(outputByte, utils.ConvertIntToByteArray(len(positionBytes)+len(colors))...)        // feature table binary length
-	outputByte = append(outputByte, utils.ConvertIntToByteArray(batchTableLen)...)                         // batch table length
-	outputByte = append(outputByte, utils.ConvertIntToByteArray(len(intensities)+len(classifications))...) // batch table binary length
-	outputByte = append(outputByte, featureTableBytes...)                                                  // feature table
-	outputByte = append(outputByte, positionBytes...)                                                      // positions array
-	outputByte = append(outputByte, colors...)                                                             // colors array
-	outputByte = append(outputByte, batchTableBytes...)                                                    // batch table
-	outputByte = append(outputByte, intensities...)                                                        // intensities array
-	outputByte = append(outputByte, classifications...)                                                    // classifications array
+	// Lossy snapping of the relative coordinates to a grid with the configured precision, improves compressibility
+	if precision := opts.PositionPrecision; precision > 0 {
+		for i := range coords {
+			coords[i] = math.Round(coords[i]/precision) * precision
+		}
+	}
+	return coords, normals, avgX, avgY, avgZ, nil
+}
 
-	// Write binary content to file
-	err := ioutil.WriteFile(pntsFilePath, outputByte, 0777)
+// Packs the given 16 bit color in 16 bits, 5 for red, 6 for green and 5 for blue from the most significant
+func packRgb565(R, G, B uint16) uint16 {
+	return R>>11<<11 | G>>10<<5 | B>>11
+}
 
-	if err != nil {
-		return err
+// Returns the function coloring the points from their elevation with the ElevationRamp of the options, or nil if no
+// ramp is set. The ramp spans the elevations of the root tile, i.e. of the whole cloud, so that the colors are
+// consistent across tiles
+func getElevationRamp(node *octree.OctNode, opts *tiler.TilerOptions) func(z float64) (R, G, B uint16) {
+	if len(opts.ElevationRamp) == 0 {
+		return nil
+	}
+	root := node
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	minZ, maxZ := root.BoundingBox.Zmin, root.BoundingBox.Zmax
+	return func(z float64) (R, G, B uint16) {
+		return opts.ElevationRamp.Color(z, minZ, maxZ)
 	}
-	return nil
 }
 
-// Generates the json representation of the feature table
-func generateFeatureTableJsonContent(x, y, z float64, pointNo int, spaceNo int) string {
-	sb := ""
-	sb += "{\"POINTS_LENGTH\":" + strconv.Itoa(pointNo) + ","
-	sb += "\"RTC_CENTER\":[" + fmt.Sprintf("%f", x) + strings.Repeat("0", spaceNo)
-	sb += "," + fmt.Sprintf("%f", y) + "," + fmt.Sprintf("%f", z) + "],"
-	sb += "\"POSITION\":" + "{\"byteOffset\":" + "0" + "},"
-	sb += "\"RGB\":" + "{\"byteOffset\":" + strconv.Itoa(pointNo*12) + "}}"
-	headerByteLength := len([]byte(sb))
-	paddingSize := headerByteLength % 4
-	if paddingSize != 0 {
-		return generateFeatureTableJsonContent(x, y, z, pointNo, 4-paddingSize)
-	}
-	return sb
+// Returns the color of the given point, from its elevation if the given elevation ramp is not nil
+func getColor(element *data.Point, ramp func(z float64) (R, G, B uint16)) (R, G, B uint16) {
+	if ramp != nil {
+		return ramp(element.Z)
+	}
+	return element.R, element.G, element.B
 }
 
-// Generates the json representation of the batch table
-func generateBatchTableJsonContent(pointNumber, spaceNumber int) string {
-	sb := ""
-	sb += "{\"INTENSITY\":" + "{\"byteOffset\":" + "0" + ", \"componentType\":\"UNSIGNED_BYTE\", \"type\":\"SCALAR\"},"
-	sb += "\"CLASSIFICATION\":" + "{\"byteOffset\":" + strconv.Itoa(pointNumber) + ", \"componentType\":\"UNSIGNED_BYTE\", \"type\":\"SCALAR\"}}"
-	sb += strings.Repeat(" ", spaceNumber)
-	headerByteLength := len([]byte(sb))
-	paddingSize := headerByteLength % 4
-	if paddingSize != 0 {
-		return generateBatchTableJsonContent(pointNumber, 4-paddingSize)
-	}
-	return sb
+// Returns the alpha value of the given point according to the per classification alpha configured in the options.
+// Points of classifications without a configured alpha are fully opaque
+func getAlpha(element *data.Point, opts *tiler.TilerOptions) uint8 {
+	if alpha, ok := opts.ClassificationAlpha[element.Classification]; ok {
+		return alpha
+	}
+	return 255
 }
 
 // Writes the tileset.json file for the given WorkUnit
 func writeTilesetJsonFile(workUnit WorkUnit, coordinateConverter converters.CoordinateConverter) error {
 	parentFolder := workUnit.BasePath
 	node := workUnit.OctNode
+	writer := getTileWriter(workUnit.Opts)
 
 	// Create base folder if it does not exist
-	if _, err := os.Stat(parentFolder); os.IsNotExist(err) {
-		err := os.MkdirAll(parentFolder, 0777)
-		if err != nil {
-			return err
-		}
+	if err := writer.MkdirAll(parentFolder); err != nil {
+		return err
 	}
 
 	// tileset.json file
 	file := path.Join(parentFolder, "tileset.json")
-	jsonData, err := generateTilesetJsonContent(node, workUnit.Opts, coordinateConverter)
+	if _, _, ok := getResumedTile(file, workUnit.Opts); ok {
+		return nil
+	}
+	jsonData, err := generateTilesetJsonContent(node, workUnit.Opts, coordinateConverter, workUnit.Regions)
+	if err != nil {
+		return err
+	}
+	jsonData, err = compressTileData(jsonData, workUnit.Opts)
 	if err != nil {
 		return err
 	}
 
 	// Writes the tileset.json binary content to the given file
-	err = ioutil.WriteFile(file, jsonData, 0666)
+	err = writer.WriteFile(file, jsonData)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return writeResumeSidecar(writer, file, jsonData, 0, workUnit.Opts)
+}
+
+// Returns the bounding region of the given node, either taken from the given precomputed regions, if not nil, or
+// converting the node bounding box
+func getRegion(node *octree.OctNode, opts *tiler.TilerOptions, converter converters.CoordinateConverter, regions map[*octree.OctNode][]float64) ([]float64, error) {
+	if regions != nil {
+		if region, ok := regions[node]; ok {
+			return region, nil
+		}
+	}
+	return convertNodeRegion(node, opts, converter)
 }
 
 // Generates the tileset.json content for the given octnode and tileroptions
-func generateTilesetJsonContent(node *octree.OctNode, opts *tiler.TilerOptions, converter converters.CoordinateConverter) ([]byte, error) {
+func generateTilesetJsonContent(node *octree.OctNode, opts *tiler.TilerOptions, converter converters.CoordinateConverter, regions map[*octree.OctNode][]float64) ([]byte, error) {
 	if !node.IsLeaf || node.Parent == nil {
 		tileset := Tileset{}
 		tileset.Asset = Asset{Version: "1.0"}
-		tileset.GeometricError = computeGeometricError(node)
+		if opts.OutputFormat == tiler.GlbOutput {
+			// glTF content requires 3D Tiles 1.1
+			tileset.Asset.Version = "1.1"
+		}
+		tileset.ExtensionsUsed = getContentExtensions(opts)
+		tileset.ExtensionsRequired = getContentExtensions(opts)
+		tileset.GeometricError = getGeometricError(node, opts)
 		root := Root{}
 		root.Children = []Child{}
-		for i, child := range node.Children {
-			if child != nil && child.GlobalChildrenCount > 0 {
+		for _, child := range node.Children {
+			if child != nil && child.HasPoints() {
 				childJson := Child{}
 				filename := "tileset.json"
 				if child.IsLeaf {
-					filename = "content.pnts"
+					filename = tileContentFile(opts)
 				}
 				childJson.Content = Content{
-					Url: strconv.Itoa(i) + "/" + filename,
+					Url: WithUrlQuery(childTileUrl(node, child, opts, filename), opts),
 				}
-				reg, err := converter.Convert2DBoundingboxToWGS84Region(child.BoundingBox, opts.Srid)
+				boundingVolume, err := getBoundingVolume(child, opts, converter, regions)
 				if err != nil {
 					return nil, err
 				}
-				childJson.BoundingVolume = BoundingVolume{
-					Region: reg,
-				}
-				childJson.GeometricError = computeGeometricError(child)
+				childJson.BoundingVolume = boundingVolume
+				childJson.GeometricError = getGeometricError(child, opts)
 				childJson.Refine = "ADD"
 				root.Children = append(root.Children, childJson)
 			}
 		}
 		root.Content = Content{
-			Url: "content.pnts",
+			Url: WithUrlQuery(tileContentFile(opts), opts),
 		}
-		reg, err := converter.Convert2DBoundingboxToWGS84Region(node.BoundingBox, opts.Srid)
+		reg, err := getRegion(node, opts, converter, regions)
 
 		if node.Parent == nil && node.IsLeaf {
 			// only one tile, no LoDs. Estimate geometric error as lenght of diagonal of region
@@ -269,17 +617,29 @@ func generateTilesetJsonContent(node *octree.OctNode, opts *tiler.TilerOptions,
 			var lngA = reg[0]
 			var lngB = reg[2]
 			latA = reg[1]
-			tileset.GeometricError = 6371000 * math.Acos(math.Cos(latA)*math.Cos(latB)*math.Cos(lngB-lngA)+math.Sin(latA)*math.Sin(latB))
+			tileset.GeometricError = getGeometricErrorMultiplier(opts) * 6371000 * math.Acos(math.Cos(latA)*math.Cos(latB)*math.Cos(lngB-lngA)+math.Sin(latA)*math.Sin(latB))
+			if math.IsNaN(tileset.GeometricError) {
+				// degenerate region, e.g. a single point
+				tileset.GeometricError = 0
+			}
 		}
 
 		if err != nil {
 			return nil, err
 		}
-		root.BoundingVolume = BoundingVolume{
-			Region: reg,
+		if root.BoundingVolume, err = getBoundingVolume(node, opts, converter, regions); err != nil {
+			return nil, err
 		}
-		root.GeometricError = computeGeometricError(node)
+		root.GeometricError = getGeometricError(node, opts)
 		root.Refine = "ADD"
+		if node.Parent == nil {
+			// the transform of the root applies to the tiles of the nested tilesets too
+			outputConverter, err := getOutputConverter(node, opts, converter)
+			if err != nil {
+				return nil, err
+			}
+			root.Transform = outputConverter.getRootTransform(opts)
+		}
 		tileset.Root = root
 
 		// Outputting a formatted json file
@@ -294,28 +654,79 @@ func generateTilesetJsonContent(node *octree.OctNode, opts *tiler.TilerOptions,
 	return nil, errors.New("this node is a leaf, cannot create tileset json for it")
 }
 
-// Computes the geometric error for the given OctNode
+// Returns the extensions used and required by the content of the tiles, nil if none
+func getContentExtensions(opts *tiler.TilerOptions) []string {
+	var extensions []string
+	if opts.DeflateBuffers {
+		extensions = append(extensions, pntsDeflateExtension)
+	}
+	if useDracoCompression(opts) && opts.OutputFormat == tiler.PntsOutput {
+		extensions = append(extensions, pntsDracoExtension)
+	}
+	return extensions
+}
+
+// Returns the geometric error of the given OctNode scaled by the multiplier set in the options. If monotonic geometric
+// errors are requested the error is capped to the one of the parent node
+func getGeometricError(node *octree.OctNode, opts *tiler.TilerOptions) float64 {
+	var geometricError float64
+	switch opts.GeometricErrors {
+	case tiler.DiagonalGeometricErrors:
+		geometricError = getGeometricErrorMultiplier(opts) * opts.DiagonalFraction * node.BoundingBox.GetDiagonal()
+	case tiler.SpacingGeometricErrors:
+		geometricError = getGeometricErrorMultiplier(opts) * computeSpacingGeometricError(node)
+	default:
+		geometricError = getGeometricErrorMultiplier(opts) * computeGeometricError(node)
+	}
+	if !(geometricError > 0) || math.IsInf(geometricError, 0) {
+		// negative or undefined values would make viewers render either nothing or everything
+		geometricError = 0
+	}
+	if opts.MonotonicGeometricError && node.Parent != nil {
+		geometricError = math.Min(geometricError, getGeometricError(node.Parent, opts))
+	}
+	return geometricError
+}
+
+func getGeometricErrorMultiplier(opts *tiler.TilerOptions) float64 {
+	if opts.GeometricErrorMultiplier <= 0 {
+		return 1
+	}
+	return opts.GeometricErrorMultiplier
+}
+
+// Computes the geometric error for the given OctNode, in meters, as the difference between the average spacing of the
+// points rendered down to this node, estimated as the cube root of the node volume per point, and the one of all the
+// points of the node, including the ones of its descendants. Negative or undefined values, possible when the points
+// are unevenly spread across the ancestors, are clamped to zero.
 func computeGeometricError(node *octree.OctNode) float64 {
 	volume := node.BoundingBox.GetVolume()
-	totalRenderedPoints := int64(node.LocalChildrenCount)
-	parent := node.Parent
-	for parent != nil {
-		for _, e := range parent.Items {
-			if canBoundingBoxContainElement(e, node.BoundingBox) {
-				totalRenderedPoints++
-			}
-		}
-		parent = parent.Parent
+	totalRenderedPoints := int64(node.LocalChildrenCount) + node.AncestorPointsCount
+	if totalRenderedPoints == 0 || !(volume > 0) {
+		// the densities are undefined
+		return 0
 	}
 	densityWithAllPoints := math.Pow(volume/float64(totalRenderedPoints+node.GlobalChildrenCount-int64(node.LocalChildrenCount)), 0.333)
 	densityWIthOnlyThisTile := math.Pow(volume/float64(totalRenderedPoints), 0.333)
 
-	return densityWIthOnlyThisTile - densityWithAllPoints
+	geometricError := densityWIthOnlyThisTile - densityWithAllPoints
+	if !(geometricError > 0) || math.IsInf(geometricError, 0) {
+		return 0
+	}
+	return geometricError
 }
 
-// Checks if the bounding box contains the given element
-func canBoundingBoxContainElement(e *data.Point, bbox *geometry.BoundingBox) bool {
-	return (e.X >= bbox.Xmin && e.X <= bbox.Xmax) &&
-		(e.Y >= bbox.Ymin && e.Y <= bbox.Ymax) &&
-		(e.Z >= bbox.Zmin && e.Z <= bbox.Zmax)
+// Computes the geometric error for the given OctNode, in meters, as the diagonal of its bounding box divided by the
+// cube root of the number of points rendered down to this node, i.e. about their average spacing along the diagonal.
+// Leaves have no points left to refine and get 0, nodes without rendered points get the whole diagonal
+func computeSpacingGeometricError(node *octree.OctNode) float64 {
+	if node.IsLeaf {
+		return 0
+	}
+	diagonal := node.BoundingBox.GetDiagonal()
+	totalRenderedPoints := int64(node.LocalChildrenCount) + node.AncestorPointsCount
+	if totalRenderedPoints == 0 {
+		return diagonal
+	}
+	return diagonal / math.Cbrt(float64(totalRenderedPoints))
 }
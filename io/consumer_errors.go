@@ -0,0 +1,94 @@
+package io
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Error raised writing the files of the tile of a WorkUnit
+type TileError struct {
+	BasePath string // Folder of the tile whose files could not be written
+	Err      error
+}
+
+func (e *TileError) Error() string {
+	return "tile " + e.BasePath + ": " + e.Err.Error()
+}
+
+func (e *TileError) Unwrap() error {
+	return e.Err
+}
+
+// Combined error of all the tiles whose files could not be written
+type TilingError struct {
+	Errors []error
+}
+
+func (e *TilingError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return strconv.Itoa(len(e.Errors)) + " tiles failed:\n" + strings.Join(messages, "\n")
+}
+
+// Returns the errors of the single tiles, to be inspected by errors.Is and errors.As
+func (e *TilingError) Unwrap() []error {
+	return e.Errors
+}
+
+// Collects the errors raised by the consumers writing the tiles of a tileset. The first error added closes the done
+// channel, signalling all the consumers to stop once done with their current WorkUnit
+type ConsumerErrors struct {
+	sync.Mutex
+	errs     []error
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+// Instances a new ConsumerErrors holding no errors
+func NewConsumerErrors() *ConsumerErrors {
+	return &ConsumerErrors{
+		done: make(chan struct{}),
+	}
+}
+
+// Adds the given error and signals the consumers to stop
+func (ce *ConsumerErrors) Add(err error) {
+	ce.Lock()
+	ce.errs = append(ce.errs, err)
+	ce.Unlock()
+	ce.doneOnce.Do(func() { close(ce.done) })
+}
+
+// Returns a channel closed as soon as an error is added
+func (ce *ConsumerErrors) Done() <-chan struct{} {
+	return ce.done
+}
+
+// Returns true if an error has been added
+func (ce *ConsumerErrors) failed() bool {
+	select {
+	case <-ce.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Returns the errors added so far
+func (ce *ConsumerErrors) Errors() []error {
+	ce.Lock()
+	defer ce.Unlock()
+	return append([]error(nil), ce.errs...)
+}
+
+// Returns a TilingError combining all the errors added so far, nil if there are none
+func (ce *ConsumerErrors) Err() error {
+	errs := ce.Errors()
+	if len(errs) == 0 {
+		return nil
+	}
+	return &TilingError{Errors: errs}
+}
@@ -0,0 +1,123 @@
+package io
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+)
+
+// Name of the custom extension of the feature table and batch table of content.pnts files whose binary bodies are
+// deflate compressed. Byte offsets refer to the decompressed binary body, whose length is stored in the extension.
+// Standard 3D Tiles loaders cannot read these files, a loader implementing the extension is required
+const pntsDeflateExtension = "GOCESIUMTILER_deflate_buffers"
+
+// Extension of a feature table or batch table json storing the length of its decompressed binary body
+type deflateExtension struct {
+	ByteLength int `json:"byteLength"`
+}
+
+// Upper bound of the bytes a deflated binary body adds to a content.pnts besides the compressed bytes and the
+// extensions json: the "extensions" key of the table json, its alignment padding and the ones of the binary bodies
+const deflateOverheadBytes = 32
+
+// Compresses the given binary body with the deflate algorithm, returning the compressed bytes and the json of the
+// extensions object of the table referencing the body. If the compressed body would not make the content.pnts
+// smaller, e.g. for a few points or for points deflate compresses poorly, the body is returned as is without extensions
+func deflateBinaryBody(body []byte) ([]byte, string, error) {
+	var buffer bytes.Buffer
+	writer, err := flate.NewWriter(&buffer, flate.BestCompression)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := writer.Write(body); err != nil {
+		return nil, "", err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	extensions, err := json.Marshal(map[string]deflateExtension{pntsDeflateExtension: {ByteLength: len(body)}})
+	if err != nil {
+		return nil, "", err
+	}
+	if buffer.Len()+len(extensions)+deflateOverheadBytes >= len(body) {
+		return body, "", nil
+	}
+	return buffer.Bytes(), string(extensions), nil
+}
+
+// Decompresses the binary bodies of the given content.pnts if its tables use the deflate extension, returning the
+// equivalent uncompressed content.pnts. Content without the extension is returned as is
+func inflatePnts(content []byte) ([]byte, error) {
+	featureTableLen := int(binary.LittleEndian.Uint32(content[12:16]))
+	featureTableBinaryLen := int(binary.LittleEndian.Uint32(content[16:20]))
+	batchTableLen := int(binary.LittleEndian.Uint32(content[20:24]))
+	batchTableBinaryLen := int(binary.LittleEndian.Uint32(content[24:28]))
+	if 28+featureTableLen+featureTableBinaryLen+batchTableLen+batchTableBinaryLen > len(content) {
+		return nil, errors.New("pnts file is truncated")
+	}
+	featureTable := content[28 : 28+featureTableLen]
+	featureTableBinary := content[28+featureTableLen : 28+featureTableLen+featureTableBinaryLen]
+	batchTableStart := 28 + featureTableLen + featureTableBinaryLen
+	batchTable := content[batchTableStart : batchTableStart+batchTableLen]
+	batchTableBinary := content[batchTableStart+batchTableLen : batchTableStart+batchTableLen+batchTableBinaryLen]
+
+	featureTableBinary, featureTableDeflated, err := inflateBinaryBody(featureTable, featureTableBinary)
+	if err != nil {
+		return nil, err
+	}
+	batchTableBinary, batchTableDeflated, err := inflateBinaryBody(batchTable, batchTableBinary)
+	if err != nil {
+		return nil, err
+	}
+	if !featureTableDeflated && !batchTableDeflated {
+		return content, nil
+	}
+
+	byteLength := 28 + len(featureTable) + len(featureTableBinary) + len(batchTable) + len(batchTableBinary)
+	inflated := make([]byte, 0, byteLength)
+	inflated = append(inflated, content[0:8]...)
+	inflated = appendUint32(inflated, uint32(byteLength))
+	inflated = appendUint32(inflated, uint32(len(featureTable)))
+	inflated = appendUint32(inflated, uint32(len(featureTableBinary)))
+	inflated = appendUint32(inflated, uint32(len(batchTable)))
+	inflated = appendUint32(inflated, uint32(len(batchTableBinary)))
+	inflated = append(inflated, featureTable...)
+	inflated = append(inflated, featureTableBinary...)
+	inflated = append(inflated, batchTable...)
+	inflated = append(inflated, batchTableBinary...)
+	return inflated, nil
+}
+
+// Decompresses the given binary body if the given table json uses the deflate extension, checking that its length
+// matches the one stored in the extension. Returns the body and whether it was compressed
+func inflateBinaryBody(tableJson []byte, body []byte) ([]byte, bool, error) {
+	if len(bytes.TrimSpace(tableJson)) == 0 {
+		return body, false, nil
+	}
+	table := struct {
+		Extensions map[string]json.RawMessage `json:"extensions"`
+	}{}
+	if err := json.Unmarshal(tableJson, &table); err != nil {
+		return nil, false, err
+	}
+	raw, ok := table.Extensions[pntsDeflateExtension]
+	if !ok {
+		return body, false, nil
+	}
+	extension := deflateExtension{}
+	if err := json.Unmarshal(raw, &extension); err != nil {
+		return nil, false, errors.New("invalid " + pntsDeflateExtension + " extension")
+	}
+	inflated, err := io.ReadAll(flate.NewReader(bytes.NewReader(body)))
+	if err != nil {
+		return nil, false, errors.New("invalid deflate compressed binary body: " + err.Error())
+	}
+	if len(inflated) != extension.ByteLength {
+		return nil, false, errors.New("decompressed binary body length " + strconv.Itoa(len(inflated)) + " does not match the extension byteLength " + strconv.Itoa(extension.ByteLength))
+	}
+	return inflated, true, nil
+}
@@ -0,0 +1,85 @@
+package io
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+)
+
+// Name of the extension of the feature table of content.pnts files storing positions, normals and colors in a Draco
+// compressed buffer
+const pntsDracoExtension = "3DTILES_draco_point_compression"
+
+// Extension of a feature table json referencing its Draco compressed attributes
+type dracoExtension struct {
+	Properties map[string]int `json:"properties"` // Unique id in the Draco buffer of each compressed semantic
+	ByteOffset int            `json:"byteOffset"`
+	ByteLength int            `json:"byteLength"`
+}
+
+// Returns true if the tiles have to be Draco compressed, i.e. if the compression is enabled and an encoder is
+// available. Otherwise tiles are written uncompressed
+func useDracoCompression(opts *tiler.TilerOptions) bool {
+	return opts.DracoCompression && opts.DracoEncoder != nil
+}
+
+// Encodes the given attributes in a Draco compressed buffer, returning a feature table binary body storing just the
+// buffer and the json of the extensions object of the feature table referencing it. The compressed semantics are
+// listed in the body properties with byte offset 0, as required by the extension
+func encodeDracoBinaryBody(pointNo int, attributes []tiler.DracoAttribute, encoder tiler.DracoEncoder) (binaryBody, string, error) {
+	buffer, ids, err := encoder.Encode(pointNo, attributes)
+	if err != nil {
+		return binaryBody{}, "", err
+	}
+	if len(ids) != len(attributes) {
+		return binaryBody{}, "", errors.New("the Draco encoder did not return an id for each attribute")
+	}
+	body := binaryBody{bytes: buffer}
+	extension := dracoExtension{Properties: make(map[string]int), ByteLength: len(buffer)}
+	for i, attribute := range attributes {
+		body.properties = append(body.properties, binaryBodyProperty{semantic: attribute.Semantic})
+		extension.Properties[attribute.Semantic] = ids[i]
+	}
+	extensionJson, err := json.Marshal(map[string]dracoExtension{pntsDracoExtension: extension})
+	if err != nil {
+		return binaryBody{}, "", err
+	}
+	return body, string(extensionJson), nil
+}
+
+// Converts the given coordinates to float32, the type of the Draco POSITION and NORMAL attributes
+func toFloat32(values []float64) []float32 {
+	result := make([]float32, len(values))
+	for i, value := range values {
+		result[i] = float32(value)
+	}
+	return result
+}
+
+// Returns the Draco extension of the given feature table json, nil if the feature table is not Draco compressed
+func readDracoExtension(featureTable map[string]json.RawMessage) (*dracoExtension, error) {
+	extensions := make(map[string]json.RawMessage)
+	if raw, ok := featureTable["extensions"]; ok {
+		if err := json.Unmarshal(raw, &extensions); err != nil {
+			return nil, errors.New("invalid feature table extensions")
+		}
+	}
+	raw, ok := extensions[pntsDracoExtension]
+	if !ok {
+		return nil, nil
+	}
+	extension := dracoExtension{}
+	if err := json.Unmarshal(raw, &extension); err != nil {
+		return nil, errors.New("invalid " + pntsDracoExtension + " extension")
+	}
+	return &extension, nil
+}
+
+// Returns the unique id in the Draco buffer of the given semantic and whether the semantic is compressed
+func (extension *dracoExtension) getProperty(semantic string) (int, bool) {
+	if extension == nil {
+		return 0, false
+	}
+	id, ok := extension.Properties[semantic]
+	return id, ok
+}
@@ -0,0 +1,41 @@
+package io
+
+import (
+	"encoding/binary"
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"math"
+)
+
+// Encodes the values of the extra attribute of the given index of the given points as a little endian array of the
+// given batch table component type. Missing values, e.g. of points read from files without the attribute, are
+// written as NaN, or 0 for integer component types
+func encodeExtraAttribute(items []*data.Point, index int, componentType string) []byte {
+	size := componentTypeSizes[componentType]
+	array := make([]byte, len(items)*size)
+	for i, item := range items {
+		value := item.GetExtraAttribute(index)
+		if math.IsNaN(value) && componentType != "FLOAT" && componentType != "DOUBLE" {
+			value = 0
+		}
+		b := array[i*size : (i+1)*size]
+		switch componentType {
+		case "BYTE":
+			b[0] = uint8(int8(value))
+		case "UNSIGNED_BYTE":
+			b[0] = uint8(value)
+		case "SHORT":
+			binary.LittleEndian.PutUint16(b, uint16(int16(value)))
+		case "UNSIGNED_SHORT":
+			binary.LittleEndian.PutUint16(b, uint16(value))
+		case "INT":
+			binary.LittleEndian.PutUint32(b, uint32(int32(value)))
+		case "UNSIGNED_INT":
+			binary.LittleEndian.PutUint32(b, uint32(value))
+		case "FLOAT":
+			binary.LittleEndian.PutUint32(b, math.Float32bits(float32(value)))
+		case "DOUBLE":
+			binary.LittleEndian.PutUint64(b, math.Float64bits(value))
+		}
+	}
+	return array
+}
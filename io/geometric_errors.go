@@ -0,0 +1,75 @@
+package io
+
+import (
+	"errors"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Tolerance, in meters, used when comparing the geometric errors of parent and child tiles
+const geometricErrorTolerance = 1e-9
+
+// Smallest and largest geometric error, in meters, of the tiles of a tileset
+type GeometricErrorRange struct {
+	Min float64
+	Max float64
+}
+
+// Checks that in the tileset rooted at the given tileset.json file the geometric error of each tile is finite,
+// non-negative and not greater than the one of its parent, following the references to the nested tileset.json files.
+// Returns the range of the geometric errors of the tiles.
+func ValidateGeometricErrors(tilesetFile string) (GeometricErrorRange, error) {
+	geometricErrorRange := GeometricErrorRange{Min: math.MaxFloat64, Max: 0}
+	tileset, err := ReadTilesetFile(tilesetFile)
+	if err != nil {
+		return geometricErrorRange, err
+	}
+	if !isValidGeometricError(tileset.GeometricError) {
+		return geometricErrorRange, errors.New(tilesetFile + ": invalid tileset geometric error " + formatGeometricError(tileset.GeometricError))
+	}
+	err = validateGeometricErrors(tilesetFile, tileset.Root.GeometricError, tileset, &geometricErrorRange)
+	return geometricErrorRange, err
+}
+
+func validateGeometricErrors(tilesetFile string, parentError float64, tileset *Tileset, geometricErrorRange *GeometricErrorRange) error {
+	rootError := tileset.Root.GeometricError
+	if !isValidGeometricError(rootError) {
+		return errors.New(tilesetFile + ": invalid root geometric error " + formatGeometricError(rootError))
+	}
+	if rootError > parentError+geometricErrorTolerance {
+		return errors.New(tilesetFile + ": root geometric error greater than the one of the parent tile")
+	}
+	geometricErrorRange.Min = math.Min(geometricErrorRange.Min, rootError)
+	geometricErrorRange.Max = math.Max(geometricErrorRange.Max, rootError)
+	for _, child := range tileset.Root.Children {
+		if !isValidGeometricError(child.GeometricError) {
+			return errors.New(tilesetFile + ": invalid geometric error " + formatGeometricError(child.GeometricError) + " of child " + child.Content.Url)
+		}
+		if child.GeometricError > rootError+geometricErrorTolerance {
+			return errors.New(tilesetFile + ": geometric error of child " + child.Content.Url + " greater than the root one")
+		}
+		geometricErrorRange.Min = math.Min(geometricErrorRange.Min, child.GeometricError)
+		geometricErrorRange.Max = math.Max(geometricErrorRange.Max, child.GeometricError)
+		if strings.HasSuffix(urlFilePath(child.Content.Url), ".json") {
+			childFile := path.Join(path.Dir(tilesetFile), urlFilePath(child.Content.Url))
+			childTileset, err := ReadTilesetFile(childFile)
+			if err != nil {
+				return err
+			}
+			if err := validateGeometricErrors(childFile, child.GeometricError, childTileset, geometricErrorRange); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func isValidGeometricError(geometricError float64) bool {
+	return geometricError >= 0 && !math.IsInf(geometricError, 0) && !math.IsNaN(geometricError)
+}
+
+func formatGeometricError(geometricError float64) string {
+	return strconv.FormatFloat(geometricError, 'g', -1, 64)
+}
@@ -0,0 +1,142 @@
+package io
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strconv"
+)
+
+// Magic, version and chunk types of the binary glTF (GLB) container
+const (
+	glbMagic          = 0x46546C67 // "glTF"
+	glbVersion        = 2
+	glbChunkJson      = 0x4E4F534A // "JSON"
+	glbChunkBin       = 0x004E4942 // "BIN\0"
+	glbHeaderLength   = 12
+	glbChunkHeader    = 8
+	glbChunkAlignment = 4
+)
+
+// Packs the given glTF json and binary buffer in a GLB container. Both chunks are padded to 4 bytes, the json with
+// spaces and the binary buffer with zeros, as required by the specification. The BIN chunk is omitted if the binary
+// buffer is empty
+func EncodeGlb(gltfJson []byte, bin []byte) []byte {
+	jsonChunk := padBytes(append([]byte(nil), gltfJson...), glbChunkAlignment, ' ')
+	length := glbHeaderLength + glbChunkHeader + len(jsonChunk)
+	var binChunk []byte
+	if len(bin) > 0 {
+		binChunk = padBytes(append([]byte(nil), bin...), glbChunkAlignment, 0)
+		length += glbChunkHeader + len(binChunk)
+	}
+
+	content := make([]byte, 0, length)
+	content = appendUint32(content, glbMagic)
+	content = appendUint32(content, glbVersion)
+	content = appendUint32(content, uint32(length))
+	content = appendUint32(content, uint32(len(jsonChunk)))
+	content = appendUint32(content, glbChunkJson)
+	content = append(content, jsonChunk...)
+	if binChunk != nil {
+		content = appendUint32(content, uint32(len(binChunk)))
+		content = appendUint32(content, glbChunkBin)
+		content = append(content, binChunk...)
+	}
+	return content
+}
+
+func appendUint32(b []byte, value uint32) []byte {
+	var bytes [4]byte
+	binary.LittleEndian.PutUint32(bytes[:], value)
+	return append(b, bytes[:]...)
+}
+
+// Reads the GLB file at the given path and validates its container layout
+func ValidateGlbFile(filePath string) error {
+	content, _, err := readTileFile(filePath)
+	if err != nil {
+		return err
+	}
+	return ValidateGlb(content)
+}
+
+// Checks that the given GLB has a valid header, a 4 byte aligned JSON chunk padded with spaces followed by an
+// optional 4 byte aligned BIN chunk padded with zeros, that the BIN chunk matches the length of the first glTF buffer
+// and that the total length matches the header
+func ValidateGlb(content []byte) error {
+	if len(content) < glbHeaderLength+glbChunkHeader || binary.LittleEndian.Uint32(content[0:4]) != glbMagic {
+		return errors.New("not a valid glb file")
+	}
+	if version := binary.LittleEndian.Uint32(content[4:8]); version != glbVersion {
+		return errors.New("unsupported glb version " + strconv.Itoa(int(version)))
+	}
+	if int(binary.LittleEndian.Uint32(content[8:12])) != len(content) {
+		return errors.New("glb length does not match the file size")
+	}
+	if len(content)%glbChunkAlignment != 0 {
+		return errors.New("glb length is not aligned to 4 bytes")
+	}
+
+	chunks := make(map[uint32][]byte)
+	chunkTypes := make([]uint32, 0, 2)
+	for offset := glbHeaderLength; offset < len(content); {
+		if offset+glbChunkHeader > len(content) {
+			return errors.New("glb chunk header exceeds the file size")
+		}
+		chunkLength := int(binary.LittleEndian.Uint32(content[offset : offset+4]))
+		chunkType := binary.LittleEndian.Uint32(content[offset+4 : offset+8])
+		start := offset + glbChunkHeader
+		if chunkLength%glbChunkAlignment != 0 {
+			return errors.New("glb chunk length is not aligned to 4 bytes")
+		}
+		if start+chunkLength > len(content) {
+			return errors.New("glb chunk exceeds the file size")
+		}
+		chunks[chunkType] = content[start : start+chunkLength]
+		chunkTypes = append(chunkTypes, chunkType)
+		offset = start + chunkLength
+	}
+	if chunkTypes[0] != glbChunkJson {
+		return errors.New("the first glb chunk is not a JSON chunk")
+	}
+	if len(chunkTypes) > 2 || (len(chunkTypes) == 2 && chunkTypes[1] != glbChunkBin) {
+		return errors.New("unexpected glb chunks, only a JSON chunk and an optional BIN chunk are allowed")
+	}
+
+	gltfJson := chunks[glbChunkJson]
+	trimmed := bytes.TrimRight(gltfJson, " ")
+	if len(gltfJson)-len(trimmed) >= glbChunkAlignment {
+		return errors.New("glb JSON chunk padding exceeds the alignment")
+	}
+	gltf := struct {
+		Buffers []struct {
+			ByteLength int     `json:"byteLength"`
+			Uri        *string `json:"uri"`
+		} `json:"buffers"`
+	}{}
+	if err := json.Unmarshal(trimmed, &gltf); err != nil {
+		return errors.New("invalid glb JSON chunk: " + err.Error())
+	}
+
+	bin, hasBin := chunks[glbChunkBin]
+	if !hasBin {
+		if len(gltf.Buffers) > 0 && gltf.Buffers[0].Uri == nil {
+			return errors.New("the first glTF buffer has no uri and the glb has no BIN chunk")
+		}
+		return nil
+	}
+	if len(gltf.Buffers) == 0 || gltf.Buffers[0].Uri != nil {
+		return errors.New("the glb BIN chunk is not referenced by the first glTF buffer")
+	}
+	byteLength := gltf.Buffers[0].ByteLength
+	if byteLength > len(bin) || len(bin)-byteLength >= glbChunkAlignment {
+		return errors.New("glb BIN chunk length " + strconv.Itoa(len(bin)) + " does not match the buffer byte length " + strconv.Itoa(byteLength))
+	}
+	for _, padding := range bin[byteLength:] {
+		if padding != 0 {
+			return errors.New("glb BIN chunk is not padded with zeros")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package io
+
+import (
+	"encoding/json"
+	"github.com/mfbonfigli/gocesiumtiler/converters"
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"github.com/mfbonfigli/gocesiumtiler/structs/octree"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"github.com/mfbonfigli/gocesiumtiler/utils"
+	"math"
+)
+
+// glTF constants used by the point cloud content
+const (
+	gltfModePoints            = 0
+	gltfComponentFloat        = 5126
+	gltfComponentUnsignedByte = 5121
+	gltfTargetArrayBuffer     = 34962
+	gltfUnlitExtension        = "KHR_materials_unlit"
+)
+
+type gltfAsset struct {
+	Version   string `json:"version"`
+	Generator string `json:"generator"`
+}
+
+type gltfNode struct {
+	Mesh        int        `json:"mesh"`
+	Translation [3]float64 `json:"translation"`
+}
+
+type gltfPrimitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Material   int            `json:"material"`
+	Mode       int            `json:"mode"`
+}
+
+type gltfMesh struct {
+	Primitives []gltfPrimitive `json:"primitives"`
+}
+
+type gltfMaterial struct {
+	PbrMetallicRoughness map[string]float64  `json:"pbrMetallicRoughness"`
+	Extensions           map[string]struct{} `json:"extensions"`
+}
+
+type gltfBuffer struct {
+	ByteLength int `json:"byteLength"`
+}
+
+type gltfBufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+	Target     int `json:"target"`
+}
+
+type gltfAccessor struct {
+	BufferView    int       `json:"bufferView"`
+	ComponentType int       `json:"componentType"`
+	Normalized    bool      `json:"normalized,omitempty"`
+	Count         int       `json:"count"`
+	Type          string    `json:"type"`
+	Min           []float64 `json:"min,omitempty"`
+	Max           []float64 `json:"max,omitempty"`
+}
+
+type gltf struct {
+	Asset          gltfAsset        `json:"asset"`
+	ExtensionsUsed []string         `json:"extensionsUsed"`
+	Scene          int              `json:"scene"`
+	Scenes         []gltfScene      `json:"scenes"`
+	Nodes          []gltfNode       `json:"nodes"`
+	Meshes         []gltfMesh       `json:"meshes"`
+	Materials      []gltfMaterial   `json:"materials"`
+	Accessors      []gltfAccessor   `json:"accessors"`
+	BufferViews    []gltfBufferView `json:"bufferViews"`
+	Buffers        []gltfBuffer     `json:"buffers"`
+}
+
+type gltfScene struct {
+	Nodes []int `json:"nodes"`
+}
+
+// Encodes the given points of the given node as a binary glTF (GLB) point cloud, as used by 3D Tiles 1.1. Positions
+// are float32 relative to the node translation, colors RGBA unsigned bytes in the COLOR_0 attribute and normals, if
+// estimated for the depth of the node, float32 in the NORMAL attribute. glTF being y-up, ECEF coordinates (x, y, z)
+// are stored as (x, z, -y). Points are rendered unlit with the KHR_materials_unlit extension
+func encodeGlbPoints(node *octree.OctNode, items []*data.Point, opts *tiler.TilerOptions, coordinateConverter converters.CoordinateConverter) ([]byte, error) {
+	pointNo := len(items)
+	coords, normals, avgX, avgY, avgZ, err := getRelativeCartesianCoordinates(node, items, opts, coordinateConverter)
+	if err != nil {
+		return nil, err
+	}
+	coords = toYUp(coords)
+	min := []float64{math.MaxFloat64, math.MaxFloat64, math.MaxFloat64}
+	max := []float64{-math.MaxFloat64, -math.MaxFloat64, -math.MaxFloat64}
+	for i := range coords {
+		// bounds of the float32 values actually stored
+		value := float64(float32(coords[i]))
+		min[i%3] = math.Min(min[i%3], value)
+		max[i%3] = math.Max(max[i%3], value)
+	}
+	colors := make([]uint8, pointNo*4)
+	ramp := getElevationRamp(node, opts)
+	for i, element := range items {
+		R, G, B := getColor(element, ramp)
+		colors[i*4] = uint8(R >> 8)
+		colors[i*4+1] = uint8(G >> 8)
+		colors[i*4+2] = uint8(B >> 8)
+		colors[i*4+3] = getAlpha(element, opts)
+	}
+
+	// each attribute in its own buffer view, all of them 4 byte aligned
+	bin := utils.ConvertTruncateFloat64ToFloat32ByteArray(coords)
+	content := gltf{
+		Asset:          gltfAsset{Version: "2.0", Generator: "gocesiumtiler"},
+		ExtensionsUsed: []string{gltfUnlitExtension},
+		Scenes:         []gltfScene{{Nodes: []int{0}}},
+		Nodes:          []gltfNode{{Mesh: 0, Translation: [3]float64{avgX, avgZ, -avgY}}},
+		Materials: []gltfMaterial{{
+			PbrMetallicRoughness: map[string]float64{"metallicFactor": 0},
+			Extensions:           map[string]struct{}{gltfUnlitExtension: {}},
+		}},
+		Accessors: []gltfAccessor{
+			{BufferView: 0, ComponentType: gltfComponentFloat, Count: pointNo, Type: "VEC3", Min: min, Max: max},
+			{BufferView: 1, ComponentType: gltfComponentUnsignedByte, Normalized: true, Count: pointNo, Type: "VEC4"},
+		},
+		BufferViews: []gltfBufferView{
+			{Buffer: 0, ByteOffset: 0, ByteLength: len(bin), Target: gltfTargetArrayBuffer},
+			{Buffer: 0, ByteOffset: len(bin), ByteLength: len(colors), Target: gltfTargetArrayBuffer},
+		},
+	}
+	attributes := map[string]int{"POSITION": 0, "COLOR_0": 1}
+	bin = append(bin, colors...)
+	if normals != nil {
+		normalBytes := utils.ConvertTruncateFloat64ToFloat32ByteArray(toYUp(normals))
+		attributes["NORMAL"] = len(content.Accessors)
+		content.Accessors = append(content.Accessors, gltfAccessor{BufferView: len(content.BufferViews), ComponentType: gltfComponentFloat, Count: pointNo, Type: "VEC3"})
+		content.BufferViews = append(content.BufferViews, gltfBufferView{Buffer: 0, ByteOffset: len(bin), ByteLength: len(normalBytes), Target: gltfTargetArrayBuffer})
+		bin = append(bin, normalBytes...)
+	}
+	content.Meshes = []gltfMesh{{Primitives: []gltfPrimitive{{Attributes: attributes, Material: 0, Mode: gltfModePoints}}}}
+	content.Buffers = []gltfBuffer{{ByteLength: len(bin)}}
+
+	gltfJson, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	return EncodeGlb(gltfJson, bin), nil
+}
+
+// Converts the given z-up (x, y, z) vectors to the y-up (x, z, -y) ones of glTF
+func toYUp(vectors []float64) []float64 {
+	for i := 0; i < len(vectors); i += 3 {
+		vectors[i+1], vectors[i+2] = vectors[i+2], -vectors[i+1]
+	}
+	return vectors
+}
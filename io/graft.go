@@ -0,0 +1,202 @@
+package io
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// A tileset.json file of an existing tileset hierarchy, decoded generically so that the properties not known by the
+// tiler are preserved when it is written back
+type graftTilesetFile struct {
+	filePath string
+	mode     os.FileMode
+	gzipped  bool // Written back gzip compressed, as it was read
+	tileset  map[string]interface{}
+}
+
+// A tile along the path to the grafting slot, together with the tileset.json file storing it
+type graftTile struct {
+	file *graftTilesetFile
+	tile map[string]interface{}
+}
+
+// Links the tileset.json at the given path in the existing tileset hierarchy whose root tileset.json is at the given
+// rootTilesetFile path, as the content of the slot tile at the given path of child indexes from the root tile. External
+// tilesets referenced by the tiles along the path are followed, e.g. the path 2/0 selects the first child of the root
+// of the tileset referenced by the third child of the root. The slot tile is created if its index is the number of
+// children of its parent, otherwise its content, bounding volume and geometric error are replaced, keeping its
+// children. The bounding regions of the ancestors of the slot are expanded to contain the grafted tileset, their
+// geometric errors are raised to at least the one of the grafted tileset and the tileset.json files storing them
+// are rewritten. The url of the grafted tileset is relative to the tileset.json file storing the slot tile, with the
+// given query string appended, if any. The rewritten files keep their permission bits
+func GraftTileset(rootTilesetFile string, slot []int, tilesetFile string, urlQuery string) error {
+	grafted, err := ReadTilesetFile(tilesetFile)
+	if err != nil {
+		return err
+	}
+	region := grafted.Root.BoundingVolume.Region
+	if len(region) != 6 {
+		return errors.New("the root tile of " + tilesetFile + " has no bounding region")
+	}
+
+	tiles, err := findGraftSlot(rootTilesetFile, slot)
+	if err != nil {
+		return err
+	}
+	slotTile := tiles[len(tiles)-1]
+	url, err := filepath.Rel(filepath.Dir(slotTile.file.filePath), tilesetFile)
+	if err != nil {
+		return err
+	}
+	if query := strings.TrimPrefix(urlQuery, "?"); query != "" {
+		url += "?" + query
+	}
+	slotTile.tile["content"] = map[string]interface{}{"uri": filepath.ToSlash(url)}
+	slotTile.tile["boundingVolume"] = map[string]interface{}{"region": region}
+	slotTile.tile["geometricError"] = grafted.GeometricError
+	if _, ok := slotTile.tile["refine"]; !ok {
+		slotTile.tile["refine"] = "ADD"
+	}
+
+	// expand the ancestors to contain the grafted tileset
+	files := make([]*graftTilesetFile, 0)
+	for _, ancestor := range tiles[:len(tiles)-1] {
+		if err := expandGraftAncestor(ancestor, region, grafted.GeometricError); err != nil {
+			return err
+		}
+		if len(files) == 0 || files[len(files)-1] != ancestor.file {
+			files = append(files, ancestor.file)
+		}
+	}
+	if len(files) == 0 || files[len(files)-1] != slotTile.file {
+		files = append(files, slotTile.file)
+	}
+
+	// write the deepest tileset first, so that an interrupted graft never references a missing slot
+	for i := len(files) - 1; i >= 0; i-- {
+		file := files[i]
+		root := file.tileset["root"].(map[string]interface{})
+		if geometricError, ok := root["geometricError"].(float64); ok {
+			file.tileset["geometricError"] = maxFloat(toGraftFloat(file.tileset["geometricError"]), geometricError)
+		}
+		jsonData, err := json.MarshalIndent(file.tileset, "", "\t")
+		if err != nil {
+			return err
+		}
+		if file.gzipped {
+			if jsonData, err = gzipBytes(jsonData); err != nil {
+				return err
+			}
+		}
+		if err := WriteFileAtomic(file.filePath, jsonData, file.mode, filepath.Dir(file.filePath)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Follows the given path of child indexes from the root tile of the given tileset.json, loading the external
+// tilesets met along the way, and returns the tiles along the path, the root tiles of external tilesets included,
+// ending with the slot tile, created if its index is the number of children of its parent
+func findGraftSlot(rootTilesetFile string, slot []int) ([]graftTile, error) {
+	if len(slot) == 0 {
+		return nil, errors.New("the grafting slot cannot be the root tile")
+	}
+	file, err := readGraftTilesetFile(rootTilesetFile)
+	if err != nil {
+		return nil, err
+	}
+	root, ok := file.tileset["root"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New(rootTilesetFile + " has no root tile")
+	}
+	tiles := []graftTile{{file: file, tile: root}}
+	for i, index := range slot {
+		parent := tiles[len(tiles)-1]
+		children, _ := parent.tile["children"].([]interface{})
+		slotPath := strconv.Itoa(index)
+		if i == len(slot)-1 && index == len(children) {
+			child := map[string]interface{}{}
+			parent.tile["children"] = append(children, child)
+			return append(tiles, graftTile{file: parent.file, tile: child}), nil
+		}
+		if index < 0 || index >= len(children) {
+			return nil, errors.New("invalid grafting slot, tile " + slotPath + " of " + parent.file.filePath + " does not exist")
+		}
+		child, ok := children[index].(map[string]interface{})
+		if !ok {
+			return nil, errors.New("invalid tile " + slotPath + " in " + parent.file.filePath)
+		}
+		tiles = append(tiles, graftTile{file: parent.file, tile: child})
+		if i == len(slot)-1 {
+			break
+		}
+
+		// descend in the external tileset referenced by the tile, if any
+		content, _ := child["content"].(map[string]interface{})
+		url, _ := content["uri"].(string)
+		if url == "" {
+			url, _ = content["url"].(string)
+		}
+		if !strings.HasSuffix(urlFilePath(url), ".json") {
+			continue
+		}
+		externalFile, err := readGraftTilesetFile(filepath.Join(filepath.Dir(parent.file.filePath), filepath.FromSlash(path.Clean(urlFilePath(url)))))
+		if err != nil {
+			return nil, err
+		}
+		externalRoot, ok := externalFile.tileset["root"].(map[string]interface{})
+		if !ok {
+			return nil, errors.New(externalFile.filePath + " has no root tile")
+		}
+		tiles = append(tiles, graftTile{file: externalFile, tile: externalRoot})
+	}
+	return tiles, nil
+}
+
+// Expands the bounding region of the given ancestor tile to contain the given region and raises its geometric error
+// to at least the given one
+func expandGraftAncestor(ancestor graftTile, region []float64, geometricError float64) error {
+	boundingVolume, _ := ancestor.tile["boundingVolume"].(map[string]interface{})
+	existing, _ := boundingVolume["region"].([]interface{})
+	if boundingVolume != nil && existing == nil {
+		return errors.New("a tile of " + ancestor.file.filePath + " along the grafting path has no bounding region")
+	}
+	expanded := region
+	if len(existing) == 6 {
+		existingRegion := make([]float64, 6)
+		for i, value := range existing {
+			existingRegion[i] = toGraftFloat(value)
+		}
+		expanded = unionOfRegions(existingRegion, region)
+	}
+	ancestor.tile["boundingVolume"] = map[string]interface{}{"region": expanded}
+	ancestor.tile["geometricError"] = maxFloat(toGraftFloat(ancestor.tile["geometricError"]), geometricError)
+	return nil
+}
+
+func readGraftTilesetFile(filePath string) (*graftTilesetFile, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, err
+	}
+	content, gzipped, err := readTileFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	tileset := make(map[string]interface{})
+	if err := json.Unmarshal(content, &tileset); err != nil {
+		return nil, errors.New("invalid tileset " + filePath + ": " + err.Error())
+	}
+	return &graftTilesetFile{filePath: filePath, mode: info.Mode().Perm(), gzipped: gzipped, tileset: tileset}, nil
+}
+
+func toGraftFloat(value interface{}) float64 {
+	number, _ := value.(float64)
+	return number
+}
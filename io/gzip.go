@@ -0,0 +1,52 @@
+package io
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"io"
+	"os"
+)
+
+// Returns the given tile file content gzip compressed if GzipOutput is set, as is otherwise. File names are unchanged,
+// so that servers can send the files with Content-Encoding: gzip
+func compressTileData(data []byte, opts *tiler.TilerOptions) ([]byte, error) {
+	if !opts.GzipOutput {
+		return data, nil
+	}
+	return gzipBytes(data)
+}
+
+// Gzip compresses the given data
+func gzipBytes(data []byte) ([]byte, error) {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return compressed.Bytes(), nil
+}
+
+// Reads the tile file at the given path, decompressing it if gzip compressed. Also returns whether it was compressed
+func readTileFile(filePath string) ([]byte, bool, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(content) < 2 || content[0] != 0x1f || content[1] != 0x8b {
+		return content, false, nil
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, false, err
+	}
+	defer reader.Close()
+	content, err = io.ReadAll(reader)
+	if err != nil {
+		return nil, false, err
+	}
+	return content, true, nil
+}
@@ -0,0 +1,87 @@
+package io
+
+import (
+	"encoding/json"
+	"github.com/mfbonfigli/gocesiumtiler/converters"
+	"github.com/mfbonfigli/gocesiumtiler/structs/octree"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"path"
+)
+
+// Number of levels spanned by each subtree of the implicit tilesets if not set in the options
+const defaultImplicitSubtreeLevels = 4
+
+// Templates of the urls of the tile contents, stored in the folders returned by tileFolder, and of the subtree files
+// of the implicit tilesets
+const (
+	implicitContentTemplate  = "content/{level}/{x}/{y}/{z}/"
+	implicitSubtreesTemplate = "subtrees/{level}/{x}/{y}/{z}.subtree"
+)
+
+// Writes the tileset.json of the 3D Tiles 1.1 implicit octree tiling of the tree rooted at the given node in the given
+// folder, followed by the .subtree files storing the availability of its tiles and contents. The tiles are the
+// uniform octree subdivisions of the root bounding volume, matching the octree cells exactly for input coordinates in
+// degrees and approximately otherwise, their content being referenced by the content template of the tile folders
+func WriteImplicitTileset(node *octree.OctNode, outputFolder string, opts *tiler.TilerOptions, converter converters.CoordinateConverter, regions map[*octree.OctNode][]float64) error {
+	levels := opts.SubtreeLevels
+	if levels <= 0 {
+		levels = defaultImplicitSubtreeLevels
+	}
+	jsonData, err := generateImplicitTilesetJsonContent(node, opts, converter, regions, levels)
+	if err != nil {
+		return err
+	}
+	jsonData, err = compressTileData(jsonData, opts)
+	if err != nil {
+		return err
+	}
+	writer := getTileWriter(opts)
+	if err := writer.MkdirAll(outputFolder); err != nil {
+		return err
+	}
+	if err := writer.WriteFile(path.Join(outputFolder, "tileset.json"), jsonData); err != nil {
+		return err
+	}
+	return WriteSubtreeFiles(node, outputFolder, levels, opts)
+}
+
+// Generates the content of the tileset.json declaring the implicit octree tiling of the tree rooted at the given
+// node, with subtrees spanning the given number of levels
+func generateImplicitTilesetJsonContent(node *octree.OctNode, opts *tiler.TilerOptions, converter converters.CoordinateConverter, regions map[*octree.OctNode][]float64, subtreeLevels int) ([]byte, error) {
+	tileset := ImplicitTileset{}
+	tileset.Asset = Asset{Version: "1.1"}
+	tileset.ExtensionsUsed = getContentExtensions(opts)
+	tileset.ExtensionsRequired = getContentExtensions(opts)
+	tileset.GeometricError = getGeometricError(node, opts)
+
+	root := ImplicitRoot{}
+	root.Content = Content{
+		Url: WithUrlQuery(implicitContentTemplate+tileContentFile(opts), opts),
+	}
+	boundingVolume, err := getBoundingVolume(node, opts, converter, regions)
+	if err != nil {
+		return nil, err
+	}
+	root.BoundingVolume = boundingVolume
+	root.GeometricError = getGeometricError(node, opts)
+	root.Refine = "ADD"
+	outputConverter, err := getOutputConverter(node, opts, converter)
+	if err != nil {
+		return nil, err
+	}
+	root.Transform = outputConverter.getRootTransform(opts)
+
+	// the levels are counted from 1 in the octree and from 0 in the implicit tiling
+	availableLevels := int(node.GetTreeDepth()) - int(node.Depth) + 1
+	if availableLevels < 1 {
+		availableLevels = 1
+	}
+	root.ImplicitTiling = ImplicitTiling{
+		SubdivisionScheme: "OCTREE",
+		SubtreeLevels:     subtreeLevels,
+		AvailableLevels:   availableLevels,
+		Subtrees:          Content{Url: implicitSubtreesTemplate},
+	}
+	tileset.Root = root
+	return json.MarshalIndent(tileset, "", "\t")
+}
@@ -0,0 +1,86 @@
+package io
+
+import (
+	"encoding/json"
+	"github.com/mfbonfigli/gocesiumtiler/structs/point_loader"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"path"
+	"sort"
+	"strconv"
+)
+
+// Names of the standard ASPRS classification codes, used for the codes not named by the input files
+var standardClassificationNames = map[uint8]string{
+	0:  "Created, never classified",
+	1:  "Unclassified",
+	2:  "Ground",
+	3:  "Low Vegetation",
+	4:  "Medium Vegetation",
+	5:  "High Vegetation",
+	6:  "Building",
+	7:  "Low Point (noise)",
+	8:  "Model Key-point",
+	9:  "Water",
+	10: "Rail",
+	11: "Road Surface",
+	12: "Overlap",
+	13: "Wire - Guard",
+	14: "Wire - Conductor",
+	15: "Transmission Tower",
+	16: "Wire - Connector",
+	17: "Bridge Deck",
+	18: "High Noise",
+}
+
+// Legend of the classifications of the points of a tileset
+type Legend struct {
+	Classes []LegendClass `json:"classes"`
+}
+
+// Name and style of a classification present in a tileset
+type LegendClass struct {
+	Classification uint8  `json:"classification"`
+	Name           string `json:"name"`
+	Points         int64  `json:"points"`
+	Alpha          *uint8 `json:"alpha,omitempty"` // Alpha of the points of the classification, if set by ClassificationAlpha
+}
+
+// Returns the legend of the classifications counted by the given statistics, sorted by classification code. Classes
+// are named after the names read from the input files, falling back to the standard ASPRS names
+func NewLegend(statistics point_loader.Statistics, opts *tiler.TilerOptions) Legend {
+	legend := Legend{Classes: make([]LegendClass, 0, len(statistics.Classifications))}
+	for classification, count := range statistics.Classifications {
+		class := LegendClass{Classification: classification, Points: count}
+		if name, ok := statistics.ClassificationNames[classification]; ok {
+			class.Name = name
+		} else if name, ok := standardClassificationNames[classification]; ok {
+			class.Name = name
+		} else {
+			class.Name = "Class " + strconv.Itoa(int(classification))
+		}
+		if alpha, ok := opts.ClassificationAlpha[classification]; ok {
+			class.Alpha = &alpha
+		}
+		legend.Classes = append(legend.Classes, class)
+	}
+	sort.Slice(legend.Classes, func(i, j int) bool {
+		return legend.Classes[i].Classification < legend.Classes[j].Classification
+	})
+	return legend
+}
+
+// Writes the legend of the classifications counted by the given statistics as a legend.json file in the given folder
+func WriteLegendFile(statistics point_loader.Statistics, opts *tiler.TilerOptions, folder string) error {
+	// Create base folder if it does not exist
+	writer := getTileWriter(opts)
+	if err := writer.MkdirAll(folder); err != nil {
+		return err
+	}
+
+	jsonData, err := json.MarshalIndent(NewLegend(statistics, opts), "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return writer.WriteFile(path.Join(folder, "legend.json"), jsonData)
+}
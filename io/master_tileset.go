@@ -0,0 +1,96 @@
+package io
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"path"
+	"path/filepath"
+)
+
+// Reference to a tileset loaded by a master tileset
+type ChildTilesetRef struct {
+	Url            string    // Url of the tileset.json, relative to the folder of the master tileset
+	Region         []float64 // Bounding region of the tileset, west, south, east, north in radians, min and max height in meters
+	GeometricError float64   // Geometric error of the tileset
+}
+
+// Root tile of a master tileset, without content
+type masterRoot struct {
+	Children       []Child        `json:"children"`
+	BoundingVolume BoundingVolume `json:"boundingVolume"`
+	GeometricError float64        `json:"geometricError"`
+	Refine         string         `json:"refine"`
+}
+
+type masterTileset struct {
+	Asset          Asset      `json:"asset"`
+	GeometricError float64    `json:"geometricError"`
+	Root           masterRoot `json:"root"`
+}
+
+// Returns a reference to the tileset.json at the given url, relative to the given output folder, reading its bounding
+// region and geometric error from its root tile
+func ReadChildTilesetRef(outputDir string, url string) (ChildTilesetRef, error) {
+	tileset, err := ReadTilesetFile(filepath.Join(outputDir, filepath.FromSlash(url)))
+	if err != nil {
+		return ChildTilesetRef{}, err
+	}
+	if len(tileset.Root.BoundingVolume.Region) != 6 {
+		return ChildTilesetRef{}, errors.New("the root tile of " + url + " has no bounding region")
+	}
+	return ChildTilesetRef{
+		Url:            url,
+		Region:         tileset.Root.BoundingVolume.Region,
+		GeometricError: tileset.GeometricError,
+	}, nil
+}
+
+// Writes in the given folder a tileset.json whose root tile, without content, has a child tile for each of the given
+// tilesets. The region of the root contains the ones of the children and its geometric error is the max of theirs
+func WriteMasterTileset(outputDir string, children []ChildTilesetRef, opts *tiler.TilerOptions) error {
+	if len(children) == 0 {
+		return errors.New("a master tileset needs at least one child tileset")
+	}
+	root := masterRoot{
+		Children: make([]Child, len(children)),
+		Refine:   "ADD",
+	}
+	var region []float64
+	for i, child := range children {
+		if len(child.Region) != 6 {
+			return errors.New("child tileset " + child.Url + " has no bounding region")
+		}
+		root.Children[i] = Child{
+			Content:        Content{Url: path.Clean(filepath.ToSlash(child.Url))},
+			BoundingVolume: BoundingVolume{Region: child.Region},
+			GeometricError: child.GeometricError,
+			Refine:         "ADD",
+		}
+		if region == nil {
+			region = child.Region
+		} else {
+			region = unionOfRegions(region, child.Region)
+		}
+		root.GeometricError = maxFloat(root.GeometricError, child.GeometricError)
+	}
+	root.BoundingVolume = BoundingVolume{Region: region}
+	tileset := masterTileset{
+		Asset:          Asset{Version: "1.0"},
+		GeometricError: root.GeometricError,
+		Root:           root,
+	}
+
+	writer := getTileWriter(opts)
+	if err := writer.MkdirAll(outputDir); err != nil {
+		return err
+	}
+	jsonData, err := json.MarshalIndent(tileset, "", "\t")
+	if err != nil {
+		return err
+	}
+	if jsonData, err = compressTileData(jsonData, opts); err != nil {
+		return err
+	}
+	return writer.WriteFile(path.Join(outputDir, "tileset.json"), jsonData)
+}
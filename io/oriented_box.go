@@ -0,0 +1,92 @@
+package io
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/converters"
+	"github.com/mfbonfigli/gocesiumtiler/structs/geometry"
+	"math"
+)
+
+// Computes the oriented bounding box, in EPSG:4978 ECEF coordinates, of the given bounding box expressed in the given
+// srid. The axes of the box follow the x and y axes of the bounding box once converted to ECEF, the third one being
+// orthogonal to both, so that rotated or locally projected boxes are fitted more tightly than by regions. The extent
+// along the axes is measured on the corners, edge midpoints and face centers of the box, as for the bounding spheres.
+// Returns the box as its center followed by its x, y and z half axes, as the box bounding volume of 3D Tiles
+func getOrientedBoundingBox(bbox *geometry.BoundingBox, srid int, converter converters.CoordinateConverter) ([]float64, error) {
+	points := make([][3]float64, 0, 27)
+	for _, x := range []float64{bbox.Xmin, bbox.Xmid, bbox.Xmax} {
+		for _, y := range []float64{bbox.Ymin, bbox.Ymid, bbox.Ymax} {
+			for _, z := range []float64{bbox.Zmin, bbox.Zmid, bbox.Zmax} {
+				point, err := convertToEcef(x, y, z, srid, converter)
+				if err != nil {
+					return nil, err
+				}
+				points = append(points, point)
+			}
+		}
+	}
+
+	// points are sorted by x, then y, then z, the face centers along x are the 5th and 23rd, along y the 11th and 17th
+	axes := getOrthonormalAxes(subtractVectors(points[22], points[4]), subtractVectors(points[16], points[10]))
+	center := points[13]
+	box := make([]float64, 12)
+	var mid [3]float64
+	for i, axis := range axes {
+		min, max := math.Inf(1), math.Inf(-1)
+		for _, point := range points {
+			projection := dotVectors(subtractVectors(point, center), axis)
+			min = math.Min(min, projection)
+			max = math.Max(max, projection)
+		}
+		for c := 0; c < 3; c++ {
+			mid[c] += axis[c] * (min + max) / 2
+			box[3+3*i+c] = axis[c] * (max - min) / 2
+		}
+	}
+	for c := 0; c < 3; c++ {
+		box[c] = center[c] + mid[c]
+	}
+	return box, nil
+}
+
+// Returns three orthonormal axes, the first along the given direction, the second along the component of the other
+// given direction orthogonal to the first one and the third orthogonal to both. Degenerate directions, e.g. of flat
+// boxes, are replaced by arbitrary orthogonal ones
+func getOrthonormalAxes(first, second [3]float64) [3][3]float64 {
+	u, ok := normalizeVector(first)
+	if !ok {
+		u, ok = normalizeVector(second)
+		if !ok {
+			u = [3]float64{1, 0, 0}
+		}
+		second = crossVectors(u, [3]float64{0, 0, 1})
+	}
+	projection := dotVectors(second, u)
+	v, ok := normalizeVector([3]float64{second[0] - projection*u[0], second[1] - projection*u[1], second[2] - projection*u[2]})
+	if !ok {
+		if v, ok = normalizeVector(crossVectors(u, [3]float64{0, 0, 1})); !ok {
+			v, _ = normalizeVector(crossVectors(u, [3]float64{0, 1, 0}))
+		}
+	}
+	return [3][3]float64{u, v, crossVectors(u, v)}
+}
+
+func subtractVectors(a, b [3]float64) [3]float64 {
+	return [3]float64{a[0] - b[0], a[1] - b[1], a[2] - b[2]}
+}
+
+func dotVectors(a, b [3]float64) float64 {
+	return a[0]*b[0] + a[1]*b[1] + a[2]*b[2]
+}
+
+func crossVectors(a, b [3]float64) [3]float64 {
+	return [3]float64{a[1]*b[2] - a[2]*b[1], a[2]*b[0] - a[0]*b[2], a[0]*b[1] - a[1]*b[0]}
+}
+
+// Returns the given vector scaled to unit length and true, or false if its length is zero
+func normalizeVector(a [3]float64) ([3]float64, bool) {
+	length := math.Sqrt(dotVectors(a, a))
+	if length == 0 || math.IsNaN(length) {
+		return a, false
+	}
+	return [3]float64{a[0] / length, a[1] / length, a[2] / length}, true
+}
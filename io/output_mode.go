@@ -0,0 +1,88 @@
+package io
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/converters"
+	"github.com/mfbonfigli/gocesiumtiler/structs/geometry"
+	"github.com/mfbonfigli/gocesiumtiler/structs/octree"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"math"
+)
+
+// Coordinate converter whose ConvertToWGS84Cartesian converts to the output coordinates of the OutputMode of the
+// options rather than to EPSG:4978 ECEF coordinates, so that the points and the bounding spheres and boxes of the
+// tiles are written in the same coordinates. The other conversions, e.g. to regions, are the ones of the wrapped
+// converter
+type outputCoordinateConverter struct {
+	converters.CoordinateConverter
+	mode   tiler.OutputMode
+	origin [3]float64    // ECEF origin of the east north up frame
+	axes   [3][3]float64 // ECEF unit vectors of the east, north and up axes
+}
+
+// Returns the converter of the points of the tree of the given node to the output coordinates of the options. The east
+// north up frame of LocalEnuCoordinates is centered on the center of the bounding box of the root node, so that all
+// the tiles share it
+func getOutputConverter(node *octree.OctNode, opts *tiler.TilerOptions, converter converters.CoordinateConverter) (*outputCoordinateConverter, error) {
+	outputConverter := &outputCoordinateConverter{CoordinateConverter: converter, mode: opts.OutputMode}
+	if opts.OutputMode != tiler.LocalEnuCoordinates {
+		return outputConverter, nil
+	}
+	root := node
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	origin, err := convertToEcef(root.BoundingBox.Xmid, root.BoundingBox.Ymid, root.BoundingBox.Zmid, opts.Srid, converter)
+	if err != nil {
+		return nil, err
+	}
+	lon, lat, _ := ecefToGeodetic(origin)
+	outputConverter.origin = origin
+	outputConverter.axes = [3][3]float64{
+		{-math.Sin(lon), math.Cos(lon), 0},
+		{-math.Sin(lat) * math.Cos(lon), -math.Sin(lat) * math.Sin(lon), math.Cos(lat)},
+		{math.Cos(lat) * math.Cos(lon), math.Cos(lat) * math.Sin(lon), math.Sin(lat)},
+	}
+	return outputConverter, nil
+}
+
+func (converter *outputCoordinateConverter) ConvertToWGS84Cartesian(coord geometry.Coordinate, sourceSrid int) (geometry.Coordinate, error) {
+	if converter.mode == tiler.RawCoordinates {
+		return coord, nil
+	}
+	ecef, err := converter.CoordinateConverter.ConvertToWGS84Cartesian(coord, sourceSrid)
+	if err != nil || converter.mode != tiler.LocalEnuCoordinates {
+		return ecef, err
+	}
+	relative := [3]float64{*ecef.X - converter.origin[0], *ecef.Y - converter.origin[1], *ecef.Z - converter.origin[2]}
+	east, north, up := dotVectors(relative, converter.axes[0]), dotVectors(relative, converter.axes[1]), dotVectors(relative, converter.axes[2])
+	return geometry.Coordinate{X: &east, Y: &north, Z: &up}, nil
+}
+
+// Returns the transform of the root tile, the east north up to ECEF transform for LocalEnuCoordinates, preceded by the
+// RootTransform of the options if set, or the RootTransform alone otherwise. Nil if there is no transform
+func (converter *outputCoordinateConverter) getRootTransform(opts *tiler.TilerOptions) []float64 {
+	transform := opts.GetRootTransform()
+	if converter.mode != tiler.LocalEnuCoordinates {
+		return transform
+	}
+	a := converter.axes
+	o := converter.origin
+	enuToEcef := []float64{a[0][0], a[0][1], a[0][2], 0, a[1][0], a[1][1], a[1][2], 0, a[2][0], a[2][1], a[2][2], 0, o[0], o[1], o[2], 1}
+	if transform == nil {
+		return enuToEcef
+	}
+	return multiplyTransforms(transform, enuToEcef)
+}
+
+// Returns the product of the given column major 4x4 transforms, i.e. the transform applying b and then a
+func multiplyTransforms(a, b []float64) []float64 {
+	product := make([]float64, 16)
+	for column := 0; column < 4; column++ {
+		for row := 0; row < 4; row++ {
+			for k := 0; k < 4; k++ {
+				product[column*4+row] += a[k*4+row] * b[column*4+k]
+			}
+		}
+	}
+	return product
+}
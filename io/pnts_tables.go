@@ -0,0 +1,87 @@
+package io
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// Json header of the feature table of a content.pnts file, the semantics not stored in the binary body are omitted
+type featureTableHeader struct {
+	PointsLength          int                  `json:"POINTS_LENGTH"`
+	RtcCenter             [3]float64           `json:"RTC_CENTER"`
+	QuantizedVolumeOffset *[3]float64          `json:"QUANTIZED_VOLUME_OFFSET,omitempty"`
+	QuantizedVolumeScale  *[3]float64          `json:"QUANTIZED_VOLUME_SCALE,omitempty"`
+	Position              *BinaryBodyReference `json:"POSITION,omitempty"`
+	PositionQuantized     *BinaryBodyReference `json:"POSITION_QUANTIZED,omitempty"`
+	Normal                *BinaryBodyReference `json:"NORMAL,omitempty"`
+	Rgb                   *BinaryBodyReference `json:"RGB,omitempty"`
+	Rgba                  *BinaryBodyReference `json:"RGBA,omitempty"`
+	Rgb565                *BinaryBodyReference `json:"RGB565,omitempty"`
+	Extensions            json.RawMessage      `json:"extensions,omitempty"`
+}
+
+// A property of the batch table of a content.pnts file stored in its binary body
+type batchTableProperty struct {
+	ByteOffset    int    `json:"byteOffset"`
+	ComponentType string `json:"componentType"`
+	Type          string `json:"type"`
+}
+
+// Generates the json header of the feature table referencing the given binary body properties, padded with spaces to
+// a 4 byte boundary. The quantized volume, if not nil, and the extensions json object, if not empty, are written as
+// well
+func generateFeatureTableJson(x, y, z float64, pointNo int, volume *quantizedVolume, properties []binaryBodyProperty, extensions string) ([]byte, error) {
+	header := featureTableHeader{PointsLength: pointNo, RtcCenter: [3]float64{x, y, z}}
+	if volume != nil {
+		header.QuantizedVolumeOffset = &volume.offset
+		header.QuantizedVolumeScale = &volume.scale
+	}
+	for _, property := range properties {
+		reference := &BinaryBodyReference{ByteOffset: property.byteOffset}
+		switch property.semantic {
+		case "POSITION":
+			header.Position = reference
+		case "POSITION_QUANTIZED":
+			header.PositionQuantized = reference
+		case "NORMAL":
+			header.Normal = reference
+		case "RGB":
+			header.Rgb = reference
+		case "RGBA":
+			header.Rgba = reference
+		case "RGB565":
+			header.Rgb565 = reference
+		default:
+			return nil, errors.New("unsupported feature table semantic " + property.semantic)
+		}
+	}
+	if extensions != "" {
+		header.Extensions = json.RawMessage(extensions)
+	}
+	jsonData, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	return padBytes(jsonData, 4, ' '), nil
+}
+
+// Generates the json header of the batch table referencing the given binary body properties and writing the given
+// extensions json object, if not empty, padded with spaces to a 4 byte boundary
+func generateBatchTableJson(properties []binaryBodyProperty, extensions string) ([]byte, error) {
+	header := make(map[string]interface{})
+	for _, property := range properties {
+		header[property.semantic] = batchTableProperty{
+			ByteOffset:    property.byteOffset,
+			ComponentType: property.componentType,
+			Type:          property.propertyType,
+		}
+	}
+	if extensions != "" {
+		header["extensions"] = json.RawMessage(extensions)
+	}
+	jsonData, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	return padBytes(jsonData, 4, ' '), nil
+}
@@ -1,37 +1,135 @@
 package io
 
 import (
+	"context"
+	"fmt"
 	"github.com/mfbonfigli/gocesiumtiler/structs/octree"
 	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"github.com/mfbonfigli/gocesiumtiler/utils"
+	"os"
 	"path"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
 )
 
 // Parses an octnode and submits WorkUnits the the provided workchannel. Should be called only on the tree root OctNode.
-// Closes the channel when all work is submitted.
-func Produce(basepath string, node *octree.OctNode, opts *tiler.TilerOptions, work chan *WorkUnit, wg *sync.WaitGroup, subfolder string) {
-	produce(filepath.Join(basepath, subfolder), node, opts, work, wg)
+// If a SubtreePath is set in the options only the tiles of the subtree rooted at that path are submitted.
+// The given precomputed bounding regions, if not nil, are forwarded to the consumers.
+// If a ProgressCallback is set in the options the consumers report the written work units to it.
+// Closes the channel when all work is submitted or as soon as the given context is done.
+func Produce(ctx context.Context, basepath string, node *octree.OctNode, opts *tiler.TilerOptions, work chan *WorkUnit, wg *sync.WaitGroup, subfolder string, regions map[*octree.OctNode][]float64) {
+	var progress *utils.ProgressReporter
+	if opts.ProgressCallback != nil {
+		progress = utils.NewProgressReporter(opts.ProgressCallback, utils.ProgressStageWriting, countWorkUnits(node, opts.SubtreePath))
+	}
+	produce(ctx, filepath.Join(basepath, subfolder), node, opts, work, wg, opts.SubtreePath, regions, progress)
 	close(work)
 	wg.Done()
 }
 
-// Parses an octnode and submits WorkUnits the the provided workchannel.
-func produce(basepath string, node *octree.OctNode, opts *tiler.TilerOptions, work chan *WorkUnit, wg *sync.WaitGroup) {
+// Parses an octnode and submits WorkUnits the the provided workchannel. Nodes are skipped until the remaining
+// subtreePath, i.e. the list of octant indexes leading to the subtree to export, is fully traversed. Each tile is
+// written in its own folder inside the tileset folder. Returns false if the context is done before all the work is
+// submitted.
+func produce(ctx context.Context, tilesetFolder string, node *octree.OctNode, opts *tiler.TilerOptions, work chan *WorkUnit, wg *sync.WaitGroup, subtreePath []uint8, regions map[*octree.OctNode][]float64, progress *utils.ProgressReporter) bool {
+	if len(subtreePath) > 0 {
+		// only descend towards the requested subtree
+		child := node.Children[subtreePath[0]]
+		if child != nil && child.Initialized {
+			return produce(ctx, tilesetFolder, child, opts, work, wg, subtreePath[1:], regions, progress)
+		}
+		return true
+	}
+
 	// if node contains children (it should always be the case), then submit work
 	if node.LocalChildrenCount > 0 {
-		work <- &WorkUnit{
+		workUnit := &WorkUnit{
 			OctNode:  node,
-			BasePath: basepath,
+			BasePath: path.Join(tilesetFolder, tileFolder(node, opts)),
 			Opts:     opts,
+			Regions:  regions,
+			Progress: progress,
+		}
+		select {
+		case work <- workUnit:
+		case <-ctx.Done():
+			return false
 		}
 	}
 
 	// iterate all non nil children and recursively submit all work units
-	for i, child := range node.Children {
+	for _, child := range node.Children {
+		if child != nil && child.Initialized {
+			if !produce(ctx, tilesetFolder, child, opts, work, wg, nil, regions, progress) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Returns an error if the given subtree path, i.e. the list of octant indexes from the given node, does not lead to a
+// tile of the built tree, which produce would otherwise silently skip
+func CheckSubtreePath(node *octree.OctNode, subtreePath []uint8) error {
+	for _, octant := range subtreePath {
+		node = node.Children[octant]
+		if node == nil || !node.Initialized {
+			tokens := make([]string, len(subtreePath))
+			for i, octant := range subtreePath {
+				tokens[i] = strconv.Itoa(int(octant))
+			}
+			return fmt.Errorf("no tile at path %s", strings.Join(tokens, "/"))
+		}
+	}
+	return nil
+}
+
+// Returns the number of work units submitted by produce for the given node and subtree path
+func countWorkUnits(node *octree.OctNode, subtreePath []uint8) int64 {
+	if len(subtreePath) > 0 {
+		child := node.Children[subtreePath[0]]
 		if child != nil && child.Initialized {
-			produce(path.Join(basepath, strconv.Itoa(i)), child, opts, work, wg)
+			return countWorkUnits(child, subtreePath[1:])
+		}
+		return 0
+	}
+	var count int64
+	if node.LocalChildrenCount > 0 {
+		count++
+	}
+	for _, child := range node.Children {
+		if child != nil && child.Initialized {
+			count += countWorkUnits(child, nil)
+		}
+	}
+	return count
+}
+
+// Removes the content.pnts and tileset.json files of all the tiles of the tree rooted at the given node, written in
+// the given subfolder of the given base path, together with their resume sidecars and the tile folders left empty. Used to clean up the output
+// of an interrupted export
+func RemoveTileFiles(basepath string, node *octree.OctNode, opts *tiler.TilerOptions, subfolder string) {
+	tilesetFolder := filepath.Join(basepath, subfolder)
+	removeTileFiles(tilesetFolder, node, opts)
+}
+
+func removeTileFiles(tilesetFolder string, node *octree.OctNode, opts *tiler.TilerOptions) {
+	for _, child := range node.Children {
+		if child != nil && child.Initialized {
+			removeTileFiles(tilesetFolder, child, opts)
+		}
+	}
+	folder := tileFolder(node, opts)
+	for _, file := range []string{tileContentFile(opts), "tileset.json"} {
+		_ = os.Remove(filepath.Join(tilesetFolder, folder, file))
+		_ = os.Remove(filepath.Join(tilesetFolder, folder, file+resumeSidecarSuffix))
+	}
+	// removes the tile folder and its parents, up to the tileset folder, unless they still contain other files
+	for ; folder != "" && folder != "."; folder = path.Dir(folder) {
+		if os.Remove(filepath.Join(tilesetFolder, folder)) != nil {
+			break
 		}
 	}
 }
@@ -0,0 +1,40 @@
+package io
+
+import "math"
+
+// Largest quantized position component, positions are quantized to 16 bits per axis
+const maxQuantizedPosition = 65535
+
+// Box enclosing the quantized positions of a tile, relative to its RTC_CENTER
+type quantizedVolume struct {
+	offset [3]float64 // minimum corner of the box
+	scale  [3]float64 // size of the box along each axis
+}
+
+// Quantizes the given X, Y, Z triplets to 16 bits per axis within the box tightly enclosing them. Returns the box and
+// the quantized positions. Each position is decoded as offset + quantized * scale / 65535, so the decoded positions
+// differ from the original ones by at most scale / 131070 along each axis, e.g. 0.76 mm for a 100 m wide tile
+func quantizePositions(coords []float64) (*quantizedVolume, []uint16) {
+	volume := &quantizedVolume{
+		offset: [3]float64{math.MaxFloat64, math.MaxFloat64, math.MaxFloat64},
+	}
+	if len(coords) == 0 {
+		volume.offset = [3]float64{}
+	}
+	max := [3]float64{-math.MaxFloat64, -math.MaxFloat64, -math.MaxFloat64}
+	for i, value := range coords {
+		volume.offset[i%3] = math.Min(volume.offset[i%3], value)
+		max[i%3] = math.Max(max[i%3], value)
+	}
+	for axis := range volume.scale {
+		volume.scale[axis] = math.Max(max[axis]-volume.offset[axis], 0)
+	}
+
+	quantized := make([]uint16, len(coords))
+	for i, value := range coords {
+		if scale := volume.scale[i%3]; scale > 0 {
+			quantized[i] = uint16(math.Round((value - volume.offset[i%3]) / scale * maxQuantizedPosition))
+		}
+	}
+	return volume, quantized
+}
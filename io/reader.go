@@ -0,0 +1,329 @@
+package io
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+)
+
+// Reference to a property stored in the binary body of a feature table
+type BinaryBodyReference struct {
+	ByteOffset int `json:"byteOffset"`
+}
+
+// Subset of the content.pnts feature table json header understood by the reader
+type FeatureTable struct {
+	PointsLength          int                        `json:"POINTS_LENGTH"`
+	RtcCenter             []float64                  `json:"RTC_CENTER"`
+	Position              *BinaryBodyReference       `json:"POSITION"`
+	PositionQuantized     *BinaryBodyReference       `json:"POSITION_QUANTIZED"`
+	QuantizedVolumeOffset []float64                  `json:"QUANTIZED_VOLUME_OFFSET"`
+	QuantizedVolumeScale  []float64                  `json:"QUANTIZED_VOLUME_SCALE"`
+	Normal                *BinaryBodyReference       `json:"NORMAL"`
+	Rgb                   *BinaryBodyReference       `json:"RGB"`
+	Rgba                  *BinaryBodyReference       `json:"RGBA"`
+	Rgb565                *BinaryBodyReference       `json:"RGB565"`
+	Extensions            map[string]json.RawMessage `json:"extensions"`
+}
+
+// Decoded content of a content.pnts file
+type Pnts struct {
+	FeatureTable     FeatureTable
+	Positions        []float64 // absolute X, Y, Z triplets, i.e. with the RTC_CENTER already added
+	Colors           []uint8   // R, G, B triplets or R, G, B, A quadruplets, depending on the feature table semantic. RGB565 colors are expanded to R, G, B triplets
+	Normals          []float64 // X, Y, Z triplets of the unit normals, if present
+	BatchTable       []byte    // raw batch table json header
+	BatchTableBinary []byte    // batch table binary body, decompressed if deflate compressed
+}
+
+// Reads and decodes the content.pnts file at the given path, gzip compressed or not
+func ReadPntsFile(filePath string) (*Pnts, error) {
+	content, _, err := readTileFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return decodePnts(content)
+}
+
+// Decodes the binary content of a content.pnts file
+func decodePnts(content []byte) (*Pnts, error) {
+	if len(content) < 28 || string(content[0:4]) != "pnts" {
+		return nil, errors.New("not a valid pnts file")
+	}
+	featureTableLen := int(binary.LittleEndian.Uint32(content[12:16]))
+	featureTableBinaryLen := int(binary.LittleEndian.Uint32(content[16:20]))
+	batchTableLen := int(binary.LittleEndian.Uint32(content[20:24]))
+	if 28+featureTableLen+featureTableBinaryLen+batchTableLen > len(content) {
+		return nil, errors.New("pnts file is truncated")
+	}
+	content, err := inflatePnts(content)
+	if err != nil {
+		return nil, err
+	}
+	featureTableBinaryLen = int(binary.LittleEndian.Uint32(content[16:20]))
+	batchTableBinaryLen := int(binary.LittleEndian.Uint32(content[24:28]))
+
+	pnts := Pnts{}
+	if err := json.Unmarshal(content[28:28+featureTableLen], &pnts.FeatureTable); err != nil {
+		return nil, err
+	}
+	if _, ok := pnts.FeatureTable.Extensions[pntsDracoExtension]; ok {
+		return nil, errors.New("Draco compressed pnts files cannot be decoded")
+	}
+	batchTableStart := 28 + featureTableLen + featureTableBinaryLen
+	pnts.BatchTable = content[batchTableStart : batchTableStart+batchTableLen]
+	if batchTableStart+batchTableLen+batchTableBinaryLen <= len(content) {
+		pnts.BatchTableBinary = content[batchTableStart+batchTableLen : batchTableStart+batchTableLen+batchTableBinaryLen]
+	}
+
+	featureTableBinary := content[28+featureTableLen : batchTableStart]
+	positions, err := decodePositions(&pnts.FeatureTable, featureTableBinary)
+	if err != nil {
+		return nil, err
+	}
+	pnts.Positions = positions
+	pnts.Colors = decodeColors(&pnts.FeatureTable, featureTableBinary)
+	pnts.Normals = decodeNormals(&pnts.FeatureTable, featureTableBinary)
+
+	return &pnts, nil
+}
+
+// Decodes the float32 POSITION array, or the uint16 POSITION_QUANTIZED one, of the feature table binary body into
+// absolute float64 coordinates
+func decodePositions(featureTable *FeatureTable, featureTableBinary []byte) ([]float64, error) {
+	rtc := []float64{0, 0, 0}
+	if len(featureTable.RtcCenter) == 3 {
+		rtc = featureTable.RtcCenter
+	}
+	if featureTable.Position == nil && featureTable.PositionQuantized != nil {
+		return decodeQuantizedPositions(featureTable, featureTableBinary, rtc)
+	}
+	if featureTable.Position == nil {
+		return nil, errors.New("feature table does not contain a POSITION property")
+	}
+
+	start := featureTable.Position.ByteOffset
+	if start+featureTable.PointsLength*12 > len(featureTableBinary) {
+		return nil, errors.New("POSITION array exceeds the feature table binary length")
+	}
+	positions := make([]float64, featureTable.PointsLength*3)
+	for i := range positions {
+		offset := start + i*4
+		value := math.Float32frombits(binary.LittleEndian.Uint32(featureTableBinary[offset : offset+4]))
+		positions[i] = float64(value) + rtc[i%3]
+	}
+	return positions, nil
+}
+
+// Decodes the uint16 POSITION_QUANTIZED array of the feature table binary body into absolute float64 coordinates
+func decodeQuantizedPositions(featureTable *FeatureTable, featureTableBinary []byte, rtc []float64) ([]float64, error) {
+	if len(featureTable.QuantizedVolumeOffset) != 3 || len(featureTable.QuantizedVolumeScale) != 3 {
+		return nil, errors.New("feature table does not contain a valid quantized volume")
+	}
+	start := featureTable.PositionQuantized.ByteOffset
+	if start+featureTable.PointsLength*6 > len(featureTableBinary) {
+		return nil, errors.New("POSITION_QUANTIZED array exceeds the feature table binary length")
+	}
+	positions := make([]float64, featureTable.PointsLength*3)
+	for i := range positions {
+		offset := start + i*2
+		value := float64(binary.LittleEndian.Uint16(featureTableBinary[offset : offset+2]))
+		positions[i] = featureTable.QuantizedVolumeOffset[i%3] + value*featureTable.QuantizedVolumeScale[i%3]/65535 + rtc[i%3]
+	}
+	return positions, nil
+}
+
+// Extracts the RGB or RGBA array from the feature table binary body, if present, or expands the RGB565 one
+func decodeColors(featureTable *FeatureTable, featureTableBinary []byte) []uint8 {
+	if reference := featureTable.Rgb565; reference != nil && featureTable.Rgb == nil && featureTable.Rgba == nil {
+		if reference.ByteOffset+featureTable.PointsLength*2 > len(featureTableBinary) {
+			return nil
+		}
+		colors := make([]uint8, featureTable.PointsLength*3)
+		for i := 0; i < featureTable.PointsLength; i++ {
+			offset := reference.ByteOffset + i*2
+			packed := binary.LittleEndian.Uint16(featureTableBinary[offset : offset+2])
+			colors[i*3] = uint8(packed >> 11 * 255 / 31)
+			colors[i*3+1] = uint8(packed >> 5 & 0x3f * 255 / 63)
+			colors[i*3+2] = uint8(packed & 0x1f * 255 / 31)
+		}
+		return colors
+	}
+	reference, components := featureTable.Rgb, 3
+	if featureTable.Rgba != nil {
+		reference, components = featureTable.Rgba, 4
+	}
+	if reference == nil || reference.ByteOffset+featureTable.PointsLength*components > len(featureTableBinary) {
+		return nil
+	}
+	return featureTableBinary[reference.ByteOffset : reference.ByteOffset+featureTable.PointsLength*components]
+}
+
+// Decodes the float32 NORMAL array of the feature table binary body, if present
+func decodeNormals(featureTable *FeatureTable, featureTableBinary []byte) []float64 {
+	reference := featureTable.Normal
+	if reference == nil || reference.ByteOffset+featureTable.PointsLength*12 > len(featureTableBinary) {
+		return nil
+	}
+	normals := make([]float64, featureTable.PointsLength*3)
+	for i := range normals {
+		offset := reference.ByteOffset + i*4
+		normals[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(featureTableBinary[offset : offset+4])))
+	}
+	return normals
+}
+
+// Reads and decodes the tileset.json file at the given path, gzip compressed or not
+func ReadTilesetFile(filePath string) (*Tileset, error) {
+	content, _, err := readTileFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	tileset := Tileset{}
+	if err := json.Unmarshal(content, &tileset); err != nil {
+		return nil, err
+	}
+	return &tileset, nil
+}
+
+// Size in bytes of each point and of each component of the per point feature table semantics
+var featureTableSemanticSizes = map[string][2]int{
+	"POSITION":           {12, 4},
+	"POSITION_QUANTIZED": {6, 2},
+	"RGBA":               {4, 1},
+	"RGB":                {3, 1},
+	"RGB565":             {2, 2},
+	"NORMAL":             {12, 4},
+	"NORMAL_OCT16P":      {2, 1},
+}
+
+// Number of components of the batch table types
+var batchTableTypeComponents = map[string]int{
+	"SCALAR": 1,
+	"VEC2":   2,
+	"VEC3":   3,
+	"VEC4":   4,
+}
+
+// Byte range of an array stored in a binary body
+type binaryRange struct {
+	name       string
+	start, end int
+}
+
+// Reads the content.pnts file at the given path and validates its binary layout
+func ValidatePntsFile(filePath string) error {
+	content, _, err := readTileFile(filePath)
+	if err != nil {
+		return err
+	}
+	return ValidatePnts(content)
+}
+
+// Checks that the byte length in the header of the given content.pnts matches its size and that the arrays referenced
+// by the feature table and batch table are aligned to their component size, do not overlap and lie within the binary
+// bodies
+func ValidatePnts(content []byte) error {
+	if len(content) < 28 || string(content[0:4]) != "pnts" {
+		return errors.New("not a valid pnts file")
+	}
+	if int(binary.LittleEndian.Uint32(content[8:12])) != len(content) {
+		return errors.New("pnts byte length does not match the file size")
+	}
+	featureTableLen := int(binary.LittleEndian.Uint32(content[12:16]))
+	featureTableBinaryLen := int(binary.LittleEndian.Uint32(content[16:20]))
+	batchTableLen := int(binary.LittleEndian.Uint32(content[20:24]))
+	batchTableBinaryLen := int(binary.LittleEndian.Uint32(content[24:28]))
+	if 28+featureTableLen+featureTableBinaryLen+batchTableLen+batchTableBinaryLen != len(content) {
+		return errors.New("pnts table lengths do not match the file size")
+	}
+	// deflate compressed binary bodies are validated once decompressed
+	content, err := inflatePnts(content)
+	if err != nil {
+		return err
+	}
+	featureTableBinaryLen = int(binary.LittleEndian.Uint32(content[16:20]))
+	batchTableBinaryLen = int(binary.LittleEndian.Uint32(content[24:28]))
+
+	featureTable := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(content[28:28+featureTableLen], &featureTable); err != nil {
+		return err
+	}
+	var pointsLength int
+	if err := json.Unmarshal(featureTable["POINTS_LENGTH"], &pointsLength); err != nil {
+		return errors.New("feature table does not contain a valid POINTS_LENGTH")
+	}
+	ranges := make([]binaryRange, 0)
+	draco, err := readDracoExtension(featureTable)
+	if err != nil {
+		return err
+	}
+	if draco != nil {
+		// the compressed semantics are all stored in the Draco buffer
+		ranges = append(ranges, binaryRange{pntsDracoExtension, draco.ByteOffset, draco.ByteOffset + draco.ByteLength})
+	}
+	for semantic, sizes := range featureTableSemanticSizes {
+		if _, compressed := draco.getProperty(semantic); compressed {
+			continue
+		}
+		if raw, ok := featureTable[semantic]; ok {
+			reference := BinaryBodyReference{}
+			if err := json.Unmarshal(raw, &reference); err != nil {
+				return errors.New("invalid reference of feature table semantic " + semantic)
+			}
+			if reference.ByteOffset%sizes[1] != 0 {
+				return errors.New("feature table semantic " + semantic + " is not aligned to its component size")
+			}
+			ranges = append(ranges, binaryRange{semantic, reference.ByteOffset, reference.ByteOffset + pointsLength*sizes[0]})
+		}
+	}
+	if err := validateBinaryRanges(ranges, featureTableBinaryLen); err != nil {
+		return err
+	}
+
+	ranges = ranges[:0]
+	if batchTableLen > 0 {
+		batchTable := make(map[string]json.RawMessage)
+		batchTableStart := 28 + featureTableLen + featureTableBinaryLen
+		if err := json.Unmarshal(content[batchTableStart:batchTableStart+batchTableLen], &batchTable); err != nil {
+			return err
+		}
+		for name, raw := range batchTable {
+			property := struct {
+				ByteOffset    *int   `json:"byteOffset"`
+				ComponentType string `json:"componentType"`
+				Type          string `json:"type"`
+			}{}
+			if err := json.Unmarshal(raw, &property); err != nil || property.ByteOffset == nil {
+				// json array property
+				continue
+			}
+			componentSize, okComponentType := componentTypeSizes[property.ComponentType]
+			components, okType := batchTableTypeComponents[property.Type]
+			if !okComponentType || !okType {
+				return errors.New("invalid component type or type of batch table property " + name)
+			}
+			if *property.ByteOffset%componentSize != 0 {
+				return errors.New("batch table property " + name + " is not aligned to its component size")
+			}
+			ranges = append(ranges, binaryRange{name, *property.ByteOffset, *property.ByteOffset + pointsLength*components*componentSize})
+		}
+	}
+	return validateBinaryRanges(ranges, batchTableBinaryLen)
+}
+
+// Checks that the given ranges do not overlap and lie within a binary body of the given length
+func validateBinaryRanges(ranges []binaryRange, length int) error {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].start < ranges[j].start })
+	for i, r := range ranges {
+		if r.start < 0 || r.end > length {
+			return errors.New(r.name + " array exceeds the binary body length")
+		}
+		if i > 0 && r.start < ranges[i-1].end {
+			return errors.New(r.name + " array overlaps the " + ranges[i-1].name + " array")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package io
+
+import (
+	"errors"
+	"github.com/mfbonfigli/gocesiumtiler/converters"
+	"github.com/mfbonfigli/gocesiumtiler/structs/octree"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"math"
+	"path"
+	"strings"
+)
+
+// Tolerance, in radians and meters, used when checking the containment of bounding regions
+const regionContainmentTolerance = 1e-9
+
+// Computes the bounding region of each node of the tree rooted at the given node, expanding the region of each node
+// where needed to contain the regions of all of its children. The conversion of the octant bounding boxes to EPSG:4326
+// regions does not necessarily preserve their containment, e.g. for projected input coordinates.
+func ComputeContainingRegions(node *octree.OctNode, opts *tiler.TilerOptions, converter converters.CoordinateConverter) (map[*octree.OctNode][]float64, error) {
+	regions := make(map[*octree.OctNode][]float64)
+	_, err := computeContainingRegion(node, opts, converter, regions)
+	return regions, err
+}
+
+func computeContainingRegion(node *octree.OctNode, opts *tiler.TilerOptions, converter converters.CoordinateConverter, regions map[*octree.OctNode][]float64) ([]float64, error) {
+	region, err := convertNodeRegion(node, opts, converter)
+	if err != nil {
+		return nil, err
+	}
+	if region == nil {
+		return nil, errors.New("unable to convert the node bounding box to a region")
+	}
+	for _, child := range node.Children {
+		if child != nil && child.HasPoints() {
+			childRegion, err := computeContainingRegion(child, opts, converter, regions)
+			if err != nil {
+				return nil, err
+			}
+			region = unionOfRegions(region, childRegion)
+		}
+	}
+	regions[node] = region
+	return region, nil
+}
+
+// Converts the bounding box of the given node, or its tight one if TightBounds is set, to a region, thickening it
+// around its mid height to MinRegionHeight if thinner, e.g. for perfectly flat nodes whose min and max heights are
+// equal, which some viewers fail to cull. If a root transform is set the region bounds the transformed box
+func convertNodeRegion(node *octree.OctNode, opts *tiler.TilerOptions, converter converters.CoordinateConverter) ([]float64, error) {
+	var region []float64
+	var err error
+	if transform := opts.GetRootTransform(); transform != nil {
+		region, err = getTransformedRegion(getNodeBoundingBox(node, opts), opts.Srid, transform, converter)
+	} else {
+		region, err = converter.Convert2DBoundingboxToWGS84Region(getNodeBoundingBox(node, opts), opts.Srid)
+	}
+	if err != nil || len(region) != 6 {
+		return region, err
+	}
+	if height := region[5] - region[4]; height < opts.MinRegionHeight || math.IsNaN(height) {
+		mid := (region[4] + region[5]) / 2
+		if math.IsNaN(mid) {
+			mid = 0
+		}
+		region[4] = mid - opts.MinRegionHeight/2
+		region[5] = mid + opts.MinRegionHeight/2
+	}
+	return region, nil
+}
+
+// Returns the smallest region containing both the given west, south, east, north, min height, max height regions
+func unionOfRegions(a, b []float64) []float64 {
+	return []float64{
+		minFloat(a[0], b[0]), minFloat(a[1], b[1]),
+		maxFloat(a[2], b[2]), maxFloat(a[3], b[3]),
+		minFloat(a[4], b[4]), maxFloat(a[5], b[5]),
+	}
+}
+
+// Checks if the outer region contains the inner region
+func regionContains(outer, inner []float64) bool {
+	return inner[0] >= outer[0]-regionContainmentTolerance && inner[1] >= outer[1]-regionContainmentTolerance &&
+		inner[2] <= outer[2]+regionContainmentTolerance && inner[3] <= outer[3]+regionContainmentTolerance &&
+		inner[4] >= outer[4]-regionContainmentTolerance && inner[5] <= outer[5]+regionContainmentTolerance
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// Checks that in the tileset rooted at the given tileset.json file the bounding region of each tile is contained in
+// the bounding region of its parent, following the references to the nested tileset.json files
+func ValidateRegionContainment(tilesetFile string) error {
+	tileset, err := ReadTilesetFile(tilesetFile)
+	if err != nil {
+		return err
+	}
+	return validateRegionContainment(tilesetFile, tileset.Root.BoundingVolume.Region, tileset)
+}
+
+func validateRegionContainment(tilesetFile string, parentRegion []float64, tileset *Tileset) error {
+	rootRegion := tileset.Root.BoundingVolume.Region
+	if len(rootRegion) != 6 || !regionContains(parentRegion, rootRegion) {
+		return errors.New(tilesetFile + ": root region not contained in the region of the parent tile")
+	}
+	for _, child := range tileset.Root.Children {
+		childRegion := child.BoundingVolume.Region
+		if len(childRegion) != 6 || !regionContains(rootRegion, childRegion) {
+			return errors.New(tilesetFile + ": region of child " + child.Content.Url + " not contained in the root region")
+		}
+		if strings.HasSuffix(urlFilePath(child.Content.Url), ".json") {
+			childFile := path.Join(path.Dir(tilesetFile), urlFilePath(child.Content.Url))
+			childTileset, err := ReadTilesetFile(childFile)
+			if err != nil {
+				return err
+			}
+			if err := validateRegionContainment(childFile, childRegion, childTileset); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+// +build !windows
+
+package io
+
+import (
+	"os"
+)
+
+// Moves the given file to the given path, atomically replacing any existing file
+func replaceFile(oldPath string, newPath string) error {
+	return os.Rename(oldPath, newPath)
+}
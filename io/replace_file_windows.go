@@ -0,0 +1,38 @@
+package io
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// Windows error raised opening a file already opened by another process without sharing it
+const errorSharingViolation syscall.Errno = 32
+
+// Number of attempts and delay between them of moving a file over one opened by another process
+const (
+	replaceFileAttempts = 10
+	replaceFileDelay    = 50 * time.Millisecond
+)
+
+// Moves the given file to the given path, replacing any existing file. os.Rename replaces existing files on Windows
+// too, but fails while a reader, e.g. a tile server or an antivirus, holds the existing file open. The move is then
+// retried for a while
+func replaceFile(oldPath string, newPath string) error {
+	var err error
+	for attempt := 0; attempt < replaceFileAttempts; attempt++ {
+		if err = os.Rename(oldPath, newPath); err == nil || !isFileInUse(err) {
+			return err
+		}
+		time.Sleep(replaceFileDelay)
+	}
+	return err
+}
+
+// Returns true if the given error is raised because a file is held open by another process
+func isFileInUse(err error) bool {
+	if linkError, ok := err.(*os.LinkError); ok {
+		err = linkError.Err
+	}
+	return err == syscall.ERROR_ACCESS_DENIED || err == errorSharingViolation
+}
@@ -0,0 +1,45 @@
+package io
+
+import (
+	"fmt"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"hash/crc32"
+	"os"
+)
+
+// Suffix of the sidecar files written next to the tile files when resuming, storing their size, CRC32 checksum and
+// number of points
+const resumeSidecarSuffix = ".crc"
+
+// Returns the size and number of points of the tile file at the given path and true if Resume is set and the file was
+// completely written by a previous run, i.e. it is not empty and its size and checksum match the ones of its sidecar.
+// Files written through a custom TileWriter cannot be read back and are always written again
+func getResumedTile(filePath string, opts *tiler.TilerOptions) (int, int, bool) {
+	if !opts.Resume || opts.TileWriter != nil {
+		return 0, 0, false
+	}
+	sidecar, err := os.ReadFile(filePath + resumeSidecarSuffix)
+	if err != nil {
+		return 0, 0, false
+	}
+	var size, pointNo int
+	var checksum uint32
+	if _, err := fmt.Sscanf(string(sidecar), "%d %x %d", &size, &checksum, &pointNo); err != nil {
+		return 0, 0, false
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil || len(data) == 0 || len(data) != size || crc32.ChecksumIEEE(data) != checksum {
+		return 0, 0, false
+	}
+	return size, pointNo, true
+}
+
+// Writes the sidecar of the given tile file data storing the given number of points, if Resume is set. The sidecar is
+// written after the tile file, so that interrupted writes are detected by the missing sidecar
+func writeResumeSidecar(writer tiler.TileWriter, filePath string, data []byte, pointNo int, opts *tiler.TilerOptions) error {
+	if !opts.Resume || opts.TileWriter != nil {
+		return nil
+	}
+	sidecar := fmt.Sprintf("%d %08x %d\n", len(data), crc32.ChecksumIEEE(data), pointNo)
+	return writer.WriteFile(filePath+resumeSidecarSuffix, []byte(sidecar))
+}
@@ -0,0 +1,102 @@
+package s3_tile_writer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Client uploading the objects to S3, e.g. a *s3.Client
+type S3PutObjectAPI interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+// TileWriter uploading the tiles to an S3 bucket as they are produced, so that nothing is written on the local disk.
+// The object keys are the paths of the files relative to the output folder of the options, under the given prefix
+type S3TileWriter struct {
+	client       S3PutObjectAPI
+	bucket       string
+	prefix       string
+	cacheControl string
+	output       string
+	gzip         bool
+	semaphore    chan struct{} // Bounds the number of concurrent uploads
+}
+
+// Instances a new S3TileWriter uploading the tiles written in the Output folder of the given options to the given
+// bucket under the given key prefix, setting the given Cache-Control header if not empty. Up to NumWriters uploads, or
+// one per CPU if not set, run concurrently. If GzipOutput is set the objects are uploaded with Content-Encoding: gzip
+func NewS3TileWriter(client S3PutObjectAPI, bucket, prefix, cacheControl string, opts *tiler.TilerOptions) *S3TileWriter {
+	numUploads := opts.NumWriters
+	if numUploads <= 0 {
+		numUploads = runtime.NumCPU()
+	}
+	return &S3TileWriter{
+		client:       client,
+		bucket:       bucket,
+		prefix:       strings.Trim(prefix, "/"),
+		cacheControl: cacheControl,
+		output:       opts.Output,
+		gzip:         opts.GzipOutput,
+		semaphore:    make(chan struct{}, numUploads),
+	}
+}
+
+func (writer *S3TileWriter) WriteFile(filePath string, data []byte) error {
+	key, err := writer.getKey(filePath)
+	if err != nil {
+		return err
+	}
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(writer.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String(getContentType(filePath)),
+	}
+	if writer.cacheControl != "" {
+		input.CacheControl = aws.String(writer.cacheControl)
+	}
+	if writer.gzip {
+		input.ContentEncoding = aws.String("gzip")
+	}
+
+	writer.semaphore <- struct{}{}
+	defer func() { <-writer.semaphore }()
+	if _, err := writer.client.PutObject(context.Background(), input); err != nil {
+		return errors.New("error uploading " + key + " to bucket " + writer.bucket + ": " + err.Error())
+	}
+	return nil
+}
+
+// Object storages have no folders, nothing to create
+func (writer *S3TileWriter) MkdirAll(folder string) error {
+	return nil
+}
+
+// Returns the object key of the given file, i.e. its path relative to the output folder under the key prefix
+func (writer *S3TileWriter) getKey(filePath string) (string, error) {
+	relative, err := filepath.Rel(writer.output, filePath)
+	if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return "", errors.New("file " + filePath + " is not in the output folder " + writer.output)
+	}
+	return path.Join(writer.prefix, filepath.ToSlash(relative)), nil
+}
+
+// Returns the Content-Type of the given tile file
+func getContentType(filePath string) string {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".json":
+		return "application/json"
+	case ".glb":
+		return "model/gltf-binary"
+	default:
+		return "application/octet-stream"
+	}
+}
@@ -0,0 +1,24 @@
+package io
+
+import (
+	"encoding/json"
+	"github.com/mfbonfigli/gocesiumtiler/structs/point_loader"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"path"
+)
+
+// Writes the given statistics as a statistics.json file in the given folder
+func WriteStatisticsFile(statistics point_loader.Statistics, opts *tiler.TilerOptions, folder string) error {
+	// Create base folder if it does not exist
+	writer := getTileWriter(opts)
+	if err := writer.MkdirAll(folder); err != nil {
+		return err
+	}
+
+	jsonData, err := json.MarshalIndent(statistics, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return writer.WriteFile(path.Join(folder, "statistics.json"), jsonData)
+}
@@ -0,0 +1,198 @@
+package io
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"github.com/mfbonfigli/gocesiumtiler/structs/octree"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"path"
+	"strconv"
+)
+
+// Availability of the tiles, contents and child subtrees of a 3D Tiles 1.1 implicit octree subtree. Bits are indexed
+// as per the spec: tile and content bits by level offset plus Morton index of the tile within its level, child subtree
+// bits by Morton index of the child subtree root within the level below the last subtree level.
+type SubtreeAvailability struct {
+	Tile         []bool
+	Content      []bool
+	ChildSubtree []bool
+}
+
+// Json representation of an availability, either as a bitstream stored in a buffer view or as a constant
+type subtreeAvailabilityJson struct {
+	Bitstream      *int `json:"bitstream,omitempty"`
+	AvailableCount *int `json:"availableCount,omitempty"`
+	Constant       *int `json:"constant,omitempty"`
+}
+
+type subtreeBufferJson struct {
+	ByteLength int `json:"byteLength"`
+}
+
+type subtreeBufferViewJson struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+}
+
+type subtreeJson struct {
+	Buffers                  []subtreeBufferJson       `json:"buffers,omitempty"`
+	BufferViews              []subtreeBufferViewJson   `json:"bufferViews,omitempty"`
+	TileAvailability         subtreeAvailabilityJson   `json:"tileAvailability"`
+	ContentAvailability      []subtreeAvailabilityJson `json:"contentAvailability"`
+	ChildSubtreeAvailability subtreeAvailabilityJson   `json:"childSubtreeAvailability"`
+}
+
+// Returns the number of tiles stored in an octree subtree with the given number of levels
+func subtreeTileCount(levels int) int {
+	return ((1 << (3 * uint(levels))) - 1) / 7
+}
+
+// Computes the availability of the octree subtree rooted at the given node spanning the given number of levels
+func ComputeSubtreeAvailability(node *octree.OctNode, levels int) SubtreeAvailability {
+	availability := SubtreeAvailability{
+		Tile:         make([]bool, subtreeTileCount(levels)),
+		Content:      make([]bool, subtreeTileCount(levels)),
+		ChildSubtree: make([]bool, 1<<(3*uint(levels))),
+	}
+	fillSubtreeAvailability(node, 0, 0, levels, &availability)
+	return availability
+}
+
+// Recursively marks the given node, located at the given relative level and Morton index, and its descendants as
+// available. As the octant of a child is the interleaving of its x, y, z bits, the Morton index of a child is
+// obtained appending its octant to the Morton index of its parent, whatever the OctantOrder of the child indexes.
+func fillSubtreeAvailability(node *octree.OctNode, level int, morton int, levels int, availability *SubtreeAvailability) {
+	if level == levels {
+		availability.ChildSubtree[morton] = true
+		return
+	}
+	index := subtreeTileCount(level) + morton
+	availability.Tile[index] = true
+	availability.Content[index] = node.LocalChildrenCount > 0
+	for i, child := range node.Children {
+		if child != nil && child.Initialized {
+			octant := int(node.Opts.GetChildOctant(uint8(i)))
+			fillSubtreeAvailability(child, level+1, morton<<3+octant, levels, availability)
+		}
+	}
+}
+
+// Encodes the given availability into the binary .subtree format of 3D Tiles 1.1. Availabilities with all bits equal
+// are encoded as constants, the others as bitstreams stored in the internal buffer
+func EncodeSubtree(availability SubtreeAvailability) ([]byte, error) {
+	binaryBody := make([]byte, 0)
+	subtree := subtreeJson{}
+	encode := func(bits []bool) subtreeAvailabilityJson {
+		count := 0
+		for _, bit := range bits {
+			if bit {
+				count++
+			}
+		}
+		if count == 0 || count == len(bits) {
+			constant := 0
+			if count > 0 {
+				constant = 1
+			}
+			return subtreeAvailabilityJson{Constant: &constant}
+		}
+		bitstream := len(subtree.BufferViews)
+		subtree.BufferViews = append(subtree.BufferViews, subtreeBufferViewJson{
+			Buffer:     0,
+			ByteOffset: len(binaryBody),
+			ByteLength: (len(bits) + 7) / 8,
+		})
+		binaryBody = append(binaryBody, padBytes(packBits(bits), 8, 0)...)
+		return subtreeAvailabilityJson{Bitstream: &bitstream, AvailableCount: &count}
+	}
+	subtree.TileAvailability = encode(availability.Tile)
+	subtree.ContentAvailability = []subtreeAvailabilityJson{encode(availability.Content)}
+	subtree.ChildSubtreeAvailability = encode(availability.ChildSubtree)
+	if len(binaryBody) > 0 {
+		subtree.Buffers = []subtreeBufferJson{{ByteLength: len(binaryBody)}}
+	}
+
+	jsonBytes, err := json.Marshal(subtree)
+	if err != nil {
+		return nil, err
+	}
+	jsonBytes = padBytes(jsonBytes, 8, ' ')
+
+	header := make([]byte, 24)
+	copy(header[0:4], "subt")
+	binary.LittleEndian.PutUint32(header[4:8], 1)
+	binary.LittleEndian.PutUint64(header[8:16], uint64(len(jsonBytes)))
+	binary.LittleEndian.PutUint64(header[16:24], uint64(len(binaryBody)))
+
+	output := append(header, jsonBytes...)
+	return append(output, binaryBody...), nil
+}
+
+// Packs the given bits in a byte array, storing the first bit in the least significant bit of the first byte
+func packBits(bits []bool) []byte {
+	packed := make([]byte, (len(bits)+7)/8)
+	for i, bit := range bits {
+		if bit {
+			packed[i/8] |= 1 << uint(i%8)
+		}
+	}
+	return packed
+}
+
+// Pads the given byte array with the given byte until its length is a multiple of the given alignment
+func padBytes(b []byte, alignment int, padding byte) []byte {
+	if remainder := len(b) % alignment; remainder != 0 {
+		b = append(b, bytes.Repeat([]byte{padding}, alignment-remainder)...)
+	}
+	return b
+}
+
+// Writes the .subtree files describing the implicit octree rooted at the given node, each spanning the given number
+// of levels, in the subtrees folder of the given output folder through the TileWriter of the given options, gzip
+// compressed if GzipOutput is set. Files are named subtrees/{level}/{x}/{y}/{z}.subtree
+func WriteSubtreeFiles(node *octree.OctNode, outputFolder string, levels int, opts *tiler.TilerOptions) error {
+	if levels < 1 {
+		return errors.New("subtrees must span at least one level")
+	}
+	return writeSubtreeFile(node, outputFolder, levels, getTileWriter(opts), opts)
+}
+
+// Writes the .subtree file of the subtree rooted at the given node, named after its implicit coordinates, then
+// recurses into the child subtrees
+func writeSubtreeFile(node *octree.OctNode, outputFolder string, levels int, writer tiler.TileWriter, opts *tiler.TilerOptions) error {
+	content, err := EncodeSubtree(ComputeSubtreeAvailability(node, levels))
+	if err != nil {
+		return err
+	}
+	content, err = compressTileData(content, opts)
+	if err != nil {
+		return err
+	}
+	level, x, y, z := node.GetImplicitCoordinates()
+	folder := path.Join(outputFolder, "subtrees", strconv.Itoa(level), strconv.Itoa(x), strconv.Itoa(y))
+	if err := writer.MkdirAll(folder); err != nil {
+		return err
+	}
+	if err := writer.WriteFile(path.Join(folder, strconv.Itoa(z)+".subtree"), content); err != nil {
+		return err
+	}
+	return writeChildSubtreeFiles(node, outputFolder, levels, writer, opts, 0)
+}
+
+// Descends the given number of levels below the subtree root and writes the child subtrees found there
+func writeChildSubtreeFiles(node *octree.OctNode, outputFolder string, levels int, writer tiler.TileWriter, opts *tiler.TilerOptions, depth int) error {
+	if depth == levels {
+		return writeSubtreeFile(node, outputFolder, levels, writer, opts)
+	}
+	for _, child := range node.Children {
+		if child != nil && child.Initialized {
+			if err := writeChildSubtreeFiles(child, outputFolder, levels, writer, opts, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package io
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/structs/octree"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Returns the folder, relative to the tileset folder, storing the files of the tile of the given node. By default
+// tiles are nested in folders named after their octant index, e.g. 0/3/5, or after their level and x, y, z indexes
+// with the hierarchical naming strategy, e.g. 1_0_1_1/2_1_3_2. If DepthFolders is set tiles are stored in a folder
+// per depth, named after the octant indexes leading to them from the root, e.g. L4/035, or after the level and
+// indexes of the tile, e.g. L4/3_2_7_5. The root tile is stored in the tileset folder itself. With implicit tiling
+// every tile, including the root, is stored in the content/{level}/{x}/{y}/{z} folder of the content template
+func tileFolder(node *octree.OctNode, opts *tiler.TilerOptions) string {
+	if opts.ImplicitTiling {
+		level, x, y, z := node.GetImplicitCoordinates()
+		return path.Join("content", strconv.Itoa(level), strconv.Itoa(x), strconv.Itoa(y), strconv.Itoa(z))
+	}
+	names := make([]string, 0, node.Depth)
+	for ; node.Parent != nil; node = node.Parent {
+		if opts.TileNamingStrategy == tiler.HierarchicalTileNames {
+			level, x, y, z := node.GetImplicitCoordinates()
+			names = append(names, strconv.Itoa(level)+"_"+strconv.Itoa(x)+"_"+strconv.Itoa(y)+"_"+strconv.Itoa(z))
+			continue
+		}
+		for i, sibling := range node.Parent.Children {
+			if sibling == node {
+				names = append(names, strconv.Itoa(i))
+				break
+			}
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	for i, j := 0, len(names)-1; i < j; i, j = i+1, j-1 {
+		names[i], names[j] = names[j], names[i]
+	}
+	if opts.DepthFolders {
+		depthFolder := "L" + strconv.Itoa(len(names)+1)
+		if opts.TileNamingStrategy == tiler.HierarchicalTileNames {
+			return path.Join(depthFolder, names[len(names)-1])
+		}
+		return path.Join(depthFolder, strings.Join(names, ""))
+	}
+	return path.Join(names...)
+}
+
+// Returns the url of the given file of the tile of the child node, relative to the folder of the tile of its parent
+func childTileUrl(parent *octree.OctNode, child *octree.OctNode, opts *tiler.TilerOptions, filename string) string {
+	parentFolder := tileFolder(parent, opts)
+	childFolder := tileFolder(child, opts)
+	if parentFolder == "" {
+		return path.Join(childFolder, filename)
+	}
+	if !opts.DepthFolders {
+		// nested folders, the child folder is always inside the parent one
+		return path.Join(strings.TrimPrefix(childFolder, parentFolder+"/"), filename)
+	}
+	return strings.Repeat("../", strings.Count(parentFolder, "/")+1) + path.Join(childFolder, filename)
+}
+
+// Returns the name of the content file of each tile, content.glb for glTF output and content.pnts otherwise
+func tileContentFile(opts *tiler.TilerOptions) string {
+	if opts.OutputFormat == tiler.GlbOutput {
+		return "content.glb"
+	}
+	return "content.pnts"
+}
+
+// Appends the query string of the options, if any, to the given content url, e.g. to bust the CDN caches of the
+// tiles on each deploy
+func WithUrlQuery(url string, opts *tiler.TilerOptions) string {
+	query := strings.TrimPrefix(opts.UrlQuery, "?")
+	if query == "" {
+		return url
+	}
+	return url + "?" + query
+}
+
+// Returns the path of the file referenced by the given content url, i.e. the url without its query string
+func urlFilePath(url string) string {
+	if i := strings.IndexByte(url, '?'); i >= 0 {
+		return url[:i]
+	}
+	return url
+}
@@ -0,0 +1,33 @@
+package io
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"os"
+)
+
+// TileWriter writing the tiles to the local disk, with the file and folder permission bits of the given options and,
+// if AtomicWrites is set, through temporary files
+type OSTileWriter struct {
+	opts *tiler.TilerOptions
+}
+
+// Instances a new OSTileWriter with the given options
+func NewOSTileWriter(opts *tiler.TilerOptions) *OSTileWriter {
+	return &OSTileWriter{opts: opts}
+}
+
+func (writer *OSTileWriter) WriteFile(filePath string, data []byte) error {
+	return writeTileFile(filePath, data, writer.opts.GetFileMode(), writer.opts)
+}
+
+func (writer *OSTileWriter) MkdirAll(folder string) error {
+	return os.MkdirAll(folder, writer.opts.GetDirMode())
+}
+
+// Returns the TileWriter of the given options, an OSTileWriter if not set
+func getTileWriter(opts *tiler.TilerOptions) tiler.TileWriter {
+	if opts.TileWriter != nil {
+		return opts.TileWriter
+	}
+	return NewOSTileWriter(opts)
+}
@@ -9,7 +9,9 @@ type Content struct {
 }
 
 type BoundingVolume struct {
-	Region []float64 `json:"region"`
+	Region []float64 `json:"region,omitempty"`
+	Sphere []float64 `json:"sphere,omitempty"`
+	Box    []float64 `json:"box,omitempty"`
 }
 
 type Child struct {
@@ -25,10 +27,37 @@ type Root struct {
 	BoundingVolume BoundingVolume `json:"boundingVolume"`
 	GeometricError float64        `json:"geometricError"`
 	Refine         string         `json:"refine"`
+	Transform      []float64      `json:"transform,omitempty"`
 }
 
 type Tileset struct {
-	Asset          Asset   `json:"asset"`
-	GeometricError float64 `json:"geometricError"`
-	Root           Root    `json:"root"`
+	Asset              Asset    `json:"asset"`
+	ExtensionsUsed     []string `json:"extensionsUsed,omitempty"`
+	ExtensionsRequired []string `json:"extensionsRequired,omitempty"`
+	GeometricError     float64  `json:"geometricError"`
+	Root               Root     `json:"root"`
+}
+
+type ImplicitTiling struct {
+	SubdivisionScheme string  `json:"subdivisionScheme"`
+	SubtreeLevels     int     `json:"subtreeLevels"`
+	AvailableLevels   int     `json:"availableLevels"`
+	Subtrees          Content `json:"subtrees"`
+}
+
+type ImplicitRoot struct {
+	Content        Content        `json:"content"`
+	BoundingVolume BoundingVolume `json:"boundingVolume"`
+	GeometricError float64        `json:"geometricError"`
+	Refine         string         `json:"refine"`
+	Transform      []float64      `json:"transform,omitempty"`
+	ImplicitTiling ImplicitTiling `json:"implicitTiling"`
+}
+
+type ImplicitTileset struct {
+	Asset              Asset        `json:"asset"`
+	ExtensionsUsed     []string     `json:"extensionsUsed,omitempty"`
+	ExtensionsRequired []string     `json:"extensionsRequired,omitempty"`
+	GeometricError     float64      `json:"geometricError"`
+	Root               ImplicitRoot `json:"root"`
 }
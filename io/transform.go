@@ -0,0 +1,59 @@
+package io
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/converters"
+	"github.com/mfbonfigli/gocesiumtiler/structs/geometry"
+	"math"
+)
+
+// WGS84 ellipsoid semi major axis, in meters, and squared eccentricity
+const (
+	wgs84SemiMajorAxis = 6378137.0
+	wgs84Eccentricity2 = 6.69437999014e-3
+)
+
+// Computes the bounding region of the given bounding box, expressed in the given srid, once its points are converted
+// to EPSG:4978 ECEF coordinates and moved by the given column major 4x4 transform. As the bounding regions of 3D Tiles
+// are not affected by the tile transforms, this keeps the regions consistent with the transformed content. Corners,
+// edge midpoints and face centers are measured, as in the bounding spheres
+func getTransformedRegion(bbox *geometry.BoundingBox, srid int, transform []float64, converter converters.CoordinateConverter) ([]float64, error) {
+	region := []float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1), math.Inf(1), math.Inf(-1)}
+	for _, x := range []float64{bbox.Xmin, bbox.Xmid, bbox.Xmax} {
+		for _, y := range []float64{bbox.Ymin, bbox.Ymid, bbox.Ymax} {
+			for _, z := range []float64{bbox.Zmin, bbox.Zmid, bbox.Zmax} {
+				point, err := convertToEcef(x, y, z, srid, converter)
+				if err != nil {
+					return nil, err
+				}
+				lon, lat, height := ecefToGeodetic(applyTransform(transform, point))
+				region = unionOfRegions(region, []float64{lon, lat, lon, lat, height, height})
+			}
+		}
+	}
+	return region, nil
+}
+
+// Applies the given column major 4x4 transform to the given point
+func applyTransform(transform []float64, point [3]float64) [3]float64 {
+	var transformed [3]float64
+	for i := range transformed {
+		transformed[i] = transform[i]*point[0] + transform[4+i]*point[1] + transform[8+i]*point[2] + transform[12+i]
+	}
+	return transformed
+}
+
+// Converts the given EPSG:4978 ECEF point to WGS84 longitude and latitude, in radians, and ellipsoidal height, in meters
+func ecefToGeodetic(point [3]float64) (lon, lat, height float64) {
+	x, y, z := point[0], point[1], point[2]
+	p := math.Hypot(x, y)
+	lon = math.Atan2(y, x)
+	lat = math.Atan2(z, p*(1-wgs84Eccentricity2))
+	var n float64
+	for i := 0; i < 10; i++ {
+		n = wgs84SemiMajorAxis / math.Sqrt(1-wgs84Eccentricity2*math.Sin(lat)*math.Sin(lat))
+		lat = math.Atan2(z+wgs84Eccentricity2*n*math.Sin(lat), p)
+	}
+	n = wgs84SemiMajorAxis / math.Sqrt(1-wgs84Eccentricity2*math.Sin(lat)*math.Sin(lat))
+	height = p*math.Cos(lat) + (z+wgs84Eccentricity2*n*math.Sin(lat))*math.Sin(lat) - n
+	return lon, lat, height
+}
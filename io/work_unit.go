@@ -3,6 +3,7 @@ package io
 import (
 	"github.com/mfbonfigli/gocesiumtiler/structs/octree"
 	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"github.com/mfbonfigli/gocesiumtiler/utils"
 )
 
 // Contains the minimal data needed to produce a single 3d tile, i.e. a binary content.pnts file and a tileset.json file
@@ -10,4 +11,6 @@ type WorkUnit struct {
 	OctNode  *octree.OctNode
 	Opts     *tiler.TilerOptions
 	BasePath string
+	Regions  map[*octree.OctNode][]float64 // Precomputed bounding regions of the nodes, nil to compute them on the fly
+	Progress *utils.ProgressReporter       // Counts the written work units, nil if no progress is reported
 }
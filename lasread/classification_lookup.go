@@ -0,0 +1,30 @@
+package lidario
+
+import (
+	"strings"
+)
+
+// User id and record id of the VLR naming the classification codes of the points
+const (
+	classificationLookupUserID   = "LASF_Spec"
+	classificationLookupRecordID = 0
+)
+
+// Length of each entry of the Classification Lookup VLR, a class number followed by a 15 characters description
+const classificationLookupEntryLength = 16
+
+// Parses the content of the Classification Lookup VLR into the names of the classification codes. Entries with an
+// empty description are skipped
+func parseClassificationLookupVlr(b []byte) map[uint8]string {
+	names := make(map[uint8]string)
+	for offset := 0; offset+classificationLookupEntryLength <= len(b); offset += classificationLookupEntryLength {
+		description := b[offset+1 : offset+classificationLookupEntryLength]
+		if i := strings.IndexByte(string(description), 0); i >= 0 {
+			description = description[:i]
+		}
+		if name := strings.TrimSpace(string(description)); name != "" {
+			names[b[offset]] = name
+		}
+	}
+	return names
+}
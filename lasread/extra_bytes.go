@@ -0,0 +1,143 @@
+package lidario
+
+import (
+	"encoding/binary"
+	"math"
+	"strings"
+)
+
+// User id and record id of the VLR describing the extra bytes appended to the point records
+const (
+	extraBytesUserID   = "LASF_Spec"
+	extraBytesRecordID = 4
+)
+
+// Length of each descriptor of the Extra Bytes VLR
+const extraBytesDescriptorLength = 192
+
+// Size in bytes of the scalar data types of the Extra Bytes VLR, indexed by data type. Types 11 to 20 and 21 to 30 are
+// arrays of 2 and 3 values of the types 1 to 10
+var extraBytesTypeSizes = [11]int{0, 1, 1, 2, 2, 4, 4, 8, 8, 4, 8}
+
+// Batch table component types of the scalar data types of the Extra Bytes VLR, indexed by data type. 64 bit integers,
+// having no batch table component type, are written as doubles
+var extraBytesComponentTypes = [11]string{"", "UNSIGNED_BYTE", "BYTE", "UNSIGNED_SHORT", "SHORT", "UNSIGNED_INT", "INT", "DOUBLE", "DOUBLE", "FLOAT", "DOUBLE"}
+
+// A per point attribute stored in the extra bytes of the point records, as declared by the Extra Bytes VLR
+type ExtraBytesDescriptor struct {
+	Name         string
+	DataType     uint8   // Data type of the Extra Bytes VLR, 1 to 10 for scalars, 0 for undocumented bytes, 11 to 30 for arrays
+	RecordOffset int     // Byte offset of the attribute within the point record
+	Size         int     // Size in bytes of the attribute within the point record
+	Scale        float64 // Scale of the stored values, 1 if not declared
+	Offset       float64 // Offset of the stored values, 0 if not declared
+}
+
+// Parses the content of the Extra Bytes VLR into the descriptors of the attributes stored at the end of the point
+// records of the given length and point format. Returns nil if the attributes do not fit in the records after the
+// fields of the point format
+func parseExtraBytesVlr(b []byte, recordLength int, formatID byte) []ExtraBytesDescriptor {
+	descriptors := make([]ExtraBytesDescriptor, 0)
+	size := 0
+	for offset := 0; offset+extraBytesDescriptorLength <= len(b); offset += extraBytesDescriptorLength {
+		d := b[offset : offset+extraBytesDescriptorLength]
+		name := string(d[4:36])
+		if i := strings.IndexByte(name, 0); i >= 0 {
+			name = name[:i]
+		}
+		descriptor := ExtraBytesDescriptor{Name: strings.TrimSpace(name), DataType: d[2], Scale: 1}
+		options := d[3]
+		switch {
+		case descriptor.DataType == 0:
+			// undocumented extra bytes, the options store their number
+			descriptor.Size = int(options)
+		case descriptor.DataType <= 10:
+			descriptor.Size = extraBytesTypeSizes[descriptor.DataType]
+		case descriptor.DataType <= 30:
+			descriptor.Size = extraBytesTypeSizes[(descriptor.DataType-1)%10+1] * int((descriptor.DataType-1)/10+1)
+		default:
+			return nil
+		}
+		if descriptor.DataType > 0 && options&0x08 != 0 {
+			descriptor.Scale = math.Float64frombits(binary.LittleEndian.Uint64(d[112:120]))
+		}
+		if descriptor.DataType > 0 && options&0x10 != 0 {
+			descriptor.Offset = math.Float64frombits(binary.LittleEndian.Uint64(d[136:144]))
+		}
+		descriptor.RecordOffset = size
+		size += descriptor.Size
+		descriptors = append(descriptors, descriptor)
+	}
+	// the extra bytes follow the fields of the point format, at the end of the records
+	if size > recordLength-getMinPointRecordLength(formatID) {
+		return nil
+	}
+	for i := range descriptors {
+		descriptors[i].RecordOffset += recordLength - size
+	}
+	return descriptors
+}
+
+// Returns the min length of the point records of the given point format, i.e. without the optional intensity and
+// user data of the legacy formats
+func getMinPointRecordLength(formatID byte) int {
+	switch {
+	case formatID <= 3:
+		return legacyPointRecordLengths[formatID][3]
+	case formatID >= 6 && formatID <= 10:
+		return extendedPointRecordLengths[formatID-6]
+	}
+	return 0
+}
+
+// Returns the total size in bytes of the given extra bytes attributes
+func getExtraBytesLength(descriptors []ExtraBytesDescriptor) int {
+	length := 0
+	for _, descriptor := range descriptors {
+		length += descriptor.Size
+	}
+	return length
+}
+
+// Returns the batch table component type of the values of the attribute, doubles if they are scaled or offset, or an
+// empty string for undocumented bytes and arrays
+func (descriptor ExtraBytesDescriptor) ComponentType() string {
+	if descriptor.DataType == 0 || descriptor.DataType > 10 {
+		return ""
+	}
+	if descriptor.Scale != 1 || descriptor.Offset != 0 {
+		return "DOUBLE"
+	}
+	return extraBytesComponentTypes[descriptor.DataType]
+}
+
+// Decodes the value of the scalar attribute from the given point record, applying its scale and offset
+func (descriptor ExtraBytesDescriptor) decode(record []byte) float64 {
+	b := record[descriptor.RecordOffset : descriptor.RecordOffset+descriptor.Size]
+	var value float64
+	switch descriptor.DataType {
+	case 1:
+		value = float64(b[0])
+	case 2:
+		value = float64(int8(b[0]))
+	case 3:
+		value = float64(binary.LittleEndian.Uint16(b))
+	case 4:
+		value = float64(int16(binary.LittleEndian.Uint16(b)))
+	case 5:
+		value = float64(binary.LittleEndian.Uint32(b))
+	case 6:
+		value = float64(int32(binary.LittleEndian.Uint32(b)))
+	case 7:
+		value = float64(binary.LittleEndian.Uint64(b))
+	case 8:
+		value = float64(int64(binary.LittleEndian.Uint64(b)))
+	case 9:
+		value = float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	case 10:
+		value = math.Float64frombits(binary.LittleEndian.Uint64(b))
+	default:
+		return math.NaN()
+	}
+	return value*descriptor.Scale + descriptor.Offset
+}
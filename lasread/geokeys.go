@@ -109,6 +109,33 @@ func (gk *GeoKeys) interpretGeokeys() string {
 	return buffer.String()
 }
 
+// GeoKey ids of the EPSG codes of the geographic and projected coordinate systems
+const (
+	geographicTypeGeoKey  = 2048
+	projectedCSTypeGeoKey = 3072
+	userDefinedGeoKey     = 32767
+)
+
+// Returns the EPSG code of the projected coordinate system declared by the GeoKeys or, if none, of the geographic
+// one. Returns 0 if no EPSG code is declared, e.g. for user defined coordinate systems
+func (gk *GeoKeys) getEpsgCode() int {
+	if len(gk.GeoKeyDirectory) < 4 {
+		return 0
+	}
+	codes := make(map[uint16]int)
+	for i := 1; i <= int(gk.GeoKeyDirectory[3]) && 4*i+3 < len(gk.GeoKeyDirectory); i++ {
+		entry := gk.GeoKeyDirectory[4*i : 4*i+4]
+		// short values are stored in the directory itself, flagged by a zero tag location
+		if entry[1] == 0 && entry[3] != 0 && entry[3] != userDefinedGeoKey {
+			codes[entry[0]] = int(entry[3])
+		}
+	}
+	if code, ok := codes[projectedCSTypeGeoKey]; ok {
+		return code
+	}
+	return codes[geographicTypeGeoKey]
+}
+
 // errors types
 var errUnsupportedDataType = errors.New("Unsupported data type")
 
@@ -0,0 +1,27 @@
+package lidario
+
+import (
+	"errors"
+	"github.com/mfbonfigli/gocesiumtiler/converters/proj4_coordinate_converter"
+	"path/filepath"
+	"strconv"
+)
+
+// Returns the srid of the coordinate system declared by the GeoKey or WKT VLRs of the given file. Fails if the file
+// declares none or if the GeoKeys and the WKT declare different EPSG codes
+func (lasFileLoader *LasFileLoader) detectSrid(las *LasFile) (int, error) {
+	fileName := filepath.Base(las.fileName)
+	epsg := las.Header.Epsg
+	if las.Header.Wkt != "" {
+		if wktEpsg, ok := proj4_coordinate_converter.GetWktEpsgCode(las.Header.Wkt); ok && epsg != 0 && wktEpsg != epsg {
+			return 0, errors.New(fileName + ": the GeoKeys declare EPSG:" + strconv.Itoa(epsg) + " while the WKT declares EPSG:" + strconv.Itoa(wktEpsg) + ", the srid must be set")
+		}
+		if epsg == 0 {
+			return lasFileLoader.CoordinateConverter.GetWktSrid(las.Header.Wkt)
+		}
+	}
+	if epsg == 0 {
+		return 0, errors.New(fileName + ": no coordinate system declared by GeoKey or WKT VLRs, the srid must be set")
+	}
+	return epsg, nil
+}
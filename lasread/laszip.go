@@ -0,0 +1,255 @@
+package lidario
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+)
+
+// User id and record id of the VLR describing the compression of LAZ files
+const (
+	laszipUserID   = "laszip encoded"
+	laszipRecordID = 22204
+)
+
+// LASzip compressors
+const (
+	laszipCompressorPointwise        = 1
+	laszipCompressorPointwiseChunked = 2
+)
+
+// LASzip item types supported by the decompressor
+const (
+	laszipItemByte      = 0
+	laszipItemPoint10   = 6
+	laszipItemGpsTime11 = 7
+	laszipItemRgb12     = 8
+)
+
+// Chunk size of the files whose chunks have variable number of points, listed in the chunk table
+const laszipVariableChunkSize = math.MaxUint32
+
+// An item of a LASzip compressed point record, e.g. the POINT10 fields or the RGB12 colors
+type laszipItem struct {
+	itemType uint16
+	size     uint16
+	version  uint16
+}
+
+// Compression parameters of a LAZ file, stored in its laszip encoded VLR
+type laszipParameters struct {
+	compressor uint16
+	coder      uint16
+	chunkSize  uint32
+	items      []laszipItem
+}
+
+// Parses the content of the laszip encoded VLR
+func parseLaszipVlr(b []byte) (*laszipParameters, error) {
+	if len(b) < 34 {
+		return nil, errors.New("invalid laszip VLR")
+	}
+	parameters := laszipParameters{
+		compressor: binary.LittleEndian.Uint16(b[0:2]),
+		coder:      binary.LittleEndian.Uint16(b[2:4]),
+		chunkSize:  binary.LittleEndian.Uint32(b[12:16]),
+	}
+	itemsNumber := int(binary.LittleEndian.Uint16(b[32:34]))
+	if len(b) < 34+itemsNumber*6 {
+		return nil, errors.New("invalid laszip VLR")
+	}
+	for i := 0; i < itemsNumber; i++ {
+		item := b[34+i*6 : 40+i*6]
+		parameters.items = append(parameters.items, laszipItem{
+			itemType: binary.LittleEndian.Uint16(item[0:2]),
+			size:     binary.LittleEndian.Uint16(item[2:4]),
+			version:  binary.LittleEndian.Uint16(item[4:6]),
+		})
+	}
+	return &parameters, nil
+}
+
+// Checks that the points of the given LAZ file are compressed in a way supported by the decompressor, i.e. with
+// the pointwise, optionally chunked, arithmetic coder and the version 2 items of the point formats 0-3
+func validateLaszipParameters(las *LasFile) error {
+	parameters := las.laszip
+	if parameters.compressor != laszipCompressorPointwise && parameters.compressor != laszipCompressorPointwiseChunked {
+		return errors.New("unsupported LAZ compressor " + strconv.Itoa(int(parameters.compressor)) + ", point formats 6 to 10 are not supported")
+	}
+	if parameters.coder != 0 {
+		return errors.New("unsupported LAZ coder " + strconv.Itoa(int(parameters.coder)))
+	}
+	recordLength := 0
+	for _, item := range parameters.items {
+		switch item.itemType {
+		case laszipItemPoint10, laszipItemGpsTime11, laszipItemRgb12, laszipItemByte:
+			if item.version != 2 {
+				return errors.New("unsupported version " + strconv.Itoa(int(item.version)) + " of LAZ item " + strconv.Itoa(int(item.itemType)))
+			}
+		default:
+			return errors.New("unsupported LAZ item " + strconv.Itoa(int(item.itemType)))
+		}
+		recordLength += int(item.size)
+	}
+	if len(parameters.items) == 0 || parameters.items[0].itemType != laszipItemPoint10 || recordLength != las.Header.PointRecordLength {
+		return errors.New("LAZ items do not match the point record")
+	}
+	return nil
+}
+
+// Reads and decompresses the point records of a LAZ file, one chunk after the other, laying them out as the point
+// records of the equivalent uncompressed LAS file
+type lazPointReader struct {
+	r            *bufio.Reader
+	items        []laszipItem
+	recordLength int
+	chunkSize    uint32
+	chunkPoints  []int // Number of points of each chunk, listed in the chunk table for variable size chunks
+	chunk        int   // Index of the next chunk
+	remaining    int   // Number of points left in the current chunk
+	decoder      *lazDecoder
+	readers      []lazItemReader
+	offsets      []int
+}
+
+// Instances a new lazPointReader reading the points of the given LAZ file
+func newLazPointReader(las *LasFile) (*lazPointReader, error) {
+	if err := validateLaszipParameters(las); err != nil {
+		return nil, err
+	}
+	reader := &lazPointReader{
+		r:            bufio.NewReader(io.NewSectionReader(las.f, las.Header.OffsetToPoints, math.MaxInt64-las.Header.OffsetToPoints)),
+		items:        las.laszip.items,
+		recordLength: las.Header.PointRecordLength,
+		chunkSize:    laszipVariableChunkSize,
+	}
+	if las.laszip.compressor == laszipCompressorPointwiseChunked {
+		reader.chunkSize = las.laszip.chunkSize
+		// the point data starts with the offset of the chunk table, only needed for the number of points of
+		// variable size chunks as the chunks of fixed size can be read one after the other
+		var tableOffset [8]byte
+		if _, err := io.ReadFull(reader.r, tableOffset[:]); err != nil {
+			return nil, errors.New("LAZ file is truncated")
+		}
+		if reader.chunkSize == laszipVariableChunkSize {
+			var err error
+			if reader.chunkPoints, err = readLaszipChunkPoints(las, int64(binary.LittleEndian.Uint64(tableOffset[:]))); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return reader, nil
+}
+
+// Decompresses the next points into the given buffer, whose length is a multiple of the record length, returning the
+// number of read records
+func (reader *lazPointReader) readRecords(b []byte) (int, error) {
+	n := 0
+	for record := 0; record+reader.recordLength <= len(b); record += reader.recordLength {
+		if reader.remaining == 0 {
+			if err := reader.startChunk(b[record : record+reader.recordLength]); err != nil {
+				return n, err
+			}
+		} else {
+			for i, itemReader := range reader.readers {
+				itemReader.read(b[record+reader.offsets[i] : record+reader.offsets[i]+int(reader.items[i].size)])
+			}
+			reader.remaining--
+		}
+		n++
+	}
+	if reader.decoder != nil && reader.decoder.overflowed() {
+		return n, errors.New("LAZ file is truncated")
+	}
+	return n, nil
+}
+
+// Reads the raw first point of the next chunk into the given record and instances the decoder and the item readers
+// of the arithmetic coded points following it
+func (reader *lazPointReader) startChunk(record []byte) error {
+	if reader.decoder != nil && reader.decoder.overflowed() {
+		return errors.New("LAZ file is truncated")
+	}
+	count := math.MaxInt32
+	if reader.chunkPoints != nil {
+		if reader.chunk >= len(reader.chunkPoints) {
+			return errors.New("LAZ chunk table lists fewer points than the header")
+		}
+		count = reader.chunkPoints[reader.chunk]
+	} else if reader.chunkSize != laszipVariableChunkSize {
+		count = int(reader.chunkSize)
+	}
+	if count <= 0 {
+		return errors.New("empty LAZ chunk")
+	}
+	if _, err := io.ReadFull(reader.r, record); err != nil {
+		return errors.New("LAZ file is truncated")
+	}
+	reader.decoder = newLazDecoder(reader.r)
+	reader.readers = make([]lazItemReader, len(reader.items))
+	reader.offsets = make([]int, len(reader.items))
+	offset := 0
+	for i, item := range reader.items {
+		first := record[offset : offset+int(item.size)]
+		switch item.itemType {
+		case laszipItemPoint10:
+			reader.readers[i] = newLazPoint10Reader(reader.decoder, first)
+		case laszipItemGpsTime11:
+			reader.readers[i] = newLazGpsTime11Reader(reader.decoder, first)
+		case laszipItemRgb12:
+			reader.readers[i] = newLazRgb12Reader(reader.decoder, first)
+		case laszipItemByte:
+			reader.readers[i] = newLazByteReader(reader.decoder, first)
+		}
+		reader.offsets[i] = offset
+		offset += int(item.size)
+	}
+	reader.chunk++
+	reader.remaining = count - 1
+	return nil
+}
+
+// Reads the number of points of each chunk from the chunk table of the given LAZ file, stored at the given offset
+func readLaszipChunkPoints(las *LasFile, tableOffset int64) ([]int, error) {
+	info, err := las.f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if tableOffset == -1 {
+		// the chunk table offset was not known when writing the point data and is stored at the end of the file
+		var b [8]byte
+		if _, err := las.f.ReadAt(b[:], info.Size()-8); err != nil {
+			return nil, errors.New("LAZ file is truncated")
+		}
+		tableOffset = int64(binary.LittleEndian.Uint64(b[:]))
+	}
+	if tableOffset < las.Header.OffsetToPoints+8 || tableOffset+8 > info.Size() {
+		return nil, errors.New("invalid LAZ chunk table offset")
+	}
+	table := make([]byte, info.Size()-tableOffset)
+	if _, err := las.f.ReadAt(table, tableOffset); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if version := binary.LittleEndian.Uint32(table[0:4]); version != 0 {
+		return nil, errors.New("unsupported LAZ chunk table version " + strconv.Itoa(int(version)))
+	}
+	chunks := int(binary.LittleEndian.Uint32(table[4:8]))
+	decoder := newLazDecoder(bytes.NewReader(table[8:]))
+	ic := newLazIntegerDecompressor(32, 2)
+	points := make([]int, chunks)
+	var lastPoints, lastBytes int32
+	for i := range points {
+		lastPoints = ic.decompress(decoder, lastPoints, 0)
+		// the byte size of the chunks is not needed, the chunks being read one after the other
+		lastBytes = ic.decompress(decoder, lastBytes, 1)
+		points[i] = int(lastPoints)
+	}
+	if decoder.overflowed() {
+		return nil, errors.New("LAZ chunk table is truncated")
+	}
+	return points, nil
+}
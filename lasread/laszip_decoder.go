@@ -0,0 +1,356 @@
+package lidario
+
+import "io"
+
+// Arithmetic decoder, probability models and integer decompressor of the LASzip compressed point streams. They port
+// the ones of the reference LASzip implementation, itself based on the FastAC coder by Amir Said, and must match it
+// bit by bit to stay in sync with the encoded stream.
+
+const (
+	lazMinLength         = 0x01000000 // threshold for the renormalization of the interval length
+	lazMaxLength         = 0xFFFFFFFF // initial interval length
+	lazBitLengthShift    = 13         // length bits discarded before multiplying by the bit model probability
+	lazBitMaxCount       = 1 << lazBitLengthShift
+	lazSymbolLengthShift = 15 // length bits discarded before multiplying by the symbol model distribution
+	lazSymbolMaxCount    = 1 << lazSymbolLengthShift
+)
+
+// Arithmetic decoder reading from a byte stream, consuming only the bytes of the encoded data. Failed reads, e.g. past
+// the end of the stream, return zeros and are reported by overflowed
+type lazDecoder struct {
+	r        io.ByteReader
+	overflow bool
+	value    uint32
+	length   uint32
+}
+
+// Instances a new decoder reading the given stream, consuming its first 4 bytes
+func newLazDecoder(r io.ByteReader) *lazDecoder {
+	decoder := &lazDecoder{r: r, length: lazMaxLength}
+	decoder.value = decoder.getByte()<<24 | decoder.getByte()<<16 | decoder.getByte()<<8 | decoder.getByte()
+	return decoder
+}
+
+func (decoder *lazDecoder) getByte() uint32 {
+	b, err := decoder.r.ReadByte()
+	if err != nil {
+		decoder.overflow = true
+		return 0
+	}
+	return uint32(b)
+}
+
+// Returns true if the decoder failed to read its stream
+func (decoder *lazDecoder) overflowed() bool {
+	return decoder.overflow
+}
+
+func (decoder *lazDecoder) renormalize() {
+	for {
+		decoder.value = decoder.value<<8 | decoder.getByte()
+		decoder.length <<= 8
+		if decoder.length >= lazMinLength {
+			return
+		}
+	}
+}
+
+// Decodes a bit with the given adaptive model
+func (decoder *lazDecoder) decodeBit(model *lazBitModel) uint32 {
+	x := model.bit0Prob * (decoder.length >> lazBitLengthShift)
+	var sym uint32
+	if decoder.value < x {
+		decoder.length = x
+		model.bit0Count++
+	} else {
+		sym = 1
+		decoder.value -= x
+		decoder.length -= x
+	}
+	if decoder.length < lazMinLength {
+		decoder.renormalize()
+	}
+	model.bitsUntilUpdate--
+	if model.bitsUntilUpdate == 0 {
+		model.update()
+	}
+	return sym
+}
+
+// Decodes a symbol with the given adaptive model, bisecting its cumulative distribution
+func (decoder *lazDecoder) decodeSymbol(model *lazSymbolModel) uint32 {
+	var x, sym uint32
+	y := decoder.length
+	decoder.length >>= lazSymbolLengthShift
+	n := model.symbols
+	k := n >> 1
+	for {
+		z := decoder.length * model.distribution[k]
+		if z > decoder.value {
+			n = k
+			y = z
+		} else {
+			sym = k
+			x = z
+		}
+		if k = (sym + n) >> 1; k == sym {
+			break
+		}
+	}
+	decoder.value -= x
+	decoder.length = y - x
+	if decoder.length < lazMinLength {
+		decoder.renormalize()
+	}
+	model.symbolCount[sym]++
+	model.symbolsUntilUpdate--
+	if model.symbolsUntilUpdate == 0 {
+		model.update()
+	}
+	return sym
+}
+
+// Reads the given number of raw bits, up to 32
+func (decoder *lazDecoder) readBits(bits uint32) uint32 {
+	if bits > 19 {
+		low := decoder.readBits(16)
+		return decoder.readBits(bits-16)<<16 | low
+	}
+	decoder.length >>= bits
+	sym := decoder.value / decoder.length
+	decoder.value -= decoder.length * sym
+	if decoder.length < lazMinLength {
+		decoder.renormalize()
+	}
+	return sym
+}
+
+// Reads a raw 32 bit integer, stored as its lower and then its upper 16 bits
+func (decoder *lazDecoder) readInt() uint32 {
+	low := decoder.readBits(16)
+	return decoder.readBits(16)<<16 | low
+}
+
+// Adaptive model of the probability of a bit
+type lazBitModel struct {
+	bit0Count       uint32
+	bitCount        uint32
+	bit0Prob        uint32
+	bitsUntilUpdate uint32
+	updateCycle     uint32
+}
+
+// Instances a new bit model with equiprobable values
+func newLazBitModel() *lazBitModel {
+	return &lazBitModel{
+		bit0Count:       1,
+		bitCount:        2,
+		bit0Prob:        1 << (lazBitLengthShift - 1),
+		bitsUntilUpdate: 4,
+		updateCycle:     4,
+	}
+}
+
+func (model *lazBitModel) update() {
+	// halve counts when a threshold is reached
+	if model.bitCount += model.updateCycle; model.bitCount > lazBitMaxCount {
+		model.bitCount = (model.bitCount + 1) >> 1
+		model.bit0Count = (model.bit0Count + 1) >> 1
+		if model.bit0Count == model.bitCount {
+			model.bitCount++
+		}
+	}
+	scale := uint32(0x80000000) / model.bitCount
+	model.bit0Prob = (model.bit0Count * scale) >> (31 - lazBitLengthShift)
+	model.updateCycle = (5 * model.updateCycle) >> 2
+	if model.updateCycle > 64 {
+		model.updateCycle = 64
+	}
+	model.bitsUntilUpdate = model.updateCycle
+}
+
+// Adaptive model of the probabilities of the symbols of an alphabet
+type lazSymbolModel struct {
+	symbols            uint32
+	distribution       []uint32
+	symbolCount        []uint32
+	totalCount         uint32
+	updateCycle        uint32
+	symbolsUntilUpdate uint32
+}
+
+// Instances a new symbol model of the given number of equiprobable symbols
+func newLazSymbolModel(symbols uint32) *lazSymbolModel {
+	model := &lazSymbolModel{
+		symbols:      symbols,
+		distribution: make([]uint32, symbols),
+		symbolCount:  make([]uint32, symbols),
+		updateCycle:  symbols,
+	}
+	for i := range model.symbolCount {
+		model.symbolCount[i] = 1
+	}
+	model.update()
+	model.updateCycle = (symbols + 6) >> 1
+	model.symbolsUntilUpdate = model.updateCycle
+	return model
+}
+
+func (model *lazSymbolModel) update() {
+	// halve counts when a threshold is reached
+	if model.totalCount += model.updateCycle; model.totalCount > lazSymbolMaxCount {
+		model.totalCount = 0
+		for i := range model.symbolCount {
+			model.symbolCount[i] = (model.symbolCount[i] + 1) >> 1
+			model.totalCount += model.symbolCount[i]
+		}
+	}
+	// compute the cumulative distribution
+	var sum uint32
+	scale := uint32(0x80000000) / model.totalCount
+	for i := range model.distribution {
+		model.distribution[i] = (scale * sum) >> (31 - lazSymbolLengthShift)
+		sum += model.symbolCount[i]
+	}
+	model.updateCycle = (5 * model.updateCycle) >> 2
+	if maxCycle := (model.symbols + 6) << 3; model.updateCycle > maxCycle {
+		model.updateCycle = maxCycle
+	}
+	model.symbolsUntilUpdate = model.updateCycle
+}
+
+// Decompresses integers predicted from a context dependent value. The corrector between the prediction and the
+// actual value is coded as the number k of bits it needs, followed by its k bits, the highest 8 of which are
+// entropy coded and the others are raw
+type lazIntegerDecompressor struct {
+	corrBits    uint32
+	corrRange   uint32
+	corrMin     int32
+	k           uint32 // number of bits of the last decompressed corrector, used as context by the point readers
+	mBits       []*lazSymbolModel
+	mCorrector0 *lazBitModel
+	mCorrector  []*lazSymbolModel
+}
+
+// Number of corrector bits coded with a symbol model, the others being written raw
+const lazCorrectorBitsHigh = 8
+
+// Instances a new decompressor of integers of the given number of bits with the given number of contexts
+func newLazIntegerDecompressor(bits uint32, contexts int) *lazIntegerDecompressor {
+	ic := &lazIntegerDecompressor{corrBits: 32, corrMin: -2147483648}
+	if bits > 0 && bits < 32 {
+		ic.corrBits = bits
+		ic.corrRange = 1 << bits
+		ic.corrMin = -int32(ic.corrRange / 2)
+	}
+	ic.mBits = make([]*lazSymbolModel, contexts)
+	for i := range ic.mBits {
+		ic.mBits[i] = newLazSymbolModel(ic.corrBits + 1)
+	}
+	ic.mCorrector0 = newLazBitModel()
+	ic.mCorrector = make([]*lazSymbolModel, ic.corrBits+1)
+	for i := uint32(1); i <= ic.corrBits; i++ {
+		if i <= lazCorrectorBitsHigh {
+			ic.mCorrector[i] = newLazSymbolModel(1 << i)
+		} else {
+			ic.mCorrector[i] = newLazSymbolModel(1 << lazCorrectorBitsHigh)
+		}
+	}
+	return ic
+}
+
+// Decompresses the integer predicted by the given value in the given context
+func (ic *lazIntegerDecompressor) decompress(decoder *lazDecoder, pred int32, context uint32) int32 {
+	real := pred + ic.readCorrector(decoder, ic.mBits[context])
+	if real < 0 {
+		real += int32(ic.corrRange)
+	} else if uint32(real) >= ic.corrRange {
+		real -= int32(ic.corrRange)
+	}
+	return real
+}
+
+func (ic *lazIntegerDecompressor) readCorrector(decoder *lazDecoder, mBits *lazSymbolModel) int32 {
+	ic.k = decoder.decodeSymbol(mBits)
+	if ic.k == 0 {
+		// the corrector is either 0 or 1
+		return int32(decoder.decodeBit(ic.mCorrector0))
+	}
+	if ic.k >= 32 {
+		return ic.corrMin
+	}
+	var c int32
+	if ic.k <= lazCorrectorBitsHigh {
+		c = int32(decoder.decodeSymbol(ic.mCorrector[ic.k]))
+	} else {
+		lowBits := ic.k - lazCorrectorBitsHigh
+		c = int32(decoder.decodeSymbol(ic.mCorrector[ic.k]))
+		c = c<<lowBits | int32(decoder.readBits(lowBits))
+	}
+	// translate c from [0, 2^k - 1] back to [-(2^k - 1), -2^(k-1)] or [2^(k-1) + 1, 2^k]
+	if c >= 1<<(ic.k-1) {
+		return c + 1
+	}
+	return c - (1<<ic.k - 1)
+}
+
+// Streaming median of the last 5 values added
+type lazMedian5 struct {
+	values [5]int32
+	low    bool // true if the next value replaces one of the lowest values, false if one of the highest
+}
+
+func (median *lazMedian5) get() int32 {
+	return median.values[2]
+}
+
+func (median *lazMedian5) add(v int32) {
+	values := &median.values
+	if !median.low {
+		if v < values[2] {
+			values[4] = values[3]
+			values[3] = values[2]
+			if v < values[0] {
+				values[2] = values[1]
+				values[1] = values[0]
+				values[0] = v
+			} else if v < values[1] {
+				values[2] = values[1]
+				values[1] = v
+			} else {
+				values[2] = v
+			}
+		} else {
+			if v < values[3] {
+				values[4] = values[3]
+				values[3] = v
+			} else {
+				values[4] = v
+			}
+			median.low = true
+		}
+	} else {
+		if values[2] < v {
+			values[0] = values[1]
+			values[1] = values[2]
+			if values[4] < v {
+				values[2] = values[3]
+				values[3] = values[4]
+				values[4] = v
+			} else if values[3] < v {
+				values[2] = values[3]
+				values[3] = v
+			} else {
+				values[2] = v
+			}
+		} else {
+			if values[1] < v {
+				values[0] = values[1]
+				values[1] = v
+			} else {
+				values[0] = v
+			}
+			median.low = false
+		}
+	}
+}
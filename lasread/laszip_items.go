@@ -0,0 +1,364 @@
+package lidario
+
+import (
+	"encoding/binary"
+)
+
+// Decompressor of the items of a LASzip compressed point record, e.g. its POINT10 or RGB12 fields. Each chunk of
+// points starts with a raw point, used to initialize the item readers, followed by the arithmetic coded others
+type lazItemReader interface {
+	// Decodes the next item into the given slice, sized as the item
+	read(item []byte)
+}
+
+// Context index of each combination of number of returns and return number, used by the POINT10 reader
+var lazNumberReturnMap = [8][8]uint8{
+	{15, 14, 13, 12, 11, 10, 9, 8},
+	{14, 0, 1, 3, 6, 10, 10, 9},
+	{13, 1, 2, 4, 7, 11, 11, 10},
+	{12, 3, 4, 5, 8, 12, 12, 11},
+	{11, 6, 7, 8, 9, 13, 13, 12},
+	{10, 10, 11, 12, 13, 14, 14, 13},
+	{9, 10, 11, 12, 13, 14, 15, 14},
+	{8, 9, 10, 11, 12, 13, 14, 15},
+}
+
+// Elevation context index of each combination of number of returns and return number, used by the POINT10 reader
+var lazNumberReturnLevel = [8][8]uint8{
+	{0, 1, 2, 3, 4, 5, 6, 7},
+	{1, 0, 1, 2, 3, 4, 5, 6},
+	{2, 1, 0, 1, 2, 3, 4, 5},
+	{3, 2, 1, 0, 1, 2, 3, 4},
+	{4, 3, 2, 1, 0, 1, 2, 3},
+	{5, 4, 3, 2, 1, 0, 1, 2},
+	{6, 5, 4, 3, 2, 1, 0, 1},
+	{7, 6, 5, 4, 3, 2, 1, 0},
+}
+
+// Reader of the 20 byte POINT10 item, version 2, holding the fields shared by the point formats 0-5
+type lazPoint10Reader struct {
+	decoder         *lazDecoder
+	lastItem        [20]byte
+	lastIntensity   [16]uint16
+	lastXDiffMedian [16]lazMedian5
+	lastYDiffMedian [16]lazMedian5
+	lastHeight      [8]int32
+	mChangedValues  *lazSymbolModel
+	mScanAngleRank  [2]*lazSymbolModel
+	mBitByte        [256]*lazSymbolModel
+	mClassification [256]*lazSymbolModel
+	mUserData       [256]*lazSymbolModel
+	icIntensity     *lazIntegerDecompressor
+	icPointSourceID *lazIntegerDecompressor
+	icDx            *lazIntegerDecompressor
+	icDy            *lazIntegerDecompressor
+	icZ             *lazIntegerDecompressor
+}
+
+func newLazPoint10Reader(decoder *lazDecoder, first []byte) *lazPoint10Reader {
+	reader := &lazPoint10Reader{
+		decoder:         decoder,
+		mChangedValues:  newLazSymbolModel(64),
+		mScanAngleRank:  [2]*lazSymbolModel{newLazSymbolModel(256), newLazSymbolModel(256)},
+		icIntensity:     newLazIntegerDecompressor(16, 4),
+		icPointSourceID: newLazIntegerDecompressor(16, 1),
+		icDx:            newLazIntegerDecompressor(32, 2),
+		icDy:            newLazIntegerDecompressor(32, 22),
+		icZ:             newLazIntegerDecompressor(32, 20),
+	}
+	copy(reader.lastItem[:], first)
+	// the intensity is predicted from lastIntensity only
+	reader.lastItem[12] = 0
+	reader.lastItem[13] = 0
+	return reader
+}
+
+// Decodes a symbol with the model of the given context, creating it on first use
+func (reader *lazPoint10Reader) decodeContextSymbol(models *[256]*lazSymbolModel, context byte) byte {
+	if models[context] == nil {
+		models[context] = newLazSymbolModel(256)
+	}
+	return byte(reader.decoder.decodeSymbol(models[context]))
+}
+
+func (reader *lazPoint10Reader) read(item []byte) {
+	last := reader.lastItem[:]
+	changedValues := reader.decoder.decodeSymbol(reader.mChangedValues)
+	if changedValues&32 != 0 {
+		// return numbers, scan direction and edge of flight line
+		last[14] = reader.decodeContextSymbol(&reader.mBitByte, last[14])
+	}
+	r := last[14] & 7
+	n := (last[14] >> 3) & 7
+	m := lazNumberReturnMap[n][r]
+	l := lazNumberReturnLevel[n][r]
+
+	if changedValues&16 != 0 {
+		context := uint32(m)
+		if context > 3 {
+			context = 3
+		}
+		reader.lastIntensity[m] = uint16(reader.icIntensity.decompress(reader.decoder, int32(reader.lastIntensity[m]), context))
+	}
+	binary.LittleEndian.PutUint16(last[12:14], reader.lastIntensity[m])
+	if changedValues&8 != 0 {
+		last[15] = reader.decodeContextSymbol(&reader.mClassification, last[15])
+	}
+	if changedValues&4 != 0 {
+		scanDirection := (last[14] >> 6) & 1
+		last[16] = byte(reader.decoder.decodeSymbol(reader.mScanAngleRank[scanDirection])) + last[16]
+	}
+	if changedValues&2 != 0 {
+		last[17] = reader.decodeContextSymbol(&reader.mUserData, last[17])
+	}
+	if changedValues&1 != 0 {
+		pointSourceID := reader.icPointSourceID.decompress(reader.decoder, int32(binary.LittleEndian.Uint16(last[18:20])), 0)
+		binary.LittleEndian.PutUint16(last[18:20], uint16(pointSourceID))
+	}
+
+	var singleReturn uint32
+	if n == 1 {
+		singleReturn = 1
+	}
+	diff := reader.icDx.decompress(reader.decoder, reader.lastXDiffMedian[m].get(), singleReturn)
+	binary.LittleEndian.PutUint32(last[0:4], uint32(int32(binary.LittleEndian.Uint32(last[0:4]))+diff))
+	reader.lastXDiffMedian[m].add(diff)
+
+	kBits := reader.icDx.k
+	context := singleReturn + 20
+	if kBits < 20 {
+		context = singleReturn + kBits&^1
+	}
+	diff = reader.icDy.decompress(reader.decoder, reader.lastYDiffMedian[m].get(), context)
+	binary.LittleEndian.PutUint32(last[4:8], uint32(int32(binary.LittleEndian.Uint32(last[4:8]))+diff))
+	reader.lastYDiffMedian[m].add(diff)
+
+	kBits = (reader.icDx.k + reader.icDy.k) / 2
+	context = singleReturn + 18
+	if kBits < 18 {
+		context = singleReturn + kBits&^1
+	}
+	reader.lastHeight[l] = reader.icZ.decompress(reader.decoder, reader.lastHeight[l], context)
+	binary.LittleEndian.PutUint32(last[8:12], uint32(reader.lastHeight[l]))
+
+	copy(item, last)
+}
+
+const (
+	lazGpsTimeMulti          = 500
+	lazGpsTimeMultiMinus     = -10
+	lazGpsTimeMultiUnchanged = lazGpsTimeMulti - lazGpsTimeMultiMinus + 1
+	lazGpsTimeMultiCodeFull  = lazGpsTimeMulti - lazGpsTimeMultiMinus + 2
+	lazGpsTimeMultiTotal     = lazGpsTimeMulti - lazGpsTimeMultiMinus + 6
+)
+
+// Reader of the 8 byte GPSTIME11 item, version 2. GPS times are coded as differences of their bit patterns,
+// predicted from the last difference of up to 4 interleaved time sequences
+type lazGpsTime11Reader struct {
+	decoder             *lazDecoder
+	last, next          int
+	lastGpsTime         [4]int64
+	lastGpsTimeDiff     [4]int32
+	multiExtremeCounter [4]int32
+	mGpsTimeMulti       *lazSymbolModel
+	mGpsTime0Diff       *lazSymbolModel
+	icGpsTime           *lazIntegerDecompressor
+}
+
+func newLazGpsTime11Reader(decoder *lazDecoder, first []byte) *lazGpsTime11Reader {
+	reader := &lazGpsTime11Reader{
+		decoder:       decoder,
+		mGpsTimeMulti: newLazSymbolModel(lazGpsTimeMultiTotal),
+		mGpsTime0Diff: newLazSymbolModel(6),
+		icGpsTime:     newLazIntegerDecompressor(32, 9),
+	}
+	reader.lastGpsTime[0] = int64(binary.LittleEndian.Uint64(first))
+	return reader
+}
+
+func (reader *lazGpsTime11Reader) read(item []byte) {
+	reader.decodeGpsTime()
+	binary.LittleEndian.PutUint64(item, uint64(reader.lastGpsTime[reader.last]))
+}
+
+// Decodes a GPS time whose upper 32 bits are predicted from the current sequence and starts a new sequence with it
+func (reader *lazGpsTime11Reader) decodeFullGpsTime() {
+	reader.next = (reader.next + 1) & 3
+	high := reader.icGpsTime.decompress(reader.decoder, int32(uint64(reader.lastGpsTime[reader.last])>>32), 8)
+	reader.lastGpsTime[reader.next] = int64(uint64(uint32(high))<<32 | uint64(reader.decoder.readInt()))
+	reader.last = reader.next
+	reader.lastGpsTimeDiff[reader.last] = 0
+	reader.multiExtremeCounter[reader.last] = 0
+}
+
+// Counts the extreme multipliers of the current sequence, adopting the given difference after more than 3 of them
+func (reader *lazGpsTime11Reader) countExtremeMultiplier(diff int32) {
+	reader.multiExtremeCounter[reader.last]++
+	if reader.multiExtremeCounter[reader.last] > 3 {
+		reader.lastGpsTimeDiff[reader.last] = diff
+		reader.multiExtremeCounter[reader.last] = 0
+	}
+}
+
+func (reader *lazGpsTime11Reader) decodeGpsTime() {
+	last := reader.last
+	if reader.lastGpsTimeDiff[last] == 0 {
+		multi := int32(reader.decoder.decodeSymbol(reader.mGpsTime0Diff))
+		switch {
+		case multi == 1:
+			// the difference fits 32 bits
+			reader.lastGpsTimeDiff[last] = reader.icGpsTime.decompress(reader.decoder, 0, 0)
+			reader.lastGpsTime[last] += int64(reader.lastGpsTimeDiff[last])
+			reader.multiExtremeCounter[last] = 0
+		case multi == 2:
+			reader.decodeFullGpsTime()
+		case multi > 2:
+			// switch to another sequence
+			reader.last = (last + int(multi) - 2) & 3
+			reader.decodeGpsTime()
+		}
+		return
+	}
+
+	multi := int32(reader.decoder.decodeSymbol(reader.mGpsTimeMulti))
+	lastDiff := reader.lastGpsTimeDiff[last]
+	switch {
+	case multi == 1:
+		reader.lastGpsTime[last] += int64(reader.icGpsTime.decompress(reader.decoder, lastDiff, 1))
+		reader.multiExtremeCounter[last] = 0
+	case multi < lazGpsTimeMultiUnchanged:
+		var diff int32
+		switch {
+		case multi == 0:
+			diff = reader.icGpsTime.decompress(reader.decoder, 0, 7)
+			reader.countExtremeMultiplier(diff)
+		case multi < lazGpsTimeMulti:
+			context := uint32(2)
+			if multi >= 10 {
+				context = 3
+			}
+			diff = reader.icGpsTime.decompress(reader.decoder, multi*lastDiff, context)
+		case multi == lazGpsTimeMulti:
+			diff = reader.icGpsTime.decompress(reader.decoder, lazGpsTimeMulti*lastDiff, 4)
+			reader.countExtremeMultiplier(diff)
+		default:
+			// negative multipliers
+			multi = lazGpsTimeMulti - multi
+			if multi > lazGpsTimeMultiMinus {
+				diff = reader.icGpsTime.decompress(reader.decoder, multi*lastDiff, 5)
+			} else {
+				diff = reader.icGpsTime.decompress(reader.decoder, lazGpsTimeMultiMinus*lastDiff, 6)
+				reader.countExtremeMultiplier(diff)
+			}
+		}
+		reader.lastGpsTime[last] += int64(diff)
+	case multi == lazGpsTimeMultiCodeFull:
+		reader.decodeFullGpsTime()
+	case multi > lazGpsTimeMultiCodeFull:
+		// switch to another sequence
+		reader.last = (last + int(multi) - lazGpsTimeMultiCodeFull) & 3
+		reader.decodeGpsTime()
+	}
+}
+
+// Reader of the 6 byte RGB12 item, version 2. Each color byte is coded as a correction of the previous color, the
+// green and blue corrections being predicted from the red one
+type lazRgb12Reader struct {
+	decoder   *lazDecoder
+	lastItem  [3]uint16
+	mByteUsed *lazSymbolModel
+	mRgbDiff  [6]*lazSymbolModel
+}
+
+func newLazRgb12Reader(decoder *lazDecoder, first []byte) *lazRgb12Reader {
+	reader := &lazRgb12Reader{decoder: decoder, mByteUsed: newLazSymbolModel(128)}
+	for i := range reader.mRgbDiff {
+		reader.mRgbDiff[i] = newLazSymbolModel(256)
+	}
+	for i := range reader.lastItem {
+		reader.lastItem[i] = binary.LittleEndian.Uint16(first[i*2:])
+	}
+	return reader
+}
+
+// Decodes the byte predicted by the given value, if flagged as changed, otherwise returns the given default
+func (reader *lazRgb12Reader) decodeByte(sym uint32, bit uint, prediction int32, unchanged uint16) uint16 {
+	if sym&(1<<bit) == 0 {
+		return unchanged
+	}
+	corr := int32(reader.decoder.decodeSymbol(reader.mRgbDiff[bit]))
+	return uint16(lazFoldByte(corr + prediction))
+}
+
+func (reader *lazRgb12Reader) read(item []byte) {
+	last := reader.lastItem
+	var rgb [3]uint16
+	sym := reader.decoder.decodeSymbol(reader.mByteUsed)
+	rgb[0] = reader.decodeByte(sym, 0, int32(last[0]&0xff), last[0]&0xff)
+	rgb[0] |= reader.decodeByte(sym, 1, int32(last[0]>>8), last[0]>>8) << 8
+	if sym&(1<<6) != 0 {
+		diff := int32(rgb[0]&0xff) - int32(last[0]&0xff)
+		rgb[1] = reader.decodeByte(sym, 2, lazClampByte(diff+int32(last[1]&0xff)), last[1]&0xff)
+		if sym&(1<<4) != 0 {
+			diff = (diff + int32(rgb[1]&0xff) - int32(last[1]&0xff)) / 2
+		}
+		rgb[2] = reader.decodeByte(sym, 4, lazClampByte(diff+int32(last[2]&0xff)), last[2]&0xff)
+		diff = int32(rgb[0]>>8) - int32(last[0]>>8)
+		rgb[1] |= reader.decodeByte(sym, 3, lazClampByte(diff+int32(last[1]>>8)), last[1]>>8) << 8
+		if sym&(1<<5) != 0 {
+			diff = (diff + int32(rgb[1]>>8) - int32(last[1]>>8)) / 2
+		}
+		rgb[2] |= reader.decodeByte(sym, 5, lazClampByte(diff+int32(last[2]>>8)), last[2]>>8) << 8
+	} else {
+		// grey levels
+		rgb[1] = rgb[0]
+		rgb[2] = rgb[0]
+	}
+	reader.lastItem = rgb
+	for i, channel := range rgb {
+		binary.LittleEndian.PutUint16(item[i*2:], channel)
+	}
+}
+
+// Reader of the BYTE item, version 2, holding the extra bytes of a point record. Each byte is coded as a difference
+// from the previous one
+type lazByteReader struct {
+	decoder  *lazDecoder
+	lastItem []byte
+	mByte    []*lazSymbolModel
+}
+
+func newLazByteReader(decoder *lazDecoder, first []byte) *lazByteReader {
+	reader := &lazByteReader{decoder: decoder, lastItem: append([]byte(nil), first...), mByte: make([]*lazSymbolModel, len(first))}
+	for i := range reader.mByte {
+		reader.mByte[i] = newLazSymbolModel(256)
+	}
+	return reader
+}
+
+func (reader *lazByteReader) read(item []byte) {
+	for i := range reader.lastItem {
+		reader.lastItem[i] += byte(reader.decoder.decodeSymbol(reader.mByte[i]))
+	}
+	copy(item, reader.lastItem)
+}
+
+// Folds the given value into the 0-255 range, as a byte sum with overflow
+func lazFoldByte(n int32) int32 {
+	if n < 0 {
+		return n + 256
+	} else if n > 255 {
+		return n - 256
+	}
+	return n
+}
+
+// Clamps the given value to the 0-255 range
+func lazClampByte(n int32) int32 {
+	if n < 0 {
+		return 0
+	} else if n > 255 {
+		return 255
+	}
+	return n
+}
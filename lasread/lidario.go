@@ -10,11 +10,22 @@ import (
 	"math"
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 )
 
+// Record ID of the VLR storing the coordinate system as WKT
+const wktRecordID = 2112
+
+// Length in bytes of the header of each VLR
+const vlrHeaderLength = 54
+
+// Largest value of int, 2^31-1 on 32 bit platforms. File offsets and lengths are int64, but point counts and
+// in memory buffers are bounded by it
+const maxInt = int(^uint(0) >> 1)
+
 // NoData value used when indexing data outside of allowable range.
 var NoData = math.Inf(-1)
 
@@ -26,6 +37,7 @@ type LasFile struct {
 	Header                 LasHeader
 	VlrData                []VLR
 	geokeys                GeoKeys
+	laszip                 *laszipParameters // LASzip compression parameters, nil if the points are not compressed
 	pointData              []PointRecord0
 	gpsData                []float64
 	rgbData                []RgbData
@@ -36,6 +48,9 @@ type LasFile struct {
 	frs2D                  *fixedRadiusSearch
 	fixedRadiusSearch3DSet bool
 	frs3D                  *fixedRadiusSearch
+	DroppedOriginPoints    int64            // Number of points at (0,0,0) dropped while loading the file, see LasFileLoader
+	ClippedPoints          int64            // Number of points outside of the clip bounds skipped while loading the file, see LasFileLoader
+	ClassificationNames    map[uint8]string // Names of the classification codes read from the Classification Lookup VLR, if present
 	sync.RWMutex
 }
 
@@ -71,6 +86,20 @@ func NewLasFile(fileName, fileMode string) (*LasFile, error) {
 	return &las, nil
 }
 
+// ReadLasHeaderInfo reads the header and the VLRs of the given LAS or LAZ file, without reading its point records, so
+// that the number of points, the extent and the coordinate system of even huge files are known instantly. The
+// extent is the one stored in the header, in scaled coordinates
+func ReadLasHeaderInfo(fileName string) (*LasHeader, error) {
+	las, err := NewLasFile(fileName, "rh")
+	if las.f != nil {
+		_ = las.f.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &las.Header, nil
+}
+
 // InitializeUsingFile initializes a new LAS file based on another existing file.
 // The function transfers values from the header and the VLRs to the new file.
 func InitializeUsingFile(fileName string, other *LasFile) (*LasFile, error) {
@@ -359,6 +388,12 @@ func (las *LasFile) read() error {
 		return err
 	}
 	if las.fileMode != "rh" {
+		if err := validatePointRecords(las); err != nil {
+			return err
+		}
+		if las.Header.PointFormatID > 3 || las.laszip != nil {
+			return errors.New("only uncompressed LAS point formats 0-3 can be read into a LasFile, use a LasFileLoader")
+		}
 		recLengths := [4][4]int{{20, 18, 19, 17}, {28, 26, 27, 25}, {26, 24, 25, 23}, {34, 32, 33, 31}}
 
 		if las.Header.PointRecordLength == recLengths[las.Header.PointFormatID][0] {
@@ -385,8 +420,8 @@ func (las *LasFile) read() error {
 func (las *LasFile) readHeader() error {
 	las.Lock()
 	defer las.Unlock()
-	b := make([]byte, 243)
-	if _, err := las.f.ReadAt(b[0:243], 0); err != nil && err != io.EOF {
+	b := make([]byte, 375)
+	if _, err := las.f.ReadAt(b[0:375], 0); err != nil && err != io.EOF {
 		return err
 	}
 
@@ -440,15 +475,16 @@ func (las *LasFile) readHeader() error {
 	offset += 2
 	las.Header.HeaderSize = int(binary.LittleEndian.Uint16(b[offset : offset+2]))
 	offset += 2
-	las.Header.OffsetToPoints = int(binary.LittleEndian.Uint32(b[offset : offset+4]))
+	las.Header.OffsetToPoints = int64(binary.LittleEndian.Uint32(b[offset : offset+4]))
 	offset += 4
 	las.Header.NumberOfVLRs = int(binary.LittleEndian.Uint32(b[offset : offset+4]))
 	offset += 4
-	las.Header.PointFormatID = b[104]
+	// LAZ files flag the compression setting the highest bits of the point format
+	las.Header.PointFormatID = b[104] & 0x3f
 	offset++
 	las.Header.PointRecordLength = int(binary.LittleEndian.Uint16(b[offset : offset+2]))
 	offset += 2
-	las.Header.NumberPoints = int(binary.LittleEndian.Uint32(b[offset : offset+4]))
+	numberPoints := uint64(binary.LittleEndian.Uint32(b[offset : offset+4]))
 	offset += 4
 	for i := 0; i < 5; i++ {
 		las.Header.NumberPointsByReturn[i] = int(binary.LittleEndian.Uint32(b[offset : offset+4]))
@@ -482,6 +518,18 @@ func (las *LasFile) readHeader() error {
 	if las.Header.VersionMajor == 1 && las.Header.VersionMinor == 3 {
 		las.Header.WaveformDataStart = binary.LittleEndian.Uint64(b[offset : offset+8])
 	}
+	if las.Header.VersionMajor == 1 && las.Header.VersionMinor >= 4 && las.Header.HeaderSize >= 375 {
+		// LAS 1.4 stores the 64 bit number of points after the extended VLR fields, the legacy one is 0 for
+		// point formats 6-10
+		if extendedNumberPoints := binary.LittleEndian.Uint64(b[247:255]); extendedNumberPoints > 0 {
+			numberPoints = extendedNumberPoints
+		}
+	}
+	// on 32 bit platforms int cannot hold more than 2^31-1 points
+	if numberPoints > uint64(maxInt) {
+		return errors.New("the LAS file has " + strconv.FormatUint(numberPoints, 10) + " points, more than the " + strconv.Itoa(maxInt) + " supported on this platform")
+	}
+	las.Header.NumberPoints = int(numberPoints)
 
 	return nil
 }
@@ -492,16 +540,16 @@ func (las *LasFile) readVLRs() error {
 	// Update the VLR slice
 	las.VlrData = make([]VLR, las.Header.NumberOfVLRs)
 
-	// Estimate how many bytes are used to store the VLRs
-	vlrLength := las.Header.OffsetToPoints - las.Header.HeaderSize
-	b := make([]byte, vlrLength)
-	// if _, err := las.r.ReadAt(b[0:vlrLength], int64(las.Header.HeaderSize)); err != nil && err != io.EOF {
-	if _, err := las.f.ReadAt(b, int64(las.Header.HeaderSize)); err != nil && err != io.EOF {
-		return err
-	}
-
-	offset := 0
+	// Each VLR is read at its own 64 bit file offset rather than reading all the bytes up to the points at once, as
+	// the points may start gigabytes after the header
+	fileOffset := int64(las.Header.HeaderSize)
+	b := make([]byte, vlrHeaderLength)
 	for i := 0; i < las.Header.NumberOfVLRs; i++ {
+		if _, err := las.f.ReadAt(b, fileOffset); err != nil {
+			return errors.New("the VLRs of the LAS file are truncated")
+		}
+		fileOffset += vlrHeaderLength
+		offset := 0
 		vlr := VLR{}
 		vlr.Reserved = int(binary.LittleEndian.Uint16(b[offset : offset+2]))
 		offset += 2
@@ -518,11 +566,10 @@ func (las *LasFile) readVLRs() error {
 		vlr.Description = strings.Trim(vlr.Description, "\x00")
 		offset += 32
 		vlr.BinaryData = make([]uint8, vlr.RecordLengthAfterHeader)
-		for j := 0; j < vlr.RecordLengthAfterHeader; j++ {
-			// vlr.BinaryData = append(vlr.BinaryData, b[offset])
-			vlr.BinaryData[j] = b[offset]
-			offset++
+		if _, err := las.f.ReadAt(vlr.BinaryData, fileOffset); err != nil {
+			return errors.New("the VLRs of the LAS file are truncated")
 		}
+		fileOffset += int64(vlr.RecordLengthAfterHeader)
 		if vlr.RecordID == 34735 {
 			// GeoKey directory
 			las.geokeys.addKeyDirectory(vlr.BinaryData)
@@ -532,9 +579,26 @@ func (las *LasFile) readVLRs() error {
 		} else if vlr.RecordID == 34737 {
 			// ASCII GeoKey parameters
 			las.geokeys.addASCIIParams(vlr.BinaryData)
+		} else if vlr.RecordID == wktRecordID {
+			// Coordinate system WKT. Read regardless of the user id and of the WKT global encoding bit, as ArcGIS
+			// stores ESRI flavored WKT in this record of LAS files with GeoTIFF global encoding too
+			las.Header.Wkt = strings.Trim(string(vlr.BinaryData), " \x00")
+		} else if vlr.UserID == laszipUserID && vlr.RecordID == laszipRecordID {
+			// LAZ compression parameters
+			var err error
+			if las.laszip, err = parseLaszipVlr(vlr.BinaryData); err != nil {
+				return err
+			}
+		} else if vlr.UserID == classificationLookupUserID && vlr.RecordID == classificationLookupRecordID {
+			// Names of the classification codes
+			las.ClassificationNames = parseClassificationLookupVlr(vlr.BinaryData)
+		} else if vlr.UserID == extraBytesUserID && vlr.RecordID == extraBytesRecordID {
+			// Attributes stored in the extra bytes of the point records
+			las.Header.ExtraBytes = parseExtraBytesVlr(vlr.BinaryData, las.Header.PointRecordLength, las.Header.PointFormatID)
 		}
 		las.VlrData[i] = vlr
 	}
+	las.Header.Epsg = las.geokeys.getEpsgCode()
 
 	return nil
 }
@@ -551,9 +615,12 @@ func (las *LasFile) readPoints() error {
 	}
 
 	// Estimate how many bytes are used to store the points
-	pointsLength := las.Header.NumberPoints * las.Header.PointRecordLength
+	pointsLength := int64(las.Header.NumberPoints) * int64(las.Header.PointRecordLength)
+	if pointsLength > int64(maxInt) {
+		return errors.New("the LAS points are too large to be read at once on this platform")
+	}
 	b := make([]byte, pointsLength)
-	if _, err := las.f.ReadAt(b, int64(las.Header.OffsetToPoints)); err != nil && err != io.EOF {
+	if _, err := las.f.ReadAt(b, las.Header.OffsetToPoints); err != nil && err != io.EOF {
 		return err
 	}
 
@@ -594,11 +661,11 @@ func (las *LasFile) readPoints() error {
 			for i := pointSt; i <= pointEnd; i++ {
 				offset = i * las.Header.PointRecordLength
 				// p := PointRecord0{}
-				p.X = float64(int32(binary.LittleEndian.Uint32(b[offset:offset+4])))*las.Header.XScaleFactor + las.Header.XOffset
+				p.X = decodeScaledCoordinate(b[offset:offset+4], las.Header.XScaleFactor, las.Header.XOffset)
 				offset += 4
-				p.Y = float64(int32(binary.LittleEndian.Uint32(b[offset:offset+4])))*las.Header.YScaleFactor + las.Header.YOffset
+				p.Y = decodeScaledCoordinate(b[offset:offset+4], las.Header.YScaleFactor, las.Header.YOffset)
 				offset += 4
-				p.Z = float64(int32(binary.LittleEndian.Uint32(b[offset:offset+4])))*las.Header.ZScaleFactor + las.Header.ZOffset
+				p.Z = decodeScaledCoordinate(b[offset:offset+4], las.Header.ZScaleFactor, las.Header.ZOffset)
 				offset += 4
 				if las.usePointIntensity {
 					p.Intensity = binary.LittleEndian.Uint16(b[offset : offset+2])
@@ -747,7 +814,7 @@ func (las *LasFile) write() error {
 	for i := 0; i < las.Header.NumberOfVLRs; i++ {
 		totalVLRSize += las.VlrData[i].RecordLengthAfterHeader
 	}
-	las.Header.OffsetToPoints = 235 + totalVLRSize
+	las.Header.OffsetToPoints = int64(235 + totalVLRSize)
 	binary.LittleEndian.PutUint32(bytes4, uint32(las.Header.OffsetToPoints))
 	w.Write(bytes4)
 
@@ -863,7 +930,11 @@ func (las *LasFile) write() error {
 	var startingPoint int
 
 	// how many bytes will it take; create a byte slice of the appropriate length
-	b := make([]byte, las.Header.NumberPoints*las.Header.PointRecordLength)
+	pointsLength := int64(las.Header.NumberPoints) * int64(las.Header.PointRecordLength)
+	if pointsLength > int64(maxInt) {
+		return errors.New("the LAS points are too large to be written at once on this platform")
+	}
+	b := make([]byte, pointsLength)
 
 	switch las.Header.PointFormatID {
 	case 0:
@@ -885,7 +956,7 @@ func (las *LasFile) write() error {
 
 					offset = i * las.Header.PointRecordLength
 
-					val = int32((p.X - las.Header.XOffset) / las.Header.XScaleFactor)
+					val = int32(math.Round((p.X - las.Header.XOffset) / las.Header.XScaleFactor))
 					binary.LittleEndian.PutUint32(b4, uint32(val))
 					b[offset] = b4[0]
 					b[offset+1] = b4[1]
@@ -893,7 +964,7 @@ func (las *LasFile) write() error {
 					b[offset+3] = b4[3]
 					offset += 4
 
-					val = int32((p.Y - las.Header.YOffset) / las.Header.YScaleFactor)
+					val = int32(math.Round((p.Y - las.Header.YOffset) / las.Header.YScaleFactor))
 					binary.LittleEndian.PutUint32(b4, uint32(val))
 					b[offset] = b4[0]
 					b[offset+1] = b4[1]
@@ -901,7 +972,7 @@ func (las *LasFile) write() error {
 					b[offset+3] = b4[3]
 					offset += 4
 
-					val = int32((p.Z - las.Header.ZOffset) / las.Header.ZScaleFactor)
+					val = int32(math.Round((p.Z - las.Header.ZOffset) / las.Header.ZScaleFactor))
 					binary.LittleEndian.PutUint32(b4, uint32(val))
 					b[offset] = b4[0]
 					b[offset+1] = b4[1]
@@ -993,7 +1064,7 @@ func (las *LasFile) write() error {
 
 					offset = i * las.Header.PointRecordLength
 
-					val = int32((p.X - las.Header.XOffset) / las.Header.XScaleFactor)
+					val = int32(math.Round((p.X - las.Header.XOffset) / las.Header.XScaleFactor))
 					binary.LittleEndian.PutUint32(b4, uint32(val))
 					b[offset] = b4[0]
 					b[offset+1] = b4[1]
@@ -1001,7 +1072,7 @@ func (las *LasFile) write() error {
 					b[offset+3] = b4[3]
 					offset += 4
 
-					val = int32((p.Y - las.Header.YOffset) / las.Header.YScaleFactor)
+					val = int32(math.Round((p.Y - las.Header.YOffset) / las.Header.YScaleFactor))
 					binary.LittleEndian.PutUint32(b4, uint32(val))
 					b[offset] = b4[0]
 					b[offset+1] = b4[1]
@@ -1009,7 +1080,7 @@ func (las *LasFile) write() error {
 					b[offset+3] = b4[3]
 					offset += 4
 
-					val = int32((p.Z - las.Header.ZOffset) / las.Header.ZScaleFactor)
+					val = int32(math.Round((p.Z - las.Header.ZOffset) / las.Header.ZScaleFactor))
 					binary.LittleEndian.PutUint32(b4, uint32(val))
 					b[offset] = b4[0]
 					b[offset+1] = b4[1]
@@ -1115,7 +1186,7 @@ func (las *LasFile) write() error {
 
 					offset = i * las.Header.PointRecordLength
 
-					val = int32((p.X - las.Header.XOffset) / las.Header.XScaleFactor)
+					val = int32(math.Round((p.X - las.Header.XOffset) / las.Header.XScaleFactor))
 					binary.LittleEndian.PutUint32(b4, uint32(val))
 					b[offset] = b4[0]
 					b[offset+1] = b4[1]
@@ -1123,7 +1194,7 @@ func (las *LasFile) write() error {
 					b[offset+3] = b4[3]
 					offset += 4
 
-					val = int32((p.Y - las.Header.YOffset) / las.Header.YScaleFactor)
+					val = int32(math.Round((p.Y - las.Header.YOffset) / las.Header.YScaleFactor))
 					binary.LittleEndian.PutUint32(b4, uint32(val))
 					b[offset] = b4[0]
 					b[offset+1] = b4[1]
@@ -1131,7 +1202,7 @@ func (las *LasFile) write() error {
 					b[offset+3] = b4[3]
 					offset += 4
 
-					val = int32((p.Z - las.Header.ZOffset) / las.Header.ZScaleFactor)
+					val = int32(math.Round((p.Z - las.Header.ZOffset) / las.Header.ZScaleFactor))
 					binary.LittleEndian.PutUint32(b4, uint32(val))
 					b[offset] = b4[0]
 					b[offset+1] = b4[1]
@@ -1244,7 +1315,7 @@ func (las *LasFile) write() error {
 
 					offset = i * las.Header.PointRecordLength
 
-					val = int32((p.X - las.Header.XOffset) / las.Header.XScaleFactor)
+					val = int32(math.Round((p.X - las.Header.XOffset) / las.Header.XScaleFactor))
 					binary.LittleEndian.PutUint32(b4, uint32(val))
 					b[offset] = b4[0]
 					b[offset+1] = b4[1]
@@ -1252,7 +1323,7 @@ func (las *LasFile) write() error {
 					b[offset+3] = b4[3]
 					offset += 4
 
-					val = int32((p.Y - las.Header.YOffset) / las.Header.YScaleFactor)
+					val = int32(math.Round((p.Y - las.Header.YOffset) / las.Header.YScaleFactor))
 					binary.LittleEndian.PutUint32(b4, uint32(val))
 					b[offset] = b4[0]
 					b[offset+1] = b4[1]
@@ -1260,7 +1331,7 @@ func (las *LasFile) write() error {
 					b[offset+3] = b4[3]
 					offset += 4
 
-					val = int32((p.Z - las.Header.ZOffset) / las.Header.ZScaleFactor)
+					val = int32(math.Round((p.Z - las.Header.ZOffset) / las.Header.ZScaleFactor))
 					binary.LittleEndian.PutUint32(b4, uint32(val))
 					b[offset] = b4[0]
 					b[offset+1] = b4[1]
@@ -1424,7 +1495,7 @@ type LasHeader struct {
 	FileCreationDay      int
 	FileCreationYear     int
 	HeaderSize           int
-	OffsetToPoints       int
+	OffsetToPoints       int64
 	NumberOfVLRs         int
 	PointFormatID        byte
 	PointRecordLength    int
@@ -1443,6 +1514,9 @@ type LasHeader struct {
 	MaxZ                 float64
 	MinZ                 float64
 	WaveformDataStart    uint64
+	Epsg                 int                    // EPSG code of the coordinate system declared by the GeoKey VLRs, 0 if none
+	Wkt                  string                 // Coordinate system WKT stored in the VLRs, if any
+	ExtraBytes           []ExtraBytesDescriptor // Attributes stored in the extra bytes of the point records, declared by the Extra Bytes VLR
 	projectIDUsed        bool
 }
 
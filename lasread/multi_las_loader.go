@@ -0,0 +1,72 @@
+package lidario
+
+import (
+	"context"
+	"errors"
+	"github.com/mfbonfigli/gocesiumtiler/converters"
+	"runtime"
+	"sync"
+)
+
+// Reads a set of LAS files, each with its own input srid and elevation correction, into the shared Loader of a
+// LasFileLoader so that their points can be tiled in a single octree. The points of each file are reprojected to
+// EPSG:4326 while reading it, before being merged with the ones of the other files.
+type MultiLasLoader struct {
+	FileNames     []string
+	Srids         []int                           // Input srid of each file
+	ZCorrections  []converters.ElevationCorrector // Elevation correction of each file
+	Concurrency   int                             // Max number of files read at a time, 0 to read a file per CPU
+	lasFileLoader *LasFileLoader
+}
+
+// Instances a new MultiLasLoader reading the given files, with the given input srids and elevation corrections, by
+// means of the given LasFileLoader
+func NewMultiLasLoader(fileNames []string, srids []int, zCorrections []converters.ElevationCorrector, lasFileLoader *LasFileLoader) (*MultiLasLoader, error) {
+	if len(srids) != len(fileNames) || len(zCorrections) != len(fileNames) {
+		return nil, errors.New("a srid and an elevation correction are required for each file")
+	}
+	return &MultiLasLoader{
+		FileNames:     fileNames,
+		Srids:         srids,
+		ZCorrections:  zCorrections,
+		lasFileLoader: lasFileLoader,
+	}, nil
+}
+
+// Reads all the files concurrently into the Loader, each file with its own reader goroutines. Returns the first
+// error raised, if any
+func (multiLasLoader *MultiLasLoader) LoadLasFiles() error {
+	return multiLasLoader.LoadLasFilesContext(context.Background())
+}
+
+// Same as LoadLasFiles, but stops reading as soon as the given context is done, returning its error
+func (multiLasLoader *MultiLasLoader) LoadLasFilesContext(ctx context.Context) error {
+	concurrency := multiLasLoader.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+	semaphore := make(chan struct{}, concurrency)
+	errs := make(chan error, len(multiLasLoader.FileNames))
+	var waitGroup sync.WaitGroup
+	for i, fileName := range multiLasLoader.FileNames {
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+			waitGroup.Wait()
+			return ctx.Err()
+		}
+		waitGroup.Add(1)
+		go func(i int, fileName string) {
+			defer waitGroup.Done()
+			defer func() { <-semaphore }()
+			las, err := multiLasLoader.lasFileLoader.LoadLasFileContext(ctx, fileName, multiLasLoader.ZCorrections[i], multiLasLoader.Srids[i])
+			if err != nil {
+				errs <- err
+			}
+			_ = las.Close()
+		}(i, fileName)
+	}
+	waitGroup.Wait()
+	close(errs)
+	return <-errs
+}
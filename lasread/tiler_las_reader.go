@@ -6,46 +6,80 @@
 package lidario
 
 import (
+	"context"
 	"encoding/binary"
+	"errors"
 	"io"
-	"log"
+	"math"
 	"github.com/mfbonfigli/gocesiumtiler/converters"
 	"github.com/mfbonfigli/gocesiumtiler/structs/data"
 	"github.com/mfbonfigli/gocesiumtiler/structs/geometry"
 	"github.com/mfbonfigli/gocesiumtiler/structs/point_loader"
+	"github.com/mfbonfigli/gocesiumtiler/utils"
 	"os"
-	"runtime"
+	"path/filepath"
+	"strconv"
 	"sync"
+	"sync/atomic"
 )
 
 type LasFileLoader struct {
 	CoordinateConverter converters.CoordinateConverter
 	ElevationConverter  converters.EllipsoidToGeoidZConverter
 	Loader              point_loader.Loader
+	WorkerPool          *utils.WorkerPool
+	UseWktProjection    bool                                  // Reads the input srid from the WKT VLR of the file, if present
+	ReadBufferSize      int                                   // Max size in bytes of the point records read at once, 0 to use DefaultReadBufferSize
+	DropOriginPoints    bool                                  // Drops the points whose source coordinates are exactly (0,0,0), usually zero filled records
+	ProgressCallback    func(stage string, done, total int64) // If not nil, periodically called with the number of parsed points
+	ScaleIntensity      func(intensity uint16) uint8          // Converts the 16 bit intensities to the 8 bit ones of the points, if nil they are divided by 256
+	ColorClassification func(class uint8) (R, G, B uint16)    // If not nil, colors the points of the files without RGB from their classification
+	ForceClassColor     bool                                  // Colors the points with ColorClassification even if the file has RGB
+	ClipBounds          *geometry.BoundingBox                 // If not nil, skips the points outside of this box, boundaries included, in the srid of the file
+	KeepEveryNth        int                                   // If > 1, loads only the points whose index in the file is a multiple of this value
+	ExtraAttributes     []string                              // Names of the Extra Bytes VLR attributes read in the ExtraAttributes of the points, in the same order
 }
 
-func NewLasFileLoader(coordinateConverter converters.CoordinateConverter, elevationConverter converters.EllipsoidToGeoidZConverter, loader point_loader.Loader) *LasFileLoader {
+// Default max size in bytes of the point records read at once from a LAS file
+const DefaultReadBufferSize = 64 * 1024 * 1024
+
+// Number of points parsed by each reader goroutine between two checks of the cancellation of the context and two
+// progress updates
+const cancellationCheckPoints = 4096
+
+// Input srid requesting to detect the srid of each file from its GeoKey or WKT VLRs
+const DetectSrid = 0
+
+func NewLasFileLoader(coordinateConverter converters.CoordinateConverter, elevationConverter converters.EllipsoidToGeoidZConverter, loader point_loader.Loader, workerPool *utils.WorkerPool, useWktProjection bool) *LasFileLoader {
 	return &LasFileLoader{
 		CoordinateConverter: coordinateConverter,
 		ElevationConverter:  elevationConverter,
 		Loader:              loader,
+		WorkerPool:          workerPool,
+		UseWktProjection:    useWktProjection,
 	}
 }
 
 // NewLasFile creates a new LasFile structure which stores the points data directly into Point instances
 // which can be retrieved by index using the GetPoint function
 func (lasFileLoader *LasFileLoader) LoadLasFile(fileName string, zCorrection converters.ElevationCorrector, inSrid int) (*LasFile, error) {
+	return lasFileLoader.LoadLasFileContext(context.Background(), fileName, zCorrection, inSrid)
+}
+
+// Same as LoadLasFile, but stops reading the points as soon as the given context is done, returning its error. The
+// points read until then are left in the Loader
+func (lasFileLoader *LasFileLoader) LoadLasFileContext(ctx context.Context, fileName string, zCorrection converters.ElevationCorrector, inSrid int) (*LasFile, error) {
 	// initialize the VLR array
 	vlrs := []VLR{}
 	las := LasFile{fileName: fileName, fileMode: "r", Header: LasHeader{}, VlrData: vlrs}
-	if err := lasFileLoader.readForOctree(zCorrection, inSrid, &las); err != nil {
+	if err := lasFileLoader.readForOctree(ctx, zCorrection, inSrid, &las); err != nil {
 		return &las, err
 	}
 	return &las, nil
 }
 
 // Reads the las file and produces a LasFile struct instance loading points data into its inner list of Point
-func (lasFileLoader *LasFileLoader) readForOctree(zCorrection converters.ElevationCorrector, inSrid int, las *LasFile) error {
+func (lasFileLoader *LasFileLoader) readForOctree(ctx context.Context, zCorrection converters.ElevationCorrector, inSrid int, las *LasFile) error {
 	var err error
 	if las.f, err = os.Open(las.fileName); err != nil {
 		return err
@@ -56,24 +90,24 @@ func (lasFileLoader *LasFileLoader) readForOctree(zCorrection converters.Elevati
 	if err := las.readVLRs(); err != nil {
 		return err
 	}
+	if namer, ok := lasFileLoader.Loader.(point_loader.ClassificationNamer); ok && len(las.ClassificationNames) > 0 {
+		namer.AddClassificationNames(las.ClassificationNames)
+	}
+	if lasFileLoader.UseWktProjection && las.Header.Wkt != "" {
+		if inSrid, err = lasFileLoader.CoordinateConverter.GetWktSrid(las.Header.Wkt); err != nil {
+			return err
+		}
+	} else if inSrid == DetectSrid {
+		if inSrid, err = lasFileLoader.detectSrid(las); err != nil {
+			return err
+		}
+	}
 	if las.fileMode != "rh" {
-		recLengths := [4][4]int{{20, 18, 19, 17}, {28, 26, 27, 25}, {26, 24, 25, 23}, {34, 32, 33, 31}}
-
-		if las.Header.PointRecordLength == recLengths[las.Header.PointFormatID][0] {
-			las.usePointIntensity = true
-			las.usePointUserdata = true
-		} else if las.Header.PointRecordLength == recLengths[las.Header.PointFormatID][1] {
-			las.usePointIntensity = false
-			las.usePointUserdata = true
-		} else if las.Header.PointRecordLength == recLengths[las.Header.PointFormatID][2] {
-			las.usePointIntensity = true
-			las.usePointUserdata = false
-		} else if las.Header.PointRecordLength == recLengths[las.Header.PointFormatID][3] {
-			las.usePointIntensity = false
-			las.usePointUserdata = false
-		}
-
-		if err := lasFileLoader.readPointsOctElem(zCorrection, inSrid, las); err != nil {
+		if err := validatePointRecords(las); err != nil {
+			return err
+		}
+		setOptionalPointFields(las)
+		if err := lasFileLoader.readPointsOctElem(ctx, zCorrection, inSrid, las); err != nil {
 			return err
 		}
 	}
@@ -81,8 +115,9 @@ func (lasFileLoader *LasFileLoader) readForOctree(zCorrection converters.Elevati
 }
 
 // Reads all the points of the given las file and parses them into a Point data structure which is then stored
-// in the given LasFile instance
-func (lasFileLoader *LasFileLoader) readPointsOctElem(zCorrection converters.ElevationCorrector, inSrid int, las *LasFile) error {
+// in the given LasFile instance. Points are read in batches so that the memory used does not depend on the file size.
+// Reading stops when the given context is done
+func (lasFileLoader *LasFileLoader) readPointsOctElem(ctx context.Context, zCorrection converters.ElevationCorrector, inSrid int, las *LasFile) error {
 	las.Lock()
 	defer las.Unlock()
 	// las.pointDataOctElement = make([]octree.OctElement, las.Header.NumberPoints)
@@ -93,102 +128,478 @@ func (lasFileLoader *LasFileLoader) readPointsOctElem(zCorrection converters.Ele
 		// las.rgbData = make([]RgbData, las.Header.NumberPoints)
 	}
 
-	// Estimate how many bytes are used to store the points
-	pointsLength := las.Header.NumberPoints * las.Header.PointRecordLength
-	b := make([]byte, pointsLength)
-	if _, err := las.f.ReadAt(b, int64(las.Header.OffsetToPoints)); err != nil && err != io.EOF {
-		// return err
+	if las.Header.NumberPoints == 0 {
+		utils.LogOutput("> warning: file", filepath.Base(las.fileName), "has no points")
+		return nil
 	}
 
-	// Intensity and userdata are both optional. Figure out if they need to be read.
-	// The only way to do this is to compare the data record length by data format
-	recLengths := [4][4]int{{20, 18, 19, 17}, {28, 26, 27, 25}, {26, 24, 25, 23}, {34, 32, 33, 31}}
+	extent := geometry.NewBoundingBox(las.Header.MinX, las.Header.MaxX, las.Header.MinY, las.Header.MaxY, las.Header.MinZ, las.Header.MaxZ)
+	if lasFileLoader.ClipBounds != nil && las.Header.NumberPoints > 0 && *extent != (geometry.BoundingBox{}) {
+		// the tree is sized on the loaded points, all within the intersection of the declared extent, if not zero
+		// filled, and the clip box
+		if extent.Intersect(lasFileLoader.ClipBounds) == nil {
+			return errors.New("the clip bounds do not intersect the extent of " + filepath.Base(las.fileName))
+		}
+	}
 
-	if las.Header.PointRecordLength == recLengths[las.Header.PointFormatID][0] {
-		las.usePointIntensity = true
-		las.usePointUserdata = true
-	} else if las.Header.PointRecordLength == recLengths[las.Header.PointFormatID][1] {
-		las.usePointIntensity = false
-		las.usePointUserdata = true
-	} else if las.Header.PointRecordLength == recLengths[las.Header.PointFormatID][2] {
-		las.usePointIntensity = true
-		las.usePointUserdata = false
-	} else if las.Header.PointRecordLength == recLengths[las.Header.PointFormatID][3] {
-		las.usePointIntensity = false
-		las.usePointUserdata = false
+	reader, err := newPointRecordReader(las)
+	if err != nil {
+		return err
+	}
+	layout, err := getPointRecordLayout(las)
+	if err != nil {
+		return err
 	}
+	layout.extraBytes = lasFileLoader.getExtraAttributeDescriptors(las)
 
-	numCPUs := runtime.NumCPU()
-	var wg sync.WaitGroup
-	blockSize := las.Header.NumberPoints / numCPUs
+	// the points are read in batches into a buffer of bounded size, reused for all the batches
+	recordLength := las.Header.PointRecordLength
+	bufferPoints := getBufferPoints(las, lasFileLoader.ReadBufferSize)
+	b := make([]byte, bufferPoints*recordLength)
+	progress := utils.NewProgressReporter(lasFileLoader.ProgressCallback, utils.ProgressStageReading, int64(las.Header.NumberPoints))
+	for readPoints := 0; readPoints < las.Header.NumberPoints; {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		batchPoints := las.Header.NumberPoints - readPoints
+		if batchPoints > bufferPoints {
+			batchPoints = bufferPoints
+		}
+		n, err := reader.readRecords(b[:batchPoints*recordLength])
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			// truncated file, only the complete point records read so far are tiled
+			utils.LogOutput("> warning: file", filepath.Base(las.fileName), "is truncated, read", readPoints, "of", las.Header.NumberPoints, "points")
+			break
+		}
+		if err := lasFileLoader.loadPointRecords(ctx, b[:n*recordLength], readPoints, recordLength, layout, zCorrection, inSrid, las, progress); err != nil {
+			return err
+		}
+		readPoints += n
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if las.DroppedOriginPoints > 0 {
+		utils.LogOutput("> warning: dropped", las.DroppedOriginPoints, "points at (0,0,0) from file", filepath.Base(las.fileName))
+	}
+	if las.ClippedPoints > 0 {
+		utils.LogOutput("> skipped", las.ClippedPoints, "points outside of the clip bounds from file", filepath.Base(las.fileName))
+	}
+	return nil
+}
+
+// Returns the min and max 16 bit intensity of the points of the given LAS or LAZ file, reading only their point
+// records in batches of the given max size in bytes, 0 to use DefaultReadBufferSize. The min is greater than the max
+// if the file has no points or its point format has no intensity
+func ReadIntensityRange(fileName string, readBufferSize int) (uint16, uint16, error) {
+	las := LasFile{fileName: fileName, fileMode: "r", Header: LasHeader{}, VlrData: []VLR{}}
+	var err error
+	if las.f, err = os.Open(fileName); err != nil {
+		return 0, 0, err
+	}
+	defer func() { _ = las.f.Close() }()
+	if err := las.readHeader(); err != nil {
+		return 0, 0, err
+	}
+	if err := las.readVLRs(); err != nil {
+		return 0, 0, err
+	}
+	if err := validatePointRecords(&las); err != nil {
+		return 0, 0, err
+	}
+	setOptionalPointFields(&las)
+	layout, err := getPointRecordLayout(&las)
+	if err != nil {
+		return 0, 0, err
+	}
+	min, max := uint16(math.MaxUint16), uint16(0)
+	if layout.intensity < 0 || las.Header.NumberPoints == 0 {
+		return min, max, nil
+	}
+	reader, err := newPointRecordReader(&las)
+	if err != nil {
+		return 0, 0, err
+	}
+	recordLength := las.Header.PointRecordLength
+	b := make([]byte, getBufferPoints(&las, readBufferSize)*recordLength)
+	for readPoints := 0; readPoints < las.Header.NumberPoints; {
+		batchPoints := las.Header.NumberPoints - readPoints
+		if batchPoints > len(b)/recordLength {
+			batchPoints = len(b) / recordLength
+		}
+		n, err := reader.readRecords(b[:batchPoints*recordLength])
+		if err != nil {
+			return 0, 0, err
+		}
+		if n == 0 {
+			break
+		}
+		for i := 0; i < n; i++ {
+			intensity := binary.LittleEndian.Uint16(b[i*recordLength+layout.intensity:])
+			if intensity < min {
+				min = intensity
+			}
+			if intensity > max {
+				max = intensity
+			}
+		}
+		readPoints += n
+	}
+	return min, max, nil
+}
+
+// Returns the reader of the point records of the given las file, decompressing them on the fly for LAZ files
+func newPointRecordReader(las *LasFile) (pointRecordReader, error) {
+	if las.laszip != nil {
+		// LAZ files are decompressed on the fly into the same layout of the uncompressed point records
+		return newLazPointReader(las)
+	}
+	return &lasPointReader{
+		r:            las.f,
+		offset:       las.Header.OffsetToPoints,
+		end:          las.Header.OffsetToPoints + int64(las.Header.NumberPoints)*int64(las.Header.PointRecordLength),
+		recordLength: las.Header.PointRecordLength,
+	}, nil
+}
+
+// Returns the number of point records of the given las file fitting in a buffer of the given max size in bytes, 0 to
+// use DefaultReadBufferSize
+func getBufferPoints(las *LasFile, bufferSize int) int {
+	if bufferSize <= 0 {
+		bufferSize = DefaultReadBufferSize
+	}
+	bufferPoints := bufferSize / las.Header.PointRecordLength
+	if bufferPoints < 1 {
+		bufferPoints = 1
+	}
+	if bufferPoints > las.Header.NumberPoints {
+		bufferPoints = las.Header.NumberPoints
+	}
+	return bufferPoints
+}
+
+// Reads the point records of a LAS file in batches
+type pointRecordReader interface {
+	// Reads the next point records into the given buffer, whose length is a multiple of the record length,
+	// returning the number of read records
+	readRecords(b []byte) (int, error)
+}
+
+// Reads the uncompressed point records of a LAS file at 64 bit file offsets, so that files larger than 2 GB are read
+// correctly even where int is 32 bit
+type lasPointReader struct {
+	r            io.ReaderAt
+	offset       int64 // File offset of the next point record
+	end          int64 // File offset following the last point record
+	recordLength int
+}
+
+func (reader *lasPointReader) readRecords(b []byte) (int, error) {
+	if remaining := reader.end - reader.offset; int64(len(b)) > remaining {
+		b = b[:remaining]
+	}
+	n, err := reader.r.ReadAt(b, reader.offset)
+	if err == io.EOF {
+		err = nil
+	}
+	records := n / reader.recordLength
+	reader.offset += int64(records) * int64(reader.recordLength)
+	return records, err
+}
+
+// Parses the given point records, the first one being the given point of the file, into Point data structures,
+// splitting the work among the goroutines of the worker pool, and adds them to the Loader. The goroutines quit early
+// when the given context is done and report the parsed points to the given progress reporter. The first goroutine
+// failing to reproject its points stops the other ones and its error is returned
+func (lasFileLoader *LasFileLoader) loadPointRecords(ctx context.Context, b []byte, firstPoint int, recordLength int, layout pointRecordLayout, zCorrection converters.ElevationCorrector, inSrid int, las *LasFile, progress *utils.ProgressReporter) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var loadErr error
+	var loadErrOnce sync.Once
+	numberPoints := len(b) / recordLength
+	numCPUs := lasFileLoader.WorkerPool.Size()
+	tasks := make([]func(), 0, numCPUs+1)
+	blockSize := numberPoints / numCPUs
 	var startingPoint int
-	for startingPoint < las.Header.NumberPoints {
+	for startingPoint < numberPoints {
 		endingPoint := startingPoint + blockSize
-		if endingPoint >= las.Header.NumberPoints {
-			endingPoint = las.Header.NumberPoints - 1
-		}
-		wg.Add(1)
-		go func(pointSt, pointEnd int) {
-			defer wg.Done()
-
-			var offset int
-			// var p PointRecord0
-			for i := pointSt; i <= pointEnd; i++ {
-				offset = i * las.Header.PointRecordLength
-				// p := PointRecord0{}
-				X := float64(int32(binary.LittleEndian.Uint32(b[offset:offset+4])))*las.Header.XScaleFactor + las.Header.XOffset
-				offset += 4
-				Y := float64(int32(binary.LittleEndian.Uint32(b[offset:offset+4])))*las.Header.YScaleFactor + las.Header.YOffset
-				offset += 4
-				Z := float64(int32(binary.LittleEndian.Uint32(b[offset:offset+4])))*las.Header.ZScaleFactor + las.Header.ZOffset
-				offset += 4
-
-				var R, G, B, Intensity, Classification uint8
-				if las.usePointIntensity {
-					Intensity = uint8(binary.LittleEndian.Uint16(b[offset:offset+2]) / 256)
-					offset += 2
-				}
-				//p.BitField = PointBitField{Value: b[offset]}
-				offset++
-				//p.ClassBitField = ClassificationBitField{Value: b[offset]}
-				Classification = b[offset]
-				offset++
-				// p.ScanAngle = int8(b[offset])
-				offset++
-				if las.usePointUserdata {
-					// p.UserData = b[offset]
-					offset++
+		if endingPoint >= numberPoints {
+			endingPoint = numberPoints - 1
+		}
+		pointSt, pointEnd := startingPoint, endingPoint
+		tasks = append(tasks, func() {
+			var droppedPoints, clippedPoints int64
+			defer func() {
+				atomic.AddInt64(&las.DroppedOriginPoints, droppedPoints)
+				atomic.AddInt64(&las.ClippedPoints, clippedPoints)
+			}()
+			slab := data.NewPointSlab(data.DefaultPointSlabSize)
+			// the coordinates of a chunk of records are reprojected with a single call
+			values := make([]float64, 3*cancellationCheckPoints)
+			coords := make([]geometry.Coordinate, 0, cancellationCheckPoints)
+			records := make([]int, 0, cancellationCheckPoints)
+			for chunkSt := pointSt; chunkSt <= pointEnd; chunkSt += cancellationCheckPoints {
+				if chunkSt > pointSt && ctx.Err() != nil {
+					return
 				}
-				// p.PointSourceID = binary.LittleEndian.Uint16(b[offset : offset+2])
-				offset += 2
-
-				// las.pointData[i] = p
-
-				if las.Header.PointFormatID == 1 || las.Header.PointFormatID == 3 {
-					// las.gpsData[i] = math.Float64frombits(binary.LittleEndian.Uint64(b[offset : offset+8]))
-					offset += 8
+				chunkEnd := chunkSt + cancellationCheckPoints - 1
+				if chunkEnd > pointEnd {
+					chunkEnd = pointEnd
 				}
-				if las.Header.PointFormatID == 2 || las.Header.PointFormatID == 3 {
-					//rgb := RgbData{}
-					R = uint8(binary.LittleEndian.Uint16(b[offset:offset+2]) / 256)
-					offset += 2
-					G = uint8(binary.LittleEndian.Uint16(b[offset:offset+2]) / 256)
-					offset += 2
-					B = uint8(binary.LittleEndian.Uint16(b[offset:offset+2]) / 256)
-					offset += 2
-					// las.rgbData[i] = rgb
+				coords, records = coords[:0], records[:0]
+				for i := chunkSt; i <= chunkEnd; i++ {
+					if lasFileLoader.KeepEveryNth > 1 && (firstPoint+i)%lasFileLoader.KeepEveryNth != 0 {
+						// the stride follows the index in the file, regardless of how the records are split
+						continue
+					}
+					record := b[i*recordLength : (i+1)*recordLength]
+					X, Y, Z := &values[3*len(coords)], &values[3*len(coords)+1], &values[3*len(coords)+2]
+					*X = decodeScaledCoordinate(record[0:4], las.Header.XScaleFactor, las.Header.XOffset)
+					*Y = decodeScaledCoordinate(record[4:8], las.Header.YScaleFactor, las.Header.YOffset)
+					*Z = decodeScaledCoordinate(record[8:12], las.Header.ZScaleFactor, las.Header.ZOffset)
+					if lasFileLoader.DropOriginPoints && *X == 0 && *Y == 0 && *Z == 0 {
+						droppedPoints++
+						continue
+					}
+					if lasFileLoader.ClipBounds != nil && !lasFileLoader.ClipBounds.Contains(*X, *Y, *Z) {
+						clippedPoints++
+						continue
+					}
+					coords = append(coords, geometry.Coordinate{X: X, Y: Y, Z: Z})
+					records = append(records, i)
 				}
-				tr, err := lasFileLoader.CoordinateConverter.ConvertCoordinateSrid(inSrid, 4326, geometry.Coordinate{X: &X, Y: &Y, Z: &Z})
+				converted, err := lasFileLoader.CoordinateConverter.ConvertCoordinateSridBatch(inSrid, 4326, coords)
 				if err != nil {
-					log.Fatal(err)
+					loadErrOnce.Do(func() {
+						loadErr = errors.New("cannot reproject the points of " + filepath.Base(las.fileName) + ": " + err.Error())
+						cancel()
+					})
+					return
+				}
+
+				for j, tr := range converted {
+					record := b[records[j]*recordLength : (records[j]+1)*recordLength]
+					var R, G, B uint16
+					var Intensity uint8
+					if layout.intensity >= 0 {
+						intensity := binary.LittleEndian.Uint16(record[layout.intensity : layout.intensity+2])
+						if lasFileLoader.ScaleIntensity != nil {
+							Intensity = lasFileLoader.ScaleIntensity(intensity)
+						} else {
+							Intensity = uint8(intensity / 256)
+						}
+					}
+					Classification := record[layout.classification]
+					if layout.rgb >= 0 && !(lasFileLoader.ForceClassColor && lasFileLoader.ColorClassification != nil) {
+						R = binary.LittleEndian.Uint16(record[layout.rgb : layout.rgb+2])
+						G = binary.LittleEndian.Uint16(record[layout.rgb+2 : layout.rgb+4])
+						B = binary.LittleEndian.Uint16(record[layout.rgb+4 : layout.rgb+6])
+					} else if lasFileLoader.ColorClassification != nil {
+						R, G, B = lasFileLoader.ColorClassification(Classification)
+					}
+					elem := slab.NewPoint(*tr.X, *tr.Y, zCorrection.CorrectElevation(*tr.X, *tr.Y, *tr.Z), R, G, B, Intensity, Classification)
+					returns := record[layout.returns]
+					elem.ReturnNumber = returns & (1<<layout.returnBits - 1)
+					elem.NumberOfReturns = returns >> layout.returnBits & (1<<layout.returnBits - 1)
+					elem.PointSourceId = binary.LittleEndian.Uint16(record[layout.pointSourceId : layout.pointSourceId+2])
+					if layout.overlapFlag >= 0 {
+						elem.Overlap = record[layout.overlapFlag]&0x08 != 0
+					} else {
+						// legacy point formats store the classification in the lowest 5 bits, 12 denoting overlap points
+						elem.Overlap = Classification&0x1f == 12
+					}
+					if layout.gpsTime >= 0 {
+						elem.GpsTime = math.Float64frombits(binary.LittleEndian.Uint64(record[layout.gpsTime : layout.gpsTime+8]))
+					}
+					if len(layout.extraBytes) > 0 {
+						values := make([]float64, len(layout.extraBytes))
+						for k, descriptor := range layout.extraBytes {
+							values[k] = math.NaN()
+							if descriptor != nil {
+								values[k] = descriptor.decode(record)
+							}
+						}
+						elem.ExtraAttributes = &values
+					}
+					lasFileLoader.Loader.AddElement(elem)
 				}
-				elem := *data.NewPoint(*tr.X, *tr.Y, zCorrection.CorrectElevation(*tr.X, *tr.Y, *tr.Z), R, G, B, Intensity, Classification)
-				lasFileLoader.Loader.AddElement(&elem)
-				// las.pointDataOctElement[i] = elem
+				progress.Add(int64(chunkEnd - chunkSt + 1))
 			}
-		}(startingPoint, endingPoint)
+		})
 		startingPoint = endingPoint + 1
 	}
-	wg.Wait()
+	lasFileLoader.WorkerPool.Run(tasks...)
+	return loadErr
+}
+
+// Byte offsets, within a point record, of the fields read by the tiler. Offsets of the fields missing from the
+// record are negative
+type pointRecordLayout struct {
+	intensity      int
+	returns        int  // Byte storing the return number in its lowest bits, followed by the number of returns
+	returnBits     uint // Bits of the return number and of the number of returns
+	classification int
+	pointSourceId  int
+	overlapFlag    int // Byte storing the overlap flag in its bit 3, negative if points are flagged by the class 12
+	gpsTime        int
+	rgb            int
+	extraBytes     []*ExtraBytesDescriptor // Descriptors of the extra attributes read, nil for the ones missing from the records
+}
+
+// Returns the descriptors of the ExtraAttributes of the loader in the Extra Bytes VLR of the given las file, in the same
+// order. Attributes missing from the file or of unsupported data types, e.g. arrays, get nil and are logged
+func (lasFileLoader *LasFileLoader) getExtraAttributeDescriptors(las *LasFile) []*ExtraBytesDescriptor {
+	if len(lasFileLoader.ExtraAttributes) == 0 {
+		return nil
+	}
+	descriptors := make([]*ExtraBytesDescriptor, len(lasFileLoader.ExtraAttributes))
+	for i, name := range lasFileLoader.ExtraAttributes {
+		for j := range las.Header.ExtraBytes {
+			if las.Header.ExtraBytes[j].Name == name {
+				descriptors[i] = &las.Header.ExtraBytes[j]
+			}
+		}
+		if descriptors[i] == nil {
+			utils.LogOutput("> warning: file", filepath.Base(las.fileName), "has no extra bytes attribute", name)
+		} else if descriptors[i].ComponentType() == "" {
+			utils.LogOutput("> warning: skipping the extra bytes attribute", name, "of unsupported data type", descriptors[i].DataType, "of file", filepath.Base(las.fileName))
+			descriptors[i] = nil
+		}
+	}
+	return descriptors
+}
+
+// Minimum record length of the LAS 1.4 point formats 6-10, indexed by point format minus 6
+var extendedPointRecordLengths = [5]int{30, 36, 38, 59, 67}
+
+// Record lengths of the legacy point formats 0-3, indexed by point format, with intensity and userdata, userdata only,
+// intensity only and neither of them
+var legacyPointRecordLengths = [4][4]int{{20, 18, 19, 17}, {28, 26, 27, 25}, {26, 24, 25, 23}, {34, 32, 33, 31}}
+
+// Checks that the point record length of the given las file is valid for its point format, so that malformed headers
+// are reported rather than decoded with wrong field offsets. If the point records of an uncompressed file declared in
+// the header do not fit in the file, e.g. for truncated files, the number of points is reduced to the complete records
+// stored in the file
+func validatePointRecords(las *LasFile) error {
+	fileName := filepath.Base(las.fileName)
+	formatID := las.Header.PointFormatID
+	recordLength := las.Header.PointRecordLength
+	switch {
+	case formatID <= 3:
+		// the extra bytes declared by the Extra Bytes VLR follow the fields of the point format
+		valid := false
+		for _, length := range legacyPointRecordLengths[formatID] {
+			valid = valid || recordLength-getExtraBytesLength(las.Header.ExtraBytes) == length
+		}
+		if !valid {
+			return errors.New("unsupported point record length " + strconv.Itoa(recordLength) + " for LAS point format " + strconv.Itoa(int(formatID)) + " in file " + fileName)
+		}
+	case formatID >= 6 && formatID <= 10:
+		if recordLength < extendedPointRecordLengths[formatID-6] {
+			return errors.New("point record length " + strconv.Itoa(recordLength) + " too short for LAS point format " + strconv.Itoa(int(formatID)) + " in file " + fileName)
+		}
+	default:
+		return errors.New("unsupported LAS point format " + strconv.Itoa(int(formatID)) + " in file " + fileName)
+	}
+	if las.laszip != nil || las.Header.NumberPoints == 0 {
+		// compressed point records have no fixed size
+		return nil
+	}
+	info, err := las.f.Stat()
+	if err != nil {
+		return err
+	}
+	if las.Header.OffsetToPoints+int64(las.Header.NumberPoints)*int64(recordLength) > info.Size() {
+		completeRecords := 0
+		if info.Size() > las.Header.OffsetToPoints {
+			completeRecords = int((info.Size() - las.Header.OffsetToPoints) / int64(recordLength))
+		}
+		utils.LogOutput("> warning: file", fileName, "is truncated, its header declares", las.Header.NumberPoints, "points but it stores", completeRecords, "complete point records")
+		las.Header.NumberPoints = completeRecords
+	}
 	return nil
 }
+
+// Intensity and userdata of the legacy point formats 0-3 are both optional. Figures out if they need to be read
+// comparing the data record length with the ones expected by each point format
+func setOptionalPointFields(las *LasFile) {
+	if las.Header.PointFormatID > 3 {
+		return
+	}
+	recLengths := legacyPointRecordLengths
+	recordLength := las.Header.PointRecordLength - getExtraBytesLength(las.Header.ExtraBytes)
+
+	if recordLength == recLengths[las.Header.PointFormatID][0] {
+		las.usePointIntensity = true
+		las.usePointUserdata = true
+	} else if recordLength == recLengths[las.Header.PointFormatID][1] {
+		las.usePointIntensity = false
+		las.usePointUserdata = true
+	} else if recordLength == recLengths[las.Header.PointFormatID][2] {
+		las.usePointIntensity = true
+		las.usePointUserdata = false
+	} else if recordLength == recLengths[las.Header.PointFormatID][3] {
+		las.usePointIntensity = false
+		las.usePointUserdata = false
+	}
+}
+
+// Returns the layout of the point records of the given las file. Legacy point formats 0-3 store intensity, 3 bit
+// return numbers and classification after the coordinates and RGB after the GPS time of formats 1 and 3. Point formats
+// 6-10, introduced by LAS 1.4, store 4 bit return numbers and flags in two bytes, followed by the classification, and
+// always store the GPS time, followed by RGB in formats 7, 8 and 10
+func getPointRecordLayout(las *LasFile) (pointRecordLayout, error) {
+	formatID := las.Header.PointFormatID
+	switch {
+	case formatID <= 3:
+		layout := pointRecordLayout{intensity: -1, gpsTime: -1, rgb: -1, overlapFlag: -1}
+		offset := 12
+		if las.usePointIntensity {
+			layout.intensity = offset
+			offset += 2
+		}
+		layout.returns = offset
+		layout.returnBits = 3
+		offset++
+		layout.classification = offset
+		offset++
+		// scan angle rank
+		offset++
+		if las.usePointUserdata {
+			offset++
+		}
+		layout.pointSourceId = offset
+		offset += 2
+		if formatID == 1 || formatID == 3 {
+			layout.gpsTime = offset
+			offset += 8
+		}
+		if formatID == 2 || formatID == 3 {
+			layout.rgb = offset
+		}
+		return layout, nil
+	case formatID >= 6 && formatID <= 10:
+		if las.Header.VersionMajor == 1 && las.Header.VersionMinor < 4 {
+			return pointRecordLayout{}, errors.New("LAS point format " + strconv.Itoa(int(formatID)) + " requires LAS 1.4")
+		}
+		if las.Header.PointRecordLength < extendedPointRecordLengths[formatID-6] {
+			return pointRecordLayout{}, errors.New("LAS point record length too short for point format " + strconv.Itoa(int(formatID)))
+		}
+		layout := pointRecordLayout{intensity: 12, returns: 14, returnBits: 4, classification: 16, pointSourceId: 20, overlapFlag: 15, gpsTime: 22, rgb: -1}
+		if formatID == 7 || formatID == 8 || formatID == 10 {
+			layout.rgb = 30
+		}
+		return layout, nil
+	}
+	return pointRecordLayout{}, errors.New("unsupported LAS point format " + strconv.Itoa(int(formatID)))
+}
+
+// Decodes a scaled LAS coordinate stored as a little endian int32 into its float64 value raw * scale + offset.
+// The product and the sum are computed with a single rounding so that extreme scale factors (e.g. 1e-7) combined
+// with large offsets and raw values near the int32 limits do not lose precision.
+func decodeScaledCoordinate(b []byte, scale, offset float64) float64 {
+	return math.FMA(float64(int32(binary.LittleEndian.Uint32(b))), scale, offset)
+}
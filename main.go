@@ -22,20 +22,30 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"github.com/mfbonfigli/gocesiumtiler/app"
+	"github.com/mfbonfigli/gocesiumtiler/converters"
 	"github.com/mfbonfigli/gocesiumtiler/converters/gh_ellipsoid_to_geoid_z_converter"
+	"github.com/mfbonfigli/gocesiumtiler/converters/grid_geoid_z_converter"
 	"github.com/mfbonfigli/gocesiumtiler/converters/proj4_coordinate_converter"
 	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"github.com/mfbonfigli/gocesiumtiler/textread"
 	"github.com/mfbonfigli/gocesiumtiler/utils"
 	"log"
 	"os"
+	"os/signal"
+	"strconv"
+	"sync"
 	"time"
 )
 
 const VERSION = "1.0.3"
 
+// Fraction of the input points sampled to recommend the tiling options with the auto flag
+const autoSampleFraction = 0.01
+
 const logo = `
                            _                 _   _ _
   __ _  ___   ___ ___  ___(_)_   _ _ __ ___ | |_(_) | ___ _ __ 
@@ -78,6 +88,179 @@ func main() {
 	if *flags.Hq {
 		strategy = tiler.BoxedRandom
 	}
+	switch *flags.Sampling {
+	case "":
+	case "random":
+		strategy = tiler.FullyRandom
+	case "grid":
+		strategy = tiler.GridDecimation
+	case "poisson":
+		strategy = tiler.PoissonDisk
+	default:
+		log.Fatal("Error parsing input parameters: sampling must be either random, grid or poisson")
+	}
+	if *flags.Hq && *flags.Sampling != "" {
+		log.Fatal("Error parsing input parameters: the hq flag cannot be used together with the sampling flag")
+	}
+
+	outputMode := tiler.GeocentricCoordinates
+	switch *flags.OutputMode {
+	case "", "geocentric":
+	case "enu":
+		outputMode = tiler.LocalEnuCoordinates
+	case "raw":
+		outputMode = tiler.RawCoordinates
+	default:
+		log.Fatal("Error parsing input parameters: outputmode must be either geocentric, enu or raw")
+	}
+
+	tileNaming := tiler.NumericTileNames
+	switch *flags.TileNaming {
+	case "", "numeric":
+	case "hierarchical":
+		tileNaming = tiler.HierarchicalTileNames
+	default:
+		log.Fatal("Error parsing input parameters: tilenaming must be either numeric or hierarchical")
+	}
+
+	subtreePath, err := utils.ParseTilePath(*flags.Subtree)
+	if err != nil {
+		log.Fatal("Error parsing input parameters: ", err)
+	}
+
+	graftSlot, err := utils.ParseChildPath(*flags.Slot)
+	if err != nil {
+		log.Fatal("Error parsing input parameters: ", err)
+	}
+
+	fileMode, err := utils.ParseFileMode(*flags.FileMode)
+	if err != nil {
+		log.Fatal("Error parsing input parameters: ", err)
+	}
+
+	dirMode, err := utils.ParseFileMode(*flags.DirMode)
+	if err != nil {
+		log.Fatal("Error parsing input parameters: ", err)
+	}
+
+	octantOrder, err := utils.ParseOctantOrder(*flags.OctantOrder)
+	if err != nil {
+		log.Fatal("Error parsing input parameters: ", err)
+	}
+
+	classificationAlpha, err := utils.ParseClassificationValues(*flags.ClassificationAlpha)
+	if err != nil {
+		log.Fatal("Error parsing input parameters: ", err)
+	}
+
+	fileZOffsets, err := utils.ParseFileOffsets(*flags.FileZOffsets)
+	if err != nil {
+		log.Fatal("Error parsing input parameters: ", err)
+	}
+
+	fileSrids, err := utils.ParseFileSrids(*flags.FileSrids)
+	if err != nil {
+		log.Fatal("Error parsing input parameters: ", err)
+	}
+
+	// eventually compute geometric errors from the tile size or the point spacing
+	geometricErrors := tiler.DensityGeometricErrors
+	if *flags.DiagonalFraction > 0 {
+		geometricErrors = tiler.DiagonalGeometricErrors
+	}
+	if *flags.SpacingErrors {
+		if geometricErrors != tiler.DensityGeometricErrors {
+			log.Fatal("Error parsing input parameters: the gespacing and gediagonal flags cannot be used together")
+		}
+		geometricErrors = tiler.SpacingGeometricErrors
+	}
+
+	// eventually write glTF tiles
+	outputFormat := tiler.PntsOutput
+	switch *flags.Format {
+	case "pnts":
+	case "glb":
+		outputFormat = tiler.GlbOutput
+	default:
+		log.Fatal("Error parsing input parameters: format must be either pnts or glb")
+	}
+
+	// eventually write bounding spheres or oriented boxes
+	boundingVolumes := tiler.RegionBoundingVolumes
+	switch *flags.Sphere {
+	case "":
+	case "root":
+		boundingVolumes = tiler.RootBoundingSphere
+	case "all":
+		boundingVolumes = tiler.BoundingSpheres
+	default:
+		log.Fatal("Error parsing input parameters: sphere must be either root or all")
+	}
+	if *flags.Box {
+		if boundingVolumes != tiler.RegionBoundingVolumes {
+			log.Fatal("Error parsing input parameters: the box and sphere flags cannot be used together")
+		}
+		boundingVolumes = tiler.OrientedBoundingBoxes
+	}
+
+	classificationGroups, err := tiler.ParseClassificationGroups(*flags.Groups)
+	if err != nil {
+		log.Fatal("Error parsing input parameters: ", err)
+	}
+
+	geoidGrids, err := grid_geoid_z_converter.NewGeoidGrids(*flags.GeoidGrids)
+	if err != nil {
+		log.Fatal("Error parsing input parameters: ", err)
+	}
+	geoidHeightProviders := make([]converters.GeoidHeightProvider, len(geoidGrids))
+	for i, grid := range geoidGrids {
+		geoidHeightProviders[i] = grid
+	}
+
+	intensityScaling, err := tiler.ParseIntensityScaling(*flags.Intensity)
+	if err != nil {
+		log.Fatal("Error parsing input parameters: ", err)
+	}
+
+	classificationColorMap, err := tiler.ParseClassificationColorMap(*flags.ClassColors)
+	if err != nil {
+		log.Fatal("Error parsing input parameters: ", err)
+	}
+
+	unmappedClassColor, err := tiler.ParseColor(*flags.UnmappedColor)
+	if err != nil {
+		log.Fatal("Error parsing input parameters: ", err)
+	}
+
+	elevationRamp, err := tiler.ParseElevationRamp(*flags.ElevationRamp)
+	if err != nil {
+		log.Fatal("Error parsing input parameters: ", err)
+	}
+
+	includeClasses, err := utils.ParseClassifications(*flags.Include)
+	if err != nil {
+		log.Fatal("Error parsing input parameters: ", err)
+	}
+
+	excludeClasses, err := utils.ParseClassifications(*flags.Exclude)
+	if err != nil {
+		log.Fatal("Error parsing input parameters: ", err)
+	}
+
+	clipBounds, err := utils.ParseBoundingBox(*flags.Clip)
+	if err != nil {
+		log.Fatal("Error parsing input parameters: ", err)
+	}
+
+	rootTransform, err := tiler.ParseTransform(*flags.Transform)
+	if err != nil {
+		log.Fatal("Error parsing input parameters: ", err)
+	}
+
+	textColumns, err := textread.ParseColumns(*flags.Columns)
+	if err != nil {
+		log.Fatal("Error parsing input parameters: ", err)
+	}
 
 	// default converter services
 	var coordinateConverterService = proj4_coordinate_converter.NewProj4CoordinateConverter()
@@ -85,18 +268,85 @@ func main() {
 
 	// Put args inside a TilerOptions struct
 	opts := tiler.TilerOptions{
-		Input:                  *flags.Input,
-		Output:                 *flags.Output,
-		Srid:                   *flags.Srid,
-		ZOffset:                *flags.ZOffset,
-		MaxNumPointsPerNode:    int32(*flags.MaxNumPts),
-		EnableGeoidZCorrection: *flags.ZGeoidCorrection,
-		FolderProcessing:       *flags.FolderProcessing,
-		Recursive:              *flags.RecursiveFolderProcessing,
-		Silent:                 *flags.Silent,
-		Strategy:               strategy,
-		CoordinateConverter:    coordinateConverterService,
-		ElevationConverter:     elevationConverterService,
+		Input:                    *flags.Input,
+		Output:                   *flags.Output,
+		Srid:                     *flags.Srid,
+		ZOffset:                  *flags.ZOffset,
+		FileZOffsets:             fileZOffsets,
+		FileSrids:                fileSrids,
+		MaxNumPointsPerNode:      int32(*flags.MaxNumPts),
+		EnableGeoidZCorrection:   *flags.ZGeoidCorrection,
+		FolderProcessing:         *flags.FolderProcessing,
+		Recursive:                *flags.RecursiveFolderProcessing,
+		Silent:                   *flags.Silent,
+		Strategy:                 strategy,
+		BoundsSigmas:             *flags.BoundsSigmas,
+		SubtreePath:              subtreePath,
+		ClassificationAlpha:      classificationAlpha,
+		FreeExportedItems:        *flags.LowMemory,
+		SubtreeLevels:            *flags.SubtreeLevels,
+		NormalsMaxDepth:          *flags.NormalsMaxDepth,
+		ComputeNormals:           *flags.Normals,
+		NormalNeighbors:          *flags.NormalNeighbors,
+		PositionPrecision:        *flags.Precision,
+		QuantizePositions:        *flags.Quantize,
+		WriteStatistics:          *flags.Statistics,
+		EnforceRegionContainment: *flags.Containment,
+		Concurrency:              *flags.Concurrency,
+		ClassificationGroups:     classificationGroups,
+		UseWktProjection:         *flags.Wkt,
+		MaxTileBytes:             *flags.MaxTileBytes,
+		GeoidHeightProviders:     geoidHeightProviders,
+		MonotonicGeometricError:  *flags.Monotonic,
+		TextColumns:              textColumns,
+		TextDelimiter:            textread.ParseDelimiter(*flags.Delimiter),
+		ColorDepth:               *flags.ColorDepth,
+		Rgb565Colors:             *flags.Rgb565,
+		NormalizeIntensity:       *flags.NormalizeIntensity,
+		ReadBufferSize:           *flags.ReadBuffer * 1024 * 1024,
+		MergeFiles:               *flags.Merge,
+		DepthFolders:             *flags.DepthFolders,
+		DropOriginPoints:         *flags.DropOrigin,
+		DeflateBuffers:           *flags.Deflate,
+		BoundingVolumes:          boundingVolumes,
+		AtomicWrites:             *flags.Atomic,
+		TempDir:                  *flags.TempDir,
+		RandomSeed:               *flags.Seed,
+		GeometricErrors:          geometricErrors,
+		DiagonalFraction:         *flags.DiagonalFraction,
+		MasterTileset:            *flags.Master,
+		OutputFormat:             outputFormat,
+		WriteLegend:              *flags.Legend,
+		MaxLevels:                *flags.MaxLevels,
+		UrlQuery:                 *flags.UrlQuery,
+		OctantOrder:              octantOrder,
+		NumWriters:               *flags.Writers,
+		OverlapCellSize:          *flags.OverlapCell,
+		GraftTileset:             *flags.Graft,
+		GraftSlot:                graftSlot,
+		FileMode:                 fileMode,
+		DirMode:                  dirMode,
+		MinRegionHeight:          *flags.MinHeight,
+		IntensityScaling:         intensityScaling,
+		ClassificationColorMap:   classificationColorMap,
+		UnmappedClassColor:       unmappedClassColor,
+		ForceClassificationColor: *flags.ForceClassColors,
+		ElevationRamp:            elevationRamp,
+		IncludeClasses:           includeClasses,
+		ExcludeClasses:           excludeClasses,
+		ClipBounds:               clipBounds,
+		KeepEveryNth:             *flags.KeepEvery,
+		RootTransform:            rootTransform,
+		GzipOutput:               *flags.Gzip,
+		TightBounds:              *flags.Tight,
+		OutputMode:               outputMode,
+		Resume:                   *flags.Resume,
+		DeduplicateTolerance:     *flags.Dedup,
+		ExtraAttributes:          tiler.ParseExtraAttributes(*flags.ExtraBytes),
+		TileNamingStrategy:       tileNaming,
+		ImplicitTiling:           *flags.Implicit,
+		CoordinateConverter:      coordinateConverterService,
+		ElevationConverter:       elevationConverterService,
 	}
 
 	// Validate TilerOptions
@@ -104,9 +354,34 @@ func main() {
 		log.Fatal("Error parsing input parameters: " + msg)
 	}
 
+	// Eventually replace the tiling parameters with the ones recommended for a sample of the input
+	if *flags.Auto {
+		recommended, err := app.RecommendOptions(&opts, autoSampleFraction)
+		if err != nil {
+			log.Fatal("Error recommending the tiling options: ", err)
+		}
+		opts = *recommended
+	}
+
+	// Logs the progress of the reading and writing of the tiles
+	if !*flags.Silent {
+		opts.ProgressCallback = newProgressLogger()
+	}
+
+	// Stops the tiler on interrupt, e.g. Ctrl+C, removing the tiles of the incomplete tileset
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	go func() {
+		<-interrupts
+		utils.LogOutput("Interrupted, stopping...")
+		cancel()
+	}()
+
 	// Starts the tiler
 	// defer timeTrack(time.Now(), "tiler")
-	err := app.RunTiler(&opts)
+	err = app.RunTilerContext(ctx, &opts)
 	if err != nil {
 		log.Fatal("Error while tiling: ", err)
 	} else {
@@ -123,16 +398,99 @@ func validateOptions(opts *tiler.TilerOptions) (string, bool) {
 	if _, err := os.Stat(opts.Output); os.IsNotExist(err) {
 		return "Output folder not found", false
 	}
+	if opts.ColorDepth != 8 && opts.ColorDepth != 16 {
+		return "Color depth must be either 8 or 16", false
+	}
+	if opts.Rgb565Colors && len(opts.ClassificationAlpha) > 0 {
+		return "RGB565 colors cannot be used together with the classification alpha", false
+	}
+	if opts.ReadBufferSize <= 0 {
+		return "Read buffer size must be greater than 0", false
+	}
+	if opts.MaxNumPointsPerNode <= 0 {
+		return "Max number of points per tile must be greater than 0", false
+	}
+	if opts.MaxLevels < 0 {
+		return "Max levels must not be negative", false
+	}
+	if opts.OverlapCellSize < 0 {
+		return "Overlap cell size must not be negative", false
+	}
+	if opts.DeduplicateTolerance < 0 {
+		return "Deduplication tolerance must not be negative", false
+	}
+	if opts.GraftTileset != "" {
+		if _, err := os.Stat(opts.GraftTileset); err != nil {
+			return "Graft tileset not found", false
+		}
+		if len(opts.GraftSlot) == 0 {
+			return "The graft tileset requires a slot", false
+		}
+		if len(opts.ClassificationGroups) > 0 || opts.BoundingVolumes != tiler.RegionBoundingVolumes || (opts.FolderProcessing && !opts.MergeFiles && !opts.MasterTileset) {
+			return "The graft tileset requires a single written tileset, i.e. a single input file, merged files or a master tileset, and cannot be used together with classification groups, bounding spheres or boxes", false
+		}
+	}
+	if opts.KeepEveryNth < 1 {
+		return "Number of points per kept point must be at least 1", false
+	}
+	if opts.MinRegionHeight < 0 {
+		return "Min region height must not be negative", false
+	}
+	if opts.NumWriters < 0 {
+		return "Number of writers must not be negative", false
+	}
+	if opts.MergeFiles && opts.Concurrency > 0 {
+		return "Merged files cannot be tiled together with the concurrency option", false
+	}
+	if opts.NormalNeighbors < 3 {
+		return "Normal neighbors must be at least 3", false
+	}
+	if opts.EnforceRegionContainment && opts.BoundingVolumes != tiler.RegionBoundingVolumes {
+		return "Bounding spheres and boxes cannot be used together with the region containment", false
+	}
+	if opts.MasterTileset && (!opts.FolderProcessing || opts.MergeFiles || len(opts.ClassificationGroups) > 0 || opts.BoundingVolumes != tiler.RegionBoundingVolumes) {
+		return "The master tileset requires folder processing and cannot be used together with merged files, classification groups, bounding spheres or boxes", false
+	}
+	if opts.OutputMode == tiler.RawCoordinates && (opts.EnforceRegionContainment || opts.MasterTileset || opts.GraftTileset != "") {
+		return "Raw output coordinates cannot be used together with the region containment, the master tileset or the graft tileset", false
+	}
+	if opts.OutputFormat == tiler.GlbOutput && (opts.QuantizePositions || opts.Rgb565Colors || opts.ColorDepth == 16 || opts.NormalizeIntensity || opts.DeflateBuffers || len(opts.ExtraAttributes) > 0) {
+		return "glb tiles cannot be used together with quantized positions, RGB565 colors, 16 bit colors, normalized intensities, deflate compression or extra bytes attributes", false
+	}
+	if opts.TempDir != "" {
+		if info, err := os.Stat(opts.TempDir); err != nil || !info.IsDir() {
+			return "Temp folder not found", false
+		}
+	}
 	return "", true
 }
 
+// Returns a progress callback logging the progress of each stage in steps of 10%
+func newProgressLogger() func(stage string, done, total int64) {
+	var mutex sync.Mutex
+	lastSteps := make(map[string]int64)
+	return func(stage string, done, total int64) {
+		if total <= 0 {
+			return
+		}
+		step := done * 10 / total
+		mutex.Lock()
+		defer mutex.Unlock()
+		if lastStep, ok := lastSteps[stage]; ok && lastStep == step {
+			return
+		}
+		lastSteps[stage] = step
+		utils.LogOutput(">", stage, strconv.FormatInt(step*10, 10)+"%")
+	}
+}
+
 func timeTrack(start time.Time, name string) {
 	elapsed := time.Since(start)
 	utils.LogOutput(fmt.Sprintf("%s took %s", name, elapsed))
 }
 
 func printLogo() {
-	fmt.Println(logo)
+	fmt.Print(logo)
 }
 
 func showHelp() {
@@ -0,0 +1,426 @@
+package plyread
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"github.com/mfbonfigli/gocesiumtiler/converters"
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"github.com/mfbonfigli/gocesiumtiler/structs/geometry"
+	"github.com/mfbonfigli/gocesiumtiler/structs/point_loader"
+	"github.com/mfbonfigli/gocesiumtiler/utils"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	plyAscii              = "ascii"
+	plyBinaryLittleEndian = "binary_little_endian"
+	plyBinaryBigEndian    = "binary_big_endian"
+)
+
+// Size in bytes of the PLY scalar types, indexed by both their original and their sized name
+var plyTypeSizes = map[string]int{
+	"char": 1, "int8": 1, "uchar": 1, "uint8": 1,
+	"short": 2, "int16": 2, "ushort": 2, "uint16": 2,
+	"int": 4, "int32": 4, "uint": 4, "uint32": 4,
+	"float": 4, "float32": 4, "double": 8, "float64": 8,
+}
+
+// Property of a PLY element. List properties have a non empty CountType
+type plyProperty struct {
+	Name      string
+	Type      string
+	CountType string
+}
+
+// Element declared in the header of a PLY file, e.g. vertex or face
+type plyElement struct {
+	Name       string
+	Count      int
+	Properties []plyProperty
+}
+
+// Parsed header of a PLY file
+type plyHeader struct {
+	Format   string
+	Elements []plyElement
+}
+
+// Reads the vertices of PLY files, either ascii or binary, and adds them as Points to a Loader
+type PlyFileLoader struct {
+	CoordinateConverter converters.CoordinateConverter
+	Loader              point_loader.Loader
+	WorkerPool          *utils.WorkerPool
+	ColorClassification func(class uint8) (R, G, B uint16) // If not nil, colors the vertices of the files without red, green and blue properties from their classification
+	ForceClassColor     bool                               // Colors the vertices with ColorClassification even if the file has colors
+	ClipBounds          *geometry.BoundingBox              // If not nil, skips the vertices outside of this box, boundaries included, in the srid of the file
+	KeepEveryNth        int                                // If > 1, loads only the vertices whose index in the file is a multiple of this value
+}
+
+func NewPlyFileLoader(coordinateConverter converters.CoordinateConverter, loader point_loader.Loader, workerPool *utils.WorkerPool) *PlyFileLoader {
+	return &PlyFileLoader{
+		CoordinateConverter: coordinateConverter,
+		Loader:              loader,
+		WorkerPool:          workerPool,
+	}
+}
+
+// Reads the vertices of the given PLY file, converting their coordinates from the given srid to EPSG:4326 and
+// correcting their elevation. The x, y, z properties are required, red, green, blue, intensity and classification
+// are read if present
+func (plyFileLoader *PlyFileLoader) LoadPlyFile(fileName string, zCorrection converters.ElevationCorrector, inSrid int) error {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	reader := bufio.NewReader(f)
+	header, err := readPlyHeader(reader)
+	if err != nil {
+		return err
+	}
+
+	// skip the elements preceding the vertices
+	for _, element := range header.Elements {
+		if element.Name == "vertex" {
+			decoder, err := newVertexDecoder(element)
+			if err != nil {
+				return err
+			}
+			if header.Format == plyAscii {
+				return plyFileLoader.readAsciiVertices(reader, element, decoder, zCorrection, inSrid)
+			}
+			return plyFileLoader.readBinaryVertices(reader, header.Format, element, decoder, zCorrection, inSrid)
+		}
+		if err := skipPlyElement(reader, header.Format, element); err != nil {
+			return err
+		}
+	}
+	return errors.New("PLY file without vertex element")
+}
+
+// Parses the header of a PLY file, leaving the reader at the beginning of the body
+func readPlyHeader(reader *bufio.Reader) (*plyHeader, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil || strings.TrimSpace(line) != "ply" {
+		return nil, errors.New("not a valid PLY file")
+	}
+	header := plyHeader{}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, errors.New("PLY header not terminated by end_header")
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "format":
+			if len(fields) < 2 || (fields[1] != plyAscii && fields[1] != plyBinaryLittleEndian && fields[1] != plyBinaryBigEndian) {
+				return nil, errors.New("unsupported PLY format " + strings.TrimSpace(line))
+			}
+			header.Format = fields[1]
+		case "element":
+			if len(fields) != 3 {
+				return nil, errors.New("invalid PLY element " + strings.TrimSpace(line))
+			}
+			count, err := strconv.Atoi(fields[2])
+			if err != nil || count < 0 {
+				return nil, errors.New("invalid PLY element count " + fields[2])
+			}
+			header.Elements = append(header.Elements, plyElement{Name: fields[1], Count: count})
+		case "property":
+			if len(header.Elements) == 0 {
+				return nil, errors.New("PLY property declared outside of an element")
+			}
+			property, err := parsePlyProperty(fields)
+			if err != nil {
+				return nil, err
+			}
+			element := &header.Elements[len(header.Elements)-1]
+			element.Properties = append(element.Properties, property)
+		case "end_header":
+			if header.Format == "" {
+				return nil, errors.New("PLY header without format")
+			}
+			return &header, nil
+		}
+	}
+}
+
+// Parses a "property type name" or "property list count_type type name" header line
+func parsePlyProperty(fields []string) (plyProperty, error) {
+	if len(fields) == 5 && fields[1] == "list" {
+		if _, ok := plyTypeSizes[fields[2]]; !ok {
+			return plyProperty{}, errors.New("unsupported PLY type " + fields[2])
+		}
+		if _, ok := plyTypeSizes[fields[3]]; !ok {
+			return plyProperty{}, errors.New("unsupported PLY type " + fields[3])
+		}
+		return plyProperty{Name: fields[4], Type: fields[3], CountType: fields[2]}, nil
+	}
+	if len(fields) != 3 {
+		return plyProperty{}, errors.New("invalid PLY property " + strings.Join(fields, " "))
+	}
+	if _, ok := plyTypeSizes[fields[1]]; !ok {
+		return plyProperty{}, errors.New("unsupported PLY type " + fields[1])
+	}
+	return plyProperty{Name: fields[2], Type: fields[1]}, nil
+}
+
+// Skips all the records of the given element
+func skipPlyElement(reader *bufio.Reader, format string, element plyElement) error {
+	if format == plyAscii {
+		for i := 0; i < element.Count; i++ {
+			if _, err := reader.ReadString('\n'); err != nil {
+				return errors.New("PLY file is truncated")
+			}
+		}
+		return nil
+	}
+	byteOrder := getPlyByteOrder(format)
+	for i := 0; i < element.Count; i++ {
+		for _, property := range element.Properties {
+			size := plyTypeSizes[property.Type]
+			if property.CountType != "" {
+				countBytes := make([]byte, plyTypeSizes[property.CountType])
+				if _, err := io.ReadFull(reader, countBytes); err != nil {
+					return errors.New("PLY file is truncated")
+				}
+				size *= int(decodePlyBinaryValue(countBytes, property.CountType, byteOrder))
+			}
+			if _, err := reader.Discard(size); err != nil {
+				return errors.New("PLY file is truncated")
+			}
+		}
+	}
+	return nil
+}
+
+func getPlyByteOrder(format string) binary.ByteOrder {
+	if format == plyBinaryBigEndian {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+// Positions, types and byte offsets of the vertex properties used to build the Points
+type vertexDecoder struct {
+	Indexes    map[string]int    // index of each property among the vertex properties
+	Types      map[string]string // type of each property
+	Offsets    map[string]int    // byte offset of each property within a binary vertex record
+	RecordSize int               // size in bytes of a binary vertex record
+}
+
+// Aliases of the property names, as written by the common point cloud tools
+var plyPropertyAliases = map[string]string{
+	"r":                     "red",
+	"g":                     "green",
+	"b":                     "blue",
+	"diffuse_red":           "red",
+	"diffuse_green":         "green",
+	"diffuse_blue":          "blue",
+	"scalar_intensity":      "intensity",
+	"scalar_classification": "classification",
+}
+
+func newVertexDecoder(element plyElement) (*vertexDecoder, error) {
+	decoder := vertexDecoder{
+		Indexes: make(map[string]int),
+		Types:   make(map[string]string),
+		Offsets: make(map[string]int),
+	}
+	for i, property := range element.Properties {
+		if property.CountType != "" {
+			return nil, errors.New("unsupported PLY vertex list property " + property.Name)
+		}
+		name := property.Name
+		if alias, ok := plyPropertyAliases[name]; ok {
+			name = alias
+		}
+		if _, ok := decoder.Indexes[name]; !ok {
+			decoder.Indexes[name] = i
+			decoder.Types[name] = property.Type
+			decoder.Offsets[name] = decoder.RecordSize
+		}
+		decoder.RecordSize += plyTypeSizes[property.Type]
+	}
+	for _, name := range []string{"x", "y", "z"} {
+		if _, ok := decoder.Indexes[name]; !ok {
+			return nil, errors.New("PLY vertex element without " + name + " property")
+		}
+	}
+	return &decoder, nil
+}
+
+// Reads the whole binary vertex block and decodes it in parallel
+func (plyFileLoader *PlyFileLoader) readBinaryVertices(reader *bufio.Reader, format string, element plyElement, decoder *vertexDecoder, zCorrection converters.ElevationCorrector, inSrid int) error {
+	b := make([]byte, element.Count*decoder.RecordSize)
+	if _, err := io.ReadFull(reader, b); err != nil {
+		return errors.New("PLY file is truncated")
+	}
+	byteOrder := getPlyByteOrder(format)
+	value := func(record []byte, name string) (float64, bool) {
+		offset, ok := decoder.Offsets[name]
+		if !ok {
+			return 0, false
+		}
+		propertyType := decoder.Types[name]
+		return decodePlyBinaryValue(record[offset:offset+plyTypeSizes[propertyType]], propertyType, byteOrder), true
+	}
+
+	numCPUs := plyFileLoader.WorkerPool.Size()
+	blockSize := element.Count/numCPUs + 1
+	tasks := make([]func(), 0, numCPUs)
+	errs := make(chan error, numCPUs+1)
+	for start := 0; start < element.Count; start += blockSize {
+		blockStart, blockEnd := start, int(math.Min(float64(start+blockSize), float64(element.Count)))
+		tasks = append(tasks, func() {
+			slab := data.NewPointSlab(data.DefaultPointSlabSize)
+			for i := blockStart; i < blockEnd; i++ {
+				if !plyFileLoader.keepsVertex(i) {
+					continue
+				}
+				record := b[i*decoder.RecordSize : (i+1)*decoder.RecordSize]
+				if err := plyFileLoader.addVertex(func(name string) (float64, bool) { return value(record, name) }, decoder, zCorrection, inSrid, slab); err != nil {
+					errs <- err
+					return
+				}
+			}
+		})
+	}
+	plyFileLoader.WorkerPool.Run(tasks...)
+	close(errs)
+	return <-errs
+}
+
+// Reads the ascii vertex records, one per line
+func (plyFileLoader *PlyFileLoader) readAsciiVertices(reader *bufio.Reader, element plyElement, decoder *vertexDecoder, zCorrection converters.ElevationCorrector, inSrid int) error {
+	slab := data.NewPointSlab(data.DefaultPointSlabSize)
+	for i := 0; i < element.Count; i++ {
+		line, err := reader.ReadString('\n')
+		if err != nil && (err != io.EOF || strings.TrimSpace(line) == "") {
+			return errors.New("PLY file is truncated")
+		}
+		if !plyFileLoader.keepsVertex(i) {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < len(element.Properties) {
+			return errors.New("invalid PLY vertex record " + strconv.Itoa(i))
+		}
+		values := make([]float64, len(element.Properties))
+		for j := range values {
+			if values[j], err = strconv.ParseFloat(fields[j], 64); err != nil {
+				return errors.New("invalid PLY vertex record " + strconv.Itoa(i))
+			}
+		}
+		value := func(name string) (float64, bool) {
+			index, ok := decoder.Indexes[name]
+			if !ok {
+				return 0, false
+			}
+			return values[index], true
+		}
+		if err := plyFileLoader.addVertex(value, decoder, zCorrection, inSrid, slab); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns true if the vertex at the given index of the file is loaded according to KeepEveryNth
+func (plyFileLoader *PlyFileLoader) keepsVertex(i int) bool {
+	return plyFileLoader.KeepEveryNth <= 1 || i%plyFileLoader.KeepEveryNth == 0
+}
+
+// Builds a Point, allocated from the given slab, from the property values returned by the given function and adds it
+// to the Loader
+func (plyFileLoader *PlyFileLoader) addVertex(value func(name string) (float64, bool), decoder *vertexDecoder, zCorrection converters.ElevationCorrector, inSrid int, slab *data.PointSlab) error {
+	X, _ := value("x")
+	Y, _ := value("y")
+	Z, _ := value("z")
+	if plyFileLoader.ClipBounds != nil && !plyFileLoader.ClipBounds.Contains(X, Y, Z) {
+		return nil
+	}
+	color := func(name string) uint16 {
+		v, ok := value(name)
+		if !ok {
+			return 0
+		}
+		return scalePlyColor(v, decoder.Types[name])
+	}
+	R, G, B := color("red"), color("green"), color("blue")
+	var Intensity uint8
+	if v, ok := value("intensity"); ok {
+		Intensity = scalePlyChannel(v, decoder.Types["intensity"])
+	}
+	var Classification uint8
+	if v, ok := value("classification"); ok {
+		Classification = uint8(math.Max(0, math.Min(255, v)))
+	}
+	if _, hasColor := decoder.Types["red"]; plyFileLoader.ColorClassification != nil && (!hasColor || plyFileLoader.ForceClassColor) {
+		R, G, B = plyFileLoader.ColorClassification(Classification)
+	}
+	tr, err := plyFileLoader.CoordinateConverter.ConvertCoordinateSrid(inSrid, 4326, geometry.Coordinate{X: &X, Y: &Y, Z: &Z})
+	if err != nil {
+		return err
+	}
+	plyFileLoader.Loader.AddElement(slab.NewPoint(*tr.X, *tr.Y, zCorrection.CorrectElevation(*tr.X, *tr.Y, *tr.Z), R, G, B, Intensity, Classification))
+	return nil
+}
+
+// Scales a color value to 16 bits. 8 bit values are multiplied by 257 so that the maximum maps to the maximum,
+// floating point values are expected in the [0, 1] range
+func scalePlyColor(v float64, propertyType string) uint16 {
+	switch propertyType {
+	case "float", "float32", "double", "float64":
+		v *= 65535
+	case "char", "int8", "uchar", "uint8":
+		v *= 257
+	case "int", "int32", "uint", "uint32":
+		v /= 65536
+	}
+	return uint16(math.Max(0, math.Min(65535, math.Round(v))))
+}
+
+// Scales an intensity value to 8 bits. 16 bit values are divided by 256 as the LAS ones, floating point values
+// are expected in the [0, 1] range
+func scalePlyChannel(v float64, propertyType string) uint8 {
+	switch propertyType {
+	case "float", "float32", "double", "float64":
+		v *= 255
+	case "short", "int16", "ushort", "uint16":
+		v /= 256
+	case "int", "int32", "uint", "uint32":
+		v /= 16777216
+	}
+	return uint8(math.Max(0, math.Min(255, math.Round(v))))
+}
+
+// Decodes a binary value of the given PLY type
+func decodePlyBinaryValue(b []byte, propertyType string, byteOrder binary.ByteOrder) float64 {
+	switch propertyType {
+	case "char", "int8":
+		return float64(int8(b[0]))
+	case "uchar", "uint8":
+		return float64(b[0])
+	case "short", "int16":
+		return float64(int16(byteOrder.Uint16(b)))
+	case "ushort", "uint16":
+		return float64(byteOrder.Uint16(b))
+	case "int", "int32":
+		return float64(int32(byteOrder.Uint32(b)))
+	case "uint", "uint32":
+		return float64(byteOrder.Uint32(b))
+	case "float", "float32":
+		return float64(math.Float32frombits(byteOrder.Uint32(b)))
+	}
+	return math.Float64frombits(byteOrder.Uint64(b))
+}
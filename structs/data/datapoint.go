@@ -1,21 +1,34 @@
 package data
 
+import "math"
+
 // Contains data of a Point Cloud Point, namely X,Y,Z coords,
-// R,G,B color components, Intensity and Classification
+// R,G,B 16 bit color components, Intensity, Classification, return numbers, flightline, GPS time and extra attributes
 type Point struct {
-	X              float64
-	Y              float64
-	Z              float64
-	R              uint8
-	G              uint8
-	B              uint8
-	Intensity      uint8
-	Classification uint8
+	X               float64
+	Y               float64
+	Z               float64
+	R               uint16
+	G               uint16
+	B               uint16
+	Intensity       uint8
+	Classification  uint8
+	ReturnNumber    uint8      // 0 if unknown
+	NumberOfReturns uint8      // 0 if unknown
+	PointSourceId   uint16     // Flightline the point was acquired by, 0 if unknown
+	Overlap         bool       // True if the point is flagged as lying in the overlap of flightlines
+	GpsTime         float64    // NaN if the point has no GPS time
+	ExtraAttributes *[]float64 // Values of the extra attributes read for the point, e.g. from LAS extra bytes, NaN if missing. Nil if none is read, a pointer keeps the points comparable
 }
 
 // Builds a new Point from the given coordinates, colors, intensity and classification values
-func NewPoint(X, Y, Z float64, R, G, B, Intensity, Classification uint8) *Point {
-	return &Point{
+func NewPoint(X, Y, Z float64, R, G, B uint16, Intensity, Classification uint8) *Point {
+	point := newPointValue(X, Y, Z, R, G, B, Intensity, Classification)
+	return &point
+}
+
+func newPointValue(X, Y, Z float64, R, G, B uint16, Intensity, Classification uint8) Point {
+	return Point{
 		X:              X,
 		Y:              Y,
 		Z:              Z,
@@ -24,5 +37,19 @@ func NewPoint(X, Y, Z float64, R, G, B, Intensity, Classification uint8) *Point
 		B:              B,
 		Intensity:      Intensity,
 		Classification: Classification,
+		GpsTime:        math.NaN(),
+	}
+}
+
+// Returns true if the GPS time of the point is known
+func (point *Point) HasGpsTime() bool {
+	return !math.IsNaN(point.GpsTime)
+}
+
+// Returns the value of the extra attribute of the given index, NaN if it has not been read for the point
+func (point *Point) GetExtraAttribute(index int) float64 {
+	if point.ExtraAttributes == nil || index >= len(*point.ExtraAttributes) {
+		return math.NaN()
 	}
+	return (*point.ExtraAttributes)[index]
 }
@@ -0,0 +1,32 @@
+package data
+
+// Default number of Points allocated at a time by a PointSlab
+const DefaultPointSlabSize = 1024
+
+// Allocates Points from slabs, i.e. contiguous slices of Points, so that reading a cloud of n Points takes n/size
+// allocations rather than n, cutting the allocation time and the number of objects scanned by the garbage collector.
+// The memory of a slab is only released once none of its Points is referenced anymore. A PointSlab is not safe for
+// concurrent use, each goroutine must use its own
+type PointSlab struct {
+	size int
+	slab []Point
+}
+
+// Instances a new PointSlab allocating the given number of Points at a time, DefaultPointSlabSize if not positive
+func NewPointSlab(size int) *PointSlab {
+	if size <= 0 {
+		size = DefaultPointSlabSize
+	}
+	return &PointSlab{size: size}
+}
+
+// Same as NewPoint, but the Point is taken from the current slab, allocating a new one if exhausted
+func (pointSlab *PointSlab) NewPoint(X, Y, Z float64, R, G, B uint16, Intensity, Classification uint8) *Point {
+	if len(pointSlab.slab) == 0 {
+		pointSlab.slab = make([]Point, pointSlab.size)
+	}
+	point := &pointSlab.slab[0]
+	pointSlab.slab = pointSlab.slab[1:]
+	*point = newPointValue(X, Y, Z, R, G, B, Intensity, Classification)
+	return point
+}
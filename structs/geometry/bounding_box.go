@@ -66,6 +66,12 @@ func (bbox *BoundingBox) GetVolume() float64 {
 	//return (bbox.Xmax - bbox.Xmin) * (bbox.Ymax - bbox.Ymin) * (bbox.Zmax - bbox.Zmin)
 }
 
+// Returns the approximate length in meters of the diagonal of the given bounding box, assuming that it is storing
+// EPSG:4326 longitudes and latitudes and elevations in meters
+func (bbox *BoundingBox) GetDiagonal() float64 {
+	return bbox.distance(bbox.Ymin, bbox.Ymax, bbox.Xmin, bbox.Xmax, bbox.Zmin, bbox.Zmax)
+}
+
 func (bbox *BoundingBox) distance(lat1, lat2, lon1, lon2, el1, el2 float64) float64 {
 	R := 6378137 / 1000; // Radius of the earth
 	latDistance := (lat2 - lat1) * toRadians
@@ -76,4 +82,22 @@ func (bbox *BoundingBox) distance(lat1, lat2, lon1, lon2, el1, el2 float64) floa
 	height := el1 - el2
 	distance = distance*distance + height*height
 	return math.Sqrt(distance)
-}
\ No newline at end of file
+}
+// Returns true if the given coordinates are within the bounding box, boundaries included
+func (bbox *BoundingBox) Contains(x, y, z float64) bool {
+	return x >= bbox.Xmin && x <= bbox.Xmax && y >= bbox.Ymin && y <= bbox.Ymax && z >= bbox.Zmin && z <= bbox.Zmax
+}
+
+// Returns the intersection of the bounding box with the given one, boundaries included, or nil if they do not
+// intersect
+func (bbox *BoundingBox) Intersect(other *BoundingBox) *BoundingBox {
+	intersection := NewBoundingBox(
+		math.Max(bbox.Xmin, other.Xmin), math.Min(bbox.Xmax, other.Xmax),
+		math.Max(bbox.Ymin, other.Ymin), math.Min(bbox.Ymax, other.Ymax),
+		math.Max(bbox.Zmin, other.Zmin), math.Min(bbox.Zmax, other.Zmax),
+	)
+	if intersection.Xmin > intersection.Xmax || intersection.Ymin > intersection.Ymax || intersection.Zmin > intersection.Zmax {
+		return nil
+	}
+	return intersection
+}
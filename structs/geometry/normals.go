@@ -0,0 +1,171 @@
+package geometry
+
+import (
+	"math"
+	"sort"
+)
+
+// Unique key of a cell of the uniform grid used to speed up neighbour lookups
+type gridKey struct {
+	X, Y, Z int
+}
+
+// Estimates the unit normal of each of the given points, stored as X, Y, Z triplets of EPSG:4978 coordinates. The
+// normal of a point is the direction of least variance of its neighbours, i.e. the eigenvector associated to the
+// smallest eigenvalue of their covariance matrix, oriented away from the Earth center. Returns the normals as
+// X, Y, Z triplets. Points with less than three neighbours get the local vertical as normal.
+func EstimateNormals(coords []float64, neighbours int) []float64 {
+	pointNo := len(coords) / 3
+	normals := make([]float64, pointNo*3)
+	if pointNo == 0 {
+		return normals
+	}
+
+	cellSize := estimateGridCellSize(coords, neighbours)
+	grid := make(map[gridKey][]int)
+	for i := 0; i < pointNo; i++ {
+		key := getGridKey(coords[i*3:i*3+3], cellSize)
+		grid[key] = append(grid[key], i)
+	}
+
+	for i := 0; i < pointNo; i++ {
+		point := coords[i*3 : i*3+3]
+		nearest := findNearestNeighbours(coords, grid, point, cellSize, neighbours)
+		var normal [3]float64
+		if len(nearest) < 3 {
+			normal = [3]float64{point[0], point[1], point[2]}
+		} else {
+			normal = smallestEigenvector(covariance(coords, nearest))
+		}
+		// orient the normal outwards
+		if normal[0]*point[0]+normal[1]*point[1]+normal[2]*point[2] < 0 {
+			normal = [3]float64{-normal[0], -normal[1], -normal[2]}
+		}
+		length := math.Sqrt(normal[0]*normal[0] + normal[1]*normal[1] + normal[2]*normal[2])
+		if length > 0 {
+			normals[i*3] = normal[0] / length
+			normals[i*3+1] = normal[1] / length
+			normals[i*3+2] = normal[2] / length
+		}
+	}
+	return normals
+}
+
+// Returns a grid cell size such that, on average, a cell and its 26 neighbours contain about the requested number
+// of neighbours assuming points to be spread over a surface
+func estimateGridCellSize(coords []float64, neighbours int) float64 {
+	min := [3]float64{math.MaxFloat64, math.MaxFloat64, math.MaxFloat64}
+	max := [3]float64{-math.MaxFloat64, -math.MaxFloat64, -math.MaxFloat64}
+	for i := 0; i < len(coords); i++ {
+		min[i%3] = math.Min(min[i%3], coords[i])
+		max[i%3] = math.Max(max[i%3], coords[i])
+	}
+	extents := []float64{max[0] - min[0], max[1] - min[1], max[2] - min[2]}
+	sort.Float64s(extents)
+	area := extents[1] * extents[2]
+	if area == 0 {
+		area = extents[2] * extents[2]
+	}
+	if area == 0 {
+		return 1
+	}
+	return math.Sqrt(area * float64(neighbours) / float64(len(coords)/3) / 9)
+}
+
+func getGridKey(point []float64, cellSize float64) gridKey {
+	return gridKey{
+		X: int(math.Floor(point[0] / cellSize)),
+		Y: int(math.Floor(point[1] / cellSize)),
+		Z: int(math.Floor(point[2] / cellSize)),
+	}
+}
+
+// Returns the indexes of up to the given number of points nearest to the given point looking in its grid cell and in
+// the 26 surrounding ones. The point itself is included.
+func findNearestNeighbours(coords []float64, grid map[gridKey][]int, point []float64, cellSize float64, neighbours int) []int {
+	key := getGridKey(point, cellSize)
+	candidates := make([]int, 0)
+	for dx := -1; dx <= 1; dx++ {
+		for dy := -1; dy <= 1; dy++ {
+			for dz := -1; dz <= 1; dz++ {
+				candidates = append(candidates, grid[gridKey{key.X + dx, key.Y + dy, key.Z + dz}]...)
+			}
+		}
+	}
+	distance := func(i int) float64 {
+		dx, dy, dz := coords[i*3]-point[0], coords[i*3+1]-point[1], coords[i*3+2]-point[2]
+		return dx*dx + dy*dy + dz*dz
+	}
+	sort.Slice(candidates, func(i, j int) bool { return distance(candidates[i]) < distance(candidates[j]) })
+	if len(candidates) > neighbours {
+		candidates = candidates[:neighbours]
+	}
+	return candidates
+}
+
+// Computes the covariance matrix of the points with the given indexes
+func covariance(coords []float64, indexes []int) [3][3]float64 {
+	var mean [3]float64
+	for _, i := range indexes {
+		for k := 0; k < 3; k++ {
+			mean[k] += coords[i*3+k]
+		}
+	}
+	for k := 0; k < 3; k++ {
+		mean[k] /= float64(len(indexes))
+	}
+	var cov [3][3]float64
+	for _, i := range indexes {
+		for r := 0; r < 3; r++ {
+			for c := 0; c < 3; c++ {
+				cov[r][c] += (coords[i*3+r] - mean[r]) * (coords[i*3+c] - mean[c])
+			}
+		}
+	}
+	return cov
+}
+
+// Returns the eigenvector associated to the smallest eigenvalue of the given symmetric matrix, computed with the
+// cyclic Jacobi eigenvalue algorithm
+func smallestEigenvector(a [3][3]float64) [3]float64 {
+	v := [3][3]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	for sweep := 0; sweep < 50; sweep++ {
+		offDiagonal := math.Abs(a[0][1]) + math.Abs(a[0][2]) + math.Abs(a[1][2])
+		if offDiagonal < 1e-15*(math.Abs(a[0][0])+math.Abs(a[1][1])+math.Abs(a[2][2])) {
+			break
+		}
+		for p := 0; p < 2; p++ {
+			for q := p + 1; q < 3; q++ {
+				if a[p][q] == 0 {
+					continue
+				}
+				theta := (a[q][q] - a[p][p]) / (2 * a[p][q])
+				t := math.Copysign(1, theta) / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+				for k := 0; k < 3; k++ {
+					akp, akq := a[k][p], a[k][q]
+					a[k][p] = c*akp - s*akq
+					a[k][q] = s*akp + c*akq
+				}
+				for k := 0; k < 3; k++ {
+					apk, aqk := a[p][k], a[q][k]
+					a[p][k] = c*apk - s*aqk
+					a[q][k] = s*apk + c*aqk
+				}
+				for k := 0; k < 3; k++ {
+					vkp, vkq := v[k][p], v[k][q]
+					v[k][p] = c*vkp - s*vkq
+					v[k][q] = s*vkp + c*vkq
+				}
+			}
+		}
+	}
+	smallest := 0
+	for i := 1; i < 3; i++ {
+		if a[i][i] < a[smallest][smallest] {
+			smallest = i
+		}
+	}
+	return [3]float64{v[0][smallest], v[1][smallest], v[2][smallest]}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/mfbonfigli/gocesiumtiler/structs/data"
 	"github.com/mfbonfigli/gocesiumtiler/structs/geometry"
 	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"math"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -19,8 +20,10 @@ type OctNode struct {
 	Children            [8]*OctNode
 	Items               []*data.Point
 	Depth               uint8
-	GlobalChildrenCount int64
-	LocalChildrenCount  int32
+	GlobalChildrenCount int64                 // Number of points stored in the subtree of the node, including the node Items
+	LocalChildrenCount  int32                 // Number of points stored in the node Items
+	AncestorPointsCount int64                 // Number of points stored in the Items of the ancestors within the node bounding box, set by CountAncestorPoints
+	TightBoundingBox    *geometry.BoundingBox // Extent of the points of the node and its descendants, set by ComputeTightBoundingBoxes
 	Opts                *tiler.TilerOptions
 	IsLeaf              bool
 	Initialized         bool
@@ -29,7 +32,16 @@ type OctNode struct {
 
 // Instantiates a new OctNode
 func NewOctNode(boundingBox *geometry.BoundingBox, opts *tiler.TilerOptions, depth uint8, parent *OctNode) *OctNode {
-	octNode := OctNode{
+	octNode := &OctNode{}
+	initOctNode(octNode, boundingBox, opts, depth, parent)
+
+	return octNode
+}
+
+// Initializes the given OctNode in place, so that nodes embedded by value, such as the tree root, are not copied
+// together with their mutex
+func initOctNode(octNode *OctNode, boundingBox *geometry.BoundingBox, opts *tiler.TilerOptions, depth uint8, parent *OctNode) {
+	*octNode = OctNode{
 		Parent:              parent,
 		BoundingBox:         boundingBox,
 		Depth:               depth,
@@ -39,29 +51,38 @@ func NewOctNode(boundingBox *geometry.BoundingBox, opts *tiler.TilerOptions, dep
 		IsLeaf:              true,
 		Initialized:         false,
 	}
-
-	return &octNode
 }
 
-// Adds a Point to the OctNode eventually propagating it to the OctNode relevant children
+// Adds a Point to the OctNode eventually propagating it to the OctNode relevant children. Nodes store up to
+// MaxNumPointsPerNode points, even when points are added concurrently
 func (octNode *OctNode) AddDataPoint(element *data.Point) {
+	// nodes at the max level are never subdivided, their Items absorb all the points reaching them. The depth is
+	// capped anyway, as coincident points exceeding the max number of points per node could not be split
+	atMaxLevel := octNode.Depth == math.MaxUint8 || (octNode.Opts.MaxLevels > 0 && int(octNode.Depth) >= octNode.Opts.MaxLevels)
 	if atomic.LoadInt32(&octNode.LocalChildrenCount) == 0 {
 		octNode.Lock()
-		for i := uint8(0); i < 8; i++ {
+		for i := uint8(0); i < 8 && !atMaxLevel; i++ {
 			if octNode.Children[i] == nil {
-				octNode.Children[i] = NewOctNode(getOctantBoundingBox(&i, octNode.BoundingBox), octNode.Opts, octNode.Depth+1, octNode)
+				octant := octNode.Opts.GetChildOctant(i)
+				octNode.Children[i] = NewOctNode(getOctantBoundingBox(&octant, octNode.BoundingBox), octNode.Opts, octNode.Depth+1, octNode)
 			}
 		}
 		octNode.Initialized = true
 		octNode.Unlock()
 	}
-	if atomic.LoadInt32(&octNode.LocalChildrenCount) < octNode.Opts.MaxNumPointsPerNode {
+	stored := false
+	if atMaxLevel || atomic.LoadInt32(&octNode.LocalChildrenCount) < octNode.Opts.MaxNumPointsPerNode {
 		octNode.Lock()
-		octNode.Items = append(octNode.Items, element)
-		atomic.AddInt32(&octNode.LocalChildrenCount, 1)
+		// other goroutines may have filled the node while waiting for the lock
+		if atMaxLevel || atomic.LoadInt32(&octNode.LocalChildrenCount) < octNode.Opts.MaxNumPointsPerNode {
+			octNode.Items = append(octNode.Items, element)
+			atomic.AddInt32(&octNode.LocalChildrenCount, 1)
+			stored = true
+		}
 		octNode.Unlock()
-	} else {
-		octNode.Children[getOctantFromElement(element, octNode.BoundingBox)].AddDataPoint(element)
+	}
+	if !stored {
+		octNode.Children[octNode.Opts.GetChildIndex(getOctantFromElement(element, octNode.BoundingBox))].AddDataPoint(element)
 		if octNode.IsLeaf {
 			octNode.Lock()
 			octNode.IsLeaf = false
@@ -71,6 +92,111 @@ func (octNode *OctNode) AddDataPoint(element *data.Point) {
 	atomic.AddInt64(&octNode.GlobalChildrenCount, 1)
 }
 
+// Returns true if the node or any of its descendants stores points, i.e. if the node has to be linked in the tileset
+// of its parent
+func (octNode *OctNode) HasPoints() bool {
+	return atomic.LoadInt64(&octNode.GlobalChildrenCount) > 0 || atomic.LoadInt32(&octNode.LocalChildrenCount) > 0
+}
+
+// Signals that the tile of this node has been exported, releasing its Items
+func (octNode *OctNode) MarkExported() {
+	octNode.Lock()
+	octNode.Items = nil
+	octNode.Unlock()
+}
+
+// Sets, for this node and all its descendants, the number of points stored in the Items of their ancestors that lie
+// within their bounding box, boundaries included, given the ones of the ancestors of this node. Each point is only
+// checked against the nodes containing it, so that the geometric errors need no scan of the ancestor points
+func (octNode *OctNode) CountAncestorPoints(ancestorPoints []*data.Point) {
+	octNode.AncestorPointsCount = int64(len(ancestorPoints))
+	points := append(ancestorPoints[:len(ancestorPoints):len(ancestorPoints)], octNode.Items...)
+	for _, child := range octNode.Children {
+		if child == nil || !child.Initialized {
+			continue
+		}
+		contained := make([]*data.Point, 0)
+		for _, point := range points {
+			if child.BoundingBox.Contains(point.X, point.Y, point.Z) {
+				contained = append(contained, point)
+			}
+		}
+		child.CountAncestorPoints(contained)
+	}
+}
+
+// Sets, for this node and all its descendants, the bounding box of the points stored in their Items and in the ones of
+// their descendants, clipped to the node bounding box so that it is always contained in it. Returns the bounding box of
+// this node, nil if its subtree stores no points
+func (octNode *OctNode) ComputeTightBoundingBoxes() *geometry.BoundingBox {
+	var extent *geometry.BoundingBox
+	for _, point := range octNode.Items {
+		extent = unionWithPoint(extent, point.X, point.Y, point.Z)
+	}
+	for _, child := range octNode.Children {
+		if child == nil || !child.Initialized {
+			continue
+		}
+		if childExtent := child.ComputeTightBoundingBoxes(); childExtent != nil {
+			extent = unionWithPoint(extent, childExtent.Xmin, childExtent.Ymin, childExtent.Zmin)
+			extent = unionWithPoint(extent, childExtent.Xmax, childExtent.Ymax, childExtent.Zmax)
+		}
+	}
+	if extent != nil {
+		extent = extent.Intersect(octNode.BoundingBox)
+	}
+	octNode.TightBoundingBox = extent
+	return extent
+}
+
+// Returns the smallest bounding box containing the given one and the given point, the box of the point alone if the
+// given one is nil
+func unionWithPoint(bbox *geometry.BoundingBox, x, y, z float64) *geometry.BoundingBox {
+	if bbox == nil {
+		return geometry.NewBoundingBox(x, x, y, y, z, z)
+	}
+	return geometry.NewBoundingBox(
+		math.Min(bbox.Xmin, x), math.Max(bbox.Xmax, x),
+		math.Min(bbox.Ymin, y), math.Max(bbox.Ymax, y),
+		math.Min(bbox.Zmin, z), math.Max(bbox.Zmax, z),
+	)
+}
+
+// Returns the depth of the deepest node storing points in the subtree of this node, e.g. the number of levels of the
+// tree when called on the root node. Returns 0 if the subtree stores no points
+func (octNode *OctNode) GetTreeDepth() uint8 {
+	var depth uint8 = 0
+	if atomic.LoadInt32(&octNode.LocalChildrenCount) > 0 {
+		depth = octNode.Depth
+	}
+	for _, child := range octNode.Children {
+		if child != nil && child.Initialized {
+			if childDepth := child.GetTreeDepth(); childDepth > depth {
+				depth = childDepth
+			}
+		}
+	}
+	return depth
+}
+
+// Returns the level of the node in the 3D Tiles 1.1 implicit octree subdivision of the tree, the root being at level
+// 0, followed by its x, y and z indexes in the grid of the nodes of that level. As the octant of a child is the
+// interleaving of its x, y, z bits, the indexes do not depend on the OctantOrder of the child indexes
+func (octNode *OctNode) GetImplicitCoordinates() (level, x, y, z int) {
+	if octNode.Parent == nil {
+		return 0, 0, 0, 0
+	}
+	level, x, y, z = octNode.Parent.GetImplicitCoordinates()
+	for i, sibling := range octNode.Parent.Children {
+		if sibling == octNode {
+			octant := int(octNode.Opts.GetChildOctant(uint8(i)))
+			x, y, z = x<<1|octant&1, y<<1|octant>>1&1, z<<1|octant>>2&1
+			break
+		}
+	}
+	return level + 1, x, y, z
+}
+
 // Prints the summary of the node contents in the console
 func (octNode *OctNode) PrintStructure() {
 	fmt.Println(strings.Repeat(" ", int(octNode.Depth)-1)+"-", "element no:", octNode.LocalChildrenCount, "leaf:", octNode.IsLeaf)
@@ -81,9 +207,6 @@ func (octNode *OctNode) PrintStructure() {
 	}
 }
 
-
-
-
 // Returns the index of the octant that contains the given Point within this BoundingBox
 func getOctantFromElement(element *data.Point, bbox *geometry.BoundingBox) uint8 {
 	var result uint8 = 0
@@ -102,4 +225,4 @@ func getOctantFromElement(element *data.Point, bbox *geometry.BoundingBox) uint8
 // Returns a bounding box from the given box and the given octant index
 func getOctantBoundingBox(octant *uint8, bbox *geometry.BoundingBox) *geometry.BoundingBox {
 	return geometry.NewBoundingBoxFromParent(bbox, octant)
-}
\ No newline at end of file
+}
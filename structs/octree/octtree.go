@@ -7,8 +7,6 @@ import (
 	"github.com/mfbonfigli/gocesiumtiler/structs/point_loader"
 	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
 	"math"
-	"runtime"
-	"sync"
 )
 
 // Represents an OctTree of Points and contains all information needed
@@ -54,15 +52,16 @@ func (octTree *OctTree) Build(loader point_loader.Loader) error {
 		return errors.New("octree already Built")
 	}
 	box := loader.GetBounds()
-	octNode := NewOctNode(geometry.NewBoundingBox(box[0], box[1], box[2], box[3], box[4], box[5]), octTree.Opts, 1, nil)
-	octTree.RootNode = *octNode
+	initOctNode(&octTree.RootNode, geometry.NewBoundingBox(box[0], box[1], box[2], box[3], box[4], box[5]), octTree.Opts, 1, nil)
 	loader.Initialize()
-	var wg sync.WaitGroup
-	//wg.Add(len(octTree.itemsToAdd))
-	N := runtime.NumCPU()
+	N := octTree.Opts.WorkerPool.Size()
+	if octTree.Opts.RandomSeed != 0 {
+		// points are added in the order they are returned, which concurrent goroutines would not preserve
+		N = 1
+	}
+	tasks := make([]func(), N)
 	for i := 0; i < N; i++ {
-		wg.Add(1)
-		go func(loader point_loader.Loader) {
+		tasks[i] = func() {
 			for {
 				val, shouldContinue := loader.GetNext()
 				if val != nil {
@@ -72,10 +71,13 @@ func (octTree *OctTree) Build(loader point_loader.Loader) error {
 					break
 				}
 			}
-			wg.Done()
-		}(loader)
+		}
+	}
+	octTree.Opts.WorkerPool.Run(tasks...)
+	octTree.RootNode.CountAncestorPoints(nil)
+	if octTree.Opts.TightBounds {
+		octTree.RootNode.ComputeTightBoundingBoxes()
 	}
-	wg.Wait()
 	octTree.itemsToAdd = nil
 	octTree.Built = true
 	return nil
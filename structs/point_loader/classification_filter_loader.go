@@ -0,0 +1,54 @@
+package point_loader
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"sync/atomic"
+)
+
+// Loader decorator that adds to the wrapped Loader only the Points of the included classifications, then drops the
+// ones of the excluded classifications. If no classification is included all the classifications are, so that a
+// classification both included and excluded is always dropped
+type ClassificationFilterLoader struct {
+	Loader
+	included map[uint8]bool
+	excluded map[uint8]bool
+	dropped  int64
+}
+
+// Instances a new ClassificationFilterLoader adding to the given Loader the Points of the given included
+// classifications, all if empty, except the ones of the given excluded classifications
+func NewClassificationFilterLoader(loader Loader, include []uint8, exclude []uint8) *ClassificationFilterLoader {
+	filter := &ClassificationFilterLoader{
+		Loader:   loader,
+		included: make(map[uint8]bool),
+		excluded: make(map[uint8]bool),
+	}
+	for _, classification := range include {
+		filter.included[classification] = true
+	}
+	for _, classification := range exclude {
+		filter.excluded[classification] = true
+	}
+	return filter
+}
+
+// Adds the given Point to the wrapped Loader if its classification passes the filter
+func (cf *ClassificationFilterLoader) AddElement(e *data.Point) {
+	if (len(cf.included) > 0 && !cf.included[e.Classification]) || cf.excluded[e.Classification] {
+		atomic.AddInt64(&cf.dropped, 1)
+		return
+	}
+	cf.Loader.AddElement(e)
+}
+
+// Forwards the given classification names to the wrapped Loader, if it collects them
+func (cf *ClassificationFilterLoader) AddClassificationNames(names map[uint8]string) {
+	if namer, ok := cf.Loader.(ClassificationNamer); ok {
+		namer.AddClassificationNames(names)
+	}
+}
+
+// Returns the number of Points dropped by the filter
+func (cf *ClassificationFilterLoader) GetDroppedCount() int64 {
+	return atomic.LoadInt64(&cf.dropped)
+}
@@ -0,0 +1,58 @@
+package point_loader
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"sync/atomic"
+)
+
+// Loader decorator that diverts the Points to one of several Loaders according to their classification. Points with
+// classifications not assigned to any Loader are added to the wrapped Loader.
+type ClassificationLoader struct {
+	Loader
+	loaders []Loader
+	indexes map[uint8]int
+	counts  []int64
+}
+
+// Instances a new ClassificationLoader adding the Points with the classifications at index i of the given
+// classifications to the Loader at index i of the given loaders, and all other Points to the given fallback Loader
+func NewClassificationLoader(fallback Loader, loaders []Loader, classifications [][]uint8) *ClassificationLoader {
+	indexes := make(map[uint8]int)
+	for i, group := range classifications {
+		for _, classification := range group {
+			indexes[classification] = i
+		}
+	}
+	return &ClassificationLoader{
+		Loader:  fallback,
+		loaders: loaders,
+		indexes: indexes,
+		counts:  make([]int64, len(loaders)+1),
+	}
+}
+
+// Adds the given Point to the Loader assigned to its classification
+func (cl *ClassificationLoader) AddElement(e *data.Point) {
+	if i, ok := cl.indexes[e.Classification]; ok {
+		atomic.AddInt64(&cl.counts[i], 1)
+		cl.loaders[i].AddElement(e)
+		return
+	}
+	atomic.AddInt64(&cl.counts[len(cl.loaders)], 1)
+	cl.Loader.AddElement(e)
+}
+
+// Returns the Loader at the given index
+func (cl *ClassificationLoader) GetLoader(i int) Loader {
+	return cl.loaders[i]
+}
+
+// Returns the number of Points added to each of the Loaders, followed by the number of Points added to the fallback
+// Loader
+func (cl *ClassificationLoader) GetCounts() []int64 {
+	counts := make([]int64, len(cl.counts))
+	for i := range cl.counts {
+		counts[i] = atomic.LoadInt64(&cl.counts[i])
+	}
+	return counts
+}
@@ -0,0 +1,104 @@
+package point_loader
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"math"
+	"sync"
+)
+
+// Loader decorator that drops the Points closer than the given tolerance to a Point already added to the wrapped
+// Loader, e.g. the duplicates of clouds merged from overlapping scans. The added Points are indexed in a spatial hash
+// grid of cells as large as the tolerance, so that each Point is only compared to the ones of the 27 cells around it.
+// Which of the coincident Points is kept depends on the order in which they are added
+type DeduplicationLoader struct {
+	Loader
+	sync.Mutex
+	tolerance  float64
+	geographic bool
+	cells      map[deduplicationKey][][3]float64
+	dropped    int64
+}
+
+// Cell of the spatial hash grid of a DeduplicationLoader
+type deduplicationKey struct {
+	X, Y, Z int
+}
+
+// Instances a new DeduplicationLoader adding to the given Loader only the Points farther than the given tolerance from
+// the ones already added. If geographic is true the Points have longitude and latitude coordinates in degrees and
+// the tolerance is in meters, otherwise the tolerance is in the units of the coordinates
+func NewDeduplicationLoader(loader Loader, tolerance float64, geographic bool) *DeduplicationLoader {
+	return &DeduplicationLoader{
+		Loader:     loader,
+		tolerance:  tolerance,
+		geographic: geographic,
+		cells:      make(map[deduplicationKey][][3]float64),
+	}
+}
+
+// Adds the given Point to the wrapped Loader unless it is within the tolerance of a Point already added
+func (dl *DeduplicationLoader) AddElement(e *data.Point) {
+	position := dl.getPosition(e)
+	key := deduplicationKey{
+		X: int(math.Floor(position[0] / dl.tolerance)),
+		Y: int(math.Floor(position[1] / dl.tolerance)),
+		Z: int(math.Floor(position[2] / dl.tolerance)),
+	}
+	dl.Lock()
+	if dl.hasNeighbour(key, position) {
+		dl.dropped++
+		dl.Unlock()
+		return
+	}
+	dl.cells[key] = append(dl.cells[key], position)
+	dl.Unlock()
+	dl.Loader.AddElement(e)
+}
+
+// Forwards the given classification names to the wrapped Loader, if it collects them
+func (dl *DeduplicationLoader) AddClassificationNames(names map[uint8]string) {
+	if namer, ok := dl.Loader.(ClassificationNamer); ok {
+		namer.AddClassificationNames(names)
+	}
+}
+
+// Releases the spatial hash grid, then initializes the wrapped Loader
+func (dl *DeduplicationLoader) Initialize() {
+	dl.Lock()
+	dl.cells = make(map[deduplicationKey][][3]float64)
+	dl.Unlock()
+	dl.Loader.Initialize()
+}
+
+// Returns the number of Points dropped as duplicates
+func (dl *DeduplicationLoader) GetDroppedCount() int64 {
+	dl.Lock()
+	defer dl.Unlock()
+	return dl.dropped
+}
+
+// Returns true if a Point within the tolerance of the given position is stored in the cells around the given one
+func (dl *DeduplicationLoader) hasNeighbour(key deduplicationKey, position [3]float64) bool {
+	for x := key.X - 1; x <= key.X+1; x++ {
+		for y := key.Y - 1; y <= key.Y+1; y++ {
+			for z := key.Z - 1; z <= key.Z+1; z++ {
+				for _, other := range dl.cells[deduplicationKey{X: x, Y: y, Z: z}] {
+					dx, dy, dz := other[0]-position[0], other[1]-position[1], other[2]-position[2]
+					if dx*dx+dy*dy+dz*dz <= dl.tolerance*dl.tolerance {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Returns the position of the given Point in the units of the tolerance. Geographic coordinates are scaled to meters
+// at the latitude of the Point
+func (dl *DeduplicationLoader) getPosition(e *data.Point) [3]float64 {
+	if !dl.geographic {
+		return [3]float64{e.X, e.Y, e.Z}
+	}
+	return [3]float64{e.X * metersPerDegree * math.Cos(e.Y*math.Pi/180), e.Y * metersPerDegree, e.Z}
+}
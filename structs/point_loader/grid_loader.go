@@ -0,0 +1,76 @@
+package point_loader
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"math"
+)
+
+// Stores Points and returns them decimated on regular grids. The first Points returned are one per cell of a grid of
+// 2x2x2 cells spanning the bounds of the cloud, followed by one per cell not yet holding a returned Point of a grid of
+// 4x4x4 cells and so on, each grid cell matching an octant of the tree. The remaining Points are returned in random
+// order. Coarse tiles get an even density regardless of the density of the input, at the expense of keeping a map of
+// the cells of each grid while initializing
+type GridLoader struct {
+	*RandomLoader
+}
+
+// Instances a new GridLoader. See NewRandomLoader for boundsSigmas
+func NewGridLoader(boundsSigmas float64) *GridLoader {
+	return &GridLoader{
+		RandomLoader: NewRandomLoader(boundsSigmas),
+	}
+}
+
+// Shuffles the Points then sorts them by grid decimation level
+func (gl *GridLoader) Initialize() {
+	gl.RandomLoader.Initialize()
+	bounds := []float64{gl.minX, gl.maxX, gl.minY, gl.maxY, gl.minZ, gl.maxZ}
+	ordered := make([]*data.Point, 0, len(gl.fullyRandomList))
+	remaining := gl.fullyRandomList
+	for level := 1; level <= maxDecimationLevel(len(remaining)) && len(remaining) > 0; level++ {
+		cells := 1 << uint(level)
+		occupied := make(map[geoKey]bool, len(ordered))
+		for _, point := range ordered {
+			occupied[computeCellKey(point, bounds, cells)] = true
+		}
+		next := remaining[:0]
+		for _, point := range remaining {
+			key := computeCellKey(point, bounds, cells)
+			if occupied[key] {
+				next = append(next, point)
+				continue
+			}
+			occupied[key] = true
+			ordered = append(ordered, point)
+		}
+		remaining = next
+	}
+	gl.fullyRandomList = append(ordered, remaining...)
+}
+
+// Returns the finest decimation level worth computing for the given number of points, i.e. the first one whose grid
+// has at least as many cells as points
+func maxDecimationLevel(pointNo int) int {
+	level := 1
+	for cells := 8; cells < pointNo && level < 20; cells *= 8 {
+		level++
+	}
+	return level
+}
+
+// Computes the key of the cell containing the given Point in a grid of the given number of cells per axis spanning
+// the given minX, maxX, minY, maxY, minZ, maxZ bounds
+func computeCellKey(e *data.Point, bounds []float64, cells int) geoKey {
+	return geoKey{
+		X: computeCellIndex(e.X, bounds[0], bounds[1], cells),
+		Y: computeCellIndex(e.Y, bounds[2], bounds[3], cells),
+		Z: computeCellIndex(e.Z, bounds[4], bounds[5], cells),
+	}
+}
+
+func computeCellIndex(value, min, max float64, cells int) int {
+	if max <= min {
+		return 0
+	}
+	return int(math.Min(math.Floor((value-min)/(max-min)*float64(cells)), float64(cells-1)))
+}
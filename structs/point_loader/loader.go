@@ -17,6 +17,24 @@ type Loader interface {
 	// before first call to GetNext
 	Initialize()
 
-	// Returns the bounding box extremes of the stored cloud minX, maxX, minY, maxY, minZ, maxZ
+	// Returns the bounding box extremes of the stored cloud minX, maxX, minY, maxY, minZ, maxZ. Loaders configured
+	// to compute robust bounds may return a box that excludes outlier points
 	GetBounds() []float64
 }
+
+// A Loader whose shuffling can be seeded to make the order of the returned Points reproducible
+type SeedableLoader interface {
+	Loader
+
+	// Sets the seed of the shuffling, 0 to seed it from the current time
+	SetRandomSeed(seed int64)
+}
+
+// A Loader collecting the names of the classifications of the Points, e.g. from the Classification Lookup VLR of
+// LAS files
+type ClassificationNamer interface {
+	Loader
+
+	// Adds the given names of classification codes, replacing the names already set for the same codes
+	AddClassificationNames(names map[uint8]string)
+}
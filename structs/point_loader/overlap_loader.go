@@ -0,0 +1,123 @@
+package point_loader
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"math"
+	"sync"
+)
+
+// Meters spanned by a degree of latitude, and of longitude at the equator
+const metersPerDegree = 111320.0
+
+// Loader decorator that thins the Points of overlapping flightlines. The Points are split in square cells of the given
+// size on the XY plane and, in each cell covered by more than one flightline, only the Points of the flightline with
+// the most Points in the cell are added to the wrapped Loader. Points flagged as overlap are dropped in the cells also
+// holding Points not flagged as overlap. Cells covered by a single flightline keep all their Points, so that the
+// density of overlap zones matches the one of single coverage zones without leaving gaps. As the thinning requires
+// all the Points, they are only added to the wrapped Loader once GetBounds or Initialize is called.
+type OverlapLoader struct {
+	Loader
+	sync.Mutex
+	cellSize   float64
+	geographic bool
+	points     []*data.Point
+	dropped    int64
+	thinOnce   sync.Once
+}
+
+// Cell of a flightline in the XY grid of an OverlapLoader
+type overlapKey struct {
+	X, Y    int
+	Source  uint16
+	Overlap bool
+}
+
+// Instances a new OverlapLoader thinning the Points in cells of the given size before adding them to the given Loader.
+// If geographic is true the Points have longitude and latitude coordinates in degrees and the cell size is in meters,
+// otherwise the cell size is in the units of the coordinates
+func NewOverlapLoader(loader Loader, cellSize float64, geographic bool) *OverlapLoader {
+	return &OverlapLoader{
+		Loader:     loader,
+		cellSize:   cellSize,
+		geographic: geographic,
+	}
+}
+
+// Stores the given Point until the thinning
+func (ol *OverlapLoader) AddElement(e *data.Point) {
+	ol.Lock()
+	ol.points = append(ol.points, e)
+	ol.Unlock()
+}
+
+// Thins the Points, then returns the bounds of the wrapped Loader
+func (ol *OverlapLoader) GetBounds() []float64 {
+	ol.thinOnce.Do(ol.thin)
+	return ol.Loader.GetBounds()
+}
+
+// Thins the Points, then initializes the wrapped Loader
+func (ol *OverlapLoader) Initialize() {
+	ol.thinOnce.Do(ol.thin)
+	ol.Loader.Initialize()
+}
+
+// Returns the number of Points dropped by the thinning
+func (ol *OverlapLoader) GetDroppedCount() int64 {
+	ol.thinOnce.Do(ol.thin)
+	return ol.dropped
+}
+
+// Adds the Points of the flightline selected in each cell to the wrapped Loader and releases the stored ones
+func (ol *OverlapLoader) thin() {
+	sizeX, sizeY := ol.getCellSizes()
+	counts := make(map[overlapKey]int)
+	for _, point := range ol.points {
+		counts[getOverlapKey(point, sizeX, sizeY)]++
+	}
+
+	// in each cell prefer Points not flagged as overlap, then the flightline with the most Points, then the lowest id
+	selected := make(map[geoKey]overlapKey)
+	for key, count := range counts {
+		cell := geoKey{X: key.X, Y: key.Y}
+		current, ok := selected[cell]
+		if !ok || (current.Overlap && !key.Overlap) ||
+			(current.Overlap == key.Overlap && (count > counts[current] || (count == counts[current] && key.Source < current.Source))) {
+			selected[cell] = key
+		}
+	}
+
+	for _, point := range ol.points {
+		key := getOverlapKey(point, sizeX, sizeY)
+		if selected[geoKey{X: key.X, Y: key.Y}] != key {
+			ol.dropped++
+			continue
+		}
+		ol.Loader.AddElement(point)
+	}
+	ol.points = nil
+}
+
+// Returns the size of the cells along X and Y in the units of the coordinates of the Points. Geographic cells are
+// sized at the mean latitude of the Points
+func (ol *OverlapLoader) getCellSizes() (float64, float64) {
+	if !ol.geographic || len(ol.points) == 0 {
+		return ol.cellSize, ol.cellSize
+	}
+	latitude := 0.0
+	for _, point := range ol.points {
+		latitude += point.Y
+	}
+	latitude /= float64(len(ol.points))
+	sizeY := ol.cellSize / metersPerDegree
+	return sizeY / math.Max(math.Cos(latitude*math.Pi/180), 1e-6), sizeY
+}
+
+func getOverlapKey(point *data.Point, sizeX, sizeY float64) overlapKey {
+	return overlapKey{
+		X:       int(math.Floor(point.X / sizeX)),
+		Y:       int(math.Floor(point.Y / sizeY)),
+		Source:  point.PointSourceId,
+		Overlap: point.Overlap,
+	}
+}
@@ -0,0 +1,80 @@
+package point_loader
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"math"
+)
+
+// Stores Points and returns them decimated by Poisson-disk sampling. The first Points returned are at least 1/2 apart,
+// the following ones at least 1/4 apart and so on, halving the distance at each level, with distances normalized to
+// the size of the bounds of the cloud along each axis. The remaining Points are returned in random order. Compared with the GridLoader, Points
+// returned at each level are never closer than the level distance, even across cell boundaries, giving an even
+// visual density without grid artifacts. Initialization is slower, as the neighbouring cells of each Point are searched
+// at each level, and the selected Points of each level are kept in a map of cells
+type PoissonDiskLoader struct {
+	*RandomLoader
+}
+
+// Instances a new PoissonDiskLoader. See NewRandomLoader for boundsSigmas
+func NewPoissonDiskLoader(boundsSigmas float64) *PoissonDiskLoader {
+	return &PoissonDiskLoader{
+		RandomLoader: NewRandomLoader(boundsSigmas),
+	}
+}
+
+// Shuffles the Points then sorts them by Poisson-disk decimation level
+func (pl *PoissonDiskLoader) Initialize() {
+	pl.RandomLoader.Initialize()
+	bounds := []float64{pl.minX, pl.maxX, pl.minY, pl.maxY, pl.minZ, pl.maxZ}
+	ordered := make([]*data.Point, 0, len(pl.fullyRandomList))
+	remaining := pl.fullyRandomList
+	for level := 1; level <= maxDecimationLevel(len(remaining)) && len(remaining) > 0; level++ {
+		cells := 1 << uint(level)
+		selected := make(map[geoKey][]*data.Point, len(ordered))
+		for _, point := range ordered {
+			key := computeCellKey(point, bounds, cells)
+			selected[key] = append(selected[key], point)
+		}
+		next := remaining[:0]
+		for _, point := range remaining {
+			key := computeCellKey(point, bounds, cells)
+			if hasSelectedNeighbour(point, key, selected, bounds, cells) {
+				next = append(next, point)
+				continue
+			}
+			selected[key] = append(selected[key], point)
+			ordered = append(ordered, point)
+		}
+		remaining = next
+	}
+	pl.fullyRandomList = append(ordered, remaining...)
+}
+
+// Returns true if a selected Point in the cell with the given key or in its neighbours is closer to the given Point
+// than the size of a cell, distances being normalized to the size of the bounds along each axis
+func hasSelectedNeighbour(point *data.Point, key geoKey, selected map[geoKey][]*data.Point, bounds []float64, cells int) bool {
+	radius := 1 / float64(cells)
+	for dx := -1; dx <= 1; dx++ {
+		for dy := -1; dy <= 1; dy++ {
+			for dz := -1; dz <= 1; dz++ {
+				for _, other := range selected[geoKey{X: key.X + dx, Y: key.Y + dy, Z: key.Z + dz}] {
+					if normalizedDistance(point, other, bounds) < radius {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+func normalizedDistance(a, b *data.Point, bounds []float64) float64 {
+	values := [3]float64{a.X - b.X, a.Y - b.Y, a.Z - b.Z}
+	sum := 0.0
+	for i, value := range values {
+		if extent := bounds[i*2+1] - bounds[i*2]; extent > 0 {
+			sum += (value / extent) * (value / extent)
+		}
+	}
+	return math.Sqrt(sum)
+}
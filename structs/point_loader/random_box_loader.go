@@ -3,7 +3,7 @@ package point_loader
 import (
 	"github.com/mfbonfigli/gocesiumtiler/structs/data"
 	"math"
-	"math/rand"
+	"sort"
 	"sync"
 )
 
@@ -17,11 +17,16 @@ type RandomBoxLoader struct {
 	Keys                               []*geoKey
 	currentKeyIndex                    int64
 	minX, maxX, minY, maxY, minZ, maxZ float64
+	boundsStats                        boundsStatistics
+	boundsSigmas                       float64
+	seed                               int64
 }
 
-// Instances a new RandomBoxLoader
-func NewRandomBoxLoader() *RandomBoxLoader {
+// Instances a new RandomBoxLoader. If boundsSigmas is positive the bounds returned by GetBounds are clipped to the mean
+// plus or minus boundsSigmas standard deviations of the loaded points, so that a few outliers cannot inflate them
+func NewRandomBoxLoader(boundsSigmas float64) *RandomBoxLoader {
 	return &RandomBoxLoader{
+		boundsSigmas:    boundsSigmas,
 		Buckets:         make(map[geoKey]*safeElementList),
 		Keys:            make([]*geoKey, 0),
 		currentKeyIndex: 0,
@@ -39,7 +44,9 @@ func (eb *RandomBoxLoader) AddElement(e *data.Point) {
 	eb.Lock()
 	eb.recomputeBoundsFromElement(e)
 	if bucket := eb.Buckets[geoKey]; bucket == nil {
-		eb.Buckets[geoKey] = newSafeElementList()
+		bucket = newSafeElementList()
+		bucket.Elements = append(bucket.Elements, e)
+		eb.Buckets[geoKey] = bucket
 		eb.Unlock()
 	} else {
 		eb.Unlock()
@@ -70,19 +77,36 @@ func (eb *RandomBoxLoader) GetNext() (*data.Point, bool) {
 	return el, count > 0
 }
 
+// Sets the seed of the shuffling of the boxes and of the Points. If not 0 boxes and Points are sorted before being
+// shuffled, so that their order only depends on the seed and on the loaded Points. If 0, the default, the shuffling is
+// seeded from the time
+func (eb *RandomBoxLoader) SetRandomSeed(seed int64) {
+	eb.seed = seed
+}
+
 // Initializes the structure to allow proper retrieval of Points. Shuffles the box order and points in each of the boxes.
 func (eb *RandomBoxLoader) Initialize() {
-	for i, b := range eb.Buckets {
+	random := newRandom(eb.seed)
+	for i := range eb.Buckets {
 		var j = i
 		eb.Keys = append(eb.Keys, &j)
-		rand.Shuffle(len(b.Elements), func(i, j int) { b.Elements[i], b.Elements[j] = b.Elements[j], b.Elements[i] })
 	}
-	rand.Shuffle(len(eb.Keys), func(i, j int) { eb.Keys[i], eb.Keys[j] = eb.Keys[j], eb.Keys[i] })
+	if eb.seed != 0 {
+		sort.Slice(eb.Keys, func(i, j int) bool { return eb.Keys[i].less(eb.Keys[j]) })
+	}
+	for _, key := range eb.Keys {
+		b := eb.Buckets[*key]
+		if eb.seed != 0 {
+			sortPoints(b.Elements)
+		}
+		random.Shuffle(len(b.Elements), func(i, j int) { b.Elements[i], b.Elements[j] = b.Elements[j], b.Elements[i] })
+	}
+	random.Shuffle(len(eb.Keys), func(i, j int) { eb.Keys[i], eb.Keys[j] = eb.Keys[j], eb.Keys[i] })
 	eb.currentKeyIndex = 0
 }
 
 func (eb *RandomBoxLoader) GetBounds() []float64 {
-	return []float64{eb.minX, eb.maxX, eb.minY, eb.maxY, eb.minZ, eb.maxZ}
+	return eb.boundsStats.clipBounds([]float64{eb.minX, eb.maxX, eb.minY, eb.maxY, eb.minZ, eb.maxZ}, eb.boundsSigmas)
 }
 
 // Updates the data cloud bounds according  to the given additional element to insert
@@ -93,4 +117,5 @@ func (eb *RandomBoxLoader) recomputeBoundsFromElement(element *data.Point) {
 	eb.maxX = math.Max(float64(element.X), eb.maxX)
 	eb.maxY = math.Max(float64(element.Y), eb.maxY)
 	eb.maxZ = math.Max(float64(element.Z), eb.maxZ)
+	eb.boundsStats.addElement(element)
 }
@@ -3,7 +3,6 @@ package point_loader
 import (
 	"github.com/mfbonfigli/gocesiumtiler/structs/data"
 	"math"
-	"math/rand"
 	"sync"
 	"sync/atomic"
 )
@@ -14,11 +13,16 @@ type RandomLoader struct {
 	fullyRandomList                    []*data.Point
 	currentKeyIndex                    int64
 	minX, maxX, minY, maxY, minZ, maxZ float64
+	boundsStats                        boundsStatistics
+	boundsSigmas                       float64
+	seed                               int64
 }
 
-// Instances a new RandomLoader
-func NewRandomLoader() *RandomLoader {
+// Instances a new RandomLoader. If boundsSigmas is positive the bounds returned by GetBounds are clipped to the mean
+// plus or minus boundsSigmas standard deviations of the loaded points, so that a few outliers cannot inflate them
+func NewRandomLoader(boundsSigmas float64) *RandomLoader {
 	return &RandomLoader{
+		boundsSigmas:    boundsSigmas,
 		currentKeyIndex: 0,
 		minX:            math.MaxFloat64,
 		minY:            math.MaxFloat64,
@@ -46,8 +50,19 @@ func (eb *RandomLoader) GetNext() (*data.Point, bool) {
 	}
 }
 
+// Sets the seed of the shuffling of the Points. If not 0 the Points are sorted before being shuffled, so that their
+// order only depends on the seed and on the loaded Points. If 0, the default, the shuffling is seeded from the time
+func (eb *RandomLoader) SetRandomSeed(seed int64) {
+	eb.seed = seed
+}
+
 func (eb *RandomLoader) Initialize() {
-	rand.Shuffle(len(eb.fullyRandomList), func(i, j int) { eb.fullyRandomList[i], eb.fullyRandomList[j] = eb.fullyRandomList[j], eb.fullyRandomList[i] })
+	if eb.seed != 0 {
+		sortPoints(eb.fullyRandomList)
+	}
+	newRandom(eb.seed).Shuffle(len(eb.fullyRandomList), func(i, j int) {
+		eb.fullyRandomList[i], eb.fullyRandomList[j] = eb.fullyRandomList[j], eb.fullyRandomList[i]
+	})
 	eb.currentKeyIndex = -1
 }
 
@@ -59,8 +74,9 @@ func (eb *RandomLoader) recomputeBoundsFromElement(element *data.Point) {
 	eb.maxX = math.Max(float64(element.X), eb.maxX)
 	eb.maxY = math.Max(float64(element.Y), eb.maxY)
 	eb.maxZ = math.Max(float64(element.Z), eb.maxZ)
+	eb.boundsStats.addElement(element)
 }
 
 func (eb *RandomLoader) GetBounds() []float64 {
-	return []float64{eb.minX, eb.maxX, eb.minY, eb.maxY, eb.minZ, eb.maxZ}
+	return eb.boundsStats.clipBounds([]float64{eb.minX, eb.maxX, eb.minY, eb.maxY, eb.minZ, eb.maxZ}, eb.boundsSigmas)
 }
@@ -0,0 +1,38 @@
+package point_loader
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"sync/atomic"
+)
+
+// Loader decorator that adds only one Point every given number of Points to the wrapped Loader, counting all of them,
+// so that large clouds can be analyzed on a decimated sample
+type SamplingLoader struct {
+	Loader
+	stride int64
+	count  int64
+}
+
+// Instances a new SamplingLoader adding one Point every given number of Points to the given Loader, starting from the
+// first one. Strides lower than 1 are treated as 1, i.e. all the Points are added
+func NewSamplingLoader(loader Loader, stride int) *SamplingLoader {
+	if stride < 1 {
+		stride = 1
+	}
+	return &SamplingLoader{
+		Loader: loader,
+		stride: int64(stride),
+	}
+}
+
+// Counts the given Point and adds it to the wrapped Loader if it falls on the stride
+func (sl *SamplingLoader) AddElement(e *data.Point) {
+	if (atomic.AddInt64(&sl.count, 1)-1)%sl.stride == 0 {
+		sl.Loader.AddElement(e)
+	}
+}
+
+// Returns the number of Points added to the SamplingLoader, sampled or not
+func (sl *SamplingLoader) GetTotalCount() int64 {
+	return atomic.LoadInt64(&sl.count)
+}
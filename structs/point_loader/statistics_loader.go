@@ -0,0 +1,115 @@
+package point_loader
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"math"
+	"sync"
+)
+
+// Summary statistics of a point cloud
+type Statistics struct {
+	TotalPoints         int64               `json:"totalPoints"`
+	Classifications     map[uint8]int64     `json:"classifications"`
+	Intensity           IntensityStatistics `json:"intensity"`
+	Bounds              BoundsStatistics    `json:"bounds"`
+	DuplicatePoints     int64               `json:"duplicatePoints,omitempty"` // Points dropped as duplicates, not counted in the other statistics
+	ClassificationNames map[uint8]string    `json:"-"`                         // Names of the classification codes read from the input files, if any
+}
+
+// Minimum, maximum and mean intensity of a point cloud
+type IntensityStatistics struct {
+	Min  uint8   `json:"min"`
+	Max  uint8   `json:"max"`
+	Mean float64 `json:"mean"`
+}
+
+// Extremes of the coordinates of a point cloud
+type BoundsStatistics struct {
+	MinX float64 `json:"minX"`
+	MaxX float64 `json:"maxX"`
+	MinY float64 `json:"minY"`
+	MaxY float64 `json:"maxY"`
+	MinZ float64 `json:"minZ"`
+	MaxZ float64 `json:"maxZ"`
+}
+
+// Loader decorator that accumulates the Statistics of the Points added to the wrapped Loader
+type StatisticsLoader struct {
+	Loader
+	sync.Mutex
+	statistics   Statistics
+	intensitySum float64
+}
+
+// Instances a new StatisticsLoader wrapping the given Loader
+func NewStatisticsLoader(loader Loader) *StatisticsLoader {
+	return &StatisticsLoader{
+		Loader: loader,
+		statistics: Statistics{
+			Classifications:     make(map[uint8]int64),
+			ClassificationNames: make(map[uint8]string),
+			Intensity:           IntensityStatistics{Min: math.MaxUint8},
+			Bounds: BoundsStatistics{
+				MinX: math.MaxFloat64,
+				MinY: math.MaxFloat64,
+				MinZ: math.MaxFloat64,
+				MaxX: -1 * math.MaxFloat64,
+				MaxY: -1 * math.MaxFloat64,
+				MaxZ: -1 * math.MaxFloat64,
+			},
+		},
+	}
+}
+
+// Updates the statistics with the given Point and adds it to the wrapped Loader
+func (sl *StatisticsLoader) AddElement(e *data.Point) {
+	sl.Lock()
+	stats := &sl.statistics
+	stats.TotalPoints++
+	stats.Classifications[e.Classification]++
+	if e.Intensity < stats.Intensity.Min {
+		stats.Intensity.Min = e.Intensity
+	}
+	if e.Intensity > stats.Intensity.Max {
+		stats.Intensity.Max = e.Intensity
+	}
+	sl.intensitySum += float64(e.Intensity)
+	stats.Bounds.MinX = math.Min(e.X, stats.Bounds.MinX)
+	stats.Bounds.MinY = math.Min(e.Y, stats.Bounds.MinY)
+	stats.Bounds.MinZ = math.Min(e.Z, stats.Bounds.MinZ)
+	stats.Bounds.MaxX = math.Max(e.X, stats.Bounds.MaxX)
+	stats.Bounds.MaxY = math.Max(e.Y, stats.Bounds.MaxY)
+	stats.Bounds.MaxZ = math.Max(e.Z, stats.Bounds.MaxZ)
+	sl.Unlock()
+	sl.Loader.AddElement(e)
+}
+
+// Adds the given names of classification codes to the statistics
+func (sl *StatisticsLoader) AddClassificationNames(names map[uint8]string) {
+	sl.Lock()
+	defer sl.Unlock()
+	for classification, name := range names {
+		sl.statistics.ClassificationNames[classification] = name
+	}
+}
+
+// Returns the statistics of the Points added so far
+func (sl *StatisticsLoader) GetStatistics() Statistics {
+	sl.Lock()
+	defer sl.Unlock()
+	stats := sl.statistics
+	stats.Classifications = make(map[uint8]int64)
+	for classification, count := range sl.statistics.Classifications {
+		stats.Classifications[classification] = count
+	}
+	stats.ClassificationNames = make(map[uint8]string)
+	for classification, name := range sl.statistics.ClassificationNames {
+		stats.ClassificationNames[classification] = name
+	}
+	if stats.TotalPoints > 0 {
+		stats.Intensity.Mean = sl.intensitySum / float64(stats.TotalPoints)
+	} else {
+		stats.Intensity.Min = 0
+	}
+	return stats
+}
@@ -3,7 +3,10 @@ package point_loader
 import (
 	"github.com/mfbonfigli/gocesiumtiler/structs/data"
 	"math"
+	"math/rand"
+	"sort"
 	"sync"
+	"time"
 )
 // Unique spatial key structure for grouping points
 type geoKey struct {
@@ -12,6 +15,59 @@ type geoKey struct {
 	Z int
 }
 
+// Returns true if the key precedes the given one, comparing X, then Y, then Z
+func (key *geoKey) less(other *geoKey) bool {
+	if key.X != other.X {
+		return key.X < other.X
+	}
+	if key.Y != other.Y {
+		return key.Y < other.Y
+	}
+	return key.Z < other.Z
+}
+
+// Returns a random number generator with the given seed, or seeded from the current time if the seed is 0
+func newRandom(seed int64) *rand.Rand {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return rand.New(rand.NewSource(seed))
+}
+
+// Sorts the given Points by all their attributes, so that their order does not depend on the order they have been
+// added in, e.g. by concurrent readers
+func sortPoints(points []*data.Point) {
+	sort.Slice(points, func(i, j int) bool {
+		a, b := points[i], points[j]
+		switch {
+		case a.X != b.X:
+			return a.X < b.X
+		case a.Y != b.Y:
+			return a.Y < b.Y
+		case a.Z != b.Z:
+			return a.Z < b.Z
+		case a.R != b.R:
+			return a.R < b.R
+		case a.G != b.G:
+			return a.G < b.G
+		case a.B != b.B:
+			return a.B < b.B
+		case a.Intensity != b.Intensity:
+			return a.Intensity < b.Intensity
+		case a.Classification != b.Classification:
+			return a.Classification < b.Classification
+		case a.ReturnNumber != b.ReturnNumber:
+			return a.ReturnNumber < b.ReturnNumber
+		case a.NumberOfReturns != b.NumberOfReturns:
+			return a.NumberOfReturns < b.NumberOfReturns
+		case math.IsNaN(a.GpsTime) || math.IsNaN(b.GpsTime):
+			return math.IsNaN(a.GpsTime) && !math.IsNaN(b.GpsTime)
+		default:
+			return a.GpsTime < b.GpsTime
+		}
+	})
+}
+
 // Mutexed list of pointers to Points for concurrent usage
 type safeElementList struct {
 	sync.Mutex
@@ -53,3 +109,37 @@ func computeGeoKey(e *data.Point) geoKey {
 		Z: int(math.Floor(e.Z / 10e-1)),
 	}
 }
+
+// Running per-axis mean and variance of the loaded cloud, used to compute robust bounds that ignore outliers.
+// Values are accumulated with the Welford online algorithm to avoid precision loss on large point counts.
+type boundsStatistics struct {
+	count int64
+	mean  [3]float64
+	m2    [3]float64
+}
+
+// Updates the running statistics with the coordinates of the given Point. Not thread safe.
+func (bs *boundsStatistics) addElement(e *data.Point) {
+	bs.count++
+	values := [3]float64{e.X, e.Y, e.Z}
+	for i, value := range values {
+		delta := value - bs.mean[i]
+		bs.mean[i] += delta / float64(bs.count)
+		bs.m2[i] += delta * (value - bs.mean[i])
+	}
+}
+
+// Clips the given minX, maxX, minY, maxY, minZ, maxZ bounds to the interval mean +/- sigmas * standard deviation
+// along each axis. Bounds are returned unchanged if sigmas is not positive or no points have been added.
+func (bs *boundsStatistics) clipBounds(bounds []float64, sigmas float64) []float64 {
+	if sigmas <= 0 || bs.count == 0 {
+		return bounds
+	}
+	clipped := make([]float64, 6)
+	for i := 0; i < 3; i++ {
+		stdDev := math.Sqrt(bs.m2[i] / float64(bs.count))
+		clipped[i*2] = math.Max(bounds[i*2], bs.mean[i]-sigmas*stdDev)
+		clipped[i*2+1] = math.Min(bounds[i*2+1], bs.mean[i]+sigmas*stdDev)
+	}
+	return clipped
+}
@@ -0,0 +1,78 @@
+package tiler
+
+import (
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Returns the colors of the standard ASPRS classifications: ground is brown, vegetation green, buildings gray and
+// water blue
+func DefaultClassificationColorMap() map[uint8][3]uint8 {
+	return map[uint8][3]uint8{
+		2: {139, 90, 43},   // ground
+		3: {144, 238, 144}, // low vegetation
+		4: {60, 179, 113},  // medium vegetation
+		5: {34, 139, 34},   // high vegetation
+		6: {128, 128, 128}, // building
+		9: {30, 144, 255},  // water
+	}
+}
+
+// Parses a color map, either asprs for the DefaultClassificationColorMap, none for an empty map or a comma separated
+// list of classification:color pairs with hex rrggbb colors, e.g. "2:8b5a2b,6:808080"
+func ParseClassificationColorMap(value string) (map[uint8][3]uint8, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "asprs":
+		return DefaultClassificationColorMap(), nil
+	case "", "none":
+		return map[uint8][3]uint8{}, nil
+	}
+	result := make(map[uint8][3]uint8)
+	for _, pair := range strings.Split(value, ",") {
+		tokens := strings.Split(strings.TrimSpace(pair), ":")
+		if len(tokens) != 2 {
+			return nil, errors.New("invalid classification:color pair " + pair)
+		}
+		classification, err := strconv.ParseUint(tokens[0], 10, 8)
+		if err != nil {
+			return nil, errors.New("invalid classification in pair " + pair)
+		}
+		color, err := ParseColor(tokens[1])
+		if err != nil {
+			return nil, err
+		}
+		result[uint8(classification)] = color
+	}
+	return result, nil
+}
+
+// Parses a hex rrggbb color, optionally prefixed by #
+func ParseColor(value string) ([3]uint8, error) {
+	var color [3]uint8
+	digits := strings.TrimPrefix(strings.TrimSpace(value), "#")
+	if len(digits) != 6 {
+		return color, errors.New("invalid color " + value + ", expected a hex rrggbb value")
+	}
+	if _, err := hex.Decode(color[:], []byte(digits)); err != nil {
+		return color, errors.New("invalid color " + value + ", expected a hex rrggbb value")
+	}
+	return color, nil
+}
+
+// Returns the function coloring the points from their classification with the ClassificationColorMap, the unmapped
+// classifications getting the UnmappedClassColor, or nil if the map is empty. Colors are scaled to 16 bits
+func (opts *TilerOptions) GetClassificationColorizer() func(classification uint8) (R, G, B uint16) {
+	if len(opts.ClassificationColorMap) == 0 {
+		return nil
+	}
+	colorMap, fallback := opts.ClassificationColorMap, opts.UnmappedClassColor
+	return func(classification uint8) (R, G, B uint16) {
+		color, ok := colorMap[classification]
+		if !ok {
+			color = fallback
+		}
+		return uint16(color[0]) * 257, uint16(color[1]) * 257, uint16(color[2]) * 257
+	}
+}
@@ -0,0 +1,56 @@
+package tiler
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Name of the group of the points whose classification is not assigned to any ClassificationGroup
+const OtherClassificationGroup = "other"
+
+// Set of classifications whose points are tiled in a separate tileset
+type ClassificationGroup struct {
+	Name                     string  // Name of the group, used as name of the tileset subfolder
+	Classifications          []uint8 // Classifications of the points of the group
+	GeometricErrorMultiplier float64 // Multiplier of the geometric error of the tiles of the group
+}
+
+// Parses a semicolon separated list of name:classifications:multiplier groups, e.g. "buildings:6:0.25;ground:2,9",
+// where classifications is a comma separated list of classification codes and the geometric error multiplier is
+// optional and defaults to 1
+func ParseClassificationGroups(value string) ([]ClassificationGroup, error) {
+	groups := make([]ClassificationGroup, 0)
+	if strings.TrimSpace(value) == "" {
+		return groups, nil
+	}
+	names := make(map[string]bool)
+	for _, token := range strings.Split(value, ";") {
+		token = strings.TrimSpace(token)
+		parts := strings.Split(token, ":")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" {
+			return nil, errors.New("invalid classification group " + token)
+		}
+		if names[parts[0]] || parts[0] == OtherClassificationGroup {
+			return nil, errors.New("duplicate classification group name " + parts[0])
+		}
+		names[parts[0]] = true
+		group := ClassificationGroup{Name: parts[0], GeometricErrorMultiplier: 1}
+		for _, code := range strings.Split(parts[1], ",") {
+			classification, err := strconv.ParseUint(strings.TrimSpace(code), 10, 8)
+			if err != nil {
+				return nil, errors.New("invalid classification in group " + token)
+			}
+			group.Classifications = append(group.Classifications, uint8(classification))
+		}
+		if len(parts) == 3 {
+			multiplier, err := strconv.ParseFloat(parts[2], 64)
+			if err != nil || multiplier <= 0 {
+				return nil, errors.New("invalid geometric error multiplier in group " + token)
+			}
+			group.GeometricErrorMultiplier = multiplier
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
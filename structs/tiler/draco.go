@@ -0,0 +1,17 @@
+package tiler
+
+// Per point attribute of a point cloud to encode with Draco
+type DracoAttribute struct {
+	Semantic   string    // Feature table semantic of the attribute, i.e. POSITION, NORMAL, RGB or RGBA
+	Components int       // Number of components of the attribute per point
+	Floats     []float32 // Values of float attributes, i.e. POSITION and NORMAL, nil for the other ones
+	Bytes      []uint8   // Values of unsigned byte attributes, i.e. RGB and RGBA, nil for the other ones
+}
+
+// Encodes point clouds in the Draco compressed format, e.g. by means of bindings to the Draco library. Used to
+// write tiles with the 3DTILES_draco_point_compression extension
+type DracoEncoder interface {
+	// Encodes the given attributes of the given number of points as a Draco point cloud, returning the compressed
+	// buffer and the unique id of each attribute in it, in the order of the given attributes
+	Encode(pointNo int, attributes []DracoAttribute) ([]byte, []int, error)
+}
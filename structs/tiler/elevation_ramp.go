@@ -0,0 +1,96 @@
+package tiler
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Color of an ElevationRamp at the given position, 0 denoting the min elevation of the points and 1 the max one
+type ColorStop struct {
+	Position float64
+	Color    [3]uint8
+}
+
+// Gradient mapping the elevations of the points to colors, through color stops sorted by position. Colors are
+// linearly interpolated between consecutive stops
+type ElevationRamp []ColorStop
+
+// Returns the blue, green, yellow, red gradient
+func DefaultElevationRamp() ElevationRamp {
+	return ElevationRamp{
+		{Position: 0, Color: [3]uint8{0, 0, 255}},
+		{Position: 1.0 / 3, Color: [3]uint8{0, 255, 0}},
+		{Position: 2.0 / 3, Color: [3]uint8{255, 255, 0}},
+		{Position: 1, Color: [3]uint8{255, 0, 0}},
+	}
+}
+
+// Parses an elevation ramp, either empty or none for no ramp, default for the DefaultElevationRamp or a comma
+// separated list of at least two color stops with hex rrggbb colors, e.g. "0000ff,ff0000". Each stop can be prefixed
+// by its position in the 0-1 range, e.g. "0:0000ff,0.2:00ff00,1:ff0000", the stops without a position are evenly
+// spaced according to their index
+func ParseElevationRamp(value string) (ElevationRamp, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "none":
+		return nil, nil
+	case "default":
+		return DefaultElevationRamp(), nil
+	}
+	tokens := strings.Split(value, ",")
+	if len(tokens) < 2 {
+		return nil, errors.New("invalid elevation ramp " + value + ", expected at least two color stops")
+	}
+	ramp := make(ElevationRamp, len(tokens))
+	for i, token := range tokens {
+		ramp[i].Position = float64(i) / float64(len(tokens)-1)
+		color := token
+		if separator := strings.Index(token, ":"); separator >= 0 {
+			position, err := strconv.ParseFloat(strings.TrimSpace(token[:separator]), 64)
+			if err != nil || position < 0 || position > 1 {
+				return nil, errors.New("invalid position of the color stop " + token + ", expected a value in the 0-1 range")
+			}
+			ramp[i].Position, color = position, token[separator+1:]
+		}
+		parsed, err := ParseColor(color)
+		if err != nil {
+			return nil, err
+		}
+		ramp[i].Color = parsed
+		if i > 0 && ramp[i].Position < ramp[i-1].Position {
+			return nil, errors.New("invalid elevation ramp " + value + ", the color stops must be sorted by position")
+		}
+	}
+	return ramp, nil
+}
+
+// Returns the 16 bit color of the given elevation, the min and max elevations being mapped to the positions 0 and 1.
+// Elevations outside of the stops get the color of the nearest stop
+func (ramp ElevationRamp) Color(z, minZ, maxZ float64) (R, G, B uint16) {
+	position := 0.0
+	if maxZ > minZ {
+		position = (z - minZ) / (maxZ - minZ)
+	}
+	last := len(ramp) - 1
+	if position <= ramp[0].Position {
+		return scaleRampColor(ramp[0].Color, ramp[0].Color, 0)
+	}
+	if position >= ramp[last].Position {
+		return scaleRampColor(ramp[last].Color, ramp[last].Color, 0)
+	}
+	i := 1
+	for ramp[i].Position < position {
+		i++
+	}
+	return scaleRampColor(ramp[i-1].Color, ramp[i].Color, (position-ramp[i-1].Position)/(ramp[i].Position-ramp[i-1].Position))
+}
+
+// Linearly interpolates the given colors at the given fraction from the first to the second, scaled to 16 bits
+func scaleRampColor(from, to [3]uint8, fraction float64) (R, G, B uint16) {
+	var color [3]uint16
+	for i := range color {
+		color[i] = uint16(math.Round((float64(from[i]) + (float64(to[i])-float64(from[i]))*fraction) * 257))
+	}
+	return color[0], color[1], color[2]
+}
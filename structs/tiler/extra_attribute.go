@@ -0,0 +1,33 @@
+package tiler
+
+import "strings"
+
+// Per point attribute read from the extra bytes of LAS and LAZ files, as declared by their Extra Bytes VLR, and
+// written as a scalar property of the batch table, e.g. to style the points on it
+type ExtraAttribute struct {
+	Name          string // Name of the attribute in the Extra Bytes VLR, also used as name of the batch table property
+	ComponentType string // Batch table component type of the property, e.g. FLOAT. If empty, set from the Extra Bytes VLRs
+}
+
+// Parses a comma separated list of names of extra bytes attributes, whose component types are left empty
+func ParseExtraAttributes(value string) []ExtraAttribute {
+	attributes := make([]ExtraAttribute, 0)
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			attributes = append(attributes, ExtraAttribute{Name: name})
+		}
+	}
+	return attributes
+}
+
+// Returns the names of the ExtraAttributes of the options, in the same order
+func (opts *TilerOptions) GetExtraAttributeNames() []string {
+	if len(opts.ExtraAttributes) == 0 {
+		return nil
+	}
+	names := make([]string, len(opts.ExtraAttributes))
+	for i, attribute := range opts.ExtraAttributes {
+		names[i] = attribute.Name
+	}
+	return names
+}
@@ -0,0 +1,27 @@
+package tiler
+
+import "os"
+
+const (
+	// Permission bits of the written files when FileMode is 0, readable by everyone and writable by the owner only
+	DefaultFileMode os.FileMode = 0644
+
+	// Permission bits of the created folders when DirMode is 0, listable by everyone and writable by the owner only
+	DefaultDirMode os.FileMode = 0755
+)
+
+// Returns the permission bits of the written files, FileMode or DefaultFileMode if not set
+func (opts *TilerOptions) GetFileMode() os.FileMode {
+	if opts.FileMode == 0 {
+		return DefaultFileMode
+	}
+	return opts.FileMode
+}
+
+// Returns the permission bits of the created folders, DirMode or DefaultDirMode if not set
+func (opts *TilerOptions) GetDirMode() os.FileMode {
+	if opts.DirMode == 0 {
+		return DefaultDirMode
+	}
+	return opts.DirMode
+}
@@ -0,0 +1,66 @@
+package tiler
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type IntensityScalingMode int
+
+const (
+	// 8 bit intensities are the 16 bit intensities of the input points divided by 256
+	NoIntensityScaling IntensityScalingMode = 0
+
+	// Intensities are linearly mapped to 0-255 from the range observed, with a first pass, across all the input points
+	AutoStretchIntensityScaling IntensityScalingMode = 1
+
+	// Intensities are linearly mapped to 0-255 from a given range, the ones outside of the range are clamped
+	FixedIntensityScaling IntensityScalingMode = 2
+)
+
+// Mapping of the 16 bit intensities of the input LAS and LAZ points to the 8 bit intensities of the tiles
+type IntensityScaling struct {
+	Mode     IntensityScalingMode
+	Min, Max uint16 // Input intensities mapped to 0 and 255 by the fixed mode
+}
+
+// Returns the scaling linearly mapping the given range of input intensities to 0-255
+func NewFixedIntensityScaling(min, max uint16) IntensityScaling {
+	return IntensityScaling{Mode: FixedIntensityScaling, Min: min, Max: max}
+}
+
+// Parses an intensity scaling, either none, auto or a min,max range of input intensities, e.g. "0,4096"
+func ParseIntensityScaling(value string) (IntensityScaling, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "none":
+		return IntensityScaling{Mode: NoIntensityScaling}, nil
+	case "auto":
+		return IntensityScaling{Mode: AutoStretchIntensityScaling}, nil
+	}
+	parts := strings.Split(value, ",")
+	if len(parts) == 2 {
+		min, minErr := strconv.ParseUint(strings.TrimSpace(parts[0]), 10, 16)
+		max, maxErr := strconv.ParseUint(strings.TrimSpace(parts[1]), 10, 16)
+		if minErr == nil && maxErr == nil && min < max {
+			return NewFixedIntensityScaling(uint16(min), uint16(max)), nil
+		}
+	}
+	return IntensityScaling{}, errors.New("invalid intensity scaling " + value + ", expected none, auto or min,max")
+}
+
+// Returns the 8 bit intensity of the given 16 bit input intensity. The auto stretch mode maps the range stored in Min
+// and Max, as the fixed mode does, the tiler replaces it with the fixed mode of the observed range before reading
+func (scaling IntensityScaling) Scale(intensity uint16) uint8 {
+	if scaling.Mode == NoIntensityScaling {
+		return uint8(intensity / 256)
+	}
+	if intensity <= scaling.Min {
+		return 0
+	}
+	if intensity >= scaling.Max {
+		return math.MaxUint8
+	}
+	return uint8(math.Round(float64(intensity-scaling.Min) * math.MaxUint8 / float64(scaling.Max-scaling.Min)))
+}
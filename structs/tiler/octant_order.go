@@ -0,0 +1,24 @@
+package tiler
+
+// Octants are numbered interleaving the bits of their position within the parent, x + 2y + 4z, with 1 denoting the
+// upper half along each axis. This is also the Morton order of implicit tiling.
+
+// Returns the index among the children of a node of the child covering the given octant, according to the
+// OctantOrder of the options
+func (opts *TilerOptions) GetChildIndex(octant uint8) uint8 {
+	for i, childOctant := range opts.OctantOrder {
+		if childOctant == octant {
+			return uint8(i)
+		}
+	}
+	return octant
+}
+
+// Returns the octant covered by the child with the given index among the children of a node, according to the
+// OctantOrder of the options
+func (opts *TilerOptions) GetChildOctant(index uint8) uint8 {
+	if len(opts.OctantOrder) == 0 {
+		return index
+	}
+	return opts.OctantOrder[index]
+}
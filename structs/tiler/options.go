@@ -2,6 +2,9 @@ package tiler
 
 import (
 	"github.com/mfbonfigli/gocesiumtiler/converters"
+	"github.com/mfbonfigli/gocesiumtiler/structs/geometry"
+	"github.com/mfbonfigli/gocesiumtiler/utils"
+	"os"
 )
 
 type LoaderStrategy int
@@ -15,20 +18,176 @@ const (
 	// is selected at random from the first box. Next data is taken at random from the following box. When boxes have all been visited
 	// the selection will begin again from the first one. If one box becomes empty is removed and replaced with the last one in the set.
 	BoxedRandom LoaderStrategy = 1
+
+	// Regular grid decimation. Coarse tiles get one point per cell of grids aligned with the octants of the tree, giving
+	// an even density regardless of the density of the input. Needs a map of the grid cells while sorting the points.
+	GridDecimation LoaderStrategy = 2
+
+	// Poisson-disk decimation. Like GridDecimation, but points of coarse tiles are never closer than the cell size, even
+	// across cells, giving the most even visual density without grid artifacts. Sorting the points is the slowest.
+	PoissonDisk LoaderStrategy = 3
+)
+
+type BoundingVolumeMode int
+
+const (
+	// Bounding regions, i.e. west, south, east, north in radians and min, max height in meters, for all the tiles
+	RegionBoundingVolumes BoundingVolumeMode = 0
+
+	// Bounding sphere in EPSG:4978 ECEF coordinates for the root tile, bounding regions for the other tiles
+	RootBoundingSphere BoundingVolumeMode = 1
+
+	// Bounding spheres in EPSG:4978 ECEF coordinates for all the tiles. Spheres are valid anywhere on the globe,
+	// including across the antimeridian and around the poles, at the cost of a looser fit
+	BoundingSpheres BoundingVolumeMode = 2
+
+	// Oriented bounding boxes in EPSG:4978 ECEF coordinates for all the tiles, aligned to the axes of the input
+	// coordinates. Tighter than regions for rotated or locally projected data
+	OrientedBoundingBoxes BoundingVolumeMode = 3
+)
+
+type OutputFormat int
+
+const (
+	// Point cloud tiles in the 3D Tiles 1.0 content.pnts format
+	PntsOutput OutputFormat = 0
+
+	// Point cloud tiles in the glTF 2.0 binary content.glb format of 3D Tiles 1.1, with POSITION and COLOR_0 attributes
+	// rendered unlit. Batch table properties are not written
+	GlbOutput OutputFormat = 1
+)
+
+type GeometricErrorMode int
+
+const (
+	// Geometric error estimated from the difference between the point spacing of the tile and the one of the tile
+	// together with its descendants. Leaf tiles get 0
+	DensityGeometricErrors GeometricErrorMode = 0
+
+	// Geometric error equal to a fraction of the diagonal of the bounding box of the tile, in meters. Always positive
+	// and halving at each level, as the size of the tiles
+	DiagonalGeometricErrors GeometricErrorMode = 1
+
+	// Geometric error equal to the diagonal of the bounding box of the tile divided by the cube root of the number of
+	// points rendered down to the tile, i.e. about the average spacing of the rendered points, in meters. Leaf tiles
+	// get 0, tiles without rendered points the whole diagonal
+	SpacingGeometricErrors GeometricErrorMode = 2
+)
+
+type OutputMode int
+
+const (
+	// Points in EPSG:4978 ECEF coordinates, placed on the globe without any transform
+	GeocentricCoordinates OutputMode = 0
+
+	// Points in east north up coordinates, in meters, relative to the center of the cloud, placed on the globe by the
+	// transform of the root tile. Suitable for scenes in local coordinates and avoids the large ECEF values
+	LocalEnuCoordinates OutputMode = 1
+
+	// Points in the input coordinates as they are, e.g. in a local or engineering coordinate system, with the root
+	// transform of the options, if any. Tiles get bounding spheres or boxes, as regions require geographic coordinates
+	RawCoordinates OutputMode = 2
+)
+
+// Naming scheme of the folders storing the tiles
+type TileNamingStrategy int
+
+const (
+	// Folders named after the index of each tile among the children of its parent, e.g. 0/3/5
+	NumericTileNames TileNamingStrategy = 0
+
+	// Folders named after the level of each tile, the root being at level 0, followed by its x, y and z indexes in the
+	// grid of the tiles of that level, e.g. 1_0_1_1/2_1_3_2, so that names are unique across levels and locate the tiles
+	HierarchicalTileNames TileNamingStrategy = 1
 )
 
 // Contains the options needed for the tiling algorithm
 type TilerOptions struct {
-	Input                  string                                // Input LAS file/folder
-	Output                 string                                // Output Cesium Tileset folder
-	Srid                   int                                   // EPSG code for SRID of input LAS points
-	ZOffset                float64                               // Z Offset in meters to apply to points during conversion
-	MaxNumPointsPerNode    int32                                 // Maximum allowed number of points per node
-	EnableGeoidZCorrection bool                                  // Enables the conversion from geoid to ellipsoid height
-	FolderProcessing       bool                                  // Enables the processing of all LAS files in folder
-	Recursive              bool                                  // Recursive lookup of LAS files in subfolders
-	Silent                 bool                                  // Suppressess console messages
-	Strategy               LoaderStrategy                        // Point loading strategy
-	CoordinateConverter    converters.CoordinateConverter        // Coordinate converter algorithm
-	ElevationConverter     converters.EllipsoidToGeoidZConverter // Elevation converter algorithm
+	Input                    string                                // Input LAS file/folder
+	Output                   string                                // Output Cesium Tileset folder
+	Srid                     int                                   // EPSG code for SRID of input LAS points
+	ZOffset                  float64                               // Z Offset in meters to apply to points during conversion
+	FileZOffsets             map[string]float64                    // Additional Z Offset in meters to apply to the points of the LAS files with the given file name
+	FileSrids                map[string]int                        // EPSG code for SRID of the points of the input files with the given file name, overriding Srid
+	MaxNumPointsPerNode      int32                                 // Maximum allowed number of points per node, i.e. per tile, nodes storing more points are split in octants
+	EnableGeoidZCorrection   bool                                  // Enables the conversion from geoid to ellipsoid height
+	FolderProcessing         bool                                  // Enables the processing of all LAS files in folder
+	Recursive                bool                                  // Recursive lookup of LAS files in subfolders
+	Silent                   bool                                  // Suppressess console messages
+	Strategy                 LoaderStrategy                        // Point loading strategy
+	BoundsSigmas             float64                               // If > 0, root bounds exclude points farther than this many std devs from the mean
+	SubtreePath              []uint8                               // Octant indexes from the root to the only subtree to write, empty to write all tiles
+	ClassificationAlpha      map[uint8]uint8                       // Alpha to apply to the points of each classification. If not empty colors are written as RGBA
+	FreeExportedItems        bool                                  // Releases the points of each node as soon as they are no longer needed by the export
+	SubtreeLevels            int                                   // If > 0, writes implicit tiling .subtree availability files each spanning this number of levels
+	NormalsMaxDepth          int                                   // Estimates and writes point normals only in tiles at depth <= this value (root has depth 1)
+	ComputeNormals           bool                                  // Estimates and writes point normals in all tiles, regardless of the NormalsMaxDepth
+	NormalNeighbors          int                                   // Number of nearest neighbours fitting the plane that estimates the normal of each point, 0 to use the default
+	PositionPrecision        float64                               // If > 0, lossy snaps point positions relative to the tile center to a grid of this size in meters
+	QuantizePositions        bool                                  // Writes positions as 16 bit integers within the tile bounds rather than float32, lossy up to 1/131070 of the tile size
+	WriteStatistics          bool                                  // Writes a statistics.json file with point counts, intensity statistics and bounds
+	EnforceRegionContainment bool                                  // Expands bounding regions to contain their children and validates the written tileset
+	Concurrency              int                                   // If > 0, tiles this number of files in parallel on a shared pool of this number of goroutines
+	WorkerPool               *utils.WorkerPool                     // Shared pool running the reading, building and exporting work, nil to use a goroutine per CPU
+	ClassificationGroups     []ClassificationGroup                 // If not empty, tiles the points of each group in a separate tileset
+	GeometricErrorMultiplier float64                               // Multiplier of the geometric error of the tiles, 0 is treated as 1
+	UseWktProjection         bool                                  // Reads the srid of each LAS file from its OGC or ESRI WKT VLR, if present
+	MaxTileBytes             int                                   // If > 0, lossy subsamples the points of each tile until its content.pnts fits in this number of bytes
+	MonotonicGeometricError  bool                                  // Caps the geometric error of each tile to the one of its parent and validates the written tileset
+	GeoidHeightProviders     []converters.GeoidHeightProvider      // Geoid heights used, in the given order, for the points they cover instead of the ElevationConverter
+	TextColumns              []string                              // Point attribute stored in each column of XYZ and CSV input files
+	TextDelimiter            string                                // Column delimiter of XYZ and CSV input files, empty to split on whitespace, commas and semicolons
+	Rgb565Colors             bool                                  // Writes colors packed in 2 bytes as RGB565 rather than RGB, ignored if ClassificationAlpha is not empty
+	NormalizeIntensity       bool                                  // Also writes the intensities divided by MaxIntensity in the NORMALIZED_INTENSITY float batch table property
+	MaxIntensity             uint8                                 // Intensity normalized to 1 by NormalizeIntensity, 0 to use the max intensity of the loaded points
+	ColorDepth               int                                   // Bits per color channel, 16 also writes the full depth colors in the RGB16 batch table property
+	OnTileWritten            func(tile TileInfo)                   // If not nil, called after each tile is written. Calls are serialized, never concurrent
+	ReadBufferSize           int                                   // Max size in bytes of the point records read at once from each LAS file, 0 to use the default
+	MergeFiles               bool                                  // Reads all the input files concurrently and tiles them in a single tileset written in the output folder
+	DepthFolders             bool                                  // Stores each tile in the L{depth}/{octant indexes from the root} folder rather than in nested octant folders
+	DropOriginPoints         bool                                  // Drops the LAS points whose source coordinates are exactly (0,0,0), logging their number
+	DeflateBuffers           bool                                  // Deflate compresses the binary bodies of the pnts tables with a custom extension, requiring a loader implementing it
+	ProgressCallback         func(stage string, done, total int64) // If not nil, called at most every 100ms with the points read and the tiles written so far, see utils.ProgressStageReading. Calls are serialized
+	BoundingVolumes          BoundingVolumeMode                    // Bounding volumes of the tiles, either regions, spheres for the root tile or for all the tiles or oriented boxes
+	AtomicWrites             bool                                  // Writes each tile file to a temporary file in TempDir, then moves it in place, so that tile files are never partially written
+	TempDir                  string                                // Folder of the temporary files, empty to write them next to the tile files
+	RandomSeed               int64                                 // If not 0, seeds the shuffling of the points and builds the tree on a single goroutine, making the output reproducible
+	GeometricErrors          GeometricErrorMode                    // Computation of the geometric error of the tiles, from the point density, from the tile size or from the point spacing
+	DiagonalFraction         float64                               // Fraction of the bounding box diagonal used as geometric error by DiagonalGeometricErrors
+	MasterTileset            bool                                  // In folder processing mode, also writes a tileset.json in the output folder loading the tilesets of all the files
+	OutputFormat             OutputFormat                          // Format of the tile contents, content.pnts or 3D Tiles 1.1 content.glb
+	WriteLegend              bool                                  // Writes a legend.json file with the name, number of points and alpha of each classification of the points
+	MaxLevels                int                                   // If > 0, nodes at this depth (root has depth 1) are never subdivided and store all their points, exceeding MaxNumPointsPerNode
+	DracoCompression         bool                                  // Stores positions, normals and colors of the content.pnts tiles in a Draco buffer with the 3DTILES_draco_point_compression extension, instead of quantized positions and RGB565 colors
+	DracoEncoder             DracoEncoder                          // Encoder of the Draco buffers, if nil tiles are written uncompressed even if DracoCompression is set
+	UrlQuery                 string                                // If not empty, query string appended to the content urls of the tilesets, e.g. v=20240101 to bust CDN caches
+	OctantOrder              []uint8                               // Octant, x + 2y + 4z, covered by each child index, i.e. each tile folder name. Empty for the identity
+	NumWriters               int                                   // Number of goroutines writing the tiles, 0 for one per CPU or per goroutine of the shared pool
+	OverlapCellSize          float64                               // If > 0, keeps only the points of a flightline in each cell of this size in meters covered by overlapping flightlines
+	GraftTileset             string                                // If not empty, root tileset.json of an existing tileset hierarchy in which the written tileset is linked
+	GraftSlot                []int                                 // Path of child indexes, from the root tile of GraftTileset, of the tile linking the written tileset
+	FileMode                 os.FileMode                           // Permission bits of the written files, 0 for DefaultFileMode. Atomically written files get exactly these bits, the others are subject to the umask
+	DirMode                  os.FileMode                           // Permission bits of the created folders, 0 for DefaultDirMode, subject to the umask
+	MinRegionHeight          float64                               // Min height span in meters of the bounding regions, thinner regions, e.g. of flat tiles, are thickened around their mid height
+	IntensityScaling         IntensityScaling                      // Mapping of the 16 bit intensities of LAS and LAZ points to 8 bit, by default divided by 256
+	ClassificationColorMap   map[uint8][3]uint8                    // If not empty, colors the points of the input files without RGB from their classification, see DefaultClassificationColorMap
+	UnmappedClassColor       [3]uint8                              // Color of the points whose classification is not in the ClassificationColorMap
+	ForceClassificationColor bool                                  // Colors the points from their classification even if the input files have RGB
+	ElevationRamp            ElevationRamp                         // If not empty, colors the points from their elevation between the min and max elevations of the root tile, replacing their colors
+	IncludeClasses           []uint8                               // If not empty, reads only the points of these classifications, before applying ExcludeClasses
+	ExcludeClasses           []uint8                               // Drops the points of these classifications while reading, even if in IncludeClasses
+	ClipBounds               *geometry.BoundingBox                 // If not nil, skips while reading the points outside of this box, boundaries included, in the input srid
+	KeepEveryNth             int                                   // If > 1, reads only one point every this number of points of each input file, e.g. for quick previews
+	RootTransform            [16]float64                           // Column major 4x4 matrix written as transform of the root tile, e.g. to translate the cloud in ECEF coordinates. All zeros or identity for none
+	OutputMode               OutputMode                            // Coordinates of the written points, EPSG:4978 ECEF, east north up relative to the cloud center or the input ones as they are
+	TightBounds              bool                                  // Bounds each tile by the extent of its points and of the ones of its descendants rather than by its octree cell, at the cost of an extra pass on the points
+	GzipOutput               bool                                  // Gzip compresses the content and tileset.json files, keeping their names, so that they can be served with Content-Encoding: gzip
+	TileWriter               TileWriter                            // Writer of all the output files, nil to write them to the local disk. Skips the validations, rejects the master and graft tilesets
+	Resume                   bool                                  // Writes a size and checksum sidecar next to each tile file and skips the tiles whose file matches its sidecar, to finish interrupted runs
+	DeduplicateTolerance     float64                               // If > 0, drops the points closer than this distance in meters to a point already read, 0 keeps all the points
+	ExtraAttributes          []ExtraAttribute                      // Attributes of the LAS extra bytes written as batch table properties, missing values are written as NaN or 0
+	TileNamingStrategy       TileNamingStrategy                    // Naming scheme of the tile folders, octant indexes or level_x_y_z
+	ImplicitTiling           bool                                  // Writes a single 3D Tiles 1.1 tileset.json with implicit octree tiling, the content template and the subtree files, rather than explicit tilesets
+	CoordinateConverter      converters.CoordinateConverter        // Coordinate converter algorithm
+	ElevationConverter       converters.EllipsoidToGeoidZConverter // Elevation converter algorithm
 }
@@ -0,0 +1,40 @@
+package tiler
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Identity 4x4 matrix, in column major order
+var identityTransform = [16]float64{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}
+
+// Parses a 4x4 matrix given as 16 comma separated values in column major order, as in the transform of 3D Tiles, i.e.
+// the translation is made of the 13th, 14th and 15th values. An empty value returns the zero matrix, i.e. no transform
+func ParseTransform(value string) ([16]float64, error) {
+	var transform [16]float64
+	if strings.TrimSpace(value) == "" {
+		return transform, nil
+	}
+	tokens := strings.Split(value, ",")
+	if len(tokens) != 16 {
+		return transform, errors.New("invalid transform " + value + ", expected 16 comma separated values in column major order")
+	}
+	for i, token := range tokens {
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(token), 64)
+		if err != nil {
+			return transform, errors.New("invalid transform value " + token)
+		}
+		transform[i] = parsed
+	}
+	return transform, nil
+}
+
+// Returns the RootTransform of the options as a slice in column major order, nil if it is all zeros or the identity,
+// i.e. if no transform has to be written
+func (opts *TilerOptions) GetRootTransform() []float64 {
+	if opts.RootTransform == [16]float64{} || opts.RootTransform == identityTransform {
+		return nil
+	}
+	return opts.RootTransform[:]
+}
@@ -0,0 +1,12 @@
+package tiler
+
+// Metadata of a written tile, passed to the TilerOptions OnTileWritten callback
+type TileInfo struct {
+	Path           string    // Path of the content.pnts file of the tile
+	ByteSize       int       // Size in bytes of the content.pnts file
+	PointCount     int       // Number of points stored in the content.pnts file
+	DroppedPoints  int       // Number of points of the tile left out of the content.pnts file to fit the max tile bytes
+	Region         []float64 // Bounding region of the tile as west, south, east, north in radians and min, max height in meters
+	Depth          int       // Depth of the tile in the tree, 1 for the root tile
+	GeometricError float64   // Geometric error of the tile in meters
+}
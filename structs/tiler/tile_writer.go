@@ -0,0 +1,15 @@
+package tiler
+
+// Writes the content and tileset.json files of the tiles, e.g. to the local disk, to a cloud storage bucket or to
+// memory, together with the subtree, statistics.json and legend.json files and the master tileset.json. Paths are the
+// ones the files would have on the local disk, i.e. inside the Output folder of the options. The master and graft
+// tilesets, reading the written tilesets back from the local disk, cannot be used with a custom writer, which also
+// skips the validation of the region containment and of the geometric errors. Implementations must be safe for
+// concurrent use, as tiles are written by several goroutines
+type TileWriter interface {
+	// Writes the given data to the file at the given path, replacing it if existing
+	WriteFile(path string, data []byte) error
+
+	// Creates the folder at the given path together with its missing parents, if any
+	MkdirAll(path string) error
+}
@@ -0,0 +1,182 @@
+package test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAtomicWriteMovesTheFileInPlace(t *testing.T) {
+	tempDir := t.TempDir()
+	file := filepath.Join(t.TempDir(), "content.pnts")
+	if err := os.WriteFile(file, []byte("old"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := io.WriteFileAtomic(file, []byte("new content"), 0666, tempDir); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(content, []byte("new content")) {
+		t.Errorf("Expected the new content, got %q", content)
+	}
+	assertEmptyFolder(t, tempDir)
+	assertEmptyFolder(t, filepath.Dir(file), "content.pnts")
+}
+
+func TestAtomicWriteRemovesTheTemporaryFileOnError(t *testing.T) {
+	tempDir := t.TempDir()
+	file := filepath.Join(t.TempDir(), "missing", "content.pnts")
+	if err := io.WriteFileAtomic(file, []byte("content"), 0666, tempDir); err == nil {
+		t.Errorf("Expected an error writing in a missing folder")
+	}
+	assertEmptyFolder(t, tempDir)
+}
+
+func TestAtomicWritesProduceTheSameTileset(t *testing.T) {
+	plain := newTestOptions(t)
+	defer os.RemoveAll(plain.Output)
+	plain.MaxNumPointsPerNode = 20
+	tree := buildTree(t, newTestPoints(), plain)
+	exportTree(t, tree, plain)
+	atomic := newTestOptions(t)
+	defer os.RemoveAll(atomic.Output)
+	atomic.MaxNumPointsPerNode = 20
+	atomic.AtomicWrites = true
+	atomic.TempDir = t.TempDir()
+	exportTree(t, tree, atomic)
+
+	plainContents := make(map[string]int)
+	collectTileContents(t, plain.Output, "tileset.json", 1, plainContents)
+	atomicContents := make(map[string]int)
+	collectTileContents(t, atomic.Output, "tileset.json", 1, atomicContents)
+	if len(plainContents) < 3 || len(plainContents) != len(atomicContents) {
+		t.Fatalf("Expected %d tiles, got %d", len(plainContents), len(atomicContents))
+	}
+	for content := range plainContents {
+		expected, err := os.ReadFile(filepath.Join(plain.Output, content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual, err := os.ReadFile(filepath.Join(atomic.Output, content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(expected, actual) {
+			t.Errorf("Tile %s differs when written atomically", content)
+		}
+	}
+	assertEmptyFolder(t, atomic.TempDir)
+}
+
+func TestAtomicWriteNeverLeavesATruncatedFile(t *testing.T) {
+	content := bytes.Repeat([]byte("complete content "), 1000)
+	for _, tc := range []struct {
+		name     string
+		existing []byte
+	}{
+		{"new file", nil},
+		{"existing file", []byte("previous complete content")},
+	} {
+		folder := t.TempDir()
+		file := filepath.Join(folder, "content.pnts")
+		if tc.existing != nil {
+			if err := os.WriteFile(file, tc.existing, 0666); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// a write failing after half the content
+		err := io.WriteFileAtomicFunc(file, func(f *os.File) error {
+			if _, err := f.Write(content[:len(content)/2]); err != nil {
+				return err
+			}
+			return errors.New("disk full")
+		}, 0666, "")
+		if err == nil {
+			t.Errorf("%s: expected the write error to be returned", tc.name)
+		}
+		assertCompleteOrAbsent(t, file, tc.existing)
+		assertEmptyFolder(t, folder, "content.pnts")
+
+		// a crash after half the content, leaving the temporary file behind
+		func() {
+			defer func() { _ = recover() }()
+			_ = io.WriteFileAtomicFunc(file, func(f *os.File) error {
+				if _, err := f.Write(content[:len(content)/2]); err != nil {
+					return err
+				}
+				panic("crash")
+			}, 0666, folder)
+		}()
+		assertCompleteOrAbsent(t, file, tc.existing)
+
+		// a completed write replaces the file
+		if err := io.WriteFileAtomic(file, content, 0666, folder); err != nil {
+			t.Fatal(err)
+		}
+		assertCompleteOrAbsent(t, file, content)
+	}
+}
+
+func TestAtomicWritesDefaultToTheTileFolder(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.MaxNumPointsPerNode = 20
+	opts.AtomicWrites = true
+	writeTileset(t, newTestPoints(), opts)
+
+	contents := make(map[string]int)
+	collectTileContents(t, opts.Output, "tileset.json", 1, contents)
+	if len(contents) < 3 {
+		t.Fatalf("Expected a tileset with at least 3 tiles, got %d", len(contents))
+	}
+	err := filepath.Walk(opts.Output, func(filePath string, info os.FileInfo, err error) error {
+		if err == nil && filepath.Ext(filePath) == ".tmp" {
+			t.Errorf("Unexpected temporary file %s left in the output folder", filePath)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Fails the test unless the given file holds the given content or, if the content is nil, does not exist
+func assertCompleteOrAbsent(t *testing.T, file string, expected []byte) {
+	actual, err := os.ReadFile(file)
+	if expected == nil {
+		if !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be absent, got %d bytes", file, len(actual))
+		}
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(actual, expected) {
+		t.Errorf("Expected %s to hold its %d bytes of complete content, got %d bytes", file, len(expected), len(actual))
+	}
+}
+
+// Fails the test if the given folder contains files other than the given ones
+func assertEmptyFolder(t *testing.T, folder string, allowed ...string) {
+	files, err := os.ReadDir(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		found := false
+		for _, name := range allowed {
+			found = found || file.Name() == name
+		}
+		if !found {
+			t.Errorf("Unexpected file %s left in %s", file.Name(), folder)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package test
+
+import (
+	"errors"
+	"github.com/mfbonfigli/gocesiumtiler/app"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/utils"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func writeBatchTestLasFiles(t *testing.T, count int) []string {
+	files := make([]string, count)
+	for i := range files {
+		rawPoints := make([][3]int32, 0)
+		for j := 0; j < 200; j++ {
+			rawPoints = append(rawPoints, [3]int32{int32(j%10 + i), int32(j % 7), int32(j % 13)})
+		}
+		files[i] = writeTestLasFile(t, testLasHeader{scale: [3]float64{1, 1, 1}}, rawPoints)
+	}
+	return files
+}
+
+func TestBatchTilingMatchesSequentialTiling(t *testing.T) {
+	files := writeBatchTestLasFiles(t, 5)
+	jobs := make([]app.BatchJob, len(files))
+	for i, file := range files {
+		output, err := os.MkdirTemp("", "gocesiumtiler")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(output)
+		jobs[i] = app.BatchJob{Input: file, Output: output}
+	}
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+
+	if err := app.RunBatchTiler(jobs, opts, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, job := range jobs {
+		sequentialOpts := newTestOptions(t)
+		defer os.RemoveAll(sequentialOpts.Output)
+		sequentialOpts.Input = job.Input
+		if err := app.RunTiler(sequentialOpts); err != nil {
+			t.Fatal(err)
+		}
+		expected := filepath.Join(sequentialOpts.Output, "test")
+		actual := filepath.Join(job.Output, "test")
+		if err := io.CompareTilesetFolders(expected, actual, 1e-3); err != nil {
+			t.Errorf("Expected batch tileset to match sequential tileset, got %v", err)
+		}
+	}
+}
+
+func TestBatchTilingReportsEveryFailedJob(t *testing.T) {
+	files := writeBatchTestLasFiles(t, 1)
+	missing, err := os.MkdirTemp("", "gocesiumtiler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(missing)
+	inputs := []string{filepath.Join(missing, "a.las"), files[0], filepath.Join(missing, "b.las"), filepath.Join(missing, "c.las")}
+	jobs := make([]app.BatchJob, len(inputs))
+	for i, input := range inputs {
+		output, err := os.MkdirTemp("", "gocesiumtiler")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(output)
+		jobs[i] = app.BatchJob{Input: input, Output: output}
+	}
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+
+	err = app.RunBatchTiler(jobs, opts, 2)
+	var batchErr *app.BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("Expected a BatchError, got %v", err)
+	}
+	failed := make(map[string]bool)
+	for _, jobErr := range batchErr.Errors {
+		var e *app.JobError
+		if !errors.As(jobErr, &e) {
+			t.Fatalf("Expected a JobError, got %v", jobErr)
+		}
+		failed[e.Input] = true
+	}
+	if len(failed) != 3 || !failed[inputs[0]] || !failed[inputs[2]] || !failed[inputs[3]] {
+		t.Errorf("Expected the jobs of the missing files to fail, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(jobs[1].Output, "test", "tileset.json")); err != nil {
+		t.Errorf("Expected the tileset of the existing file to be written, got %v", err)
+	}
+}
+
+func TestWorkerPoolBoundsRunningTasks(t *testing.T) {
+	pool := utils.NewWorkerPool(3)
+	defer pool.Close()
+
+	var running, maxRunning, completed int32
+	tasks := make([]func(), 50)
+	for i := range tasks {
+		tasks[i] = func() {
+			current := atomic.AddInt32(&running, 1)
+			for {
+				max := atomic.LoadInt32(&maxRunning)
+				if current <= max || atomic.CompareAndSwapInt32(&maxRunning, max, current) {
+					break
+				}
+			}
+			atomic.AddInt32(&running, -1)
+			atomic.AddInt32(&completed, 1)
+		}
+	}
+
+	// run the tasks from several goroutines sharing the pool
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pool.Run(tasks...)
+		}()
+	}
+	wg.Wait()
+
+	if completed != 200 {
+		t.Errorf("Expected 200 completed tasks, got %d", completed)
+	}
+	if maxRunning > 3 {
+		t.Errorf("Expected at most 3 tasks running at once, got %d", maxRunning)
+	}
+}
+
+func TestNilWorkerPoolRunsTasks(t *testing.T) {
+	var pool *utils.WorkerPool
+	var completed int32
+	pool.Run(func() { atomic.AddInt32(&completed, 1) }, func() { atomic.AddInt32(&completed, 1) })
+	if completed != 2 {
+		t.Errorf("Expected 2 completed tasks, got %d", completed)
+	}
+}
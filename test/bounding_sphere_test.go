@@ -0,0 +1,130 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"github.com/mfbonfigli/gocesiumtiler/structs/geometry"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// CoordinateConverter converting WGS84 longitude, latitude and ellipsoidal height, in degrees and meters, to ECEF
+type geodeticCoordinateConverter struct {
+	identityCoordinateConverter
+}
+
+func (c *geodeticCoordinateConverter) ConvertToWGS84Cartesian(coord geometry.Coordinate, sourceSrid int) (geometry.Coordinate, error) {
+	const a = 6378137.0
+	const e2 = 6.69437999014e-3
+	lon, lat, h := *coord.X*math.Pi/180, *coord.Y*math.Pi/180, *coord.Z
+	n := a / math.Sqrt(1-e2*math.Sin(lat)*math.Sin(lat))
+	x := (n + h) * math.Cos(lat) * math.Cos(lon)
+	y := (n + h) * math.Cos(lat) * math.Sin(lon)
+	z := (n*(1-e2) + h) * math.Sin(lat)
+	return geometry.Coordinate{X: &x, Y: &y, Z: &z}, nil
+}
+
+// Returns points spread on a grid of the given size in degrees and meters around the given longitude and latitude
+func newTestGeographicPoints(lon, lat, size, height float64) []*data.Point {
+	points := make([]*data.Point, 0)
+	for i := 0; i < 1000; i++ {
+		x := lon + size*float64(i%10)/9 - size/2
+		y := lat + size*float64(i/10%10)/9 - size/2
+		points = append(points, data.NewPoint(x, y, height*float64(i/100)/9, 0, 0, 0, 0, 0))
+	}
+	return points
+}
+
+func TestBoundingSpheresContainThePointsOfTheirTilesAnywhereOnTheGlobe(t *testing.T) {
+	for _, tc := range []struct {
+		name               string
+		lon, lat, size, dz float64
+	}{
+		{"equator", 12.5, 0, 0.01, 50},
+		{"mid latitude", -73.9, 40.7, 0.05, 300},
+		{"antimeridian", 179.99, -16.5, 0.019, 20},
+		{"north pole", 45, 89.99, 0.019, 10},
+		{"south pole", -120, -89.98, 0.03, 2000},
+	} {
+		opts := newTestOptions(t)
+		opts.Srid = 4326
+		opts.MaxNumPointsPerNode = 100
+		opts.CoordinateConverter = &geodeticCoordinateConverter{}
+		opts.BoundingVolumes = tiler.BoundingSpheres
+		writeTileset(t, newTestGeographicPoints(tc.lon, tc.lat, tc.size, tc.dz), opts)
+
+		spheres := make(map[string][]float64)
+		collectTileSpheres(t, opts.Output, "tileset.json", spheres)
+		if len(spheres) < 2 {
+			t.Errorf("%s: expected more than one tile, got %d", tc.name, len(spheres))
+		}
+		for content, sphere := range spheres {
+			if len(sphere) != 4 || sphere[3] <= 0 {
+				t.Fatalf("%s: invalid sphere %v of %s", tc.name, sphere, content)
+			}
+			pnts, err := io.ReadPntsFile(filepath.Join(opts.Output, content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < len(pnts.Positions); i += 3 {
+				distance := math.Sqrt(math.Pow(pnts.Positions[i]-sphere[0], 2) + math.Pow(pnts.Positions[i+1]-sphere[1], 2) + math.Pow(pnts.Positions[i+2]-sphere[2], 2))
+				// float32 positions relative to the tile center
+				if distance > sphere[3]+0.01 {
+					t.Errorf("%s: point at %f m from the center of the sphere of %s, radius %f m", tc.name, distance, content, sphere[3])
+					break
+				}
+			}
+		}
+		_ = os.RemoveAll(opts.Output)
+	}
+}
+
+func TestRootBoundingSphereKeepsRegionsForTheOtherTiles(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.Srid = 4326
+	opts.MaxNumPointsPerNode = 100
+	opts.CoordinateConverter = &geodeticCoordinateConverter{}
+	opts.BoundingVolumes = tiler.RootBoundingSphere
+	writeTileset(t, newTestGeographicPoints(12.5, 41.9, 0.01, 50), opts)
+
+	tileset, err := io.ReadTilesetFile(filepath.Join(opts.Output, "tileset.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tileset.Root.BoundingVolume.Sphere) != 4 || tileset.Root.BoundingVolume.Region != nil {
+		t.Errorf("Expected a root bounding sphere, got %v", tileset.Root.BoundingVolume)
+	}
+	if len(tileset.Root.Children) == 0 {
+		t.Fatalf("Expected child tiles")
+	}
+	for _, child := range tileset.Root.Children {
+		if len(child.BoundingVolume.Region) != 6 || child.BoundingVolume.Sphere != nil {
+			t.Errorf("Expected a bounding region for %s, got %v", child.Content.Url, child.BoundingVolume)
+		}
+	}
+}
+
+// Follows the content urls of the given tileset.json, relative to the given folder, collecting the bounding sphere of
+// the tile of every referenced content.pnts file
+func collectTileSpheres(t *testing.T, folder string, tilesetFile string, spheres map[string][]float64) {
+	tileset, err := io.ReadTilesetFile(filepath.Join(folder, tilesetFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := path.Dir(tilesetFile)
+	spheres[path.Join(dir, tileset.Root.Content.Url)] = tileset.Root.BoundingVolume.Sphere
+	for _, child := range tileset.Root.Children {
+		url := path.Join(dir, child.Content.Url)
+		if strings.HasSuffix(url, ".json") {
+			collectTileSpheres(t, folder, url, spheres)
+		} else {
+			spheres[url] = child.BoundingVolume.Sphere
+		}
+	}
+}
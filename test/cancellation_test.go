@@ -0,0 +1,128 @@
+package test
+
+import (
+	"context"
+	"github.com/mfbonfigli/gocesiumtiler/app"
+	"github.com/mfbonfigli/gocesiumtiler/converters/offset_elevation_corrector"
+	"github.com/mfbonfigli/gocesiumtiler/lasread"
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"github.com/mfbonfigli/gocesiumtiler/structs/point_loader"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Loader cancelling a context once the given number of points is added
+type cancellingLoader struct {
+	point_loader.Loader
+	added  int64
+	after  int64
+	cancel context.CancelFunc
+}
+
+func (loader *cancellingLoader) AddElement(e *data.Point) {
+	if atomic.AddInt64(&loader.added, 1) == loader.after {
+		loader.cancel()
+	}
+	loader.Loader.AddElement(e)
+}
+
+// Writes a LAS file with the given number of points spread on a 100 x 100 x 100 grid
+func writeGridTestLasFile(t *testing.T, pointNo int) string {
+	rawPoints := make([][3]int32, pointNo)
+	for i := range rawPoints {
+		rawPoints[i] = [3]int32{int32(i % 100), int32(i / 100 % 100), int32(i / 10000 % 100)}
+	}
+	return writeTestLasFile(t, testLasHeader{scale: [3]float64{1, 1, 1}}, rawPoints)
+}
+
+// Waits up to the given time for the number of goroutines to drop to the given one
+func waitForGoroutines(t *testing.T, expected int, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for runtime.NumGoroutine() > expected {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected at most %d goroutines after %v, got %d", expected, timeout, runtime.NumGoroutine())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCancelledLasReadStopsIngestingPoints(t *testing.T) {
+	const pointNo = 200000
+	file := writeGridTestLasFile(t, pointNo)
+	defer os.RemoveAll(filepath.Dir(file))
+	goroutines := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	loader := &cancellingLoader{Loader: point_loader.NewRandomLoader(0), after: 1000, cancel: cancel}
+	lasFileLoader := lidario.NewLasFileLoader(&identityCoordinateConverter{}, nil, loader, nil, false)
+	lasFileLoader.ReadBufferSize = 20 * 10000
+	lf, err := lasFileLoader.LoadLasFileContext(ctx, file, offset_elevation_corrector.NewOffsetElevationCorrector(0), 4326)
+	_ = lf.Close()
+	if err != context.Canceled {
+		t.Fatalf("Expected %v, got %v", context.Canceled, err)
+	}
+	if added := atomic.LoadInt64(&loader.added); added >= pointNo {
+		t.Errorf("Expected the reading to stop before all the %d points, got %d", pointNo, added)
+	}
+	waitForGoroutines(t, goroutines, 5*time.Second)
+}
+
+func TestCancelledTilingStopsAndRemovesTheWrittenTiles(t *testing.T) {
+	file := writeGridTestLasFile(t, 20000)
+	defer os.RemoveAll(filepath.Dir(file))
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.Input = file
+	opts.MaxNumPointsPerNode = 20
+	goroutines := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	written := 0
+	opts.OnTileWritten = func(tile tiler.TileInfo) {
+		written++
+		if written == 5 {
+			cancel()
+		}
+	}
+	if err := app.RunTilerContext(ctx, opts); err != context.Canceled {
+		t.Fatalf("Expected %v, got %v", context.Canceled, err)
+	}
+	waitForGoroutines(t, goroutines, 5*time.Second)
+
+	err := filepath.Walk(opts.Output, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			t.Errorf("Expected the files of the interrupted tileset to be removed, found %s", path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written >= 20000/20 {
+		t.Errorf("Expected the export to stop early, %d tiles written", written)
+	}
+}
+
+func TestTilingWithCancelledContextDoesNotStart(t *testing.T) {
+	file := writeGridTestLasFile(t, 100)
+	defer os.RemoveAll(filepath.Dir(file))
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.Input = file
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := app.RunTilerContext(ctx, opts); err != context.Canceled {
+		t.Fatalf("Expected %v, got %v", context.Canceled, err)
+	}
+	if _, err := os.Stat(filepath.Join(opts.Output, "test", "tileset.json")); !os.IsNotExist(err) {
+		t.Errorf("Expected no tileset, got %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/converters/offset_elevation_corrector"
+	"github.com/mfbonfigli/gocesiumtiler/lasread"
+	"github.com/mfbonfigli/gocesiumtiler/structs/point_loader"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"github.com/mfbonfigli/gocesiumtiler/textread"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClassificationColorMapIsParsed(t *testing.T) {
+	colorMap, err := tiler.ParseClassificationColorMap("2:8b5a2b, 6:#808080")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(colorMap) != 2 || colorMap[2] != [3]uint8{0x8b, 0x5a, 0x2b} || colorMap[6] != [3]uint8{128, 128, 128} {
+		t.Errorf("Expected the colors of the classifications 2 and 6, got %v", colorMap)
+	}
+	if asprs, err := tiler.ParseClassificationColorMap("asprs"); err != nil || len(asprs) != len(tiler.DefaultClassificationColorMap()) {
+		t.Errorf("Expected the default ASPRS colors, got %v, %v", asprs, err)
+	}
+	if none, err := tiler.ParseClassificationColorMap("none"); err != nil || len(none) != 0 {
+		t.Errorf("Expected no colors, got %v, %v", none, err)
+	}
+	for _, invalid := range []string{"2", "2:8b5a2", "2:gg0000", "256:ffffff", "2:ffffff,x"} {
+		if _, err := tiler.ParseClassificationColorMap(invalid); err == nil {
+			t.Errorf("Expected an error parsing the color map %s", invalid)
+		}
+	}
+}
+
+func TestLasPointsWithoutRgbAreColoredByClassification(t *testing.T) {
+	classifications := []uint8{2, 6, 9, 1}
+	file := writeTestLasFile(t, testLasHeader{scale: [3]float64{1, 1, 1}}, [][3]int32{{0, 0, 0}, {1, 1, 1}, {2, 2, 2}, {3, 3, 3}})
+	defer os.RemoveAll(filepath.Dir(file))
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the 20 bytes records of point format 0 store the classification after the coordinates, intensity and returns
+	pointsOffset := len(content) - 20*len(classifications)
+	for i, classification := range classifications {
+		content[pointsOffset+20*i+15] = classification
+	}
+	if err := os.WriteFile(file, content, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := tiler.TilerOptions{ClassificationColorMap: tiler.DefaultClassificationColorMap(), UnmappedClassColor: [3]uint8{255, 0, 255}}
+	loader := point_loader.NewRandomLoader(0)
+	lasFileLoader := lidario.NewLasFileLoader(&identityCoordinateConverter{}, nil, loader, nil, false)
+	lasFileLoader.ColorClassification = opts.GetClassificationColorizer()
+	lf, err := lasFileLoader.LoadLasFile(file, offset_elevation_corrector.NewOffsetElevationCorrector(0), 4326)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = lf.Close()
+
+	loader.Initialize()
+	colored := 0
+	for {
+		point, shouldContinue := loader.GetNext()
+		if point != nil {
+			expected, ok := opts.ClassificationColorMap[point.Classification]
+			if !ok {
+				expected = opts.UnmappedClassColor
+			}
+			if actual := [3]uint8{uint8(point.R >> 8), uint8(point.G >> 8), uint8(point.B >> 8)}; actual != expected {
+				t.Errorf("Expected the color %v for the classification %d, got %v", expected, point.Classification, actual)
+			}
+			colored++
+		}
+		if !shouldContinue {
+			break
+		}
+	}
+	if colored != len(classifications) {
+		t.Errorf("Expected %d points, got %d", len(classifications), colored)
+	}
+}
+
+func TestClassificationColorsReplaceNativeColorsOnlyIfForced(t *testing.T) {
+	file := writeTestTextFile(t, "test.xyz", "1 2 3 10 20 30 6\n")
+	defer os.RemoveAll(filepath.Dir(file))
+	columns, err := textread.ParseColumns("x,y,z,r,g,b,class")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := tiler.TilerOptions{ClassificationColorMap: tiler.DefaultClassificationColorMap()}
+	for _, force := range []bool{false, true} {
+		loader := point_loader.NewRandomLoader(0)
+		textFileLoader := textread.NewTextFileLoader(&identityCoordinateConverter{}, loader, columns, "")
+		textFileLoader.ColorClassification = opts.GetClassificationColorizer()
+		textFileLoader.ForceClassColor = force
+		if err := textFileLoader.LoadTextFile(file, offset_elevation_corrector.NewOffsetElevationCorrector(0), 4326); err != nil {
+			t.Fatal(err)
+		}
+		loader.Initialize()
+		point, _ := loader.GetNext()
+		expected := [3]uint8{10, 20, 30}
+		if force {
+			expected = opts.ClassificationColorMap[6]
+		}
+		if actual := [3]uint8{uint8(point.R >> 8), uint8(point.G >> 8), uint8(point.B >> 8)}; actual != expected {
+			t.Errorf("Expected the color %v with force %v, got %v", expected, force, actual)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/app"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"github.com/mfbonfigli/gocesiumtiler/structs/point_loader"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClassificationFilterIncludesThenExcludes(t *testing.T) {
+	for _, c := range []struct {
+		include, exclude []uint8
+		expected         map[uint8]bool
+	}{
+		{nil, nil, map[uint8]bool{1: true, 2: true, 7: true, 9: true}},
+		{[]uint8{2, 9}, nil, map[uint8]bool{2: true, 9: true}},
+		{nil, []uint8{7}, map[uint8]bool{1: true, 2: true, 9: true}},
+		{[]uint8{2, 7}, []uint8{7, 9}, map[uint8]bool{2: true}},
+	} {
+		loader := point_loader.NewRandomLoader(0)
+		filter := point_loader.NewClassificationFilterLoader(loader, c.include, c.exclude)
+		for _, classification := range []uint8{1, 2, 7, 9} {
+			filter.AddElement(data.NewPoint(0, 0, 0, 0, 0, 0, 0, classification))
+		}
+		filter.Initialize()
+		kept := make(map[uint8]bool)
+		for {
+			point, shouldContinue := filter.GetNext()
+			if point != nil {
+				kept[point.Classification] = true
+			}
+			if !shouldContinue {
+				break
+			}
+		}
+		if len(kept) != len(c.expected) || int(filter.GetDroppedCount()) != 4-len(c.expected) {
+			t.Errorf("Expected the classifications %v including %v and excluding %v, got %v with %d dropped points", c.expected, c.include, c.exclude, kept, filter.GetDroppedCount())
+		}
+		for classification := range c.expected {
+			if !kept[classification] {
+				t.Errorf("Expected the classification %d including %v and excluding %v, got %v", classification, c.include, c.exclude, kept)
+			}
+		}
+	}
+}
+
+func TestFilteredClassificationsAreNotTiled(t *testing.T) {
+	// 100 points of each of the classifications 1, 2, 7 and 18
+	classifications := []uint8{1, 2, 7, 18}
+	rawPoints := make([][3]int32, 400)
+	for i := range rawPoints {
+		rawPoints[i] = [3]int32{int32(i % 20), int32(i / 20), int32(i % 7)}
+	}
+	file := writeTestLasFile(t, testLasHeader{scale: [3]float64{1, 1, 1}}, rawPoints)
+	defer os.RemoveAll(filepath.Dir(file))
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pointsOffset := len(content) - 20*len(rawPoints)
+	for i := range rawPoints {
+		content[pointsOffset+20*i+15] = classifications[i%len(classifications)]
+	}
+	if err := os.WriteFile(file, content, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.Input = file
+	opts.MaxNumPointsPerNode = 20
+	opts.IncludeClasses = []uint8{1, 2, 7}
+	opts.ExcludeClasses = []uint8{7, 18}
+	var tiledPoints int
+	opts.OnTileWritten = func(tile tiler.TileInfo) {
+		tiledPoints += tile.PointCount
+	}
+	if err := app.RunTiler(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	contents := make(map[string]int)
+	collectTileContents(t, filepath.Join(opts.Output, "test"), "tileset.json", 1, contents)
+	total := 0
+	for content := range contents {
+		pnts, err := io.ReadPntsFile(filepath.Join(opts.Output, "test", content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		batchTable := readTestBatchTable(t, filepath.Join(opts.Output, "test", content))
+		for _, classification := range readTestBatchTableBytes(t, filepath.Join(opts.Output, "test", content), batchTable, "CLASSIFICATION", pnts.FeatureTable.PointsLength) {
+			if classification != 1 && classification != 2 {
+				t.Errorf("Expected only the classifications 1 and 2 in %s, got %d", content, classification)
+			}
+		}
+		total += pnts.FeatureTable.PointsLength
+	}
+	if total != 200 || tiledPoints != 200 {
+		t.Errorf("Expected 200 tiled points, got %d in the tiles and %d in the written tiles", total, tiledPoints)
+	}
+}
@@ -0,0 +1,69 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/converters/offset_elevation_corrector"
+	"github.com/mfbonfigli/gocesiumtiler/lasread"
+	"github.com/mfbonfigli/gocesiumtiler/structs/geometry"
+	"github.com/mfbonfigli/gocesiumtiler/structs/point_loader"
+	"github.com/mfbonfigli/gocesiumtiler/textread"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Writes a LAS file with 20 points along the diagonal from (0,0,0) to (19,19,19), declaring their extent
+func writeTestDiagonalLasFile(t *testing.T) string {
+	rawPoints := make([][3]int32, 20)
+	for i := range rawPoints {
+		rawPoints[i] = [3]int32{int32(i), int32(i), int32(i)}
+	}
+	return writeTestLasFile(t, testLasHeader{scale: [3]float64{1, 1, 1}, extent: [6]float64{19, 0, 19, 0, 19, 0}}, rawPoints)
+}
+
+func TestPointsOutsideOfTheClipBoundsAreSkipped(t *testing.T) {
+	file := writeTestDiagonalLasFile(t)
+	defer os.RemoveAll(filepath.Dir(file))
+	loader := point_loader.NewRandomLoader(0)
+	lasFileLoader := lidario.NewLasFileLoader(&identityCoordinateConverter{}, nil, loader, nil, false)
+	lasFileLoader.ClipBounds = geometry.NewBoundingBox(5, 10, 0, 100, -100, 100)
+	lf, err := lasFileLoader.LoadLasFile(file, offset_elevation_corrector.NewOffsetElevationCorrector(0), 4326)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = lf.Close()
+	if lf.ClippedPoints != 14 {
+		t.Errorf("Expected 14 clipped points, got %d", lf.ClippedPoints)
+	}
+
+	// points on the boundaries are included, and the tree is sized on the remaining points
+	bounds := loader.GetBounds()
+	if bounds[0] != 5 || bounds[1] != 10 || bounds[4] != 5 || bounds[5] != 10 {
+		t.Errorf("Expected the points from 5 to 10 to be loaded, got bounds %v", bounds)
+	}
+}
+
+func TestClipBoundsNotIntersectingTheLasExtentAreRejected(t *testing.T) {
+	file := writeTestDiagonalLasFile(t)
+	defer os.RemoveAll(filepath.Dir(file))
+	lasFileLoader := lidario.NewLasFileLoader(&identityCoordinateConverter{}, nil, point_loader.NewRandomLoader(0), nil, false)
+	lasFileLoader.ClipBounds = geometry.NewBoundingBox(20, 30, 0, 100, -100, 100)
+	_, err := lasFileLoader.LoadLasFile(file, offset_elevation_corrector.NewOffsetElevationCorrector(0), 4326)
+	if err == nil || !strings.Contains(err.Error(), "do not intersect") {
+		t.Errorf("Expected an error for clip bounds not intersecting the file extent, got %v", err)
+	}
+}
+
+func TestTextPointsOutsideOfTheClipBoundsAreSkipped(t *testing.T) {
+	file := writeTestTextFile(t, "test.xyz", "0 0 0\n5 5 5\n10 10 10\n")
+	defer os.RemoveAll(filepath.Dir(file))
+	loader := point_loader.NewRandomLoader(0)
+	textFileLoader := textread.NewTextFileLoader(&identityCoordinateConverter{}, loader, nil, "")
+	textFileLoader.ClipBounds = geometry.NewBoundingBox(5, 10, 5, 10, 5, 10)
+	if err := textFileLoader.LoadTextFile(file, offset_elevation_corrector.NewOffsetElevationCorrector(0), 4326); err != nil {
+		t.Fatal(err)
+	}
+	if bounds := loader.GetBounds(); bounds[0] != 5 || bounds[1] != 10 {
+		t.Errorf("Expected the points from 5 to 10 to be loaded, got bounds %v", bounds)
+	}
+}
@@ -0,0 +1,141 @@
+package test
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEightBitColorDepthWritesTheHighByteOfTheColors(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	writeTileset(t, newTestColorDepthPoints(), opts)
+
+	pnts, err := io.ReadPntsFile(filepath.Join(opts.Output, "content.pnts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < pnts.FeatureTable.PointsLength; i++ {
+		if pnts.Colors[i*3] != 0x12 || pnts.Colors[i*3+1] != 0xab || pnts.Colors[i*3+2] != 0xff {
+			t.Errorf("Unexpected color %v", pnts.Colors[i*3:i*3+3])
+		}
+	}
+	if _, ok := readTestBatchTable(t, filepath.Join(opts.Output, "content.pnts"))["RGB16"]; ok {
+		t.Errorf("Expected no RGB16 property with 8 bit colors")
+	}
+}
+
+func TestSixteenBitColorDepthWritesTheFullDepthColors(t *testing.T) {
+	for _, normalsMaxDepth := range []int{0, 10} {
+		opts := newTestOptions(t)
+		opts.ColorDepth = 16
+		opts.NormalsMaxDepth = normalsMaxDepth
+		writeTileset(t, newTestColorDepthPoints(), opts)
+		file := filepath.Join(opts.Output, "content.pnts")
+
+		if err := io.ValidatePntsFile(file); err != nil {
+			t.Errorf("Expected a valid pnts layout, got %v", err)
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		batchTable := readTestBatchTable(t, file)
+		property := struct {
+			ByteOffset    int    `json:"byteOffset"`
+			ComponentType string `json:"componentType"`
+			Type          string `json:"type"`
+		}{}
+		if err := json.Unmarshal(batchTable["RGB16"], &property); err != nil {
+			t.Fatalf("Expected a RGB16 batch table property, got %v", err)
+		}
+		if property.ComponentType != "UNSIGNED_SHORT" || property.Type != "VEC3" {
+			t.Errorf("Expected UNSIGNED_SHORT VEC3 RGB16 property, got %s %s", property.ComponentType, property.Type)
+		}
+		featureTableLen := int(binary.LittleEndian.Uint32(content[12:16]))
+		featureTableBinaryLen := int(binary.LittleEndian.Uint32(content[16:20]))
+		batchTableLen := int(binary.LittleEndian.Uint32(content[20:24]))
+		start := 28 + featureTableLen + featureTableBinaryLen + batchTableLen + property.ByteOffset
+		if start%2 != 0 {
+			t.Errorf("Expected the RGB16 array to be aligned to 2 bytes in the file, got offset %d", start)
+		}
+		for i := 0; i < len(newTestColorDepthPoints()); i++ {
+			offset := start + i*6
+			r := binary.LittleEndian.Uint16(content[offset : offset+2])
+			g := binary.LittleEndian.Uint16(content[offset+2 : offset+4])
+			b := binary.LittleEndian.Uint16(content[offset+4 : offset+6])
+			if r != 0x1234 || g != 0xabcd || b != 0xffff {
+				t.Errorf("Unexpected 16 bit color %x %x %x", r, g, b)
+				break
+			}
+		}
+		_ = os.RemoveAll(opts.Output)
+	}
+}
+
+// Returns 100 points sharing the same 16 bit color
+func newTestColorDepthPoints() []*data.Point {
+	points := make([]*data.Point, 0)
+	for i := 0; i < 100; i++ {
+		points = append(points, data.NewPoint(float64(i), float64(i%7), float64(i%11), 0x1234, 0xabcd, 0xffff, 0, 0))
+	}
+	return points
+}
+
+// Reads the batch table json header of the given content.pnts file
+func readTestBatchTable(t *testing.T, file string) map[string]json.RawMessage {
+	pnts, err := io.ReadPntsFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	batchTable := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(pnts.BatchTable, &batchTable); err != nil {
+		t.Fatal(err)
+	}
+	return batchTable
+}
+
+func TestRgb565ColorsArePackedInTwoBytes(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	writeTileset(t, newTestColorDepthPoints(), opts)
+	rgbInfo, err := os.Stat(filepath.Join(opts.Output, "content.pnts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts565 := newTestOptions(t)
+	opts565.Rgb565Colors = true
+	defer os.RemoveAll(opts565.Output)
+	writeTileset(t, newTestColorDepthPoints(), opts565)
+	file := filepath.Join(opts565.Output, "content.pnts")
+	if err := io.ValidatePntsFile(file); err != nil {
+		t.Errorf("Expected a valid pnts layout, got %v", err)
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() >= rgbInfo.Size() {
+		t.Errorf("Expected RGB565 tile smaller than %d bytes, got %d", rgbInfo.Size(), info.Size())
+	}
+
+	pnts, err := io.ReadPntsFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pnts.FeatureTable.Rgb565 == nil || pnts.FeatureTable.Rgb != nil {
+		t.Fatalf("Expected RGB565 colors")
+	}
+	for i := 0; i < pnts.FeatureTable.PointsLength; i++ {
+		// 0x12, 0xab and 0xff truncated to 5, 6 and 5 bits and expanded back to 8 bits
+		if pnts.Colors[i*3] != 16 || pnts.Colors[i*3+1] != 170 || pnts.Colors[i*3+2] != 255 {
+			t.Errorf("Unexpected color %v", pnts.Colors[i*3:i*3+3])
+			break
+		}
+	}
+}
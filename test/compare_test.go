@@ -0,0 +1,125 @@
+package test
+
+import (
+	"encoding/json"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompareTilesetFoldersIgnoresPointOrder(t *testing.T) {
+	first := newTestOptions(t)
+	defer os.RemoveAll(first.Output)
+	second := newTestOptions(t)
+	defer os.RemoveAll(second.Output)
+
+	writeTileset(t, newTestPoints(), first)
+	writeTileset(t, newTestPoints(), second)
+
+	if err := io.CompareTilesetFolders(first.Output, second.Output, 1e-3); err != nil {
+		t.Errorf("Expected equivalent tilesets, got %v", err)
+	}
+}
+
+func TestCompareTilesetFoldersDetectsMovedPoints(t *testing.T) {
+	first := newTestOptions(t)
+	defer os.RemoveAll(first.Output)
+	second := newTestOptions(t)
+	defer os.RemoveAll(second.Output)
+
+	writeTileset(t, newTestPoints(), first)
+	moved := newTestPoints()
+	moved[0].X += 1
+	writeTileset(t, moved, second)
+
+	if err := io.CompareTilesetFolders(first.Output, second.Output, 1e-3); err == nil {
+		t.Errorf("Expected tilesets to differ")
+	}
+}
+
+func TestCompareTilesetFoldersDetectsMissingFiles(t *testing.T) {
+	first := newTestOptions(t)
+	defer os.RemoveAll(first.Output)
+	second := newTestOptions(t)
+	defer os.RemoveAll(second.Output)
+
+	writeTileset(t, newTestPoints(), first)
+	writeTileset(t, newTestPoints(), second)
+	if err := os.Remove(filepath.Join(second.Output, "tileset.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := io.CompareTilesetFolders(first.Output, second.Output, 1e-3); err == nil {
+		t.Errorf("Expected tilesets to differ")
+	}
+}
+
+func TestCompareTilesetFoldersMatchesPointsCloserThanTheTolerance(t *testing.T) {
+	first := newTestOptions(t)
+	defer os.RemoveAll(first.Output)
+	second := newTestOptions(t)
+	defer os.RemoveAll(second.Output)
+
+	// the jitter swaps the order of the x coordinates of the two points
+	writeTileset(t, append(newTestPoints(), data.NewPoint(4.5, 5, 5, 0, 0, 0, 0, 0), data.NewPoint(4.5002, 1, 5, 0, 0, 0, 0, 0)), first)
+	writeTileset(t, append(newTestPoints(), data.NewPoint(4.5003, 5, 5, 0, 0, 0, 0, 0), data.NewPoint(4.5001, 1, 5, 0, 0, 0, 0, 0)), second)
+
+	if err := io.CompareTilesetFolders(first.Output, second.Output, 1e-3); err != nil {
+		t.Errorf("Expected equivalent tilesets, got %v", err)
+	}
+	if err := io.CompareTilesetFolders(first.Output, second.Output, 1e-5); err == nil {
+		t.Errorf("Expected tilesets to differ with a tolerance smaller than the jitter")
+	}
+}
+
+func TestCompareTilesetFoldersDetectsDifferentTiles(t *testing.T) {
+	first := newTestOptions(t)
+	defer os.RemoveAll(first.Output)
+	first.MaxNumPointsPerNode = 50
+	first.RandomSeed = 1
+	writeTileset(t, newTestPoints(), first)
+
+	for name, change := range map[string]func(tile map[string]interface{}){
+		"geometric error": func(tile map[string]interface{}) { tile["geometricError"] = tile["geometricError"].(float64) + 1 },
+		"bounding volume": func(tile map[string]interface{}) {
+			volume := tile["boundingVolume"].(map[string]interface{})
+			for kind, values := range volume {
+				volume[kind] = append(values.([]interface{}), 0.0)
+			}
+		},
+		"children": func(tile map[string]interface{}) {
+			tile["children"] = append(tile["children"].([]interface{}), tile["children"].([]interface{})[0])
+		},
+	} {
+		second := newTestOptions(t)
+		defer os.RemoveAll(second.Output)
+		second.MaxNumPointsPerNode = 50
+		second.RandomSeed = 1
+		writeTileset(t, newTestPoints(), second)
+		if err := io.CompareTilesetFolders(first.Output, second.Output, 1e-3); err != nil {
+			t.Fatalf("Expected equivalent tilesets, got %v", err)
+		}
+		tilesetFile := filepath.Join(second.Output, "tileset.json")
+		content, err := os.ReadFile(tilesetFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tileset := make(map[string]interface{})
+		if err := json.Unmarshal(content, &tileset); err != nil {
+			t.Fatal(err)
+		}
+		change(tileset["root"].(map[string]interface{}))
+		if content, err = json.Marshal(tileset); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(tilesetFile, content, 0666); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := io.CompareTilesetFolders(first.Output, second.Output, 1e-3); err == nil {
+			t.Errorf("Expected tilesets with a different %s to differ", name)
+		}
+	}
+}
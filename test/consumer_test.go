@@ -0,0 +1,186 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"github.com/mfbonfigli/gocesiumtiler/structs/octree"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestColorsAreWrittenAsRgbByDefault(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	writeTileset(t, newTestPoints(), opts)
+
+	pnts, err := io.ReadPntsFile(filepath.Join(opts.Output, "content.pnts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pnts.FeatureTable.Rgb == nil || pnts.FeatureTable.Rgba != nil {
+		t.Errorf("Expected RGB colors")
+	}
+	if len(pnts.Colors) != pnts.FeatureTable.PointsLength*3 {
+		t.Errorf("Expected %d color components, got %d", pnts.FeatureTable.PointsLength*3, len(pnts.Colors))
+	}
+}
+
+func TestClassificationAlphaWritesRgba(t *testing.T) {
+	opts := newTestOptions(t)
+	opts.ClassificationAlpha = map[uint8]uint8{7: 64}
+	defer os.RemoveAll(opts.Output)
+	points := make([]*data.Point, 0)
+	for i := 0; i < 100; i++ {
+		points = append(points, data.NewPoint(float64(i), float64(i), float64(i), 10*257, 20*257, 30*257, 0, uint8(7*(i%2))))
+	}
+	writeTileset(t, points, opts)
+
+	pnts, err := io.ReadPntsFile(filepath.Join(opts.Output, "content.pnts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pnts.FeatureTable.Rgba == nil || pnts.FeatureTable.Rgb != nil {
+		t.Fatalf("Expected RGBA colors")
+	}
+	translucent := 0
+	for i := 0; i < pnts.FeatureTable.PointsLength; i++ {
+		if pnts.Colors[i*4] != 10 || pnts.Colors[i*4+1] != 20 || pnts.Colors[i*4+2] != 30 {
+			t.Errorf("Unexpected color %v", pnts.Colors[i*4:i*4+4])
+		}
+		if pnts.Colors[i*4+3] == 64 {
+			translucent++
+		} else if pnts.Colors[i*4+3] != 255 {
+			t.Errorf("Unexpected alpha %d", pnts.Colors[i*4+3])
+		}
+	}
+	if translucent != 50 {
+		t.Errorf("Expected 50 translucent points, got %d", translucent)
+	}
+}
+
+func TestFreeExportedItemsReleasesAllNodes(t *testing.T) {
+	reference := newTestOptions(t)
+	reference.MaxNumPointsPerNode = 50
+	defer os.RemoveAll(reference.Output)
+	tree := buildTree(t, newTestPoints(), reference)
+	exportTree(t, tree, reference)
+
+	opts := newTestOptions(t)
+	opts.MaxNumPointsPerNode = 50
+	opts.FreeExportedItems = true
+	defer os.RemoveAll(opts.Output)
+	exportTree(t, tree, opts)
+
+	if err := io.CompareTilesetFolders(reference.Output, opts.Output, 1e-3); err != nil {
+		t.Errorf("Expected same output when releasing items, got %v", err)
+	}
+	assertItemsReleased(t, &tree.RootNode)
+}
+
+func assertItemsReleased(t *testing.T, node *octree.OctNode) {
+	if node.Items != nil {
+		t.Errorf("Expected items of node at depth %d to be released", node.Depth)
+	}
+	for _, child := range node.Children {
+		if child != nil && child.Initialized {
+			assertItemsReleased(t, child)
+		}
+	}
+}
+
+func TestPositionPrecisionSnapsPositions(t *testing.T) {
+	opts := newTestOptions(t)
+	opts.PositionPrecision = 0.5
+	defer os.RemoveAll(opts.Output)
+	points := make([]*data.Point, 0)
+	for i := 0; i < 100; i++ {
+		points = append(points, data.NewPoint(float64(i)*0.37, float64(i)*0.11, float64(i)*0.53, 0, 0, 0, 0, 0))
+	}
+	writeTileset(t, points, opts)
+
+	pnts, err := io.ReadPntsFile(filepath.Join(opts.Output, "content.pnts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, position := range pnts.Positions {
+		relative := (position - pnts.FeatureTable.RtcCenter[i%3]) / opts.PositionPrecision
+		if math.Abs(relative-math.Round(relative)) > 1e-4 {
+			t.Errorf("Expected position relative to center multiple of %f, got %f", opts.PositionPrecision, relative)
+		}
+	}
+}
+
+func TestMaxTileBytesCapsContentSize(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.MaxNumPointsPerNode = 100
+	opts.MaxTileBytes = 1000
+	points := make([]*data.Point, 0)
+	for i := 0; i < 2000; i++ {
+		points = append(points, data.NewPoint(float64(i%37), float64(i%41), float64(i%43), 0, 0, 0, 0, 0))
+	}
+	writeTileset(t, points, opts)
+
+	tiles := 0
+	err := filepath.Walk(opts.Output, func(path string, info os.FileInfo, err error) error {
+		if err != nil || filepath.Base(path) != "content.pnts" {
+			return err
+		}
+		tiles++
+		if info.Size() > int64(opts.MaxTileBytes) {
+			t.Errorf("Expected %s to be at most %d bytes, got %d", path, opts.MaxTileBytes, info.Size())
+		}
+		pnts, err := io.ReadPntsFile(path)
+		if err != nil {
+			return err
+		}
+		if pnts.FeatureTable.PointsLength == 0 {
+			t.Errorf("Expected %s to contain points", path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tiles == 0 {
+		t.Errorf("Expected tiles to be written")
+	}
+}
+
+func TestMaxTileBytesSubsamplesPointsInSpatialOrder(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.MaxTileBytes = 2000
+	dropped := 0
+	opts.OnTileWritten = func(tile tiler.TileInfo) {
+		dropped += tile.DroppedPoints
+	}
+	points := make([]*data.Point, 0)
+	for i := 0; i < 1000; i++ {
+		points = append(points, data.NewPoint(float64(i%100), float64(i%7), float64(i%11), 0, 0, 0, 0, 0))
+	}
+	tree := buildTree(t, points, opts)
+	// the grid and Poisson-disk loaders store the points in cell order rather than in random order
+	sort.Slice(tree.RootNode.Items, func(i, j int) bool { return tree.RootNode.Items[i].X < tree.RootNode.Items[j].X })
+	exportTree(t, tree, opts)
+
+	pnts, err := io.ReadPntsFile(filepath.Join(opts.Output, "content.pnts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pnts.FeatureTable.PointsLength == 1000 || pnts.FeatureTable.PointsLength+dropped != 1000 {
+		t.Errorf("Expected the %d written points and the %d dropped ones to make up the 1000 points", pnts.FeatureTable.PointsLength, dropped)
+	}
+	minX, maxX := math.Inf(1), math.Inf(-1)
+	for p := 0; p < pnts.FeatureTable.PointsLength; p++ {
+		minX = math.Min(minX, pnts.Positions[3*p])
+		maxX = math.Max(maxX, pnts.Positions[3*p])
+	}
+	if minX > 1 || maxX < 98 {
+		t.Errorf("Expected the subsample to span the whole tile, got x from %f to %f", minX, maxX)
+	}
+}
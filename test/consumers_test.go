@@ -0,0 +1,149 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/structs/geometry"
+	"github.com/mfbonfigli/gocesiumtiler/structs/octree"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"github.com/mfbonfigli/gocesiumtiler/utils"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunConsumersWritesAllTheTiles(t *testing.T) {
+	single := newTestOptions(t)
+	defer os.RemoveAll(single.Output)
+	single.MaxNumPointsPerNode = 20
+	tree := buildTree(t, newTestPoints(), single)
+	exportTree(t, tree, single)
+	expected := make(map[string]int)
+	collectTileContents(t, single.Output, "tileset.json", 1, expected)
+
+	pool := utils.NewWorkerPool(2)
+	defer pool.Close()
+	for _, tc := range []struct {
+		name       string
+		numWorkers int
+		pool       *utils.WorkerPool
+	}{
+		{"one worker", 1, nil},
+		{"a goroutine per worker", 8, nil},
+		{"more workers than pool goroutines", 8, pool},
+	} {
+		opts := newTestOptions(t)
+		opts.MaxNumPointsPerNode = 20
+		if err := runTestConsumers(tree, opts, tc.numWorkers, tc.pool); err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		contents := make(map[string]int)
+		collectTileContents(t, opts.Output, "tileset.json", 1, contents)
+		if len(contents) != len(expected) {
+			t.Errorf("%s: expected %d tiles, got %d", tc.name, len(expected), len(contents))
+		}
+		for content, depth := range expected {
+			if contents[content] != depth {
+				t.Errorf("%s: expected tile %s at depth %d", tc.name, content, depth)
+			}
+		}
+		os.RemoveAll(opts.Output)
+	}
+}
+
+func TestRunConsumersStopsAllTheWorkersOnError(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.MaxNumPointsPerNode = 5
+	tree := buildTree(t, newTestPoints(), opts)
+
+	// the tiles cannot be written inside a regular file
+	output := filepath.Join(opts.Output, "file")
+	if err := os.WriteFile(output, []byte{}, 0666); err != nil {
+		t.Fatal(err)
+	}
+	opts.Output = output
+
+	done := make(chan error)
+	go func() { done <- runTestConsumers(tree, opts, 4, nil) }()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("Expected the error of the failed worker to be returned")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Expected the workers and the producer to quit after the first error")
+	}
+}
+
+// CoordinateConverter failing every conversion once the given number of conversions are in progress, so that as many
+// consumers fail together
+type failingCoordinateConverter struct {
+	identityCoordinateConverter
+	barrier sync.WaitGroup
+}
+
+func newFailingCoordinateConverter(failures int) *failingCoordinateConverter {
+	converter := &failingCoordinateConverter{}
+	converter.barrier.Add(failures)
+	return converter
+}
+
+func (c *failingCoordinateConverter) ConvertToWGS84Cartesian(coord geometry.Coordinate, sourceSrid int) (geometry.Coordinate, error) {
+	c.barrier.Done()
+	c.barrier.Wait()
+	return coord, errors.New("injected failure")
+}
+
+func TestRunConsumersReturnsTheErrorsOfEveryFailedTile(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.MaxNumPointsPerNode = 5
+	tree := buildTree(t, newTestPoints(), opts)
+	opts.CoordinateConverter = newFailingCoordinateConverter(3)
+
+	err := runTestConsumers(tree, opts, 3, nil)
+	tilingError, ok := err.(*io.TilingError)
+	if !ok {
+		t.Fatalf("Expected a TilingError, got %v", err)
+	}
+	if len(tilingError.Errors) != 3 {
+		t.Fatalf("Expected the errors of the 3 failed tiles, got %d", len(tilingError.Errors))
+	}
+	basePaths := make(map[string]bool)
+	for _, tileErr := range tilingError.Errors {
+		tileError, ok := tileErr.(*io.TileError)
+		if !ok {
+			t.Fatalf("Expected a TileError, got %v", tileErr)
+		}
+		if tileError.Err.Error() != "injected failure" {
+			t.Errorf("Unexpected tile error %v", tileError.Err)
+		}
+		if !strings.Contains(err.Error(), tileError.BasePath) {
+			t.Errorf("Expected the combined error to list the tile %s", tileError.BasePath)
+		}
+		basePaths[tileError.BasePath] = true
+	}
+	if len(basePaths) != 3 {
+		t.Errorf("Expected 3 distinct failed tiles, got %v", basePaths)
+	}
+}
+
+// Exports the given tree running the given number of consumers on the given pool, stopping the producer once they
+// quit as the tiler does
+func runTestConsumers(tree *octree.OctTree, opts *tiler.TilerOptions, numWorkers int, pool *utils.WorkerPool) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	workChannel := make(chan *io.WorkUnit)
+	var waitGroup sync.WaitGroup
+	waitGroup.Add(1)
+	go io.Produce(ctx, opts.Output, &tree.RootNode, opts, workChannel, &waitGroup, "", nil)
+	err := io.RunConsumers(context.Background(), numWorkers, workChannel, pool, opts.CoordinateConverter)
+	cancel()
+	waitGroup.Wait()
+	return err
+}
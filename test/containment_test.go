@@ -0,0 +1,54 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"path"
+	"testing"
+)
+
+func TestRegionsNotContainedAreDetected(t *testing.T) {
+	opts := newTestOptions(t)
+	opts.MaxNumPointsPerNode = 50
+	opts.CoordinateConverter = &shrinkingCoordinateConverter{}
+	writeTileset(t, newTestPoints(), opts)
+
+	if err := io.ValidateRegionContainment(path.Join(opts.Output, "tileset.json")); err == nil {
+		t.Errorf("Expected a containment error, got nil")
+	}
+}
+
+func TestEnforcedRegionsAreContained(t *testing.T) {
+	opts := newTestOptions(t)
+	opts.MaxNumPointsPerNode = 50
+	opts.CoordinateConverter = &shrinkingCoordinateConverter{}
+	opts.EnforceRegionContainment = true
+	writeTileset(t, newTestPoints(), opts)
+
+	if err := io.ValidateRegionContainment(path.Join(opts.Output, "tileset.json")); err != nil {
+		t.Errorf("Expected no containment error, got %v", err)
+	}
+}
+
+func TestEnforcedRegionsContainTheirChildren(t *testing.T) {
+	opts := newTestOptions(t)
+	opts.MaxNumPointsPerNode = 50
+	opts.CoordinateConverter = &shrinkingCoordinateConverter{}
+	tree := buildTree(t, newTestPoints(), opts)
+
+	regions, err := io.ComputeContainingRegions(&tree.RootNode, opts, opts.CoordinateConverter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for node, region := range regions {
+		for _, child := range node.Children {
+			if child == nil || child.GlobalChildrenCount == 0 {
+				continue
+			}
+			childRegion := regions[child]
+			if childRegion[0] < region[0] || childRegion[1] < region[1] || childRegion[2] > region[2] ||
+				childRegion[3] > region[3] || childRegion[4] < region[4] || childRegion[5] > region[5] {
+				t.Errorf("Expected region %v to contain child region %v", region, childRegion)
+			}
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/converters/offset_elevation_corrector"
+	"github.com/mfbonfigli/gocesiumtiler/lasread"
+	"github.com/mfbonfigli/gocesiumtiler/structs/point_loader"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Returns a GeoKey directory declaring the given EPSG code under the given key
+func testGeoKeys(key uint16, code uint16) []uint16 {
+	return []uint16{1, 1, 0, 1, key, 0, 1, code}
+}
+
+// Reads the given LAS file with the given input srid and no WKT projection, returning the srids the points were
+// converted from
+func loadTestLasFileSrids(t *testing.T, file string, srid int) (map[int]bool, error) {
+	converter := &recordingCoordinateConverter{srids: make(map[int]bool)}
+	lasFileLoader := lidario.NewLasFileLoader(converter, nil, point_loader.NewRandomLoader(0), nil, false)
+	lf, err := lasFileLoader.LoadLasFile(file, offset_elevation_corrector.NewOffsetElevationCorrector(0), srid)
+	if lf != nil {
+		lf.Close()
+	}
+	return converter.srids, err
+}
+
+func TestSridIsDetectedFromGeoKeys(t *testing.T) {
+	points := [][3]int32{{1, 2, 3}, {4, 5, 6}}
+	for _, tc := range []struct {
+		geoKeys  []uint16
+		expected int
+	}{
+		{testGeoKeys(3072, 32633), 32633},
+		{testGeoKeys(2048, 4269), 4269},
+		{[]uint16{1, 1, 0, 2, 2048, 0, 1, 4326, 3072, 0, 1, 32632}, 32632},
+	} {
+		file := writeTestLasFile(t, testLasHeader{scale: [3]float64{1, 1, 1}, geoKeys: tc.geoKeys}, points)
+		defer os.RemoveAll(filepath.Dir(file))
+		srids, err := loadTestLasFileSrids(t, file, lidario.DetectSrid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(srids) != 1 || !srids[tc.expected] {
+			t.Errorf("Expected the points to be converted from EPSG:%d, got %v", tc.expected, srids)
+		}
+	}
+}
+
+func TestSridIsDetectedFromWkt(t *testing.T) {
+	file := writeTestLasFile(t, testLasHeader{scale: [3]float64{1, 1, 1}, wkt: ogcUtm33nWkt}, [][3]int32{{1, 2, 3}})
+	defer os.RemoveAll(filepath.Dir(file))
+	srids, err := loadTestLasFileSrids(t, file, lidario.DetectSrid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(srids) != 1 || !srids[1000000] {
+		t.Errorf("Expected the points to be converted from the srid of the WKT, got %v", srids)
+	}
+}
+
+func TestSridDetectionAcceptsMatchingGeoKeysAndWkt(t *testing.T) {
+	header := testLasHeader{scale: [3]float64{1, 1, 1}, wkt: ogcUtm33nWkt, geoKeys: testGeoKeys(3072, 32633)}
+	file := writeTestLasFile(t, header, [][3]int32{{1, 2, 3}})
+	defer os.RemoveAll(filepath.Dir(file))
+	srids, err := loadTestLasFileSrids(t, file, lidario.DetectSrid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(srids) != 1 || !srids[32633] {
+		t.Errorf("Expected the points to be converted from EPSG:32633, got %v", srids)
+	}
+}
+
+func TestSridDetectionFailsOnConflictingGeoKeysAndWkt(t *testing.T) {
+	header := testLasHeader{scale: [3]float64{1, 1, 1}, wkt: ogcUtm33nWkt, geoKeys: testGeoKeys(3072, 32632)}
+	file := writeTestLasFile(t, header, [][3]int32{{1, 2, 3}})
+	defer os.RemoveAll(filepath.Dir(file))
+	_, err := loadTestLasFileSrids(t, file, lidario.DetectSrid)
+	if err == nil || !strings.Contains(err.Error(), "EPSG:32632") || !strings.Contains(err.Error(), "EPSG:32633") {
+		t.Errorf("Expected an error naming both EPSG codes, got %v", err)
+	}
+}
+
+func TestSridDetectionFailsWithoutGeoreference(t *testing.T) {
+	file := writeTestLasFile(t, testLasHeader{scale: [3]float64{1, 1, 1}}, [][3]int32{{1, 2, 3}})
+	defer os.RemoveAll(filepath.Dir(file))
+	if _, err := loadTestLasFileSrids(t, file, lidario.DetectSrid); err == nil || !strings.Contains(err.Error(), "test.las") {
+		t.Errorf("Expected an error naming the file, got %v", err)
+	}
+}
+
+func TestExplicitSridOverridesGeoKeys(t *testing.T) {
+	file := writeTestLasFile(t, testLasHeader{scale: [3]float64{1, 1, 1}, geoKeys: testGeoKeys(3072, 32633)}, [][3]int32{{1, 2, 3}})
+	defer os.RemoveAll(filepath.Dir(file))
+	srids, err := loadTestLasFileSrids(t, file, 32632)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(srids) != 1 || !srids[32632] {
+		t.Errorf("Expected the points to be converted from EPSG:32632, got %v", srids)
+	}
+}
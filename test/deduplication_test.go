@@ -0,0 +1,110 @@
+package test
+
+import (
+	"encoding/json"
+	"github.com/mfbonfigli/gocesiumtiler/app"
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"github.com/mfbonfigli/gocesiumtiler/structs/point_loader"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Adds the given points to a new DeduplicationLoader with the given tolerance and returns the kept points
+func deduplicateTestPoints(points []*data.Point, tolerance float64, geographic bool) ([]*data.Point, int64) {
+	deduplicationLoader := point_loader.NewDeduplicationLoader(point_loader.NewRandomLoader(0), tolerance, geographic)
+	for _, point := range points {
+		deduplicationLoader.AddElement(point)
+	}
+	deduplicationLoader.Initialize()
+	kept := make([]*data.Point, 0)
+	for {
+		point, shouldContinue := deduplicationLoader.GetNext()
+		if point != nil {
+			kept = append(kept, point)
+		}
+		if !shouldContinue {
+			break
+		}
+	}
+	return kept, deduplicationLoader.GetDroppedCount()
+}
+
+func TestDeduplicationDropsThePointsWithinTheTolerance(t *testing.T) {
+	// a 10x10 grid spaced 1 unit apart, scanned three times: exactly, 0.05 units off and 0.3 units off
+	points := make([]*data.Point, 0)
+	for _, offset := range []float64{0, 0.05, 0.3} {
+		for i := 0; i < 100; i++ {
+			points = append(points, data.NewPoint(float64(i%10)+offset, float64(i/10), offset, 0, 0, 0, 0, 0))
+		}
+	}
+	kept, dropped := deduplicateTestPoints(points, 0.1, false)
+	if len(kept) != 200 || dropped != 100 {
+		t.Errorf("Expected 200 kept and 100 dropped points, got %d and %d", len(kept), dropped)
+	}
+	for _, point := range kept {
+		if point.Z == 0.05 {
+			t.Errorf("Expected the point %f, %f, %f dropped as a duplicate", point.X, point.Y, point.Z)
+		}
+	}
+
+	kept, dropped = deduplicateTestPoints(points, 0, false)
+	if len(kept) != 300 || dropped != 0 {
+		t.Errorf("Expected all the points kept with a zero tolerance, got %d kept and %d dropped", len(kept), dropped)
+	}
+}
+
+func TestDeduplicationToleranceIsInMetersForGeographicCoordinates(t *testing.T) {
+	// points 5 centimeters apart along the longitude at 60 degrees of latitude, and along the latitude
+	points := []*data.Point{
+		data.NewPoint(10, 60, 0, 0, 0, 0, 0, 0),
+		data.NewPoint(10+0.05/(111320*0.5), 60, 0, 0, 0, 0, 0, 0),
+		data.NewPoint(10, 60+0.05/111320, 0, 0, 0, 0, 0, 0),
+	}
+	if _, dropped := deduplicateTestPoints(points, 0.1, true); dropped != 2 {
+		t.Errorf("Expected 2 points dropped within 10 centimeters, got %d", dropped)
+	}
+	if _, dropped := deduplicateTestPoints(points, 0.01, true); dropped != 0 {
+		t.Errorf("Expected no point dropped within 1 centimeter, got %d", dropped)
+	}
+}
+
+func TestDuplicatePointsAreNotTiledAndAreReported(t *testing.T) {
+	// 300 distinct points, then 100 of them again
+	rawPoints := make([][3]int32, 400)
+	for i := range rawPoints {
+		rawPoints[i] = [3]int32{int32(i % 300 % 20), int32(i % 300 / 20), int32(i % 300 % 7)}
+	}
+	file := writeTestLasFile(t, testLasHeader{scale: [3]float64{1, 1, 1}}, rawPoints)
+	defer os.RemoveAll(filepath.Dir(file))
+
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.Input = file
+	opts.MaxNumPointsPerNode = 20
+	opts.DeduplicateTolerance = 0.01
+	opts.WriteStatistics = true
+	var tiledPoints int
+	opts.OnTileWritten = func(tile tiler.TileInfo) {
+		tiledPoints += tile.PointCount
+	}
+	if err := app.RunTiler(opts); err != nil {
+		t.Fatal(err)
+	}
+	if tiledPoints != 300 {
+		t.Errorf("Expected 300 tiled points, got %d", tiledPoints)
+	}
+
+	content, err := os.ReadFile(filepath.Join(opts.Output, "test", "statistics.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var statistics point_loader.Statistics
+	if err := json.Unmarshal(content, &statistics); err != nil {
+		t.Fatal(err)
+	}
+	if statistics.TotalPoints != 300 || statistics.DuplicatePoints != 100 {
+		t.Errorf("Expected 300 points and 100 duplicates in the statistics, got %d and %d", statistics.TotalPoints, statistics.DuplicatePoints)
+	}
+}
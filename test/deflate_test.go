@@ -0,0 +1,103 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDeflatedTilesAreSmallerAndDecodeToTheSamePoints(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.MaxNumPointsPerNode = 50
+	tree := buildTree(t, newTestPoints(), opts)
+	exportTree(t, tree, opts)
+	deflatedOpts := newTestOptions(t)
+	defer os.RemoveAll(deflatedOpts.Output)
+	deflatedOpts.MaxNumPointsPerNode = 50
+	deflatedOpts.DeflateBuffers = true
+	exportTree(t, tree, deflatedOpts)
+
+	var size, deflatedSize int64
+	deflatedTiles := 0
+	err := filepath.Walk(opts.Output, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(path) != ".pnts" {
+			return err
+		}
+		deflatedPath := filepath.Join(deflatedOpts.Output, strings.TrimPrefix(path, opts.Output))
+		if err := io.ValidatePntsFile(deflatedPath); err != nil {
+			return err
+		}
+		deflatedInfo, err := os.Stat(deflatedPath)
+		if err != nil {
+			return err
+		}
+		if deflatedInfo.Size() > info.Size() {
+			t.Errorf("Expected %s not larger than %d bytes, got %d", deflatedPath, info.Size(), deflatedInfo.Size())
+		}
+		size += info.Size()
+		deflatedSize += deflatedInfo.Size()
+
+		expected, err := io.ReadPntsFile(path)
+		if err != nil {
+			return err
+		}
+		actual, err := io.ReadPntsFile(deflatedPath)
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(expected.Positions, actual.Positions) || !reflect.DeepEqual(expected.Colors, actual.Colors) {
+			t.Errorf("Expected the same points in %s and %s", path, deflatedPath)
+		}
+		if _, ok := readTestBatchTable(t, deflatedPath)["extensions"]; ok {
+			deflatedTiles++
+		}
+		if len(actual.BatchTableBinary) == 0 || !reflect.DeepEqual(expected.BatchTableBinary, actual.BatchTableBinary) {
+			t.Errorf("Expected the same batch table binary body in %s and %s", path, deflatedPath)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deflatedTiles == 0 || deflatedSize >= size {
+		t.Errorf("Expected deflated tiles smaller than %d bytes, got %d bytes in %d deflated tiles", size, deflatedSize, deflatedTiles)
+	}
+
+	tileset, err := io.ReadTilesetFile(filepath.Join(deflatedOpts.Output, "tileset.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tileset.ExtensionsRequired, []string{"GOCESIUMTILER_deflate_buffers"}) {
+		t.Errorf("Expected the deflate extension to be required, got %v", tileset.ExtensionsRequired)
+	}
+	tileset, err = io.ReadTilesetFile(filepath.Join(opts.Output, "tileset.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tileset.ExtensionsUsed != nil || tileset.ExtensionsRequired != nil {
+		t.Errorf("Expected no extensions by default, got %v and %v", tileset.ExtensionsUsed, tileset.ExtensionsRequired)
+	}
+}
+
+func TestDeflateKeepsTheBodiesItWouldNotMakeSmaller(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	tree := buildTree(t, newTestPoints(), opts)
+	points := newTestPoints()[:2]
+	content, err := io.EncodePnts(&tree.RootNode, points, opts, opts.CoordinateConverter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.DeflateBuffers = true
+	deflated, err := io.EncodePnts(&tree.RootNode, points, opts, opts.CoordinateConverter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(content, deflated) {
+		t.Errorf("Expected the content of 2 points to be written uncompressed, got %d bytes rather than %d", len(deflated), len(content))
+	}
+}
@@ -0,0 +1,104 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDepthFoldersStoreTilesByDepth(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.MaxNumPointsPerNode = 20
+	opts.DepthFolders = true
+	writeTileset(t, newTestPoints(), opts)
+
+	contents := make(map[string]int)
+	collectTileContents(t, opts.Output, "tileset.json", 1, contents)
+	if len(contents) < 3 {
+		t.Fatalf("Expected a tileset with at least 3 tiles, got %d", len(contents))
+	}
+	for content, depth := range contents {
+		folder := path.Dir(content)
+		if depth == 1 {
+			if folder != "." {
+				t.Errorf("Expected the root content in the output folder, got %s", content)
+			}
+			continue
+		}
+		expected := "L" + strconv.Itoa(depth)
+		if parts := strings.Split(folder, "/"); len(parts) != 2 || parts[0] != expected || len(parts[1]) != depth-1 {
+			t.Errorf("Expected the content of a tile at depth %d in %s/<%d octants>, got %s", depth, expected, depth-1, content)
+		}
+	}
+
+	// every written tile is reachable from the root tileset
+	written, err := filepath.Glob(filepath.Join(opts.Output, "L*", "*", "content.pnts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(written) != len(contents)-1 {
+		t.Errorf("Expected %d tiles in depth folders, got %d", len(contents)-1, len(written))
+	}
+	if _, err := io.ValidateGeometricErrors(filepath.Join(opts.Output, "tileset.json")); err != nil {
+		t.Errorf("Unexpected error validating the tileset: %v", err)
+	}
+}
+
+func TestDepthFoldersKeepTheTileHierarchy(t *testing.T) {
+	nested := newTestOptions(t)
+	defer os.RemoveAll(nested.Output)
+	nested.MaxNumPointsPerNode = 20
+	tree := buildTree(t, newTestPoints(), nested)
+	exportTree(t, tree, nested)
+	byDepth := newTestOptions(t)
+	defer os.RemoveAll(byDepth.Output)
+	byDepth.MaxNumPointsPerNode = 20
+	byDepth.DepthFolders = true
+	exportTree(t, tree, byDepth)
+
+	nestedContents := make(map[string]int)
+	collectTileContents(t, nested.Output, "tileset.json", 1, nestedContents)
+	depthContents := make(map[string]int)
+	collectTileContents(t, byDepth.Output, "tileset.json", 1, depthContents)
+	for content, depth := range nestedContents {
+		folder := path.Dir(content)
+		expected := content
+		if folder != "." {
+			expected = path.Join("L"+strconv.Itoa(depth), strings.Replace(folder, "/", "", -1), path.Base(content))
+		}
+		if depthContents[expected] != depth {
+			t.Errorf("Expected tile %s stored as %s at depth %d", content, expected, depth)
+		}
+	}
+	if len(nestedContents) != len(depthContents) {
+		t.Errorf("Expected %d tiles, got %d", len(nestedContents), len(depthContents))
+	}
+}
+
+// Follows the content urls of the given tileset.json, relative to the given folder, collecting the path of every
+// referenced content file together with the depth of its tile, the root having the given depth. Url query strings
+// are ignored
+func collectTileContents(t *testing.T, folder string, tilesetFile string, depth int, contents map[string]int) {
+	tileset, err := io.ReadTilesetFile(filepath.Join(folder, tilesetFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := path.Dir(tilesetFile)
+	contents[path.Join(dir, strings.Split(tileset.Root.Content.Url, "?")[0])] = depth
+	for _, child := range tileset.Root.Children {
+		url := path.Join(dir, strings.Split(child.Content.Url, "?")[0])
+		if _, err := os.Stat(filepath.Join(folder, url)); err != nil {
+			t.Fatalf("Unresolved child url %s: %v", url, err)
+		}
+		if strings.HasSuffix(url, ".json") {
+			collectTileContents(t, folder, url, depth+1, contents)
+		} else {
+			contents[url] = depth + 1
+		}
+	}
+}
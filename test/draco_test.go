@@ -0,0 +1,136 @@
+package test
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// Fake Draco encoder storing the attributes uncompressed one after the other, with ids in reverse order
+type recordingDracoEncoder struct {
+	sync.Mutex
+	pointNo   int
+	semantics map[string]bool
+}
+
+func (encoder *recordingDracoEncoder) Encode(pointNo int, attributes []tiler.DracoAttribute) ([]byte, []int, error) {
+	encoder.Lock()
+	defer encoder.Unlock()
+	encoder.pointNo += pointNo
+	buffer := make([]byte, 0)
+	ids := make([]int, len(attributes))
+	for i, attribute := range attributes {
+		encoder.semantics[attribute.Semantic] = true
+		ids[i] = len(attributes) - 1 - i
+		for _, value := range attribute.Floats {
+			var bytes [4]byte
+			binary.LittleEndian.PutUint32(bytes[:], math.Float32bits(value))
+			buffer = append(buffer, bytes[:]...)
+		}
+		buffer = append(buffer, attribute.Bytes...)
+	}
+	return buffer, ids, nil
+}
+
+func TestDracoCompressedTilesDeclareTheExtension(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.MaxNumPointsPerNode = 20
+	opts.ComputeNormals = true
+	opts.DracoCompression = true
+	encoder := &recordingDracoEncoder{semantics: make(map[string]bool)}
+	opts.DracoEncoder = encoder
+	points := newTestPoints()
+	writeTileset(t, points, opts)
+
+	if encoder.pointNo != len(points) {
+		t.Errorf("Expected %d points Draco encoded, got %d", len(points), encoder.pointNo)
+	}
+	if len(encoder.semantics) != 3 || !encoder.semantics["POSITION"] || !encoder.semantics["NORMAL"] || !encoder.semantics["RGB"] {
+		t.Errorf("Expected POSITION, NORMAL and RGB to be Draco encoded, got %v", encoder.semantics)
+	}
+	tileset, err := io.ReadTilesetFile(filepath.Join(opts.Output, "tileset.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tileset.ExtensionsUsed) != 1 || tileset.ExtensionsUsed[0] != "3DTILES_draco_point_compression" ||
+		len(tileset.ExtensionsRequired) != 1 || tileset.ExtensionsRequired[0] != "3DTILES_draco_point_compression" {
+		t.Errorf("Expected the Draco extension to be used and required, got %v and %v", tileset.ExtensionsUsed, tileset.ExtensionsRequired)
+	}
+
+	contents := make(map[string]int)
+	collectTileContents(t, opts.Output, "tileset.json", 1, contents)
+	for content := range contents {
+		file := filepath.Join(opts.Output, content)
+		if err := io.ValidatePntsFile(file); err != nil {
+			t.Errorf("Expected a valid pnts layout for %s, got %v", content, err)
+		}
+		if _, err := io.ReadPntsFile(file); err == nil {
+			t.Errorf("Expected Draco compressed %s not to be decoded", content)
+		}
+
+		featureTable, binaryLength := readTestFeatureTable(t, file)
+		extension := struct {
+			Properties map[string]int `json:"properties"`
+			ByteOffset int            `json:"byteOffset"`
+			ByteLength int            `json:"byteLength"`
+		}{}
+		if err := json.Unmarshal(featureTable.Extensions["3DTILES_draco_point_compression"], &extension); err != nil {
+			t.Fatalf("Expected the Draco extension in the feature table of %s: %v", content, err)
+		}
+		pointNo := featureTable.PointsLength
+		if extension.ByteOffset != 0 || extension.ByteLength != pointNo*27 || extension.ByteLength > binaryLength {
+			t.Errorf("Expected a Draco buffer of %d bytes at offset 0, got %d bytes at %d", pointNo*27, extension.ByteLength, extension.ByteOffset)
+		}
+		if extension.Properties["POSITION"] != 2 || extension.Properties["NORMAL"] != 1 || extension.Properties["RGB"] != 0 {
+			t.Errorf("Expected the ids returned by the encoder, got %v", extension.Properties)
+		}
+		if featureTable.Position == nil || featureTable.Position.ByteOffset != 0 || featureTable.Rgb == nil || featureTable.Normal == nil {
+			t.Errorf("Expected the compressed semantics to be declared with byte offset 0")
+		}
+	}
+}
+
+func TestDracoCompressionWithoutEncoderWritesUncompressedTiles(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.DracoCompression = true
+	points := newTestPoints()
+	writeTileset(t, points, opts)
+
+	tileset, err := io.ReadTilesetFile(filepath.Join(opts.Output, "tileset.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tileset.ExtensionsUsed) != 0 || len(tileset.ExtensionsRequired) != 0 {
+		t.Errorf("Expected no extensions, got %v and %v", tileset.ExtensionsUsed, tileset.ExtensionsRequired)
+	}
+	pnts, err := io.ReadPntsFile(filepath.Join(opts.Output, "content.pnts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pnts.Positions) != 3*len(points) || pnts.FeatureTable.Extensions != nil {
+		t.Errorf("Expected %d uncompressed points, got %d", len(points), len(pnts.Positions)/3)
+	}
+}
+
+// Reads the feature table json header of the given content.pnts file, returning it with the feature table binary
+// body length
+func readTestFeatureTable(t *testing.T, file string) (io.FeatureTable, int) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	featureTableLength := int(binary.LittleEndian.Uint32(content[12:16]))
+	featureTable := io.FeatureTable{}
+	if err := json.Unmarshal(content[28:28+featureTableLength], &featureTable); err != nil {
+		t.Fatal(err)
+	}
+	return featureTable, int(binary.LittleEndian.Uint32(content[16:20]))
+}
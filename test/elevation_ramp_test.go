@@ -0,0 +1,68 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestElevationRampIsInterpolated(t *testing.T) {
+	ramp, err := tiler.ParseElevationRamp("0:000000, 0.5:ff0000,1:#ff00ff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		z        float64
+		expected [3]uint16
+	}{
+		{-5, [3]uint16{0, 0, 0}}, {0, [3]uint16{0, 0, 0}}, {25, [3]uint16{32768, 0, 0}}, {50, [3]uint16{65535, 0, 0}},
+		{75, [3]uint16{65535, 0, 32768}}, {100, [3]uint16{65535, 0, 65535}}, {150, [3]uint16{65535, 0, 65535}},
+	} {
+		R, G, B := ramp.Color(c.z, 0, 100)
+		if actual := [3]uint16{R, G, B}; actual != c.expected {
+			t.Errorf("Expected the color %v at the elevation %f, got %v", c.expected, c.z, actual)
+		}
+	}
+	if evenly, err := tiler.ParseElevationRamp("0000ff,00ff00,ff0000"); err != nil || len(evenly) != 3 || evenly[1].Position != 0.5 {
+		t.Errorf("Expected three evenly spaced stops, got %v, %v", evenly, err)
+	}
+	if none, err := tiler.ParseElevationRamp(""); err != nil || none != nil {
+		t.Errorf("Expected no ramp, got %v, %v", none, err)
+	}
+	for _, invalid := range []string{"ff0000", "0.5:ff0000,0.2:00ff00", "2:ff0000,00ff00", "ff0000,x"} {
+		if _, err := tiler.ParseElevationRamp(invalid); err == nil {
+			t.Errorf("Expected an error parsing the elevation ramp %s", invalid)
+		}
+	}
+}
+
+func TestElevationRampSpansTheWholeCloud(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.MaxNumPointsPerNode = 20
+	opts.ElevationRamp = tiler.DefaultElevationRamp()
+	writeTileset(t, newTestPoints(), opts)
+
+	// the points of the test cloud have elevations from 0 to 12
+	contents := make(map[string]int)
+	collectTileContents(t, opts.Output, "tileset.json", 1, contents)
+	if len(contents) < 3 {
+		t.Fatalf("Expected a tileset with at least 3 tiles, got %d", len(contents))
+	}
+	for content := range contents {
+		pnts, err := io.ReadPntsFile(filepath.Join(opts.Output, content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < pnts.FeatureTable.PointsLength; i++ {
+			R, G, B := opts.ElevationRamp.Color(math.Round(pnts.Positions[i*3+2]), 0, 12)
+			expected := [3]uint8{uint8(R >> 8), uint8(G >> 8), uint8(B >> 8)}
+			if actual := [3]uint8{pnts.Colors[i*3], pnts.Colors[i*3+1], pnts.Colors[i*3+2]}; actual != expected {
+				t.Fatalf("Expected the color %v at the elevation %f of %s, got %v", expected, pnts.Positions[i*3+2], content, actual)
+			}
+		}
+	}
+}
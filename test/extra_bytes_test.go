@@ -0,0 +1,154 @@
+package test
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"github.com/mfbonfigli/gocesiumtiler/app"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Attribute stored in the Extra Bytes VLR and in the extra bytes of the synthetic LAS files generated by the tests
+type testExtraBytes struct {
+	name     string
+	dataType uint8
+	size     int
+	scale    float64 // if not zero, stored as the scale of the attribute
+	encode   func(b []byte, i int)
+}
+
+// Writes a LAS 1.2 file with point format 0 storing the given raw X, Y, Z values followed by the given extra bytes
+// attributes, declared in an Extra Bytes VLR. Returns the path of the written file.
+func writeTestExtraBytesLasFile(t *testing.T, attributes []testExtraBytes, rawPoints [][3]int32) string {
+	const headerSize = 227
+	const vlrHeaderSize = 54
+	file := writeTestLasFile(t, testLasHeader{scale: [3]float64{1, 1, 1}}, rawPoints)
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vlr := make([]byte, vlrHeaderSize+192*len(attributes))
+	copy(vlr[2:18], "LASF_Spec")
+	binary.LittleEndian.PutUint16(vlr[18:20], 4)
+	binary.LittleEndian.PutUint16(vlr[20:22], uint16(192*len(attributes)))
+	extraLength := 0
+	for i, attribute := range attributes {
+		descriptor := vlr[vlrHeaderSize+192*i : vlrHeaderSize+192*(i+1)]
+		descriptor[2] = attribute.dataType
+		copy(descriptor[4:36], attribute.name)
+		if attribute.scale != 0 {
+			descriptor[3] = 0x08
+			binary.LittleEndian.PutUint64(descriptor[112:120], math.Float64bits(attribute.scale))
+		}
+		extraLength += attribute.size
+	}
+
+	pointsOffset := int(binary.LittleEndian.Uint32(content[96:100]))
+	b := append(append([]byte{}, content[:pointsOffset]...), vlr...)
+	binary.LittleEndian.PutUint32(b[96:100], uint32(len(b)))
+	binary.LittleEndian.PutUint32(b[100:104], binary.LittleEndian.Uint32(b[100:104])+1)
+	binary.LittleEndian.PutUint16(b[105:107], uint16(20+extraLength))
+	for i := range rawPoints {
+		b = append(b, content[pointsOffset+20*i:pointsOffset+20*(i+1)]...)
+		for _, attribute := range attributes {
+			extra := make([]byte, attribute.size)
+			attribute.encode(extra, i)
+			b = append(b, extra...)
+		}
+	}
+	if err := os.WriteFile(file, b, 0666); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestExtraBytesAreWrittenAsBatchTableProperties(t *testing.T) {
+	rawPoints := make([][3]int32, 0)
+	for i := 0; i < 100; i++ {
+		rawPoints = append(rawPoints, [3]int32{int32(i % 10), int32(i / 10), int32(i)})
+	}
+	file := writeTestExtraBytesLasFile(t, []testExtraBytes{
+		{name: "Reflectance", dataType: 9, size: 4, encode: func(b []byte, i int) {
+			binary.LittleEndian.PutUint32(b, math.Float32bits(float32(i)/2))
+		}},
+		{name: "Echo", dataType: 1, size: 1, encode: func(b []byte, i int) { b[0] = uint8(i % 4) }},
+		{name: "Amplitude", dataType: 4, size: 2, scale: 0.1, encode: func(b []byte, i int) {
+			binary.LittleEndian.PutUint16(b, uint16(int16(-i)))
+		}},
+		{name: "Pair", dataType: 13, size: 4, encode: func(b []byte, i int) {}},
+	}, rawPoints)
+	defer os.RemoveAll(filepath.Dir(file))
+
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.Input = file
+	opts.MaxNumPointsPerNode = 30
+	opts.ExtraAttributes = tiler.ParseExtraAttributes("Reflectance, Echo,Amplitude,Pair,Missing")
+	if err := app.RunTiler(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	contents := make(map[string]int)
+	collectTileContents(t, filepath.Join(opts.Output, "test"), "tileset.json", 1, contents)
+	total := 0
+	for content := range contents {
+		file := filepath.Join(opts.Output, "test", content)
+		if err := io.ValidatePntsFile(file); err != nil {
+			t.Fatalf("Invalid %s: %v", content, err)
+		}
+		pnts, err := io.ReadPntsFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		batchTable := readTestBatchTable(t, file)
+		for name, componentType := range map[string]string{"Reflectance": "FLOAT", "Echo": "UNSIGNED_BYTE", "Amplitude": "DOUBLE"} {
+			property := struct {
+				ComponentType string `json:"componentType"`
+				Type          string `json:"type"`
+			}{}
+			if err := json.Unmarshal(batchTable[name], &property); err != nil {
+				t.Fatalf("Expected a %s batch table property in %s: %v", name, content, err)
+			}
+			if property.ComponentType != componentType || property.Type != "SCALAR" {
+				t.Errorf("Expected a SCALAR %s %s property, got %s %s", componentType, name, property.Type, property.ComponentType)
+			}
+		}
+		for _, name := range []string{"Pair", "Missing"} {
+			if _, ok := batchTable[name]; ok {
+				t.Errorf("Expected no %s batch table property in %s", name, content)
+			}
+		}
+
+		pointNo := pnts.FeatureTable.PointsLength
+		reflectances := readTestBatchTableBytes(t, file, batchTable, "Reflectance", 4*pointNo)
+		echoes := readTestBatchTableBytes(t, file, batchTable, "Echo", pointNo)
+		amplitudes := readTestBatchTableBytes(t, file, batchTable, "Amplitude", 8*pointNo)
+		for p := 0; p < pointNo; p++ {
+			i := int(math.Round(pnts.Positions[3*p+2]))
+			reflectance := math.Float32frombits(binary.LittleEndian.Uint32(reflectances[4*p:]))
+			amplitude := math.Float64frombits(binary.LittleEndian.Uint64(amplitudes[8*p:]))
+			if reflectance != float32(i)/2 || echoes[p] != uint8(i%4) || math.Abs(amplitude+float64(i)*0.1) > 1e-9 {
+				t.Errorf("Unexpected attributes %f, %d, %f of the point %d", reflectance, echoes[p], amplitude, i)
+			}
+		}
+		total += pointNo
+	}
+	if total != 100 {
+		t.Errorf("Expected 100 tiled points, got %d", total)
+	}
+}
+
+func TestExtraAttributesAreParsed(t *testing.T) {
+	attributes := tiler.ParseExtraAttributes(" Reflectance,,Deviation ")
+	if len(attributes) != 2 || attributes[0].Name != "Reflectance" || attributes[1].Name != "Deviation" || attributes[0].ComponentType != "" {
+		t.Errorf("Unexpected extra attributes %v", attributes)
+	}
+	if attributes := tiler.ParseExtraAttributes(""); len(attributes) != 0 {
+		t.Errorf("Expected no extra attributes, got %v", attributes)
+	}
+}
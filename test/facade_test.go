@@ -0,0 +1,96 @@
+package test
+
+import (
+	"context"
+	"github.com/mfbonfigli/gocesiumtiler/app"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTilerRunWritesTheTilesetOfTheInputFile(t *testing.T) {
+	file := writeTestLasFile(t, testLasHeader{scale: [3]float64{1, 1, 1}}, newTestPlyPoints())
+	defer os.RemoveAll(filepath.Dir(file))
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.Srid = 4326
+	opts.Output = ""
+	tiler := app.New(opts)
+
+	// each run writes in its own output folder, created if missing
+	for _, outputDir := range []string{filepath.Join(filepath.Dir(file), "first"), filepath.Join(filepath.Dir(file), "second", "nested")} {
+		if _, err := tiler.Run(context.Background(), file, outputDir); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(filepath.Join(outputDir, "test", "tileset.json")); err != nil {
+			t.Errorf("Expected the tileset written in %s, got %v", outputDir, err)
+		}
+	}
+	if opts.Input != "" || opts.Output != "" || opts.Srid != 4326 {
+		t.Errorf("Expected the options not to be changed, got input %s, output %s and srid %d", opts.Input, opts.Output, opts.Srid)
+	}
+}
+
+func TestTilerRunStopsWhenTheContextIsDone(t *testing.T) {
+	file := writeTestLasFile(t, testLasHeader{scale: [3]float64{1, 1, 1}}, newTestPlyPoints())
+	defer os.RemoveAll(filepath.Dir(file))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := app.New(newTestOptions(t)).Run(ctx, file, filepath.Join(filepath.Dir(file), "output")); err != context.Canceled {
+		t.Errorf("Expected the context error, got %v", err)
+	}
+}
+
+func TestTilerRunReturnsTheStatisticsOfTheWrittenTiles(t *testing.T) {
+	file := writeTestLasFile(t, testLasHeader{scale: [3]float64{1, 1, 1}}, newTestPlyPoints())
+	defer os.RemoveAll(filepath.Dir(file))
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.MaxNumPointsPerNode = 20
+	written := 0
+	opts.OnTileWritten = func(tile tiler.TileInfo) { written++ }
+	outputDir := filepath.Join(filepath.Dir(file), "output")
+	result, err := app.New(opts).Run(context.Background(), file, outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents := make(map[string]int)
+	collectTileContents(t, filepath.Join(outputDir, "test"), "tileset.json", 1, contents)
+	files := 0
+	maxDepth := 0
+	for content, depth := range contents {
+		if strings.HasSuffix(content, "content.pnts") {
+			files++
+		}
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+	}
+	if result.TilesWritten != files || written != files {
+		t.Errorf("Expected %d written tiles, got %d and %d notified", files, result.TilesWritten, written)
+	}
+	if result.MaxDepth != maxDepth || maxDepth < 2 {
+		t.Errorf("Expected a maximum depth of %d, got %d", maxDepth, result.MaxDepth)
+	}
+	if result.TotalPoints != int64(len(newTestPlyPoints())) {
+		t.Errorf("Expected %d points, got %d", len(newTestPlyPoints()), result.TotalPoints)
+	}
+	tileset, err := io.ReadTilesetFile(filepath.Join(outputDir, "test", "tileset.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RootGeometricError != tileset.Root.GeometricError {
+		t.Errorf("Expected the root geometric error %f, got %f", tileset.Root.GeometricError, result.RootGeometricError)
+	}
+	if !reflect.DeepEqual(result.BoundingRegion, tileset.Root.BoundingVolume.Region) {
+		t.Errorf("Expected the root region %v, got %v", tileset.Root.BoundingVolume.Region, result.BoundingRegion)
+	}
+	if result.Elapsed <= 0 {
+		t.Errorf("Expected a positive elapsed time, got %v", result.Elapsed)
+	}
+}
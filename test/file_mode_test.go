@@ -0,0 +1,94 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"github.com/mfbonfigli/gocesiumtiler/utils"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestTilesAreWrittenWithTheDefaultModes(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.MaxNumPointsPerNode = 100
+	writeTileset(t, newTestPoints(), opts)
+	assertTileModes(t, opts.Output, applyUmask(t, tiler.DefaultFileMode), applyUmask(t, tiler.DefaultDirMode))
+}
+
+func TestTilesAreWrittenWithTheGivenModes(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.MaxNumPointsPerNode = 100
+	opts.FileMode = 0600
+	opts.DirMode = 0700
+	writeTileset(t, newTestPoints(), opts)
+	assertTileModes(t, opts.Output, applyUmask(t, 0600), applyUmask(t, 0700))
+}
+
+func TestAtomicTilesGetExactlyTheFileMode(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.MaxNumPointsPerNode = 100
+	opts.AtomicWrites = true
+	opts.FileMode = 0640
+	writeTileset(t, newTestPoints(), opts)
+	assertTileModes(t, opts.Output, 0640, applyUmask(t, tiler.DefaultDirMode))
+}
+
+func TestParseFileMode(t *testing.T) {
+	for value, expected := range map[string]os.FileMode{"0644": 0644, "755": 0755, "0": 0} {
+		if mode, err := utils.ParseFileMode(value); err != nil || mode != expected {
+			t.Errorf("Expected %s to be parsed as %o, got %o, %v", value, expected, mode, err)
+		}
+	}
+	for _, invalid := range []string{"", "0648", "rw-r--r--", "1777"} {
+		if _, err := utils.ParseFileMode(invalid); err == nil {
+			t.Errorf("Expected %s to be rejected", invalid)
+		}
+	}
+}
+
+// Returns the given permission bits as masked by the umask of the process, found creating a folder
+func applyUmask(t *testing.T, mode os.FileMode) os.FileMode {
+	folder := filepath.Join(t.TempDir(), "umask")
+	if err := os.Mkdir(folder, 0777); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mode & info.Mode().Perm()
+}
+
+// Fails the test unless all the files in the given folder, recursively, have the given permission bits, and all the
+// folders below it the given ones
+func assertTileModes(t *testing.T, folder string, fileMode os.FileMode, dirMode os.FileMode) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Permission bits are not supported on Windows")
+	}
+	files := 0
+	err := filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
+		if err != nil || path == folder {
+			return err
+		}
+		expected := fileMode
+		if info.IsDir() {
+			expected = dirMode
+		} else {
+			files++
+		}
+		if info.Mode().Perm() != expected {
+			t.Errorf("Expected %s to have mode %o, got %o", path, expected, info.Mode().Perm())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files < 3 {
+		t.Errorf("Expected at least 3 written files, got %d", files)
+	}
+}
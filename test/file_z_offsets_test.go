@@ -0,0 +1,101 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/app"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Writes a synthetic LAS file storing the given raw X, Y, Z values at the given path, relative to the given folder
+func writeTestLasFileAt(t *testing.T, folder string, name string, rawPoints [][3]int32) {
+	file := writeTestLasFile(t, testLasHeader{scale: [3]float64{1, 1, 1}}, rawPoints)
+	defer os.RemoveAll(filepath.Dir(file))
+	target := filepath.Join(folder, name)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(target, content, 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileZOffsetsShiftThePointsOfEachFile(t *testing.T) {
+	input, err := os.MkdirTemp("", "gocesiumtiler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(input)
+	// the points of b.las are shifted by 100 along x to tell them apart in the merged tileset
+	for i, name := range []string{"a.las", "b.las"} {
+		rawPoints := make([][3]int32, 0)
+		for j := 0; j < 100; j++ {
+			rawPoints = append(rawPoints, [3]int32{int32(100*i + j%10), int32(j / 10), int32(j)})
+		}
+		writeTestLasFileAt(t, input, name, rawPoints)
+	}
+
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.Input = input
+	opts.FolderProcessing = true
+	opts.MergeFiles = true
+	opts.MaxNumPointsPerNode = 30
+	opts.ZOffset = 1
+	opts.FileZOffsets = map[string]float64{"a.las": 10, "b.las": -5}
+	if err := app.RunTiler(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	contents := make(map[string]int)
+	collectTileContents(t, opts.Output, "tileset.json", 1, contents)
+	counts := make(map[float64]int)
+	for content := range contents {
+		pnts, err := io.ReadPntsFile(filepath.Join(opts.Output, content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for p := 0; p < pnts.FeatureTable.PointsLength; p++ {
+			x := math.Round(pnts.Positions[3*p])
+			offset := 11.0
+			if x >= 100 {
+				offset = -4
+			}
+			j := math.Mod(x, 100) + 10*math.Round(pnts.Positions[3*p+1])
+			if z := pnts.Positions[3*p+2]; math.Abs(z-j-offset) > 1e-3 {
+				t.Errorf("Expected the point %.0f at x %.0f at height %f, got %f", j, x, j+offset, z)
+			}
+			counts[offset]++
+		}
+	}
+	if counts[11] != 100 || counts[-4] != 100 {
+		t.Errorf("Expected 100 tiled points of each file, got %v", counts)
+	}
+}
+
+func TestFileZOffsetsOfDuplicateFileNamesAreAnError(t *testing.T) {
+	input, err := os.MkdirTemp("", "gocesiumtiler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(input)
+	writeTestLasFileAt(t, input, filepath.Join("north", "a.las"), [][3]int32{{0, 0, 0}, {1, 1, 1}})
+	writeTestLasFileAt(t, input, filepath.Join("south", "a.las"), [][3]int32{{0, 0, 0}, {1, 1, 1}})
+
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.Input = input
+	opts.FolderProcessing = true
+	opts.Recursive = true
+	opts.FileZOffsets = map[string]float64{"a.las": 10}
+	if err := app.RunTiler(opts); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("Expected an ambiguous file z offset error, got %v", err)
+	}
+}
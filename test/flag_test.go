@@ -18,7 +18,7 @@ func TestInputFlagIsParsed(t *testing.T) {
 	}
 }
 
-func TestiFlagIsParsed(t *testing.T) {
+func TestIFlagIsParsed(t *testing.T) {
 	expected := "/home/user/file.las"
 	os.Args = []string{"gocesiumtiler", "-i=" + expected}
 	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
@@ -38,7 +38,7 @@ func TestOutputFlagIsParsed(t *testing.T) {
 	}
 }
 
-func TestoFlagIsParsed(t *testing.T) {
+func TestOFlagIsParsed(t *testing.T) {
 	expected := "/home/user/output"
 	os.Args = []string{"gocesiumtiler", "-o=" + expected}
 	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
@@ -57,7 +57,7 @@ func TestSridFlagIsParsed(t *testing.T) {
 		t.Errorf("Expected Srid = %d, got %d", expected, *flags.Srid)
 	}
 }
-func TesteFlagIsParsed(t *testing.T) {
+func TestEFlagIsParsed(t *testing.T) {
 	expected := 32633
 	os.Args = []string{"gocesiumtiler", "-e=" + strconv.Itoa(expected)}
 	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
@@ -87,7 +87,7 @@ func TestZOffsetFlagIsParsed(t *testing.T) {
 	}
 }
 
-func TestzFlagIsParsed(t *testing.T) {
+func TestZFlagIsParsed(t *testing.T) {
 	expected := 10.0
 	os.Args = []string{"gocesiumtiler", "-z=10"}
 	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
@@ -116,7 +116,7 @@ func TestMaxPtsFlagIsParsed(t *testing.T) {
 		t.Errorf("Expected MaxNumPts = %d, got %d", expected, *flags.MaxNumPts)
 	}
 }
-func TestmFlagIsParsed(t *testing.T) {
+func TestMFlagIsParsed(t *testing.T) {
 	expected := 2000
 	os.Args = []string{"gocesiumtiler", "-m=2000"}
 	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
@@ -146,7 +146,7 @@ func TestGeoidFlagIsParsed(t *testing.T) {
 	}
 }
 
-func TestgFlagIsParsed(t *testing.T) {
+func TestGFlagIsParsed(t *testing.T) {
 	expected := true
 	os.Args = []string{"gocesiumtiler", "-g"}
 	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
@@ -176,7 +176,7 @@ func TestFolderProcessingFlagIsParsed(t *testing.T) {
 	}
 }
 
-func TestfFlagIsParsed(t *testing.T) {
+func TestFFlagIsParsed(t *testing.T) {
 	expected := true
 	os.Args = []string{"gocesiumtiler", "-f"}
 	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
@@ -206,7 +206,7 @@ func TestRecursiveFolderProcessingFlagIsParsed(t *testing.T) {
 	}
 }
 
-func TestrFlagIsParsed(t *testing.T) {
+func TestRFlagIsParsed(t *testing.T) {
 	expected := true
 	os.Args = []string{"gocesiumtiler", "-r"}
 	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
@@ -236,7 +236,7 @@ func TestSilentFlagIsParsed(t *testing.T) {
 	}
 }
 
-func TestsFlagIsParsed(t *testing.T) {
+func TestSFlagIsParsed(t *testing.T) {
 	expected := true
 	os.Args = []string{"gocesiumtiler", "-silent"}
 	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
@@ -266,7 +266,7 @@ func TestLogTimestampFlagIsParsed(t *testing.T) {
 	}
 }
 
-func TesttFlagIsParsed(t *testing.T) {
+func TestTFlagIsParsed(t *testing.T) {
 	expected := true
 	os.Args = []string{"gocesiumtiler", "-timestamp"}
 	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
@@ -316,7 +316,7 @@ func TestHelpFlagIsParsed(t *testing.T) {
 	}
 }
 
-func TesthFlagIsParsed(t *testing.T) {
+func TestHFlagIsParsed(t *testing.T) {
 	expected := true
 	os.Args = []string{"gocesiumtiler", "-h"}
 	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
@@ -335,3 +335,1254 @@ func TestHelpDefaultIsFalse(t *testing.T) {
 		t.Errorf("Expected Help = %t, got %t", expected, *flags.Help)
 	}
 }
+
+func TestBoundsSigmasFlagIsParsed(t *testing.T) {
+	expected := 3.5
+	os.Args = []string{"gocesiumtiler", "-boundssigmas=3.5"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.BoundsSigmas != expected {
+		t.Errorf("Expected BoundsSigmas = %f, got %f", expected, *flags.BoundsSigmas)
+	}
+}
+
+func TestBoundsSigmasDefaultIsZero(t *testing.T) {
+	expected := 0.0
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.BoundsSigmas != expected {
+		t.Errorf("Expected BoundsSigmas = %f, got %f", expected, *flags.BoundsSigmas)
+	}
+}
+
+func TestSubtreeFlagIsParsed(t *testing.T) {
+	expected := "0/3/2"
+	os.Args = []string{"gocesiumtiler", "-subtree=" + expected}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Subtree != expected {
+		t.Errorf("Expected Subtree = %s, got %s", expected, *flags.Subtree)
+	}
+}
+
+func TestSubtreeDefaultIsEmpty(t *testing.T) {
+	expected := ""
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Subtree != expected {
+		t.Errorf("Expected Subtree = %s, got %s", expected, *flags.Subtree)
+	}
+}
+
+func TestAlphaFlagIsParsed(t *testing.T) {
+	expected := "7:64,18:0"
+	os.Args = []string{"gocesiumtiler", "-alpha=" + expected}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.ClassificationAlpha != expected {
+		t.Errorf("Expected ClassificationAlpha = %s, got %s", expected, *flags.ClassificationAlpha)
+	}
+}
+
+func TestAlphaDefaultIsEmpty(t *testing.T) {
+	expected := ""
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.ClassificationAlpha != expected {
+		t.Errorf("Expected ClassificationAlpha = %s, got %s", expected, *flags.ClassificationAlpha)
+	}
+}
+
+func TestParseClassificationValues(t *testing.T) {
+	values, err := utils.ParseClassificationValues("7:64, 18:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 2 || values[7] != 64 || values[18] != 0 {
+		t.Errorf("Expected map[7:64 18:0], got %v", values)
+	}
+	if _, err := utils.ParseClassificationValues("7:256"); err == nil {
+		t.Errorf("Expected error for value out of range")
+	}
+}
+
+func TestLowMemFlagIsParsed(t *testing.T) {
+	expected := true
+	os.Args = []string{"gocesiumtiler", "-lowmem"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if !*flags.LowMemory {
+		t.Errorf("Expected LowMemory = %t, got %t", expected, *flags.LowMemory)
+	}
+}
+
+func TestLowMemDefaultIsFalse(t *testing.T) {
+	expected := false
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.LowMemory {
+		t.Errorf("Expected LowMemory = %t, got %t", expected, *flags.LowMemory)
+	}
+}
+
+func TestSubtreeLevelsFlagIsParsed(t *testing.T) {
+	expected := 3
+	os.Args = []string{"gocesiumtiler", "-subtreelevels=" + strconv.Itoa(expected)}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.SubtreeLevels != expected {
+		t.Errorf("Expected SubtreeLevels = %d, got %d", expected, *flags.SubtreeLevels)
+	}
+}
+
+func TestSubtreeLevelsDefaultIsZero(t *testing.T) {
+	expected := 0
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.SubtreeLevels != expected {
+		t.Errorf("Expected SubtreeLevels = %d, got %d", expected, *flags.SubtreeLevels)
+	}
+}
+
+func TestNormalsDepthFlagIsParsed(t *testing.T) {
+	expected := 2
+	os.Args = []string{"gocesiumtiler", "-normalsdepth=" + strconv.Itoa(expected)}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.NormalsMaxDepth != expected {
+		t.Errorf("Expected NormalsMaxDepth = %d, got %d", expected, *flags.NormalsMaxDepth)
+	}
+}
+
+func TestNormalsDepthDefaultIsZero(t *testing.T) {
+	expected := 0
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.NormalsMaxDepth != expected {
+		t.Errorf("Expected NormalsMaxDepth = %d, got %d", expected, *flags.NormalsMaxDepth)
+	}
+}
+
+func TestFileZOffsetsFlagIsParsed(t *testing.T) {
+	expected := "a.las:1.5,b.las:-0.3"
+	os.Args = []string{"gocesiumtiler", "-filezoffsets=" + expected}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.FileZOffsets != expected {
+		t.Errorf("Expected FileZOffsets = %s, got %s", expected, *flags.FileZOffsets)
+	}
+}
+
+func TestParseFileOffsets(t *testing.T) {
+	offsets, err := utils.ParseFileOffsets("a.las:1.5, b:c.las:-0.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(offsets) != 2 || offsets["a.las"] != 1.5 || offsets["b:c.las"] != -0.3 {
+		t.Errorf("Expected map[a.las:1.5 b:c.las:-0.3], got %v", offsets)
+	}
+	if _, err := utils.ParseFileOffsets("a.las"); err == nil {
+		t.Errorf("Expected error for missing offset")
+	}
+}
+
+func TestPrecisionFlagIsParsed(t *testing.T) {
+	expected := 0.01
+	os.Args = []string{"gocesiumtiler", "-precision=0.01"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Precision != expected {
+		t.Errorf("Expected Precision = %f, got %f", expected, *flags.Precision)
+	}
+}
+
+func TestPrecisionDefaultIsZero(t *testing.T) {
+	expected := 0.0
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Precision != expected {
+		t.Errorf("Expected Precision = %f, got %f", expected, *flags.Precision)
+	}
+}
+
+func TestStatsFlagIsParsed(t *testing.T) {
+	expected := true
+	os.Args = []string{"gocesiumtiler", "-stats"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if !*flags.Statistics {
+		t.Errorf("Expected Statistics = %t, got %t", expected, *flags.Statistics)
+	}
+}
+
+func TestStatsDefaultIsFalse(t *testing.T) {
+	expected := false
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Statistics {
+		t.Errorf("Expected Statistics = %t, got %t", expected, *flags.Statistics)
+	}
+}
+
+func TestContainmentFlagIsParsed(t *testing.T) {
+	expected := true
+	os.Args = []string{"gocesiumtiler", "-containment"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if !*flags.Containment {
+		t.Errorf("Expected Containment = %t, got %t", expected, *flags.Containment)
+	}
+}
+
+func TestContainmentDefaultIsFalse(t *testing.T) {
+	expected := false
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Containment {
+		t.Errorf("Expected Containment = %t, got %t", expected, *flags.Containment)
+	}
+}
+
+func TestConcurrencyFlagIsParsed(t *testing.T) {
+	expected := 4
+	os.Args = []string{"gocesiumtiler", "-concurrency", "4"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Concurrency != expected {
+		t.Errorf("Expected Concurrency = %d, got %d", expected, *flags.Concurrency)
+	}
+}
+
+func TestConcurrencyDefaultIsZero(t *testing.T) {
+	expected := 0
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Concurrency != expected {
+		t.Errorf("Expected Concurrency = %d, got %d", expected, *flags.Concurrency)
+	}
+}
+
+func TestGroupsFlagIsParsed(t *testing.T) {
+	expected := "buildings:6:0.25;ground:2"
+	os.Args = []string{"gocesiumtiler", "-groups", expected}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Groups != expected {
+		t.Errorf("Expected Groups = %s, got %s", expected, *flags.Groups)
+	}
+}
+
+func TestGroupsDefaultIsEmpty(t *testing.T) {
+	expected := ""
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Groups != expected {
+		t.Errorf("Expected Groups = %s, got %s", expected, *flags.Groups)
+	}
+}
+
+func TestWktFlagIsParsed(t *testing.T) {
+	expected := true
+	os.Args = []string{"gocesiumtiler", "-wkt"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if !*flags.Wkt {
+		t.Errorf("Expected Wkt = %t, got %t", expected, *flags.Wkt)
+	}
+}
+
+func TestWktDefaultIsFalse(t *testing.T) {
+	expected := false
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Wkt {
+		t.Errorf("Expected Wkt = %t, got %t", expected, *flags.Wkt)
+	}
+}
+
+func TestMaxBytesFlagIsParsed(t *testing.T) {
+	expected := 2000000
+	os.Args = []string{"gocesiumtiler", "-maxbytes", "2000000"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.MaxTileBytes != expected {
+		t.Errorf("Expected MaxTileBytes = %d, got %d", expected, *flags.MaxTileBytes)
+	}
+}
+
+func TestMaxBytesDefaultIsZero(t *testing.T) {
+	expected := 0
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.MaxTileBytes != expected {
+		t.Errorf("Expected MaxTileBytes = %d, got %d", expected, *flags.MaxTileBytes)
+	}
+}
+
+func TestGeoidGridsFlagIsParsed(t *testing.T) {
+	expected := "a.gtx,b.gtx"
+	os.Args = []string{"gocesiumtiler", "-geoidgrids", "a.gtx,b.gtx"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.GeoidGrids != expected {
+		t.Errorf("Expected GeoidGrids = %s, got %s", expected, *flags.GeoidGrids)
+	}
+}
+
+func TestGeoidGridsDefaultIsEmpty(t *testing.T) {
+	expected := ""
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.GeoidGrids != expected {
+		t.Errorf("Expected GeoidGrids = %s, got %s", expected, *flags.GeoidGrids)
+	}
+}
+
+func TestMonotonicFlagIsParsed(t *testing.T) {
+	expected := true
+	os.Args = []string{"gocesiumtiler", "-monotonic"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Monotonic != expected {
+		t.Errorf("Expected Monotonic = %t, got %t", expected, *flags.Monotonic)
+	}
+}
+
+func TestMonotonicDefaultIsFalse(t *testing.T) {
+	expected := false
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Monotonic != expected {
+		t.Errorf("Expected Monotonic = %t, got %t", expected, *flags.Monotonic)
+	}
+}
+
+func TestColumnsFlagIsParsed(t *testing.T) {
+	expected := "x,y,z,intensity"
+	os.Args = []string{"gocesiumtiler", "-columns", "x,y,z,intensity"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Columns != expected {
+		t.Errorf("Expected Columns = %s, got %s", expected, *flags.Columns)
+	}
+}
+
+func TestColumnsDefaultIsXyz(t *testing.T) {
+	expected := "x,y,z"
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Columns != expected {
+		t.Errorf("Expected Columns = %s, got %s", expected, *flags.Columns)
+	}
+}
+
+func TestDelimiterFlagIsParsed(t *testing.T) {
+	expected := ";"
+	os.Args = []string{"gocesiumtiler", "-delimiter", ";"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Delimiter != expected {
+		t.Errorf("Expected Delimiter = %s, got %s", expected, *flags.Delimiter)
+	}
+}
+
+func TestDelimiterDefaultIsEmpty(t *testing.T) {
+	expected := ""
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Delimiter != expected {
+		t.Errorf("Expected Delimiter = %s, got %s", expected, *flags.Delimiter)
+	}
+}
+
+func TestColorDepthFlagIsParsed(t *testing.T) {
+	expected := 16
+	os.Args = []string{"gocesiumtiler", "-colordepth", "16"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.ColorDepth != expected {
+		t.Errorf("Expected ColorDepth = %d, got %d", expected, *flags.ColorDepth)
+	}
+}
+
+func TestColorDepthDefaultIsEight(t *testing.T) {
+	expected := 8
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.ColorDepth != expected {
+		t.Errorf("Expected ColorDepth = %d, got %d", expected, *flags.ColorDepth)
+	}
+}
+
+func TestRgb565FlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-rgb565"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if !*flags.Rgb565 {
+		t.Errorf("Expected Rgb565 = true, got false")
+	}
+}
+
+func TestRgb565DefaultIsFalse(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Rgb565 {
+		t.Errorf("Expected Rgb565 = false, got true")
+	}
+}
+
+func TestReadBufferFlagIsParsed(t *testing.T) {
+	expected := 16
+	os.Args = []string{"gocesiumtiler", "-readbuffer", "16"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.ReadBuffer != expected {
+		t.Errorf("Expected ReadBuffer = %d, got %d", expected, *flags.ReadBuffer)
+	}
+}
+
+func TestReadBufferDefaultIs64(t *testing.T) {
+	expected := 64
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.ReadBuffer != expected {
+		t.Errorf("Expected ReadBuffer = %d, got %d", expected, *flags.ReadBuffer)
+	}
+}
+
+func TestMergeFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-merge"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if !*flags.Merge {
+		t.Errorf("Expected Merge = true, got false")
+	}
+}
+
+func TestMergeDefaultIsFalse(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Merge {
+		t.Errorf("Expected Merge = false, got true")
+	}
+}
+
+func TestDepthFoldersFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-depthfolders"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if !*flags.DepthFolders {
+		t.Errorf("Expected DepthFolders = true, got false")
+	}
+}
+
+func TestDepthFoldersDefaultIsFalse(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.DepthFolders {
+		t.Errorf("Expected DepthFolders = false, got true")
+	}
+}
+
+func TestNormalsFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-normals"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if !*flags.Normals {
+		t.Errorf("Expected Normals = true, got false")
+	}
+}
+
+func TestNormalsDefaultIsFalse(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Normals {
+		t.Errorf("Expected Normals = false, got true")
+	}
+}
+
+func TestNormalNeighborsFlagIsParsed(t *testing.T) {
+	expected := 32
+	os.Args = []string{"gocesiumtiler", "-normalneighbors=" + strconv.Itoa(expected)}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.NormalNeighbors != expected {
+		t.Errorf("Expected NormalNeighbors = %d, got %d", expected, *flags.NormalNeighbors)
+	}
+}
+
+func TestNormalNeighborsDefaultIs16(t *testing.T) {
+	expected := 16
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.NormalNeighbors != expected {
+		t.Errorf("Expected NormalNeighbors = %d, got %d", expected, *flags.NormalNeighbors)
+	}
+}
+
+func TestQuantizeFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-quantize"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if !*flags.Quantize {
+		t.Errorf("Expected Quantize = true, got false")
+	}
+}
+
+func TestQuantizeDefaultIsFalse(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Quantize {
+		t.Errorf("Expected Quantize = false, got true")
+	}
+}
+
+func TestNormIntensityFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-normintensity"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if !*flags.NormalizeIntensity {
+		t.Errorf("Expected NormalizeIntensity = true, got false")
+	}
+}
+
+func TestNormIntensityDefaultIsFalse(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.NormalizeIntensity {
+		t.Errorf("Expected NormalizeIntensity = false, got true")
+	}
+}
+
+func TestParseFileSrids(t *testing.T) {
+	srids, err := utils.ParseFileSrids("a.las:32632, b:c.las:32633")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(srids) != 2 || srids["a.las"] != 32632 || srids["b:c.las"] != 32633 {
+		t.Errorf("Expected map[a.las:32632 b:c.las:32633], got %v", srids)
+	}
+	if _, err := utils.ParseFileSrids("a.las:1.5"); err == nil {
+		t.Errorf("Expected error for invalid srid")
+	}
+}
+
+func TestFileSridsFlagIsParsed(t *testing.T) {
+	expected := "a.las:32632"
+	os.Args = []string{"gocesiumtiler", "-filesrids=" + expected}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.FileSrids != expected {
+		t.Errorf("Expected FileSrids = %s, got %s", expected, *flags.FileSrids)
+	}
+}
+
+func TestDropOriginFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-droporigin"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if !*flags.DropOrigin {
+		t.Errorf("Expected DropOrigin = true, got false")
+	}
+}
+
+func TestDropOriginDefaultIsFalse(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.DropOrigin {
+		t.Errorf("Expected DropOrigin = false, got true")
+	}
+}
+
+func TestDeflateFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-deflate"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if !*flags.Deflate {
+		t.Errorf("Expected Deflate = true, got false")
+	}
+}
+
+func TestDeflateDefaultIsFalse(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Deflate {
+		t.Errorf("Expected Deflate = false, got true")
+	}
+}
+
+func TestSphereFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-sphere=all"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Sphere != "all" {
+		t.Errorf("Expected Sphere = all, got %s", *flags.Sphere)
+	}
+}
+
+func TestSphereDefaultIsEmpty(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Sphere != "" {
+		t.Errorf("Expected empty Sphere, got %s", *flags.Sphere)
+	}
+}
+
+func TestAtomicFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-atomic"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if !*flags.Atomic {
+		t.Errorf("Expected Atomic = true, got false")
+	}
+}
+
+func TestAtomicDefaultIsFalse(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Atomic {
+		t.Errorf("Expected Atomic = false, got true")
+	}
+}
+
+func TestTempDirFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-tempdir=/mnt/scratch"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.TempDir != "/mnt/scratch" {
+		t.Errorf("Expected TempDir = /mnt/scratch, got %s", *flags.TempDir)
+	}
+}
+
+func TestTempDirDefaultIsEmpty(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.TempDir != "" {
+		t.Errorf("Expected empty TempDir, got %s", *flags.TempDir)
+	}
+}
+
+func TestSamplingFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-sampling=poisson"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Sampling != "poisson" {
+		t.Errorf("Expected Sampling = poisson, got %s", *flags.Sampling)
+	}
+}
+
+func TestSamplingDefaultIsEmpty(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Sampling != "" {
+		t.Errorf("Expected empty Sampling, got %s", *flags.Sampling)
+	}
+}
+
+func TestSeedFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-seed=42"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Seed != 42 {
+		t.Errorf("Expected Seed = 42, got %d", *flags.Seed)
+	}
+}
+
+func TestSeedDefaultIsZero(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Seed != 0 {
+		t.Errorf("Expected Seed = 0, got %d", *flags.Seed)
+	}
+}
+
+func TestGeometricErrorDiagonalFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-gediagonal=0.05"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.DiagonalFraction != 0.05 {
+		t.Errorf("Expected DiagonalFraction = 0.05, got %f", *flags.DiagonalFraction)
+	}
+}
+
+func TestGeometricErrorDiagonalDefaultIsZero(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.DiagonalFraction != 0 {
+		t.Errorf("Expected DiagonalFraction = 0, got %f", *flags.DiagonalFraction)
+	}
+}
+
+func TestMasterFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-master"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if !*flags.Master {
+		t.Errorf("Expected Master = true, got false")
+	}
+}
+
+func TestMasterDefaultIsFalse(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Master {
+		t.Errorf("Expected Master = false, got true")
+	}
+}
+
+func TestFormatFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-format=glb"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Format != "glb" {
+		t.Errorf("Expected Format = glb, got %s", *flags.Format)
+	}
+}
+
+func TestFormatDefaultIsPnts(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Format != "pnts" {
+		t.Errorf("Expected Format = pnts, got %s", *flags.Format)
+	}
+}
+
+func TestLegendFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-legend"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if !*flags.Legend {
+		t.Errorf("Expected Legend = true, got false")
+	}
+}
+
+func TestLegendDefaultIsFalse(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Legend {
+		t.Errorf("Expected Legend = false, got true")
+	}
+}
+
+func TestMaxLevelsFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-maxlevels=4"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.MaxLevels != 4 {
+		t.Errorf("Expected MaxLevels = 4, got %d", *flags.MaxLevels)
+	}
+}
+
+func TestMaxLevelsDefaultIsZero(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.MaxLevels != 0 {
+		t.Errorf("Expected MaxLevels = 0, got %d", *flags.MaxLevels)
+	}
+}
+
+func TestUrlQueryFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-urlquery=v=20240101"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.UrlQuery != "v=20240101" {
+		t.Errorf("Expected UrlQuery = v=20240101, got %s", *flags.UrlQuery)
+	}
+}
+
+func TestUrlQueryDefaultIsEmpty(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.UrlQuery != "" {
+		t.Errorf("Expected UrlQuery to be empty, got %s", *flags.UrlQuery)
+	}
+}
+
+func TestOctantOrderFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-octantorder=0,2,1,3,4,6,5,7"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.OctantOrder != "0,2,1,3,4,6,5,7" {
+		t.Errorf("Expected OctantOrder = 0,2,1,3,4,6,5,7, got %s", *flags.OctantOrder)
+	}
+}
+
+func TestOctantOrderDefaultIsEmpty(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.OctantOrder != "" {
+		t.Errorf("Expected OctantOrder to be empty, got %s", *flags.OctantOrder)
+	}
+}
+
+func TestWritersFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-writers=3"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Writers != 3 {
+		t.Errorf("Expected Writers = 3, got %d", *flags.Writers)
+	}
+}
+
+func TestWritersDefaultIsZero(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Writers != 0 {
+		t.Errorf("Expected Writers = 0, got %d", *flags.Writers)
+	}
+}
+
+func TestOverlapCellFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-overlapcell=2.5"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.OverlapCell != 2.5 {
+		t.Errorf("Expected OverlapCell = 2.5, got %f", *flags.OverlapCell)
+	}
+}
+
+func TestOverlapCellDefaultIsZero(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.OverlapCell != 0 {
+		t.Errorf("Expected OverlapCell = 0, got %f", *flags.OverlapCell)
+	}
+}
+
+func TestGraftFlagsAreParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-graft=world/tileset.json", "-slot=2/0"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Graft != "world/tileset.json" || *flags.Slot != "2/0" {
+		t.Errorf("Expected Graft = world/tileset.json and Slot = 2/0, got %s and %s", *flags.Graft, *flags.Slot)
+	}
+}
+
+func TestGraftFlagsDefaultToEmpty(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Graft != "" || *flags.Slot != "" {
+		t.Errorf("Expected Graft and Slot to be empty, got %s and %s", *flags.Graft, *flags.Slot)
+	}
+}
+
+func TestModeFlagsAreParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-filemode=0600", "-dirmode=0700"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.FileMode != "0600" || *flags.DirMode != "0700" {
+		t.Errorf("Expected FileMode = 0600 and DirMode = 0700, got %s and %s", *flags.FileMode, *flags.DirMode)
+	}
+}
+
+func TestModeFlagsDefaultToOwnerWritable(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.FileMode != "0644" || *flags.DirMode != "0755" {
+		t.Errorf("Expected FileMode = 0644 and DirMode = 0755, got %s and %s", *flags.FileMode, *flags.DirMode)
+	}
+}
+
+func TestMinHeightFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-minheight=0.5"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.MinHeight != 0.5 {
+		t.Errorf("Expected MinHeight = 0.5, got %f", *flags.MinHeight)
+	}
+}
+
+func TestMinHeightFlagDefaultsToOneCentimeter(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.MinHeight != 0.01 {
+		t.Errorf("Expected MinHeight = 0.01, got %f", *flags.MinHeight)
+	}
+}
+
+func TestAutoFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-auto"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if !*flags.Auto {
+		t.Errorf("Expected Auto = true, got false")
+	}
+}
+
+func TestAutoFlagDefaultsToFalse(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Auto {
+		t.Errorf("Expected Auto = false, got true")
+	}
+}
+
+func TestIntensityFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-intensity", "0,4096"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Intensity != "0,4096" {
+		t.Errorf("Expected Intensity = 0,4096, got %s", *flags.Intensity)
+	}
+}
+
+func TestIntensityFlagDefaultsToNone(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Intensity != "none" {
+		t.Errorf("Expected Intensity = none, got %s", *flags.Intensity)
+	}
+}
+
+func TestClassColorsFlagsAreParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-classcolors", "2:8b5a2b", "-forceclasscolors", "-unmappedcolor", "000000"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.ClassColors != "2:8b5a2b" || !*flags.ForceClassColors || *flags.UnmappedColor != "000000" {
+		t.Errorf("Expected ClassColors = 2:8b5a2b, ForceClassColors = true, UnmappedColor = 000000, got %s, %v, %s", *flags.ClassColors, *flags.ForceClassColors, *flags.UnmappedColor)
+	}
+}
+
+func TestClassColorsFlagsDefaults(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.ClassColors != "asprs" || *flags.ForceClassColors || *flags.UnmappedColor != "ffffff" {
+		t.Errorf("Expected ClassColors = asprs, ForceClassColors = false, UnmappedColor = ffffff, got %s, %v, %s", *flags.ClassColors, *flags.ForceClassColors, *flags.UnmappedColor)
+	}
+}
+
+func TestElevationRampFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-elevationramp", "default"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.ElevationRamp != "default" {
+		t.Errorf("Expected ElevationRamp = default, got %s", *flags.ElevationRamp)
+	}
+}
+
+func TestElevationRampFlagDefaultsToEmpty(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.ElevationRamp != "" {
+		t.Errorf("Expected ElevationRamp to be empty, got %s", *flags.ElevationRamp)
+	}
+}
+
+func TestIncludeAndExcludeFlagsAreParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-include", "2,9", "-exclude", "7"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Include != "2,9" || *flags.Exclude != "7" {
+		t.Errorf("Expected Include = 2,9 and Exclude = 7, got %s and %s", *flags.Include, *flags.Exclude)
+	}
+	if classifications, err := utils.ParseClassifications(*flags.Include); err != nil || len(classifications) != 2 || classifications[1] != 9 {
+		t.Errorf("Expected the classifications 2 and 9, got %v, %v", classifications, err)
+	}
+	if _, err := utils.ParseClassifications("2,256"); err == nil {
+		t.Errorf("Expected an error parsing the classification 256")
+	}
+}
+
+func TestIncludeAndExcludeFlagsDefaultToEmpty(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Include != "" || *flags.Exclude != "" {
+		t.Errorf("Expected Include and Exclude to be empty, got %s and %s", *flags.Include, *flags.Exclude)
+	}
+}
+
+func TestClipFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-clip", "0,1,10,11"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Clip != "0,1,10,11" {
+		t.Errorf("Expected Clip = 0,1,10,11, got %s", *flags.Clip)
+	}
+	bounds, err := utils.ParseBoundingBox(*flags.Clip)
+	if err != nil || bounds.Xmin != 0 || bounds.Ymin != 1 || bounds.Xmax != 10 || bounds.Ymax != 11 || !bounds.Contains(0, 11, -1e9) {
+		t.Errorf("Expected bounds from 0,1 to 10,11 unbounded along z, got %v, %v", bounds, err)
+	}
+	if bounds, err := utils.ParseBoundingBox("0,1,10,11,-5,5"); err != nil || bounds.Zmin != -5 || bounds.Zmax != 5 {
+		t.Errorf("Expected z bounds from -5 to 5, got %v, %v", bounds, err)
+	}
+	for _, invalid := range []string{"0,1,10", "10,1,0,11", "0,1,10,11,5,-5", "0,1,10,x"} {
+		if _, err := utils.ParseBoundingBox(invalid); err == nil {
+			t.Errorf("Expected an error parsing the bounds %s", invalid)
+		}
+	}
+}
+
+func TestClipFlagDefaultsToEmpty(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Clip != "" {
+		t.Errorf("Expected Clip to be empty, got %s", *flags.Clip)
+	}
+}
+
+func TestKeepEveryFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-keepevery", "10"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.KeepEvery != 10 {
+		t.Errorf("Expected KeepEvery = 10, got %d", *flags.KeepEvery)
+	}
+}
+
+func TestKeepEveryFlagDefaultsToOne(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.KeepEvery != 1 {
+		t.Errorf("Expected KeepEvery = 1, got %d", *flags.KeepEvery)
+	}
+}
+
+func TestTransformFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-transform", "1,0,0,0,0,1,0,0,0,0,1,0,10,20,30,1"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Transform != "1,0,0,0,0,1,0,0,0,0,1,0,10,20,30,1" {
+		t.Errorf("Expected Transform = 1,0,0,0,0,1,0,0,0,0,1,0,10,20,30,1, got %s", *flags.Transform)
+	}
+}
+
+func TestTransformFlagDefaultsToEmpty(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Transform != "" {
+		t.Errorf("Expected Transform to be empty, got %s", *flags.Transform)
+	}
+}
+
+func TestBoxFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-box"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if !*flags.Box {
+		t.Errorf("Expected Box = true, got false")
+	}
+}
+
+func TestBoxFlagDefaultsToFalse(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Box {
+		t.Errorf("Expected Box = false, got true")
+	}
+}
+
+func TestGeSpacingFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-gespacing"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if !*flags.SpacingErrors {
+		t.Errorf("Expected SpacingErrors = true, got false")
+	}
+}
+
+func TestGeSpacingFlagDefaultsToFalse(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.SpacingErrors {
+		t.Errorf("Expected SpacingErrors = false, got true")
+	}
+}
+
+func TestGzipFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-gzip"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if !*flags.Gzip {
+		t.Errorf("Expected Gzip = true, got false")
+	}
+}
+
+func TestGzipFlagDefaultsToFalse(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Gzip {
+		t.Errorf("Expected Gzip = false, got true")
+	}
+}
+
+func TestTightFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-tight"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if !*flags.Tight {
+		t.Errorf("Expected Tight = true, got false")
+	}
+}
+
+func TestTightFlagDefaultsToFalse(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Tight {
+		t.Errorf("Expected Tight = false, got true")
+	}
+}
+
+func TestOutputModeFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-outputmode", "enu"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.OutputMode != "enu" {
+		t.Errorf("Expected OutputMode = enu, got %s", *flags.OutputMode)
+	}
+}
+
+func TestOutputModeFlagDefaultsToEmpty(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.OutputMode != "" {
+		t.Errorf("Expected OutputMode to be empty, got %s", *flags.OutputMode)
+	}
+}
+
+func TestResumeFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-resume"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if !*flags.Resume {
+		t.Errorf("Expected Resume = true, got false")
+	}
+}
+
+func TestResumeFlagDefaultsToFalse(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Resume {
+		t.Errorf("Expected Resume = false, got true")
+	}
+}
+
+func TestDedupFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-dedup=0.01"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Dedup != 0.01 {
+		t.Errorf("Expected Dedup = 0.01, got %f", *flags.Dedup)
+	}
+}
+
+func TestDedupDefaultIsZero(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Dedup != 0 {
+		t.Errorf("Expected Dedup = 0, got %f", *flags.Dedup)
+	}
+}
+
+func TestExtraBytesFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-extrabytes", "Reflectance, Deviation"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.ExtraBytes != "Reflectance, Deviation" {
+		t.Errorf("Expected ExtraBytes = Reflectance, Deviation, got %s", *flags.ExtraBytes)
+	}
+}
+
+func TestExtraBytesFlagDefaultsToEmpty(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.ExtraBytes != "" {
+		t.Errorf("Expected ExtraBytes to be empty, got %s", *flags.ExtraBytes)
+	}
+}
+
+func TestTileNamingFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-tilenaming", "hierarchical"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.TileNaming != "hierarchical" {
+		t.Errorf("Expected TileNaming = hierarchical, got %s", *flags.TileNaming)
+	}
+}
+
+func TestTileNamingFlagDefaultsToEmpty(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.TileNaming != "" {
+		t.Errorf("Expected TileNaming to be empty, got %s", *flags.TileNaming)
+	}
+}
+
+func TestImplicitFlagIsParsed(t *testing.T) {
+	os.Args = []string{"gocesiumtiler", "-implicit"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if !*flags.Implicit {
+		t.Errorf("Expected Implicit = true, got false")
+	}
+}
+
+func TestImplicitFlagDefaultsToFalse(t *testing.T) {
+	os.Args = []string{"gocesiumtiler"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := utils.ParseFlags()
+	if *flags.Implicit {
+		t.Errorf("Expected Implicit = false, got true")
+	}
+}
@@ -0,0 +1,105 @@
+package test
+
+import (
+	"encoding/binary"
+	"github.com/mfbonfigli/gocesiumtiler/converters"
+	"github.com/mfbonfigli/gocesiumtiler/converters/geoid_height_corrector"
+	"github.com/mfbonfigli/gocesiumtiler/converters/grid_geoid_z_converter"
+	"github.com/mfbonfigli/gocesiumtiler/converters/offset_elevation_corrector"
+	"math"
+	"os"
+	"path"
+	"testing"
+)
+
+// Writes a GTX file with the given south west node, spacing and undulations, stored row by row from the south
+func writeTestGtxFile(t *testing.T, filePath string, minLat, minLon, step float64, rows, cols int, values []float32) {
+	content := make([]byte, 40+len(values)*4)
+	for i, value := range []float64{minLat, minLon, step, step} {
+		binary.BigEndian.PutUint64(content[i*8:i*8+8], math.Float64bits(value))
+	}
+	binary.BigEndian.PutUint32(content[32:36], uint32(rows))
+	binary.BigEndian.PutUint32(content[36:40], uint32(cols))
+	for i, value := range values {
+		binary.BigEndian.PutUint32(content[40+i*4:44+i*4], math.Float32bits(value))
+	}
+	if err := os.WriteFile(filePath, content, 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Returns the elevation corrector built, as the tiler does, from two grids covering lon 10-11 and 11-12 at lat 45-46
+func getTestCompositeCorrector(t *testing.T) converters.ElevationCorrector {
+	folder, err := os.MkdirTemp("", "gocesiumtiler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+	writeTestGtxFile(t, path.Join(folder, "west.gtx"), 45, 10, 1, 2, 2, []float32{40, 42, 44, 46})
+	writeTestGtxFile(t, path.Join(folder, "east.gtx"), 45, 11, 0.5, 3, 3, []float32{50, 50, 50, 50, 50, 50, 50, 50, 50})
+	grids, err := grid_geoid_z_converter.NewGtxGeoidGrids(path.Join(folder, "west.gtx") + "," + path.Join(folder, "east.gtx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	providers := make([]converters.GeoidHeightProvider, len(grids))
+	for i, grid := range grids {
+		providers[i] = grid
+	}
+	return geoid_height_corrector.NewGeoidHeightCorrector(0, providers, offset_elevation_corrector.NewOffsetElevationCorrector(-1))
+}
+
+func TestGtxGridIsBilinearlyInterpolated(t *testing.T) {
+	folder, err := os.MkdirTemp("", "gocesiumtiler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+	writeTestGtxFile(t, path.Join(folder, "grid.gtx"), 45, 10, 1, 2, 2, []float32{40, 42, 44, 46})
+	grid, err := grid_geoid_z_converter.NewGtxGeoidGrid(path.Join(folder, "grid.gtx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct{ lat, lon, expected float64 }{
+		{45, 10, 40}, {45, 11, 42}, {46, 10, 44}, {46, 11, 46}, {45.5, 10.5, 43}, {45.25, 10.75, 42.5},
+	} {
+		value, err := grid.GetEllipsoidToGeoidZOffset(c.lat, c.lon, 4326)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(value-c.expected) > 1e-9 {
+			t.Errorf("Expected undulation %f at %f, %f, got %f", c.expected, c.lat, c.lon, value)
+		}
+	}
+	if _, err := grid.GetEllipsoidToGeoidZOffset(47, 10, 4326); err == nil {
+		t.Errorf("Expected an error outside of the grid coverage")
+	}
+}
+
+func TestPointsInDifferentGridCoveragesGetTheirGridUndulation(t *testing.T) {
+	corrector := getTestCompositeCorrector(t)
+	for _, c := range []struct{ lon, lat, expected float64 }{
+		{10.5, 45.5, 143}, // west grid, interpolated
+		{10.25, 45.75, 143.5},
+		{11.5, 45.5, 150}, // east grid
+		{11.9, 45.1, 150},
+		{12.5, 45.5, 99}, // outside of both grids, fallback corrector
+		{10.5, 44.5, 99},
+	} {
+		z := corrector.CorrectElevation(c.lon, c.lat, 100)
+		if math.Abs(z-c.expected) > 1e-3 {
+			t.Errorf("Expected corrected elevation %f at %f, %f, got %f", c.expected, c.lon, c.lat, z)
+		}
+	}
+}
+
+func TestInvalidGtxFileIsRejected(t *testing.T) {
+	folder, err := os.MkdirTemp("", "gocesiumtiler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+	writeTestGtxFile(t, path.Join(folder, "grid.gtx"), 45, 10, 1, 3, 3, []float32{40, 42, 44, 46})
+	if _, err := grid_geoid_z_converter.NewGtxGeoidGrids(path.Join(folder, "grid.gtx")); err == nil {
+		t.Errorf("Expected an error reading a truncated GTX file")
+	}
+}
@@ -0,0 +1,281 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"github.com/mfbonfigli/gocesiumtiler/structs/octree"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestNoNegativeGeometricErrorsAreWritten(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.MaxNumPointsPerNode = 20
+	writeTileset(t, newTestPoints(), opts)
+
+	err := filepath.Walk(opts.Output, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil || info.Name() != "tileset.json" {
+			return err
+		}
+		tileset, err := io.ReadTilesetFile(filePath)
+		if err != nil {
+			return err
+		}
+		geometricErrors := []float64{tileset.GeometricError, tileset.Root.GeometricError}
+		for _, child := range tileset.Root.Children {
+			geometricErrors = append(geometricErrors, child.GeometricError)
+		}
+		for _, geometricError := range geometricErrors {
+			if geometricError < 0 {
+				t.Errorf("Expected non-negative geometric errors in %s, got %f", filePath, geometricError)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMonotonicGeometricErrorsAreValid(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.MaxNumPointsPerNode = 20
+	opts.MonotonicGeometricError = true
+	writeTileset(t, newTestPoints(), opts)
+
+	geometricErrorRange, err := io.ValidateGeometricErrors(path.Join(opts.Output, "tileset.json"))
+	if err != nil {
+		t.Fatalf("Expected no geometric error validation error, got %v", err)
+	}
+	if geometricErrorRange.Min < 0 || geometricErrorRange.Max < geometricErrorRange.Min {
+		t.Errorf("Expected a non-negative geometric error range, got %v", geometricErrorRange)
+	}
+}
+
+func TestIncreasingGeometricErrorsAreDetected(t *testing.T) {
+	output, err := os.MkdirTemp("", "gocesiumtiler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(output)
+	tileset := `{"asset":{"version":"1.0"},"geometricError":10,"root":{"geometricError":10,"refine":"ADD",` +
+		`"content":{"uri":"content.pnts"},"boundingVolume":{"region":[0,0,1,1,0,1]},"children":[` +
+		`{"geometricError":12,"refine":"ADD","content":{"uri":"0/content.pnts"},"boundingVolume":{"region":[0,0,1,1,0,1]}}]}}`
+	if err := os.WriteFile(path.Join(output, "tileset.json"), []byte(tileset), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := io.ValidateGeometricErrors(path.Join(output, "tileset.json")); err == nil {
+		t.Errorf("Expected an error for a child geometric error greater than its parent one, got nil")
+	}
+}
+
+func TestDiagonalGeometricErrorsHalveAtEachLevel(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.MaxNumPointsPerNode = 20
+	opts.GeometricErrors = tiler.DiagonalGeometricErrors
+	opts.DiagonalFraction = 0.1
+	writeTileset(t, newTestPoints(), opts)
+
+	geometricErrorRange, err := io.ValidateGeometricErrors(path.Join(opts.Output, "tileset.json"))
+	if err != nil {
+		t.Fatalf("Expected no geometric error validation error, got %v", err)
+	}
+	if !(geometricErrorRange.Min > 0) {
+		t.Errorf("Expected positive geometric errors, got %v", geometricErrorRange)
+	}
+	children := 0
+	err = filepath.Walk(opts.Output, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil || info.Name() != "tileset.json" {
+			return err
+		}
+		tileset, err := io.ReadTilesetFile(filePath)
+		if err != nil {
+			return err
+		}
+		for _, child := range tileset.Root.Children {
+			children++
+			if ratio := child.GeometricError / tileset.Root.GeometricError; math.Abs(ratio-0.5) > 0.01 {
+				t.Errorf("Expected the geometric error of a child tile to be half the one of its parent in %s, got ratio %f", filePath, ratio)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if children == 0 {
+		t.Fatalf("Expected a tileset with child tiles")
+	}
+}
+
+func TestGeometricErrorsMatchTheScanOfTheAncestorPoints(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.MaxNumPointsPerNode = 20
+	// integer coordinates put many points on the boundaries shared by sibling octants
+	tree := buildTree(t, newTestPoints(), opts)
+	exportTree(t, tree, opts)
+
+	// geometric error of each tile by the folder of its content, relative to the output folder
+	geometricErrors := make(map[string]float64)
+	err := filepath.Walk(opts.Output, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil || info.Name() != "tileset.json" {
+			return err
+		}
+		tileset, err := io.ReadTilesetFile(filePath)
+		if err != nil {
+			return err
+		}
+		folder, err := filepath.Rel(opts.Output, filepath.Dir(filePath))
+		if err != nil {
+			return err
+		}
+		geometricErrors[filepath.ToSlash(folder)] = tileset.Root.GeometricError
+		for _, child := range tileset.Root.Children {
+			geometricErrors[path.Dir(path.Join(filepath.ToSlash(folder), child.Content.Url))] = child.GeometricError
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(geometricErrors) < 10 {
+		t.Fatalf("Expected a tileset with at least 10 tiles, got %d", len(geometricErrors))
+	}
+	for folder, geometricError := range geometricErrors {
+		node := &tree.RootNode
+		if folder != "." {
+			for _, index := range strings.Split(folder, "/") {
+				i, err := strconv.Atoi(index)
+				if err != nil {
+					t.Fatal(err)
+				}
+				node = node.Children[i]
+			}
+		}
+		if expected := scanGeometricError(node); math.Abs(geometricError-expected) > 1e-9*math.Max(1, expected) {
+			t.Errorf("Expected the geometric error %f for the tile %s, got %f", expected, folder, geometricError)
+		}
+	}
+}
+
+// Computes the geometric error of the given node scanning the points of its ancestors within its bounding box, as
+// done before the ancestor points were counted while building the tree
+func scanGeometricError(node *octree.OctNode) float64 {
+	volume := node.BoundingBox.GetVolume()
+	totalRenderedPoints := int64(node.LocalChildrenCount)
+	for parent := node.Parent; parent != nil; parent = parent.Parent {
+		for _, e := range parent.Items {
+			if e.X >= node.BoundingBox.Xmin && e.X <= node.BoundingBox.Xmax && e.Y >= node.BoundingBox.Ymin &&
+				e.Y <= node.BoundingBox.Ymax && e.Z >= node.BoundingBox.Zmin && e.Z <= node.BoundingBox.Zmax {
+				totalRenderedPoints++
+			}
+		}
+	}
+	densityWithAllPoints := math.Pow(volume/float64(totalRenderedPoints+node.GlobalChildrenCount-int64(node.LocalChildrenCount)), 0.333)
+	densityWithOnlyThisTile := math.Pow(volume/float64(totalRenderedPoints), 0.333)
+	geometricError := densityWithOnlyThisTile - densityWithAllPoints
+	if !(geometricError > 0) || math.IsInf(geometricError, 0) {
+		return 0
+	}
+	return geometricError
+}
+
+func TestGeometricErrorsOfPathologicalTreesAreFinite(t *testing.T) {
+	corner := make([]*data.Point, 0)
+	for i := 0; i < 100; i++ {
+		corner = append(corner, data.NewPoint(float64(i%5)*0.01, float64(i%3)*0.01, 0, 0, 0, 0, 0, 0))
+	}
+	corner = append(corner, data.NewPoint(100, 100, 100, 0, 0, 0, 0, 0))
+	flat := make([]*data.Point, 0)
+	for i := 0; i < 100; i++ {
+		flat = append(flat, data.NewPoint(float64(i%10), float64(i/10), 5, 0, 0, 0, 0, 0))
+	}
+	pointSets := map[string][]*data.Point{
+		"single point":        {data.NewPoint(1, 2, 3, 0, 0, 0, 0, 0)},
+		"coincident points":   {data.NewPoint(1, 2, 3, 0, 0, 0, 0, 0), data.NewPoint(1, 2, 3, 0, 0, 0, 0, 0)},
+		"empty child octants": corner,
+		"flat points":         flat,
+	}
+	modes := map[string]tiler.GeometricErrorMode{
+		"density":  tiler.DensityGeometricErrors,
+		"diagonal": tiler.DiagonalGeometricErrors,
+		"spacing":  tiler.SpacingGeometricErrors,
+	}
+	for setName, points := range pointSets {
+		for modeName, mode := range modes {
+			opts := newTestOptions(t)
+			opts.MaxNumPointsPerNode = 1
+			opts.GeometricErrors = mode
+			opts.DiagonalFraction = 0.1
+			writeTileset(t, points, opts)
+			forEachTestGeometricError(t, opts.Output, func(file string, url string, geometricError float64) {
+				if !(geometricError >= 0) || math.IsInf(geometricError, 0) {
+					t.Errorf("%s, %s: expected a finite non-negative geometric error for %s in %s, got %f", setName, modeName, url, file, geometricError)
+				}
+			})
+			_ = os.RemoveAll(opts.Output)
+		}
+	}
+}
+
+func TestSpacingGeometricErrorsAreZeroOnlyForLeaves(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.MaxNumPointsPerNode = 20
+	opts.GeometricErrors = tiler.SpacingGeometricErrors
+	writeTileset(t, newTestPoints(), opts)
+
+	leaves := 0
+	forEachTestGeometricError(t, opts.Output, func(file string, url string, geometricError float64) {
+		switch {
+		case url == "":
+			// root of the tileset.json, a tile with children
+			if !(geometricError > 0) {
+				t.Errorf("Expected a positive geometric error for the root of %s, got %f", file, geometricError)
+			}
+		case strings.HasSuffix(url, "content.pnts"):
+			leaves++
+			if geometricError != 0 {
+				t.Errorf("Expected no geometric error for the leaf %s in %s, got %f", url, file, geometricError)
+			}
+		}
+	})
+	if leaves == 0 {
+		t.Errorf("Expected leaf tiles")
+	}
+}
+
+// Calls the given function with the geometric error of every tileset and tile in the tileset.json files of the given
+// output folder, together with the tileset.json path and the content url of the tile, empty for the tileset and its
+// root tile
+func forEachTestGeometricError(t *testing.T, output string, check func(file string, url string, geometricError float64)) {
+	err := filepath.Walk(output, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil || info.Name() != "tileset.json" {
+			return err
+		}
+		tileset, err := io.ReadTilesetFile(filePath)
+		if err != nil {
+			return err
+		}
+		check(filePath, "", tileset.GeometricError)
+		check(filePath, "", tileset.Root.GeometricError)
+		for _, child := range tileset.Root.Children {
+			check(filePath, child.Content.Url, child.GeometricError)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,217 @@
+package test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"github.com/mfbonfigli/gocesiumtiler/converters"
+	"github.com/mfbonfigli/gocesiumtiler/converters/geoid_height_corrector"
+	"github.com/mfbonfigli/gocesiumtiler/converters/grid_geoid_z_converter"
+	"github.com/mfbonfigli/gocesiumtiler/converters/offset_elevation_corrector"
+	"math"
+	"os"
+	"path"
+	"sort"
+	"testing"
+)
+
+// GeoTIFF geoid grid generated by the tests
+type testGeoTiff struct {
+	minLat, minLon, step float64
+	rows, cols           int
+	values               []float32 // Undulations row by row starting from the south west node
+	tiled                bool      // Stores a deflate compressed 16x16 tile with the floating point predictor rather than an uncompressed strip
+	pixelIsArea          bool      // Georeferences the pixel areas rather than their corners
+	noData               string    // If not empty, stored as the GDAL no data value
+}
+
+// Writes the given grid as a little endian GeoTIFF file
+func writeTestGeoTiffFile(t *testing.T, filePath string, grid testGeoTiff) {
+	type entry struct {
+		tag, dataType uint16
+		count         uint32
+		value         []byte
+	}
+	shorts := func(values ...uint16) []byte {
+		b := make([]byte, 2*len(values))
+		for i, value := range values {
+			binary.LittleEndian.PutUint16(b[2*i:], value)
+		}
+		return b
+	}
+	longs := func(values ...uint32) []byte {
+		b := make([]byte, 4*len(values))
+		for i, value := range values {
+			binary.LittleEndian.PutUint32(b[4*i:], value)
+		}
+		return b
+	}
+	doubles := func(values ...float64) []byte {
+		b := make([]byte, 8*len(values))
+		for i, value := range values {
+			binary.LittleEndian.PutUint64(b[8*i:], math.Float64bits(value))
+		}
+		return b
+	}
+
+	// image rows start from the north
+	data := make([]byte, 0)
+	blockWidth := grid.cols
+	if grid.tiled {
+		blockWidth = 16
+	}
+	for r := 0; r < grid.rows || (grid.tiled && r < 16); r++ {
+		row := make([]byte, 4*blockWidth)
+		for c := 0; c < grid.cols && r < grid.rows; c++ {
+			binary.LittleEndian.PutUint32(row[4*c:], math.Float32bits(grid.values[(grid.rows-1-r)*grid.cols+c]))
+		}
+		if grid.tiled {
+			// floating point predictor, big endian bytes split in planes and differenced
+			planes := make([]byte, len(row))
+			for s := 0; s < blockWidth; s++ {
+				for b := 0; b < 4; b++ {
+					planes[b*blockWidth+s] = row[4*s+3-b]
+				}
+			}
+			for i := len(planes) - 1; i > 0; i-- {
+				planes[i] -= planes[i-1]
+			}
+			row = planes
+		}
+		data = append(data, row...)
+	}
+	compression, predictor := uint16(1), uint16(1)
+	if grid.tiled {
+		var compressed bytes.Buffer
+		writer := zlib.NewWriter(&compressed)
+		if _, err := writer.Write(data); err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatal(err)
+		}
+		data = compressed.Bytes()
+		compression, predictor = 8, 3
+	}
+
+	maxLat := grid.minLat + float64(grid.rows-1)*grid.step
+	tiepoint := []float64{0, 0, 0, grid.minLon, maxLat, 0}
+	rasterType := uint16(2)
+	if grid.pixelIsArea {
+		tiepoint[3], tiepoint[4] = grid.minLon-grid.step/2, maxLat+grid.step/2
+		rasterType = 1
+	}
+	entries := []entry{
+		{256, 4, 1, longs(uint32(grid.cols))},
+		{257, 4, 1, longs(uint32(grid.rows))},
+		{258, 3, 1, shorts(32)},
+		{259, 3, 1, shorts(compression)},
+		{277, 3, 1, shorts(1)},
+		{317, 3, 1, shorts(predictor)},
+		{339, 3, 1, shorts(3)},
+		{33550, 12, 3, doubles(grid.step, grid.step, 0)},
+		{33922, 12, 6, doubles(tiepoint...)},
+		{34735, 3, 8, shorts(1, 1, 0, 1, 1025, 0, 1, rasterType)},
+	}
+	if grid.tiled {
+		entries = append(entries, entry{322, 3, 1, shorts(16)}, entry{323, 3, 1, shorts(16)}, entry{324, 4, 1, longs(8)}, entry{325, 4, 1, longs(uint32(len(data)))})
+	} else {
+		entries = append(entries, entry{273, 4, 1, longs(8)}, entry{278, 4, 1, longs(uint32(grid.rows))}, entry{279, 4, 1, longs(uint32(len(data)))})
+	}
+	if grid.noData != "" {
+		entries = append(entries, entry{42113, 2, uint32(len(grid.noData) + 1), append([]byte(grid.noData), 0)})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].tag < entries[j].tag })
+
+	content := append([]byte{'I', 'I', 42, 0}, longs(uint32(8+len(data)))...)
+	content = append(content, data...)
+	external := 8 + len(data) + 2 + 12*len(entries) + 4
+	directory := shorts(uint16(len(entries)))
+	values := make([]byte, 0)
+	for _, e := range entries {
+		directory = append(directory, shorts(e.tag, e.dataType)...)
+		directory = append(directory, longs(e.count)...)
+		if len(e.value) <= 4 {
+			directory = append(directory, append(e.value, make([]byte, 4-len(e.value))...)...)
+		} else {
+			directory = append(directory, longs(uint32(external+len(values)))...)
+			values = append(values, e.value...)
+		}
+	}
+	content = append(append(append(content, directory...), longs(0)...), values...)
+	if err := os.WriteFile(filePath, content, 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGeoTiffGridIsBilinearlyInterpolated(t *testing.T) {
+	folder, err := os.MkdirTemp("", "gocesiumtiler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+	values := []float32{40, 42, 41, 44, 46, 45}
+	for name, grid := range map[string]testGeoTiff{
+		"strip.tif":  {minLat: 45, minLon: 10, step: 1, rows: 2, cols: 3, values: values},
+		"tiled.tiff": {minLat: 45, minLon: 10, step: 1, rows: 2, cols: 3, values: values, tiled: true},
+		"area.tif":   {minLat: 45, minLon: 10, step: 1, rows: 2, cols: 3, values: values, pixelIsArea: true},
+	} {
+		writeTestGeoTiffFile(t, path.Join(folder, name), grid)
+		grids, err := grid_geoid_z_converter.NewGeoidGrids(path.Join(folder, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range []struct{ lon, lat, expected float64 }{
+			{10, 45, 40}, {12, 45, 41}, {10, 46, 44}, {12, 46, 45}, {10.5, 45.5, 43}, {11.25, 45.75, 44.75},
+		} {
+			value, ok := grids[0].GetGeoidHeight(c.lon, c.lat)
+			if !ok || math.Abs(value-c.expected) > 1e-6 {
+				t.Errorf("Expected geoid height %f at %f, %f of %s, got %f, %v", c.expected, c.lon, c.lat, name, value, ok)
+			}
+		}
+		if _, ok := grids[0].GetGeoidHeight(12.5, 45.5); ok {
+			t.Errorf("Expected no geoid height outside of the grid %s", name)
+		}
+	}
+}
+
+func TestGeoidHeightCorrectorFallsBackOutsideOfTheGrids(t *testing.T) {
+	folder, err := os.MkdirTemp("", "gocesiumtiler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+	// global grid from 0 to 360 degrees of longitude without data in its north east node
+	writeTestGeoTiffFile(t, path.Join(folder, "global.tif"), testGeoTiff{
+		minLat: -90, minLon: 0, step: 90, rows: 3, cols: 5, noData: "-32768",
+		values: []float32{10, 10, 10, 10, 10, 20, 20, 20, 20, 20, 30, 30, 30, 30, -32768},
+	})
+	grids, err := grid_geoid_z_converter.NewGeoidGrids(path.Join(folder, "global.tif"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrector := geoid_height_corrector.NewGeoidHeightCorrector(1, []converters.GeoidHeightProvider{grids[0]}, offset_elevation_corrector.NewOffsetElevationCorrector(-1))
+	for _, c := range []struct{ lon, lat, expected float64 }{
+		{45, 0, 121},    // interpolated
+		{-45, -45, 116}, // negative longitudes wrap around the grid
+		{315, 60, 99},   // next to the node without data, fallback corrector
+	} {
+		if z := corrector.CorrectElevation(c.lon, c.lat, 100); math.Abs(z-c.expected) > 1e-6 {
+			t.Errorf("Expected corrected elevation %f at %f, %f, got %f", c.expected, c.lon, c.lat, z)
+		}
+	}
+}
+
+func TestUnsupportedGeoTiffIsRejected(t *testing.T) {
+	folder, err := os.MkdirTemp("", "gocesiumtiler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+	if err := os.WriteFile(path.Join(folder, "grid.tif"), []byte("MM\x00\x2b\x00\x08"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := grid_geoid_z_converter.NewGeoidGrids(path.Join(folder, "grid.tif")); err == nil {
+		t.Errorf("Expected an error reading a BigTIFF file")
+	}
+}
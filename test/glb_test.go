@@ -0,0 +1,127 @@
+package test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestGlbChunksArePaddedToFourBytes(t *testing.T) {
+	for jsonLength := 0; jsonLength < 8; jsonLength++ {
+		for binLength := 0; binLength < 9; binLength++ {
+			gltfJson := testGltfJson(binLength, jsonLength)
+			bin := bytes.Repeat([]byte{0xff}, binLength)
+			glb := io.EncodeGlb(gltfJson, bin)
+
+			if err := io.ValidateGlb(glb); err != nil {
+				t.Fatalf("json %d bytes, bin %d bytes: %v", len(gltfJson), binLength, err)
+			}
+			if int(binary.LittleEndian.Uint32(glb[8:12])) != len(glb) || len(glb)%4 != 0 {
+				t.Errorf("json %d bytes, bin %d bytes: unexpected glb length %d", len(gltfJson), binLength, len(glb))
+			}
+
+			jsonChunkLength := int(binary.LittleEndian.Uint32(glb[12:16]))
+			jsonChunk := glb[20 : 20+jsonChunkLength]
+			if jsonChunkLength%4 != 0 || jsonChunkLength-len(gltfJson) >= 4 {
+				t.Errorf("json %d bytes: unexpected json chunk length %d", len(gltfJson), jsonChunkLength)
+			}
+			if !bytes.Equal(jsonChunk[:len(gltfJson)], gltfJson) || strings.Trim(string(jsonChunk[len(gltfJson):]), " ") != "" {
+				t.Errorf("json %d bytes: json chunk is not padded with spaces", len(gltfJson))
+			}
+
+			binStart := 20 + jsonChunkLength
+			if binLength == 0 {
+				if binStart != len(glb) {
+					t.Errorf("unexpected BIN chunk for an empty binary buffer")
+				}
+				continue
+			}
+			binChunkLength := int(binary.LittleEndian.Uint32(glb[binStart : binStart+4]))
+			binChunk := glb[binStart+8:]
+			if binChunkLength != len(binChunk) || binChunkLength%4 != 0 || binChunkLength-binLength >= 4 {
+				t.Errorf("bin %d bytes: unexpected BIN chunk length %d", binLength, binChunkLength)
+			}
+			if !bytes.Equal(binChunk[:binLength], bin) || len(bytes.Trim(binChunk[binLength:], "\x00")) != 0 {
+				t.Errorf("bin %d bytes: BIN chunk is not padded with zeros", binLength)
+			}
+		}
+	}
+}
+
+func TestGlbValidatorRejectsMalformedContent(t *testing.T) {
+	valid := io.EncodeGlb(testGltfJson(5, 0), []byte{1, 2, 3, 4, 5})
+	for _, tc := range []struct {
+		name   string
+		mutate func(glb []byte) []byte
+	}{
+		{"bad magic", func(glb []byte) []byte {
+			glb[0] = 'x'
+			return glb
+		}},
+		{"bad version", func(glb []byte) []byte {
+			binary.LittleEndian.PutUint32(glb[4:8], 1)
+			return glb
+		}},
+		{"bad length", func(glb []byte) []byte {
+			binary.LittleEndian.PutUint32(glb[8:12], uint32(len(glb)+4))
+			return glb
+		}},
+		{"trailing data", func(glb []byte) []byte {
+			glb = append(glb, 0, 0, 0, 0)
+			binary.LittleEndian.PutUint32(glb[8:12], uint32(len(glb)))
+			return glb
+		}},
+		{"misaligned json chunk", func(glb []byte) []byte {
+			binary.LittleEndian.PutUint32(glb[12:16], binary.LittleEndian.Uint32(glb[12:16])-1)
+			return glb
+		}},
+		{"wrong first chunk type", func(glb []byte) []byte {
+			binary.LittleEndian.PutUint32(glb[16:20], 0x004E4942)
+			return glb
+		}},
+		{"missing bin chunk", func(glb []byte) []byte {
+			glb = glb[:20+binary.LittleEndian.Uint32(glb[12:16])]
+			binary.LittleEndian.PutUint32(glb[8:12], uint32(len(glb)))
+			return glb
+		}},
+		{"bin padding not zero", func(glb []byte) []byte {
+			glb[len(glb)-1] = 0x20
+			return glb
+		}},
+	} {
+		glb := tc.mutate(append([]byte(nil), valid...))
+		if err := io.ValidateGlb(glb); err == nil {
+			t.Errorf("%s: expected the glb to be rejected", tc.name)
+		}
+	}
+
+	mismatched := io.EncodeGlb(testGltfJson(12, 0), []byte{1, 2, 3, 4, 5})
+	if err := io.ValidateGlb(mismatched); err == nil {
+		t.Errorf("expected a glb whose buffer byteLength exceeds the BIN chunk to be rejected")
+	}
+}
+
+func TestGlbFileIsValidated(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "content.glb")
+	if err := os.WriteFile(file, io.EncodeGlb(testGltfJson(3, 1), []byte{1, 2, 3}), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := io.ValidateGlbFile(file); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// Returns a minimal glTF json whose first buffer has the given byte length, stretched by the given number of bytes
+// to exercise every JSON chunk padding
+func testGltfJson(byteLength int, extra int) []byte {
+	buffers := ""
+	if byteLength > 0 {
+		buffers = `,"buffers":[{"byteLength":` + strconv.Itoa(byteLength) + `}]`
+	}
+	return []byte(`{"asset":{"version":"2.0","generator":"` + strings.Repeat("x", extra) + `"}` + buffers + `}`)
+}
@@ -0,0 +1,177 @@
+package test
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Subset of the glTF json of the point cloud tiles checked by the tests
+type testGltf struct {
+	ExtensionsUsed []string `json:"extensionsUsed"`
+	Nodes          []struct {
+		Mesh        int        `json:"mesh"`
+		Translation [3]float64 `json:"translation"`
+	} `json:"nodes"`
+	Meshes []struct {
+		Primitives []struct {
+			Attributes map[string]int `json:"attributes"`
+			Material   int            `json:"material"`
+			Mode       int            `json:"mode"`
+		} `json:"primitives"`
+	} `json:"meshes"`
+	Materials []struct {
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"materials"`
+	Accessors []struct {
+		BufferView    int    `json:"bufferView"`
+		ComponentType int    `json:"componentType"`
+		Count         int    `json:"count"`
+		Type          string `json:"type"`
+	} `json:"accessors"`
+	BufferViews []struct {
+		ByteOffset int `json:"byteOffset"`
+		ByteLength int `json:"byteLength"`
+	} `json:"bufferViews"`
+}
+
+func TestGlbTilesAreValidPointClouds(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.MaxNumPointsPerNode = 20
+	opts.OutputFormat = tiler.GlbOutput
+	points := newTestPoints()
+	expected := make(map[[3]float64]bool)
+	for i, point := range points {
+		point.R, point.G, point.B = uint16(i)<<8, 0x1200, 0xff00
+		expected[[3]float64{point.X, point.Y, point.Z}] = true
+	}
+	writeTileset(t, points, opts)
+
+	tileset, err := io.ReadTilesetFile(filepath.Join(opts.Output, "tileset.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tileset.Asset.Version != "1.1" {
+		t.Errorf("Expected a 3D Tiles 1.1 tileset, got version %s", tileset.Asset.Version)
+	}
+	contents := make(map[string]int)
+	collectTileContents(t, opts.Output, "tileset.json", 1, contents)
+	if len(contents) < 3 {
+		t.Fatalf("Expected a tileset with at least 3 tiles, got %d", len(contents))
+	}
+	pointNo := 0
+	for content := range contents {
+		if !strings.HasSuffix(content, "content.glb") {
+			t.Fatalf("Expected glb contents, got %s", content)
+		}
+		for _, position := range readTestGlbPositions(t, filepath.Join(opts.Output, content)) {
+			pointNo++
+			rounded := [3]float64{math.Round(position[0]), math.Round(position[1]), math.Round(position[2])}
+			if !expected[rounded] || math.Abs(rounded[0]-position[0]) > 1e-3 || math.Abs(rounded[1]-position[1]) > 1e-3 || math.Abs(rounded[2]-position[2]) > 1e-3 {
+				t.Fatalf("Unexpected point %v in %s", position, content)
+			}
+		}
+	}
+	if pointNo != len(points) {
+		t.Errorf("Expected %d points in the glb tiles, got %d", len(points), pointNo)
+	}
+	if pnts, _ := filepath.Glob(filepath.Join(opts.Output, "*", "content.pnts")); len(pnts) > 0 {
+		t.Errorf("Expected no content.pnts files, got %v", pnts)
+	}
+}
+
+// Validates the given glb point cloud and returns the ECEF coordinates of its points
+func readTestGlbPositions(t *testing.T, file string) [][3]float64 {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := io.ValidateGlb(content); err != nil {
+		t.Fatalf("Invalid glb %s: %v", file, err)
+	}
+	jsonLength := int(binary.LittleEndian.Uint32(content[12:16]))
+	gltf := testGltf{}
+	if err := json.Unmarshal(content[20:20+jsonLength], &gltf); err != nil {
+		t.Fatal(err)
+	}
+	bin := content[20+jsonLength+8:]
+
+	if len(gltf.ExtensionsUsed) != 1 || gltf.ExtensionsUsed[0] != "KHR_materials_unlit" {
+		t.Errorf("Expected the KHR_materials_unlit extension to be used, got %v", gltf.ExtensionsUsed)
+	}
+	if _, ok := gltf.Materials[0].Extensions["KHR_materials_unlit"]; !ok {
+		t.Errorf("Expected an unlit material")
+	}
+	primitive := gltf.Meshes[0].Primitives[0]
+	if primitive.Mode != 0 {
+		t.Errorf("Expected a POINTS primitive, got mode %d", primitive.Mode)
+	}
+	colors, ok := primitive.Attributes["COLOR_0"]
+	if !ok || gltf.Accessors[colors].Type != "VEC4" || gltf.Accessors[colors].ComponentType != 5121 {
+		t.Errorf("Expected RGBA unsigned byte colors, got %v", gltf.Accessors)
+	}
+
+	accessor := gltf.Accessors[primitive.Attributes["POSITION"]]
+	if accessor.Type != "VEC3" || accessor.ComponentType != 5126 {
+		t.Fatalf("Expected float VEC3 positions, got %v", accessor)
+	}
+	view := gltf.BufferViews[accessor.BufferView]
+	if view.ByteLength != accessor.Count*12 {
+		t.Fatalf("Expected %d bytes of positions, got %d", accessor.Count*12, view.ByteLength)
+	}
+	translation := gltf.Nodes[0].Translation
+	positions := make([][3]float64, accessor.Count)
+	for i := range positions {
+		var yUp [3]float64
+		for j := range yUp {
+			offset := view.ByteOffset + i*12 + j*4
+			yUp[j] = translation[j] + float64(math.Float32frombits(binary.LittleEndian.Uint32(bin[offset:offset+4])))
+		}
+		// glTF is y-up, ECEF (x, y, z) is stored as (x, z, -y)
+		positions[i] = [3]float64{yUp[0], -yUp[2], yUp[1]}
+	}
+	return positions
+}
+
+func TestGlbTilesStoreNormals(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.OutputFormat = tiler.GlbOutput
+	opts.ComputeNormals = true
+	points := make([]*data.Point, 0)
+	for i := 0; i < 100; i++ {
+		points = append(points, data.NewPoint(float64(i%10), float64(i/10), 0, 0, 0, 0, 0, 0))
+	}
+	writeTileset(t, points, opts)
+
+	content, err := os.ReadFile(filepath.Join(opts.Output, "content.glb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonLength := int(binary.LittleEndian.Uint32(content[12:16]))
+	gltf := testGltf{}
+	if err := json.Unmarshal(content[20:20+jsonLength], &gltf); err != nil {
+		t.Fatal(err)
+	}
+	normals, ok := gltf.Meshes[0].Primitives[0].Attributes["NORMAL"]
+	if !ok {
+		t.Fatalf("Expected a NORMAL attribute")
+	}
+	bin := content[20+jsonLength+8:]
+	view := gltf.BufferViews[gltf.Accessors[normals].BufferView]
+	for i := 0; i < gltf.Accessors[normals].Count; i++ {
+		// normals of the z = 0 plane are along the ECEF z axis, i.e. the glTF y axis
+		y := math.Float32frombits(binary.LittleEndian.Uint32(bin[view.ByteOffset+i*12+4:]))
+		if math.Abs(math.Abs(float64(y))-1) > 1e-3 {
+			t.Fatalf("Expected normals along the y axis, got y = %f", y)
+		}
+	}
+}
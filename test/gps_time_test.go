@@ -0,0 +1,112 @@
+package test
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGpsTimeIsReadFromThePointFormatsStoringIt(t *testing.T) {
+	points := []testLasPoint{
+		{raw: [3]int32{1, 2, 3}, gpsTime: 12345.678},
+		{raw: [3]int32{4, 5, 6}, gpsTime: -0.5},
+	}
+	for _, format := range []struct {
+		versionMinor byte
+		pointFormat  byte
+		recordLength int
+		withGpsTime  bool
+	}{{2, 0, 20, false}, {2, 1, 28, true}, {2, 2, 26, false}, {2, 3, 34, true}, {4, 6, 30, true}, {4, 7, 36, true}} {
+		file := writeTestLasRecords(t, format.versionMinor, format.pointFormat, format.recordLength, points)
+		for _, point := range readTestLasFile(t, file) {
+			expected := points[0]
+			if point.X == 4 {
+				expected = points[1]
+			}
+			if !format.withGpsTime && point.HasGpsTime() {
+				t.Errorf("Expected no GPS time for point format %d, got %f", format.pointFormat, point.GpsTime)
+			}
+			if format.withGpsTime && point.GpsTime != expected.gpsTime {
+				t.Errorf("Expected GPS time %f for point format %d, got %f", expected.gpsTime, format.pointFormat, point.GpsTime)
+			}
+		}
+		_ = os.RemoveAll(filepath.Dir(file))
+	}
+}
+
+func TestGpsTimeIsWrittenInTheBatchTable(t *testing.T) {
+	for _, colorDepth := range []int{8, 16} {
+		opts := newTestOptions(t)
+		opts.ColorDepth = colorDepth
+		points := newTestGpsTimePoints()
+		writeTileset(t, points, opts)
+		file := filepath.Join(opts.Output, "content.pnts")
+
+		if err := io.ValidatePntsFile(file); err != nil {
+			t.Errorf("Expected a valid pnts layout, got %v", err)
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		property := struct {
+			ByteOffset    int    `json:"byteOffset"`
+			ComponentType string `json:"componentType"`
+			Type          string `json:"type"`
+		}{}
+		raw, ok := readTestBatchTable(t, file)["GPS_TIME"]
+		if !ok {
+			t.Fatalf("Expected a GPS_TIME batch table property")
+		}
+		if err := json.Unmarshal(raw, &property); err != nil {
+			t.Fatal(err)
+		}
+		if property.ComponentType != "DOUBLE" || property.Type != "SCALAR" {
+			t.Errorf("Expected DOUBLE SCALAR GPS times, got %s %s", property.ComponentType, property.Type)
+		}
+		featureTableLen := int(binary.LittleEndian.Uint32(content[12:16]))
+		featureTableBinaryLen := int(binary.LittleEndian.Uint32(content[16:20]))
+		batchTableLen := int(binary.LittleEndian.Uint32(content[20:24]))
+		start := 28 + featureTableLen + featureTableBinaryLen + batchTableLen + property.ByteOffset
+		if start%8 != 0 {
+			t.Errorf("Expected the GPS_TIME array to be aligned to 8 bytes in the file, got offset %d", start)
+		}
+
+		// points are written in random order, the GPS time of each point being its x coordinate
+		gpsTimes := make(map[float64]bool)
+		for i := range points {
+			gpsTimes[math.Float64frombits(binary.LittleEndian.Uint64(content[start+i*8:start+i*8+8]))] = true
+		}
+		for _, point := range points {
+			if !gpsTimes[point.GpsTime] {
+				t.Errorf("Expected GPS time %f in the batch table", point.GpsTime)
+			}
+		}
+		_ = os.RemoveAll(opts.Output)
+	}
+}
+
+func TestGpsTimeIsNotWrittenForPointsWithoutIt(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	writeTileset(t, newTestColorDepthPoints(), opts)
+	if _, ok := readTestBatchTable(t, filepath.Join(opts.Output, "content.pnts"))["GPS_TIME"]; ok {
+		t.Errorf("Expected no GPS_TIME property for points without GPS time")
+	}
+}
+
+// Returns 100 points whose GPS time equals their x coordinate
+func newTestGpsTimePoints() []*data.Point {
+	points := make([]*data.Point, 0)
+	for i := 0; i < 100; i++ {
+		point := data.NewPoint(float64(i), float64(i%7), float64(i%11), 0, 0, 0, 0, 0)
+		point.GpsTime = float64(i)
+		points = append(points, point)
+	}
+	return points
+}
@@ -0,0 +1,194 @@
+package test
+
+import (
+	"encoding/json"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/utils"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Root tileset of a global hierarchy whose first child loads a regional tileset and whose second child is an empty
+// slot with a child of its own
+const testGlobalTileset = `{
+	"asset": {"version": "1.0", "tilesetVersion": "2024"},
+	"geometricError": 1,
+	"root": {
+		"boundingVolume": {"region": [-1, -1, 1, 1, 0, 1]},
+		"geometricError": 1,
+		"refine": "ADD",
+		"extras": {"name": "world"},
+		"children": [
+			{"boundingVolume": {"region": [-1, -1, 0, 0, 0, 1]}, "geometricError": 0.5, "content": {"uri": "regions/south/tileset.json"}},
+			{"boundingVolume": {"region": [0, 0, 1, 1, 0, 1]}, "geometricError": 0.5, "refine": "REPLACE", "children": [
+				{"boundingVolume": {"region": [0, 0, 1, 1, 0, 1]}, "geometricError": 0, "content": {"uri": "north.pnts"}}
+			]}
+		]
+	}
+}`
+
+const testRegionalTileset = `{
+	"asset": {"version": "1.0"},
+	"geometricError": 0.5,
+	"root": {"boundingVolume": {"region": [-1, -1, 0, 0, 0, 1]}, "geometricError": 0.5, "refine": "ADD"}
+}`
+
+// Writes the global hierarchy in a new folder and returns the path of its root tileset.json
+func writeTestGlobalTileset(t *testing.T) string {
+	folder := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(folder, "regions", "south"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(folder, "tileset.json"), []byte(testGlobalTileset), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(folder, "regions", "south", "tileset.json"), []byte(testRegionalTileset), 0666); err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(folder, "tileset.json")
+}
+
+// Decodes the given tileset.json generically
+func readTestJson(t *testing.T, file string) map[string]interface{} {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := make(map[string]interface{})
+	if err := json.Unmarshal(content, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+// Fails the test unless the given generically decoded bounding region contains the given one
+func assertRegionContains(t *testing.T, name string, tile interface{}, region []float64) {
+	values := tile.(map[string]interface{})["boundingVolume"].(map[string]interface{})["region"].([]interface{})
+	for i := 0; i < 6; i++ {
+		value := values[i].(float64)
+		if (i%4 < 2 && value > region[i]) || (i%4 >= 2 && value < region[i]) {
+			t.Errorf("Expected the region of %s %v to contain %v", name, values, region)
+			return
+		}
+	}
+}
+
+func TestGraftTilesetInAnExternalTileset(t *testing.T) {
+	globalFile := writeTestGlobalTileset(t)
+	opts := newTestOptions(t)
+	opts.Output = filepath.Join(filepath.Dir(globalFile), "regions", "south", "new")
+	opts.MaxNumPointsPerNode = 100
+	writeTileset(t, newTestPoints(), opts)
+	newFile := filepath.Join(opts.Output, "tileset.json")
+	newTileset, err := io.ReadTilesetFile(newFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	region := newTileset.Root.BoundingVolume.Region
+
+	if err := io.GraftTileset(globalFile, []int{0, 0}, newFile, "v=2"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the new tileset is linked by a new child of the regional root
+	regional := readTestJson(t, filepath.Join(filepath.Dir(globalFile), "regions", "south", "tileset.json"))
+	regionalRoot := regional["root"].(map[string]interface{})
+	children := regionalRoot["children"].([]interface{})
+	if len(children) != 1 {
+		t.Fatalf("Expected a new child in the regional tileset, got %d children", len(children))
+	}
+	slot := children[0].(map[string]interface{})
+	if uri := slot["content"].(map[string]interface{})["uri"]; uri != "new/tileset.json?v=2" {
+		t.Errorf("Expected the slot to load new/tileset.json?v=2, got %v", uri)
+	}
+	if slot["geometricError"].(float64) != newTileset.GeometricError {
+		t.Errorf("Expected the slot geometric error %f, got %v", newTileset.GeometricError, slot["geometricError"])
+	}
+
+	// the ancestors contain the new tileset and the unknown properties are preserved
+	global := readTestJson(t, globalFile)
+	globalRoot := global["root"].(map[string]interface{})
+	assertRegionContains(t, "the slot", slot, region)
+	assertRegionContains(t, "the regional root", regionalRoot, region)
+	assertRegionContains(t, "the global child", globalRoot["children"].([]interface{})[0], region)
+	assertRegionContains(t, "the global root", globalRoot, region)
+	for _, tile := range []map[string]interface{}{regionalRoot, globalRoot, globalRoot["children"].([]interface{})[0].(map[string]interface{})} {
+		if tile["geometricError"].(float64) < newTileset.GeometricError {
+			t.Errorf("Expected the ancestor geometric errors to be at least %f, got %v", newTileset.GeometricError, tile["geometricError"])
+		}
+	}
+	if !reflect.DeepEqual(globalRoot["extras"], map[string]interface{}{"name": "world"}) || global["asset"].(map[string]interface{})["tilesetVersion"] != "2024" {
+		t.Errorf("Expected the unknown properties of the global tileset to be preserved")
+	}
+	if global["geometricError"].(float64) < globalRoot["geometricError"].(float64) {
+		t.Errorf("Expected the tileset geometric error to be at least the one of its root")
+	}
+
+	// the tiles of the new tileset are reachable from the global root
+	contents := make(map[string]int)
+	collectTileContents(t, filepath.Dir(globalFile), "tileset.json", 1, contents)
+	newContents := make(map[string]int)
+	collectTileContents(t, opts.Output, "tileset.json", 1, newContents)
+	for content := range newContents {
+		if _, ok := contents[filepath.ToSlash(filepath.Join("regions", "south", "new", content))]; !ok {
+			t.Errorf("Expected the tile %s of the new tileset under the slot", content)
+		}
+	}
+}
+
+func TestGraftTilesetReplacesAnExistingSlot(t *testing.T) {
+	globalFile := writeTestGlobalTileset(t)
+	opts := newTestOptions(t)
+	opts.Output = filepath.Join(filepath.Dir(globalFile), "north")
+	writeTileset(t, newTestPoints(), opts)
+
+	if err := io.GraftTileset(globalFile, []int{1}, filepath.Join(opts.Output, "tileset.json"), ""); err != nil {
+		t.Fatal(err)
+	}
+	slot := readTestJson(t, globalFile)["root"].(map[string]interface{})["children"].([]interface{})[1].(map[string]interface{})
+	if uri := slot["content"].(map[string]interface{})["uri"]; uri != "north/tileset.json" {
+		t.Errorf("Expected the slot to load north/tileset.json, got %v", uri)
+	}
+	if slot["refine"] != "REPLACE" || len(slot["children"].([]interface{})) != 1 {
+		t.Errorf("Expected the refinement and the children of the slot to be kept")
+	}
+}
+
+func TestGraftTilesetRejectsInvalidSlots(t *testing.T) {
+	globalFile := writeTestGlobalTileset(t)
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	writeTileset(t, newTestPoints(), opts)
+	original, err := os.ReadFile(globalFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, slot := range [][]int{{}, {3}, {1, 0, 1}, {0, 1}} {
+		if err := io.GraftTileset(globalFile, slot, filepath.Join(opts.Output, "tileset.json"), ""); err == nil {
+			t.Errorf("Expected slot %v to be rejected", slot)
+		}
+	}
+	if content, err := os.ReadFile(globalFile); err != nil || string(content) != string(original) {
+		t.Errorf("Expected the global tileset to be left unchanged")
+	}
+}
+
+func TestParseChildPath(t *testing.T) {
+	indexes, err := utils.ParseChildPath("/2/0/15/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(indexes, []int{2, 0, 15}) {
+		t.Errorf("Unexpected child path %v", indexes)
+	}
+	if indexes, err := utils.ParseChildPath(""); err != nil || len(indexes) != 0 {
+		t.Errorf("Expected an empty path, got %v, %v", indexes, err)
+	}
+	for _, invalid := range []string{"1/-1", "1/x", "1//2"} {
+		if _, err := utils.ParseChildPath(invalid); err == nil {
+			t.Errorf("Expected child path %s to be rejected", invalid)
+		}
+	}
+}
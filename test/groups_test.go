@@ -0,0 +1,111 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/app"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Sets the classification of the points of a LAS file written by writeTestLasFile
+func setTestLasClassifications(t *testing.T, file string, classifications []uint8) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, classification := range classifications {
+		b[227+i*20+15] = classification
+	}
+	if err := os.WriteFile(file, b, 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseClassificationGroups(t *testing.T) {
+	groups, err := tiler.ParseClassificationGroups("buildings:6:0.25;ground:2,9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 groups, got %d", len(groups))
+	}
+	if groups[0].Name != "buildings" || len(groups[0].Classifications) != 1 || groups[0].Classifications[0] != 6 || groups[0].GeometricErrorMultiplier != 0.25 {
+		t.Errorf("Unexpected buildings group %v", groups[0])
+	}
+	if groups[1].Name != "ground" || len(groups[1].Classifications) != 2 || groups[1].Classifications[1] != 9 || groups[1].GeometricErrorMultiplier != 1 {
+		t.Errorf("Unexpected ground group %v", groups[1])
+	}
+}
+
+func TestParseClassificationGroupsRejectsInvalidGroups(t *testing.T) {
+	for _, value := range []string{"buildings", "buildings:x", "buildings:6:0", "a:6;a:2", "other:6", ":6"} {
+		if _, err := tiler.ParseClassificationGroups(value); err == nil {
+			t.Errorf("Expected an error parsing %s", value)
+		}
+	}
+}
+
+func TestGeometricErrorMultiplierScalesGeometricError(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	writeTileset(t, newTestPoints(), opts)
+	scaledOpts := newTestOptions(t)
+	defer os.RemoveAll(scaledOpts.Output)
+	scaledOpts.GeometricErrorMultiplier = 0.25
+	writeTileset(t, newTestPoints(), scaledOpts)
+
+	tileset, err := io.ReadTilesetFile(filepath.Join(opts.Output, "tileset.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scaledTileset, err := io.ReadTilesetFile(filepath.Join(scaledOpts.Output, "tileset.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(scaledTileset.GeometricError-tileset.GeometricError*0.25) > 1e-9 {
+		t.Errorf("Expected geometric error %f, got %f", tileset.GeometricError*0.25, scaledTileset.GeometricError)
+	}
+}
+
+func TestClassificationGroupsAreTiledSeparately(t *testing.T) {
+	// the same points once as buildings, once as ground and once unclassified
+	rawPoints := make([][3]int32, 0)
+	classifications := make([]uint8, 0)
+	for _, classification := range []uint8{6, 2, 1} {
+		for j := 0; j < 100; j++ {
+			rawPoints = append(rawPoints, [3]int32{int32(j % 10), int32(j % 7), int32(j % 13)})
+			classifications = append(classifications, classification)
+		}
+	}
+	file := writeTestLasFile(t, testLasHeader{scale: [3]float64{1, 1, 1}}, rawPoints)
+	setTestLasClassifications(t, file, classifications)
+
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.Input = file
+	opts.ClassificationGroups = []tiler.ClassificationGroup{
+		{Name: "buildings", Classifications: []uint8{6}, GeometricErrorMultiplier: 0.25},
+		{Name: "ground", Classifications: []uint8{2}, GeometricErrorMultiplier: 1},
+	}
+	if err := app.RunTiler(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	geometricErrors := make(map[string]float64)
+	for _, group := range []string{"buildings", "ground", "other"} {
+		tileset, err := io.ReadTilesetFile(filepath.Join(opts.Output, "test", group, "tileset.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		geometricErrors[group] = tileset.GeometricError
+	}
+	if math.Abs(geometricErrors["buildings"]-geometricErrors["ground"]*0.25) > 1e-9 {
+		t.Errorf("Expected buildings geometric error %f, got %f", geometricErrors["ground"]*0.25, geometricErrors["buildings"])
+	}
+	if geometricErrors["other"] != geometricErrors["ground"] {
+		t.Errorf("Expected other geometric error %f, got %f", geometricErrors["ground"], geometricErrors["other"])
+	}
+}
@@ -0,0 +1,80 @@
+package test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	goio "io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGzipOutputRoundTripsToTheUncompressedTiles(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.MaxNumPointsPerNode = 50
+	tree := buildTree(t, newTestPoints(), opts)
+	exportTree(t, tree, opts)
+
+	gzipOpts := *opts
+	gzipOpts.Output = filepath.Join(opts.Output, "gzip")
+	gzipOpts.GzipOutput = true
+	exportTree(t, tree, &gzipOpts)
+
+	files := 0
+	err := filepath.Walk(opts.Output, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if filePath == gzipOpts.Output {
+			return filepath.SkipDir
+		}
+		if info.IsDir() {
+			return nil
+		}
+		files++
+		relative, err := filepath.Rel(opts.Output, filePath)
+		if err != nil {
+			return err
+		}
+		expected, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		compressed, err := os.ReadFile(filepath.Join(gzipOpts.Output, relative))
+		if err != nil {
+			return err
+		}
+		reader, err := gzip.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			t.Errorf("Expected %s gzip compressed: %v", relative, err)
+			return nil
+		}
+		actual, err := goio.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(actual, expected) {
+			t.Errorf("Expected the decompressed %s to match the uncompressed one", relative)
+		}
+		if filepath.Ext(filePath) == ".pnts" {
+			if err := io.ValidatePnts(actual); err != nil {
+				t.Errorf("Expected a valid decompressed %s: %v", relative, err)
+			}
+			if _, err := io.ReadPntsFile(filepath.Join(gzipOpts.Output, relative)); err != nil {
+				t.Errorf("Expected the gzip compressed %s to be readable: %v", relative, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files < 2 {
+		t.Errorf("Expected several tile files, got %d", files)
+	}
+	if err := io.ValidateRegionContainment(filepath.Join(gzipOpts.Output, "tileset.json")); err != nil {
+		t.Errorf("Expected the gzip compressed tilesets to be validated: %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package test
+
+import (
+	"context"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"github.com/mfbonfigli/gocesiumtiler/structs/octree"
+	"github.com/mfbonfigli/gocesiumtiler/structs/point_loader"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"os"
+	"sync"
+	"testing"
+)
+
+// Tiles the given points in the output folder specified in the options
+func writeTileset(t *testing.T, points []*data.Point, opts *tiler.TilerOptions) {
+	exportTree(t, buildTree(t, points, opts), opts)
+}
+
+// Builds an octree containing the given points, shuffled as per the random seed of the options
+func buildTree(t *testing.T, points []*data.Point, opts *tiler.TilerOptions) *octree.OctTree {
+	loader := point_loader.NewRandomLoader(0)
+	loader.SetRandomSeed(opts.RandomSeed)
+	for _, point := range points {
+		loader.AddElement(point)
+	}
+	tree := octree.NewOctTree(opts)
+	if err := tree.Build(loader); err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
+
+// Writes the tiles of the given octree in the output folder specified in the options
+func exportTree(t *testing.T, tree *octree.OctTree, opts *tiler.TilerOptions) {
+	var regions map[*octree.OctNode][]float64
+	if opts.EnforceRegionContainment {
+		var err error
+		regions, err = io.ComputeContainingRegions(&tree.RootNode, opts, opts.CoordinateConverter)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	workChannel := make(chan *io.WorkUnit, 10)
+	errs := io.NewConsumerErrors()
+	var waitGroup sync.WaitGroup
+	waitGroup.Add(2)
+	go io.Produce(context.Background(), opts.Output, &tree.RootNode, opts, workChannel, &waitGroup, "", regions)
+	go io.Consume(context.Background(), workChannel, errs, &waitGroup, opts.CoordinateConverter)
+	waitGroup.Wait()
+	if err := errs.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestOptions(t *testing.T) *tiler.TilerOptions {
+	output, err := os.MkdirTemp("", "gocesiumtiler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &tiler.TilerOptions{
+		Output:              output,
+		Srid:                4978,
+		MaxNumPointsPerNode: 1000,
+		CoordinateConverter: &identityCoordinateConverter{},
+	}
+}
+
+func newTestPoints() []*data.Point {
+	points := make([]*data.Point, 0)
+	for i := 0; i < 500; i++ {
+		points = append(points, data.NewPoint(float64(i%10), float64(i%7), float64(i%13), 0, 0, 0, 0, 0))
+	}
+	return points
+}
@@ -0,0 +1,421 @@
+package test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"github.com/mfbonfigli/gocesiumtiler/app"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/structs/octree"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	goio "io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Splits the given .subtree file content in its decoded json chunk and binary chunk
+func decodeSubtree(t *testing.T, content []byte) (map[string]interface{}, []byte) {
+	if string(content[0:4]) != "subt" || binary.LittleEndian.Uint32(content[4:8]) != 1 {
+		t.Fatalf("Invalid subtree header")
+	}
+	jsonLength := int(binary.LittleEndian.Uint64(content[8:16]))
+	binaryLength := int(binary.LittleEndian.Uint64(content[16:24]))
+	if jsonLength%8 != 0 || binaryLength%8 != 0 || 24+jsonLength+binaryLength != len(content) {
+		t.Fatalf("Invalid subtree chunk lengths %d, %d for %d bytes", jsonLength, binaryLength, len(content))
+	}
+	subtree := make(map[string]interface{})
+	if err := json.Unmarshal(content[24:24+jsonLength], &subtree); err != nil {
+		t.Fatal(err)
+	}
+	return subtree, content[24+jsonLength:]
+}
+
+func TestEncodeSubtreeUsesConstantsForUniformAvailability(t *testing.T) {
+	content, err := io.EncodeSubtree(io.SubtreeAvailability{
+		Tile:         []bool{true},
+		Content:      []bool{true},
+		ChildSubtree: make([]bool, 8),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	subtree, binaryBody := decodeSubtree(t, content)
+	if len(binaryBody) != 0 || subtree["buffers"] != nil {
+		t.Errorf("Expected no buffers")
+	}
+	if subtree["tileAvailability"].(map[string]interface{})["constant"] != 1.0 {
+		t.Errorf("Expected constant tile availability, got %v", subtree["tileAvailability"])
+	}
+	if subtree["childSubtreeAvailability"].(map[string]interface{})["constant"] != 0.0 {
+		t.Errorf("Expected constant child subtree availability, got %v", subtree["childSubtreeAvailability"])
+	}
+}
+
+func TestEncodeSubtreePacksBitstreams(t *testing.T) {
+	tiles := make([]bool, 9)
+	tiles[0] = true
+	tiles[1] = true
+	tiles[8] = true
+	content, err := io.EncodeSubtree(io.SubtreeAvailability{
+		Tile:         tiles,
+		Content:      tiles,
+		ChildSubtree: make([]bool, 64),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	subtree, binaryBody := decodeSubtree(t, content)
+	tileAvailability := subtree["tileAvailability"].(map[string]interface{})
+	if tileAvailability["bitstream"] != 0.0 || tileAvailability["availableCount"] != 3.0 {
+		t.Errorf("Unexpected tile availability %v", tileAvailability)
+	}
+	views := subtree["bufferViews"].([]interface{})
+	if len(views) != 2 {
+		t.Fatalf("Expected 2 buffer views, got %d", len(views))
+	}
+	second := views[1].(map[string]interface{})
+	if second["byteOffset"] != 8.0 || second["byteLength"] != 2.0 {
+		t.Errorf("Expected second buffer view aligned to 8 bytes, got %v", second)
+	}
+	if binaryBody[0] != 0x03 || binaryBody[1] != 0x01 {
+		t.Errorf("Expected bits packed least significant first, got %x %x", binaryBody[0], binaryBody[1])
+	}
+}
+
+func TestWriteSubtreeFiles(t *testing.T) {
+	opts := newTestOptions(t)
+	opts.MaxNumPointsPerNode = 20
+	defer os.RemoveAll(opts.Output)
+	tree := buildTree(t, newTestPoints(), opts)
+
+	if err := io.WriteSubtreeFiles(&tree.RootNode, opts.Output, 1, opts); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(filepath.Join(opts.Output, "subtrees", "0", "0", "0", "0.subtree"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subtree, _ := decodeSubtree(t, content)
+	if subtree["tileAvailability"].(map[string]interface{})["constant"] != 1.0 {
+		t.Errorf("Expected root tile to be available")
+	}
+	for i, child := range tree.RootNode.Children {
+		if child == nil || !child.Initialized {
+			continue
+		}
+		name := filepath.Join(opts.Output, "subtrees", "1", strconv.Itoa(i&1), strconv.Itoa(i>>1&1), strconv.Itoa(i>>2&1)+".subtree")
+		if _, err := os.Stat(name); err != nil {
+			t.Errorf("Expected child subtree file %s", name)
+		}
+	}
+}
+
+// Calls the given function on the given node and on all its initialized descendants
+func visitTestNodes(node *octree.OctNode, visit func(node *octree.OctNode)) {
+	visit(node)
+	for _, child := range node.Children {
+		if child != nil && child.Initialized {
+			visitTestNodes(child, visit)
+		}
+	}
+}
+
+func TestImplicitCoordinatesLocateTheOctreeCells(t *testing.T) {
+	for _, octantOrder := range [][]uint8{nil, {7, 6, 5, 4, 3, 2, 1, 0}} {
+		opts := newTestOptions(t)
+		opts.MaxNumPointsPerNode = 20
+		opts.OctantOrder = octantOrder
+		tree := buildTree(t, newTestPoints(), opts)
+		rootBox := tree.RootNode.BoundingBox
+		visitTestNodes(&tree.RootNode, func(node *octree.OctNode) {
+			level, x, y, z := node.GetImplicitCoordinates()
+			if level != int(node.Depth)-1 {
+				t.Errorf("Expected level %d for a node at depth %d, got %d", node.Depth-1, node.Depth, level)
+			}
+			cells := float64(int(1) << uint(level))
+			xmin := rootBox.Xmin + float64(x)*(rootBox.Xmax-rootBox.Xmin)/cells
+			ymin := rootBox.Ymin + float64(y)*(rootBox.Ymax-rootBox.Ymin)/cells
+			zmin := rootBox.Zmin + float64(z)*(rootBox.Zmax-rootBox.Zmin)/cells
+			box := node.BoundingBox
+			if math.Abs(xmin-box.Xmin) > 1e-9 || math.Abs(ymin-box.Ymin) > 1e-9 || math.Abs(zmin-box.Zmin) > 1e-9 {
+				t.Errorf("Expected cell %d/%d/%d/%d at %f, %f, %f with order %v, got %f, %f, %f", level, x, y, z, xmin, ymin, zmin, octantOrder, box.Xmin, box.Ymin, box.Zmin)
+			}
+		})
+		_ = os.RemoveAll(opts.Output)
+	}
+}
+
+func TestImplicitTilesetReferencesTheTileContents(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.MaxNumPointsPerNode = 20
+	opts.ImplicitTiling = true
+	opts.SubtreeLevels = 2
+	tree := buildTree(t, newTestPoints(), opts)
+	exportTree(t, tree, opts)
+	if err := io.WriteImplicitTileset(&tree.RootNode, opts.Output, opts, opts.CoordinateConverter, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(opts.Output, "tileset.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tileset := struct {
+		Asset struct {
+			Version string `json:"version"`
+		} `json:"asset"`
+		Root map[string]json.RawMessage `json:"root"`
+	}{}
+	if err := json.Unmarshal(content, &tileset); err != nil {
+		t.Fatal(err)
+	}
+	if tileset.Asset.Version != "1.1" {
+		t.Errorf("Expected a 3D Tiles 1.1 tileset, got version %s", tileset.Asset.Version)
+	}
+	if _, ok := tileset.Root["children"]; ok {
+		t.Errorf("Expected no explicit children in the implicit root tile")
+	}
+	var implicitTiling io.ImplicitTiling
+	if err := json.Unmarshal(tileset.Root["implicitTiling"], &implicitTiling); err != nil {
+		t.Fatal(err)
+	}
+	if implicitTiling.SubdivisionScheme != "OCTREE" || implicitTiling.SubtreeLevels != 2 || implicitTiling.AvailableLevels != int(tree.RootNode.GetTreeDepth()) {
+		t.Errorf("Unexpected implicit tiling %v for a tree of depth %d", implicitTiling, tree.RootNode.GetTreeDepth())
+	}
+	if implicitTiling.AvailableLevels < 3 {
+		t.Fatalf("Expected a tree with at least 3 levels, got %d", implicitTiling.AvailableLevels)
+	}
+	if implicitTiling.Subtrees.Url != "subtrees/{level}/{x}/{y}/{z}.subtree" {
+		t.Errorf("Unexpected subtrees template %s", implicitTiling.Subtrees.Url)
+	}
+	var rootContent io.Content
+	if err := json.Unmarshal(tileset.Root["content"], &rootContent); err != nil {
+		t.Fatal(err)
+	}
+	if rootContent.Url != "content/{level}/{x}/{y}/{z}/content.pnts" {
+		t.Errorf("Unexpected content template %s", rootContent.Url)
+	}
+
+	// every tile with points is stored at the url resolved from its implicit coordinates
+	tiles := 0
+	visitTestNodes(&tree.RootNode, func(node *octree.OctNode) {
+		level, x, y, z := node.GetImplicitCoordinates()
+		coordinates := []string{strconv.Itoa(level), strconv.Itoa(x), strconv.Itoa(y), strconv.Itoa(z)}
+		if level%implicitTiling.SubtreeLevels == 0 {
+			subtree := filepath.Join(opts.Output, "subtrees", filepath.Join(coordinates...)+".subtree")
+			if _, err := os.Stat(subtree); err != nil {
+				t.Errorf("Expected the subtree file %s", subtree)
+			}
+		}
+		if node.LocalChildrenCount == 0 {
+			return
+		}
+		tiles++
+		url := strings.NewReplacer("{level}", coordinates[0], "{x}", coordinates[1], "{y}", coordinates[2], "{z}", coordinates[3]).Replace(rootContent.Url)
+		pnts, err := io.ReadPntsFile(filepath.Join(opts.Output, filepath.FromSlash(url)))
+		if err != nil {
+			t.Errorf("Expected the content of tile %s: %v", url, err)
+			return
+		}
+		if pnts.FeatureTable.PointsLength != int(node.LocalChildrenCount) {
+			t.Errorf("Expected %d points in %s, got %d", node.LocalChildrenCount, url, pnts.FeatureTable.PointsLength)
+		}
+	})
+
+	// no other tile files are written
+	written, tilesets := 0, 0
+	err = filepath.Walk(opts.Output, func(file string, info os.FileInfo, err error) error {
+		if err == nil && info.Name() == "content.pnts" {
+			written++
+		}
+		if err == nil && info.Name() == "tileset.json" {
+			tilesets++
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written != tiles || tilesets != 1 {
+		t.Errorf("Expected %d tiles and a single tileset.json, got %d tiles and %d tileset.json files", tiles, written, tilesets)
+	}
+
+	// the coordinates of each content folder match the content availability bits of the subtrees
+	checkTestContentAvailability(t, opts.Output, implicitTiling.SubtreeLevels)
+}
+
+// Returns the availability bits of the given decoded subtree availability, either constant or a bitstream
+func decodeTestAvailability(subtree map[string]interface{}, binaryBody []byte, availability map[string]interface{}, length int) []bool {
+	bits := make([]bool, length)
+	if constant, ok := availability["constant"]; ok {
+		for i := range bits {
+			bits[i] = constant == 1.0
+		}
+		return bits
+	}
+	view := subtree["bufferViews"].([]interface{})[int(availability["bitstream"].(float64))].(map[string]interface{})
+	offset := int(view["byteOffset"].(float64))
+	for i := range bits {
+		bits[i] = binaryBody[offset+i/8]&(1<<uint(i%8)) != 0
+	}
+	return bits
+}
+
+// Returns the Morton index of the given x, y, z cell of the given level, interleaving their bits as x + 2y + 4z
+func testMortonIndex(level, x, y, z int) int {
+	morton := 0
+	for bit := level - 1; bit >= 0; bit-- {
+		morton = morton<<3 | (x>>uint(bit)&1 | (y>>uint(bit)&1)<<1 | (z>>uint(bit)&1)<<2)
+	}
+	return morton
+}
+
+// Checks that each content/{level}/{x}/{y}/{z} folder found in the given output folder is available in the content
+// availability of the subtree file covering it, and that no other content is available
+func checkTestContentAvailability(t *testing.T, output string, subtreeLevels int) {
+	folders := 0
+	err := filepath.Walk(filepath.Join(output, "content"), func(file string, info os.FileInfo, err error) error {
+		if err != nil || info.Name() != "content.pnts" {
+			return err
+		}
+		folders++
+		relative, err := filepath.Rel(filepath.Join(output, "content"), filepath.Dir(file))
+		if err != nil {
+			return err
+		}
+		coordinates := make([]int, 0, 4)
+		for _, token := range strings.Split(filepath.ToSlash(relative), "/") {
+			value, err := strconv.Atoi(token)
+			if err != nil {
+				t.Fatalf("Invalid content folder %s", relative)
+			}
+			coordinates = append(coordinates, value)
+		}
+		level, x, y, z := coordinates[0], coordinates[1], coordinates[2], coordinates[3]
+		relativeLevel := level % subtreeLevels
+		rootLevel := level - relativeLevel
+		subtreeFile := filepath.Join(output, "subtrees", strconv.Itoa(rootLevel), strconv.Itoa(x>>uint(relativeLevel)), strconv.Itoa(y>>uint(relativeLevel)), strconv.Itoa(z>>uint(relativeLevel))+".subtree")
+		content, err := os.ReadFile(subtreeFile)
+		if err != nil {
+			t.Errorf("Expected the subtree %s covering the content folder %s", subtreeFile, relative)
+			return nil
+		}
+		subtree, binaryBody := decodeSubtree(t, content)
+		tileCount := ((1 << (3 * uint(subtreeLevels))) - 1) / 7
+		bits := decodeTestAvailability(subtree, binaryBody, subtree["contentAvailability"].([]interface{})[0].(map[string]interface{}), tileCount)
+		mask := 1<<uint(relativeLevel) - 1
+		index := ((1<<(3*uint(relativeLevel)))-1)/7 + testMortonIndex(relativeLevel, x&mask, y&mask, z&mask)
+		if !bits[index] {
+			t.Errorf("Expected the content of %s available at bit %d of %s", relative, index, subtreeFile)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	available := 0
+	err = filepath.Walk(filepath.Join(output, "subtrees"), func(file string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		subtree, binaryBody := decodeSubtree(t, content)
+		tileCount := ((1 << (3 * uint(subtreeLevels))) - 1) / 7
+		for _, bit := range decodeTestAvailability(subtree, binaryBody, subtree["contentAvailability"].([]interface{})[0].(map[string]interface{}), tileCount) {
+			if bit {
+				available++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if available != folders {
+		t.Errorf("Expected %d available contents, one per content folder, got %d", folders, available)
+	}
+}
+
+func TestImplicitTilesetIsWrittenThroughTheTileWriter(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.MaxNumPointsPerNode = 20
+	opts.ImplicitTiling = true
+	opts.SubtreeLevels = 1
+	opts.GzipOutput = true
+	writer := newMemoryTileWriter()
+	opts.TileWriter = writer
+	tree := buildTree(t, newTestPoints(), opts)
+	exportTree(t, tree, opts)
+	if err := io.WriteImplicitTileset(&tree.RootNode, opts.Output, opts, opts.CoordinateConverter, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if entries, err := os.ReadDir(opts.Output); err != nil || len(entries) > 0 {
+		t.Errorf("Expected nothing written on disk using a custom TileWriter, got %v", entries)
+	}
+	if _, ok := writer.files[filepath.Join(opts.Output, "tileset.json")]; !ok {
+		t.Errorf("Expected the tileset.json written through the TileWriter")
+	}
+	subtrees := 0
+	visitTestNodes(&tree.RootNode, func(node *octree.OctNode) {
+		level, x, y, z := node.GetImplicitCoordinates()
+		file := filepath.Join(opts.Output, "subtrees", strconv.Itoa(level), strconv.Itoa(x), strconv.Itoa(y), strconv.Itoa(z)+".subtree")
+		compressed, ok := writer.files[file]
+		if !ok {
+			t.Errorf("Expected the subtree %s written through the TileWriter", file)
+			return
+		}
+		subtrees++
+		if !writer.folders[filepath.Dir(file)] {
+			t.Errorf("Expected the folder of %s created through the TileWriter", file)
+		}
+		reader, err := gzip.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			t.Errorf("Expected the subtree %s gzip compressed: %v", file, err)
+			return
+		}
+		content, err := goio.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decodeSubtree(t, content)
+	})
+	if subtrees < 3 {
+		t.Errorf("Expected at least 3 subtrees, got %d", subtrees)
+	}
+}
+
+func TestImplicitTilingOfUnsupportedOptionsIsAnError(t *testing.T) {
+	file := writeTestLasFile(t, testLasHeader{scale: [3]float64{1, 1, 1}}, [][3]int32{{0, 0, 0}, {1, 1, 1}, {2, 2, 2}})
+	defer os.RemoveAll(filepath.Dir(file))
+	for name, configure := range map[string]func(opts *tiler.TilerOptions){
+		"oriented boxes":  func(opts *tiler.TilerOptions) { opts.BoundingVolumes = tiler.OrientedBoundingBoxes },
+		"raw coordinates": func(opts *tiler.TilerOptions) { opts.OutputMode = tiler.RawCoordinates },
+		"tight bounds":    func(opts *tiler.TilerOptions) { opts.TightBounds = true },
+		"subtree path":    func(opts *tiler.TilerOptions) { opts.SubtreePath = []uint8{0} },
+	} {
+		opts := newTestOptions(t)
+		opts.Input = file
+		opts.ImplicitTiling = true
+		configure(opts)
+		if err := app.RunTiler(opts); err == nil || !strings.Contains(err.Error(), "implicit tiling cannot be used") {
+			t.Errorf("Expected the implicit tiling with %s to be rejected, got %v", name, err)
+		}
+		if _, err := app.New(opts).Run(context.Background(), file, opts.Output); err == nil {
+			t.Errorf("Expected the implicit tiling with %s to be rejected by the facade", name)
+		}
+		os.RemoveAll(opts.Output)
+	}
+}
@@ -0,0 +1,121 @@
+package test
+
+import (
+	"encoding/binary"
+	"github.com/mfbonfigli/gocesiumtiler/app"
+	"github.com/mfbonfigli/gocesiumtiler/lasread"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Writes a LAS file with the given intensities of its points, spread along a line
+func writeTestIntensityLasFile(t *testing.T, intensities []uint16) string {
+	rawPoints := make([][3]int32, len(intensities))
+	for i := range rawPoints {
+		rawPoints[i] = [3]int32{int32(i), int32(i % 7), int32(i % 11)}
+	}
+	file := writeTestLasFile(t, testLasHeader{scale: [3]float64{1, 1, 1}}, rawPoints)
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the 20 bytes records of point format 0 store the intensity after the coordinates
+	pointsOffset := len(content) - 20*len(intensities)
+	for i, intensity := range intensities {
+		binary.LittleEndian.PutUint16(content[pointsOffset+20*i+12:], intensity)
+	}
+	if err := os.WriteFile(file, content, 0666); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// Returns 101 intensities evenly spaced from the given min to the given max
+func testIntensityRange(min, max uint16) []uint16 {
+	intensities := make([]uint16, 101)
+	for i := range intensities {
+		intensities[i] = min + uint16(int(max-min)*i/100)
+	}
+	return intensities
+}
+
+func TestIntensityScalingModes(t *testing.T) {
+	none, err := tiler.ParseIntensityScaling("none")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixed, err := tiler.ParseIntensityScaling("1000, 2000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		scaling   tiler.IntensityScaling
+		intensity uint16
+		expected  uint8
+	}{
+		{none, 0, 0}, {none, 1000, 3}, {none, 65535, 255},
+		{fixed, 500, 0}, {fixed, 1000, 0}, {fixed, 1500, 128}, {fixed, 2000, 255}, {fixed, 65535, 255},
+	} {
+		if scaled := c.scaling.Scale(c.intensity); scaled != c.expected {
+			t.Errorf("Expected intensity %d scaled to %d by %v, got %d", c.intensity, c.expected, c.scaling, scaled)
+		}
+	}
+	if auto, err := tiler.ParseIntensityScaling("auto"); err != nil || auto.Mode != tiler.AutoStretchIntensityScaling {
+		t.Errorf("Expected the auto stretch mode, got %v, %v", auto, err)
+	}
+	for _, invalid := range []string{"2000,1000", "0,70000", "x", "1,2,3"} {
+		if _, err := tiler.ParseIntensityScaling(invalid); err == nil {
+			t.Errorf("Expected an error parsing the intensity scaling %s", invalid)
+		}
+	}
+}
+
+func TestIntensityRangeIsReadFromThePointRecords(t *testing.T) {
+	file := writeTestIntensityLasFile(t, testIntensityRange(1000, 1500))
+	defer os.RemoveAll(filepath.Dir(file))
+	min, max, err := lidario.ReadIntensityRange(file, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if min != 1000 || max != 1500 {
+		t.Errorf("Expected the intensity range 1000-1500, got %d-%d", min, max)
+	}
+}
+
+func TestAutoStretchUsesTheSameScaleForAllTheFiles(t *testing.T) {
+	files := []string{writeTestIntensityLasFile(t, testIntensityRange(1000, 1500)), writeTestIntensityLasFile(t, testIntensityRange(1500, 2000))}
+	jobs := make([]app.BatchJob, len(files))
+	for i, file := range files {
+		defer os.RemoveAll(filepath.Dir(file))
+		jobs[i] = app.BatchJob{Input: file, Output: filepath.Dir(file)}
+	}
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.IntensityScaling = tiler.IntensityScaling{Mode: tiler.AutoStretchIntensityScaling}
+	if err := app.RunBatchTiler(jobs, opts, 2); err != nil {
+		t.Fatal(err)
+	}
+	if opts.IntensityScaling.Mode != tiler.AutoStretchIntensityScaling {
+		t.Errorf("Expected the options not to be changed, got %v", opts.IntensityScaling)
+	}
+
+	// the first file is stretched to the lower half of the range, the second one to the upper half
+	for i, expected := range [][2]uint8{{0, 128}, {128, 255}} {
+		contentFile := filepath.Join(jobs[i].Output, "test", "content.pnts")
+		intensities := readTestBatchTableBytes(t, contentFile, readTestBatchTable(t, contentFile), "INTENSITY", 101)
+		min, max := uint8(255), uint8(0)
+		for _, intensity := range intensities {
+			if intensity < min {
+				min = intensity
+			}
+			if intensity > max {
+				max = intensity
+			}
+		}
+		if min != expected[0] || max != expected[1] {
+			t.Errorf("Expected the intensities of file %d stretched to %d-%d, got %d-%d", i, expected[0], expected[1], min, max)
+		}
+	}
+}
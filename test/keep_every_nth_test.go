@@ -0,0 +1,83 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/converters/offset_elevation_corrector"
+	"github.com/mfbonfigli/gocesiumtiler/lasread"
+	"github.com/mfbonfigli/gocesiumtiler/structs/point_loader"
+	"github.com/mfbonfigli/gocesiumtiler/textread"
+	"github.com/mfbonfigli/gocesiumtiler/utils"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// Reads the given LAS file keeping every given number of points, in batches of the given max size in bytes on a pool
+// of the given number of goroutines. Returns the sorted X coordinates of the loaded points
+func readTestLasFileKeepingEveryNth(t *testing.T, file string, keepEveryNth int, readBufferSize int, goroutines int) []float64 {
+	pool := utils.NewWorkerPool(goroutines)
+	defer pool.Close()
+	loader := point_loader.NewRandomLoader(0)
+	lasFileLoader := lidario.NewLasFileLoader(&identityCoordinateConverter{}, nil, loader, pool, false)
+	lasFileLoader.ReadBufferSize = readBufferSize
+	lasFileLoader.KeepEveryNth = keepEveryNth
+	lf, err := lasFileLoader.LoadLasFile(file, offset_elevation_corrector.NewOffsetElevationCorrector(0), 4326)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = lf.Close()
+
+	xs := make([]float64, 0)
+	loader.Initialize()
+	for {
+		point, shouldContinue := loader.GetNext()
+		if point != nil {
+			xs = append(xs, point.X)
+		}
+		if !shouldContinue {
+			break
+		}
+	}
+	sort.Float64s(xs)
+	return xs
+}
+
+func TestKeepEveryNthStrideIsIndependentOfThePartitioning(t *testing.T) {
+	rawPoints := make([][3]int32, 100)
+	for i := range rawPoints {
+		rawPoints[i] = [3]int32{int32(i), 0, 0}
+	}
+	file := writeTestLasFile(t, testLasHeader{scale: [3]float64{1, 1, 1}}, rawPoints)
+	defer os.RemoveAll(filepath.Dir(file))
+
+	for _, c := range []struct{ readBufferSize, goroutines int }{{0, 1}, {0, 4}, {7 * 20, 3}, {20, 2}} {
+		xs := readTestLasFileKeepingEveryNth(t, file, 3, c.readBufferSize, c.goroutines)
+		if len(xs) != 34 {
+			t.Fatalf("Expected 34 points reading batches of %d bytes on %d goroutines, got %d", c.readBufferSize, c.goroutines, len(xs))
+		}
+		for i, x := range xs {
+			if x != float64(3*i) {
+				t.Errorf("Expected the point %d reading batches of %d bytes on %d goroutines, got %f", 3*i, c.readBufferSize, c.goroutines, x)
+			}
+		}
+	}
+	for _, keepEveryNth := range []int{0, 1} {
+		if xs := readTestLasFileKeepingEveryNth(t, file, keepEveryNth, 0, 4); len(xs) != 100 {
+			t.Errorf("Expected all the 100 points keeping every %d points, got %d", keepEveryNth, len(xs))
+		}
+	}
+}
+
+func TestKeepEveryNthSkipsTextPoints(t *testing.T) {
+	file := writeTestTextFile(t, "test.xyz", "x y z\n0 0 0\n1 1 1\n2 2 2\n3 3 3\n4 4 4\n5 5 5\n")
+	defer os.RemoveAll(filepath.Dir(file))
+	loader := point_loader.NewRandomLoader(0)
+	textFileLoader := textread.NewTextFileLoader(&identityCoordinateConverter{}, loader, nil, "")
+	textFileLoader.KeepEveryNth = 2
+	if err := textFileLoader.LoadTextFile(file, offset_elevation_corrector.NewOffsetElevationCorrector(0), 4326); err != nil {
+		t.Fatal(err)
+	}
+	if bounds := loader.GetBounds(); bounds[0] != 0 || bounds[1] != 4 {
+		t.Errorf("Expected the points 0, 2 and 4 to be loaded, got bounds %v", bounds)
+	}
+}
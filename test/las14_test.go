@@ -0,0 +1,184 @@
+package test
+
+import (
+	"encoding/binary"
+	"github.com/mfbonfigli/gocesiumtiler/converters/offset_elevation_corrector"
+	"github.com/mfbonfigli/gocesiumtiler/lasread"
+	"github.com/mfbonfigli/gocesiumtiler/structs/point_loader"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Attributes of a point of the synthetic LAS files with point formats other than 0
+type testLasPoint struct {
+	raw            [3]int32
+	intensity      uint16
+	classification uint8
+	rgb            [3]uint16
+	gpsTime        float64
+	returns        byte // Return number and number of returns bit fields, 0 for return 1 of 1
+	pointSourceId  uint16
+	overlap        bool // Overlap flag of point formats 6-10
+}
+
+// Writes a LAS 1.<versionMinor> file with unit scale, storing the given points with the given point format and
+// record length. LAS 1.4 files get the 375 bytes header with the 64 bit number of points and a zero legacy one.
+// Returns the path of the written file.
+func writeTestLasRecords(t *testing.T, versionMinor byte, pointFormat byte, recordLength int, points []testLasPoint) string {
+	headerSize := 227
+	if versionMinor >= 4 {
+		headerSize = 375
+	}
+	b := make([]byte, headerSize+len(points)*recordLength)
+	copy(b[0:4], "LASF")
+	b[24] = 1
+	b[25] = versionMinor
+	binary.LittleEndian.PutUint16(b[94:96], uint16(headerSize))
+	binary.LittleEndian.PutUint32(b[96:100], uint32(headerSize))
+	b[104] = pointFormat
+	binary.LittleEndian.PutUint16(b[105:107], uint16(recordLength))
+	if versionMinor >= 4 {
+		binary.LittleEndian.PutUint64(b[247:255], uint64(len(points)))
+	} else {
+		binary.LittleEndian.PutUint32(b[107:111], uint32(len(points)))
+	}
+	for i := 0; i < 3; i++ {
+		binary.LittleEndian.PutUint64(b[131+i*8:139+i*8], 0x3ff0000000000000) // scale 1.0
+	}
+
+	for i, point := range points {
+		record := b[headerSize+i*recordLength : headerSize+(i+1)*recordLength]
+		for j := 0; j < 3; j++ {
+			binary.LittleEndian.PutUint32(record[j*4:j*4+4], uint32(point.raw[j]))
+		}
+		binary.LittleEndian.PutUint16(record[12:14], point.intensity)
+		rgbOffset, gpsTimeOffset := -1, -1
+		if pointFormat >= 6 {
+			gpsTimeOffset = 22
+			record[14] = 0x11 // return 1 of 1
+			if point.returns != 0 {
+				record[14] = point.returns
+			}
+			record[16] = point.classification
+			if point.overlap {
+				record[15] = 0x08
+			}
+			binary.LittleEndian.PutUint16(record[20:22], point.pointSourceId)
+			if pointFormat == 7 || pointFormat == 8 || pointFormat == 10 {
+				rgbOffset = 30
+			}
+		} else {
+			record[14] = 0x09 // return 1 of 1
+			if point.returns != 0 {
+				record[14] = point.returns
+			}
+			record[15] = point.classification
+			binary.LittleEndian.PutUint16(record[18:20], point.pointSourceId)
+			if pointFormat == 2 {
+				rgbOffset = 20
+			} else if pointFormat == 3 {
+				rgbOffset = 28
+			}
+			if pointFormat == 1 || pointFormat == 3 {
+				gpsTimeOffset = 20
+			}
+		}
+		if gpsTimeOffset >= 0 {
+			binary.LittleEndian.PutUint64(record[gpsTimeOffset:gpsTimeOffset+8], math.Float64bits(point.gpsTime))
+		}
+		if rgbOffset >= 0 {
+			for j := 0; j < 3; j++ {
+				binary.LittleEndian.PutUint16(record[rgbOffset+j*2:rgbOffset+j*2+2], point.rgb[j])
+			}
+		}
+	}
+
+	folder, err := os.MkdirTemp("", "gocesiumtiler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(folder, "test.las")
+	if err := os.WriteFile(file, b, 0666); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func newTestLasPoints() []testLasPoint {
+	return []testLasPoint{
+		{raw: [3]int32{1, 2, 3}, intensity: 256 * 10, classification: 2, rgb: [3]uint16{256 * 255, 256 * 128, 0}},
+		{raw: [3]int32{-4, 5, -6}, intensity: 256 * 200, classification: 6, rgb: [3]uint16{0, 256 * 64, 256 * 32}},
+		{raw: [3]int32{7, -8, 9}, intensity: 0, classification: 40, rgb: [3]uint16{256*1 + 1, 256*2 + 2, 256*3 + 3}},
+	}
+}
+
+// Reads the given LAS file and checks that the decoded points match the given ones
+func assertLasPointsAreDecoded(t *testing.T, file string, expected []testLasPoint, withColors bool) {
+	points := readTestLasFile(t, file)
+	if len(points) != len(expected) {
+		t.Fatalf("Expected %d points, got %d", len(expected), len(points))
+	}
+	for _, e := range expected {
+		found := false
+		for _, point := range points {
+			if point.X != float64(e.raw[0]) || point.Y != float64(e.raw[1]) || point.Z != float64(e.raw[2]) {
+				continue
+			}
+			found = true
+			var r, g, b uint16
+			if withColors {
+				r, g, b = e.rgb[0], e.rgb[1], e.rgb[2]
+			}
+			if point.Classification != e.classification || point.Intensity != uint8(e.intensity/256) ||
+				point.R != r || point.G != g || point.B != b {
+				t.Errorf("Expected classification %d, intensity %d, color %d %d %d, got %v", e.classification, e.intensity/256, r, g, b, point)
+			}
+		}
+		if !found {
+			t.Errorf("Expected a point at %v", e.raw)
+		}
+	}
+}
+
+func TestLas14PointFormat6IsDecoded(t *testing.T) {
+	file := writeTestLasRecords(t, 4, 6, 30, newTestLasPoints())
+	defer os.RemoveAll(filepath.Dir(file))
+	assertLasPointsAreDecoded(t, file, newTestLasPoints(), false)
+}
+
+func TestLas14PointFormat7IsDecoded(t *testing.T) {
+	file := writeTestLasRecords(t, 4, 7, 36, newTestLasPoints())
+	defer os.RemoveAll(filepath.Dir(file))
+	assertLasPointsAreDecoded(t, file, newTestLasPoints(), true)
+}
+
+func TestLas14PointFormat8WithExtraBytesIsDecoded(t *testing.T) {
+	file := writeTestLasRecords(t, 4, 8, 42, newTestLasPoints())
+	defer os.RemoveAll(filepath.Dir(file))
+	assertLasPointsAreDecoded(t, file, newTestLasPoints(), true)
+}
+
+func TestLegacyPointFormatsAreDecoded(t *testing.T) {
+	points := newTestLasPoints()
+	for i := range points {
+		// legacy classifications are limited to 5 bits
+		points[i].classification %= 32
+	}
+	for format, recordLength := range map[byte]int{2: 26, 3: 34} {
+		file := writeTestLasRecords(t, 2, format, recordLength, points)
+		assertLasPointsAreDecoded(t, file, points, true)
+		_ = os.RemoveAll(filepath.Dir(file))
+	}
+}
+
+func TestExtendedPointFormatRequiresLas14(t *testing.T) {
+	file := writeTestLasRecords(t, 2, 6, 30, newTestLasPoints())
+	defer os.RemoveAll(filepath.Dir(file))
+
+	loader := lidario.NewLasFileLoader(&identityCoordinateConverter{}, nil, point_loader.NewRandomLoader(0), nil, false)
+	if _, err := loader.LoadLasFile(file, offset_elevation_corrector.NewOffsetElevationCorrector(0), 4326); err == nil {
+		t.Errorf("Expected an error for point format 6 in a LAS 1.2 file, got nil")
+	}
+}
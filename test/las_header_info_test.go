@@ -0,0 +1,50 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/lasread"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLasHeaderInfoIsReadWithoutThePoints(t *testing.T) {
+	rawPoints := make([][3]int32, 1000)
+	for i := range rawPoints {
+		rawPoints[i] = [3]int32{int32(i), int32(2 * i), int32(i % 10)}
+	}
+	header := testLasHeader{
+		scale:   [3]float64{0.01, 0.01, 0.01},
+		offset:  [3]float64{600000, 5000000, 100},
+		extent:  [6]float64{600009.99, 600000, 5000019.98, 5000000, 100.09, 100},
+		geoKeys: testGeoKeys(3072, 32633),
+	}
+	file := writeTestLasFile(t, header, rawPoints)
+	defer os.RemoveAll(filepath.Dir(file))
+	// the point records are not read, the file is truncated right after the VLRs
+	info, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(file, info.Size()-int64(len(rawPoints))*20); err != nil {
+		t.Fatal(err)
+	}
+
+	lasHeader, err := lidario.ReadLasHeaderInfo(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lasHeader.NumberPoints != len(rawPoints) {
+		t.Errorf("Expected %d points, got %d", len(rawPoints), lasHeader.NumberPoints)
+	}
+	extent := [6]float64{lasHeader.MaxX, lasHeader.MinX, lasHeader.MaxY, lasHeader.MinY, lasHeader.MaxZ, lasHeader.MinZ}
+	if extent != header.extent {
+		t.Errorf("Expected the extent %v, got %v", header.extent, extent)
+	}
+	if lasHeader.Epsg != 32633 {
+		t.Errorf("Expected the EPSG code 32633, got %d", lasHeader.Epsg)
+	}
+
+	if _, err := lidario.ReadLasHeaderInfo(filepath.Join(filepath.Dir(file), "missing.las")); err == nil {
+		t.Errorf("Expected an error reading a missing file")
+	}
+}
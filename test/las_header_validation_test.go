@@ -0,0 +1,98 @@
+package test
+
+import (
+	"context"
+	"github.com/mfbonfigli/gocesiumtiler/app"
+	"github.com/mfbonfigli/gocesiumtiler/converters/offset_elevation_corrector"
+	"github.com/mfbonfigli/gocesiumtiler/lasread"
+	"github.com/mfbonfigli/gocesiumtiler/structs/point_loader"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Reads the given LAS file without any coordinate conversion, returning the error of the reader
+func loadTestLasFile(file string) error {
+	lasFileLoader := lidario.NewLasFileLoader(&identityCoordinateConverter{}, nil, point_loader.NewRandomLoader(0), nil, false)
+	lf, err := lasFileLoader.LoadLasFile(file, offset_elevation_corrector.NewOffsetElevationCorrector(0), 4326)
+	if lf != nil {
+		_ = lf.Close()
+	}
+	return err
+}
+
+func TestZeroPointLasFileProducesAnEmptyResult(t *testing.T) {
+	for _, versionMinor := range []byte{2, 4} {
+		file := writeTestLasRecords(t, versionMinor, 0, 20, nil)
+		defer os.RemoveAll(filepath.Dir(file))
+		if read := readTestLasFile(t, file); len(read) != 0 {
+			t.Errorf("Expected no points read from the LAS 1.%d file, got %d", versionMinor, len(read))
+		}
+		if min, max, err := lidario.ReadIntensityRange(file, 0); err != nil || min <= max {
+			t.Errorf("Expected an empty intensity range for the LAS 1.%d file, got %d-%d, %v", versionMinor, min, max, err)
+		}
+
+		opts := newTestOptions(t)
+		defer os.RemoveAll(opts.Output)
+		result, err := app.New(opts).Run(context.Background(), file, filepath.Join(filepath.Dir(file), "output"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.TotalPoints != 0 || result.TilesWritten != 0 {
+			t.Errorf("Expected no points and no tiles for the LAS 1.%d file, got %d and %d", versionMinor, result.TotalPoints, result.TilesWritten)
+		}
+	}
+}
+
+func TestTruncatedLasFileHeaderIsClampedToTheStoredRecords(t *testing.T) {
+	points := []testLasPoint{{raw: [3]int32{1, 2, 3}, intensity: 10}, {raw: [3]int32{4, 5, 6}, intensity: 20}}
+	file := writeTestLasRecords(t, 2, 0, 20, points)
+	defer os.RemoveAll(filepath.Dir(file))
+
+	// a record and a half, then no records at all
+	for _, c := range []struct {
+		size     int64
+		expected int
+	}{{227 + 30, 1}, {227, 0}} {
+		if err := os.Truncate(file, c.size); err != nil {
+			t.Fatal(err)
+		}
+		if read := readTestLasFile(t, file); len(read) != c.expected {
+			t.Errorf("Expected %d points read from the file truncated to %d bytes, got %d", c.expected, c.size, len(read))
+		}
+		min, max, err := lidario.ReadIntensityRange(file, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.expected == 1 && (min != 10 || max != 10) {
+			t.Errorf("Expected the intensity range of the complete record only, got %d-%d", min, max)
+		}
+	}
+}
+
+func TestUnknownLasPointRecordLengthsAreRejected(t *testing.T) {
+	for _, c := range []struct {
+		versionMinor, pointFormat byte
+		recordLength              int
+		message                   string
+	}{
+		{2, 0, 21, "unsupported point record length 21"},
+		{2, 3, 28, "unsupported point record length 28"},
+		{2, 1, 0, "unsupported point record length 0"},
+		{4, 6, 0, "too short"},
+		{4, 4, 57, "unsupported LAS point format 4"},
+	} {
+		file := writeTestLasRecords(t, c.versionMinor, c.pointFormat, c.recordLength, nil)
+		defer os.RemoveAll(filepath.Dir(file))
+		if err := loadTestLasFile(file); err == nil || !strings.Contains(err.Error(), c.message) {
+			t.Errorf("Expected an error containing %q for point format %d with record length %d, got %v", c.message, c.pointFormat, c.recordLength, err)
+		}
+		if _, _, err := lidario.ReadIntensityRange(file, 0); err == nil {
+			t.Errorf("Expected an error reading the intensity range for point format %d with record length %d", c.pointFormat, c.recordLength)
+		}
+		if _, err := lidario.NewLasFile(file, "r"); err == nil {
+			t.Errorf("Expected an error reading point format %d with record length %d into a LasFile", c.pointFormat, c.recordLength)
+		}
+	}
+}
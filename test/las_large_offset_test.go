@@ -0,0 +1,92 @@
+package test
+
+import (
+	"encoding/binary"
+	"github.com/mfbonfigli/gocesiumtiler/lasread"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Writes a sparse LAS 1.2 file with point format 0 and unit scale, storing a WKT VLR right after the header and the
+// given raw points at the given file offset, past the 2 GB boundary if large. Only the written bytes take disk space.
+// Returns the path of the written file
+func writeTestSparseLasFile(t *testing.T, pointsOffset int64, wkt string, rawPoints [][3]int32) string {
+	const headerSize = 227
+	const recordLength = 20
+	header := make([]byte, headerSize)
+	copy(header[0:4], "LASF")
+	header[24] = 1
+	header[25] = 2
+	binary.LittleEndian.PutUint16(header[94:96], headerSize)
+	binary.LittleEndian.PutUint32(header[96:100], uint32(pointsOffset))
+	binary.LittleEndian.PutUint32(header[100:104], 1)
+	binary.LittleEndian.PutUint16(header[105:107], recordLength)
+	binary.LittleEndian.PutUint32(header[107:111], uint32(len(rawPoints)))
+	for i := 0; i < 3; i++ {
+		binary.LittleEndian.PutUint64(header[131+i*8:139+i*8], 0x3ff0000000000000) // scale 1.0
+	}
+	vlr := make([]byte, 54)
+	copy(vlr[2:18], "LASF_Projection")
+	binary.LittleEndian.PutUint16(vlr[18:20], 2112)
+	binary.LittleEndian.PutUint16(vlr[20:22], uint16(len(wkt)+1))
+	vlr = append(append(vlr, wkt...), 0)
+	records := make([]byte, len(rawPoints)*recordLength)
+	for i, point := range rawPoints {
+		for j := 0; j < 3; j++ {
+			binary.LittleEndian.PutUint32(records[i*recordLength+j*4:], uint32(point[j]))
+		}
+	}
+
+	folder, err := os.MkdirTemp("", "gocesiumtiler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(folder, "sparse.las")
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	for _, part := range []struct {
+		offset int64
+		data   []byte
+	}{{0, header}, {headerSize, vlr}, {pointsOffset, records}} {
+		if _, err := f.WriteAt(part.data, part.offset); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return file
+}
+
+func TestLasPointsPastThe2GBBoundaryAreRead(t *testing.T) {
+	// the points start 3 GB into the file, past the largest 32 bit signed offset
+	const pointsOffset = int64(3) << 30
+	rawPoints := [][3]int32{{1, 2, 3}, {4, 5, 6}, {-7, 8, -9}}
+	file := writeTestSparseLasFile(t, pointsOffset, "LOCAL_CS[\"test\"]", rawPoints)
+	defer os.RemoveAll(filepath.Dir(file))
+
+	header, err := lidario.ReadLasHeaderInfo(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.OffsetToPoints != pointsOffset || header.Wkt != "LOCAL_CS[\"test\"]" {
+		t.Errorf("Expected the points at offset %d and the WKT VLR read, got offset %d and WKT %q", pointsOffset, header.OffsetToPoints, header.Wkt)
+	}
+
+	for _, readBufferSize := range []int{0, 20, 40} {
+		read := readTestLasFileInBatches(t, file, readBufferSize)
+		if len(read) != len(rawPoints) {
+			t.Fatalf("Expected %d points with read buffer size %d, got %d", len(rawPoints), readBufferSize, len(read))
+		}
+		found := make(map[[3]float64]bool)
+		for _, point := range read {
+			found[[3]float64{point.X, point.Y, point.Z}] = true
+		}
+		for _, raw := range rawPoints {
+			if !found[[3]float64{float64(raw[0]), float64(raw[1]), float64(raw[2])}] {
+				t.Errorf("Expected the point %v read with read buffer size %d", raw, readBufferSize)
+			}
+		}
+	}
+}
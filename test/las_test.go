@@ -0,0 +1,281 @@
+package test
+
+import (
+	"encoding/binary"
+	"github.com/mfbonfigli/gocesiumtiler/converters/offset_elevation_corrector"
+	"github.com/mfbonfigli/gocesiumtiler/lasread"
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"github.com/mfbonfigli/gocesiumtiler/structs/point_loader"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Header values of the synthetic LAS 1.2 files generated by the tests
+type testLasHeader struct {
+	scale      [3]float64
+	offset     [3]float64
+	wkt        string           // if not empty, stored in a coordinate system WKT VLR
+	geoKeys    []uint16         // if not empty, stored in a GeoKeyDirectoryTag VLR
+	classNames map[uint8]string // if not empty, stored in a Classification Lookup VLR
+	extent     [6]float64       // MaxX, MinX, MaxY, MinY, MaxZ, MinZ stored in the header
+}
+
+// Writes a LAS 1.2 file with point format 0 storing the given raw (unscaled) X, Y, Z values. Returns the path of the
+// written file.
+func writeTestLasFile(t testing.TB, header testLasHeader, rawPoints [][3]int32) string {
+	const headerSize = 227
+	const vlrHeaderSize = 54
+	const recordLength = 20
+	vlrs := make([]byte, 0)
+	vlrCount := 0
+	if header.wkt != "" {
+		vlr := make([]byte, vlrHeaderSize)
+		copy(vlr[2:18], "LASF_Projection")
+		binary.LittleEndian.PutUint16(vlr[18:20], 2112)
+		binary.LittleEndian.PutUint16(vlr[20:22], uint16(len(header.wkt)+1))
+		vlrs = append(append(append(vlrs, vlr...), header.wkt...), 0)
+		vlrCount++
+	}
+	if len(header.geoKeys) > 0 {
+		vlr := make([]byte, vlrHeaderSize+2*len(header.geoKeys))
+		copy(vlr[2:18], "LASF_Projection")
+		binary.LittleEndian.PutUint16(vlr[18:20], 34735)
+		binary.LittleEndian.PutUint16(vlr[20:22], uint16(2*len(header.geoKeys)))
+		for i, key := range header.geoKeys {
+			binary.LittleEndian.PutUint16(vlr[vlrHeaderSize+2*i:], key)
+		}
+		vlrs = append(vlrs, vlr...)
+		vlrCount++
+	}
+	if len(header.classNames) > 0 {
+		vlr := make([]byte, vlrHeaderSize, vlrHeaderSize+16*len(header.classNames))
+		copy(vlr[2:18], "LASF_Spec")
+		binary.LittleEndian.PutUint16(vlr[20:22], uint16(16*len(header.classNames)))
+		for classification, name := range header.classNames {
+			entry := make([]byte, 16)
+			entry[0] = classification
+			copy(entry[1:], name)
+			vlr = append(vlr, entry...)
+		}
+		vlrs = append(vlrs, vlr...)
+		vlrCount++
+	}
+	pointsOffset := headerSize + len(vlrs)
+	b := make([]byte, pointsOffset+len(rawPoints)*recordLength)
+	copy(b[0:4], "LASF")
+	b[24] = 1
+	b[25] = 2
+	binary.LittleEndian.PutUint16(b[94:96], headerSize)
+	binary.LittleEndian.PutUint32(b[96:100], uint32(pointsOffset))
+	if len(vlrs) > 0 {
+		binary.LittleEndian.PutUint32(b[100:104], uint32(vlrCount))
+		copy(b[headerSize:], vlrs)
+	}
+	b[104] = 0
+	binary.LittleEndian.PutUint16(b[105:107], recordLength)
+	binary.LittleEndian.PutUint32(b[107:111], uint32(len(rawPoints)))
+	for i := 0; i < 3; i++ {
+		binary.LittleEndian.PutUint64(b[131+i*8:139+i*8], math.Float64bits(header.scale[i]))
+		binary.LittleEndian.PutUint64(b[155+i*8:163+i*8], math.Float64bits(header.offset[i]))
+	}
+	for i, value := range header.extent {
+		binary.LittleEndian.PutUint64(b[179+i*8:187+i*8], math.Float64bits(value))
+	}
+	for i, point := range rawPoints {
+		offset := pointsOffset + i*recordLength
+		for j := 0; j < 3; j++ {
+			binary.LittleEndian.PutUint32(b[offset+j*4:offset+j*4+4], uint32(point[j]))
+		}
+	}
+
+	folder, err := os.MkdirTemp("", "gocesiumtiler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(folder, "test.las")
+	if err := os.WriteFile(file, b, 0666); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// Reads the given LAS file, without any coordinate conversion, returning the loaded points
+func readTestLasFile(t *testing.T, file string) []*data.Point {
+	return readTestLasFileInBatches(t, file, 0)
+}
+
+// Reads the given LAS file, without any coordinate conversion, in batches of point records of the given max size in
+// bytes, returning the loaded points
+func readTestLasFileInBatches(t *testing.T, file string, readBufferSize int) []*data.Point {
+	loader := point_loader.NewRandomLoader(0)
+	lasFileLoader := lidario.NewLasFileLoader(&identityCoordinateConverter{}, nil, loader, nil, false)
+	lasFileLoader.ReadBufferSize = readBufferSize
+	lf, err := lasFileLoader.LoadLasFile(file, offset_elevation_corrector.NewOffsetElevationCorrector(0), 4326)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = lf.Close()
+
+	points := make([]*data.Point, 0)
+	loader.Initialize()
+	for {
+		point, shouldContinue := loader.GetNext()
+		if point != nil {
+			points = append(points, point)
+		}
+		if !shouldContinue {
+			break
+		}
+	}
+	return points
+}
+
+// Returns the correctly rounded value of raw * scale + offset
+func exactScaledCoordinate(raw int32, scale, offset float64) float64 {
+	value := new(big.Float).SetPrec(200).SetInt64(int64(raw))
+	value.Mul(value, new(big.Float).SetPrec(200).SetFloat64(scale))
+	value.Add(value, new(big.Float).SetPrec(200).SetFloat64(offset))
+	result, _ := value.Float64()
+	return result
+}
+
+func TestLasDecodingWithExtremeScaleFactorsAndLargeOffsets(t *testing.T) {
+	header := testLasHeader{
+		scale:  [3]float64{1e-7, 1e-7, 1e-7},
+		offset: [3]float64{500000.123456789, 4649776.224, 1024.5},
+	}
+	rawValues := []int32{math.MinInt32, math.MinInt32 + 1, -123456789, -1, 0, 1, 987654321, math.MaxInt32 - 1, math.MaxInt32}
+	rawPoints := make([][3]int32, len(rawValues))
+	for i, value := range rawValues {
+		rawPoints[i] = [3]int32{value, rawValues[len(rawValues)-1-i], value}
+	}
+	file := writeTestLasFile(t, header, rawPoints)
+	defer os.RemoveAll(filepath.Dir(file))
+
+	points := readTestLasFile(t, file)
+	if len(points) != len(rawPoints) {
+		t.Fatalf("Expected %d points, got %d", len(rawPoints), len(points))
+	}
+
+	expected := make(map[float64][2]float64)
+	for _, raw := range rawPoints {
+		x := exactScaledCoordinate(raw[0], header.scale[0], header.offset[0])
+		expected[x] = [2]float64{
+			exactScaledCoordinate(raw[1], header.scale[1], header.offset[1]),
+			exactScaledCoordinate(raw[2], header.scale[2], header.offset[2]),
+		}
+	}
+	for _, point := range points {
+		yz, ok := expected[point.X]
+		if !ok {
+			t.Errorf("Unexpected decoded X = %.10f", point.X)
+			continue
+		}
+		if point.Y != yz[0] || point.Z != yz[1] {
+			t.Errorf("Expected Y = %.10f, Z = %.10f, got Y = %.10f, Z = %.10f", yz[0], yz[1], point.Y, point.Z)
+		}
+	}
+}
+
+func TestTruncatedLasFileTilesOnlyTheCompletePoints(t *testing.T) {
+	points := []testLasPoint{
+		{raw: [3]int32{1, 2, 3}},
+		{raw: [3]int32{4, 5, 6}},
+		{raw: [3]int32{7, 8, 9}},
+		{raw: [3]int32{10, 11, 12}},
+	}
+	file := writeTestLasRecords(t, 2, 0, 20, points)
+	defer os.RemoveAll(filepath.Dir(file))
+	// keep two records and a half
+	if err := os.Truncate(file, 227+2*20+10); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, readBufferSize := range []int{0, 20, 40} {
+		read := readTestLasFileInBatches(t, file, readBufferSize)
+		if len(read) != 2 {
+			t.Fatalf("Expected the 2 complete points with read buffer size %d, got %d", readBufferSize, len(read))
+		}
+		for _, point := range read {
+			if point.X != 1 && point.X != 4 {
+				t.Errorf("Expected only the complete points with read buffer size %d, got (%f, %f, %f)", readBufferSize, point.X, point.Y, point.Z)
+			}
+		}
+	}
+}
+
+func TestLasPointsLengthOver2GBDoesNotOverflow(t *testing.T) {
+	points := []testLasPoint{
+		{raw: [3]int32{1, 2, 3}},
+		{raw: [3]int32{4, 5, 6}},
+	}
+	file := writeTestLasRecords(t, 2, 0, 20, points)
+	defer os.RemoveAll(filepath.Dir(file))
+	// declare 3 GB of point records, the length of the point data overflows a 32 bit int
+	f, err := os.OpenFile(file, os.O_RDWR, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := make([]byte, 4)
+	binary.LittleEndian.PutUint32(header, 150000000)
+	if _, err := f.WriteAt(header, 107); err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+
+	read := readTestLasFileInBatches(t, file, 40)
+	if len(read) != 2 {
+		t.Fatalf("Expected the 2 points stored in the file, got %d", len(read))
+	}
+}
+
+func TestOriginPointsAreDroppedAndCounted(t *testing.T) {
+	points := []testLasPoint{
+		{raw: [3]int32{0, 0, 0}},
+		{raw: [3]int32{1, 2, 3}},
+		{raw: [3]int32{0, 0, 0}},
+		{raw: [3]int32{0, 0, 4}},
+		{raw: [3]int32{0, 0, 0}},
+	}
+	file := writeTestLasRecords(t, 2, 0, 20, points)
+	defer os.RemoveAll(filepath.Dir(file))
+
+	for _, dropOriginPoints := range []bool{false, true} {
+		loader := point_loader.NewRandomLoader(0)
+		lasFileLoader := lidario.NewLasFileLoader(&identityCoordinateConverter{}, nil, loader, nil, false)
+		lasFileLoader.DropOriginPoints = dropOriginPoints
+		lf, err := lasFileLoader.LoadLasFile(file, offset_elevation_corrector.NewOffsetElevationCorrector(0), 4326)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = lf.Close()
+
+		expectedDropped, expectedLoaded := int64(0), 5
+		if dropOriginPoints {
+			expectedDropped, expectedLoaded = 3, 2
+		}
+		if lf.DroppedOriginPoints != expectedDropped {
+			t.Errorf("Expected %d dropped points, got %d", expectedDropped, lf.DroppedOriginPoints)
+		}
+		loader.Initialize()
+		loaded := 0
+		for {
+			point, shouldContinue := loader.GetNext()
+			if point != nil {
+				loaded++
+				if dropOriginPoints && point.X == 0 && point.Y == 0 && point.Z == 0 {
+					t.Errorf("Unexpected point at the origin")
+				}
+			}
+			if !shouldContinue {
+				break
+			}
+		}
+		if loaded != expectedLoaded {
+			t.Errorf("Expected %d loaded points, got %d", expectedLoaded, loaded)
+		}
+	}
+}
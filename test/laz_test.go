@@ -0,0 +1,868 @@
+package test
+
+import (
+	"encoding/binary"
+	"github.com/mfbonfigli/gocesiumtiler/converters/offset_elevation_corrector"
+	"github.com/mfbonfigli/gocesiumtiler/lasread"
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"github.com/mfbonfigli/gocesiumtiler/structs/point_loader"
+	"math"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// The tests compress synthetic point records with a port of the LASzip encoder, the reference counterpart of the
+// decoder of the lasread package, and check that the decompressed points match the original ones. The decoder is also
+// checked against the laszip_points.laz fixture of the testdata folder or, if missing, against the output of the
+// LASzip or PDAL executables when available
+
+// LAZ fixture compressed by LASzip from the LAS file of the newTestLazRecords(1000) records written by
+// writeTestLasRecordsFile, e.g. with "laszip -i test.las -o testdata/laszip_points.laz"
+const laszipFixture = "testdata/laszip_points.laz"
+
+func TestLazFileWithFixedChunksIsDecoded(t *testing.T) {
+	records := newTestLazRecords(1000)
+	file := writeTestLazFile(t, records, []int{300, 300, 300, 100}, false)
+	defer os.RemoveAll(filepath.Dir(file))
+	assertLazPointsAreDecoded(t, file, records)
+}
+
+func TestLazFileWithVariableChunksIsDecoded(t *testing.T) {
+	records := newTestLazRecords(700)
+	file := writeTestLazFile(t, records, []int{1, 250, 449}, true)
+	defer os.RemoveAll(filepath.Dir(file))
+	assertLazPointsAreDecoded(t, file, records)
+}
+
+func TestTruncatedLazFileIsRejected(t *testing.T) {
+	records := newTestLazRecords(500)
+	file := writeTestLazFile(t, records, []int{500}, false)
+	defer os.RemoveAll(filepath.Dir(file))
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, content[:len(content)/2], 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	lasFileLoader := lidario.NewLasFileLoader(&identityCoordinateConverter{}, nil, point_loader.NewRandomLoader(0), nil, false)
+	if _, err := lasFileLoader.LoadLasFile(file, offset_elevation_corrector.NewOffsetElevationCorrector(0), 4326); err == nil {
+		t.Errorf("Expected an error for a truncated LAZ file, got nil")
+	}
+}
+
+func TestLazFileCompressedByLaszipIsDecoded(t *testing.T) {
+	records := newTestLazRecords(1000)
+	if _, err := os.Stat(laszipFixture); err == nil {
+		assertLazPointsAreDecoded(t, laszipFixture, records)
+		return
+	}
+
+	file := writeTestLasRecordsFile(t, records)
+	defer os.RemoveAll(filepath.Dir(file))
+	compressed := filepath.Join(filepath.Dir(file), "test.laz")
+	var command *exec.Cmd
+	if laszip, err := exec.LookPath("laszip"); err == nil {
+		command = exec.Command(laszip, "-i", file, "-o", compressed)
+	} else if pdal, err := exec.LookPath("pdal"); err == nil {
+		command = exec.Command(pdal, "translate", file, compressed, "--writers.las.forward=all")
+	} else {
+		t.Skip("no " + laszipFixture + " fixture nor LASzip or PDAL executable to compress the test points")
+	}
+	if output, err := command.CombinedOutput(); err != nil {
+		t.Fatalf("Compression of the test points failed: %v %s", err, output)
+	}
+	assertLazPointsAreDecoded(t, compressed, records)
+}
+
+// Returns the given number of 34 byte records of point format 3, with varying returns, attributes, GPS times
+// and colors
+func newTestLazRecords(n int) [][]byte {
+	random := rand.New(rand.NewSource(42))
+	records := make([][]byte, n)
+	gpsTime := 250000.0
+	var rgb [3]uint16
+	for i := range records {
+		record := make([]byte, 34)
+		// unique x coordinates identify the points
+		binary.LittleEndian.PutUint32(record[0:4], uint32(int32(i*7-2000)))
+		binary.LittleEndian.PutUint32(record[4:8], uint32(int32(random.Intn(100000)-50000)))
+		binary.LittleEndian.PutUint32(record[8:12], uint32(int32(random.Intn(3000)-1000)))
+		binary.LittleEndian.PutUint16(record[12:14], uint16(random.Intn(65536)))
+		returns := 1 + random.Intn(3)
+		record[14] = byte(1+random.Intn(returns)) | byte(returns)<<3 | byte(random.Intn(2))<<6
+		record[15] = byte(random.Intn(4) * 3)
+		record[16] = byte(int8(random.Intn(60) - 30))
+		record[17] = byte(random.Intn(2))
+		binary.LittleEndian.PutUint16(record[18:20], uint16(7+i/400))
+		switch {
+		case i%250 == 249:
+			// a new flight line
+			gpsTime += 1e6
+		case random.Intn(3) > 0:
+			gpsTime += 1e-5 * float64(1+random.Intn(3))
+		}
+		binary.LittleEndian.PutUint64(record[20:28], math.Float64bits(gpsTime))
+		if random.Intn(4) == 0 {
+			rgb[0] = uint16(random.Intn(65536))
+			if random.Intn(2) == 0 {
+				// grey
+				rgb[1], rgb[2] = rgb[0], rgb[0]
+			} else {
+				rgb[1], rgb[2] = uint16(random.Intn(65536)), uint16(random.Intn(65536))
+			}
+		}
+		for j := 0; j < 3; j++ {
+			binary.LittleEndian.PutUint16(record[28+j*2:30+j*2], rgb[j])
+		}
+		records[i] = record
+	}
+	return records
+}
+
+// Reads the given LAZ file and checks that the decoded points match the given records
+func assertLazPointsAreDecoded(t *testing.T, file string, records [][]byte) {
+	assertPointsMatchLazRecords(t, readTestLasFile(t, file), records)
+}
+
+// Checks that the given points match the given point format 3 records
+func assertPointsMatchLazRecords(t *testing.T, points []*data.Point, records [][]byte) {
+	if len(points) != len(records) {
+		t.Fatalf("Expected %d points, got %d", len(records), len(points))
+	}
+	expected := make(map[float64][]byte)
+	for _, record := range records {
+		expected[float64(int32(binary.LittleEndian.Uint32(record[0:4])))] = record
+	}
+	for _, point := range points {
+		record, ok := expected[point.X]
+		if !ok {
+			t.Fatalf("Unexpected point %v", point)
+		}
+		if point.Y != float64(int32(binary.LittleEndian.Uint32(record[4:8]))) ||
+			point.Z != float64(int32(binary.LittleEndian.Uint32(record[8:12]))) ||
+			point.Intensity != uint8(binary.LittleEndian.Uint16(record[12:14])/256) ||
+			point.Classification != record[15] ||
+			point.ReturnNumber != record[14]&7 || point.NumberOfReturns != record[14]>>3&7 ||
+			point.R != binary.LittleEndian.Uint16(record[28:30]) ||
+			point.G != binary.LittleEndian.Uint16(record[30:32]) ||
+			point.B != binary.LittleEndian.Uint16(record[32:34]) ||
+			point.GpsTime != math.Float64frombits(binary.LittleEndian.Uint64(record[20:28])) {
+			t.Fatalf("Point %v does not match its record %v", point, record)
+		}
+	}
+}
+
+// Compresses the given point format 3 records into a LAS 1.2 LAZ file, splitting them in chunks of the given number
+// of points. Chunks of variable size are listed in the chunk table, otherwise all chunks but the last one are
+// expected of the same size. Returns the path of the written file.
+func writeTestLazFile(t *testing.T, records [][]byte, chunkPoints []int, variable bool) string {
+	const headerSize = 227
+	vlr := make([]byte, 54+34+3*6)
+	copy(vlr[2:18], "laszip encoded")
+	binary.LittleEndian.PutUint16(vlr[18:20], 22204)
+	binary.LittleEndian.PutUint16(vlr[20:22], uint16(len(vlr)-54))
+	laszip := vlr[54:]
+	binary.LittleEndian.PutUint16(laszip[0:2], 2) // pointwise chunked compressor
+	laszip[4] = 2                                 // version 2.2
+	laszip[5] = 2
+	chunkSize := uint32(chunkPoints[0])
+	if variable {
+		chunkSize = math.MaxUint32
+	}
+	binary.LittleEndian.PutUint32(laszip[12:16], chunkSize)
+	binary.LittleEndian.PutUint64(laszip[16:24], math.MaxUint64)
+	binary.LittleEndian.PutUint64(laszip[24:32], math.MaxUint64)
+	binary.LittleEndian.PutUint16(laszip[32:34], 3)
+	for i, item := range [][3]uint16{{6, 20, 2}, {7, 8, 2}, {8, 6, 2}} {
+		for j, value := range item {
+			binary.LittleEndian.PutUint16(laszip[34+i*6+j*2:36+i*6+j*2], value)
+		}
+	}
+
+	header := make([]byte, headerSize)
+	copy(header[0:4], "LASF")
+	header[24] = 1
+	header[25] = 2
+	binary.LittleEndian.PutUint16(header[94:96], headerSize)
+	binary.LittleEndian.PutUint32(header[96:100], uint32(headerSize+len(vlr)))
+	binary.LittleEndian.PutUint32(header[100:104], 1)
+	header[104] = 3 | 0x80
+	binary.LittleEndian.PutUint16(header[105:107], 34)
+	binary.LittleEndian.PutUint32(header[107:111], uint32(len(records)))
+	for i := 0; i < 3; i++ {
+		binary.LittleEndian.PutUint64(header[131+i*8:139+i*8], 0x3ff0000000000000) // scale 1.0
+	}
+
+	points := make([]byte, 8)
+	chunkBytes := make([]int, 0)
+	start := 0
+	for _, count := range chunkPoints {
+		chunk := compressTestLazChunk(records[start : start+count])
+		chunkBytes = append(chunkBytes, len(chunk))
+		points = append(points, chunk...)
+		start += count
+	}
+	offsetToPoints := headerSize + len(vlr)
+	binary.LittleEndian.PutUint64(points[0:8], uint64(offsetToPoints+len(points)))
+
+	// chunk table
+	table := make([]byte, 8)
+	binary.LittleEndian.PutUint32(table[4:8], uint32(len(chunkPoints)))
+	encoder := newTestLazEncoder()
+	ic := newTestLazIntegerCompressor(32, 2)
+	var lastPoints, lastBytes int32
+	for i := range chunkPoints {
+		if variable {
+			ic.compress(encoder, lastPoints, int32(chunkPoints[i]), 0)
+			lastPoints = int32(chunkPoints[i])
+		}
+		ic.compress(encoder, lastBytes, int32(chunkBytes[i]), 1)
+		lastBytes = int32(chunkBytes[i])
+	}
+	table = append(table, encoder.done()...)
+
+	content := append(append(append(header, vlr...), points...), table...)
+	folder, err := os.MkdirTemp("", "gocesiumtiler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(folder, "test.laz")
+	if err := os.WriteFile(file, content, 0666); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// Writes the given point format 3 records into an uncompressed LAS 1.2 file with unit scales. Returns the path of the
+// written file.
+func writeTestLasRecordsFile(t *testing.T, records [][]byte) string {
+	const headerSize = 227
+	header := make([]byte, headerSize)
+	copy(header[0:4], "LASF")
+	header[24] = 1
+	header[25] = 2
+	binary.LittleEndian.PutUint16(header[94:96], headerSize)
+	binary.LittleEndian.PutUint32(header[96:100], headerSize)
+	header[104] = 3
+	binary.LittleEndian.PutUint16(header[105:107], 34)
+	binary.LittleEndian.PutUint32(header[107:111], uint32(len(records)))
+	for i := 0; i < 3; i++ {
+		binary.LittleEndian.PutUint64(header[131+i*8:139+i*8], 0x3ff0000000000000) // scale 1.0
+	}
+
+	content := header
+	for _, record := range records {
+		content = append(content, record...)
+	}
+	folder, err := os.MkdirTemp("", "gocesiumtiler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(folder, "test.las")
+	if err := os.WriteFile(file, content, 0666); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// Compresses a chunk of point format 3 records, storing the first one raw
+func compressTestLazChunk(records [][]byte) []byte {
+	chunk := append([]byte(nil), records[0]...)
+	encoder := newTestLazEncoder()
+	point10 := newTestLazPoint10Writer(records[0][0:20])
+	gpsTime := newTestLazGpsTimeWriter(records[0][20:28])
+	rgb := newTestLazRgbWriter(records[0][28:34])
+	for _, record := range records[1:] {
+		point10.write(encoder, record[0:20])
+		gpsTime.write(encoder, record[20:28])
+		rgb.write(encoder, record[28:34])
+	}
+	return append(chunk, encoder.done()...)
+}
+
+// Arithmetic encoder of the LASzip compressed point streams
+type testLazEncoder struct {
+	out    []byte
+	base   uint32
+	length uint32
+}
+
+func newTestLazEncoder() *testLazEncoder {
+	return &testLazEncoder{length: math.MaxUint32}
+}
+
+func (encoder *testLazEncoder) propagateCarry() {
+	for i := len(encoder.out) - 1; i >= 0; i-- {
+		if encoder.out[i] != 0xff {
+			encoder.out[i]++
+			return
+		}
+		encoder.out[i] = 0
+	}
+}
+
+func (encoder *testLazEncoder) renormalize() {
+	for {
+		encoder.out = append(encoder.out, byte(encoder.base>>24))
+		encoder.base <<= 8
+		encoder.length <<= 8
+		if encoder.length >= 1<<24 {
+			return
+		}
+	}
+}
+
+func (encoder *testLazEncoder) encodeBit(model *testLazBitModel, sym uint32) {
+	x := model.bit0Prob * (encoder.length >> 13)
+	if sym == 0 {
+		encoder.length = x
+		model.bit0Count++
+	} else {
+		initBase := encoder.base
+		encoder.base += x
+		encoder.length -= x
+		if initBase > encoder.base {
+			encoder.propagateCarry()
+		}
+	}
+	if encoder.length < 1<<24 {
+		encoder.renormalize()
+	}
+	if model.bitsUntilUpdate--; model.bitsUntilUpdate == 0 {
+		model.update()
+	}
+}
+
+func (encoder *testLazEncoder) encodeSymbol(model *testLazSymbolModel, sym uint32) {
+	initBase := encoder.base
+	if sym == model.symbols-1 {
+		x := model.distribution[sym] * (encoder.length >> 15)
+		encoder.base += x
+		encoder.length -= x
+	} else {
+		encoder.length >>= 15
+		x := model.distribution[sym] * encoder.length
+		encoder.base += x
+		encoder.length = model.distribution[sym+1]*encoder.length - x
+	}
+	if initBase > encoder.base {
+		encoder.propagateCarry()
+	}
+	if encoder.length < 1<<24 {
+		encoder.renormalize()
+	}
+	model.symbolCount[sym]++
+	if model.symbolsUntilUpdate--; model.symbolsUntilUpdate == 0 {
+		model.update()
+	}
+}
+
+func (encoder *testLazEncoder) writeBits(bits uint32, sym uint32) {
+	if bits > 19 {
+		encoder.writeBits(16, sym&0xffff)
+		sym >>= 16
+		bits -= 16
+	}
+	initBase := encoder.base
+	encoder.length >>= bits
+	encoder.base += sym * encoder.length
+	if initBase > encoder.base {
+		encoder.propagateCarry()
+	}
+	if encoder.length < 1<<24 {
+		encoder.renormalize()
+	}
+}
+
+// Flushes the encoder, padding the stream with the bytes read ahead by the decoder
+func (encoder *testLazEncoder) done() []byte {
+	initBase := encoder.base
+	anotherByte := true
+	if encoder.length > 2<<24 {
+		encoder.base += 1 << 24
+		encoder.length = 1 << 23
+	} else {
+		encoder.base += 1 << 23
+		encoder.length = 1 << 15
+		anotherByte = false
+	}
+	if initBase > encoder.base {
+		encoder.propagateCarry()
+	}
+	encoder.renormalize()
+	encoder.out = append(encoder.out, 0, 0)
+	if anotherByte {
+		encoder.out = append(encoder.out, 0)
+	}
+	return encoder.out
+}
+
+type testLazBitModel struct {
+	bit0Count, bitCount, bit0Prob, bitsUntilUpdate, updateCycle uint32
+}
+
+func newTestLazBitModel() *testLazBitModel {
+	return &testLazBitModel{bit0Count: 1, bitCount: 2, bit0Prob: 1 << 12, bitsUntilUpdate: 4, updateCycle: 4}
+}
+
+func (model *testLazBitModel) update() {
+	if model.bitCount += model.updateCycle; model.bitCount > 1<<13 {
+		model.bitCount = (model.bitCount + 1) >> 1
+		model.bit0Count = (model.bit0Count + 1) >> 1
+		if model.bit0Count == model.bitCount {
+			model.bitCount++
+		}
+	}
+	model.bit0Prob = (model.bit0Count * (0x80000000 / model.bitCount)) >> 18
+	if model.updateCycle = (5 * model.updateCycle) >> 2; model.updateCycle > 64 {
+		model.updateCycle = 64
+	}
+	model.bitsUntilUpdate = model.updateCycle
+}
+
+type testLazSymbolModel struct {
+	symbols                                     uint32
+	distribution, symbolCount                   []uint32
+	totalCount, updateCycle, symbolsUntilUpdate uint32
+}
+
+func newTestLazSymbolModel(symbols uint32) *testLazSymbolModel {
+	model := &testLazSymbolModel{symbols: symbols, distribution: make([]uint32, symbols), symbolCount: make([]uint32, symbols), updateCycle: symbols}
+	for i := range model.symbolCount {
+		model.symbolCount[i] = 1
+	}
+	model.update()
+	model.updateCycle = (symbols + 6) >> 1
+	model.symbolsUntilUpdate = model.updateCycle
+	return model
+}
+
+func (model *testLazSymbolModel) update() {
+	if model.totalCount += model.updateCycle; model.totalCount > 1<<15 {
+		model.totalCount = 0
+		for i := range model.symbolCount {
+			model.symbolCount[i] = (model.symbolCount[i] + 1) >> 1
+			model.totalCount += model.symbolCount[i]
+		}
+	}
+	var sum uint32
+	scale := 0x80000000 / model.totalCount
+	for i := range model.distribution {
+		model.distribution[i] = (scale * sum) >> 16
+		sum += model.symbolCount[i]
+	}
+	if model.updateCycle = (5 * model.updateCycle) >> 2; model.updateCycle > (model.symbols+6)<<3 {
+		model.updateCycle = (model.symbols + 6) << 3
+	}
+	model.symbolsUntilUpdate = model.updateCycle
+}
+
+type testLazIntegerCompressor struct {
+	corrRange        uint32
+	corrMin, corrMax int32
+	k                uint32
+	mBits            []*testLazSymbolModel
+	mCorrector0      *testLazBitModel
+	mCorrector       []*testLazSymbolModel
+}
+
+func newTestLazIntegerCompressor(bits uint32, contexts int) *testLazIntegerCompressor {
+	ic := &testLazIntegerCompressor{corrMin: math.MinInt32, corrMax: math.MaxInt32, mCorrector0: newTestLazBitModel()}
+	corrBits := uint32(32)
+	if bits < 32 {
+		corrBits = bits
+		ic.corrRange = 1 << bits
+		ic.corrMin = -int32(ic.corrRange / 2)
+		ic.corrMax = ic.corrMin + int32(ic.corrRange) - 1
+	}
+	for i := 0; i < contexts; i++ {
+		ic.mBits = append(ic.mBits, newTestLazSymbolModel(corrBits+1))
+	}
+	ic.mCorrector = make([]*testLazSymbolModel, corrBits+1)
+	for i := uint32(1); i <= corrBits; i++ {
+		ic.mCorrector[i] = newTestLazSymbolModel(1 << uint32(math.Min(float64(i), 8)))
+	}
+	return ic
+}
+
+func (ic *testLazIntegerCompressor) compress(encoder *testLazEncoder, pred, real int32, context uint32) {
+	c := real - pred
+	if c < ic.corrMin {
+		c += int32(ic.corrRange)
+	} else if c > ic.corrMax {
+		c -= int32(ic.corrRange)
+	}
+	var c1 uint32
+	if c <= 0 {
+		c1 = uint32(-c)
+	} else {
+		c1 = uint32(c - 1)
+	}
+	for ic.k = 0; c1 != 0; ic.k++ {
+		c1 >>= 1
+	}
+	encoder.encodeSymbol(ic.mBits[context], ic.k)
+	if ic.k == 0 {
+		encoder.encodeBit(ic.mCorrector0, uint32(c))
+		return
+	}
+	if ic.k >= 32 {
+		return
+	}
+	if c < 0 {
+		c += 1<<ic.k - 1
+	} else {
+		c--
+	}
+	if ic.k <= 8 {
+		encoder.encodeSymbol(ic.mCorrector[ic.k], uint32(c))
+	} else {
+		lowBits := ic.k - 8
+		encoder.encodeSymbol(ic.mCorrector[ic.k], uint32(c)>>lowBits)
+		encoder.writeBits(lowBits, uint32(c)&(1<<lowBits-1))
+	}
+}
+
+type testLazMedian5 struct {
+	values [5]int32
+	low    bool
+}
+
+func (median *testLazMedian5) add(v int32) {
+	values := &median.values
+	if !median.low {
+		if v < values[2] {
+			values[4], values[3] = values[3], values[2]
+			if v < values[0] {
+				values[2], values[1], values[0] = values[1], values[0], v
+			} else if v < values[1] {
+				values[2], values[1] = values[1], v
+			} else {
+				values[2] = v
+			}
+		} else {
+			if v < values[3] {
+				values[4], values[3] = values[3], v
+			} else {
+				values[4] = v
+			}
+			median.low = true
+		}
+	} else {
+		if values[2] < v {
+			values[0], values[1] = values[1], values[2]
+			if values[4] < v {
+				values[2], values[3], values[4] = values[3], values[4], v
+			} else if values[3] < v {
+				values[2], values[3] = values[3], v
+			} else {
+				values[2] = v
+			}
+		} else {
+			if values[1] < v {
+				values[0], values[1] = values[1], v
+			} else {
+				values[0] = v
+			}
+			median.low = false
+		}
+	}
+}
+
+var testLazNumberReturnMap = [8][8]uint8{
+	{15, 14, 13, 12, 11, 10, 9, 8},
+	{14, 0, 1, 3, 6, 10, 10, 9},
+	{13, 1, 2, 4, 7, 11, 11, 10},
+	{12, 3, 4, 5, 8, 12, 12, 11},
+	{11, 6, 7, 8, 9, 13, 13, 12},
+	{10, 10, 11, 12, 13, 14, 14, 13},
+	{9, 10, 11, 12, 13, 14, 15, 14},
+	{8, 9, 10, 11, 12, 13, 14, 15},
+}
+
+type testLazPoint10Writer struct {
+	lastItem                         [20]byte
+	lastIntensity                    [16]uint16
+	lastXDiffMedian, lastYDiffMedian [16]testLazMedian5
+	lastHeight                       [8]int32
+	mChangedValues                   *testLazSymbolModel
+	mScanAngleRank                   [2]*testLazSymbolModel
+	mBitByte, mClass, mUserData      [256]*testLazSymbolModel
+	icIntensity, icPointSourceID     *testLazIntegerCompressor
+	icDx, icDy, icZ                  *testLazIntegerCompressor
+}
+
+func newTestLazPoint10Writer(first []byte) *testLazPoint10Writer {
+	writer := &testLazPoint10Writer{
+		mChangedValues:  newTestLazSymbolModel(64),
+		mScanAngleRank:  [2]*testLazSymbolModel{newTestLazSymbolModel(256), newTestLazSymbolModel(256)},
+		icIntensity:     newTestLazIntegerCompressor(16, 4),
+		icPointSourceID: newTestLazIntegerCompressor(16, 1),
+		icDx:            newTestLazIntegerCompressor(32, 2),
+		icDy:            newTestLazIntegerCompressor(32, 22),
+		icZ:             newTestLazIntegerCompressor(32, 20),
+	}
+	copy(writer.lastItem[:], first)
+	writer.lastItem[12], writer.lastItem[13] = 0, 0
+	return writer
+}
+
+func encodeTestLazContextSymbol(encoder *testLazEncoder, models *[256]*testLazSymbolModel, context byte, sym byte) {
+	if models[context] == nil {
+		models[context] = newTestLazSymbolModel(256)
+	}
+	encoder.encodeSymbol(models[context], uint32(sym))
+}
+
+func (writer *testLazPoint10Writer) write(encoder *testLazEncoder, item []byte) {
+	last := writer.lastItem[:]
+	r, n := item[14]&7, (item[14]>>3)&7
+	m, l := testLazNumberReturnMap[n][r], lazTestNumberReturnLevel(n, r)
+	intensity := binary.LittleEndian.Uint16(item[12:14])
+	var changedValues uint32
+	for i, changed := range []bool{
+		binary.LittleEndian.Uint16(last[18:20]) != binary.LittleEndian.Uint16(item[18:20]),
+		last[17] != item[17], last[16] != item[16], last[15] != item[15],
+		writer.lastIntensity[m] != intensity, last[14] != item[14],
+	} {
+		if changed {
+			changedValues |= 1 << uint(i)
+		}
+	}
+	encoder.encodeSymbol(writer.mChangedValues, changedValues)
+	if changedValues&32 != 0 {
+		encodeTestLazContextSymbol(encoder, &writer.mBitByte, last[14], item[14])
+	}
+	if changedValues&16 != 0 {
+		writer.icIntensity.compress(encoder, int32(writer.lastIntensity[m]), int32(intensity), uint32(math.Min(float64(m), 3)))
+		writer.lastIntensity[m] = intensity
+	}
+	if changedValues&8 != 0 {
+		encodeTestLazContextSymbol(encoder, &writer.mClass, last[15], item[15])
+	}
+	if changedValues&4 != 0 {
+		encoder.encodeSymbol(writer.mScanAngleRank[(item[14]>>6)&1], uint32(item[16]-last[16]))
+	}
+	if changedValues&2 != 0 {
+		encodeTestLazContextSymbol(encoder, &writer.mUserData, last[17], item[17])
+	}
+	if changedValues&1 != 0 {
+		writer.icPointSourceID.compress(encoder, int32(binary.LittleEndian.Uint16(last[18:20])), int32(binary.LittleEndian.Uint16(item[18:20])), 0)
+	}
+
+	var singleReturn uint32
+	if n == 1 {
+		singleReturn = 1
+	}
+	diff := int32(binary.LittleEndian.Uint32(item[0:4])) - int32(binary.LittleEndian.Uint32(last[0:4]))
+	writer.icDx.compress(encoder, writer.lastXDiffMedian[m].values[2], diff, singleReturn)
+	writer.lastXDiffMedian[m].add(diff)
+	context := singleReturn + 20
+	if writer.icDx.k < 20 {
+		context = singleReturn + writer.icDx.k&^1
+	}
+	diff = int32(binary.LittleEndian.Uint32(item[4:8])) - int32(binary.LittleEndian.Uint32(last[4:8]))
+	writer.icDy.compress(encoder, writer.lastYDiffMedian[m].values[2], diff, context)
+	writer.lastYDiffMedian[m].add(diff)
+	kBits := (writer.icDx.k + writer.icDy.k) / 2
+	context = singleReturn + 18
+	if kBits < 18 {
+		context = singleReturn + kBits&^1
+	}
+	z := int32(binary.LittleEndian.Uint32(item[8:12]))
+	writer.icZ.compress(encoder, writer.lastHeight[l], z, context)
+	writer.lastHeight[l] = z
+	copy(last, item)
+}
+
+func lazTestNumberReturnLevel(n, r byte) int {
+	if n > r {
+		return int(n - r)
+	}
+	return int(r - n)
+}
+
+type testLazGpsTimeWriter struct {
+	last, next          int
+	lastGpsTime         [4]int64
+	lastGpsTimeDiff     [4]int32
+	multiExtremeCounter [4]int32
+	mGpsTimeMulti       *testLazSymbolModel
+	mGpsTime0Diff       *testLazSymbolModel
+	icGpsTime           *testLazIntegerCompressor
+}
+
+func newTestLazGpsTimeWriter(first []byte) *testLazGpsTimeWriter {
+	writer := &testLazGpsTimeWriter{
+		mGpsTimeMulti: newTestLazSymbolModel(516),
+		mGpsTime0Diff: newTestLazSymbolModel(6),
+		icGpsTime:     newTestLazIntegerCompressor(32, 9),
+	}
+	writer.lastGpsTime[0] = int64(binary.LittleEndian.Uint64(first))
+	return writer
+}
+
+func (writer *testLazGpsTimeWriter) countExtremeMultiplier(diff int32) {
+	writer.multiExtremeCounter[writer.last]++
+	if writer.multiExtremeCounter[writer.last] > 3 {
+		writer.lastGpsTimeDiff[writer.last] = diff
+		writer.multiExtremeCounter[writer.last] = 0
+	}
+}
+
+func (writer *testLazGpsTimeWriter) write(encoder *testLazEncoder, item []byte) {
+	gpsTime := int64(binary.LittleEndian.Uint64(item))
+	last := writer.last
+	unchangedSymbol, fullSymbol := uint32(0), uint32(2)
+	model := writer.mGpsTime0Diff
+	if writer.lastGpsTimeDiff[last] != 0 {
+		unchangedSymbol, fullSymbol = 511, 512
+		model = writer.mGpsTimeMulti
+	}
+	if gpsTime == writer.lastGpsTime[last] {
+		encoder.encodeSymbol(model, unchangedSymbol)
+		return
+	}
+	diff64 := gpsTime - writer.lastGpsTime[last]
+	if diff := int32(diff64); int64(diff) == diff64 {
+		if writer.lastGpsTimeDiff[last] == 0 {
+			encoder.encodeSymbol(model, 1)
+			writer.icGpsTime.compress(encoder, 0, diff, 0)
+			writer.lastGpsTimeDiff[last] = diff
+			writer.multiExtremeCounter[last] = 0
+		} else {
+			lastDiff := writer.lastGpsTimeDiff[last]
+			multiF := float32(diff) / float32(lastDiff)
+			var multi int32
+			if multiF >= 0 {
+				multi = int32(multiF + 0.5)
+			} else {
+				multi = int32(multiF - 0.5)
+			}
+			switch {
+			case multi == 1:
+				encoder.encodeSymbol(model, 1)
+				writer.icGpsTime.compress(encoder, lastDiff, diff, 1)
+				writer.multiExtremeCounter[last] = 0
+			case multi > 0 && multi < 500:
+				encoder.encodeSymbol(model, uint32(multi))
+				context := uint32(2)
+				if multi >= 10 {
+					context = 3
+				}
+				writer.icGpsTime.compress(encoder, multi*lastDiff, diff, context)
+			case multi >= 500:
+				encoder.encodeSymbol(model, 500)
+				writer.icGpsTime.compress(encoder, 500*lastDiff, diff, 4)
+				writer.countExtremeMultiplier(diff)
+			case multi < 0 && multi > -10:
+				encoder.encodeSymbol(model, uint32(500-multi))
+				writer.icGpsTime.compress(encoder, multi*lastDiff, diff, 5)
+			case multi < 0:
+				encoder.encodeSymbol(model, 510)
+				writer.icGpsTime.compress(encoder, -10*lastDiff, diff, 6)
+				writer.countExtremeMultiplier(diff)
+			default:
+				encoder.encodeSymbol(model, 0)
+				writer.icGpsTime.compress(encoder, 0, diff, 7)
+				writer.countExtremeMultiplier(diff)
+			}
+		}
+	} else {
+		for i := 1; i < 4; i++ {
+			otherDiff64 := gpsTime - writer.lastGpsTime[(last+i)&3]
+			if int64(int32(otherDiff64)) == otherDiff64 {
+				// the time belongs to another sequence
+				encoder.encodeSymbol(model, fullSymbol+uint32(i))
+				writer.last = (last + i) & 3
+				writer.write(encoder, item)
+				return
+			}
+		}
+		encoder.encodeSymbol(model, fullSymbol)
+		writer.icGpsTime.compress(encoder, int32(uint64(writer.lastGpsTime[last])>>32), int32(uint64(gpsTime)>>32), 8)
+		encoder.writeBits(16, uint32(gpsTime)&0xffff)
+		encoder.writeBits(16, uint32(gpsTime)>>16)
+		writer.next = (writer.next + 1) & 3
+		writer.last = writer.next
+		writer.lastGpsTimeDiff[writer.last] = 0
+		writer.multiExtremeCounter[writer.last] = 0
+	}
+	writer.lastGpsTime[writer.last] = gpsTime
+}
+
+type testLazRgbWriter struct {
+	lastItem  [3]uint16
+	mByteUsed *testLazSymbolModel
+	mRgbDiff  [6]*testLazSymbolModel
+}
+
+func newTestLazRgbWriter(first []byte) *testLazRgbWriter {
+	writer := &testLazRgbWriter{mByteUsed: newTestLazSymbolModel(128)}
+	for i := range writer.mRgbDiff {
+		writer.mRgbDiff[i] = newTestLazSymbolModel(256)
+	}
+	for i := range writer.lastItem {
+		writer.lastItem[i] = binary.LittleEndian.Uint16(first[i*2:])
+	}
+	return writer
+}
+
+func clampTestLazByte(n int32) int32 {
+	return int32(math.Max(0, math.Min(255, float64(n))))
+}
+
+func (writer *testLazRgbWriter) write(encoder *testLazEncoder, item []byte) {
+	last := writer.lastItem
+	var rgb [3]uint16
+	for i := range rgb {
+		rgb[i] = binary.LittleEndian.Uint16(item[i*2:])
+	}
+	var sym uint32
+	for i, changed := range []bool{
+		last[0]&0xff != rgb[0]&0xff, last[0]>>8 != rgb[0]>>8,
+		last[1]&0xff != rgb[1]&0xff, last[1]>>8 != rgb[1]>>8,
+		last[2]&0xff != rgb[2]&0xff, last[2]>>8 != rgb[2]>>8,
+		rgb[0] != rgb[1] || rgb[0] != rgb[2],
+	} {
+		if changed {
+			sym |= 1 << uint(i)
+		}
+	}
+	encoder.encodeSymbol(writer.mByteUsed, sym)
+	var diffLow, diffHigh int32
+	if sym&1 != 0 {
+		diffLow = int32(rgb[0]&0xff) - int32(last[0]&0xff)
+		encoder.encodeSymbol(writer.mRgbDiff[0], uint32(byte(diffLow)))
+	}
+	if sym&2 != 0 {
+		diffHigh = int32(rgb[0]>>8) - int32(last[0]>>8)
+		encoder.encodeSymbol(writer.mRgbDiff[1], uint32(byte(diffHigh)))
+	}
+	if sym&64 != 0 {
+		if sym&4 != 0 {
+			corr := int32(rgb[1]&0xff) - clampTestLazByte(diffLow+int32(last[1]&0xff))
+			encoder.encodeSymbol(writer.mRgbDiff[2], uint32(byte(corr)))
+		}
+		if sym&16 != 0 {
+			diffLow = (diffLow + int32(rgb[1]&0xff) - int32(last[1]&0xff)) / 2
+			corr := int32(rgb[2]&0xff) - clampTestLazByte(diffLow+int32(last[2]&0xff))
+			encoder.encodeSymbol(writer.mRgbDiff[4], uint32(byte(corr)))
+		}
+		if sym&8 != 0 {
+			corr := int32(rgb[1]>>8) - clampTestLazByte(diffHigh+int32(last[1]>>8))
+			encoder.encodeSymbol(writer.mRgbDiff[3], uint32(byte(corr)))
+		}
+		if sym&32 != 0 {
+			diffHigh = (diffHigh + int32(rgb[1]>>8) - int32(last[1]>>8)) / 2
+			corr := int32(rgb[2]>>8) - clampTestLazByte(diffHigh+int32(last[2]>>8))
+			encoder.encodeSymbol(writer.mRgbDiff[5], uint32(byte(corr)))
+		}
+	}
+	writer.lastItem = rgb
+}
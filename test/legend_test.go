@@ -0,0 +1,76 @@
+package test
+
+import (
+	"encoding/json"
+	"github.com/mfbonfigli/gocesiumtiler/app"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/structs/point_loader"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLegendListsThePresentClasses(t *testing.T) {
+	statistics := point_loader.Statistics{
+		Classifications:     map[uint8]int64{2: 10, 0: 5, 6: 3, 200: 1},
+		ClassificationNames: map[uint8]string{6: "Roofs", 9: "Lake"},
+	}
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.ClassificationAlpha = map[uint8]uint8{2: 64, 7: 32}
+
+	legend := io.NewLegend(statistics, opts)
+	expected := []struct {
+		classification uint8
+		name           string
+		points         int64
+		alpha          int
+	}{
+		{0, "Created, never classified", 5, -1},
+		{2, "Ground", 10, 64},
+		{6, "Roofs", 3, -1},
+		{200, "Class 200", 1, -1},
+	}
+	if len(legend.Classes) != len(expected) {
+		t.Fatalf("Expected %d classes, got %v", len(expected), legend.Classes)
+	}
+	for i, class := range legend.Classes {
+		if class.Classification != expected[i].classification || class.Name != expected[i].name || class.Points != expected[i].points {
+			t.Errorf("Expected class %v, got %v", expected[i], class)
+		}
+		if (class.Alpha == nil) != (expected[i].alpha < 0) || (class.Alpha != nil && int(*class.Alpha) != expected[i].alpha) {
+			t.Errorf("Expected alpha %d for class %d, got %v", expected[i].alpha, class.Classification, class.Alpha)
+		}
+	}
+}
+
+func TestLegendNamesAreReadFromTheClassificationLookupVlr(t *testing.T) {
+	file := writeTestLasFile(t, testLasHeader{
+		scale:      [3]float64{1, 1, 1},
+		classNames: map[uint8]string{0: "Unlabelled", 9: "Lake"},
+	}, [][3]int32{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}})
+	defer os.RemoveAll(filepath.Dir(file))
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.Input = file
+	opts.WriteLegend = true
+	if err := app.RunTiler(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(opts.Output, "test", "legend.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	legend := io.Legend{}
+	if err := json.Unmarshal(content, &legend); err != nil {
+		t.Fatal(err)
+	}
+	if len(legend.Classes) != 1 {
+		t.Fatalf("Expected only the classification of the points, got %v", legend.Classes)
+	}
+	class := legend.Classes[0]
+	if class.Classification != 0 || class.Name != "Unlabelled" || class.Points != 3 || class.Alpha != nil {
+		t.Errorf("Expected 3 points of class 0 named Unlabelled, got %v", class)
+	}
+}
@@ -0,0 +1,189 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"github.com/mfbonfigli/gocesiumtiler/structs/point_loader"
+	"sync"
+	"testing"
+)
+
+// Loads a regular grid of points in [0,10]^3 plus a single far away outlier
+func loadGridWithOutlier(loader point_loader.Loader) {
+	for x := 0; x <= 10; x++ {
+		for y := 0; y <= 10; y++ {
+			for z := 0; z <= 10; z++ {
+				loader.AddElement(data.NewPoint(float64(x), float64(y), float64(z), 0, 0, 0, 0, 0))
+			}
+		}
+	}
+	loader.AddElement(data.NewPoint(1e6, 1e6, 1e6, 0, 0, 0, 0, 0))
+}
+
+func TestRandomLoaderBoundsIncludeOutliersByDefault(t *testing.T) {
+	loader := point_loader.NewRandomLoader(0)
+	loadGridWithOutlier(loader)
+	bounds := loader.GetBounds()
+	if bounds[1] != 1e6 || bounds[3] != 1e6 || bounds[5] != 1e6 {
+		t.Errorf("Expected max bounds to include the outlier, got %v", bounds)
+	}
+}
+
+func TestRandomLoaderRobustBoundsExcludeOutliers(t *testing.T) {
+	loader := point_loader.NewRandomLoader(3)
+	loadGridWithOutlier(loader)
+	assertRobustBounds(t, loader.GetBounds())
+}
+
+func TestRandomBoxLoaderRobustBoundsExcludeOutliers(t *testing.T) {
+	loader := point_loader.NewRandomBoxLoader(3)
+	loadGridWithOutlier(loader)
+	assertRobustBounds(t, loader.GetBounds())
+}
+
+func assertRobustBounds(t *testing.T, bounds []float64) {
+	for i := 0; i < 3; i++ {
+		if bounds[i*2] != 0 {
+			t.Errorf("Expected min bound %d = 0, got %f", i, bounds[i*2])
+		}
+		if bounds[i*2+1] < 10 || bounds[i*2+1] > 1e5 {
+			t.Errorf("Expected max bound %d to contain the grid and exclude the outlier, got %f", i, bounds[i*2+1])
+		}
+	}
+}
+
+func TestStatisticsLoaderCollectsStatistics(t *testing.T) {
+	loader := point_loader.NewStatisticsLoader(point_loader.NewRandomLoader(0))
+	loader.AddElement(data.NewPoint(1, 2, 3, 0, 0, 0, 10, 2))
+	loader.AddElement(data.NewPoint(-1, 5, 0, 0, 0, 0, 20, 2))
+	loader.AddElement(data.NewPoint(0, 0, 9, 0, 0, 0, 60, 7))
+
+	stats := loader.GetStatistics()
+	if stats.TotalPoints != 3 {
+		t.Errorf("Expected 3 points, got %d", stats.TotalPoints)
+	}
+	if len(stats.Classifications) != 2 || stats.Classifications[2] != 2 || stats.Classifications[7] != 1 {
+		t.Errorf("Expected map[2:2 7:1], got %v", stats.Classifications)
+	}
+	if stats.Intensity.Min != 10 || stats.Intensity.Max != 60 || stats.Intensity.Mean != 30 {
+		t.Errorf("Expected intensity 10/60/30, got %v", stats.Intensity)
+	}
+	if stats.Bounds.MinX != -1 || stats.Bounds.MaxY != 5 || stats.Bounds.MaxZ != 9 {
+		t.Errorf("Unexpected bounds %v", stats.Bounds)
+	}
+	if bounds := loader.GetBounds(); bounds[0] != -1 || bounds[5] != 9 {
+		t.Errorf("Expected points to be added to the wrapped loader, got bounds %v", bounds)
+	}
+}
+
+func TestRandomLoaderKeepsPointsAddedConcurrently(t *testing.T) {
+	assertLoaderKeepsPointsAddedConcurrently(t, point_loader.NewRandomLoader(0))
+}
+
+func TestRandomBoxLoaderKeepsPointsAddedConcurrently(t *testing.T) {
+	assertLoaderKeepsPointsAddedConcurrently(t, point_loader.NewRandomBoxLoader(0))
+}
+
+func TestGridLoaderKeepsPointsAddedConcurrently(t *testing.T) {
+	assertLoaderKeepsPointsAddedConcurrently(t, point_loader.NewGridLoader(0))
+}
+
+func TestPoissonDiskLoaderKeepsPointsAddedConcurrently(t *testing.T) {
+	assertLoaderKeepsPointsAddedConcurrently(t, point_loader.NewPoissonDiskLoader(0))
+}
+
+func TestGridLoaderReturnsAPointPerOctantFirst(t *testing.T) {
+	loader := point_loader.NewGridLoader(0)
+	loadClusterAndSparseGrid(loader)
+	loader.Initialize()
+	octants := make(map[int]bool)
+	clustered := 0
+	for i := 0; i < 8; i++ {
+		point, _ := loader.GetNext()
+		octant := 0
+		for axis, value := range []float64{point.X, point.Y, point.Z} {
+			if value >= 5 {
+				octant += 1 << uint(axis)
+			}
+		}
+		octants[octant] = true
+		if point.X < 1 && point.Y < 1 && point.Z < 1 {
+			clustered++
+		}
+	}
+	if len(octants) != 8 {
+		t.Errorf("Expected the first 8 points in distinct octants, got %d octants", len(octants))
+	}
+	if clustered > 1 {
+		t.Errorf("Expected at most 1 of the first 8 points in the dense cluster, got %d", clustered)
+	}
+}
+
+func TestPoissonDiskLoaderSpacesTheFirstPoints(t *testing.T) {
+	loader := point_loader.NewPoissonDiskLoader(0)
+	loadClusterAndSparseGrid(loader)
+	loader.Initialize()
+	points := make([]*data.Point, 0)
+	for i := 0; i < 20; i++ {
+		point, _ := loader.GetNext()
+		points = append(points, point)
+	}
+	for i, a := range points {
+		for _, b := range points[:i] {
+			dx, dy, dz := (a.X-b.X)/10, (a.Y-b.Y)/10, (a.Z-b.Z)/10
+			if dx*dx+dy*dy+dz*dz < 0.25 {
+				t.Fatalf("Expected the first points at least half the bounds apart, got %v and %v", *a, *b)
+			}
+		}
+	}
+}
+
+// Loads a dense cluster of 1000 points in [0,1)^3 plus a sparse grid of 27 points spanning [0,10]^3
+func loadClusterAndSparseGrid(loader point_loader.Loader) {
+	for x := 0; x < 10; x++ {
+		for y := 0; y < 10; y++ {
+			for z := 0; z < 10; z++ {
+				loader.AddElement(data.NewPoint(float64(x)/10, float64(y)/10, float64(z)/10, 0, 0, 0, 0, 0))
+			}
+		}
+	}
+	for x := 0; x <= 10; x += 5 {
+		for y := 0; y <= 10; y += 5 {
+			for z := 0; z <= 10; z += 5 {
+				loader.AddElement(data.NewPoint(float64(x), float64(y), float64(z), 0, 0, 0, 0, 0))
+			}
+		}
+	}
+}
+
+// Adds points to the given loader from several goroutines and checks that all of them are returned and bounded
+func assertLoaderKeepsPointsAddedConcurrently(t *testing.T, loader point_loader.Loader) {
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				loader.AddElement(data.NewPoint(float64(i), float64(j), float64(i*j), 0, 0, 0, 0, 0))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	loader.Initialize()
+	count := 0
+	for {
+		point, shouldContinue := loader.GetNext()
+		if point != nil {
+			count++
+		}
+		if !shouldContinue {
+			break
+		}
+	}
+	if count != 4000 {
+		t.Errorf("Expected 4000 points, got %d", count)
+	}
+	if bounds := loader.GetBounds(); bounds[0] != 0 || bounds[1] != 7 || bounds[3] != 499 || bounds[5] != 7*499 {
+		t.Errorf("Unexpected bounds %v", bounds)
+	}
+}
@@ -0,0 +1,95 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/app"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestMasterTilesetContainsItsChildren(t *testing.T) {
+	output := t.TempDir()
+	children := []io.ChildTilesetRef{
+		{Url: "a/tileset.json", Region: []float64{0.1, 0.2, 0.3, 0.4, 10, 20}, GeometricError: 5},
+		{Url: "b/tileset.json", Region: []float64{0.2, 0.1, 0.5, 0.3, 5, 15}, GeometricError: 8},
+	}
+	if err := io.WriteMasterTileset(output, children, &tiler.TilerOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	tileset, err := io.ReadTilesetFile(filepath.Join(output, "tileset.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedRegion := []float64{0.1, 0.1, 0.5, 0.4, 5, 20}
+	for i, value := range expectedRegion {
+		if tileset.Root.BoundingVolume.Region[i] != value {
+			t.Errorf("Expected root region %v, got %v", expectedRegion, tileset.Root.BoundingVolume.Region)
+			break
+		}
+	}
+	if tileset.GeometricError != 8 || tileset.Root.GeometricError != 8 {
+		t.Errorf("Expected the max geometric error of the children 8, got %f and %f", tileset.GeometricError, tileset.Root.GeometricError)
+	}
+	if tileset.Root.Content.Url != "" {
+		t.Errorf("Expected a root without content, got %s", tileset.Root.Content.Url)
+	}
+	if len(tileset.Root.Children) != 2 || tileset.Root.Children[0].Content.Url != "a/tileset.json" || tileset.Root.Children[1].GeometricError != 8 {
+		t.Errorf("Unexpected children %v", tileset.Root.Children)
+	}
+}
+
+func TestMasterTilesetWithoutChildrenIsRejected(t *testing.T) {
+	if err := io.WriteMasterTileset(t.TempDir(), nil, &tiler.TilerOptions{}); err == nil {
+		t.Errorf("Expected an error writing a master tileset without children")
+	}
+}
+
+func TestFolderTilingWritesTheMasterTileset(t *testing.T) {
+	folder, err := os.MkdirTemp("", "gocesiumtiler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+	for i, file := range writeBatchTestLasFiles(t, 3) {
+		if err := os.Rename(file, filepath.Join(folder, "test"+strconv.Itoa(i)+".las")); err != nil {
+			t.Fatal(err)
+		}
+		_ = os.RemoveAll(filepath.Dir(file))
+	}
+
+	for _, concurrency := range []int{0, 2} {
+		opts := newTestOptions(t)
+		defer os.RemoveAll(opts.Output)
+		opts.Input = folder
+		opts.FolderProcessing = true
+		opts.MasterTileset = true
+		opts.MaxNumPointsPerNode = 50
+		opts.Concurrency = concurrency
+		if err := app.RunTiler(opts); err != nil {
+			t.Fatal(err)
+		}
+
+		masterFile := filepath.Join(opts.Output, "tileset.json")
+		tileset, err := io.ReadTilesetFile(masterFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tileset.Root.Children) != 3 {
+			t.Fatalf("Expected 3 child tilesets, got %d", len(tileset.Root.Children))
+		}
+		for i, child := range tileset.Root.Children {
+			if expected := "test" + strconv.Itoa(i) + "/tileset.json"; child.Content.Url != expected {
+				t.Errorf("Expected child url %s, got %s", expected, child.Content.Url)
+			}
+		}
+		if err := io.ValidateRegionContainment(masterFile); err != nil {
+			t.Errorf("Expected the master region to contain the child tilesets, got %v", err)
+		}
+		if _, err := io.ValidateGeometricErrors(masterFile); err != nil {
+			t.Errorf("Expected valid geometric errors, got %v", err)
+		}
+	}
+}
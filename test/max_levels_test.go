@@ -0,0 +1,89 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/structs/octree"
+	"os"
+	"testing"
+)
+
+func TestMaxLevelsKeepsAllPointsInTheLastLevel(t *testing.T) {
+	unlimited := newTestOptions(t)
+	defer os.RemoveAll(unlimited.Output)
+	unlimited.MaxNumPointsPerNode = 20
+	if depth, _ := countTestTreePoints(&buildTree(t, newTestPoints(), unlimited).RootNode); depth <= 2 {
+		t.Fatalf("Expected more than 2 levels without a limit, got %d", depth)
+	}
+
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.MaxNumPointsPerNode = 20
+	opts.MaxLevels = 2
+	tree := buildTree(t, newTestPoints(), opts)
+	depth, pointNo := countTestTreePoints(&tree.RootNode)
+	if depth != 2 {
+		t.Errorf("Expected 2 levels, got %d", depth)
+	}
+	if pointNo != int64(len(newTestPoints())) {
+		t.Errorf("Expected %d points in the tree, got %d", len(newTestPoints()), pointNo)
+	}
+	for _, child := range tree.RootNode.Children {
+		if child != nil && child.LocalChildrenCount > 0 && !child.IsLeaf {
+			t.Errorf("Expected the tiles of the last level to be leaves")
+		}
+	}
+
+	exportTree(t, tree, opts)
+	contents := make(map[string]int)
+	collectTileContents(t, opts.Output, "tileset.json", 1, contents)
+	for content, contentDepth := range contents {
+		if contentDepth > 2 {
+			t.Errorf("Expected at most 2 tileset levels, got %s at level %d", content, contentDepth)
+		}
+	}
+}
+
+// Returns the depth of the deepest node storing points in the subtree of the given node and the number of points
+// stored in the subtree
+func countTestTreePoints(node *octree.OctNode) (int, int64) {
+	depth := 0
+	pointNo := int64(len(node.Items))
+	if len(node.Items) > 0 {
+		depth = int(node.Depth)
+	}
+	for _, child := range node.Children {
+		if child != nil {
+			childDepth, childPoints := countTestTreePoints(child)
+			if childDepth > depth {
+				depth = childDepth
+			}
+			pointNo += childPoints
+		}
+	}
+	return depth, pointNo
+}
+
+func TestTreeDepthNeverExceedsTheMaxLevels(t *testing.T) {
+	for _, maxLevels := range []int{1, 2, 3, 4} {
+		opts := newTestOptions(t)
+		defer os.RemoveAll(opts.Output)
+		opts.MaxNumPointsPerNode = 5
+		opts.MaxLevels = maxLevels
+		tree := buildTree(t, newTestPoints(), opts)
+		depth, pointNo := countTestTreePoints(&tree.RootNode)
+		if int(tree.RootNode.GetTreeDepth()) != depth || depth != maxLevels {
+			t.Errorf("Expected a tree depth of %d, got %d, reported %d", maxLevels, depth, tree.RootNode.GetTreeDepth())
+		}
+		if pointNo != int64(len(newTestPoints())) {
+			t.Errorf("Expected %d points in the tree, got %d", len(newTestPoints()), pointNo)
+		}
+		if maxLevels == 1 && (!tree.RootNode.IsLeaf || tree.RootNode.LocalChildrenCount != int32(pointNo)) {
+			t.Errorf("Expected a single tile storing all the points")
+		}
+	}
+
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	if depth := octree.NewOctNode(nil, opts, 1, nil).GetTreeDepth(); depth != 0 {
+		t.Errorf("Expected depth 0 for an empty tree, got %d", depth)
+	}
+}
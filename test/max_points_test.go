@@ -0,0 +1,60 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"github.com/mfbonfigli/gocesiumtiler/structs/octree"
+	"math"
+	"os"
+	"testing"
+)
+
+func TestNodesNeverExceedTheMaxNumberOfPoints(t *testing.T) {
+	depths := make(map[int32]int)
+	for _, maxPoints := range []int32{1, 5, 20, 1000} {
+		opts := newTestOptions(t)
+		defer os.RemoveAll(opts.Output)
+		opts.MaxNumPointsPerNode = maxPoints
+		tree := buildTree(t, newTestPoints(), opts)
+		assertNodeSizes(t, &tree.RootNode, maxPoints)
+		depth, pointNo := countTestTreePoints(&tree.RootNode)
+		if pointNo != int64(len(newTestPoints())) {
+			t.Errorf("max %d: expected %d points in the tree, got %d", maxPoints, len(newTestPoints()), pointNo)
+		}
+		depths[maxPoints] = depth
+	}
+	if depths[1] <= depths[5] || depths[5] <= depths[20] || depths[1000] != 1 {
+		t.Errorf("Expected deeper trees for smaller max numbers of points, got %v", depths)
+	}
+}
+
+func TestCoincidentPointsExceedingTheMaxNumberOfPointsAreKept(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.MaxNumPointsPerNode = 1
+	points := newTestPoints()
+	// more coincident points than levels, as each node of their path stores one of them
+	for i := 0; i < 2*math.MaxUint8; i++ {
+		points = append(points, data.NewPoint(3, 3, 3, 0, 0, 0, 0, 0))
+	}
+	tree := buildTree(t, points, opts)
+	depth, pointNo := countTestTreePoints(&tree.RootNode)
+	if pointNo != int64(len(points)) {
+		t.Errorf("Expected %d points in the tree, got %d", len(points), pointNo)
+	}
+	if depth != math.MaxUint8 {
+		t.Errorf("Expected the coincident points in a node at depth %d, got %d", math.MaxUint8, depth)
+	}
+}
+
+// Checks that the given node and its descendants store at most the given number of points, except the nodes at the
+// max depth
+func assertNodeSizes(t *testing.T, node *octree.OctNode, maxPoints int32) {
+	if len(node.Items) > int(maxPoints) || node.LocalChildrenCount > maxPoints {
+		t.Errorf("max %d: node at depth %d stores %d points", maxPoints, node.Depth, len(node.Items))
+	}
+	for _, child := range node.Children {
+		if child != nil {
+			assertNodeSizes(t, child, maxPoints)
+		}
+	}
+}
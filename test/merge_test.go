@@ -0,0 +1,71 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/app"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestMergedFilesMatchTheTilesetOfAllTheirPoints(t *testing.T) {
+	folder, err := os.MkdirTemp("", "gocesiumtiler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+	points := make([]*data.Point, 0)
+	for i, file := range writeBatchTestLasFiles(t, 10) {
+		points = append(points, readTestLasFile(t, file)...)
+		if err := os.Rename(file, filepath.Join(folder, "test"+strconv.Itoa(i)+".las")); err != nil {
+			t.Fatal(err)
+		}
+		_ = os.RemoveAll(filepath.Dir(file))
+	}
+
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.Input = folder
+	opts.FolderProcessing = true
+	opts.MergeFiles = true
+	// a single tile, so that the random point selection cannot change the tree
+	opts.MaxNumPointsPerNode = 100000
+	if err := app.RunTiler(opts); err != nil {
+		t.Fatal(err)
+	}
+	if opts.Srid != 4326 {
+		t.Errorf("Expected srid 4326 after reading, got %d", opts.Srid)
+	}
+
+	expectedOpts := newTestOptions(t)
+	defer os.RemoveAll(expectedOpts.Output)
+	expectedOpts.Srid = 4326
+	expectedOpts.MaxNumPointsPerNode = 100000
+	writeTileset(t, points, expectedOpts)
+
+	if err := io.CompareTilesetFolders(expectedOpts.Output, opts.Output, 1e-3); err != nil {
+		t.Errorf("Expected merged tileset to match the tileset of all the points, got %v", err)
+	}
+}
+
+func TestMergedFilesReadErrorsAreReturned(t *testing.T) {
+	folder, err := os.MkdirTemp("", "gocesiumtiler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+	if err := os.WriteFile(filepath.Join(folder, "broken.las"), []byte("not a las file"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.Input = folder
+	opts.FolderProcessing = true
+	opts.MergeFiles = true
+	if err := app.RunTiler(opts); err == nil {
+		t.Errorf("Expected an error reading a broken file, got nil")
+	}
+}
@@ -0,0 +1,92 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Points on the z = 5 plane
+func newFlatTestPoints() []*data.Point {
+	points := make([]*data.Point, 0)
+	for i := 0; i < 500; i++ {
+		points = append(points, data.NewPoint(float64(i%10), float64(i%7)+float64(i%3)/10, 5, 0, 0, 0, 0, 0))
+	}
+	return points
+}
+
+func TestFlatTilesGetTheMinRegionHeight(t *testing.T) {
+	for _, containment := range []bool{false, true} {
+		opts := newTestOptions(t)
+		defer os.RemoveAll(opts.Output)
+		opts.MaxNumPointsPerNode = 50
+		opts.MinRegionHeight = 0.01
+		opts.EnforceRegionContainment = containment
+		writeTileset(t, newFlatTestPoints(), opts)
+
+		regions := make(map[string][]float64)
+		collectTileRegions(t, opts.Output, "tileset.json", regions)
+		if len(regions) < 3 {
+			t.Fatalf("Expected a tileset with at least 3 tiles, got %d", len(regions))
+		}
+		for tile, region := range regions {
+			if math.Abs(region[4]-4.995) > 1e-9 || math.Abs(region[5]-5.005) > 1e-9 {
+				t.Errorf("Expected the region of %s to span the heights 4.995 to 5.005, got %f to %f", tile, region[4], region[5])
+			}
+		}
+	}
+}
+
+func TestZeroMinRegionHeightKeepsFlatRegions(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.MaxNumPointsPerNode = 50
+	writeTileset(t, newFlatTestPoints(), opts)
+
+	regions := make(map[string][]float64)
+	collectTileRegions(t, opts.Output, "tileset.json", regions)
+	for tile, region := range regions {
+		if region[4] != 5 || region[5] != 5 {
+			t.Errorf("Expected the region of %s to be flat at height 5, got %f to %f", tile, region[4], region[5])
+		}
+	}
+}
+
+func TestMinRegionHeightKeepsThickerRegions(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.MinRegionHeight = 0.01
+	writeTileset(t, newTestPoints(), opts)
+
+	tileset, err := io.ReadTilesetFile(filepath.Join(opts.Output, "tileset.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if region := tileset.Root.BoundingVolume.Region; region[4] != 0 || region[5] != 12 {
+		t.Errorf("Expected the root region to span the heights 0 to 12, got %f to %f", region[4], region[5])
+	}
+}
+
+// Follows the content urls of the given tileset.json, relative to the given folder, collecting the bounding region of
+// every tile by the url of its content
+func collectTileRegions(t *testing.T, folder string, tilesetFile string, regions map[string][]float64) {
+	tileset, err := io.ReadTilesetFile(filepath.Join(folder, tilesetFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := path.Dir(tilesetFile)
+	regions[path.Join(dir, tileset.Root.Content.Url)] = tileset.Root.BoundingVolume.Region
+	for _, child := range tileset.Root.Children {
+		url := path.Join(dir, child.Content.Url)
+		if strings.HasSuffix(url, ".json") {
+			collectTileRegions(t, folder, url, regions)
+		} else {
+			regions[url] = child.BoundingVolume.Region
+		}
+	}
+}
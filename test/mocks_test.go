@@ -0,0 +1,41 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/structs/geometry"
+)
+
+// CoordinateConverter that leaves coordinates unchanged, to test the tiler without the proj4 static data
+type identityCoordinateConverter struct{}
+
+func (c *identityCoordinateConverter) ConvertCoordinateSrid(sourceSrid int, targetSrid int, coord geometry.Coordinate) (geometry.Coordinate, error) {
+	return coord, nil
+}
+
+func (c *identityCoordinateConverter) ConvertCoordinateSridBatch(sourceSrid int, targetSrid int, coords []geometry.Coordinate) ([]geometry.Coordinate, error) {
+	return coords, nil
+}
+
+func (c *identityCoordinateConverter) Convert2DBoundingboxToWGS84Region(bbox *geometry.BoundingBox, srid int) ([]float64, error) {
+	return []float64{bbox.Xmin, bbox.Ymin, bbox.Xmax, bbox.Ymax, bbox.Zmin, bbox.Zmax}, nil
+}
+
+func (c *identityCoordinateConverter) ConvertToWGS84Cartesian(coord geometry.Coordinate, sourceSrid int) (geometry.Coordinate, error) {
+	return coord, nil
+}
+
+func (c *identityCoordinateConverter) GetWktSrid(wkt string) (int, error) {
+	return 4326, nil
+}
+
+func (c *identityCoordinateConverter) Cleanup() {}
+
+// CoordinateConverter that shrinks regions by a tenth of their size on each side, so that the regions of the children
+// are not contained in the region of their parent
+type shrinkingCoordinateConverter struct {
+	identityCoordinateConverter
+}
+
+func (c *shrinkingCoordinateConverter) Convert2DBoundingboxToWGS84Region(bbox *geometry.BoundingBox, srid int) ([]float64, error) {
+	dx, dy, dz := (bbox.Xmax-bbox.Xmin)/10, (bbox.Ymax-bbox.Ymin)/10, (bbox.Zmax-bbox.Zmin)/10
+	return []float64{bbox.Xmin + dx, bbox.Ymin + dy, bbox.Xmax - dx, bbox.Ymax - dy, bbox.Zmin + dz, bbox.Zmax - dz}, nil
+}
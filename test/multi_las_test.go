@@ -0,0 +1,113 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/app"
+	"github.com/mfbonfigli/gocesiumtiler/converters"
+	"github.com/mfbonfigli/gocesiumtiler/converters/offset_elevation_corrector"
+	"github.com/mfbonfigli/gocesiumtiler/lasread"
+	"github.com/mfbonfigli/gocesiumtiler/structs/geometry"
+	"github.com/mfbonfigli/gocesiumtiler/structs/point_loader"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// Converter shifting the x coordinate by the source srid, so that the srid each point was read with is recognizable
+type sridShiftingCoordinateConverter struct {
+	identityCoordinateConverter
+}
+
+func (c *sridShiftingCoordinateConverter) ConvertCoordinateSrid(sourceSrid int, targetSrid int, coord geometry.Coordinate) (geometry.Coordinate, error) {
+	x := *coord.X + float64(sourceSrid)
+	return geometry.Coordinate{X: &x, Y: coord.Y, Z: coord.Z}, nil
+}
+
+func (c *sridShiftingCoordinateConverter) ConvertCoordinateSridBatch(sourceSrid int, targetSrid int, coords []geometry.Coordinate) ([]geometry.Coordinate, error) {
+	return converters.ConvertEachCoordinateSrid(c, sourceSrid, targetSrid, coords)
+}
+
+func TestMultiLasLoaderReprojectsEachFileBeforeMerging(t *testing.T) {
+	files := writeBatchTestLasFiles(t, 3)
+	for _, file := range files {
+		defer os.RemoveAll(filepath.Dir(file))
+	}
+	srids := []int{1000, 2000, 3000}
+	zCorrections := make([]converters.ElevationCorrector, len(files))
+	for i := range zCorrections {
+		zCorrections[i] = offset_elevation_corrector.NewOffsetElevationCorrector(float64(i * 100))
+	}
+
+	loader := point_loader.NewRandomLoader(0)
+	lasFileLoader := lidario.NewLasFileLoader(&sridShiftingCoordinateConverter{}, nil, loader, nil, false)
+	multiLasLoader, err := lidario.NewMultiLasLoader(files, srids, zCorrections, lasFileLoader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := multiLasLoader.LoadLasFiles(); err != nil {
+		t.Fatal(err)
+	}
+
+	// file i stores x in [i, i+9] and z in [0, 12]
+	bounds := loader.GetBounds()
+	if bounds[0] != 1000 || bounds[1] != 3011 || bounds[4] != 0 || bounds[5] != 212 {
+		t.Errorf("Expected bounds spanning all the files, got %v", bounds)
+	}
+	counts := make(map[int]int)
+	loader.Initialize()
+	for {
+		point, shouldContinue := loader.GetNext()
+		if point != nil {
+			file := int(point.Z) / 100
+			if x := point.X - float64(srids[file]); x < float64(file) || x > float64(file+9) {
+				t.Errorf("Expected the point at %f to be read from file %d with srid %d", point.X, file, srids[file])
+			}
+			counts[file]++
+		}
+		if !shouldContinue {
+			break
+		}
+	}
+	if len(counts) != 3 || counts[0] != 200 || counts[1] != 200 || counts[2] != 200 {
+		t.Errorf("Expected 200 points per file, got %v", counts)
+	}
+}
+
+func TestMultiLasLoaderRequiresASridPerFile(t *testing.T) {
+	lasFileLoader := lidario.NewLasFileLoader(&identityCoordinateConverter{}, nil, point_loader.NewRandomLoader(0), nil, false)
+	if _, err := lidario.NewMultiLasLoader([]string{"a.las", "b.las"}, []int{4326}, nil, lasFileLoader); err == nil {
+		t.Errorf("Expected an error for missing srids")
+	}
+}
+
+func TestFilesAreReadWithTheirOwnSrid(t *testing.T) {
+	folder, err := os.MkdirTemp("", "gocesiumtiler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+	for i, file := range writeBatchTestLasFiles(t, 3) {
+		if err := os.Rename(file, filepath.Join(folder, "test"+strconv.Itoa(i)+".las")); err != nil {
+			t.Fatal(err)
+		}
+		_ = os.RemoveAll(filepath.Dir(file))
+	}
+
+	for _, merge := range []bool{false, true} {
+		opts := newTestOptions(t)
+		defer os.RemoveAll(opts.Output)
+		opts.Input = folder
+		opts.FolderProcessing = true
+		opts.MergeFiles = merge
+		opts.Srid = 32632
+		opts.FileSrids = map[string]int{"test1.las": 32633}
+		converter := &recordingCoordinateConverter{srids: make(map[int]bool)}
+		opts.CoordinateConverter = converter
+		if err := app.RunTiler(opts); err != nil {
+			t.Fatal(err)
+		}
+		if len(converter.srids) != 2 || !converter.srids[32632] || !converter.srids[32633] {
+			t.Errorf("Expected the points read from srids 32632 and 32633 with merge %t, got %v", merge, converter.srids)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package test
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"github.com/mfbonfigli/gocesiumtiler/app"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"github.com/mfbonfigli/gocesiumtiler/textread"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestNormalizedIntensityIsWrittenAsFloat(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.NormalizeIntensity = true
+	opts.MaxIntensity = 200
+	points := make([]*data.Point, 0)
+	for i := 0; i < 101; i++ {
+		points = append(points, data.NewPoint(float64(i), float64(i%7), float64(i%11), 0, 0, 0, uint8(i*2), 0))
+	}
+	writeTileset(t, points, opts)
+
+	file := filepath.Join(opts.Output, "content.pnts")
+	if err := io.ValidatePntsFile(file); err != nil {
+		t.Errorf("Expected a valid pnts layout, got %v", err)
+	}
+	assertNormalizedIntensities(t, file, len(points), 200)
+}
+
+func TestIntensityIsNormalizedByTheMaxLoadedIntensity(t *testing.T) {
+	content := ""
+	for i := 0; i < 50; i++ {
+		content += strconv.Itoa(i) + " " + strconv.Itoa(i%7) + " " + strconv.Itoa(i%11) + " " + strconv.Itoa(i*3) + "\n"
+	}
+	file := writeTestTextFile(t, "test.xyz", content)
+	defer os.RemoveAll(filepath.Dir(file))
+
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.Input = file
+	opts.TextColumns, _ = textread.ParseColumns("x,y,z,intensity")
+	opts.NormalizeIntensity = true
+	if err := app.RunTiler(opts); err != nil {
+		t.Fatal(err)
+	}
+	if opts.MaxIntensity != 0 {
+		t.Errorf("Expected the options not to be changed, got max intensity %d", opts.MaxIntensity)
+	}
+	assertNormalizedIntensities(t, filepath.Join(opts.Output, "test", "content.pnts"), 50, 147)
+}
+
+// Checks that the NORMALIZED_INTENSITY batch table property of the given content.pnts file stores the INTENSITY of
+// each point divided by the given max intensity, the max intensity being stored as 1
+func assertNormalizedIntensities(t *testing.T, file string, pointNo int, maxIntensity uint8) {
+	batchTable := readTestBatchTable(t, file)
+	property := struct {
+		ComponentType string `json:"componentType"`
+		Type          string `json:"type"`
+	}{}
+	if err := json.Unmarshal(batchTable["NORMALIZED_INTENSITY"], &property); err != nil {
+		t.Fatal(err)
+	}
+	if property.ComponentType != "FLOAT" || property.Type != "SCALAR" {
+		t.Errorf("Expected a FLOAT SCALAR property, got %s %s", property.ComponentType, property.Type)
+	}
+
+	intensities := readTestBatchTableBytes(t, file, batchTable, "INTENSITY", pointNo)
+	normalized := readTestBatchTableBytes(t, file, batchTable, "NORMALIZED_INTENSITY", pointNo*4)
+	max := float32(0)
+	for i, intensity := range intensities {
+		value := math.Float32frombits(binary.LittleEndian.Uint32(normalized[i*4 : i*4+4]))
+		if expected := float64(intensity) / float64(maxIntensity); math.Abs(float64(value)-expected) > 1e-6 {
+			t.Errorf("Expected normalized intensity %f for intensity %d, got %f", expected, intensity, value)
+		}
+		max = float32(math.Max(float64(max), float64(value)))
+	}
+	if max != 1 {
+		t.Errorf("Expected a max normalized intensity of 1, got %f", max)
+	}
+}
@@ -0,0 +1,128 @@
+package test
+
+import (
+	"fmt"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"github.com/mfbonfigli/gocesiumtiler/structs/geometry"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestEstimateNormalsOfPlane(t *testing.T) {
+	coords := make([]float64, 0)
+	for x := 0; x < 20; x++ {
+		for y := 0; y < 20; y++ {
+			coords = append(coords, float64(x), float64(y), 6378137+0.01*float64((x*7+y*3)%5))
+		}
+	}
+	normals := geometry.EstimateNormals(coords, 16)
+	for i := 0; i < len(normals)/3; i++ {
+		if math.Abs(normals[i*3+2]-1) > 1e-2 {
+			t.Fatalf("Expected normal close to (0, 0, 1), got (%f, %f, %f)", normals[i*3], normals[i*3+1], normals[i*3+2])
+		}
+	}
+}
+
+func TestNormalsAreWrittenOnlyForCoarseTiles(t *testing.T) {
+	opts := newTestOptions(t)
+	opts.MaxNumPointsPerNode = 50
+	opts.NormalsMaxDepth = 1
+	defer os.RemoveAll(opts.Output)
+	writeTileset(t, newTestPoints(), opts)
+
+	err := filepath.Walk(opts.Output, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(path) != ".pnts" {
+			return err
+		}
+		pnts, err := io.ReadPntsFile(path)
+		if err != nil {
+			return err
+		}
+		isRoot := filepath.Dir(path) == filepath.Clean(opts.Output)
+		if isRoot && len(pnts.Normals) != pnts.FeatureTable.PointsLength*3 {
+			t.Errorf("Expected normals in root tile")
+		}
+		if !isRoot && pnts.Normals != nil {
+			t.Errorf("Expected no normals in tile %s", path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNormalsAreWrittenInAllTilesIfComputed(t *testing.T) {
+	opts := newTestOptions(t)
+	opts.MaxNumPointsPerNode = 50
+	opts.ComputeNormals = true
+	defer os.RemoveAll(opts.Output)
+	writeTileset(t, newTestPoints(), opts)
+
+	tiles := 0
+	err := filepath.Walk(opts.Output, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(path) != ".pnts" {
+			return err
+		}
+		if err := io.ValidatePntsFile(path); err != nil {
+			return err
+		}
+		pnts, err := io.ReadPntsFile(path)
+		if err != nil {
+			return err
+		}
+		if len(pnts.Normals) != pnts.FeatureTable.PointsLength*3 {
+			t.Errorf("Expected normals in tile %s", path)
+		}
+		tiles++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tiles < 2 {
+		t.Errorf("Expected more than one tile, got %d", tiles)
+	}
+}
+
+func TestNormalNeighborsAreConfigurable(t *testing.T) {
+	// points on a bumpy surface, whose normals depend on the size of the fitted neighbourhood
+	points := make([]*data.Point, 0)
+	for x := 0; x < 20; x++ {
+		for y := 0; y < 20; y++ {
+			z := 6378137 + math.Sin(float64(x))*math.Cos(float64(y))
+			points = append(points, data.NewPoint(float64(x), float64(y), z, 0, 0, 0, 0, 0))
+		}
+	}
+	readNormals := func(neighbours int) []string {
+		opts := newTestOptions(t)
+		defer os.RemoveAll(opts.Output)
+		opts.ComputeNormals = true
+		opts.NormalNeighbors = neighbours
+		writeTileset(t, points, opts)
+		pnts, err := io.ReadPntsFile(filepath.Join(opts.Output, "content.pnts"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// points are stored in random order
+		normals := make([]string, 0, len(pnts.Normals)/3)
+		for i := 0; i < len(pnts.Normals); i += 3 {
+			normals = append(normals, fmt.Sprintf("%.4f %.4f %.4f", pnts.Normals[i], pnts.Normals[i+1], pnts.Normals[i+2]))
+		}
+		sort.Strings(normals)
+		return normals
+	}
+
+	defaultNormals := readNormals(0)
+	if explicitNormals := readNormals(16); !reflect.DeepEqual(defaultNormals, explicitNormals) {
+		t.Errorf("Expected the default number of neighbours to be 16")
+	}
+	if fewNormals := readNormals(3); reflect.DeepEqual(defaultNormals, fewNormals) {
+		t.Errorf("Expected the normals to depend on the number of neighbours")
+	}
+}
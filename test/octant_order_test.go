@@ -0,0 +1,108 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/structs/octree"
+	"github.com/mfbonfigli/gocesiumtiler/structs/point_loader"
+	"github.com/mfbonfigli/gocesiumtiler/utils"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseOctantOrder(t *testing.T) {
+	order, err := utils.ParseOctantOrder("0, 2,1,3,4,6,5,7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(order, []uint8{0, 2, 1, 3, 4, 6, 5, 7}) {
+		t.Errorf("Unexpected octant order %v", order)
+	}
+	if order, err := utils.ParseOctantOrder(""); err != nil || len(order) != 0 {
+		t.Errorf("Expected an empty order, got %v, %v", order, err)
+	}
+	for _, invalid := range []string{"0,1,2,3,4,5,6", "0,1,2,3,4,5,6,6", "0,1,2,3,4,5,6,8", "0,1,2,3,4,5,6,x"} {
+		if _, err := utils.ParseOctantOrder(invalid); err == nil {
+			t.Errorf("Expected octant order %s to be rejected", invalid)
+		}
+	}
+}
+
+func TestOctantOrderPermutesTheChildren(t *testing.T) {
+	reversed := []uint8{7, 6, 5, 4, 3, 2, 1, 0}
+	defaultTree := buildSeededTestTree(t, nil)
+	reversedTree := buildSeededTestTree(t, reversed)
+	assertPermutedChildren(t, &defaultTree.RootNode, &reversedTree.RootNode, reversed)
+
+	// implicit tiling keeps the Morton order of the octants
+	if !reflect.DeepEqual(io.ComputeSubtreeAvailability(&defaultTree.RootNode, 3), io.ComputeSubtreeAvailability(&reversedTree.RootNode, 3)) {
+		t.Errorf("Expected the subtree availability not to depend on the octant order")
+	}
+}
+
+func TestOctantOrderNamesTheTileFolders(t *testing.T) {
+	tree := buildSeededTestTree(t, []uint8{7, 6, 5, 4, 3, 2, 1, 0})
+	defer os.RemoveAll(tree.Opts.Output)
+	exportTree(t, tree, tree.Opts)
+
+	contents := make(map[string]int)
+	collectTileContents(t, tree.Opts.Output, "tileset.json", 1, contents)
+	checked := 0
+	root := tree.RootNode.BoundingBox
+	for content := range contents {
+		if !strings.HasPrefix(content, "0/") {
+			continue
+		}
+		// folder 0 stores the upper octant along all the axes
+		pnts, err := io.ReadPntsFile(filepath.Join(tree.Opts.Output, content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < len(pnts.Positions); i += 3 {
+			checked++
+			if pnts.Positions[i] <= root.Xmid || pnts.Positions[i+1] <= root.Ymid || pnts.Positions[i+2] <= root.Zmid {
+				t.Fatalf("Expected the points of folder 0 in the upper octant, got %v in %s", pnts.Positions[i:i+3], content)
+			}
+		}
+	}
+	if checked == 0 {
+		t.Errorf("Expected points in folder 0")
+	}
+}
+
+// Builds a tree of the test points, added in a reproducible order, with the given octant order
+func buildSeededTestTree(t *testing.T, octantOrder []uint8) *octree.OctTree {
+	opts := newTestOptions(t)
+	opts.MaxNumPointsPerNode = 20
+	opts.RandomSeed = 42
+	opts.OctantOrder = octantOrder
+	loader := point_loader.NewRandomLoader(0)
+	loader.SetRandomSeed(opts.RandomSeed)
+	for _, point := range newTestPoints() {
+		loader.AddElement(point)
+	}
+	tree := octree.NewOctTree(opts)
+	if err := tree.Build(loader); err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
+
+// Checks that the child with index i of each node of the permuted tree matches the child of the default tree storing
+// the octant order[i]
+func assertPermutedChildren(t *testing.T, node *octree.OctNode, permuted *octree.OctNode, order []uint8) {
+	if *node.BoundingBox != *permuted.BoundingBox || node.LocalChildrenCount != permuted.LocalChildrenCount {
+		t.Fatalf("Expected matching nodes at depth %d, got %v and %v", node.Depth, *node.BoundingBox, *permuted.BoundingBox)
+	}
+	for i, child := range permuted.Children {
+		expected := node.Children[order[i]]
+		if (child == nil) != (expected == nil) {
+			t.Fatalf("Expected child %d to match octant %d", i, order[i])
+		}
+		if child != nil {
+			assertPermutedChildren(t, expected, child, order)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOrientedBoxesContainThePointsOfTheirTiles(t *testing.T) {
+	for _, tc := range []struct {
+		name               string
+		lon, lat, size, dz float64
+	}{
+		{"equator", 12.5, 0, 0.01, 50},
+		{"mid latitude", -73.9, 40.7, 0.05, 300},
+		{"antimeridian", 179.99, -16.5, 0.019, 20},
+		{"north pole", 45, 89.99, 0.019, 10},
+	} {
+		opts := newTestOptions(t)
+		opts.Srid = 4326
+		opts.MaxNumPointsPerNode = 100
+		opts.CoordinateConverter = &geodeticCoordinateConverter{}
+		opts.BoundingVolumes = tiler.OrientedBoundingBoxes
+		writeTileset(t, newTestGeographicPoints(tc.lon, tc.lat, tc.size, tc.dz), opts)
+
+		boxes := make(map[string][]float64)
+		collectTileBoxes(t, opts.Output, "tileset.json", boxes)
+		if len(boxes) < 2 {
+			t.Errorf("%s: expected more than one tile, got %d", tc.name, len(boxes))
+		}
+		for content, box := range boxes {
+			if len(box) != 12 {
+				t.Fatalf("%s: invalid box %v of %s", tc.name, box, content)
+			}
+			for i := 0; i < 3; i++ {
+				for j := i + 1; j < 3; j++ {
+					if dot := box[3+3*i]*box[3+3*j] + box[4+3*i]*box[4+3*j] + box[5+3*i]*box[5+3*j]; math.Abs(dot) > 1e-6 {
+						t.Errorf("%s: expected orthogonal half axes for %s, got %v", tc.name, content, box)
+					}
+				}
+			}
+			pnts, err := io.ReadPntsFile(filepath.Join(opts.Output, content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < len(pnts.Positions); i += 3 {
+				if !isInTestBox(box, pnts.Positions[i:i+3]) {
+					t.Errorf("%s: point %v outside of the box %v of %s", tc.name, pnts.Positions[i:i+3], box, content)
+					break
+				}
+			}
+		}
+		_ = os.RemoveAll(opts.Output)
+	}
+}
+
+func TestOrientedBoxIsTighterThanTheBoundingSphere(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.Srid = 4326
+	opts.MaxNumPointsPerNode = 100
+	opts.CoordinateConverter = &geodeticCoordinateConverter{}
+	tree := buildTree(t, newTestGeographicPoints(-73.9, 40.7, 0.05, 300), opts)
+
+	volumes := make(map[tiler.BoundingVolumeMode]io.BoundingVolume)
+	for _, mode := range []tiler.BoundingVolumeMode{tiler.OrientedBoundingBoxes, tiler.BoundingSpheres} {
+		opts.BoundingVolumes = mode
+		exportTree(t, tree, opts)
+		tileset, err := io.ReadTilesetFile(filepath.Join(opts.Output, "tileset.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		volumes[mode] = tileset.Root.BoundingVolume
+	}
+	box, sphere := volumes[tiler.OrientedBoundingBoxes].Box, volumes[tiler.BoundingSpheres].Sphere
+	if volumes[tiler.OrientedBoundingBoxes].Region != nil || len(box) != 12 {
+		t.Fatalf("Expected only a box bounding volume, got %v", volumes[tiler.OrientedBoundingBoxes])
+	}
+	boxVolume := 8.0
+	for i := 0; i < 3; i++ {
+		boxVolume *= math.Sqrt(box[3+3*i]*box[3+3*i] + box[4+3*i]*box[4+3*i] + box[5+3*i]*box[5+3*i])
+	}
+	if sphereVolume := 4.0 / 3 * math.Pi * math.Pow(sphere[3], 3); boxVolume >= sphereVolume {
+		t.Errorf("Expected the box volume %f to be smaller than the sphere volume %f", boxVolume, sphereVolume)
+	}
+}
+
+// Checks if the given point is within the given box, given as center and half axes, with a centimeter tolerance for
+// the float32 positions
+func isInTestBox(box []float64, point []float64) bool {
+	for i := 0; i < 3; i++ {
+		axis := box[3+3*i : 6+3*i]
+		length := math.Sqrt(axis[0]*axis[0] + axis[1]*axis[1] + axis[2]*axis[2])
+		projection := ((point[0]-box[0])*axis[0] + (point[1]-box[1])*axis[1] + (point[2]-box[2])*axis[2]) / length
+		if math.Abs(projection) > length+0.01 {
+			return false
+		}
+	}
+	return true
+}
+
+// Follows the content urls of the given tileset.json, relative to the given folder, collecting the bounding box of
+// the tile of every referenced content.pnts file
+func collectTileBoxes(t *testing.T, folder string, tilesetFile string, boxes map[string][]float64) {
+	tileset, err := io.ReadTilesetFile(filepath.Join(folder, tilesetFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := path.Dir(tilesetFile)
+	boxes[path.Join(dir, tileset.Root.Content.Url)] = tileset.Root.BoundingVolume.Box
+	for _, child := range tileset.Root.Children {
+		url := path.Join(dir, child.Content.Url)
+		if strings.HasSuffix(url, ".json") {
+			collectTileBoxes(t, folder, url, boxes)
+		} else {
+			boxes[url] = child.BoundingVolume.Box
+		}
+	}
+}
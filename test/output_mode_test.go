@@ -0,0 +1,113 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/structs/geometry"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalEnuOutputIsPlacedOnTheGlobeByTheRootTransform(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.Srid = 4326
+	opts.CoordinateConverter = &geodeticCoordinateConverter{}
+	opts.MaxNumPointsPerNode = 100
+	opts.BoundingVolumes = tiler.BoundingSpheres
+	opts.OutputMode = tiler.LocalEnuCoordinates
+	points := newTestGeographicPoints(11.2558, 43.7696, 0.01, 60)
+	writeTileset(t, points, opts)
+
+	tileset, err := io.ReadTilesetFile(filepath.Join(opts.Output, "tileset.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tileset.Root.Transform) != 16 {
+		t.Fatalf("Expected a root transform, got %v", tileset.Root.Transform)
+	}
+	if sphere := tileset.Root.BoundingVolume.Sphere; math.Sqrt(sphere[0]*sphere[0]+sphere[1]*sphere[1]+sphere[2]*sphere[2]) > 1000 {
+		t.Errorf("Expected the root bounding sphere centered next to the local origin, got %v", sphere)
+	}
+
+	expected := make([][3]float64, len(points))
+	for i, point := range points {
+		coord, _ := opts.CoordinateConverter.ConvertToWGS84Cartesian(geometry.Coordinate{X: &point.X, Y: &point.Y, Z: &point.Z}, opts.Srid)
+		expected[i] = [3]float64{*coord.X, *coord.Y, *coord.Z}
+	}
+	placed := 0
+	identity := [16]float64{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}
+	for file, transform := range collectTestContentTransforms(t, opts.Output, "tileset.json", identity) {
+		pnts, err := io.ReadPntsFile(filepath.Join(opts.Output, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < len(pnts.Positions); i += 3 {
+			local := pnts.Positions[i : i+3]
+			if math.Abs(local[0]) > 1000 || math.Abs(local[1]) > 1000 || math.Abs(local[2]) > 1000 {
+				t.Fatalf("%s: expected local east north up coordinates, got %v", file, local)
+			}
+			var ecef [3]float64
+			for r := 0; r < 3; r++ {
+				ecef[r] = transform[r]*local[0] + transform[4+r]*local[1] + transform[8+r]*local[2] + transform[12+r]
+			}
+			nearest := math.MaxFloat64
+			for _, e := range expected {
+				nearest = math.Min(nearest, math.Sqrt(math.Pow(ecef[0]-e[0], 2)+math.Pow(ecef[1]-e[1], 2)+math.Pow(ecef[2]-e[2], 2)))
+			}
+			if nearest > 0.01 {
+				t.Errorf("%s: point placed at %v, %f meters away from the nearest input point", file, ecef, nearest)
+			}
+			placed++
+		}
+	}
+	if placed != len(points) {
+		t.Errorf("Expected %d placed points, got %d", len(points), placed)
+	}
+}
+
+func TestRawOutputKeepsTheInputCoordinatesAndWritesBoxes(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.MaxNumPointsPerNode = 50
+	opts.OutputMode = tiler.RawCoordinates
+	opts.RootTransform = [16]float64{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 100, 200, 300, 1}
+	points := newTestPoints()
+	for _, point := range points {
+		point.X, point.Y, point.Z = point.X+1000, point.Y+2000, point.Z+10
+	}
+	writeTileset(t, points, opts)
+
+	tileset, err := io.ReadTilesetFile(filepath.Join(opts.Output, "tileset.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tileset.Root.Transform) != 16 || tileset.Root.Transform[12] != 100 || tileset.Root.Transform[13] != 200 || tileset.Root.Transform[14] != 300 {
+		t.Errorf("Expected the root transform of the options, got %v", tileset.Root.Transform)
+	}
+	boxes := make(map[string][]float64)
+	collectTileBoxes(t, opts.Output, "tileset.json", boxes)
+	for file, box := range boxes {
+		if len(box) != 12 {
+			t.Fatalf("%s: expected a bounding box rather than a region, got %v", file, box)
+		}
+		pnts, err := io.ReadPntsFile(filepath.Join(opts.Output, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < len(pnts.Positions); i += 3 {
+			x, y, z := pnts.Positions[i], pnts.Positions[i+1], pnts.Positions[i+2]
+			if x < 1000-1e-3 || x > 1009+1e-3 || y < 2000-1e-3 || y > 2006+1e-3 || z < 10-1e-3 || z > 22+1e-3 {
+				t.Errorf("%s: expected the input coordinates, got %f, %f, %f", file, x, y, z)
+			}
+			if math.Abs(x-box[0]) > math.Abs(box[3])+1e-3 || math.Abs(y-box[1]) > math.Abs(box[7])+1e-3 || math.Abs(z-box[2]) > math.Abs(box[11])+1e-3 {
+				t.Errorf("%s: expected the point %f, %f, %f inside the box %v", file, x, y, z, box)
+			}
+		}
+	}
+	if len(boxes) == 0 {
+		t.Errorf("Expected some tiles")
+	}
+}
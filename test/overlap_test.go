@@ -0,0 +1,172 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"github.com/mfbonfigli/gocesiumtiler/structs/point_loader"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Returns the points of a flightline with the given point source id, one per square meter in the given X range and
+// in Y from 0 to 100
+func newTestFlightlinePoints(source uint16, minX, maxX int) []*data.Point {
+	points := make([]*data.Point, 0)
+	for x := minX; x < maxX; x++ {
+		for y := 0; y < 100; y++ {
+			point := data.NewPoint(float64(x)+0.5, float64(y)+0.5, 0, 0, 0, 0, 0, 0)
+			point.PointSourceId = source
+			points = append(points, point)
+		}
+	}
+	return points
+}
+
+// Adds the given points to a new OverlapLoader with the given cell size and returns the thinned points
+func thinTestPoints(points []*data.Point, cellSize float64, geographic bool) ([]*data.Point, int64) {
+	overlapLoader := point_loader.NewOverlapLoader(point_loader.NewRandomLoader(0), cellSize, geographic)
+	for _, point := range points {
+		overlapLoader.AddElement(point)
+	}
+	overlapLoader.GetBounds()
+	overlapLoader.Initialize()
+	thinned := make([]*data.Point, 0)
+	for {
+		point, shouldContinue := overlapLoader.GetNext()
+		if point != nil {
+			thinned = append(thinned, point)
+		}
+		if !shouldContinue {
+			break
+		}
+	}
+	return thinned, overlapLoader.GetDroppedCount()
+}
+
+func TestOverlapThinningMatchesTheSingleCoverageDensity(t *testing.T) {
+	// two flightlines overlapping between x 60 and 100
+	points := append(newTestFlightlinePoints(1, 0, 100), newTestFlightlinePoints(2, 60, 160)...)
+	thinned, dropped := thinTestPoints(points, 10, false)
+
+	if len(thinned)+int(dropped) != len(points) {
+		t.Fatalf("Expected %d kept and dropped points, got %d kept and %d dropped", len(points), len(thinned), dropped)
+	}
+	if len(thinned) != 160*100 {
+		t.Errorf("Expected a point per square meter, %d points, got %d", 160*100, len(thinned))
+	}
+
+	// every cell keeps the points of a single flightline, with the single coverage density and no gaps
+	cells := make(map[[2]int]map[uint16]int)
+	for _, point := range thinned {
+		cell := [2]int{int(point.X / 10), int(point.Y / 10)}
+		if cells[cell] == nil {
+			cells[cell] = make(map[uint16]int)
+		}
+		cells[cell][point.PointSourceId]++
+	}
+	if len(cells) != 16*10 {
+		t.Errorf("Expected %d cells with points, got %d", 16*10, len(cells))
+	}
+	for cell, sources := range cells {
+		if len(sources) != 1 {
+			t.Errorf("Expected the points of a single flightline in cell %v, got %v", cell, sources)
+		}
+		for _, count := range sources {
+			if count != 100 {
+				t.Errorf("Expected 100 points in cell %v, got %d", cell, count)
+			}
+		}
+	}
+}
+
+func TestOverlapThinningPrefersTheFlightlineWithTheMostPoints(t *testing.T) {
+	// in the overlap the second flightline has twice the density of the first
+	points := append(newTestFlightlinePoints(1, 0, 10), newTestFlightlinePoints(2, 0, 10)...)
+	points = append(points, newTestFlightlinePoints(2, 0, 10)...)
+	thinned, _ := thinTestPoints(points, 10, false)
+	for _, point := range thinned {
+		if point.PointSourceId != 2 {
+			t.Fatalf("Expected only the points of the denser flightline, got a point of flightline %d", point.PointSourceId)
+		}
+	}
+	if len(thinned) != 2000 {
+		t.Errorf("Expected 2000 points, got %d", len(thinned))
+	}
+}
+
+func TestOverlapThinningDropsTheOverlapFlaggedPoints(t *testing.T) {
+	// overlap flagged points cover x 0 to 20, unflagged ones x 10 to 30
+	flagged := newTestFlightlinePoints(1, 0, 20)
+	for _, point := range flagged {
+		point.Overlap = true
+	}
+	points := append(flagged, newTestFlightlinePoints(2, 10, 30)...)
+	thinned, dropped := thinTestPoints(points, 10, false)
+	if dropped != 1000 {
+		t.Errorf("Expected the 1000 overlap points alongside unflagged ones to be dropped, got %d", dropped)
+	}
+	for _, point := range thinned {
+		if point.Overlap && point.X >= 10 {
+			t.Errorf("Unexpected overlap point at x %f, where unflagged points are available", point.X)
+		}
+		if point.Overlap != (point.X < 10) {
+			t.Errorf("Expected the overlap points to be kept only where they are the only coverage, got a point at x %f", point.X)
+		}
+	}
+}
+
+func TestOverlapThinningCellsAreInMetersForGeographicCoordinates(t *testing.T) {
+	// two flightlines at latitude 60, where a degree of longitude spans half the meters of a degree of latitude
+	points := make([]*data.Point, 0)
+	for i := 0; i < 2; i++ {
+		point := data.NewPoint(10, 60, 0, 0, 0, 0, 0, 0)
+		point.PointSourceId = uint16(i + 1)
+		points = append(points, point)
+	}
+	// 40 meters east along the parallel, farther than a 30 meters cell
+	points[1].X += 40 / (111320 * math.Cos(60*math.Pi/180))
+	if thinned, _ := thinTestPoints(points, 30, true); len(thinned) != 2 {
+		t.Errorf("Expected the points of both flightlines in distinct cells, got %d points", len(thinned))
+	}
+	// 5 meters east, within the same cell
+	points[1].X = 10 + 5/(111320*math.Cos(60*math.Pi/180))
+	if thinned, _ := thinTestPoints(points, 30, true); len(thinned) != 1 {
+		t.Errorf("Expected the points of a single flightline in the same cell, got %d points", len(thinned))
+	}
+}
+
+func TestLasPointSourceIdAndOverlapAreRead(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		versionMinor byte
+		pointFormat  byte
+		recordLength int
+	}{
+		{"point format 1", 2, 1, 28},
+		{"point format 6", 4, 6, 30},
+	} {
+		points := []testLasPoint{
+			{raw: [3]int32{1, 2, 3}, classification: 2, pointSourceId: 7},
+			{raw: [3]int32{4, 5, 6}, classification: 2, pointSourceId: 65535, overlap: true},
+		}
+		if tc.pointFormat < 6 {
+			// legacy point formats flag overlap points with the class 12
+			points[1].classification = 12
+		}
+		file := writeTestLasRecords(t, tc.versionMinor, tc.pointFormat, tc.recordLength, points)
+		defer os.RemoveAll(filepath.Dir(file))
+		for _, point := range readTestLasFile(t, file) {
+			switch point.X {
+			case 1:
+				if point.PointSourceId != 7 || point.Overlap {
+					t.Errorf("%s: expected point source id 7 without overlap, got %d %t", tc.name, point.PointSourceId, point.Overlap)
+				}
+			case 4:
+				if point.PointSourceId != 65535 || !point.Overlap {
+					t.Errorf("%s: expected point source id 65535 flagged as overlap, got %d %t", tc.name, point.PointSourceId, point.Overlap)
+				}
+			}
+		}
+	}
+}
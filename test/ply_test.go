@@ -0,0 +1,128 @@
+package test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/mfbonfigli/gocesiumtiler/app"
+	"github.com/mfbonfigli/gocesiumtiler/converters/offset_elevation_corrector"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/plyread"
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"github.com/mfbonfigli/gocesiumtiler/structs/point_loader"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// Writes a test.ply file with the given header and body in a new folder. Returns the path of the written file.
+func writeTestPlyFile(t *testing.T, header string, body []byte) string {
+	folder, err := os.MkdirTemp("", "gocesiumtiler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(folder, "test.ply")
+	if err := os.WriteFile(file, append([]byte(header), body...), 0666); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func newTestPlyPoints() [][3]int32 {
+	points := make([][3]int32, 0)
+	for i := 0; i < 200; i++ {
+		points = append(points, [3]int32{int32(i % 10), int32(i % 7), int32(i % 13)})
+	}
+	return points
+}
+
+// Tiles the given file and the LAS file with the same points and checks that the tilesets match
+func assertTilesLikeLas(t *testing.T, file string, points [][3]int32) {
+	lasFile := writeTestLasFile(t, testLasHeader{scale: [3]float64{1, 1, 1}}, points)
+	defer os.RemoveAll(filepath.Dir(lasFile))
+	defer os.RemoveAll(filepath.Dir(file))
+
+	lasOpts := newTestOptions(t)
+	defer os.RemoveAll(lasOpts.Output)
+	lasOpts.Input = lasFile
+	if err := app.RunTiler(lasOpts); err != nil {
+		t.Fatal(err)
+	}
+	plyOpts := newTestOptions(t)
+	defer os.RemoveAll(plyOpts.Output)
+	plyOpts.Input = file
+	if err := app.RunTiler(plyOpts); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := io.CompareTilesetFolders(filepath.Join(lasOpts.Output, "test"), filepath.Join(plyOpts.Output, "test"), 1e-3); err != nil {
+		t.Errorf("Expected the PLY tileset to match the LAS one, got %v", err)
+	}
+}
+
+func TestAsciiPlyTilesLikeLas(t *testing.T) {
+	points := newTestPlyPoints()
+	header := "ply\nformat ascii 1.0\ncomment test\nelement vertex " + strconv.Itoa(len(points)) +
+		"\nproperty float x\nproperty float y\nproperty float z\nend_header\n"
+	var body bytes.Buffer
+	for _, point := range points {
+		body.WriteString(strconv.Itoa(int(point[0])) + " " + strconv.Itoa(int(point[1])) + " " + strconv.Itoa(int(point[2])) + "\n")
+	}
+	assertTilesLikeLas(t, writeTestPlyFile(t, header, body.Bytes()), points)
+}
+
+func TestBinaryPlyTilesLikeLas(t *testing.T) {
+	points := newTestPlyPoints()
+	header := "ply\nformat binary_little_endian 1.0\nelement vertex " + strconv.Itoa(len(points)) +
+		"\nproperty double x\nproperty double y\nproperty uchar alpha\nproperty double z\n" +
+		"element face 1\nproperty list uchar int vertex_indices\nend_header\n"
+	var body bytes.Buffer
+	for _, point := range points {
+		_ = binary.Write(&body, binary.LittleEndian, []float64{float64(point[0]), float64(point[1])})
+		body.WriteByte(255)
+		_ = binary.Write(&body, binary.LittleEndian, float64(point[2]))
+	}
+	body.WriteByte(3)
+	_ = binary.Write(&body, binary.LittleEndian, []int32{0, 1, 2})
+	assertTilesLikeLas(t, writeTestPlyFile(t, header, body.Bytes()), points)
+}
+
+func TestPlyColorsAndClassificationsAreRead(t *testing.T) {
+	header := "ply\nformat binary_big_endian 1.0\nelement face 1\nproperty list uchar int vertex_indices\n" +
+		"element vertex 1\nproperty float x\nproperty float y\nproperty float z\nproperty uchar red\n" +
+		"property ushort green\nproperty float blue\nproperty uchar classification\nend_header\n"
+	var body bytes.Buffer
+	body.WriteByte(1)
+	_ = binary.Write(&body, binary.BigEndian, int32(0))
+	_ = binary.Write(&body, binary.BigEndian, []float32{1.5, 2.5, 3.5})
+	body.WriteByte(10)
+	_ = binary.Write(&body, binary.BigEndian, uint16(20*256+7))
+	_ = binary.Write(&body, binary.BigEndian, float32(1))
+	body.WriteByte(6)
+	file := writeTestPlyFile(t, header, body.Bytes())
+	defer os.RemoveAll(filepath.Dir(file))
+
+	loader := point_loader.NewRandomLoader(0)
+	plyFileLoader := plyread.NewPlyFileLoader(&identityCoordinateConverter{}, loader, nil)
+	if err := plyFileLoader.LoadPlyFile(file, offset_elevation_corrector.NewOffsetElevationCorrector(0), 4326); err != nil {
+		t.Fatal(err)
+	}
+	loader.Initialize()
+	point, _ := loader.GetNext()
+	expected := data.NewPoint(1.5, 2.5, 3.5, 10*257, 20*256+7, 65535, 0, 6)
+	if point == nil || math.Abs(point.X-expected.X) > 1e-9 || math.Abs(point.Y-expected.Y) > 1e-9 || math.Abs(point.Z-expected.Z) > 1e-9 ||
+		point.R != expected.R || point.G != expected.G || point.B != expected.B || point.Classification != expected.Classification {
+		t.Errorf("Expected point %v, got %v", expected, point)
+	}
+}
+
+func TestPlyWithoutCoordinatesIsRejected(t *testing.T) {
+	file := writeTestPlyFile(t, "ply\nformat ascii 1.0\nelement vertex 1\nproperty float x\nproperty float y\nend_header\n1 2\n", nil)
+	defer os.RemoveAll(filepath.Dir(file))
+
+	plyFileLoader := plyread.NewPlyFileLoader(&identityCoordinateConverter{}, point_loader.NewRandomLoader(0), nil)
+	if err := plyFileLoader.LoadPlyFile(file, offset_elevation_corrector.NewOffsetElevationCorrector(0), 4326); err == nil {
+		t.Errorf("Expected an error for a PLY file without z property, got nil")
+	}
+}
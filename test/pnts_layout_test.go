@@ -0,0 +1,93 @@
+package test
+
+import (
+	"bytes"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestPntsLayoutIsValidForAllBufferCombinations(t *testing.T) {
+	for _, normalsMaxDepth := range []int{0, 10} {
+		for _, classificationAlpha := range []map[uint8]uint8{nil, {0: 128}} {
+			opts := newTestOptions(t)
+			opts.MaxNumPointsPerNode = 50
+			opts.NormalsMaxDepth = normalsMaxDepth
+			opts.ClassificationAlpha = classificationAlpha
+			writeTileset(t, newTestPoints(), opts)
+			name := "normals depth " + strconv.Itoa(normalsMaxDepth) + ", alpha " + strconv.FormatBool(classificationAlpha != nil)
+
+			files := 0
+			err := filepath.Walk(opts.Output, func(filePath string, info os.FileInfo, err error) error {
+				if err != nil || info.Name() != "content.pnts" {
+					return err
+				}
+				files++
+				if err := io.ValidatePntsFile(filePath); err != nil {
+					t.Errorf("Expected a valid pnts layout with %s, got %v", name, err)
+				}
+				pnts, err := io.ReadPntsFile(filePath)
+				if err != nil {
+					return err
+				}
+				if (pnts.Normals != nil) != (normalsMaxDepth > 0) {
+					t.Errorf("Expected normals only if enabled with %s", name)
+				}
+				if (pnts.FeatureTable.Rgba != nil) != (classificationAlpha != nil) {
+					t.Errorf("Expected RGBA colors only if alpha is enabled with %s", name)
+				}
+				for i := 0; i < len(pnts.Positions); i += 3 {
+					x, y, z := pnts.Positions[i], pnts.Positions[i+1], pnts.Positions[i+2]
+					if !isTestPoint(x, y, z) {
+						t.Errorf("Expected decoded position %f, %f, %f to be a test point with %s", x, y, z, name)
+						break
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if files == 0 {
+				t.Errorf("Expected content.pnts files to be written with %s", name)
+			}
+			_ = os.RemoveAll(opts.Output)
+		}
+	}
+}
+
+// Checks if the given position matches, within the float32 precision, one of the points returned by newTestPoints
+func isTestPoint(x, y, z float64) bool {
+	for _, point := range newTestPoints() {
+		if math.Abs(point.X-x) < 1e-3 && math.Abs(point.Y-y) < 1e-3 && math.Abs(point.Z-z) < 1e-3 {
+			return true
+		}
+	}
+	return false
+}
+
+func TestOverlappingPntsArraysAreDetected(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	writeTileset(t, newTestPoints(), opts)
+	file := filepath.Join(opts.Output, "content.pnts")
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// moves the RGB array onto the positions, keeping the feature table json length
+	corrupted := bytes.Replace(content, []byte("\"RGB\":{\"byteOffset\":6000}"), []byte("\"RGB\":{\"byteOffset\":0000}"), 1)
+	if bytes.Equal(corrupted, content) {
+		t.Fatal("Expected the RGB array at byte offset 6000")
+	}
+	if err := os.WriteFile(file, corrupted, 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := io.ValidatePntsFile(file); err == nil {
+		t.Errorf("Expected an error for overlapping arrays, got nil")
+	}
+}
@@ -0,0 +1,153 @@
+package test
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestPntsHeaderLengthsMatchTheSections(t *testing.T) {
+	variants := map[string]func(opts *tiler.TilerOptions){
+		"default":   func(opts *tiler.TilerOptions) {},
+		"quantized": func(opts *tiler.TilerOptions) { opts.QuantizePositions = true; opts.ComputeNormals = true },
+		"rgb565":    func(opts *tiler.TilerOptions) { opts.Rgb565Colors = true; opts.ColorDepth = 16 },
+		"alpha": func(opts *tiler.TilerOptions) {
+			opts.ClassificationAlpha = map[uint8]uint8{1: 128}
+			opts.NormalizeIntensity = true
+		},
+		"deflate": func(opts *tiler.TilerOptions) { opts.DeflateBuffers = true; opts.NormalizeIntensity = true },
+	}
+	for name, configure := range variants {
+		opts := newTestOptions(t)
+		_ = os.RemoveAll(opts.Output)
+		configure(opts)
+		tree := buildTree(t, newTestPoints(), opts)
+		for _, pointNo := range []int{0, 1, 20000} {
+			label := name + " with " + strconv.Itoa(pointNo) + " points"
+			content, err := io.EncodePnts(&tree.RootNode, newGpsTestPoints(pointNo), opts, opts.CoordinateConverter)
+			if err != nil {
+				t.Fatalf("Unexpected error encoding %s: %v", label, err)
+			}
+			assertPntsHeaderLengths(t, label, content)
+			if err := io.ValidatePnts(content); err != nil {
+				t.Errorf("Expected a valid pnts layout for %s, got %v", label, err)
+			}
+		}
+	}
+}
+
+func TestEmptyPntsIsDecodable(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	tree := buildTree(t, newTestPoints(), opts)
+	content, err := io.EncodePnts(&tree.RootNode, nil, opts, opts.CoordinateConverter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(opts.Output, "content.pnts")
+	if err := os.WriteFile(file, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	pnts, err := io.ReadPntsFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pnts.FeatureTable.PointsLength != 0 || len(pnts.Positions) != 0 {
+		t.Errorf("Expected no points, got %d", pnts.FeatureTable.PointsLength)
+	}
+	for _, value := range pnts.FeatureTable.RtcCenter {
+		if value != 0 {
+			t.Errorf("Expected the RTC_CENTER at the origin, got %v", pnts.FeatureTable.RtcCenter)
+			break
+		}
+	}
+}
+
+// Returns the given number of points with return numbers and GPS times, filling all the batch table properties
+func newGpsTestPoints(pointNo int) []*data.Point {
+	points := make([]*data.Point, pointNo)
+	for i := range points {
+		points[i] = data.NewPoint(float64(i%10), float64(i%7), float64(i%13), uint16(i), uint16(2*i), uint16(3*i), uint8(i), uint8(i%3))
+		points[i].ReturnNumber = 1
+		points[i].NumberOfReturns = 2
+		points[i].GpsTime = float64(i)
+	}
+	return points
+}
+
+// Fails the test unless the lengths in the header of the given content.pnts add up to its size and the declared byte
+// length matches it
+func assertPntsHeaderLengths(t *testing.T, name string, content []byte) {
+	if len(content) < 28 || string(content[0:4]) != "pnts" {
+		t.Fatalf("Expected a pnts header for %s", name)
+	}
+	lengths := make([]int, 6)
+	for i := range lengths {
+		lengths[i] = int(binary.LittleEndian.Uint32(content[4+4*i:]))
+	}
+	if lengths[1] != len(content) {
+		t.Errorf("Expected the byte length of %s to be %d, got %d", name, len(content), lengths[1])
+	}
+	if sections := 28 + lengths[2] + lengths[3] + lengths[4] + lengths[5]; sections != len(content) {
+		t.Errorf("Expected the header and the sections of %s to span %d bytes, got %d", name, len(content), sections)
+	}
+	if lengths[2]%4 != 0 || lengths[4]%4 != 0 {
+		t.Errorf("Expected the table json lengths of %s to be 4 byte aligned, got %d and %d", name, lengths[2], lengths[4])
+	}
+}
+
+func TestPntsTablesAreValidJson(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.QuantizePositions = true
+	opts.ColorDepth = 16
+	opts.DeflateBuffers = true
+	tree := buildTree(t, newTestPoints(), opts)
+	content, err := io.EncodePnts(&tree.RootNode, newGpsTestPoints(100), opts, opts.CoordinateConverter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	featureTableLen := int(binary.LittleEndian.Uint32(content[12:16]))
+	featureTableBinaryLen := int(binary.LittleEndian.Uint32(content[16:20]))
+	batchTableLen := int(binary.LittleEndian.Uint32(content[20:24]))
+	batchTableStart := 28 + featureTableLen + featureTableBinaryLen
+
+	featureTable := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(content[28:28+featureTableLen], &featureTable); err != nil {
+		t.Fatalf("Expected a valid feature table json, got %v", err)
+	}
+	for _, key := range []string{"POINTS_LENGTH", "RTC_CENTER", "QUANTIZED_VOLUME_OFFSET", "QUANTIZED_VOLUME_SCALE", "POSITION_QUANTIZED", "RGB", "extensions"} {
+		if _, ok := featureTable[key]; !ok {
+			t.Errorf("Expected %s in the feature table", key)
+		}
+	}
+	for _, key := range []string{"POSITION", "NORMAL", "RGBA", "RGB565"} {
+		if _, ok := featureTable[key]; ok {
+			t.Errorf("Expected %s to be omitted from the feature table", key)
+		}
+	}
+
+	batchTable := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(content[batchTableStart:batchTableStart+batchTableLen], &batchTable); err != nil {
+		t.Fatalf("Expected a valid batch table json, got %v", err)
+	}
+	for _, name := range []string{"INTENSITY", "CLASSIFICATION", "RETURN_NUMBER", "NUMBER_OF_RETURNS", "RGB16", "GPS_TIME"} {
+		property := struct {
+			ByteOffset    *int   `json:"byteOffset"`
+			ComponentType string `json:"componentType"`
+			Type          string `json:"type"`
+		}{}
+		if err := json.Unmarshal(batchTable[name], &property); err != nil || property.ByteOffset == nil || property.ComponentType == "" || property.Type == "" {
+			t.Errorf("Expected the batch table property %s with byte offset, component type and type, got %s", name, batchTable[name])
+		}
+	}
+	if _, ok := batchTable["extensions"]; !ok {
+		t.Errorf("Expected the deflate extension in the batch table")
+	}
+}
@@ -0,0 +1,97 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/converters/offset_elevation_corrector"
+	"github.com/mfbonfigli/gocesiumtiler/lasread"
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"github.com/mfbonfigli/gocesiumtiler/structs/octree"
+	"github.com/mfbonfigli/gocesiumtiler/structs/point_loader"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestPointSlabAllocatesPointsInSlabs(t *testing.T) {
+	var points []*data.Point
+	allocs := testing.AllocsPerRun(10, func() {
+		slab := data.NewPointSlab(256)
+		points = make([]*data.Point, 0, 1024)
+		for i := 0; i < 1024; i++ {
+			points = append(points, slab.NewPoint(float64(i), 2, 3, 4, 5, 6, 7, 8))
+		}
+	})
+	// the slab, the slice of points and 4 slabs of 256 points
+	if allocs > 6 {
+		t.Errorf("Expected at most 6 allocations for 1024 points, got %f", allocs)
+	}
+
+	for i, point := range points {
+		expected := data.NewPoint(float64(i), 2, 3, 4, 5, 6, 7, 8)
+		if point.X != expected.X || point.Y != expected.Y || point.Z != expected.Z || point.R != expected.R ||
+			point.G != expected.G || point.B != expected.B || point.Intensity != expected.Intensity ||
+			point.Classification != expected.Classification || point.HasGpsTime() {
+			t.Fatalf("Expected point %d to be %+v, got %+v", i, *expected, *point)
+		}
+	}
+	points[0].X = -1
+	if points[1].X != 1 {
+		t.Errorf("Expected the points of a slab to be independent")
+	}
+}
+
+// Keeps the benchmarked points on the heap
+var benchmarkPoint *data.Point
+
+func BenchmarkNewPoint(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchmarkPoint = data.NewPoint(float64(i), 2, 3, 4, 5, 6, 7, 8)
+	}
+}
+
+func BenchmarkPointSlab(b *testing.B) {
+	b.ReportAllocs()
+	slab := data.NewPointSlab(data.DefaultPointSlabSize)
+	for i := 0; i < b.N; i++ {
+		benchmarkPoint = slab.NewPoint(float64(i), 2, 3, 4, 5, 6, 7, 8)
+	}
+}
+
+// Reads a LAS file of 1 million points and builds its octree
+func BenchmarkLargeCloudBuild(b *testing.B) {
+	rawPoints := make([][3]int32, 1000000)
+	for i := range rawPoints {
+		rawPoints[i] = [3]int32{int32(i % 1000), int32(i / 1000), int32(i % 97)}
+	}
+	file := writeTestLasFile(b, testLasHeader{scale: [3]float64{0.01, 0.01, 0.01}}, rawPoints)
+	defer os.RemoveAll(filepath.Dir(file))
+	opts := &tiler.TilerOptions{
+		Srid:                4978,
+		MaxNumPointsPerNode: 5000,
+		CoordinateConverter: &identityCoordinateConverter{},
+	}
+
+	b.ReportAllocs()
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		loader := point_loader.NewRandomLoader(0)
+		lasFileLoader := lidario.NewLasFileLoader(&identityCoordinateConverter{}, nil, loader, nil, false)
+		lf, err := lasFileLoader.LoadLasFile(file, offset_elevation_corrector.NewOffsetElevationCorrector(0), 4326)
+		if err != nil {
+			b.Fatal(err)
+		}
+		_ = lf.Close()
+		if err := octree.NewOctTree(opts).Build(loader); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+	runtime.ReadMemStats(&after)
+	b.ReportMetric(float64(after.PauseTotalNs-before.PauseTotalNs)/1e6/float64(b.N), "gc-pause-ms/op")
+	b.ReportMetric(float64(after.NumGC-before.NumGC)/float64(b.N), "gcs/op")
+}
@@ -0,0 +1,101 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/app"
+	"github.com/mfbonfigli/gocesiumtiler/utils"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Progress callback recording the reported counts of each stage
+type progressRecorder struct {
+	sync.Mutex
+	done   map[string][]int64
+	totals map[string]int64
+}
+
+func newProgressRecorder() *progressRecorder {
+	return &progressRecorder{done: make(map[string][]int64), totals: make(map[string]int64)}
+}
+
+func (recorder *progressRecorder) callback(stage string, done, total int64) {
+	recorder.Lock()
+	defer recorder.Unlock()
+	recorder.done[stage] = append(recorder.done[stage], done)
+	recorder.totals[stage] = total
+}
+
+func TestProgressReportsAreThrottledAndComplete(t *testing.T) {
+	recorder := newProgressRecorder()
+	reporter := utils.NewProgressReporter(recorder.callback, "stage", 80000)
+	var waitGroup sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < 8; i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			for j := 0; j < 10000; j++ {
+				reporter.Add(1)
+			}
+		}()
+	}
+	waitGroup.Wait()
+	elapsed := time.Since(start)
+
+	done := recorder.done["stage"]
+	if len(done) == 0 || done[len(done)-1] != 80000 || recorder.totals["stage"] != 80000 {
+		t.Fatalf("Expected a final report of 80000 of 80000, got %v", done)
+	}
+	if maxReports := int(elapsed/utils.ProgressReportInterval) + 2; len(done) > maxReports {
+		t.Errorf("Expected at most %d reports in %v, got %d", maxReports, elapsed, len(done))
+	}
+	for i := 1; i < len(done); i++ {
+		if done[i] <= done[i-1] {
+			t.Errorf("Expected increasing reports, got %v", done)
+		}
+	}
+}
+
+func TestNilProgressReporterIsANoOp(t *testing.T) {
+	reporter := utils.NewProgressReporter(nil, "stage", 10)
+	if reporter != nil {
+		t.Fatalf("Expected a nil reporter for a nil callback")
+	}
+	reporter.Add(1)
+}
+
+func TestTilingReportsReadPointsAndWrittenTiles(t *testing.T) {
+	file := writeGridTestLasFile(t, 20000)
+	defer os.RemoveAll(filepath.Dir(file))
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.Input = file
+	opts.MaxNumPointsPerNode = 500
+	recorder := newProgressRecorder()
+	opts.ProgressCallback = recorder.callback
+	if err := app.RunTiler(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	reading := recorder.done[utils.ProgressStageReading]
+	if len(reading) == 0 || reading[len(reading)-1] != 20000 || recorder.totals[utils.ProgressStageReading] != 20000 {
+		t.Errorf("Expected the reading of 20000 points to be reported, got %v", reading)
+	}
+	tiles := int64(0)
+	err := filepath.Walk(opts.Output, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.Name() == "content.pnts" {
+			tiles++
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writing := recorder.done[utils.ProgressStageWriting]
+	if tiles < 2 || len(writing) == 0 || writing[len(writing)-1] != tiles || recorder.totals[utils.ProgressStageWriting] != tiles {
+		t.Errorf("Expected the writing of %d tiles to be reported, got %v", tiles, writing)
+	}
+}
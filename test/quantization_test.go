@@ -0,0 +1,74 @@
+package test
+
+import (
+	"encoding/binary"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestQuantizedPositionsAreWithinTheErrorBound(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.MaxNumPointsPerNode = 50
+	tree := buildTree(t, newTestPoints(), opts)
+	exportTree(t, tree, opts)
+	quantizedOpts := newTestOptions(t)
+	defer os.RemoveAll(quantizedOpts.Output)
+	quantizedOpts.MaxNumPointsPerNode = 50
+	quantizedOpts.QuantizePositions = true
+	exportTree(t, tree, quantizedOpts)
+
+	tiles := 0
+	err := filepath.Walk(opts.Output, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(path) != ".pnts" {
+			return err
+		}
+		quantizedPath := filepath.Join(quantizedOpts.Output, strings.TrimPrefix(path, opts.Output))
+		if err := io.ValidatePntsFile(quantizedPath); err != nil {
+			return err
+		}
+		expected, err := io.ReadPntsFile(path)
+		if err != nil {
+			return err
+		}
+		actual, err := io.ReadPntsFile(quantizedPath)
+		if err != nil {
+			return err
+		}
+		if actual.FeatureTable.Position != nil || actual.FeatureTable.PositionQuantized == nil {
+			t.Fatalf("Expected only quantized positions in %s", quantizedPath)
+		}
+		expectedBinaryLength, actualBinaryLength := readFeatureTableBinaryLength(t, path), readFeatureTableBinaryLength(t, quantizedPath)
+		if expectedBinaryLength-actualBinaryLength != expected.FeatureTable.PointsLength*6 {
+			t.Errorf("Expected 6 bytes less per point in %s, got %d instead of %d bytes", quantizedPath, actualBinaryLength, expectedBinaryLength)
+		}
+		for i, position := range actual.Positions {
+			// half a quantization step plus the float32 rounding of the reference positions
+			tolerance := actual.FeatureTable.QuantizedVolumeScale[i%3]/131070 + 1e-5
+			if math.Abs(position-expected.Positions[i]) > tolerance {
+				t.Errorf("Expected position %f within %f of %f in %s", position, tolerance, expected.Positions[i], quantizedPath)
+			}
+		}
+		tiles++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tiles < 2 {
+		t.Errorf("Expected more than one tile, got %d", tiles)
+	}
+}
+
+// Returns the feature table binary body length written in the header of the given content.pnts file
+func readFeatureTableBinaryLength(t *testing.T, file string) int {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return int(binary.LittleEndian.Uint32(content[16:20]))
+}
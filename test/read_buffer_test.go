@@ -0,0 +1,51 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Read buffer size holding 7 point records of 34 bytes and a partial one
+const testReadBufferSize = 7*34 + 5
+
+func TestLasFileIsReadInBatches(t *testing.T) {
+	points := make([]testLasPoint, 1000)
+	for i := range points {
+		points[i] = testLasPoint{
+			raw:            [3]int32{int32(i), int32(-i), int32(i % 17)},
+			intensity:      uint16(i * 64),
+			classification: uint8(i % 10),
+			rgb:            [3]uint16{uint16(i), uint16(i * 3), uint16(i * 7)},
+		}
+	}
+	file := writeTestLasRecords(t, 2, 3, 34, points)
+	defer os.RemoveAll(filepath.Dir(file))
+
+	read := readTestLasFileInBatches(t, file, testReadBufferSize)
+	if len(read) != len(points) {
+		t.Fatalf("Expected %d points, got %d", len(points), len(read))
+	}
+	for _, point := range read {
+		expected := points[int(point.X)]
+		if point.Y != float64(expected.raw[1]) || point.Z != float64(expected.raw[2]) ||
+			point.Intensity != uint8(expected.intensity/256) || point.Classification != expected.classification ||
+			point.R != expected.rgb[0] || point.G != expected.rgb[1] || point.B != expected.rgb[2] {
+			t.Fatalf("Point %v does not match %v", point, expected)
+		}
+	}
+}
+
+func TestLazFileIsReadInBatches(t *testing.T) {
+	records := newTestLazRecords(1000)
+	file := writeTestLazFile(t, records, []int{300, 300, 300, 100}, false)
+	defer os.RemoveAll(filepath.Dir(file))
+	assertPointsMatchLazRecords(t, readTestLasFileInBatches(t, file, testReadBufferSize), records)
+}
+
+func TestReadBufferSmallerThanARecordReadsOnePointAtATime(t *testing.T) {
+	records := newTestLazRecords(100)
+	file := writeTestLazFile(t, records, []int{1, 99}, true)
+	defer os.RemoveAll(filepath.Dir(file))
+	assertPointsMatchLazRecords(t, readTestLasFileInBatches(t, file, 1), records)
+}
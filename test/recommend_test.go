@@ -0,0 +1,153 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/app"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"github.com/mfbonfigli/gocesiumtiler/structs/point_loader"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Writes an xyz file with the given longitude, latitude and height points in a new folder
+func writeTestXyzFile(t *testing.T, points [][3]float64) string {
+	var sb strings.Builder
+	for _, point := range points {
+		sb.WriteString(strconv.FormatFloat(point[0], 'f', 8, 64) + " " + strconv.FormatFloat(point[1], 'f', 8, 64) + " " + strconv.FormatFloat(point[2], 'f', 3, 64) + "\n")
+	}
+	return writeTestTextFile(t, "test.xyz", sb.String())
+}
+
+// Returns the points of a regular grid with the given number of points per side, spaced by the given degrees, around
+// longitude 11 and latitude 45
+func newTestGridPoints(side int, step float64) [][3]float64 {
+	points := make([][3]float64, 0, side*side)
+	for i := 0; i < side; i++ {
+		for j := 0; j < side; j++ {
+			points = append(points, [3]float64{11 + float64(i)*step, 45 + float64(j)*step, float64((i+j)%10) / 10})
+		}
+	}
+	return points
+}
+
+func TestSamplingLoaderAddsOnePointPerStride(t *testing.T) {
+	loader := point_loader.NewRandomLoader(0)
+	sampler := point_loader.NewSamplingLoader(loader, 10)
+	for i := 0; i < 1005; i++ {
+		sampler.AddElement(data.NewPoint(float64(i), 0, 0, 0, 0, 0, 0, 0))
+	}
+	if sampler.GetTotalCount() != 1005 {
+		t.Errorf("Expected 1005 points counted, got %d", sampler.GetTotalCount())
+	}
+	loader.Initialize()
+	sampled := 0
+	for {
+		point, hasNext := loader.GetNext()
+		if point != nil {
+			sampled++
+			if int(point.X)%10 != 0 {
+				t.Errorf("Unexpected point %f in the sample", point.X)
+			}
+		}
+		if !hasNext {
+			break
+		}
+	}
+	if sampled != 101 {
+		t.Errorf("Expected 101 sampled points, got %d", sampled)
+	}
+}
+
+func TestRecommendedOptionsForAnEvenCloud(t *testing.T) {
+	// 40000 points spaced by about 0.8 m in latitude and 0.6 m in longitude
+	points := newTestGridPoints(200, 0.000007)
+	file := writeTestXyzFile(t, points)
+	defer os.RemoveAll(filepath.Dir(file))
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.Input = file
+	opts.Srid = 4326
+	opts.CoordinateConverter = &geodeticCoordinateConverter{}
+
+	recommended, err := app.RecommendOptions(opts, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recommended.MaxNumPointsPerNode != 5000 {
+		t.Errorf("Expected 5000 max points per tile, got %d", recommended.MaxNumPointsPerNode)
+	}
+	if recommended.Strategy != tiler.BoxedRandom {
+		t.Errorf("Expected the boxed random strategy for an even density, got %d", recommended.Strategy)
+	}
+	if recommended.GeometricErrors != tiler.DiagonalGeometricErrors || math.Abs(recommended.DiagonalFraction-1/math.Sqrt(15000)) > 1e-9 {
+		t.Errorf("Expected diagonal geometric errors with fraction %f, got %d and %f", 1/math.Sqrt(15000), recommended.GeometricErrors, recommended.DiagonalFraction)
+	}
+	if recommended.BoundsSigmas != 0 {
+		t.Errorf("Expected no bounds sigmas without outliers, got %f", recommended.BoundsSigmas)
+	}
+	if opts.MaxNumPointsPerNode != 1000 || opts.Srid != 4326 {
+		t.Errorf("Expected the given options to be left unchanged")
+	}
+
+	// the recommended options tile the cloud in a few levels with halving geometric errors
+	if err := app.RunTiler(recommended); err != nil {
+		t.Fatal(err)
+	}
+	tilesetFile := filepath.Join(recommended.Output, "test", "tileset.json")
+	errorRange, err := io.ValidateGeometricErrors(tilesetFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errorRange.Min <= 0 || errorRange.Max > 10 {
+		t.Errorf("Expected positive geometric errors of at most 10 m, got %f to %f", errorRange.Min, errorRange.Max)
+	}
+	contents := make(map[string]int)
+	collectTileContents(t, filepath.Dir(tilesetFile), "tileset.json", 1, contents)
+	if len(contents) < 8 {
+		t.Errorf("Expected at least 8 tiles, got %d", len(contents))
+	}
+}
+
+func TestRecommendedOptionsForAnUnevenCloudWithOutliers(t *testing.T) {
+	// a sparse grid, a dense cluster in a corner and two far points
+	points := newTestGridPoints(100, 0.0001)
+	for _, point := range newTestGridPoints(200, 0.000001) {
+		points = append(points, [3]float64{point[0] + 0.0002, point[1] + 0.0002, point[2]})
+	}
+	points = append(points, [3]float64{11.005, 45.005, 3000}, [3]float64{11.005, 45.005, 3000})
+	file := writeTestXyzFile(t, points)
+	defer os.RemoveAll(filepath.Dir(file))
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.Input = file
+	opts.Srid = 4326
+
+	recommended, err := app.RecommendOptions(opts, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recommended.MaxNumPointsPerNode != 5000 {
+		t.Errorf("Expected 5000 max points per tile, got %d", recommended.MaxNumPointsPerNode)
+	}
+	if recommended.Strategy != tiler.GridDecimation {
+		t.Errorf("Expected the grid strategy for an uneven density, got %d", recommended.Strategy)
+	}
+	if recommended.BoundsSigmas != 6 {
+		t.Errorf("Expected bounds sigmas 6 with outliers, got %f", recommended.BoundsSigmas)
+	}
+}
+
+func TestRecommendOptionsRejectsInvalidFractions(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	for _, fraction := range []float64{0, -0.5, 1.5} {
+		if _, err := app.RecommendOptions(opts, fraction); err == nil {
+			t.Errorf("Expected the sample fraction %f to be rejected", fraction)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/converters/offset_elevation_corrector"
+	"github.com/mfbonfigli/gocesiumtiler/lasread"
+	"github.com/mfbonfigli/gocesiumtiler/structs/point_loader"
+	"github.com/mfbonfigli/gocesiumtiler/utils"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPointsFailingReprojectionReturnAnError(t *testing.T) {
+	rawPoints := make([][3]int32, 0)
+	for i := 0; i < 100; i++ {
+		rawPoints = append(rawPoints, [3]int32{int32(4000000 + i), int32(3000000 + i), int32(i)})
+	}
+	// out of the domain of the EPSG:3035 Lambert azimuthal equal area projection
+	rawPoints[50] = [3]int32{2000000000, 2000000000, 0}
+	file := writeTestLasFile(t, testLasHeader{scale: [3]float64{1, 1, 1}}, rawPoints)
+	defer os.RemoveAll(filepath.Dir(file))
+
+	pool := utils.NewWorkerPool(4)
+	defer pool.Close()
+	lasFileLoader := lidario.NewLasFileLoader(newTestProj4Converter(t), nil, point_loader.NewRandomLoader(0), pool, false)
+	_, err := lasFileLoader.LoadLasFile(file, offset_elevation_corrector.NewOffsetElevationCorrector(0), 3035)
+	if err == nil {
+		t.Fatal("Expected an error reading a point that cannot be reprojected")
+	}
+	if !strings.Contains(err.Error(), filepath.Base(file)) {
+		t.Errorf("Expected the error to name the file, got %v", err)
+	}
+}
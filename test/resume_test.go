@@ -0,0 +1,121 @@
+package test
+
+import (
+	"bytes"
+	"github.com/mfbonfigli/gocesiumtiler/app"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Returns the paths of the content.pnts and tileset.json files written in the given folder
+func collectTestTileFiles(t *testing.T, folder string) []string {
+	files := make([]string, 0)
+	err := filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() && (strings.HasSuffix(path, ".pnts") || strings.HasSuffix(path, ".json")) {
+			files = append(files, path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestResumeSkipsTheTilesAlreadyWritten(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.MaxNumPointsPerNode = 50
+	opts.RandomSeed = 42
+	opts.Resume = true
+	tree := buildTree(t, newTestPoints(), opts)
+	exportTree(t, tree, opts)
+
+	files := collectTestTileFiles(t, opts.Output)
+	if len(files) < 3 {
+		t.Fatalf("Expected several tile files, got %d", len(files))
+	}
+	past := time.Now().Add(-time.Hour)
+	for _, file := range files {
+		if _, err := os.Stat(file + ".crc"); err != nil {
+			t.Fatalf("Expected a sidecar for %s", file)
+		}
+		if err := os.Chtimes(file, past, past); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a tile corrupted keeping its size and a tile without sidecar, as if interrupted while writing it
+	corrupted, unchecked := files[0], files[1]
+	original, err := os.ReadFile(corrupted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	damaged := append([]byte(nil), original...)
+	damaged[len(damaged)/2] ^= 0xff
+	if err := os.WriteFile(corrupted, damaged, 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(corrupted, past, past); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(unchecked + ".crc"); err != nil {
+		t.Fatal(err)
+	}
+
+	pointCount := 0
+	opts.OnTileWritten = func(tile tiler.TileInfo) { pointCount += tile.PointCount }
+	exportTree(t, tree, opts)
+
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rewritten := info.ModTime().After(past.Add(time.Minute))
+		if expected := file == corrupted || file == unchecked; rewritten != expected {
+			t.Errorf("Expected %s rewritten %v, got %v", file, expected, rewritten)
+		}
+	}
+	if restored, err := os.ReadFile(corrupted); err != nil || !bytes.Equal(restored, original) {
+		t.Errorf("Expected the corrupted tile %s written again", corrupted)
+	}
+	if _, err := os.Stat(unchecked + ".crc"); err != nil {
+		t.Errorf("Expected the sidecar of %s written again", unchecked)
+	}
+	if pointCount != 500 {
+		t.Errorf("Expected the skipped tiles reported with their 500 points, got %d", pointCount)
+	}
+}
+
+func TestSidecarsAreNotWrittenWithoutResume(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	writeTileset(t, newTestPoints(), opts)
+	for _, file := range collectTestTileFiles(t, opts.Output) {
+		if _, err := os.Stat(file + ".crc"); err == nil {
+			t.Errorf("Expected no sidecar for %s", file)
+		}
+	}
+}
+
+func TestResumeWithoutSeedIsAnError(t *testing.T) {
+	file := writeTestLasFile(t, testLasHeader{scale: [3]float64{1, 1, 1}}, [][3]int32{{0, 0, 0}, {1, 1, 1}, {2, 2, 2}})
+	defer os.RemoveAll(filepath.Dir(file))
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.Input = file
+	opts.Resume = true
+	if err := app.RunTiler(opts); err == nil || !strings.Contains(err.Error(), "requires a random seed") {
+		t.Errorf("Expected resuming without seed to be rejected, got %v", err)
+	}
+	if err := app.RunBatchTiler([]app.BatchJob{{Input: file, Output: opts.Output}}, opts, 1); err == nil {
+		t.Errorf("Expected resuming a batch without seed to be rejected")
+	}
+	if files := collectTestTileFiles(t, opts.Output); len(files) != 0 {
+		t.Errorf("Expected no tile files, got %v", files)
+	}
+}
@@ -0,0 +1,122 @@
+package test
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReturnNumbersAreReadFromLegacyPointFormats(t *testing.T) {
+	points := []testLasPoint{
+		{raw: [3]int32{1, 2, 3}, returns: 2 | 3<<3},
+		{raw: [3]int32{4, 5, 6}, returns: 7 | 7<<3 | 0xc0},
+	}
+	for _, format := range []struct {
+		pointFormat  byte
+		recordLength int
+	}{{0, 20}, {1, 28}, {2, 26}, {3, 34}} {
+		file := writeTestLasRecords(t, 2, format.pointFormat, format.recordLength, points)
+		assertTestReturnNumbers(t, readTestLasFile(t, file), map[float64][2]uint8{1: {2, 3}, 4: {7, 7}})
+		_ = os.RemoveAll(filepath.Dir(file))
+	}
+}
+
+func TestReturnNumbersAreReadFromExtendedPointFormats(t *testing.T) {
+	points := []testLasPoint{
+		{raw: [3]int32{1, 2, 3}, returns: 2 | 3<<4},
+		{raw: [3]int32{4, 5, 6}, returns: 15 | 15<<4},
+	}
+	for _, format := range []struct {
+		pointFormat  byte
+		recordLength int
+	}{{6, 30}, {7, 36}, {8, 38}} {
+		file := writeTestLasRecords(t, 4, format.pointFormat, format.recordLength, points)
+		assertTestReturnNumbers(t, readTestLasFile(t, file), map[float64][2]uint8{1: {2, 3}, 4: {15, 15}})
+		_ = os.RemoveAll(filepath.Dir(file))
+	}
+}
+
+// Checks that the given points have the return number and number of returns expected for their x coordinate
+func assertTestReturnNumbers(t *testing.T, points []*data.Point, expected map[float64][2]uint8) {
+	if len(points) != len(expected) {
+		t.Fatalf("Expected %d points, got %d", len(expected), len(points))
+	}
+	for _, point := range points {
+		returns := expected[point.X]
+		if point.ReturnNumber != returns[0] || point.NumberOfReturns != returns[1] {
+			t.Errorf("Expected return %d of %d, got %d of %d", returns[0], returns[1], point.ReturnNumber, point.NumberOfReturns)
+		}
+	}
+}
+
+func TestReturnNumbersAreWrittenInTheBatchTable(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	points := make([]*data.Point, 0)
+	for i := 0; i < 100; i++ {
+		point := data.NewPoint(float64(i), float64(i%7), float64(i%11), 0, 0, 0, 0, 0)
+		// the number of returns identifies the point, its return number being the last one
+		point.NumberOfReturns = uint8(i%15 + 1)
+		point.ReturnNumber = point.NumberOfReturns
+		points = append(points, point)
+	}
+	writeTileset(t, points, opts)
+	file := filepath.Join(opts.Output, "content.pnts")
+	if err := io.ValidatePntsFile(file); err != nil {
+		t.Errorf("Expected a valid pnts layout, got %v", err)
+	}
+
+	batchTable := readTestBatchTable(t, file)
+	returnNumbers := readTestBatchTableBytes(t, file, batchTable, "RETURN_NUMBER", len(points))
+	numbersOfReturns := readTestBatchTableBytes(t, file, batchTable, "NUMBER_OF_RETURNS", len(points))
+	counts := make(map[uint8]int)
+	for i := range points {
+		if returnNumbers[i] != numbersOfReturns[i] || numbersOfReturns[i] < 1 || numbersOfReturns[i] > 15 {
+			t.Fatalf("Unexpected return %d of %d", returnNumbers[i], numbersOfReturns[i])
+		}
+		counts[numbersOfReturns[i]]++
+	}
+	if len(counts) != 15 {
+		t.Errorf("Expected 15 distinct numbers of returns, got %v", counts)
+	}
+}
+
+func TestReturnNumbersAreNotWrittenForPointsWithoutThem(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	writeTileset(t, newTestColorDepthPoints(), opts)
+	batchTable := readTestBatchTable(t, filepath.Join(opts.Output, "content.pnts"))
+	if _, ok := batchTable["RETURN_NUMBER"]; ok {
+		t.Errorf("Expected no RETURN_NUMBER property for points without returns")
+	}
+	if _, ok := batchTable["NUMBER_OF_RETURNS"]; ok {
+		t.Errorf("Expected no NUMBER_OF_RETURNS property for points without returns")
+	}
+}
+
+// Returns the given number of bytes of the given batch table property of the given content.pnts file
+func readTestBatchTableBytes(t *testing.T, file string, batchTable map[string]json.RawMessage, name string, length int) []byte {
+	raw, ok := batchTable[name]
+	if !ok {
+		t.Fatalf("Expected a %s batch table property", name)
+	}
+	property := struct {
+		ByteOffset int `json:"byteOffset"`
+	}{}
+	if err := json.Unmarshal(raw, &property); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	featureTableLen := int(binary.LittleEndian.Uint32(content[12:16]))
+	featureTableBinaryLen := int(binary.LittleEndian.Uint32(content[16:20]))
+	batchTableLen := int(binary.LittleEndian.Uint32(content[20:24]))
+	start := 28 + featureTableLen + featureTableBinaryLen + batchTableLen + property.ByteOffset
+	return content[start : start+length]
+}
@@ -0,0 +1,117 @@
+package test
+
+import (
+	"bytes"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"github.com/mfbonfigli/gocesiumtiler/structs/octree"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRootTransformMovesThePointsAndTheirRegions(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.Srid = 4326
+	opts.CoordinateConverter = &geodeticCoordinateConverter{}
+	opts.MaxNumPointsPerNode = 20
+
+	// lifts by 100 meters a grid of points around lon 11.2558, lat 43.7696, at heights from 50 to 90 meters
+	points := make([]*data.Point, 0)
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 10; j++ {
+			for k := 0; k < 3; k++ {
+				points = append(points, data.NewPoint(11.2558+float64(i)*0.0005, 43.7696+float64(j)*0.0005, 50+float64(k)*20, 0, 0, 0, 0, 0))
+			}
+		}
+	}
+	lon, lat := 11.2558*math.Pi/180, 43.7696*math.Pi/180
+	up := [3]float64{math.Cos(lat) * math.Cos(lon), math.Cos(lat) * math.Sin(lon), math.Sin(lat)}
+	opts.RootTransform = [16]float64{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 100 * up[0], 100 * up[1], 100 * up[2], 1}
+	writeTileset(t, points, opts)
+
+	tileset, err := io.ReadTilesetFile(filepath.Join(opts.Output, "tileset.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tileset.Root.Transform, opts.RootTransform[:]) {
+		t.Fatalf("Expected the root transform %v, got %v", opts.RootTransform, tileset.Root.Transform)
+	}
+	regions := make(map[string][]float64)
+	collectTileRegions(t, opts.Output, "tileset.json", regions)
+	identity := [16]float64{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}
+	placed := 0
+	for file, transform := range collectTestContentTransforms(t, opts.Output, "tileset.json", identity) {
+		pnts, err := io.ReadPntsFile(filepath.Join(opts.Output, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		region := regions[file]
+		for i := 0; i < len(pnts.Positions); i += 3 {
+			var ecef [3]float64
+			for r := 0; r < 3; r++ {
+				ecef[r] = transform[r]*pnts.Positions[i] + transform[4+r]*pnts.Positions[i+1] + transform[8+r]*pnts.Positions[i+2] + transform[12+r]
+			}
+			lonDeg, latDeg, h := ecefToGeodetic(ecef)
+			if h < 149.9 || h > 190.1 {
+				t.Errorf("%s: expected the point lifted by 100 meters, got height %f", file, h)
+			}
+			x, y := lonDeg*math.Pi/180, latDeg*math.Pi/180
+			if x < region[0]-1e-9 || y < region[1]-1e-9 || x > region[2]+1e-9 || y > region[3]+1e-9 || h < region[4]-1e-3 || h > region[5]+1e-3 {
+				t.Errorf("%s: expected the point %f, %f, %f in the tile region %v", file, x, y, h, region)
+			}
+			placed++
+		}
+	}
+	if placed != len(points) {
+		t.Errorf("Expected %d placed points, got %d", len(points), placed)
+	}
+	if err := io.ValidateRegionContainment(filepath.Join(opts.Output, "tileset.json")); err != nil {
+		t.Errorf("Expected the regions of the children contained in their parents, got %v", err)
+	}
+}
+
+func TestIdentityRootTransformKeepsTheTileset(t *testing.T) {
+	var tree *octree.OctTree
+	tilesets := make([][]byte, 0)
+	for _, transform := range [][16]float64{{}, {1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}} {
+		opts := newTestOptions(t)
+		defer os.RemoveAll(opts.Output)
+		opts.MaxNumPointsPerNode = 50
+		opts.RootTransform = transform
+		if tree == nil {
+			tree = buildTree(t, newTestPoints(), opts)
+		}
+		exportTree(t, tree, opts)
+		content, err := os.ReadFile(filepath.Join(opts.Output, "tileset.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(content, []byte("transform")) {
+			t.Errorf("Expected no transform for the transform %v", transform)
+		}
+		tilesets = append(tilesets, content)
+	}
+	if !bytes.Equal(tilesets[0], tilesets[1]) {
+		t.Errorf("Expected the identity transform to write the same tileset.json as no transform")
+	}
+}
+
+func TestTransformIsParsedInColumnMajorOrder(t *testing.T) {
+	transform, err := tiler.ParseTransform("1,0,0,0, 0,1,0,0, 0,0,1,0, 10,20,30,1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transform[12] != 10 || transform[13] != 20 || transform[14] != 30 || transform[15] != 1 {
+		t.Errorf("Expected the translation in the 13th to 15th values, got %v", transform)
+	}
+	for _, value := range []string{"1,0,0,0", "1,0,0,0,0,1,0,0,0,0,1,0,0,0,0,x"} {
+		if _, err := tiler.ParseTransform(value); err == nil {
+			t.Errorf("Expected an error parsing the transform %s", value)
+		}
+	}
+}
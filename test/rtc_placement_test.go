@@ -0,0 +1,200 @@
+package test
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// WGS84 ellipsoid semi-major axis and first eccentricity squared
+const (
+	wgs84A  = 6378137.0
+	wgs84E2 = 6.69437999014e-3
+)
+
+func TestPntsPlacesThePointsAtTheirGeographicLocation(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.Srid = 4326
+	opts.CoordinateConverter = &geodeticCoordinateConverter{}
+	opts.MaxNumPointsPerNode = 20
+
+	// a 10x10x3 grid of points spaced about 50 meters apart, starting from Piazza del Duomo in Florence
+	points := make([]*data.Point, 0)
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 10; j++ {
+			for k := 0; k < 3; k++ {
+				points = append(points, data.NewPoint(11.2558+float64(i)*0.0005, 43.7696+float64(j)*0.0005, 50+float64(k)*20, 0, 0, 0, 0, 0))
+			}
+		}
+	}
+	writeTileset(t, points, opts)
+
+	// hand computed ECEF coordinates of the first point, lon 11.2558, lat 43.7696, height 50
+	expectedEcef := [3]float64{4524524.867, 900460.098, 4389675.582}
+	firstPointFound := false
+
+	placed := 0
+	identity := [16]float64{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}
+	for file, transform := range collectTestContentTransforms(t, opts.Output, "tileset.json", identity) {
+		featureTable, _ := readTestFeatureTable(t, filepath.Join(opts.Output, file))
+		content, err := os.ReadFile(filepath.Join(opts.Output, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if featureTable.Position == nil || len(featureTable.RtcCenter) != 3 {
+			t.Fatalf("%s: expected float32 positions relative to a RTC_CENTER", file)
+		}
+		body := content[28+int(binary.LittleEndian.Uint32(content[12:16])):]
+		for i := 0; i < featureTable.PointsLength; i++ {
+			// the RTC_CENTER is added to the positions in the tile coordinate system, then the tile transform applies
+			var local [3]float64
+			for c := 0; c < 3; c++ {
+				offset := featureTable.Position.ByteOffset + (i*3+c)*4
+				local[c] = float64(math.Float32frombits(binary.LittleEndian.Uint32(body[offset:]))) + featureTable.RtcCenter[c]
+			}
+			var ecef [3]float64
+			for r := 0; r < 3; r++ {
+				// column-major transform
+				ecef[r] = transform[r]*local[0] + transform[4+r]*local[1] + transform[8+r]*local[2] + transform[12+r]
+			}
+
+			lon, lat, h := ecefToGeodetic(ecef)
+			nearest := math.MaxFloat64
+			for _, point := range points {
+				north := (lat - point.Y) * math.Pi / 180 * wgs84A
+				east := (lon - point.X) * math.Pi / 180 * wgs84A * math.Cos(lat*math.Pi/180)
+				nearest = math.Min(nearest, math.Sqrt(north*north+east*east+(h-point.Z)*(h-point.Z)))
+			}
+			if nearest > 0.1 {
+				t.Errorf("%s: point placed at lon %f, lat %f, height %f, %f meters away from the nearest input point", file, lon, lat, h, nearest)
+			}
+			if d := math.Sqrt(math.Pow(ecef[0]-expectedEcef[0], 2) + math.Pow(ecef[1]-expectedEcef[1], 2) + math.Pow(ecef[2]-expectedEcef[2], 2)); d < 0.1 {
+				firstPointFound = true
+			}
+			placed++
+		}
+	}
+	if placed != len(points) {
+		t.Errorf("Expected %d placed points, got %d", len(points), placed)
+	}
+	if !firstPointFound {
+		t.Errorf("Expected a point placed at the ECEF coordinates %v", expectedEcef)
+	}
+}
+
+// Follows the content urls of the given tileset.json, relative to the given folder, returning the path of every
+// referenced content.pnts file together with the column-major transform from its coordinate system to ECEF, i.e. the
+// product of the transforms of its tile and its ancestors, starting from the given transform of the root
+func collectTestContentTransforms(t *testing.T, folder string, tilesetFile string, transform [16]float64) map[string][16]float64 {
+	content, err := os.ReadFile(filepath.Join(folder, tilesetFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tileset struct {
+		Root testTransformTile `json:"root"`
+	}
+	if err := json.Unmarshal(content, &tileset); err != nil {
+		t.Fatal(err)
+	}
+	transforms := make(map[string][16]float64)
+	var visit func(tile testTransformTile, parentTransform [16]float64)
+	visit = func(tile testTransformTile, parentTransform [16]float64) {
+		tileTransform := parentTransform
+		if tile.Transform != nil {
+			tileTransform = multiplyColumnMajor(parentTransform, *tile.Transform)
+		}
+		if tile.Content != nil {
+			url := path.Join(path.Dir(tilesetFile), strings.Split(tile.Content.Url, "?")[0])
+			if strings.HasSuffix(url, ".json") {
+				for file, contentTransform := range collectTestContentTransforms(t, folder, url, tileTransform) {
+					transforms[file] = contentTransform
+				}
+			} else {
+				transforms[url] = tileTransform
+			}
+		}
+		for _, child := range tile.Children {
+			visit(child, tileTransform)
+		}
+	}
+	visit(tileset.Root, transform)
+	return transforms
+}
+
+// Tile of a tileset.json with its optional transform
+type testTransformTile struct {
+	Transform *[16]float64 `json:"transform"`
+	Content   *struct {
+		Url string `json:"uri"`
+	} `json:"content"`
+	Children []testTransformTile `json:"children"`
+}
+
+// Multiplies the given column-major 4x4 matrices
+func multiplyColumnMajor(a, b [16]float64) [16]float64 {
+	var product [16]float64
+	for column := 0; column < 4; column++ {
+		for row := 0; row < 4; row++ {
+			for k := 0; k < 4; k++ {
+				product[column*4+row] += a[k*4+row] * b[column*4+k]
+			}
+		}
+	}
+	return product
+}
+
+// Converts the given ECEF coordinates to WGS84 longitude and latitude in degrees and ellipsoidal height, iterating
+// the latitude until convergence
+func ecefToGeodetic(ecef [3]float64) (float64, float64, float64) {
+	lon := math.Atan2(ecef[1], ecef[0])
+	p := math.Hypot(ecef[0], ecef[1])
+	lat := math.Atan2(ecef[2], p*(1-wgs84E2))
+	h := 0.0
+	for i := 0; i < 10; i++ {
+		n := wgs84A / math.Sqrt(1-wgs84E2*math.Sin(lat)*math.Sin(lat))
+		h = p/math.Cos(lat) - n
+		lat = math.Atan2(ecef[2], p*(1-wgs84E2*n/(n+h)))
+	}
+	return lon * 180 / math.Pi, lat * 180 / math.Pi, h
+}
+
+func TestRtcCenterKeepsTheFullPrecision(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	tree := buildTree(t, newTestPoints(), opts)
+	center := [3]float64{4524524.867123456, 900460.0984567891, -4389675.582789012}
+	point := data.NewPoint(center[0], center[1], center[2], 0, 0, 0, 0, 0)
+	content, err := io.EncodePnts(&tree.RootNode, []*data.Point{point}, opts, opts.CoordinateConverter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	featureTableLen := int(binary.LittleEndian.Uint32(content[12:16]))
+	featureTable := content[28 : 28+featureTableLen]
+	trimmed := strings.TrimRight(string(featureTable), " ")
+	if featureTableLen%4 != 0 || !strings.HasSuffix(trimmed, "}") || len(featureTable)-len(trimmed) > 3 {
+		t.Errorf("Expected the feature table json to be padded with trailing spaces to 4 bytes, got %q", featureTable)
+	}
+	decoded := struct {
+		RtcCenter []float64 `json:"RTC_CENTER"`
+	}{}
+	if err := json.Unmarshal(featureTable, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.RtcCenter) != 3 {
+		t.Fatalf("Expected an RTC_CENTER, got %v", decoded.RtcCenter)
+	}
+	for i, value := range decoded.RtcCenter {
+		if math.Abs(value-center[i]) > math.Abs(center[i])*1e-15 {
+			t.Errorf("Expected the RTC_CENTER coordinate %d to round-trip as %.9f, got %.9f", i, center[i], value)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/mfbonfigli/gocesiumtiler/io/s3_tile_writer"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// S3 client keeping the uploaded objects in memory and tracking the maximum number of concurrent uploads
+type memoryS3Client struct {
+	mutex         sync.Mutex
+	objects       map[string]*s3.PutObjectInput
+	inFlight      int
+	maxInFlight   int
+	uploadLatency time.Duration
+}
+
+func (client *memoryS3Client) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	client.mutex.Lock()
+	client.inFlight++
+	if client.inFlight > client.maxInFlight {
+		client.maxInFlight = client.inFlight
+	}
+	client.mutex.Unlock()
+	time.Sleep(client.uploadLatency)
+	data, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	params.Body = strings.NewReader(string(data))
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	client.inFlight--
+	client.objects[aws.ToString(params.Bucket)+"/"+aws.ToString(params.Key)] = params
+	return &s3.PutObjectOutput{}, nil
+}
+
+func TestTilesAreUploadedToS3(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.Output = filepath.Join(opts.Output, "tiles")
+	opts.MaxNumPointsPerNode = 50
+	client := &memoryS3Client{objects: make(map[string]*s3.PutObjectInput)}
+	opts.TileWriter = s3_tile_writer.NewS3TileWriter(client, "bucket", "/tilesets/cloud/", "max-age=3600", opts)
+	exportTree(t, buildTree(t, newTestPoints(), opts), opts)
+
+	if _, err := os.Stat(opts.Output); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing written on disk uploading the tiles to S3")
+	}
+	root, ok := client.objects["bucket/tilesets/cloud/tileset.json"]
+	if !ok {
+		t.Fatalf("Expected the root tileset.json uploaded to the prefix, got %d objects", len(client.objects))
+	}
+	if aws.ToString(root.ContentType) != "application/json" {
+		t.Errorf("Expected the tileset.json uploaded as application/json, got %s", aws.ToString(root.ContentType))
+	}
+	contents := 0
+	for key, object := range client.objects {
+		if aws.ToString(object.CacheControl) != "max-age=3600" {
+			t.Errorf("Expected the Cache-Control of %s set, got %s", key, aws.ToString(object.CacheControl))
+		}
+		if strings.HasSuffix(key, ".pnts") {
+			contents++
+			if aws.ToString(object.ContentType) != "application/octet-stream" {
+				t.Errorf("Expected %s uploaded as application/octet-stream, got %s", key, aws.ToString(object.ContentType))
+			}
+		}
+	}
+	if contents < 2 {
+		t.Errorf("Expected several content.pnts files uploaded, got %d", contents)
+	}
+}
+
+func TestS3UploadsAreBoundedByTheNumberOfWriters(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.NumWriters = 3
+	client := &memoryS3Client{objects: make(map[string]*s3.PutObjectInput), uploadLatency: 10 * time.Millisecond}
+	writer := s3_tile_writer.NewS3TileWriter(client, "bucket", "", "", opts)
+
+	var waitGroup sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		waitGroup.Add(1)
+		go func(i int) {
+			defer waitGroup.Done()
+			if err := writer.WriteFile(filepath.Join(opts.Output, fmt.Sprintf("%d", i), "content.pnts"), []byte{1}); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	waitGroup.Wait()
+	if len(client.objects) != 20 || client.maxInFlight > 3 {
+		t.Errorf("Expected 20 objects uploaded by at most 3 concurrent uploads, got %d by %d", len(client.objects), client.maxInFlight)
+	}
+	if _, ok := client.objects["bucket/7/content.pnts"]; !ok {
+		t.Errorf("Expected the objects uploaded without prefix")
+	}
+	if err := writer.WriteFile(filepath.Join(os.TempDir(), "content.pnts"), []byte{1}); err == nil {
+		t.Errorf("Expected an error writing a file outside of the output folder")
+	}
+}
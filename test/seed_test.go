@@ -0,0 +1,78 @@
+package test
+
+import (
+	"crypto/sha256"
+	"github.com/mfbonfigli/gocesiumtiler/app"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSeededRunsWriteIdenticalTiles(t *testing.T) {
+	file := writeGridTestLasFile(t, 20000)
+	defer os.RemoveAll(filepath.Dir(file))
+	for _, strategy := range []tiler.LoaderStrategy{tiler.FullyRandom, tiler.BoxedRandom, tiler.GridDecimation, tiler.PoissonDisk} {
+		first := runSeededTiler(t, file, strategy, 42)
+		second := runSeededTiler(t, file, strategy, 42)
+		if len(first) < 3 {
+			t.Fatalf("strategy %d: expected a tileset with at least 3 tiles, got %d files", strategy, len(first))
+		}
+		if len(first) != len(second) {
+			t.Errorf("strategy %d: expected %d files, got %d", strategy, len(first), len(second))
+		}
+		for name, hash := range first {
+			if second[name] != hash {
+				t.Errorf("strategy %d: file %s differs between runs with the same seed", strategy, name)
+			}
+		}
+	}
+}
+
+func TestSeedChangesTheTiles(t *testing.T) {
+	file := writeGridTestLasFile(t, 20000)
+	defer os.RemoveAll(filepath.Dir(file))
+	first := runSeededTiler(t, file, tiler.FullyRandom, 1)
+	second := runSeededTiler(t, file, tiler.FullyRandom, 2)
+	root := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)) + "/content.pnts"
+	if _, ok := first[root]; !ok {
+		t.Fatalf("Expected the root tile %s to be written", root)
+	}
+	if first[root] == second[root] {
+		t.Errorf("Expected different root tiles with different seeds")
+	}
+}
+
+// Tiles the given file with the given strategy and seed, returning the sha256 hash of each written file by its path
+// relative to the output folder
+func runSeededTiler(t *testing.T, file string, strategy tiler.LoaderStrategy, seed int64) map[string][sha256.Size]byte {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.Input = file
+	opts.Strategy = strategy
+	opts.RandomSeed = seed
+	if err := app.RunTiler(opts); err != nil {
+		t.Fatal(err)
+	}
+	hashes := make(map[string][sha256.Size]byte)
+	err := filepath.Walk(opts.Output, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		relativePath, err := filepath.Rel(opts.Output, path)
+		if err != nil {
+			return err
+		}
+		hashes[filepath.ToSlash(relativePath)] = sha256.Sum256(content)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hashes
+}
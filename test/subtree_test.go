@@ -0,0 +1,75 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/app"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/utils"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseTilePath(t *testing.T) {
+	path, err := utils.ParseTilePath("0/3/2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(path) != 3 || path[0] != 0 || path[1] != 3 || path[2] != 2 {
+		t.Errorf("Expected [0 3 2], got %v", path)
+	}
+	if _, err := utils.ParseTilePath("0/8"); err == nil {
+		t.Errorf("Expected error for octant index out of range")
+	}
+	if _, err := utils.ParseTilePath("a/1"); err == nil {
+		t.Errorf("Expected error for non numeric octant index")
+	}
+}
+
+func TestSubtreePathWritesOnlySubtree(t *testing.T) {
+	full := newTestOptions(t)
+	full.MaxNumPointsPerNode = 50
+	defer os.RemoveAll(full.Output)
+	tree := buildTree(t, newTestPoints(), full)
+	exportTree(t, tree, full)
+
+	subtree := newTestOptions(t)
+	subtree.MaxNumPointsPerNode = 50
+	subtree.SubtreePath = []uint8{0}
+	defer os.RemoveAll(subtree.Output)
+	exportTree(t, tree, subtree)
+
+	if _, err := os.Stat(filepath.Join(subtree.Output, "content.pnts")); !os.IsNotExist(err) {
+		t.Errorf("Expected root tile not to be written")
+	}
+	if err := io.CompareTilesetFolders(filepath.Join(full.Output, "0"), filepath.Join(subtree.Output, "0"), 1e-3); err != nil {
+		t.Errorf("Expected subtree to match the full tileset, got %v", err)
+	}
+}
+
+func TestSubtreePathOfAMissingTileIsAnError(t *testing.T) {
+	opts := newTestOptions(t)
+	opts.MaxNumPointsPerNode = 50
+	defer os.RemoveAll(opts.Output)
+	tree := buildTree(t, newTestPoints(), opts)
+	if err := io.CheckSubtreePath(&tree.RootNode, []uint8{0}); err != nil {
+		t.Errorf("Expected a tile at path 0, got %v", err)
+	}
+	// the tree is not deep enough to have a tile at this path
+	missing := make([]uint8, 20)
+	missing[1] = 3
+	if err := io.CheckSubtreePath(&tree.RootNode, missing); err == nil || !strings.Contains(err.Error(), "no tile at path 0/3/0/") {
+		t.Errorf("Expected a missing tile error, got %v", err)
+	}
+
+	file := writeTestLasFile(t, testLasHeader{scale: [3]float64{1, 1, 1}}, [][3]int32{{0, 0, 0}, {1, 1, 1}, {2, 2, 2}})
+	defer os.RemoveAll(filepath.Dir(file))
+	opts.Input = file
+	opts.SubtreePath = []uint8{0, 3, 2}
+	if err := app.RunTiler(opts); err == nil || !strings.Contains(err.Error(), "no tile at path 0/3/2") {
+		t.Errorf("Expected a missing tile error, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(opts.Output, "test", "tileset.json")); !os.IsNotExist(err) {
+		t.Errorf("Expected no tileset to be written")
+	}
+}
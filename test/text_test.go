@@ -0,0 +1,109 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/app"
+	"github.com/mfbonfigli/gocesiumtiler/converters/offset_elevation_corrector"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"github.com/mfbonfigli/gocesiumtiler/structs/point_loader"
+	"github.com/mfbonfigli/gocesiumtiler/textread"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// Writes a test file with the given name and content in a new folder. Returns the path of the written file.
+func writeTestTextFile(t *testing.T, name string, content string) string {
+	folder, err := os.MkdirTemp("", "gocesiumtiler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(folder, name)
+	if err := os.WriteFile(file, []byte(content), 0666); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestCsvTilesLikeLas(t *testing.T) {
+	points := newTestPlyPoints()
+	content := "class;x;y;z;name\n"
+	for _, point := range points {
+		content += "0;" + strconv.Itoa(int(point[0])) + ";" + strconv.Itoa(int(point[1])) + ";" + strconv.Itoa(int(point[2])) + ";a\n"
+	}
+	file := writeTestTextFile(t, "test.csv", content)
+	lasFile := writeTestLasFile(t, testLasHeader{scale: [3]float64{1, 1, 1}}, points)
+	defer os.RemoveAll(filepath.Dir(lasFile))
+	defer os.RemoveAll(filepath.Dir(file))
+
+	lasOpts := newTestOptions(t)
+	defer os.RemoveAll(lasOpts.Output)
+	lasOpts.Input = lasFile
+	if err := app.RunTiler(lasOpts); err != nil {
+		t.Fatal(err)
+	}
+	csvOpts := newTestOptions(t)
+	defer os.RemoveAll(csvOpts.Output)
+	csvOpts.Input = file
+	csvOpts.TextColumns, _ = textread.ParseColumns("class,x,y,z,-")
+	csvOpts.TextDelimiter = ";"
+	csvOpts.Srid = 32633
+	converter := &recordingCoordinateConverter{srids: make(map[int]bool)}
+	csvOpts.CoordinateConverter = converter
+	if err := app.RunTiler(csvOpts); err != nil {
+		t.Fatal(err)
+	}
+
+	if !converter.srids[32633] {
+		t.Errorf("Expected the points to be converted from the declared srid 32633, got %v", converter.srids)
+	}
+	if err := io.CompareTilesetFolders(filepath.Join(lasOpts.Output, "test"), filepath.Join(csvOpts.Output, "test"), 1e-3); err != nil {
+		t.Errorf("Expected the CSV tileset to match the LAS one, got %v", err)
+	}
+}
+
+func TestTextColumnsAreMappedToPointAttributes(t *testing.T) {
+	file := writeTestTextFile(t, "test.xyz", "# comment\n7 1.5 2.5 3.5 10 20 30 40 99\n")
+	defer os.RemoveAll(filepath.Dir(file))
+	columns, err := textread.ParseColumns("class,x,y,z,r,g,b,intensity,-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loader := point_loader.NewRandomLoader(0)
+	textFileLoader := textread.NewTextFileLoader(&identityCoordinateConverter{}, loader, columns, "")
+	if err := textFileLoader.LoadTextFile(file, offset_elevation_corrector.NewOffsetElevationCorrector(1), 4326); err != nil {
+		t.Fatal(err)
+	}
+	loader.Initialize()
+	point, _ := loader.GetNext()
+	expected := data.NewPoint(1.5, 2.5, 4.5, 10*257, 20*257, 30*257, 40, 7)
+	if point == nil || point.HasGpsTime() {
+		t.Fatalf("Expected a point without GPS time, got %v", point)
+	}
+	// NaN GPS times are never equal
+	actual := *point
+	actual.GpsTime, expected.GpsTime = 0, 0
+	if actual != *expected {
+		t.Errorf("Expected point %v, got %v", expected, point)
+	}
+}
+
+func TestInvalidTextLineIsRejected(t *testing.T) {
+	file := writeTestTextFile(t, "test.xyz", "x y z\n1 2 3\n1 a 3\n")
+	defer os.RemoveAll(filepath.Dir(file))
+
+	textFileLoader := textread.NewTextFileLoader(&identityCoordinateConverter{}, point_loader.NewRandomLoader(0), nil, "")
+	if err := textFileLoader.LoadTextFile(file, offset_elevation_corrector.NewOffsetElevationCorrector(0), 4326); err == nil {
+		t.Errorf("Expected an error for an invalid line after the first point, got nil")
+	}
+}
+
+func TestInvalidTextColumnsAreRejected(t *testing.T) {
+	for _, spec := range []string{"x,y", "x,y,z,x", "x,y,z,w"} {
+		if _, err := textread.ParseColumns(spec); err == nil {
+			t.Errorf("Expected an error parsing columns %s, got nil", spec)
+		}
+	}
+}
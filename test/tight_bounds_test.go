@@ -0,0 +1,98 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"github.com/mfbonfigli/gocesiumtiler/structs/geometry"
+	"github.com/mfbonfigli/gocesiumtiler/structs/octree"
+	"os"
+	"testing"
+)
+
+// Points clustered in a corner of a sparse cloud, so that the cells of the tiles are larger than their points
+func newTestSparsePoints() []*data.Point {
+	points := newTestPoints()
+	for i := 0; i < 100; i++ {
+		points = append(points, data.NewPoint(100+float64(i%5)*0.1, 100+float64(i%3)*0.1, 100+float64(i%4)*0.1, 0, 0, 0, 0, 0))
+	}
+	return points
+}
+
+// Checks that the tight bounding box of the given node is contained in its cell and contains its points and the tight
+// bounding boxes of its children. Returns the number of nodes whose tight bounding box is smaller than their cell
+func checkTightBoundingBoxes(t *testing.T, node *octree.OctNode) int {
+	tight, cell := node.TightBoundingBox, node.BoundingBox
+	if tight == nil {
+		t.Fatalf("Expected a tight bounding box for the node at depth %d storing points", node.Depth)
+	}
+	if tight.Xmin < cell.Xmin || tight.Ymin < cell.Ymin || tight.Zmin < cell.Zmin || tight.Xmax > cell.Xmax || tight.Ymax > cell.Ymax || tight.Zmax > cell.Zmax {
+		t.Errorf("Expected the tight bounding box %v contained in the cell %v", *tight, *cell)
+	}
+	for _, point := range node.Items {
+		if !tight.Contains(point.X, point.Y, point.Z) {
+			t.Errorf("Expected the tight bounding box %v to contain the point %f, %f, %f", *tight, point.X, point.Y, point.Z)
+		}
+	}
+	tighter := 0
+	if tight.GetDiagonal() < cell.GetDiagonal() {
+		tighter++
+	}
+	for _, child := range node.Children {
+		if child == nil || !child.HasPoints() {
+			continue
+		}
+		if !containsBox(tight, child.TightBoundingBox) {
+			t.Errorf("Expected the tight bounding box %v to contain the one of its child", *tight)
+		}
+		tighter += checkTightBoundingBoxes(t, child)
+	}
+	return tighter
+}
+
+func containsBox(outer, inner *geometry.BoundingBox) bool {
+	return inner != nil && outer.Contains(inner.Xmin, inner.Ymin, inner.Zmin) && outer.Contains(inner.Xmax, inner.Ymax, inner.Zmax)
+}
+
+func TestTightBoundingBoxesAreContainedInTheCells(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.MaxNumPointsPerNode = 20
+	opts.TightBounds = true
+	tree := buildTree(t, newTestSparsePoints(), opts)
+	if tighter := checkTightBoundingBoxes(t, &tree.RootNode); tighter == 0 {
+		t.Errorf("Expected some tight bounding boxes smaller than their cells")
+	}
+}
+
+func TestTightBoundsShrinkTheTileRegions(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.MaxNumPointsPerNode = 20
+	opts.TightBounds = true
+	tree := buildTree(t, newTestSparsePoints(), opts)
+	exportTree(t, tree, opts)
+	tightRegions := make(map[string][]float64)
+	collectTileRegions(t, opts.Output, "tileset.json", tightRegions)
+
+	cellOpts := *opts
+	cellOpts.TightBounds = false
+	exportTree(t, tree, &cellOpts)
+	cellRegions := make(map[string][]float64)
+	collectTileRegions(t, opts.Output, "tileset.json", cellRegions)
+
+	smaller := 0
+	for url, cell := range cellRegions {
+		tight, ok := tightRegions[url]
+		if !ok {
+			t.Fatalf("Expected the tile %s written with tight bounds", url)
+		}
+		if tight[0] < cell[0] || tight[1] < cell[1] || tight[2] > cell[2] || tight[3] > cell[3] || tight[4] < cell[4] || tight[5] > cell[5] {
+			t.Errorf("Expected the tight region %v of %s contained in the cell region %v", tight, url, cell)
+		}
+		if (tight[2]-tight[0])*(tight[3]-tight[1]) < (cell[2]-cell[0])*(cell[3]-cell[1]) {
+			smaller++
+		}
+	}
+	if len(cellRegions) < 2 || smaller == 0 {
+		t.Errorf("Expected some of the %d tile regions shrunk by tight bounds", len(cellRegions))
+	}
+}
@@ -0,0 +1,75 @@
+package test
+
+import (
+	"context"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestTileWrittenCallbackIsCalledForEachTile(t *testing.T) {
+	opts := newTestOptions(t)
+	opts.MaxNumPointsPerNode = 50
+	defer os.RemoveAll(opts.Output)
+	// the callback appends without locking as its calls are serialized
+	tiles := make([]tiler.TileInfo, 0)
+	opts.OnTileWritten = func(tile tiler.TileInfo) {
+		tiles = append(tiles, tile)
+	}
+
+	tree := buildTree(t, newTestPoints(), opts)
+	workChannel := make(chan *io.WorkUnit, 10)
+	errs := io.NewConsumerErrors()
+	var waitGroup sync.WaitGroup
+	waitGroup.Add(5)
+	go io.Produce(context.Background(), opts.Output, &tree.RootNode, opts, workChannel, &waitGroup, "", nil)
+	for i := 0; i < 4; i++ {
+		go io.Consume(context.Background(), workChannel, errs, &waitGroup, opts.CoordinateConverter)
+	}
+	waitGroup.Wait()
+	if err := errs.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	files := 0
+	err := filepath.Walk(opts.Output, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil || info.Name() != "content.pnts" {
+			return err
+		}
+		files++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files < 2 || len(tiles) != files {
+		t.Fatalf("Expected a callback for each of the %d tiles, got %d", files, len(tiles))
+	}
+	points := 0
+	for _, tile := range tiles {
+		info, err := os.Stat(tile.Path)
+		if err != nil {
+			t.Fatalf("Expected the tile path %s to exist, got %v", tile.Path, err)
+		}
+		if info.Size() != int64(tile.ByteSize) {
+			t.Errorf("Expected byte size %d of %s, got %d", info.Size(), tile.Path, tile.ByteSize)
+		}
+		pnts, err := io.ReadPntsFile(tile.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pnts.FeatureTable.PointsLength != tile.PointCount {
+			t.Errorf("Expected point count %d of %s, got %d", pnts.FeatureTable.PointsLength, tile.Path, tile.PointCount)
+		}
+		if len(tile.Region) != 6 {
+			t.Errorf("Expected a bounding region of %s, got %v", tile.Path, tile.Region)
+		}
+		points += tile.PointCount
+	}
+	if points != len(newTestPoints()) {
+		t.Errorf("Expected %d points in the tiles, got %d", len(newTestPoints()), points)
+	}
+}
@@ -0,0 +1,88 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/structs/tiler"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Returns the level_x_y_z folders of the tile stored in the given nested numeric folder, e.g. 1_0_1_1/2_1_3_2 for 6/5
+func hierarchicalTileFolder(numericFolder string) string {
+	names := make([]string, 0)
+	x, y, z := 0, 0, 0
+	for level, token := range strings.Split(numericFolder, "/") {
+		octant, _ := strconv.Atoi(token)
+		x, y, z = 2*x+octant&1, 2*y+octant>>1&1, 2*z+octant>>2&1
+		names = append(names, strconv.Itoa(level+1)+"_"+strconv.Itoa(x)+"_"+strconv.Itoa(y)+"_"+strconv.Itoa(z))
+	}
+	return path.Join(names...)
+}
+
+func TestHierarchicalTileNamesKeepTheTileHierarchy(t *testing.T) {
+	numeric := newTestOptions(t)
+	defer os.RemoveAll(numeric.Output)
+	numeric.MaxNumPointsPerNode = 20
+	tree := buildTree(t, newTestPoints(), numeric)
+	exportTree(t, tree, numeric)
+	hierarchical := newTestOptions(t)
+	defer os.RemoveAll(hierarchical.Output)
+	hierarchical.MaxNumPointsPerNode = 20
+	hierarchical.TileNamingStrategy = tiler.HierarchicalTileNames
+	exportTree(t, tree, hierarchical)
+	byDepth := newTestOptions(t)
+	defer os.RemoveAll(byDepth.Output)
+	byDepth.MaxNumPointsPerNode = 20
+	byDepth.TileNamingStrategy = tiler.HierarchicalTileNames
+	byDepth.DepthFolders = true
+	exportTree(t, tree, byDepth)
+
+	numericContents := make(map[string]int)
+	collectTileContents(t, numeric.Output, "tileset.json", 1, numericContents)
+	hierarchicalContents := make(map[string]int)
+	collectTileContents(t, hierarchical.Output, "tileset.json", 1, hierarchicalContents)
+	depthContents := make(map[string]int)
+	collectTileContents(t, byDepth.Output, "tileset.json", 1, depthContents)
+	if len(numericContents) < 3 {
+		t.Fatalf("Expected a tileset with at least 3 tiles, got %d", len(numericContents))
+	}
+	for content, depth := range numericContents {
+		folder := path.Dir(content)
+		expected, expectedByDepth := content, content
+		if folder != "." {
+			expected = path.Join(hierarchicalTileFolder(folder), path.Base(content))
+			expectedByDepth = path.Join("L"+strconv.Itoa(depth), path.Base(path.Dir(expected)), path.Base(content))
+		}
+		if hierarchicalContents[expected] != depth {
+			t.Errorf("Expected tile %s stored as %s at depth %d", content, expected, depth)
+		}
+		if depthContents[expectedByDepth] != depth {
+			t.Errorf("Expected tile %s stored as %s at depth %d", content, expectedByDepth, depth)
+		}
+	}
+	if len(numericContents) != len(hierarchicalContents) || len(numericContents) != len(depthContents) {
+		t.Errorf("Expected %d tiles, got %d and %d", len(numericContents), len(hierarchicalContents), len(depthContents))
+	}
+
+	// every written tile is reachable from the root tileset
+	written := 0
+	err := filepath.Walk(hierarchical.Output, func(file string, info os.FileInfo, err error) error {
+		if err == nil && info.Name() == "content.pnts" {
+			written++
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written != len(hierarchicalContents) {
+		t.Errorf("Expected %d written tiles, got %d", len(hierarchicalContents), written)
+	}
+	if _, err := io.ValidateGeometricErrors(filepath.Join(hierarchical.Output, "tileset.json")); err != nil {
+		t.Errorf("Unexpected error validating the tileset: %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"github.com/mfbonfigli/gocesiumtiler/app"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// TileWriter keeping the written files in memory
+type memoryTileWriter struct {
+	mutex   sync.Mutex
+	files   map[string][]byte
+	folders map[string]bool
+}
+
+func newMemoryTileWriter() *memoryTileWriter {
+	return &memoryTileWriter{files: make(map[string][]byte), folders: make(map[string]bool)}
+}
+
+func (writer *memoryTileWriter) WriteFile(path string, data []byte) error {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+	writer.files[path] = append([]byte(nil), data...)
+	return nil
+}
+
+func (writer *memoryTileWriter) MkdirAll(path string) error {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+	writer.folders[path] = true
+	return nil
+}
+
+func TestTilesAreWrittenThroughTheTileWriter(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.MaxNumPointsPerNode = 50
+	tree := buildTree(t, newTestPoints(), opts)
+	exportTree(t, tree, opts)
+
+	memoryOpts := *opts
+	memoryOpts.Output = filepath.Join(opts.Output, "memory")
+	writer := newMemoryTileWriter()
+	memoryOpts.TileWriter = writer
+	exportTree(t, tree, &memoryOpts)
+
+	if _, err := os.Stat(memoryOpts.Output); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing written on disk using a custom TileWriter")
+	}
+	diskFiles := 0
+	err := filepath.Walk(opts.Output, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		diskFiles++
+		relative, err := filepath.Rel(opts.Output, filePath)
+		if err != nil {
+			return err
+		}
+		expected, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		if actual, ok := writer.files[filepath.Join(memoryOpts.Output, relative)]; !ok {
+			t.Errorf("Expected %s written through the TileWriter", relative)
+		} else if !bytes.Equal(actual, expected) {
+			t.Errorf("Expected %s written through the TileWriter to match the one on disk", relative)
+		}
+		if !writer.folders[filepath.Dir(filepath.Join(memoryOpts.Output, relative))] {
+			t.Errorf("Expected the folder of %s created through the TileWriter", relative)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diskFiles < 2 || len(writer.files) != diskFiles {
+		t.Errorf("Expected the same files on disk and in memory, got %d and %d", diskFiles, len(writer.files))
+	}
+}
+
+func TestAllOutputFilesAreWrittenThroughTheTileWriter(t *testing.T) {
+	rawPoints := make([][3]int32, 0)
+	for i := 0; i < 200; i++ {
+		rawPoints = append(rawPoints, [3]int32{int32(i % 10), int32(i % 7), int32(i % 13)})
+	}
+	file := writeTestLasFile(t, testLasHeader{scale: [3]float64{1, 1, 1}}, rawPoints)
+	defer os.RemoveAll(filepath.Dir(file))
+
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.Input = file
+	opts.Output = filepath.Join(opts.Output, "memory")
+	opts.WriteStatistics = true
+	opts.WriteLegend = true
+	opts.EnforceRegionContainment = true
+	opts.MonotonicGeometricError = true
+	writer := newMemoryTileWriter()
+	opts.TileWriter = writer
+	if _, err := app.New(opts).Run(context.Background(), file, opts.Output); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(opts.Output); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing written on disk using a custom TileWriter")
+	}
+	for _, name := range []string{"tileset.json", "statistics.json", "legend.json"} {
+		if _, ok := writer.files[filepath.Join(opts.Output, "test", name)]; !ok {
+			t.Errorf("Expected %s written through the TileWriter", name)
+		}
+	}
+
+	opts.MasterTileset = true
+	opts.FolderProcessing = true
+	if err := app.RunTiler(opts); err == nil {
+		t.Errorf("Expected an error writing a master tileset through a custom TileWriter")
+	}
+}
@@ -0,0 +1,83 @@
+package test
+
+import (
+	"encoding/json"
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"github.com/mfbonfigli/gocesiumtiler/structs/octree"
+	"os"
+	"path"
+	"strconv"
+	"testing"
+)
+
+func TestLeafChildWithPointsIsLinkedInTheParentTileset(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.MaxNumPointsPerNode = 2
+	// the root stores two points, the third one goes to a leaf child without further descendants
+	points := []*data.Point{
+		data.NewPoint(0, 0, 0, 0, 0, 0, 0, 0),
+		data.NewPoint(10, 10, 10, 0, 0, 0, 0, 0),
+		data.NewPoint(1, 1, 1, 0, 0, 0, 0, 0),
+	}
+	tree := buildTree(t, points, opts)
+	exportTree(t, tree, opts)
+
+	leaves := assertChildrenWithPointsAreLinked(t, &tree.RootNode, opts.Output)
+	if leaves != 1 {
+		t.Errorf("Expected 1 leaf child with points, got %d", leaves)
+	}
+}
+
+func TestAllChildrenWithPointsAreLinked(t *testing.T) {
+	opts := newTestOptions(t)
+	defer os.RemoveAll(opts.Output)
+	opts.MaxNumPointsPerNode = 50
+	tree := buildTree(t, newTestPoints(), opts)
+	exportTree(t, tree, opts)
+
+	if leaves := assertChildrenWithPointsAreLinked(t, &tree.RootNode, opts.Output); leaves == 0 {
+		t.Errorf("Expected leaf children with points, got none")
+	}
+}
+
+// Checks that the tileset.json of the given node, written in the given folder, and the ones of its descendants link
+// all the children storing points. Returns the number of linked leaf children
+func assertChildrenWithPointsAreLinked(t *testing.T, node *octree.OctNode, folder string) int {
+	content, err := os.ReadFile(path.Join(folder, "tileset.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tileset io.Tileset
+	if err := json.Unmarshal(content, &tileset); err != nil {
+		t.Fatal(err)
+	}
+	urls := make(map[string]bool)
+	for _, child := range tileset.Root.Children {
+		urls[child.Content.Url] = true
+	}
+
+	leaves := 0
+	for i, child := range node.Children {
+		if child == nil || child.LocalChildrenCount == 0 {
+			continue
+		}
+		childFolder := path.Join(folder, strconv.Itoa(i))
+		if child.IsLeaf {
+			if !urls[strconv.Itoa(i)+"/content.pnts"] {
+				t.Errorf("Expected leaf child %s to be linked", childFolder)
+			}
+			if _, err := os.Stat(path.Join(childFolder, "content.pnts")); err != nil {
+				t.Errorf("Expected the content of leaf child %s, got %v", childFolder, err)
+			}
+			leaves++
+			continue
+		}
+		if !urls[strconv.Itoa(i)+"/tileset.json"] {
+			t.Errorf("Expected child %s to be linked", childFolder)
+		}
+		leaves += assertChildrenWithPointsAreLinked(t, child, childFolder)
+	}
+	return leaves
+}
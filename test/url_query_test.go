@@ -0,0 +1,58 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestContentUrlsCarryTheUrlQuery(t *testing.T) {
+	for _, query := range []string{"v=20240101", "?v=20240101"} {
+		opts := newTestOptions(t)
+		defer os.RemoveAll(opts.Output)
+		opts.MaxNumPointsPerNode = 20
+		opts.UrlQuery = query
+		opts.EnforceRegionContainment = true
+		opts.MonotonicGeometricError = true
+		writeTileset(t, newTestPoints(), opts)
+
+		urlNo := assertUrlQuery(t, opts.Output, "tileset.json", "?v=20240101")
+		contents := make(map[string]int)
+		collectTileContents(t, opts.Output, "tileset.json", 1, contents)
+		if urlNo < len(contents) || len(contents) < 3 {
+			t.Errorf("Expected a url per tile, got %d urls for %d tiles", urlNo, len(contents))
+		}
+		if _, err := io.ValidateGeometricErrors(filepath.Join(opts.Output, "tileset.json")); err != nil {
+			t.Errorf("Unexpected error validating the tileset: %v", err)
+		}
+	}
+}
+
+// Checks that all the content urls of the given tileset.json and of the tilesets it references end with the given
+// query, returning the number of checked urls
+func assertUrlQuery(t *testing.T, folder string, tilesetFile string, query string) int {
+	tileset, err := io.ReadTilesetFile(filepath.Join(folder, tilesetFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	urls := []string{tileset.Root.Content.Url}
+	for _, child := range tileset.Root.Children {
+		urls = append(urls, child.Content.Url)
+	}
+	urlNo := 0
+	for _, url := range urls {
+		urlNo++
+		if !strings.HasSuffix(url, query) || strings.Count(url, "?") != 1 {
+			t.Errorf("Expected url %s in %s to end with %s", url, tilesetFile, query)
+			continue
+		}
+		file := path.Join(path.Dir(tilesetFile), strings.TrimSuffix(url, query))
+		if strings.HasSuffix(file, ".json") {
+			urlNo += assertUrlQuery(t, folder, file, query)
+		}
+	}
+	return urlNo
+}
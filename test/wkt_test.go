@@ -0,0 +1,123 @@
+package test
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/converters"
+	"github.com/mfbonfigli/gocesiumtiler/converters/offset_elevation_corrector"
+	"github.com/mfbonfigli/gocesiumtiler/converters/proj4_coordinate_converter"
+	"github.com/mfbonfigli/gocesiumtiler/lasread"
+	"github.com/mfbonfigli/gocesiumtiler/structs/geometry"
+	"github.com/mfbonfigli/gocesiumtiler/structs/point_loader"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// ESRI WKT of WGS 84 / UTM zone 33N as written by ArcGIS
+const esriUtm33nWkt = `PROJCS["WGS_1984_UTM_Zone_33N",GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]],PROJECTION["Transverse_Mercator"],PARAMETER["False_Easting",500000.0],PARAMETER["False_Northing",0.0],PARAMETER["Central_Meridian",15.0],PARAMETER["Scale_Factor",0.9996],PARAMETER["Latitude_Of_Origin",0.0],UNIT["Meter",1.0]]`
+
+// ESRI WKT of a State Plane coordinate system in US feet as written by ArcGIS
+const esriStatePlaneWkt = `PROJCS["NAD_1983_StatePlane_California_III_FIPS_0403_Feet",GEOGCS["GCS_North_American_1983",DATUM["D_North_American_1983",SPHEROID["GRS_1980",6378137.0,298.257222101]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]],PROJECTION["Lambert_Conformal_Conic"],PARAMETER["False_Easting",6561666.666666666],PARAMETER["False_Northing",1640416.666666667],PARAMETER["Central_Meridian",-120.5],PARAMETER["Standard_Parallel_1",37.06666666666667],PARAMETER["Standard_Parallel_2",38.43333333333333],PARAMETER["Latitude_Of_Origin",36.5],UNIT["Foot_US",0.3048006096012192]]`
+
+// OGC WKT of WGS 84 / UTM zone 33N
+const ogcUtm33nWkt = `PROJCS["WGS 84 / UTM zone 33N",GEOGCS["WGS 84",DATUM["WGS_1984",SPHEROID["WGS 84",6378137,298.257223563,AUTHORITY["EPSG","7030"]],AUTHORITY["EPSG","6326"]],PRIMEM["Greenwich",0,AUTHORITY["EPSG","8901"]],UNIT["degree",0.0174532925199433,AUTHORITY["EPSG","9122"]],AUTHORITY["EPSG","4326"]],PROJECTION["Transverse_Mercator"],PARAMETER["latitude_of_origin",0],PARAMETER["central_meridian",15],PARAMETER["scale_factor",0.9996],PARAMETER["false_easting",500000],PARAMETER["false_northing",0],UNIT["metre",1,AUTHORITY["EPSG","9001"]],AXIS["Easting",EAST],AXIS["Northing",NORTH],AUTHORITY["EPSG","32633"]]`
+
+// CoordinateConverter that records the WKT and srids it receives
+type recordingCoordinateConverter struct {
+	identityCoordinateConverter
+	sync.Mutex
+	wkt   string
+	srids map[int]bool
+}
+
+func (c *recordingCoordinateConverter) GetWktSrid(wkt string) (int, error) {
+	c.wkt = wkt
+	return 1000000, nil
+}
+
+func (c *recordingCoordinateConverter) ConvertCoordinateSrid(sourceSrid int, targetSrid int, coord geometry.Coordinate) (geometry.Coordinate, error) {
+	c.Lock()
+	c.srids[sourceSrid] = true
+	c.Unlock()
+	return coord, nil
+}
+
+func (c *recordingCoordinateConverter) ConvertCoordinateSridBatch(sourceSrid int, targetSrid int, coords []geometry.Coordinate) ([]geometry.Coordinate, error) {
+	return converters.ConvertEachCoordinateSrid(c, sourceSrid, targetSrid, coords)
+}
+
+func TestEsriWktIsTranslatedToProj4(t *testing.T) {
+	proj4, err := proj4_coordinate_converter.WktToProj4(esriUtm33nWkt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "+proj=tmerc +x_0=500000 +y_0=0 +lon_0=15 +k=0.9996 +lat_0=0 +datum=WGS84 +units=m +no_defs"
+	if proj4 != expected {
+		t.Errorf("Expected %s, got %s", expected, proj4)
+	}
+}
+
+func TestEsriWktInFeetIsTranslatedToProj4(t *testing.T) {
+	proj4, err := proj4_coordinate_converter.WktToProj4(esriStatePlaneWkt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, parameter := range []string{"+proj=lcc", "+x_0=2000000 ", "+y_0=500000 ", "+lon_0=-120.5", "+lat_1=37.0666", "+lat_2=38.4333", "+lat_0=36.5", "+datum=NAD83", "+to_meter=0.3048006096012192"} {
+		if !strings.Contains(proj4, parameter) {
+			t.Errorf("Expected %s to contain %s", proj4, parameter)
+		}
+	}
+}
+
+func TestOgcWktEpsgCodeIsRead(t *testing.T) {
+	code, ok := proj4_coordinate_converter.GetWktEpsgCode(ogcUtm33nWkt)
+	if !ok || code != 32633 {
+		t.Errorf("Expected EPSG code 32633, got %d", code)
+	}
+	if _, ok := proj4_coordinate_converter.GetWktEpsgCode(esriUtm33nWkt); ok {
+		t.Errorf("Expected no EPSG code for ESRI WKT")
+	}
+}
+
+func TestInvalidWktIsRejected(t *testing.T) {
+	for _, wkt := range []string{"", "PROJCS[", `PROJCS["a",PROJECTION["Unknown"]]`, `COMPD_CS["a"]`} {
+		if _, err := proj4_coordinate_converter.WktToProj4(wkt); err == nil {
+			t.Errorf("Expected an error translating %s", wkt)
+		}
+	}
+}
+
+func TestLasFileWithEsriWktIsGeoreferencedFromWkt(t *testing.T) {
+	file := writeTestLasFile(t, testLasHeader{scale: [3]float64{1, 1, 1}, wkt: esriUtm33nWkt}, [][3]int32{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}})
+	converter := &recordingCoordinateConverter{srids: make(map[int]bool)}
+	lasFileLoader := lidario.NewLasFileLoader(converter, nil, point_loader.NewRandomLoader(0), nil, true)
+	lf, err := lasFileLoader.LoadLasFile(file, offset_elevation_corrector.NewOffsetElevationCorrector(0), 4326)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lf.Close()
+
+	if lf.Header.Wkt != esriUtm33nWkt {
+		t.Errorf("Expected WKT %s, got %s", esriUtm33nWkt, lf.Header.Wkt)
+	}
+	if converter.wkt != esriUtm33nWkt {
+		t.Errorf("Expected the WKT to be passed to the converter, got %s", converter.wkt)
+	}
+	if len(converter.srids) != 1 || !converter.srids[1000000] {
+		t.Errorf("Expected the points to be converted from the WKT srid, got %v", converter.srids)
+	}
+}
+
+func TestLasFileWktIsIgnoredByDefault(t *testing.T) {
+	file := writeTestLasFile(t, testLasHeader{scale: [3]float64{1, 1, 1}, wkt: esriUtm33nWkt}, [][3]int32{{1, 2, 3}, {4, 5, 6}})
+	converter := &recordingCoordinateConverter{srids: make(map[int]bool)}
+	lasFileLoader := lidario.NewLasFileLoader(converter, nil, point_loader.NewRandomLoader(0), nil, false)
+	lf, err := lasFileLoader.LoadLasFile(file, offset_elevation_corrector.NewOffsetElevationCorrector(0), 32633)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lf.Close()
+
+	if len(converter.srids) != 1 || !converter.srids[32633] {
+		t.Errorf("Expected the points to be converted from the given srid, got %v", converter.srids)
+	}
+}
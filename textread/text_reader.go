@@ -0,0 +1,190 @@
+package textread
+
+import (
+	"bufio"
+	"errors"
+	"github.com/mfbonfigli/gocesiumtiler/converters"
+	"github.com/mfbonfigli/gocesiumtiler/structs/data"
+	"github.com/mfbonfigli/gocesiumtiler/structs/geometry"
+	"github.com/mfbonfigli/gocesiumtiler/structs/point_loader"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Name of the columns not mapped to any point attribute
+const SkipColumn = "-"
+
+// Point attributes that can be mapped to the columns of a text file
+var textColumnNames = map[string]bool{
+	"x": true, "y": true, "z": true, "r": true, "g": true, "b": true, "intensity": true, "class": true, SkipColumn: true,
+}
+
+// Parses a comma separated column mapping, e.g. "x,y,z,-,intensity", listing the point attribute stored in each
+// column of a text file. Columns mapped to - are ignored. The x, y and z columns are required
+func ParseColumns(spec string) ([]string, error) {
+	columns := strings.Split(strings.ToLower(strings.TrimSpace(spec)), ",")
+	mapped := make(map[string]bool)
+	for i, column := range columns {
+		column = strings.TrimSpace(column)
+		if column == "" {
+			column = SkipColumn
+		}
+		if !textColumnNames[column] {
+			return nil, errors.New("invalid column " + column + ", expected one of x, y, z, r, g, b, intensity, class or -")
+		}
+		if column != SkipColumn && mapped[column] {
+			return nil, errors.New("column " + column + " is mapped more than once")
+		}
+		mapped[column] = true
+		columns[i] = column
+	}
+	if !mapped["x"] || !mapped["y"] || !mapped["z"] {
+		return nil, errors.New("columns must include x, y and z")
+	}
+	return columns, nil
+}
+
+// Reads the points of XYZ or CSV text files, one per line, and adds them to a Loader
+type TextFileLoader struct {
+	CoordinateConverter converters.CoordinateConverter
+	Loader              point_loader.Loader
+	Columns             []string                           // Point attribute stored in each column, as returned by ParseColumns
+	Delimiter           string                             // Delimiter of the columns, empty to split on any whitespace, comma or semicolon
+	ColorClassification func(class uint8) (R, G, B uint16) // If not nil, colors the points of the files without r, g and b columns from their classification
+	ForceClassColor     bool                               // Colors the points with ColorClassification even if the file has colors
+	ClipBounds          *geometry.BoundingBox              // If not nil, skips the points outside of this box, boundaries included, in the srid of the file
+	KeepEveryNth        int                                // If > 1, loads only the points whose index in the file is a multiple of this value
+}
+
+// Instances a new TextFileLoader. If no columns are given the files are expected to store x, y, z columns
+func NewTextFileLoader(coordinateConverter converters.CoordinateConverter, loader point_loader.Loader, columns []string, delimiter string) *TextFileLoader {
+	if len(columns) == 0 {
+		columns = []string{"x", "y", "z"}
+	}
+	return &TextFileLoader{
+		CoordinateConverter: coordinateConverter,
+		Loader:              loader,
+		Columns:             columns,
+		Delimiter:           delimiter,
+	}
+}
+
+// Reads the points of the given text file, converting their coordinates from the given srid to EPSG:4326 and
+// correcting their elevation. Empty lines and lines starting with # are ignored, as well as the header lines not
+// made of numbers preceding the first point. Colors, intensity and classification are expected in the 0-255 range
+func (textFileLoader *TextFileLoader) LoadTextFile(fileName string, zCorrection converters.ElevationCorrector, inSrid int) error {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNumber := 0
+	readPoints := 0
+	slab := data.NewPointSlab(data.DefaultPointSlabSize)
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		values, err := textFileLoader.parseLine(line)
+		if err != nil {
+			if readPoints == 0 {
+				// header line
+				continue
+			}
+			return errors.New("line " + strconv.Itoa(lineNumber) + " of " + fileName + ": " + err.Error())
+		}
+		readPoints++
+		if textFileLoader.KeepEveryNth > 1 && (readPoints-1)%textFileLoader.KeepEveryNth != 0 {
+			continue
+		}
+		if err := textFileLoader.addPoint(values, zCorrection, inSrid, slab); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// Splits the given line in its columns and parses the mapped ones
+func (textFileLoader *TextFileLoader) parseLine(line string) (map[string]float64, error) {
+	var fields []string
+	if textFileLoader.Delimiter == "" {
+		fields = strings.FieldsFunc(line, func(r rune) bool { return unicode.IsSpace(r) || r == ',' || r == ';' })
+	} else {
+		fields = strings.Split(line, textFileLoader.Delimiter)
+	}
+	if len(fields) < len(textFileLoader.Columns) {
+		return nil, errors.New("expected " + strconv.Itoa(len(textFileLoader.Columns)) + " columns, got " + strconv.Itoa(len(fields)))
+	}
+	values := make(map[string]float64, len(textFileLoader.Columns))
+	for i, column := range textFileLoader.Columns {
+		if column == SkipColumn {
+			continue
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(fields[i]), 64)
+		if err != nil {
+			return nil, errors.New("invalid " + column + " value " + fields[i])
+		}
+		values[column] = value
+	}
+	return values, nil
+}
+
+// Builds a Point, allocated from the given slab, from the given column values and adds it to the Loader
+func (textFileLoader *TextFileLoader) addPoint(values map[string]float64, zCorrection converters.ElevationCorrector, inSrid int, slab *data.PointSlab) error {
+	X, Y, Z := values["x"], values["y"], values["z"]
+	if textFileLoader.ClipBounds != nil && !textFileLoader.ClipBounds.Contains(X, Y, Z) {
+		return nil
+	}
+	tr, err := textFileLoader.CoordinateConverter.ConvertCoordinateSrid(inSrid, 4326, geometry.Coordinate{X: &X, Y: &Y, Z: &Z})
+	if err != nil {
+		return err
+	}
+	R, G, B := toColor(values["r"]), toColor(values["g"]), toColor(values["b"])
+	Classification := toUint8(values["class"])
+	if textFileLoader.ColorClassification != nil && (textFileLoader.ForceClassColor || !textFileLoader.hasColorColumns()) {
+		R, G, B = textFileLoader.ColorClassification(Classification)
+	}
+	textFileLoader.Loader.AddElement(slab.NewPoint(
+		*tr.X, *tr.Y, zCorrection.CorrectElevation(*tr.X, *tr.Y, *tr.Z),
+		R, G, B, toUint8(values["intensity"]), Classification,
+	))
+	return nil
+}
+
+// Returns true if any of the columns stores a color component
+func (textFileLoader *TextFileLoader) hasColorColumns() bool {
+	for _, column := range textFileLoader.Columns {
+		if column == "r" || column == "g" || column == "b" {
+			return true
+		}
+	}
+	return false
+}
+
+func toUint8(value float64) uint8 {
+	return uint8(math.Max(0, math.Min(255, math.Round(value))))
+}
+
+// Scales the given 8 bit color component to 16 bits
+func toColor(value float64) uint16 {
+	return uint16(toUint8(value)) * 257
+}
+
+// Returns the column delimiter named by the given flag value, translating the tab and space names
+func ParseDelimiter(name string) string {
+	switch strings.ToLower(name) {
+	case "tab":
+		return "\t"
+	case "space":
+		return " "
+	}
+	return name
+}
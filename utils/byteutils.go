@@ -15,6 +15,15 @@ func ConvertIntToByteArray(i int) []uint8 {
 	return b
 }
 
+// Returns a byte array containing the little endian representation of the uint16 values provided by the input slice
+func ConvertUint16ToByteArray(inData []uint16) []uint8 {
+	outData := make([]byte, len(inData)*2)
+	for i, value := range inData {
+		binary.LittleEndian.PutUint16(outData[i*2:], value)
+	}
+	return outData
+}
+
 // Returns a byte array containing the float32 representation of the float64 values provided by the input slice
 func ConvertTruncateFloat64ToFloat32ByteArray(inData []float64) []uint8 {
 	j := 0
@@ -35,3 +44,11 @@ func ConvertTruncateFloat64ToFloat32ByteArray(inData []float64) []uint8 {
 	return outData
 }
 
+// Returns a byte array containing the little endian representation of the float64 values provided by the input slice
+func ConvertFloat64ToByteArray(inData []float64) []uint8 {
+	outData := make([]byte, len(inData)*8)
+	for i, value := range inData {
+		binary.LittleEndian.PutUint64(outData[i*8:], math.Float64bits(value))
+	}
+	return outData
+}
@@ -1,12 +1,21 @@
 package utils
 
-import "flag"
+import (
+	"errors"
+	"flag"
+	"github.com/mfbonfigli/gocesiumtiler/structs/geometry"
+	"math"
+	"strconv"
+	"strings"
+)
 
 type Flags struct {
 	Input                     *string
 	Output                    *string
 	Srid                      *int
 	ZOffset                   *float64
+	FileZOffsets              *string
+	FileSrids                 *string
 	MaxNumPts                 *int
 	ZGeoidCorrection          *bool
 	FolderProcessing          *bool
@@ -14,22 +23,160 @@ type Flags struct {
 	Silent                    *bool
 	LogTimestamp              *bool
 	Hq                        *bool
+	BoundsSigmas              *float64
+	Subtree                   *string
+	ClassificationAlpha       *string
+	LowMemory                 *bool
+	SubtreeLevels             *int
+	NormalsMaxDepth           *int
+	Normals                   *bool
+	NormalNeighbors           *int
+	Precision                 *float64
+	Quantize                  *bool
+	Statistics                *bool
+	Containment               *bool
+	Concurrency               *int
+	Groups                    *string
+	Wkt                       *bool
+	MaxTileBytes              *int
+	GeoidGrids                *string
+	Monotonic                 *bool
+	Columns                   *string
+	Delimiter                 *string
+	ColorDepth                *int
+	Rgb565                    *bool
+	NormalizeIntensity        *bool
+	ReadBuffer                *int
+	Merge                     *bool
+	DepthFolders              *bool
+	DropOrigin                *bool
+	Deflate                   *bool
+	Sphere                    *string
+	Atomic                    *bool
+	TempDir                   *string
+	Sampling                  *string
+	Seed                      *int64
+	DiagonalFraction          *float64
+	Master                    *bool
+	Format                    *string
+	Legend                    *bool
+	MaxLevels                 *int
+	UrlQuery                  *string
+	OctantOrder               *string
+	Writers                   *int
+	OverlapCell               *float64
+	Graft                     *string
+	Slot                      *string
+	FileMode                  *string
+	DirMode                   *string
+	MinHeight                 *float64
+	Auto                      *bool
+	Intensity                 *string
+	ClassColors               *string
+	ForceClassColors          *bool
+	UnmappedColor             *string
+	ElevationRamp             *string
+	Include                   *string
+	Exclude                   *string
+	Clip                      *string
+	KeepEvery                 *int
+	Transform                 *string
+	Box                       *bool
+	SpacingErrors             *bool
+	Gzip                      *bool
+	Tight                     *bool
+	OutputMode                *string
+	Resume                    *bool
+	Dedup                     *float64
+	ExtraBytes                *string
+	TileNaming                *string
+	Implicit                  *bool
 	Help                      *bool
 	Version                   *bool
 }
 
 func ParseFlags() Flags {
-	input := defineStringFlag("input", "i", "", "Specifies the input las file/folder.")
+	input := defineStringFlag("input", "i", "", "Specifies the input las, laz, ply, xyz or csv file/folder.")
 	output := defineStringFlag("output", "o", "", "Specifies the output folder where to write the tileset data.")
-	srid := defineIntFlag("srid", "e", 4326, "EPSG srid code of input points.")
+	srid := defineIntFlag("srid", "e", 4326, "EPSG srid code of input points, 0 to detect the srid of each LAS file from its GeoKey or WKT VLRs.")
 	zOffset := defineFloat64Flag("zoffset", "z", 0, "Vertical offset to apply to points, in meters.")
+	fileZOffsets := defineStringFlag("filezoffsets", "filezoffsets", "", "Comma separated list of file:offset pairs, e.g. a.las:1.5,b.las:-0.3, specifying additional vertical offsets, in meters, to apply to the points of the LAS files with the given name, which must not be shared by several input files. Useful to align files with different vertical datums.")
 	maxNumPts := defineIntFlag("maxpts", "m", 50000, "Max number of points per tile. ")
 	zGeoidCorrection := defineBoolFlag("geoid", "g", false, "Enables Geoid to Ellipsoid elevation correction. Use this flag if your input LAS files have Z coordinates specified relative to the Earth geoid rather than to the standard ellipsoid.")
-	folderProcessing := defineBoolFlag("folder", "f", false, "Enables processing of all las files from input folder. Input must be a folder if specified")
-	recursiveFolderProcessing := defineBoolFlag("recursive", "r", false, "Enables recursive lookup for all .las files inside the subfolders")
+	folderProcessing := defineBoolFlag("folder", "f", false, "Enables processing of all las, laz, ply, xyz and csv files from input folder. Input must be a folder if specified")
+	recursiveFolderProcessing := defineBoolFlag("recursive", "r", false, "Enables recursive lookup for all .las, .laz, .ply, .xyz and .csv files inside the subfolders")
 	silent := defineBoolFlag("silent", "s", false, "Use to suppress all the non-error messages.")
 	logTimestamp := defineBoolFlag("timestamp", "t", false, "Adds timestamp to log messages.")
 	hq := defineBoolFlag("hq", "hq", false, "Enables a higher quality random pick algorithm.")
+	boundsSigmas := defineFloat64Flag("boundssigmas", "boundssigmas", 0, "If greater than 0, excludes from the root bounding region the points farther than this number of standard deviations from the mean. Outliers are still written in the tiles.")
+	subtree := defineStringFlag("subtree", "subtree", "", "Writes only the tiles of the subtree at the given tile path, e.g. 0/3/2. The whole input is still read to build the tree.")
+	classificationAlpha := defineStringFlag("alpha", "alpha", "", "Comma separated list of classification:alpha pairs, e.g. 7:64,18:64. If set, colors are written as RGBA and points of the listed classifications get the given alpha (0-255), the others are opaque.")
+	lowMemory := defineBoolFlag("lowmem", "lowmem", false, "Releases the points of each tile as soon as they are no longer needed while writing the tileset, reducing the peak memory usage.")
+	subtreeLevels := defineIntFlag("subtreelevels", "subtreelevels", 0, "If greater than 0, also writes the 3D Tiles 1.1 implicit tiling .subtree availability files, each spanning the given number of levels, in the subtrees folder.")
+	normalsMaxDepth := defineIntFlag("normalsdepth", "normalsdepth", 0, "Estimates point normals for lit rendering and writes them only in the coarse tiles up to the given depth, the root having depth 1. 0 disables normals.")
+	precision := defineFloat64Flag("precision", "precision", 0, "If greater than 0, rounds the point positions to a grid of the given size, in meters, to improve the compression of the tiles. This is a lossy transformation, positions can move by up to half the given size along each axis.")
+	statistics := defineBoolFlag("stats", "stats", false, "Writes a statistics.json file next to the tileset.json with the total number of points, the number of points per classification, intensity min/max/mean and the bounds of the points.")
+	containment := defineBoolFlag("containment", "containment", false, "Expands the bounding region of each tile where needed to contain the regions of its children, then validates this invariant on the written tileset.")
+	concurrency := defineIntFlag("concurrency", "concurrency", 0, "If greater than 0, in folder processing mode tiles up to the given number of files in parallel, running all the work on a shared pool of the given number of goroutines.")
+	groups := defineStringFlag("groups", "groups", "", "Semicolon separated list of name:classifications:multiplier groups, e.g. buildings:6:0.25;ground:2,9. If set, the points of each group are tiled in a separate tileset in the subfolder named as the group, with the geometric error of the tiles scaled by the optional multiplier (default 1). Lower multipliers keep the tiles loaded at longer ranges. Points of the other classifications are tiled in the other subfolder.")
+	wkt := defineBoolFlag("wkt", "wkt", false, "Reads the coordinate system of each LAS file from its WKT VLR, either OGC or ESRI (ArcGIS) flavored, if present. Files without WKT use the srid.")
+	maxTileBytes := defineIntFlag("maxbytes", "maxbytes", 0, "If greater than 0, caps the size in bytes of each content.pnts file, subsampling the points of the tiles exceeding it. This is a lossy transformation, the points exceeding the cap are not written.")
+	geoidGrids := defineStringFlag("geoidgrids", "geoidgrids", "", "Comma separated list of GTX or GeoTIFF (.tif, .tiff) geoid grid files, e.g. the EGM96 and EGM2008 grids distributed by PROJ. If set together with the geoid flag, the points covered by a grid are corrected with the undulation bilinearly interpolated from the first grid covering them, the others, including the ones next to grid nodes without data, with the default global geoid model.")
+	monotonic := defineBoolFlag("monotonic", "monotonic", false, "Caps the geometric error of each tile to the one of its parent, then validates that the geometric errors of the written tileset are non-negative and not increasing from parent to child tiles and logs their range. Geometric errors are expressed in meters.")
+	columns := defineStringFlag("columns", "columns", "x,y,z", "Comma separated list of the point attributes stored in the columns of .xyz and .csv input files, among x, y, z, r, g, b, intensity, class and - for the ignored columns, e.g. x,y,z,-,intensity. Colors, intensity and classification are expected in the 0-255 range.")
+	delimiter := defineStringFlag("delimiter", "delimiter", "", "Column delimiter of .xyz and .csv input files, tab and space are accepted as names. If empty, columns are split on any whitespace, comma or semicolon.")
+	colorDepth := defineIntFlag("colordepth", "colordepth", 8, "Bits per color channel, either 8 or 16. If 16, the full depth colors are also written in the RGB16 batch table property as unsigned shorts, the RGB feature table colors being limited to 8 bits by the pnts format.")
+	rgb565 := defineBoolFlag("rgb565", "rgb565", false, "Writes the colors packed in 2 bytes per point as RGB565 rather than 3 bytes as RGB, using 5 bits for red and blue and 6 bits for green. This is a lossy transformation. Cannot be used together with the alpha flag.")
+	readBuffer := defineIntFlag("readbuffer", "readbuffer", 64, "Max size in MB of the point records read at once from each las or laz file. Files are read in batches of this size, so that the memory used to read them does not depend on their size.")
+	merge := defineBoolFlag("merge", "merge", false, "In folder processing mode, reads all the files concurrently and tiles their points together in a single tileset written in the output folder, rather than a tileset per file. Cannot be used together with the concurrency flag.")
+	depthFolders := defineBoolFlag("depthfolders", "depthfolders", false, "Stores each tile in a folder named after its depth and the octant indexes leading to it from the root, e.g. L4/035, rather than in nested folders, e.g. 0/3/5, so that all the tiles of a given depth are in the same folder. The root tile, having depth 1, is stored in the output folder.")
+	normals := defineBoolFlag("normals", "normals", false, "Estimates point normals for lit rendering and writes them in all the tiles. To write them only in the coarse tiles use the normalsdepth flag instead.")
+	normalNeighbors := defineIntFlag("normalneighbors", "normalneighbors", 16, "Number of nearest neighbours of each point fitting the plane whose orientation gives the point normal. Higher values give smoother normals at the expense of processing speed.")
+	quantize := defineBoolFlag("quantize", "quantize", false, "Writes the point positions as 16 bit integers quantized within the bounds of each tile, rather than as 32 bit floats, halving their size. This is a lossy transformation, positions can move by up to 1/131070 of the tile size along each axis, e.g. 0.76 mm in a 100 m wide tile.")
+	normalizeIntensity := defineBoolFlag("normintensity", "normintensity", false, "Also writes the intensity of the points divided by the max intensity of the input points, i.e. in the 0-1 range, in the NORMALIZED_INTENSITY float batch table property.")
+	fileSrids := defineStringFlag("filesrids", "filesrids", "", "Comma separated list of file:srid pairs, e.g. a.las:32632,b.las:32633, specifying the EPSG srid code of the points of the input files with the given name, overriding the srid flag. Useful to merge files in different coordinate systems.")
+	dropOrigin := defineBoolFlag("droporigin", "droporigin", false, "Drops the points of LAS files whose coordinates, before any conversion, are exactly (0,0,0), usually artifacts of zero filled point records, logging their number.")
+	deflate := defineBoolFlag("deflate", "deflate", false, "Deflate compresses the binary bodies of the feature and batch tables of each content.pnts, declaring the custom GOCESIUMTILER_deflate_buffers extension as required in the tilesets. Bodies that deflate would not make smaller are written uncompressed. Tiles are smaller but can be read only by a loader implementing the extension, not by standard 3D Tiles viewers.")
+	sphere := defineStringFlag("sphere", "sphere", "", "Writes bounding spheres in ECEF coordinates rather than bounding regions, either for the root tile only (root) or for all the tiles (all). Spheres are valid anywhere on the globe, including across the antimeridian and around the poles. Cannot be used together with the containment and box flags.")
+	atomic := defineBoolFlag("atomic", "atomic", false, "Writes each content.pnts and tileset.json file to a temporary file, then moves it in place, so that an interrupted run never leaves partially written tiles.")
+	tempDir := defineStringFlag("tempdir", "tempdir", "", "Folder of the temporary files, e.g. on a fast or large volume. If empty, temporary files are written next to the tile files they replace. Temporary files are moved within the output folder if the temp folder is on another volume.")
+	sampling := defineStringFlag("sampling", "sampling", "", "Order in which points are assigned to the tiles, selecting the points of the coarse tiles: random (default), grid to decimate the points on regular grids for an even density, or poisson for Poisson-disk decimation, the most even but the slowest to compute. Cannot be used together with the hq flag.")
+	seed := defineInt64Flag("seed", "seed", 0, "If not 0, seeds the random selection of the points of the tiles, so that runs with the same seed and inputs write byte-identical tiles. The tree is then built on a single goroutine. If 0 a time based seed is used.")
+	diagonalFraction := defineFloat64Flag("gediagonal", "gediagonal", 0, "If greater than 0, sets the geometric error of each tile to the given fraction of the diagonal of its bounding box, in meters, rather than estimating it from the point density. Geometric errors are then always positive and halve at each level, making the screen space error easier to tune.")
+	master := defineBoolFlag("master", "master", false, "In folder processing mode, also writes a tileset.json in the output folder loading the tilesets of all the files, so that they can be loaded together. Cannot be used together with the merge, groups, sphere and box flags.")
+	format := defineStringFlag("format", "format", "pnts", "Format of the tile contents, either pnts for 3D Tiles 1.0 content.pnts files or glb for 3D Tiles 1.1 content.glb glTF point clouds. glb tiles store positions, colors and normals only and cannot be used together with the quantize, rgb565, colordepth 16, normintensity, deflate and extrabytes flags.")
+	legend := defineBoolFlag("legend", "legend", false, "Writes a legend.json file next to the tileset.json listing each classification of the points with its name, number of points and alpha, if set by the alpha flag. Names are read from the Classification Lookup VLR of LAS files, falling back to the standard ASPRS names.")
+	maxLevels := defineIntFlag("maxlevels", "maxlevels", 0, "If greater than 0, limits the tileset to the given number of levels, the root being level 1, e.g. 1 writes a single tile. Tiles at the last level are not subdivided and store all the points reaching them, exceeding the max number of points per tile if needed, so that no point is dropped.")
+	urlQuery := defineStringFlag("urlquery", "urlquery", "", "Query string appended to the content urls of the tilesets, e.g. v=20240101, so that redeployed tiles bypass stale CDN and browser caches without renaming the files.")
+	octantOrder := defineStringFlag("octantorder", "octantorder", "", "Comma separated permutation of the octants 0 to 7, listing the octant stored in each child folder 0 to 7, e.g. 0,2,1,3,4,6,5,7, to match the child ordering expected by other tools. Octants are numbered x + 2y + 4z, 1 denoting the upper half along each axis. If empty, child folders are named after the octants.")
+	writers := defineIntFlag("writers", "writers", 0, "Number of goroutines writing the tiles in parallel. If 0, a goroutine per CPU is used, or per goroutine of the shared pool if the concurrency flag is set.")
+	overlapCell := defineFloat64Flag("overlapcell", "overlapcell", 0, "If greater than 0, thins the points of overlapping flightlines, splitting the points in square cells of the given size in meters and keeping in each cell only the points of the flightline, i.e. of the LAS point source id, with the most points. Points flagged as overlap are dropped where points not flagged as overlap are available. Statistics and legend still count all the read points.")
+	graft := defineStringFlag("graft", "graft", "", "Root tileset.json of an existing tileset hierarchy in which the written tileset is linked, at the tile given by the slot flag. The bounding regions and geometric errors of the ancestors of the slot are expanded to include the new tileset, rewriting their tileset.json files. Requires a single written tileset, i.e. a single input file, merged files or a master tileset.")
+	slot := defineStringFlag("slot", "slot", "", "Path of child indexes, e.g. 2/0/5, from the root tile of the tileset given by the graft flag to the tile linking the written tileset, following external tilesets. If the last index equals the number of children of its parent a new child tile is appended.")
+	fileMode := defineStringFlag("filemode", "filemode", "0644", "Permission bits of the written files, in octal. Atomically written files get exactly these bits, the others are subject to the umask.")
+	dirMode := defineStringFlag("dirmode", "dirmode", "0755", "Permission bits of the created folders, in octal, subject to the umask.")
+	minHeight := defineFloat64Flag("minheight", "minheight", 0.01, "Min height span, in meters, of the bounding regions of the tiles. Thinner regions, e.g. of perfectly flat tiles whose min and max heights are equal, are thickened around their mid height, as degenerate regions can break the culling of some Cesium versions. 0 keeps the regions as computed.")
+	auto := defineBoolFlag("auto", "auto", false, "Reads a sample of 1% of the input points, measures their extent and density and overrides the max points per tile, the sampling strategy, the geometric errors and the bounds sigmas with the values recommended for them, logging the rationale.")
+	intensity := defineStringFlag("intensity", "intensity", "none", "Mapping of the 16 bit intensities of the LAS and LAZ points to the 8 bit intensities of the tiles: none divides them by 256, auto linearly stretches the range of intensities of all the input files, read with a first pass, to 0-255, and min,max, e.g. 0,4096, linearly maps the given range to 0-255, clamping the intensities outside of it.")
+	classColors := defineStringFlag("classcolors", "classcolors", "asprs", "Colors of the points of the input files without RGB, e.g. LAS point formats 0 and 1, assigned from their classification: asprs colors ground brown, vegetation green, buildings gray and water blue, none leaves the points black, otherwise a comma separated list of classification:color pairs with hex rrggbb colors, e.g. 2:8b5a2b,6:808080. Points of the other classifications get the unmappedcolor.")
+	forceClassColors := defineBoolFlag("forceclasscolors", "forceclasscolors", false, "Colors all the points from their classification with the classcolors map, including the points of the input files with RGB.")
+	unmappedColor := defineStringFlag("unmappedcolor", "unmappedcolor", "ffffff", "Hex rrggbb color of the points colored from their classification whose classification is not in the classcolors map.")
+	elevationRamp := defineStringFlag("elevationramp", "elevationramp", "", "Colors the points from their elevation, replacing their colors, with a gradient spanning the min and max elevations of the whole cloud, so that the colors are consistent across tiles: default for blue, green, yellow and red, otherwise a comma separated list of at least two hex rrggbb color stops, e.g. 0000ff,ff0000, each optionally prefixed by its position in the 0-1 range, e.g. 0:0000ff,0.2:00ff00,1:ff0000. Stops without a position are evenly spaced. If empty the points keep their colors.")
+	include := defineStringFlag("include", "include", "", "Comma separated list of classifications, e.g. 2,9, whose points are the only ones tiled. If empty the points of all the classifications are tiled, except the excluded ones.")
+	exclude := defineStringFlag("exclude", "exclude", "", "Comma separated list of classifications, e.g. 7,18 to drop the noise, whose points are not tiled. Applied after the include flag, so that a classification both included and excluded is dropped.")
+	clip := defineStringFlag("clip", "clip", "", "Comma separated minx,miny,maxx,maxy bounds, optionally followed by minz,maxz, in the srid of the input points, e.g. 500000,4500000,501000,4501000. If set, only the points within the bounds, boundaries included, are read and tiled. LAS and LAZ files whose declared extent does not intersect the bounds are rejected.")
+	keepEvery := defineIntFlag("keepevery", "keepevery", 1, "Reads only one point every the given number of points of each input file, e.g. 10 keeps 10% of the points, for quick coarse previews of massive clouds. 1 keeps all the points.")
+	transform := defineStringFlag("transform", "transform", "", "Column major 4x4 matrix, as 16 comma separated values, written as transform of the root tile, e.g. 1,0,0,0,0,1,0,0,0,0,1,0,tx,ty,tz,1 to translate the points by tx, ty, tz meters in ECEF coordinates without reprojecting them. The bounding regions are computed on the transformed points.")
+	box := defineBoolFlag("box", "box", false, "Writes oriented bounding boxes in ECEF coordinates rather than bounding regions for all the tiles, aligned to the axes of the input coordinates. Boxes fit rotated or locally projected data more tightly than regions. Cannot be used together with the sphere and containment flags.")
+	spacingErrors := defineBoolFlag("gespacing", "gespacing", false, "Sets the geometric error of each tile with children to the diagonal of its bounding box divided by the cube root of the number of points rendered down to the tile, i.e. about their average spacing, rather than estimating it from the difference of the point densities. Leaf tiles get 0. Cannot be used together with the gediagonal flag.")
+	gzip := defineBoolFlag("gzip", "gzip", false, "Gzip compresses the content and tileset.json files, keeping their names. The files must then be served with the Content-Encoding: gzip header.")
+	tight := defineBoolFlag("tight", "tight", false, "Bounds each tile by the extent of its points and of the ones of its descendants rather than by its octree cell, so that sparse tiles are culled more accurately. Costs an extra pass on the points.")
+	outputMode := defineStringFlag("outputmode", "outputmode", "", "Coordinates of the written points: geocentric (default) for EPSG:4978 ECEF coordinates, enu for east north up coordinates relative to the center of the cloud, placed on the globe by the transform of the root tile, or raw to keep the input coordinates as they are, e.g. for local or engineering scenes, placed by the transform flag if set. Bounding regions are replaced by boxes in raw mode. The raw mode cannot be used together with the containment, master and graft flags.")
+	resume := defineBoolFlag("resume", "resume", false, "Writes a .crc sidecar with the size and checksum of each tile file and skips the tiles whose file matches its sidecar, so that an interrupted run can be finished by running it again with the same flags. Interrupted runs keep their tiles. Requires a seed, so that the runs build the same tiles.")
+	dedup := defineFloat64Flag("dedup", "dedup", 0, "If greater than 0, drops the points closer than the given distance in meters to a point already read, e.g. the duplicates of clouds merged from overlapping scans. Which of the duplicates is kept depends on the reading order. The number of dropped points is logged and written in the statistics, which do not count them.")
+	extraBytes := defineStringFlag("extrabytes", "extrabytes", "", "Comma separated list of names of LAS extra bytes attributes, e.g. Reflectance,Deviation, written as batch table properties of the same name to style the points on them. Their types are the ones declared by the Extra Bytes VLRs of the input files, scaled values being written as doubles. Points missing an attribute get NaN, or 0 for integer types. Cannot be used together with glb tiles.")
+	tileNaming := defineStringFlag("tilenaming", "tilenaming", "", "Naming scheme of the tile folders: numeric (default) for folders named after the octant index of each tile, e.g. 0/3/5, or hierarchical for folders named after the level of each tile, the root being at level 0, and its x, y and z indexes in the grid of that level, e.g. 1_0_1_1/2_1_3_2, unique across levels. With the depthfolders flag the hierarchical tiles are stored as e.g. L4/3_2_7_5.")
+	implicit := defineBoolFlag("implicit", "implicit", false, "Writes a single 3D Tiles 1.1 tileset.json declaring an implicit octree tiling rather than a tileset.json per tile with children. Tile contents are stored in the content/{level}/{x}/{y}/{z} folders and their availability in the subtree files of the subtrees folder, each spanning the levels given by the subtreelevels flag (default 4). Cannot be used together with the depthfolders, tilenaming, subtree, sphere, box, tight and containment flags, nor with the raw output mode.")
 	help := defineBoolFlag("help", "h", false, "Displays this help.")
 	version := defineBoolFlag("version", "v", false, "Displays the version of gocesiumtiler.")
 
@@ -40,6 +187,8 @@ func ParseFlags() Flags {
 		Output:                    output,
 		Srid:                      srid,
 		ZOffset:                   zOffset,
+		FileZOffsets:              fileZOffsets,
+		FileSrids:                 fileSrids,
 		MaxNumPts:                 maxNumPts,
 		ZGeoidCorrection:          zGeoidCorrection,
 		FolderProcessing:          folderProcessing,
@@ -47,6 +196,74 @@ func ParseFlags() Flags {
 		Silent:                    silent,
 		LogTimestamp:              logTimestamp,
 		Hq:                        hq,
+		BoundsSigmas:              boundsSigmas,
+		Subtree:                   subtree,
+		ClassificationAlpha:       classificationAlpha,
+		LowMemory:                 lowMemory,
+		SubtreeLevels:             subtreeLevels,
+		NormalsMaxDepth:           normalsMaxDepth,
+		Normals:                   normals,
+		NormalNeighbors:           normalNeighbors,
+		Precision:                 precision,
+		Quantize:                  quantize,
+		Statistics:                statistics,
+		Containment:               containment,
+		Concurrency:               concurrency,
+		Groups:                    groups,
+		Wkt:                       wkt,
+		MaxTileBytes:              maxTileBytes,
+		GeoidGrids:                geoidGrids,
+		Monotonic:                 monotonic,
+		Columns:                   columns,
+		Delimiter:                 delimiter,
+		ColorDepth:                colorDepth,
+		Rgb565:                    rgb565,
+		NormalizeIntensity:        normalizeIntensity,
+		ReadBuffer:                readBuffer,
+		Merge:                     merge,
+		DepthFolders:              depthFolders,
+		DropOrigin:                dropOrigin,
+		Deflate:                   deflate,
+		Sphere:                    sphere,
+		Atomic:                    atomic,
+		TempDir:                   tempDir,
+		Sampling:                  sampling,
+		Seed:                      seed,
+		DiagonalFraction:          diagonalFraction,
+		Master:                    master,
+		Format:                    format,
+		Legend:                    legend,
+		MaxLevels:                 maxLevels,
+		UrlQuery:                  urlQuery,
+		OctantOrder:               octantOrder,
+		Writers:                   writers,
+		OverlapCell:               overlapCell,
+		Graft:                     graft,
+		Slot:                      slot,
+		FileMode:                  fileMode,
+		DirMode:                   dirMode,
+		MinHeight:                 minHeight,
+		Auto:                      auto,
+		Intensity:                 intensity,
+		ClassColors:               classColors,
+		ForceClassColors:          forceClassColors,
+		UnmappedColor:             unmappedColor,
+		ElevationRamp:             elevationRamp,
+		Include:                   include,
+		Exclude:                   exclude,
+		Clip:                      clip,
+		KeepEvery:                 keepEvery,
+		Transform:                 transform,
+		Box:                       box,
+		SpacingErrors:             spacingErrors,
+		Gzip:                      gzip,
+		Tight:                     tight,
+		OutputMode:                outputMode,
+		Resume:                    resume,
+		Dedup:                     dedup,
+		ExtraBytes:                extraBytes,
+		TileNaming:                tileNaming,
+		Implicit:                  implicit,
 		Help:                      help,
 		Version:                   version,
 	}
@@ -72,6 +289,15 @@ func defineIntFlag(name string, shortHand string, defaultValue int, usage string
 	return &output
 }
 
+func defineInt64Flag(name string, shortHand string, defaultValue int64, usage string) *int64 {
+	var output int64
+	flag.Int64Var(&output, name, defaultValue, usage)
+	if shortHand != name {
+		flag.Int64Var(&output, shortHand, defaultValue, usage+" (shorthand for "+name+")")
+	}
+	return &output
+}
+
 func defineFloat64Flag(name string, shortHand string, defaultValue float64, usage string) *float64 {
 	var output float64
 	flag.Float64Var(&output, name, defaultValue, usage)
@@ -89,3 +315,112 @@ func defineBoolFlag(name string, shortHand string, defaultValue bool, usage stri
 	}
 	return &output
 }
+
+// Parses a comma separated list of classification:value pairs, e.g. "7:64,18:0", into a map from classification
+// to value. Both classifications and values must be integers between 0 and 255
+func ParseClassificationValues(value string) (map[uint8]uint8, error) {
+	result := make(map[uint8]uint8)
+	if strings.TrimSpace(value) == "" {
+		return result, nil
+	}
+	for _, pair := range strings.Split(value, ",") {
+		tokens := strings.Split(strings.TrimSpace(pair), ":")
+		if len(tokens) != 2 {
+			return nil, errors.New("invalid classification:value pair " + pair)
+		}
+		classification, err := strconv.ParseUint(tokens[0], 10, 8)
+		if err != nil {
+			return nil, errors.New("invalid classification in pair " + pair)
+		}
+		val, err := strconv.ParseUint(tokens[1], 10, 8)
+		if err != nil {
+			return nil, errors.New("invalid value in pair " + pair)
+		}
+		result[uint8(classification)] = uint8(val)
+	}
+	return result, nil
+}
+
+// Parses a comma separated list of classifications, e.g. "2,9", each an integer between 0 and 255
+func ParseClassifications(value string) ([]uint8, error) {
+	result := make([]uint8, 0)
+	if strings.TrimSpace(value) == "" {
+		return result, nil
+	}
+	for _, token := range strings.Split(value, ",") {
+		classification, err := strconv.ParseUint(strings.TrimSpace(token), 10, 8)
+		if err != nil {
+			return nil, errors.New("invalid classification " + token)
+		}
+		result = append(result, uint8(classification))
+	}
+	return result, nil
+}
+
+// Parses comma separated minx,miny,maxx,maxy bounds, optionally followed by minz,maxz, e.g. "0,0,10,10,-5,5", into
+// a bounding box unbounded along z if no z bounds are given. Returns nil if the value is empty
+func ParseBoundingBox(value string) (*geometry.BoundingBox, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	tokens := strings.Split(value, ",")
+	if len(tokens) != 4 && len(tokens) != 6 {
+		return nil, errors.New("invalid bounds " + value + ", expected minx,miny,maxx,maxy[,minz,maxz]")
+	}
+	bounds := []float64{0, 0, 0, 0, math.Inf(-1), math.Inf(1)}
+	for i, token := range tokens {
+		bound, err := strconv.ParseFloat(strings.TrimSpace(token), 64)
+		if err != nil || math.IsNaN(bound) {
+			return nil, errors.New("invalid bound " + token)
+		}
+		bounds[i] = bound
+	}
+	if bounds[0] > bounds[2] || bounds[1] > bounds[3] || bounds[4] > bounds[5] {
+		return nil, errors.New("invalid bounds " + value + ", the min bounds cannot exceed the max ones")
+	}
+	return geometry.NewBoundingBox(bounds[0], bounds[2], bounds[1], bounds[3], bounds[4], bounds[5]), nil
+}
+
+// Parses a comma separated list of file:srid pairs, e.g. "a.las:32632,b.las:32633", into a map from file name to
+// srid. The file name is separated from the srid by the last colon
+func ParseFileSrids(value string) (map[string]int, error) {
+	result := make(map[string]int)
+	if strings.TrimSpace(value) == "" {
+		return result, nil
+	}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		separator := strings.LastIndex(pair, ":")
+		if separator <= 0 {
+			return nil, errors.New("invalid file:srid pair " + pair)
+		}
+		srid, err := strconv.Atoi(pair[separator+1:])
+		if err != nil {
+			return nil, errors.New("invalid srid in pair " + pair)
+		}
+		result[pair[:separator]] = srid
+	}
+	return result, nil
+}
+
+// Parses a comma separated list of file:offset pairs, e.g. "a.las:1.5,b.las:-0.3", into a map from file name to
+// offset. The file name is separated from the offset by the last colon
+func ParseFileOffsets(value string) (map[string]float64, error) {
+	result := make(map[string]float64)
+	if strings.TrimSpace(value) == "" {
+		return result, nil
+	}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		separator := strings.LastIndex(pair, ":")
+		if separator <= 0 {
+			return nil, errors.New("invalid file:offset pair " + pair)
+		}
+		offset, err := strconv.ParseFloat(pair[separator+1:], 64)
+		if err != nil {
+			return nil, errors.New("invalid offset in pair " + pair)
+		}
+		result[pair[:separator]] = offset
+	}
+	return result, nil
+}
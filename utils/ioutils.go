@@ -1,9 +1,11 @@
 package utils
 
 import (
+	"errors"
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 )
 
 func OpenFileOrFail(filePath string) *os.File {
@@ -15,6 +17,15 @@ func OpenFileOrFail(filePath string) *os.File {
 	return file
 }
 
+// Parses permission bits written in octal, e.g. 0644 or 755
+func ParseFileMode(value string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, errors.New("invalid permission bits " + value + ", expected an octal number between 0 and 0777")
+	}
+	return os.FileMode(mode), nil
+}
+
 func GetExecutablePath() string {
 	//Executable path
 	ex, err := os.Executable()
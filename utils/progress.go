@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stages of the tiling reported to the progress callbacks
+const (
+	ProgressStageReading = "reading" // done and total count the points of the file being read
+	ProgressStageWriting = "writing" // done and total count the tiles of the tileset being written
+)
+
+// Minimum interval between two progress reports, the final one excluded
+const ProgressReportInterval = 100 * time.Millisecond
+
+// Counts the progress of a stage of the tiling and reports it to a callback, at most once per ProgressReportInterval
+// and always when the stage completes. Safe for concurrent use, the callback calls are serialized. A nil
+// *ProgressReporter is valid and does nothing.
+type ProgressReporter struct {
+	callback   func(stage string, done, total int64)
+	stage      string
+	total      int64
+	done       int64
+	lastReport int64 // Unix time in nanoseconds of the last report
+	reported   int64 // Done count of the last report
+	sync.Mutex
+}
+
+// Instances a new ProgressReporter of the given stage and total, returns nil if the callback is nil
+func NewProgressReporter(callback func(stage string, done, total int64), stage string, total int64) *ProgressReporter {
+	if callback == nil {
+		return nil
+	}
+	return &ProgressReporter{callback: callback, stage: stage, total: total}
+}
+
+// Adds the given amount to the done count and reports it, unless the last report is too recent
+func (reporter *ProgressReporter) Add(n int64) {
+	if reporter == nil {
+		return
+	}
+	done := atomic.AddInt64(&reporter.done, n)
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&reporter.lastReport)
+	if done < reporter.total && (now-last < int64(ProgressReportInterval) || !atomic.CompareAndSwapInt64(&reporter.lastReport, last, now)) {
+		return
+	}
+	reporter.Lock()
+	defer reporter.Unlock()
+	// reports the latest count, which may be higher than the one that triggered the report, unless already reported
+	if done = atomic.LoadInt64(&reporter.done); done > reporter.reported {
+		reporter.reported = done
+		reporter.callback(reporter.stage, done, reporter.total)
+	}
+}
@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Parses a tile path expressed as a sequence of octant indexes separated by slashes, e.g. "0/3/2", into the
+// corresponding list of octant indexes. An empty string denotes the root tile and returns an empty list.
+func ParseTilePath(tilePath string) ([]uint8, error) {
+	tilePath = strings.Trim(tilePath, "/")
+	if tilePath == "" {
+		return []uint8{}, nil
+	}
+
+	tokens := strings.Split(tilePath, "/")
+	octants := make([]uint8, len(tokens))
+	for i, token := range tokens {
+		octant, err := strconv.Atoi(token)
+		if err != nil || octant < 0 || octant > 7 {
+			return nil, errors.New("invalid tile path " + tilePath + ", octant indexes must be integers between 0 and 7")
+		}
+		octants[i] = uint8(octant)
+	}
+	return octants, nil
+}
+
+// Parses a path of child tile indexes separated by slashes, e.g. "2/0/5", selecting a tile of a tileset whose tiles
+// may have any number of children. An empty string returns an empty list.
+func ParseChildPath(childPath string) ([]int, error) {
+	childPath = strings.Trim(childPath, "/")
+	if childPath == "" {
+		return []int{}, nil
+	}
+
+	tokens := strings.Split(childPath, "/")
+	indexes := make([]int, len(tokens))
+	for i, token := range tokens {
+		index, err := strconv.Atoi(token)
+		if err != nil || index < 0 {
+			return nil, errors.New("invalid child path " + childPath + ", child indexes must be non negative integers")
+		}
+		indexes[i] = index
+	}
+	return indexes, nil
+}
+
+// Parses a comma separated permutation of the octants 0-7, e.g. "0,2,1,3,4,6,5,7", listing the octant stored under
+// each child index. An empty string denotes the default order and returns an empty list.
+func ParseOctantOrder(value string) ([]uint8, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return []uint8{}, nil
+	}
+
+	tokens := strings.Split(value, ",")
+	order := make([]uint8, len(tokens))
+	seen := make(map[int]bool)
+	for i, token := range tokens {
+		octant, err := strconv.Atoi(strings.TrimSpace(token))
+		if err != nil || octant < 0 || octant > 7 || seen[octant] {
+			return nil, errors.New("invalid octant order " + value + ", a permutation of the octants 0 to 7 is required")
+		}
+		seen[octant] = true
+		order[i] = uint8(octant)
+	}
+	if len(order) != 8 {
+		return nil, errors.New("invalid octant order " + value + ", a permutation of the octants 0 to 7 is required")
+	}
+	return order, nil
+}
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"runtime"
+	"sync"
+)
+
+// Fixed size pool of goroutines running the submitted tasks. A nil *WorkerPool is valid and runs each task in its own
+// goroutine, sizing the work as per the number of CPUs.
+type WorkerPool struct {
+	size  int
+	tasks chan func()
+}
+
+// Instances a new WorkerPool and starts its goroutines. The pool must be closed when no longer needed.
+func NewWorkerPool(size int) *WorkerPool {
+	if size < 1 {
+		size = 1
+	}
+	pool := &WorkerPool{
+		size:  size,
+		tasks: make(chan func()),
+	}
+	for i := 0; i < size; i++ {
+		go func() {
+			for task := range pool.tasks {
+				task()
+			}
+		}()
+	}
+	return pool
+}
+
+// Returns the number of goroutines of the pool, or the number of CPUs if the pool is nil
+func (pool *WorkerPool) Size() int {
+	if pool == nil {
+		return runtime.NumCPU()
+	}
+	return pool.size
+}
+
+// Runs the given tasks and waits for their completion. Must not be called from a task running in the same pool.
+func (pool *WorkerPool) Run(tasks ...func()) {
+	var wg sync.WaitGroup
+	wg.Add(len(tasks))
+	for _, task := range tasks {
+		task := task
+		run := func() {
+			defer wg.Done()
+			task()
+		}
+		if pool == nil {
+			go run()
+		} else {
+			pool.tasks <- run
+		}
+	}
+	wg.Wait()
+}
+
+// Stops the goroutines of the pool once they complete the tasks being run
+func (pool *WorkerPool) Close() {
+	if pool != nil {
+		close(pool.tasks)
+	}
+}